@@ -0,0 +1,461 @@
+// Package a11y derives an accessibility tree from the DOM: roles, accessible
+// names and states, in the shape platform accessibility APIs expect
+package a11y
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"go-browser/css"
+	"go-browser/dom"
+)
+
+// Role is the ARIA role of an accessible node
+type Role string
+
+const (
+	RoleDocument   Role = "document"
+	RoleHeading    Role = "heading"
+	RoleLink       Role = "link"
+	RoleButton     Role = "button"
+	RoleTextbox    Role = "textbox"
+	RoleCheckbox   Role = "checkbox"
+	RoleRadio      Role = "radio"
+	RoleCombobox   Role = "combobox"
+	RoleList       Role = "list"
+	RoleListItem   Role = "listitem"
+	RoleImage      Role = "img"
+	RoleTable      Role = "table"
+	RoleRow        Role = "row"
+	RoleCell       Role = "cell"
+	RoleForm       Role = "form"
+	RoleNavigation Role = "navigation"
+	RoleMain       Role = "main"
+	RoleBanner     Role = "banner"
+	RoleFooter     Role = "contentinfo"
+	RoleParagraph  Role = "paragraph"
+	RoleText       Role = "text"
+	RoleGeneric    Role = "generic"
+)
+
+// Node is one entry of the accessibility tree
+type Node struct {
+	Role     Role
+	Name     string
+	Level    int    // heading level (1-6), 0 otherwise
+	Value    string // current value of text fields and selects
+	Checked  bool
+	Disabled bool
+	Children []*Node
+	DOM      *dom.Node
+}
+
+// =============================================================================
+// TREE CONSTRUCTION
+// =============================================================================
+
+// Checked reports the live checked state of a checkbox or radio, which the
+// user may have changed since its checked attribute was parsed; ok is false
+// when the control has not been touched
+type Checked func(node *dom.Node) (checked, ok bool)
+
+// Build derives the accessibility tree of a DOM tree. Hidden subtrees are
+// dropped and generic containers (div, span, ...) are flattened into their
+// parent so only meaningful nodes remain.
+func Build(root *dom.Node) *Node {
+	return BuildWith(root, nil)
+}
+
+// BuildWith is Build taking the checked state of controls from checked,
+// falling back to their attributes where it has none
+func BuildWith(root *dom.Node, checked Checked) *Node {
+	b := builder{checked: checked}
+	doc := &Node{Role: RoleDocument, DOM: root}
+	if root == nil {
+		return doc
+	}
+	if title := findTitle(root); title != "" {
+		doc.Name = title
+	}
+	doc.Children = b.children(root)
+	return doc
+}
+
+// builder carries the live state a tree is built with
+type builder struct {
+	checked Checked
+}
+
+func (b builder) children(node *dom.Node) []*Node {
+	var out []*Node
+	for _, child := range node.Children {
+		out = append(out, b.node(child)...)
+	}
+	return out
+}
+
+// node returns the accessible nodes for a DOM node: one node for elements
+// with a role, or the flattened children for generic ones
+func (b builder) node(node *dom.Node) []*Node {
+	if node.Type == dom.NodeText {
+		text := collapseSpace(node.Content)
+		if text == "" {
+			return nil
+		}
+		return []*Node{{Role: RoleText, Name: text, DOM: node}}
+	}
+	if node.Type != dom.NodeElement || isHidden(node) {
+		return nil
+	}
+
+	role := RoleOf(node)
+	if role == RoleGeneric {
+		return b.children(node)
+	}
+
+	an := &Node{
+		Role:     role,
+		Name:     AccessibleName(node),
+		DOM:      node,
+		Disabled: node.HasAttr("disabled") || node.GetAttr("aria-disabled") == "true",
+	}
+
+	switch role {
+	case RoleHeading:
+		an.Level = headingLevel(node)
+	case RoleCheckbox, RoleRadio:
+		an.Checked = b.isChecked(node)
+	case RoleTextbox:
+		if node.Tag == "textarea" {
+			an.Value = collapseSpace(node.TextContent())
+		} else {
+			an.Value = node.GetAttr("value")
+		}
+	case RoleCombobox:
+		an.Value = selectedOption(node)
+	}
+
+	// Leaves whose name already comes from their content don't repeat it
+	if !nameFromContent(role) && role != RoleCombobox && role != RoleTextbox && role != RoleImage {
+		an.Children = b.children(node)
+	}
+	return []*Node{an}
+}
+
+// isChecked returns the checked state of a checkbox or radio
+func (b builder) isChecked(node *dom.Node) bool {
+	if b.checked != nil {
+		if checked, ok := b.checked(node); ok {
+			return checked
+		}
+	}
+	return node.HasAttr("checked") || node.GetAttr("aria-checked") == "true"
+}
+
+// RoleOf returns the role of an element, honouring an explicit role attribute
+func RoleOf(node *dom.Node) Role {
+	if explicit := strings.Fields(node.GetAttr("role")); len(explicit) > 0 {
+		return Role(explicit[0])
+	}
+
+	switch node.Tag {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		return RoleHeading
+	case "a":
+		if node.HasAttr("href") {
+			return RoleLink
+		}
+	case "button":
+		return RoleButton
+	case "input":
+		switch strings.ToLower(node.GetAttr("type")) {
+		case "submit", "button", "reset", "image":
+			return RoleButton
+		case "checkbox":
+			return RoleCheckbox
+		case "radio":
+			return RoleRadio
+		case "hidden":
+			return RoleGeneric
+		default:
+			return RoleTextbox
+		}
+	case "textarea":
+		return RoleTextbox
+	case "select":
+		return RoleCombobox
+	case "ul", "ol":
+		return RoleList
+	case "li":
+		return RoleListItem
+	case "img":
+		return RoleImage
+	case "table":
+		return RoleTable
+	case "tr":
+		return RoleRow
+	case "td", "th":
+		return RoleCell
+	case "form":
+		return RoleForm
+	case "nav":
+		return RoleNavigation
+	case "main":
+		return RoleMain
+	case "header":
+		return RoleBanner
+	case "footer":
+		return RoleFooter
+	case "p":
+		return RoleParagraph
+	}
+	return RoleGeneric
+}
+
+// =============================================================================
+// ACCESSIBLE NAMES
+// =============================================================================
+
+// AccessibleName computes the name announced for an element: aria-labelledby,
+// aria-label, an associated <label>, alt text, then content or title
+func AccessibleName(node *dom.Node) string {
+	if ids := strings.Fields(node.GetAttr("aria-labelledby")); len(ids) > 0 {
+		root := documentRoot(node)
+		var parts []string
+		for _, id := range ids {
+			if ref := root.GetElementById(id); ref != nil {
+				parts = append(parts, collapseSpace(ref.TextContent()))
+			}
+		}
+		if name := strings.Join(parts, " "); strings.TrimSpace(name) != "" {
+			return name
+		}
+	}
+	if label := strings.TrimSpace(node.GetAttr("aria-label")); label != "" {
+		return label
+	}
+
+	switch node.Tag {
+	case "input", "textarea", "select":
+		if label := labelFor(node); label != "" {
+			return label
+		}
+		switch strings.ToLower(node.GetAttr("type")) {
+		case "submit":
+			if v := node.GetAttr("value"); v != "" {
+				return v
+			}
+			return "Submit"
+		case "reset":
+			if v := node.GetAttr("value"); v != "" {
+				return v
+			}
+			return "Reset"
+		case "button":
+			return node.GetAttr("value")
+		case "image":
+			return node.GetAttr("alt")
+		}
+		if placeholder := node.GetAttr("placeholder"); placeholder != "" {
+			return placeholder
+		}
+	case "img":
+		if alt := node.GetAttr("alt"); alt != "" {
+			return alt
+		}
+	case "table":
+		for _, child := range node.Children {
+			if child.Tag == "caption" {
+				return collapseSpace(child.TextContent())
+			}
+		}
+	}
+
+	if nameFromContent(RoleOf(node)) {
+		if text := collapseSpace(visibleText(node)); text != "" {
+			return text
+		}
+	}
+	return strings.TrimSpace(node.GetAttr("title"))
+}
+
+// nameFromContent reports whether a role takes its name from its text
+func nameFromContent(role Role) bool {
+	switch role {
+	case RoleHeading, RoleLink, RoleButton, RoleCell:
+		return true
+	}
+	return false
+}
+
+// labelFor returns the text of the <label> associated with a form control,
+// either through for= or by wrapping it
+func labelFor(node *dom.Node) string {
+	if id := node.GetAttr("id"); id != "" {
+		for _, label := range documentRoot(node).GetElementsByTagName("label") {
+			if label.GetAttr("for") == id {
+				return collapseSpace(label.TextContent())
+			}
+		}
+	}
+	if label := node.ClosestAncestor("label"); label != nil {
+		return collapseSpace(visibleText(label))
+	}
+	return ""
+}
+
+// visibleText is TextContent skipping hidden subtrees and form controls
+func visibleText(node *dom.Node) string {
+	if node.Type == dom.NodeText {
+		return node.Content
+	}
+	if isHidden(node) || node.Tag == "select" || node.Tag == "textarea" {
+		return ""
+	}
+	if node.Tag == "img" {
+		return node.GetAttr("alt")
+	}
+	var sb strings.Builder
+	for _, child := range node.Children {
+		sb.WriteString(visibleText(child))
+		sb.WriteString(" ")
+	}
+	return sb.String()
+}
+
+// =============================================================================
+// HELPERS
+// =============================================================================
+
+// isHidden reports whether an element is excluded from the accessibility tree
+func isHidden(node *dom.Node) bool {
+	switch node.Tag {
+	case "head", "script", "style", "template", "noscript", "meta", "link", "title":
+		return true
+	}
+	if node.HasAttr("hidden") || node.GetAttr("aria-hidden") == "true" {
+		return true
+	}
+	if node.Display == dom.DisplayNone {
+		return true
+	}
+	if cs, ok := node.ComputedStyle.(*css.ComputedStyle); ok && cs.Display == "none" {
+		return true
+	}
+	return false
+}
+
+func headingLevel(node *dom.Node) int {
+	if level := node.GetAttr("aria-level"); level != "" {
+		var n int
+		if _, err := fmt.Sscanf(level, "%d", &n); err == nil && n > 0 {
+			return n
+		}
+	}
+	if len(node.Tag) == 2 && node.Tag[0] == 'h' {
+		return int(node.Tag[1] - '0')
+	}
+	return 2
+}
+
+func selectedOption(node *dom.Node) string {
+	first := ""
+	for _, opt := range node.GetElementsByTagName("option") {
+		text := collapseSpace(opt.TextContent())
+		if opt.HasAttr("selected") {
+			return text
+		}
+		if first == "" {
+			first = text
+		}
+	}
+	return first
+}
+
+func findTitle(root *dom.Node) string {
+	if doc := root.OwnerDocument(); doc != nil {
+		return doc.Title()
+	}
+	if titles := root.GetElementsByTagName("title"); len(titles) > 0 {
+		return collapseSpace(titles[0].TextContent())
+	}
+	return ""
+}
+
+func documentRoot(node *dom.Node) *dom.Node {
+	for node.Parent != nil {
+		node = node.Parent
+	}
+	return node
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// =============================================================================
+// TEXT DUMP
+// =============================================================================
+
+// String describes a single node the way a screen reader would announce it
+func (n *Node) String() string {
+	var sb strings.Builder
+	sb.WriteString(string(n.Role))
+	if n.Role == RoleHeading && n.Level > 0 {
+		fmt.Fprintf(&sb, " level %d", n.Level)
+	}
+	if n.Name != "" {
+		fmt.Fprintf(&sb, " %q", n.Name)
+	}
+	if n.Value != "" {
+		fmt.Fprintf(&sb, " value=%q", n.Value)
+	}
+	if n.Role == RoleCheckbox || n.Role == RoleRadio {
+		if n.Checked {
+			sb.WriteString(" checked")
+		} else {
+			sb.WriteString(" unchecked")
+		}
+	}
+	if n.Disabled {
+		sb.WriteString(" disabled")
+	}
+	return sb.String()
+}
+
+// Lines flattens the tree into indented text lines, one per node
+func (n *Node) Lines() []string {
+	var lines []string
+	var walk func(node *Node, depth int)
+	walk = func(node *Node, depth int) {
+		lines = append(lines, strings.Repeat("  ", depth)+node.String())
+		for _, child := range node.Children {
+			walk(child, depth+1)
+		}
+	}
+	walk(n, 0)
+	return lines
+}
+
+// Dump writes the indented tree to w, each line ending with the DOM node ID
+// (#N) of the node it describes
+func Dump(w io.Writer, n *Node) error {
+	var walk func(node *Node, depth int) error
+	walk = func(node *Node, depth int) error {
+		line := strings.Repeat("  ", depth) + node.String()
+		if node.DOM != nil && node.DOM.NodeID != 0 {
+			line += fmt.Sprintf(" #%d", node.DOM.NodeID)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+		for _, child := range node.Children {
+			if err := walk(child, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walk(n, 0)
+}
@@ -5,21 +5,24 @@ import (
 	"fmt"
 	"image/color"
 	"image/png"
-	"io"
 	"net/http"
 	"net/url"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
-	"unicode/utf8"
 
+	"go-browser/clipboard"
 	"go-browser/css"
 	"go-browser/dom"
 	"go-browser/gocko/forms"
 	"go-browser/layout"
+	"go-browser/logging"
+	"go-browser/perf"
 	"go-browser/render"
 	"go-browser/spidergopher"
-	spiderdom "go-browser/spidergopher/dom"
+	"go-browser/spidergopher/webapi"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/inpututil"
@@ -31,9 +34,11 @@ const (
 	WindowWidth  = 1024
 	WindowHeight = 768
 	NavBarHeight = 56.0
+	TabBarHeight = 30.0
+	ChromeHeight = NavBarHeight + TabBarHeight // browser UI above the page
 	URLBarHeight = 36.0
 	Padding      = 16.0
-	ContentTop   = NavBarHeight + 20
+	ContentTop   = ChromeHeight + 20
 	FontSizeBody = 15
 	FontSizeUI   = 14
 )
@@ -50,6 +55,7 @@ var (
 	ColorTextMuted     = color.RGBA{100, 100, 110, 255}
 	ColorAccent        = color.RGBA{25, 118, 210, 255}
 	ColorBorder        = color.RGBA{200, 200, 210, 255}
+	ColorDisabledVeil  = color.RGBA{140, 140, 140, 140} // translucent white
 	ColorButton        = color.RGBA{230, 230, 235, 255}
 	ColorButtonPrimary = color.RGBA{76, 120, 90, 255}
 	ColorButtonText    = color.RGBA{255, 255, 255, 255}
@@ -59,6 +65,9 @@ var (
 	ColorTableRow1     = color.RGBA{250, 250, 252, 255}
 	ColorTableRow2     = color.RGBA{240, 240, 245, 255}
 	ColorImageBg       = color.RGBA{230, 230, 235, 255}
+	ColorFocusRing     = color.RGBA{66, 133, 244, 255}
+	ColorURLText       = color.RGBA{50, 50, 55, 255}
+	ColorURLSelection  = color.RGBA{179, 212, 252, 255}
 )
 
 // NavBar represents the navigation bar
@@ -67,214 +76,224 @@ type NavBar struct {
 	IsEditing   bool
 	IsHovering  bool
 	CursorPos   int
+	SelAnchor   int     // other end of the selection; equals CursorPos when none
+	ScrollX     float64 // horizontal scroll of the text being edited
 	CursorBlink int
 	URLBarX     float32
 	URLBarY     float32
 	URLBarW     float32
+
+	dragging bool // a mouse drag is extending the selection
+
+	suggestions            []suggestion // autocomplete dropdown rows
+	suggestFor             string       // URLText the suggestions were built for
+	suggestSel             int          // highlighted row
+	lastMouseX, lastMouseY int
+
+	securityOpen bool    // the connection security panel is shown
+	securityW    float64 // width of the padlock area drawn last frame
 }
 
-// App represents the browser application
+// App represents the browser application. The active tab is embedded so its
+// page state (URL, Document, RenderTree, ...) reads as the app's own.
 type App struct {
-	URL               string
-	DOMRoot           *dom.Node
-	RenderTree        *layout.RenderBox
-	Stylesheets       []*css.Stylesheet
-	ScrollY           float64
-	IsLoading         bool
-	ErrorMsg          string
+	*Tab
+	Tabs              []*Tab // Open tabs, in strip order
 	NavBar            NavBar
-	History           []string             // Browser history
-	HistoryPos        int                  // Current position in history
-	FormState         *forms.FormState     // Form element state
-	captureScreenshot bool                 // Flag to capture screenshot on next draw
-	JSEngine          *spidergopher.Engine // SpiderGopher JavaScript engine
+	captureScreenshot bool     // Flag to capture screenshot on next draw
+	DevTools          DevTools // F12 devtools panel
+	Find              FindBar  // Ctrl+F find in page
+	SavePrompt        SavePrompt
+	Palette           CommandPalette // Ctrl+Shift+P command palette
+	Shortcuts         *ShortcutManager
+	Settings          *Settings
+	Network           *NetworkLog       // requests made by every tab
+	Cookies           http.CookieJar    // cookies shared by every tab
+	cookies           *cookieStore      // Cookies, saving the persistent ones
+	frame             int               // Update ticks, drives the loading spinners
+	windowTitle       string            // last title given to the OS window
+	cursor            customCursor      // cursor: url(...) image under the mouse
+	deviceImage       *ebiten.Image     // the page at its own scale in device mode
+	transformLayer    *layout.RenderBox // transformed box being painted to its layer
+	windowFullscreen  bool              // F11 made the OS window fullscreen
+	osFullscreen      bool              // fullscreen state last given to the OS window
+	hintedFullscreen  *dom.Node         // fullscreen element seen last tick
+	fullscreenSince   int               // frame the current element went fullscreen
+	embedded          bool              // a BrowserView's: one tab and no chrome
+	private           bool              // a private window: nothing kept on disk
+	sessionSavedAt    time.Time         // when the session was last autosaved
+	sessionSaved      string            // the session autosaved last, as JSON
 }
 
-// NewApp creates a new browser application
+// NewApp creates a new browser application with a single tab
 func NewApp() *App {
-	return &App{
-		URL:        "https://example.com",
-		History:    []string{},
-		HistoryPos: -1,
-		FormState:  forms.NewFormState(),
-	}
-}
-
-// Navigate navigates to a URL and adds it to history
-func (a *App) Navigate(urlStr string) {
-	// Truncate forward history if we were in the middle
-	if a.HistoryPos < len(a.History)-1 {
-		a.History = a.History[:a.HistoryPos+1]
-	}
-	// Add to history
-	a.History = append(a.History, urlStr)
-	a.HistoryPos = len(a.History) - 1
-	a.URL = urlStr
-	a.LoadFromURL(urlStr)
-}
-
-// LoadContent parses and renders HTML content
-func (a *App) LoadContent(rawHTML string) {
-	// Parse HTML into DOM
-	a.DOMRoot = dom.ParseHTML(rawHTML)
-
-	// Extract <style> blocks
-	a.Stylesheets = css.ExtractStylesheets(a.DOMRoot)
-
-	// Fetch external stylesheets from <link rel="stylesheet">
-	externalCSS := css.FetchExternalStylesheets(a.DOMRoot, render.CurrentBaseURL)
-	if len(externalCSS) > 0 {
-		a.Stylesheets = append(a.Stylesheets, externalCSS...)
-	}
-
-	// Apply CSS to DOM tree
-	css.ApplyStylesToTree(a.DOMRoot, a.Stylesheets)
-
-	// Build render tree with computed styles
-	a.RenderTree = layout.BuildRenderTree(a.DOMRoot, WindowWidth-(Padding*2))
-
-	// Initialize SpiderGopher and connect to DOM
-	a.initJSEngine()
+	return newApp(false)
 }
 
-// LoadFromURL fetches and loads content from a URL
-func (a *App) LoadFromURL(urlStr string) {
-	// Handle file:// protocol for local files
-	if strings.HasPrefix(urlStr, "file://") {
-		path := strings.TrimPrefix(urlStr, "file://")
-		a.LoadFromFile(path)
-		return
+// newApp creates the application of a normal or a private window
+func newApp(private bool) *App {
+	settings, err := LoadSettings(SettingsPath())
+	if err != nil {
+		logging.App.Error("loading settings", "err", err)
 	}
-
-	// Handle relative paths (no protocol) as local files
-	if !strings.HasPrefix(urlStr, "http://") && !strings.HasPrefix(urlStr, "https://") {
-		// Check if it's a local file path
-		if _, err := os.Stat(urlStr); err == nil {
-			a.LoadFromFile(urlStr)
-			return
-		}
-		// Otherwise assume https
-		urlStr = "https://" + urlStr
+	if err := logging.Configure(settings.Log); err != nil {
+		logging.App.Error("reading log setting", "err", err)
 	}
-
-	a.IsLoading = true
-	render.CurrentBaseURL = urlStr
-	go func() {
-		resp, err := http.Get(urlStr)
-		if err != nil {
-			a.ErrorMsg = err.Error()
-			a.IsLoading = false
-			return
-		}
-		defer resp.Body.Close()
-		body, _ := io.ReadAll(resp.Body)
-		a.LoadContent(string(body))
-		a.IsLoading = false
-	}()
+	return buildApp(settings, private, false)
 }
 
-// LoadFromFile loads HTML from a local file
-func (a *App) LoadFromFile(path string) {
-	content, err := os.ReadFile(path)
+// buildApp creates an application with settings and the profile's
+// blocklist, user stylesheet, shortcuts and, for a normal window, cookies
+// and localStorage. A private or embedded one, a BrowserView's, keeps its
+// cookies and localStorage in memory.
+func buildApp(settings *Settings, private, embedded bool) *App {
+	blocklist, err := LoadBlocklist(BlocklistPath())
 	if err != nil {
-		a.ErrorMsg = "File not found: " + err.Error()
-		return
+		logging.App.Error("loading blocklist", "err", err)
+	}
+	if blocklist.Len() > 0 {
+		logging.Net.Info("blocking domains", "count", blocklist.Len(), "path", BlocklistPath())
 	}
-	a.LoadContent(string(content))
+	if err := LoadUserStylesheet(UserStylesheetPath()); err != nil {
+		logging.App.Error("loading user stylesheet", "err", err)
+	}
+	network, cookies, storage := NewNetworkLog(), newCookieStore(), webapi.NewMemoryLocalStore()
+	if !private && !embedded {
+		if err := cookies.Load(CookiesPath()); err != nil {
+			logging.App.Error("loading cookies", "err", err)
+		}
+		storage = webapi.NewLocalStore(LocalStoragePath())
+	}
+
+	tab := newTab(settings, newBrowserState(settings, NewClient(blocklist, network, cookies), storage))
+	a := &App{
+		Tab:       tab,
+		Tabs:      []*Tab{tab},
+		Shortcuts: NewShortcutManager(),
+		Settings:  settings,
+		Network:   network,
+		Cookies:   cookies,
+		cookies:   cookies,
+		embedded:  embedded,
+		private:   private,
+	}
+	if err := a.Shortcuts.LoadConfig(ShortcutConfigPath()); err != nil {
+		logging.App.Error("loading shortcuts", "err", err)
+	}
+	a.registerShortcuts()
+	return a
 }
 
 // Update handles input and updates state
 func (a *App) Update() error {
+	a.frame++
+	a.syncWindowTitle()
+	a.syncFullscreen()
+	a.autosaveSession()
+
+	// Browser shortcuts see keystrokes before the find bar, URL bar and page
+	a.askDownloads()
+	textFocused := a.NavBar.IsEditing || a.FormState.FocusedID != "" || a.Find.Visible || a.SavePrompt.Visible || a.Palette.Visible
+	keyboardHandled := a.Shortcuts.Dispatch(textFocused)
+	if !keyboardHandled {
+		keyboardHandled = a.handleSavePromptInput()
+	}
+	if !keyboardHandled {
+		keyboardHandled = a.handlePaletteInput()
+	}
+	if !keyboardHandled {
+		keyboardHandled = a.handleFullscreenKeys()
+	}
+	if !keyboardHandled {
+		keyboardHandled = a.handleDialogKeys()
+	}
+	if !keyboardHandled {
+		keyboardHandled = a.handleFindInput()
+	}
 
 	_, dy := ebiten.Wheel()
-	a.ScrollY += dy * 30
-	if a.ScrollY > 0 {
-		a.ScrollY = 0
+	if wx, _ := ebiten.CursorPosition(); a.devToolsContains(wx) {
+		a.DevTools.ScrollY += dy * 30
+		dy = 0
+	}
+	if dy != 0 {
+		if f, _, _ := a.screenFrameAt(ebiten.CursorPosition()); f != nil {
+			f.tab.wheelScroll(dy)
+		} else {
+			a.wheelScroll(dy)
+		}
+	}
+	a.stepScroll()
+	a.stepFrames()
+	for _, t := range a.Tabs {
+		t.stepLayout()
+		t.stepMutations()
+		t.stepMedia()
+		t.stepRefresh()
+		t.takeScriptErrors()
+		t.stepPageLoad()
+		t.stepViewport(t == a.Tab && ebiten.IsFocused())
 	}
 
 	// Update form state cursor blink
 	a.FormState.CursorBlink++
 
-	// Handle mouse clicks
-	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+	// Middle-click opens links in a background tab
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonMiddle) && a.fullscreenTree == nil {
 		mx, my := ebiten.CursorPosition()
-
-		// First check nav bar
-		a.NavBar.HandleClick(a, mx, my)
-
-		// Then check content area
-		if my > int(NavBarHeight) && a.RenderTree != nil {
-			clickX := float64(mx) - Padding
-			clickY := float64(my) - ContentTop - a.ScrollY
-
-			// First try to handle form element clicks
-			if handled := a.handleFormClick(a.RenderTree, clickX, clickY); handled {
-				// Form element handled the click
-			} else {
-				// Check for link clicks
-				clickedURL := a.findClickedLink(a.RenderTree, clickX, clickY)
-				if clickedURL != "" {
-					if strings.HasPrefix(clickedURL, "#") {
-						// Anchor link
-					} else if strings.HasPrefix(clickedURL, "http") {
-						a.Navigate(clickedURL)
-					} else if strings.HasPrefix(clickedURL, "/") {
-						if base, err := url.Parse(a.URL); err == nil {
-							rel, _ := url.Parse(clickedURL)
-							fullURL := base.ResolveReference(rel).String()
-							a.Navigate(fullURL)
-						}
-					} else {
-						if base, err := url.Parse(a.URL); err == nil {
-							rel, _ := url.Parse(clickedURL)
-							fullURL := base.ResolveReference(rel).String()
-							a.Navigate(fullURL)
-						}
-					}
-				} else {
-					// Click outside form elements - clear focus
-					a.FormState.ClearFocus()
-				}
+		if pointX, pointY := a.pagePoint(mx, my); my > int(ChromeHeight) && a.RenderTree != nil {
+			if link := a.findLinkBox(a.RenderTree, pointX, pointY); link != nil {
+				a.OpenInBackgroundTab(a.resolveLink(link.LinkURL))
 			}
+		} else if my > int(NavBarHeight) {
+			a.handleTabStripMiddleClick(mx)
 		}
 	}
 
-	// Handle keyboard input for focused form elements
-	if a.FormState.FocusedID != "" && !a.NavBar.IsEditing {
-		runes := ebiten.AppendInputChars(nil)
-		var keys []ebiten.Key
-		if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) {
-			keys = append(keys, ebiten.KeyBackspace)
-		}
-		if inpututil.IsKeyJustPressed(ebiten.KeyDelete) {
-			keys = append(keys, ebiten.KeyDelete)
-		}
-		if inpututil.IsKeyJustPressed(ebiten.KeyLeft) {
-			keys = append(keys, ebiten.KeyLeft)
-		}
-		if inpututil.IsKeyJustPressed(ebiten.KeyRight) {
-			keys = append(keys, ebiten.KeyRight)
-		}
-		if inpututil.IsKeyJustPressed(ebiten.KeyHome) {
-			keys = append(keys, ebiten.KeyHome)
-		}
-		if inpututil.IsKeyJustPressed(ebiten.KeyEnd) {
-			keys = append(keys, ebiten.KeyEnd)
-		}
-		if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
-			keys = append(keys, ebiten.KeyEnter)
+	// Handle mouse clicks; a fullscreen element covers the chrome and page
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) && a.fullscreenTree != nil {
+		a.handleFullscreenClick(ebiten.CursorPosition())
+	} else if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		mx, my := ebiten.CursorPosition()
+
+		// The URL bar dropdown sits over the tab strip and page
+		picked := a.handlePaletteClick(mx, my) || a.NavBar.handleSuggestionClick(a, mx, my) || a.NavBar.securityPanelContains(a, mx, my)
+
+		// First check nav bar and tab strip
+		if !picked {
+			a.NavBar.HandleClick(a, mx, my)
 		}
-		if inpututil.IsKeyJustPressed(ebiten.KeyTab) {
-			keys = append(keys, ebiten.KeyTab)
+		if !picked && my > int(NavBarHeight) && my <= int(ChromeHeight) {
+			a.handleTabStripClick(mx)
 		}
 
-		if len(runes) > 0 || len(keys) > 0 {
-			a.handleFormInput(runes, keys)
+		// Then check content area; a modal dialog takes every click on it, and
+		// a frameset page hands the click to a frame
+		if !picked && my > int(ChromeHeight) && a.dialogTree != nil {
+			a.handleDialogClick(mx, my)
+		} else if !picked && my > int(ChromeHeight) && len(a.frames) > 0 {
+			a.handleFrameClick(float64(mx), float64(my)-ChromeHeight)
+		} else if !picked && my > int(ChromeHeight) && a.RenderTree != nil {
+			a.activate()
+			a.handlePageClick(a.pagePoint(mx, my))
 		}
 	}
+	if a.columnDrag != nil {
+		x, _ := a.pagePoint(ebiten.CursorPosition())
+		a.updateColumnDrag(x)
+	}
+
+	// Keys the browser didn't take go to the page
+	if !keyboardHandled && !a.NavBar.IsEditing {
+		a.handlePageKeys()
+	}
+
+	// Popups the page's click and key handlers opened
+	a.openPopups()
 
 	// URL bar hover detection
 	mx, my := ebiten.CursorPosition()
+	a.NavBar.hoverSuggestion(a, mx, my)
 	a.NavBar.IsHovering = float32(my) >= a.NavBar.URLBarY &&
 		float32(my) <= a.NavBar.URLBarY+URLBarHeight &&
 		float32(mx) >= a.NavBar.URLBarX &&
@@ -289,54 +308,60 @@ func (a *App) Update() error {
 	isOverButton := float32(my) >= btnY && float32(my) <= btnY+btnSize &&
 		float32(mx) >= btnStartX && float32(mx) <= btnStartX+(btnSize+btnSpacing)*3
 
+	hideCursor := false
 	if isOverButton {
 		ebiten.SetCursorShape(ebiten.CursorShapePointer)
-	} else if my > int(NavBarHeight) && a.RenderTree != nil {
-		hoveredURL := a.findClickedLink(a.RenderTree, float64(mx)-Padding, float64(my)-ContentTop-a.ScrollY)
-		if hoveredURL != "" {
-			ebiten.SetCursorShape(ebiten.CursorShapePointer)
-		} else {
-			ebiten.SetCursorShape(ebiten.CursorShapeDefault)
-		}
+	} else if my > int(ChromeHeight) && a.RenderTree != nil && !a.devToolsContains(mx) {
+		var shape ebiten.CursorShapeType
+		shape, hideCursor = a.pageCursor(mx, my)
+		ebiten.SetCursorShape(shape)
 	} else if a.NavBar.IsHovering {
 		ebiten.SetCursorShape(ebiten.CursorShapeText)
 	} else {
 		ebiten.SetCursorShape(ebiten.CursorShapeDefault)
 	}
+	a.updateCustomCursor(mx, my, hideCursor)
 
 	// Handle URL bar input
-	a.NavBar.HandleInput(a)
-
-	// Reload with R key (only when not editing URL or form)
-	if !a.NavBar.IsEditing && a.FormState.FocusedID == "" && inpututil.IsKeyJustPressed(ebiten.KeyR) {
-		if strings.HasPrefix(a.URL, "http") {
-			a.LoadFromURL(a.URL)
-		}
+	if !keyboardHandled {
+		a.NavBar.HandleInput(a)
 	}
 	return nil
 }
 
 // findClickedLink recursively finds a link at the given coordinates
 func (a *App) findClickedLink(box *layout.RenderBox, x, y float64) string {
+	if link := a.findLinkBox(box, x, y); link != nil {
+		return link.LinkURL
+	}
+	return ""
+}
+
+// findLinkBox returns the link text box at the given coordinates
+func (a *App) findLinkBox(box *layout.RenderBox, x, y float64) *layout.RenderBox {
 	if box == nil {
-		return ""
+		return nil
+	}
+	x, y, ok := box.Untransform(x, y)
+	if !ok {
+		return nil
 	}
 
 	// Check if click is within this box and it's a link
 	if box.IsLink && box.LinkURL != "" {
 		if x >= box.X && x <= box.X+box.W && y >= box.Y && y <= box.Y+box.H {
-			return box.LinkURL
+			return box
 		}
 	}
 
 	// Check children
 	for _, child := range box.Children {
-		if url := a.findClickedLink(child, x, y); url != "" {
-			return url
+		if link := a.findLinkBox(child, x, y); link != nil {
+			return link
 		}
 	}
 
-	return ""
+	return nil
 }
 
 // handleFormClick recursively finds and handles form element clicks
@@ -344,9 +369,13 @@ func (a *App) handleFormClick(box *layout.RenderBox, x, y float64) bool {
 	if box == nil {
 		return false
 	}
+	x, y, ok := box.Untransform(x, y)
+	if !ok {
+		return false
+	}
 
 	// Check if click is within this box
-	if box.Node != nil && x >= box.X && x <= box.X+box.W && y >= box.Y && y <= box.Y+box.H {
+	if box.Node != nil && x >= box.X && x <= box.X+box.W && y >= box.Y && y <= box.Y+box.H && !isDisabledControl(box.Node) {
 		// Dispatch click event to SpiderGopher listeners
 		a.dispatchJSClickEvent(box.Node)
 	}
@@ -365,7 +394,12 @@ func (a *App) handleFormClick(box *layout.RenderBox, x, y float64) bool {
 		}
 
 		if x >= box.X && x <= box.X+box.W && y >= box.Y && y <= box.Y+hitH {
+			// A disabled control swallows the click without reacting
+			if forms.IsDisabled(box.Node) {
+				return true
+			}
 			if handler := forms.GetHandler(box.Node.Tag); handler != nil {
+				a.changes++
 				return handler.HandleClick(box, box.Node, x, y, a.FormState)
 			}
 		}
@@ -392,23 +426,83 @@ func (a *App) countSelectOptions(node *dom.Node) int {
 	return count
 }
 
+// handlePageClick handles a left click at a page point: form controls,
+// media, <details> summaries, links and table column borders, in that order
+func (a *App) handlePageClick(clickX, clickY float64) {
+	if a.handleFormClick(a.RenderTree, clickX, clickY) {
+		// Form element handled the click
+	} else if a.handleMediaClick(a.RenderTree, clickX, clickY) {
+		// Media element played, paused or seeked
+	} else if a.handleSummaryClick(a.RenderTree, clickX, clickY) {
+		// A <details> opened or closed
+	} else if link := a.findLinkBox(a.RenderTree, clickX, clickY); link != nil {
+		// Ctrl/Cmd+click and target=_blank open a background tab
+		newTab := ebiten.IsKeyPressed(ebiten.KeyControl) || ebiten.IsKeyPressed(ebiten.KeyMeta)
+		if link.LinkNode != nil && strings.EqualFold(link.LinkNode.GetAttr("target"), "_blank") {
+			newTab = true
+		}
+		if newTab && !strings.HasPrefix(link.LinkURL, "#") {
+			a.OpenInBackgroundTab(a.resolveLink(link.LinkURL))
+		} else {
+			a.followLink(link.LinkURL)
+		}
+	} else if !a.handleTableMouseDown(clickX, clickY) {
+		// Click outside form elements - clear focus
+		a.FormState.ClearFocus()
+	}
+}
+
+// handlePageKeys sends this frame's key presses to the page: any key but
+// Escape is user activation, Tab/Shift+Tab move focus, Enter/Space activate
+// the focused element, and the rest is typed into a focused form field
+func (a *App) handlePageKeys() {
+	for _, key := range inpututil.AppendJustPressedKeys(nil) {
+		if key != ebiten.KeyEscape {
+			a.activate()
+			break
+		}
+	}
+	if a.Document != nil && a.handleFocusKeys() {
+		return
+	}
+	if a.FormState.FocusedID == "" {
+		return
+	}
+
+	runes := ebiten.AppendInputChars(nil)
+	var keys []ebiten.Key
+	for _, key := range []ebiten.Key{
+		ebiten.KeyBackspace, ebiten.KeyDelete, ebiten.KeyLeft, ebiten.KeyRight,
+		ebiten.KeyHome, ebiten.KeyEnd, ebiten.KeyEnter, ebiten.KeyA,
+	} {
+		if inpututil.IsKeyJustPressed(key) {
+			keys = append(keys, key)
+		}
+	}
+	if len(runes) > 0 || len(keys) > 0 {
+		a.handleFormInput(runes, keys)
+	}
+}
+
 // handleFormInput sends keyboard input to the focused form element
 func (a *App) handleFormInput(runes []rune, keys []ebiten.Key) {
 	if a.FormState.FocusedID == "" {
 		return
 	}
 
-	// Handle Tab key for navigation
-	for _, key := range keys {
-		if key == ebiten.KeyTab {
-			a.focusNextElement()
-			return
+	// Ctrl/Cmd+A selects the whole field
+	if ebiten.IsKeyPressed(ebiten.KeyControl) || ebiten.IsKeyPressed(ebiten.KeyMeta) {
+		for _, key := range keys {
+			if key == ebiten.KeyA {
+				a.FormState.SelectAll()
+				return
+			}
 		}
 	}
 
 	// Find the focused element and its handler
-	focusedNode := a.findNodeByID(a.DOMRoot, a.FormState.FocusedID)
-	if focusedNode == nil {
+	focusedNode := a.findNodeByID(a.root(), a.FormState.FocusedID)
+	if focusedNode == nil || forms.IsDisabled(focusedNode) {
 		return
 	}
 
@@ -417,66 +511,256 @@ func (a *App) handleFormInput(runes []rune, keys []ebiten.Key) {
 	}
 }
 
-// focusNextElement moves focus to the next focusable form element
-func (a *App) focusNextElement() {
-	// Collect all focusable elements
-	var focusableIDs []string
-	a.collectFocusableIDs(a.DOMRoot, &focusableIDs)
+// followLink navigates to an href, resolving it against the current page
+func (t *Tab) followLink(href string) {
+	if strings.HasPrefix(href, "#") {
+		t.scrollToFragment(href[1:])
+		return
+	}
+	t.Navigate(t.resolveLink(href))
+}
+
+// resolveLink makes an href absolute against the page's base URL, which a
+// <base href> may set
+func (t *Tab) resolveLink(href string) string {
+	if t.Document != nil && t.Document.BaseURL != "" {
+		return t.Document.ResolveURL(href)
+	}
+	if strings.HasPrefix(href, "http") {
+		return href
+	}
+	base, err := url.Parse(t.URL)
+	if err != nil {
+		return href
+	}
+	rel, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return base.ResolveReference(rel).String()
+}
+
+// handleFocusKeys handles Tab/Shift+Tab traversal and Enter/Space activation.
+// It returns true when the key press was consumed.
+func (a *App) handleFocusKeys() bool {
+	ctrl := ebiten.IsKeyPressed(ebiten.KeyControl) || ebiten.IsKeyPressed(ebiten.KeyMeta)
+	if inpututil.IsKeyJustPressed(ebiten.KeyTab) && !ctrl {
+		a.focusNextElement(ebiten.IsKeyPressed(ebiten.KeyShift))
+		return true
+	}
+
+	enter := inpututil.IsKeyJustPressed(ebiten.KeyEnter)
+	space := inpututil.IsKeyJustPressed(ebiten.KeySpace)
+	if (enter || space) && a.FormState.FocusedID != "" {
+		return a.activateFocusedElement(enter)
+	}
+	return false
+}
+
+// activateFocusedElement runs the default action of the focused element as if
+// it had been clicked. Links react to Enter only, checkboxes and radios to
+// Space only; text fields keep both keys for typing.
+func (a *App) activateFocusedElement(enter bool) bool {
+	node := a.findNodeByID(a.root(), a.FormState.FocusedID)
+	if node == nil {
+		return false
+	}
+
+	switch node.Tag {
+	case "a":
+		if !enter {
+			return false
+		}
+		href := node.GetAttr("href")
+		a.dispatchJSClickEvent(node)
+		if href != "" {
+			a.followLink(href)
+		}
+		return true
+	case "button":
+		a.dispatchJSClickEvent(node)
+		a.clickFormElement(node)
+		return true
+	case "input":
+		switch strings.ToLower(node.GetAttr("type")) {
+		case "submit", "button", "reset", "image":
+		case "checkbox", "radio":
+			if enter {
+				return false
+			}
+		default:
+			return false
+		}
+		a.dispatchJSClickEvent(node)
+		a.clickFormElement(node)
+		return true
+	case "select", "textarea":
+		return false
+	}
+
+	// Any other element made focusable with tabindex
+	a.dispatchJSClickEvent(node)
+	return true
+}
+
+// isDisabledControl reports whether node is a form control that is disabled
+func isDisabledControl(node *dom.Node) bool {
+	return forms.IsInteractive(node.Tag) && forms.IsDisabled(node)
+}
+
+// clickFormElement forwards a synthetic click to the element's tag handler
+func (a *App) clickFormElement(node *dom.Node) {
+	if forms.IsDisabled(node) {
+		return
+	}
+	if handler := forms.GetHandler(node.Tag); handler != nil {
+		a.changes++
+		handler.HandleClick(&layout.RenderBox{Node: node}, node, 0, 0, a.FormState)
+	}
+}
 
-	if len(focusableIDs) == 0 {
+// focusNextElement moves focus to the next (or, with reverse, previous)
+// element in tab order, wrapping around at either end
+func (a *App) focusNextElement(reverse bool) {
+	focusable := a.focusOrder()
+	if len(focusable) == 0 {
 		return
 	}
 
 	// Find current position
 	currentIdx := -1
-	for i, id := range focusableIDs {
-		if id == a.FormState.FocusedID {
+	for i, node := range focusable {
+		if forms.MatchesElementID(node, a.FormState.FocusedID) {
 			currentIdx = i
 			break
 		}
 	}
 
-	// Move to next element (wrap around)
-	nextIdx := (currentIdx + 1) % len(focusableIDs)
-	a.FormState.FocusedID = focusableIDs[nextIdx]
-	a.FormState.CursorPos = len(a.FormState.Values[a.FormState.FocusedID])
+	var nextIdx int
+	if reverse {
+		if currentIdx <= 0 {
+			nextIdx = len(focusable) - 1
+		} else {
+			nextIdx = currentIdx - 1
+		}
+	} else {
+		nextIdx = (currentIdx + 1) % len(focusable)
+	}
+
+	next := focusable[nextIdx]
+	a.FormState.SetFocus(forms.GetElementID(next))
+	a.scrollIntoView(next)
+}
+
+// focusOrder returns the focusable elements in tab order: positive tabindex
+// values first in ascending order, then the rest in document order
+func (a *App) focusOrder() []*dom.Node {
+	var nodes []*dom.Node
+	var indexes []int
+	a.collectFocusable(a.root(), &nodes, &indexes)
+
+	var ordered []*dom.Node
+	for pass := 0; pass < 2; pass++ {
+		var group []int
+		for i, idx := range indexes {
+			if (pass == 0) == (idx > 0) {
+				group = append(group, i)
+			}
+		}
+		sort.SliceStable(group, func(x, y int) bool {
+			return indexes[group[x]] < indexes[group[y]]
+		})
+		for _, i := range group {
+			ordered = append(ordered, nodes[i])
+		}
+	}
+	return ordered
 }
 
-// collectFocusableIDs collects IDs of all focusable form elements
-func (a *App) collectFocusableIDs(node *dom.Node, ids *[]string) {
+// collectFocusable collects focusable elements with their tabindex: form
+// controls, links with an href, and anything with tabindex >= 0
+func (a *App) collectFocusable(node *dom.Node, nodes *[]*dom.Node, indexes *[]int) {
 	if node == nil {
 		return
 	}
 
-	// Check if this is a focusable form element
-	if forms.IsInteractive(node.Tag) {
-		if handler := forms.GetHandler(node.Tag); handler != nil && handler.IsFocusable() {
-			id := forms.GetElementID(node)
-			*ids = append(*ids, id)
+	if node.Type == dom.NodeElement && !isHiddenNode(node) {
+		focusable := false
+		if forms.IsInteractive(node.Tag) {
+			if handler := forms.GetHandler(node.Tag); handler != nil && handler.IsFocusable() {
+				focusable = !forms.IsDisabled(node) && node.GetAttr("type") != "hidden"
+			}
+		} else if node.Tag == "a" && node.HasAttr("href") {
+			focusable = true
+		}
+
+		tabIndex := 0
+		if raw := node.GetAttr("tabindex"); raw != "" {
+			if n, err := strconv.Atoi(strings.TrimSpace(raw)); err == nil {
+				tabIndex = n
+				focusable = n >= 0
+			}
+		}
+
+		if focusable {
+			*nodes = append(*nodes, node)
+			*indexes = append(*indexes, tabIndex)
 		}
 	}
 
 	// Check children
 	for _, child := range node.Children {
-		a.collectFocusableIDs(child, ids)
+		a.collectFocusable(child, nodes, indexes)
+	}
+}
+
+// isHiddenNode reports whether an element is excluded from rendering
+func isHiddenNode(node *dom.Node) bool {
+	if cs, ok := node.ComputedStyle.(*css.ComputedStyle); ok && cs.Display == "none" {
+		return true
+	}
+	return false
+}
+
+// scrollIntoView scrolls the page so that node's first box is visible
+func (a *App) scrollIntoView(node *dom.Node) {
+	box := findBoxForNode(a.RenderTree, node)
+	if box == nil {
+		return
+	}
+	viewH := float64(WindowHeight) - ContentTop
+	top := box.Y + a.ScrollY
+	if top < 0 {
+		a.scrollTo(-box.Y)
+	} else if top+box.H > viewH {
+		a.scrollTo(viewH - box.Y - box.H)
+	}
+}
+
+// findBoxForNode returns the first render box drawn for node (including link text)
+func findBoxForNode(box *layout.RenderBox, node *dom.Node) *layout.RenderBox {
+	if box == nil {
+		return nil
+	}
+	if box.Node == node || box.LinkNode == node {
+		return box
+	}
+	for _, child := range box.Children {
+		if found := findBoxForNode(child, node); found != nil {
+			return found
+		}
 	}
+	return nil
 }
 
-// findNodeByID finds a DOM node by its id or name attribute
+// findNodeByID finds a DOM node by the ID forms.GetElementID gave it
 func (a *App) findNodeByID(node *dom.Node, id string) *dom.Node {
 	if node == nil {
 		return nil
 	}
 
 	// Check this node
-	nodeID := node.GetAttr("id")
-	if nodeID == "" {
-		nodeID = node.GetAttr("name")
-	}
-	if nodeID == "" {
-		nodeID = node.Tag
-	}
-	if nodeID == id {
+	if node.Type == dom.NodeElement && forms.MatchesElementID(node, id) {
 		return node
 	}
 
@@ -490,37 +774,115 @@ func (a *App) findNodeByID(node *dom.Node, id string) *dom.Node {
 	return nil
 }
 
+// drawFocusRing outlines the box if it belongs to the focused element.
+// Text fields and selects show focus through their own border instead.
+func (a *App) drawFocusRing(screen *ebiten.Image, box *layout.RenderBox, x, y float64) {
+	id := a.FormState.FocusedID
+	if id == "" {
+		return
+	}
+	node := box.LinkNode
+	if node == nil {
+		node = box.Node
+	}
+	if node == nil || node.Type != dom.NodeElement || !forms.MatchesElementID(node, id) {
+		return
+	}
+	switch node.Tag {
+	case "textarea", "select":
+		return
+	case "input":
+		switch strings.ToLower(node.GetAttr("type")) {
+		case "checkbox", "radio", "submit", "button", "reset", "image":
+		default:
+			return
+		}
+	}
+
+	w, h := box.W, box.H
+	if w <= 0 || h <= 0 {
+		return
+	}
+	vector.StrokeRect(screen, float32(x-2), float32(y-2), float32(w+4), float32(h+4), 2, ColorFocusRing, false)
+}
+
+// syncWindowTitle shows the active tab's title in the OS window title bar
+func (a *App) syncWindowTitle() {
+	title := "GoBrowser"
+	if profile != "" {
+		title += " [" + profile + "]"
+	}
+	if a.private {
+		title += " (Private)"
+	}
+	if a.PageTitle != "" {
+		title += ": " + a.PageTitle
+	}
+	if title != a.windowTitle {
+		ebiten.SetWindowTitle(title)
+		a.windowTitle = title
+	}
+}
+
 // Draw renders the browser window
 func (a *App) Draw(screen *ebiten.Image) {
+	defer perf.Since(perf.StagePaint, time.Now())
+	if a.fullscreenTree != nil {
+		a.drawFullscreen(screen)
+		a.drawCustomCursor(screen)
+		return
+	}
+
 	// Get page background from body/html computed style
 	pageBackground := ColorBackground
-	if a.DOMRoot != nil {
+	if a.Document != nil {
 		pageBackground = a.getPageBackground()
 	}
 
-	// Check for gradient background on body
-	if gradient := a.getBodyGradient(); gradient != nil && len(gradient.Stops) >= 2 {
-		// Convert CSS gradient stops to render stops
-		stops := make([]render.GradientStop, len(gradient.Stops))
-		for i, s := range gradient.Stops {
-			stops[i] = render.GradientStop{
-				R:        float64(s.Color.R),
-				G:        float64(s.Color.G),
-				B:        float64(s.Color.B),
-				A:        float64(s.Color.A),
-				Position: s.Position,
-			}
-		}
-		render.DrawLinearGradient(screen, 0, float32(NavBarHeight), float32(WindowWidth), float32(WindowHeight-NavBarHeight), gradient.Angle, stops)
-	} else {
-		screen.Fill(pageBackground)
+	screen.Fill(pageBackground)
+	// A gradient on body fills the viewport
+	if cs := a.getBodyStyle(); cs != nil {
+		drawBackgroundGradient(screen, cs, 0, ChromeHeight, WindowWidth, WindowHeight-ChromeHeight, 1)
+	}
+	a.drawPageBackgroundImage(screen)
+
+	a.drawContent(screen)
+
+	if a.device == nil {
+		a.drawFindHighlights(screen)
+	}
+	a.drawFindBar(screen)
+	a.drawSavePrompt(screen)
+	a.drawErrorToast(screen)
+	a.drawDevTools(screen)
+
+	// Draw nav bar and tab strip on top
+	a.NavBar.Draw(screen, a)
+	a.drawTabStrip(screen)
+	a.NavBar.drawSuggestions(screen, a)
+	a.NavBar.drawSecurityPanel(screen, a)
+	a.drawPalette(screen)
+
+	// Capture screenshot if requested
+	if a.captureScreenshot {
+		a.saveScreenshot(screen)
+		a.captureScreenshot = false
 	}
 
-	// Draw content area
+	a.drawCustomCursor(screen)
+}
+
+// drawContent draws the content area: the page, or what stands for it
+func (a *App) drawContent(screen *ebiten.Image) {
+	defer a.recoverPage("painting")
 	if a.IsLoading {
 		render.DrawText(screen, "Loading...", Padding, ContentTop+30, FontSizeBody, ColorTextMuted)
 	} else if a.ErrorMsg != "" {
 		render.DrawText(screen, "Error: "+a.ErrorMsg, Padding, ContentTop+30, FontSizeBody, color.RGBA{255, 100, 100, 255})
+	} else if len(a.frames) > 0 {
+		a.drawFrames(screen, a.Tab, 0, ChromeHeight)
+	} else if a.device != nil && a.RenderTree != nil {
+		a.drawDevice(screen)
 	} else if a.RenderTree != nil {
 		a.renderNode(screen, a.RenderTree, Padding, ContentTop+a.ScrollY)
 
@@ -529,14 +891,8 @@ func (a *App) Draw(screen *ebiten.Image) {
 			a.renderSelectOverlay(screen, a.RenderTree, Padding, ContentTop+a.ScrollY)
 		}
 	}
-
-	// Draw nav bar on top
-	a.NavBar.Draw(screen, a)
-
-	// Capture screenshot if requested
-	if a.captureScreenshot {
-		a.saveScreenshot(screen)
-		a.captureScreenshot = false
+	if a.dialogTree != nil {
+		a.drawDialog(screen)
 	}
 }
 
@@ -545,16 +901,16 @@ func (a *App) saveScreenshot(screen *ebiten.Image) {
 	filename := fmt.Sprintf("screenshot_%s.png", time.Now().Format("20060102_150405"))
 	file, err := os.Create(filename)
 	if err != nil {
-		fmt.Println("Error creating screenshot:", err)
+		logging.App.Error("creating screenshot", "err", err)
 		return
 	}
 	defer file.Close()
 
 	if err := png.Encode(file, screen); err != nil {
-		fmt.Println("Error encoding screenshot:", err)
+		logging.App.Error("encoding screenshot", "err", err)
 		return
 	}
-	fmt.Println("Screenshot saved:", filename)
+	logging.App.Info("screenshot saved", "path", filename)
 }
 
 // renderSelectOverlay finds and renders the open select dropdown on top of other content
@@ -589,10 +945,10 @@ func (a *App) renderSelectOverlay(screen *ebiten.Image, box *layout.RenderBox, o
 }
 
 // getPageBackground extracts background color from body or html element
-func (a *App) getPageBackground() color.RGBA {
+func (t *Tab) getPageBackground() color.RGBA {
 	// Look for body first, then html
-	for _, child := range a.DOMRoot.Children {
-		bg := a.findBackgroundColor(child)
+	for _, node := range []*dom.Node{t.Document.Body, t.Document.DocumentElement} {
+		bg := t.findBackgroundColor(node)
 		if bg.A > 0 {
 			return bg
 		}
@@ -600,60 +956,214 @@ func (a *App) getPageBackground() color.RGBA {
 	return ColorBackground
 }
 
-// getBodyGradient returns the gradient from body element if present
-func (a *App) getBodyGradient() *css.Gradient {
-	if a.DOMRoot == nil {
+// getBodyStyle returns the computed style of the body element, if any
+func (a *App) getBodyStyle() *css.ComputedStyle {
+	if a.Document == nil || a.Document.Body == nil {
 		return nil
 	}
-	return a.findBodyGradient(a.DOMRoot)
+	cs, _ := a.Document.Body.ComputedStyle.(*css.ComputedStyle)
+	return cs
 }
 
-func (a *App) findBodyGradient(node *dom.Node) *css.Gradient {
+// findBackgroundColor returns the background color of body or html, if set
+func (t *Tab) findBackgroundColor(node *dom.Node) color.RGBA {
 	if node == nil {
-		return nil
+		return color.RGBA{}
+	}
+	if cs, ok := node.ComputedStyle.(*css.ComputedStyle); ok {
+		return cs.BackgroundColor
 	}
+	return color.RGBA{}
+}
 
-	if node.Tag == "body" {
-		if node.ComputedStyle != nil {
-			if cs, ok := node.ComputedStyle.(*css.ComputedStyle); ok {
-				return cs.BackgroundGradient
-			}
+// drawListMarker paints the bullet or number in front of a list item,
+// honouring list-style-type and ::marker colors
+func (a *App) drawListMarker(screen *ebiten.Image, box *layout.RenderBox, offsetX, absY float64) {
+	marker, markerColor, fontSize := listMarker(box.Node)
+	if marker == "" {
+		return
+	}
+	markerColor = render.Fade(markerColor, box.Opacity)
+	markerX := box.X + offsetX - 6 - render.MeasureText(marker, fontSize)
+	if line := firstTextLine(box); line != nil {
+		// The marker sits on the baseline of the item's first line
+		render.DrawTextAtBaseline(screen, marker, markerX, absY-box.Y+line.Y+line.Baseline, fontSize, markerColor)
+		return
+	}
+	render.DrawText(screen, marker, markerX, absY, fontSize, markerColor)
+}
+
+// listMarker returns the marker of a list item, its color and font size;
+// "" when list-style-type is none
+func listMarker(node *dom.Node) (string, color.RGBA, float64) {
+	cs, _ := node.ComputedStyle.(*css.ComputedStyle)
+
+	styleType := ""
+	if cs != nil {
+		styleType = cs.ListStyleType
+	}
+	if styleType == "" && node.Parent != nil {
+		if ps, ok := node.Parent.ComputedStyle.(*css.ComputedStyle); ok {
+			styleType = ps.ListStyleType
 		}
 	}
+	ordered := node.Parent != nil && node.Parent.Tag == "ol"
+	if styleType == "" {
+		styleType = "disc"
+		if ordered {
+			styleType = "decimal"
+		}
+	}
+	if styleType == "none" {
+		return "", color.RGBA{}, 0
+	}
 
-	// Search children
-	for _, child := range node.Children {
-		if g := a.findBodyGradient(child); g != nil {
-			return g
+	var marker string
+	switch styleType {
+	case "circle":
+		marker = "◦"
+	case "square":
+		marker = "▪"
+	case "decimal":
+		marker = fmt.Sprintf("%d.", listItemNumber(node))
+	default:
+		marker = "•"
+	}
+
+	markerColor := ColorText
+	fontSize := float64(FontSizeBody)
+	if cs != nil {
+		if cs.Color.A > 0 {
+			markerColor = cs.Color
+		}
+		if cs.FontSize > 0 {
+			fontSize = cs.FontSize
+		}
+		if ms := cs.PseudoStyle("marker"); ms != nil && ms.Color.A > 0 {
+			markerColor = ms.Color
 		}
 	}
-	return nil
+	return marker, markerColor, fontSize
 }
 
-func (a *App) findBackgroundColor(node *dom.Node) color.RGBA {
-	if node == nil {
-		return color.RGBA{}
+// drawFieldset draws a fieldset's border. The top edge runs through the
+// middle of the first <legend> and breaks around its text.
+func drawFieldset(screen *ebiten.Image, box *layout.RenderBox, offsetX, absY float64) {
+	cs, ok := box.Node.ComputedStyle.(*css.ComputedStyle)
+	if !ok {
+		return
+	}
+	x, w := box.X+offsetX, box.W
+	top, bottom := absY, absY+box.H
+	gapL, gapR := 0.0, 0.0
+	for _, child := range box.Children {
+		if child.Node == nil || child.Node.Tag != "legend" {
+			continue
+		}
+		if line := firstTextLine(child); line != nil {
+			top = absY - box.Y + child.Y + child.H/2
+			gapL = line.X + offsetX - 4
+			gapR = textRight(child) + offsetX + 4
+		}
+		break
 	}
 
-	if node.Tag == "body" || node.Tag == "html" {
-		if node.ComputedStyle != nil {
-			if cs, ok := node.ComputedStyle.(*css.ComputedStyle); ok {
-				if cs.BackgroundColor.A > 0 {
-					return cs.BackgroundColor
-				}
-			}
+	bc := render.Fade(cs.BorderColor, box.Opacity)
+	fill := func(x, y, w, h float64) {
+		if w > 0 && h > 0 {
+			vector.DrawFilledRect(screen, float32(x), float32(y), float32(w), float32(h), bc, false)
 		}
 	}
+	bt, br, bb, bl := cs.BorderTopWidth, cs.BorderRightWidth, cs.BorderBottomWidth, cs.BorderLeftWidth
+	if gapR > gapL {
+		fill(x, top, gapL-x, bt)
+		fill(gapR, top, x+w-gapR, bt)
+	} else {
+		fill(x, top, w, bt)
+	}
+	fill(x, bottom-bb, w, bb)
+	fill(x, top, bl, bottom-top)
+	fill(x+w-br, top, br, bottom-top)
+}
 
-	// Search children
-	for _, child := range node.Children {
-		bg := a.findBackgroundColor(child)
-		if bg.A > 0 {
-			return bg
+// drawBorders draws the sides of an element's border inside its border box
+// at opacity. The border takes the text color unless it has its own.
+func drawBorders(screen *ebiten.Image, cs *css.ComputedStyle, x, y, w, h, opacity float64) {
+	bc := cs.BorderColor
+	if bc.A == 0 {
+		bc = cs.Color
+	}
+	bc = render.Fade(bc, opacity)
+	fill := func(x, y, w, h float64) {
+		if w > 0 && h > 0 {
+			vector.DrawFilledRect(screen, float32(x), float32(y), float32(w), float32(h), bc, false)
 		}
 	}
+	fill(x, y, w, cs.BorderTopWidth)
+	fill(x, y+h-cs.BorderBottomWidth, w, cs.BorderBottomWidth)
+	fill(x, y, cs.BorderLeftWidth, h)
+	fill(x+w-cs.BorderRightWidth, y, cs.BorderRightWidth, h)
+}
 
-	return color.RGBA{}
+// alignedTextX returns where the text of box starts, placed by its
+// text-align in a page width wide drawn at offsetX
+func alignedTextX(box *layout.RenderBox, fontSize, offsetX, width float64) float64 {
+	switch box.TextAlign {
+	case "center":
+		// Estimate text width and center it
+		textWidth := float64(len(box.Text)) * fontSize * 0.55
+		return offsetX + (width-textWidth)/2
+	case "right":
+		textWidth := float64(len(box.Text)) * fontSize * 0.55
+		return offsetX + width - textWidth
+	}
+	return box.X + offsetX
+}
+
+// textRight returns the right edge of the text laid out inside box
+func textRight(box *layout.RenderBox) float64 {
+	right := box.X
+	if box.Text != "" {
+		right = box.X + box.W
+	}
+	for _, child := range box.Children {
+		right = max(right, textRight(child))
+	}
+	return right
+}
+
+// firstTextLine returns the first line of text laid out inside box
+func firstTextLine(box *layout.RenderBox) *layout.RenderBox {
+	if box.Text != "" {
+		return box
+	}
+	for _, child := range box.Children {
+		if line := firstTextLine(child); line != nil {
+			return line
+		}
+	}
+	return nil
+}
+
+// listItemNumber returns the ordinal of an <li> within its list, honouring
+// the parent's start attribute
+func listItemNumber(li *dom.Node) int {
+	n := 1
+	if li.Parent == nil {
+		return n
+	}
+	if start, err := strconv.Atoi(li.Parent.GetAttr("start")); err == nil {
+		n = start
+	}
+	for _, sib := range li.Parent.Children {
+		if sib == li {
+			break
+		}
+		if sib.Type == dom.NodeElement && sib.Tag == "li" {
+			n++
+		}
+	}
+	return n
 }
 
 // Layout returns the window size
@@ -662,13 +1172,33 @@ func (a *App) Layout(w, h int) (int, int) {
 }
 
 func (a *App) renderNode(screen *ebiten.Image, box *layout.RenderBox, offsetX, offsetY float64) {
-	// Handle position:fixed - ignore scroll offset
-	if box.IsFixed {
+	// Handle position:fixed - ignore scroll offset. Inside a transformed
+	// element fixed boxes move with it.
+	if box.IsFixed && a.transformLayer == nil {
 		offsetY = ContentTop // Fixed elements stay at top, ignore scroll
 	}
 
 	absY := box.Y + offsetY
 
+	// visibility: hidden leaves the box's space empty, though its children
+	// may be visible again; opacity: 0 leaves the whole subtree out
+	if box.Opacity <= 0 {
+		return
+	}
+	if box.Transform != nil && a.transformLayer != box {
+		a.drawTransformed(screen, box, offsetX, offsetY)
+		return
+	}
+	if box.Hidden {
+		for _, child := range box.Children {
+			a.renderNode(screen, child, offsetX, offsetY)
+		}
+		return
+	}
+	fade := func(c color.RGBA) color.RGBA { return render.Fade(c, box.Opacity) }
+
+	a.drawFocusRing(screen, box, box.X+offsetX, absY)
+
 	// Draw CSS background-color for any element with computed style
 	// Skip form elements - they have their own handlers
 	if box.Node != nil && box.Node.ComputedStyle != nil {
@@ -680,7 +1210,14 @@ func (a *App) renderNode(screen *ebiten.Image, box *layout.RenderBox, offsetX, o
 					vector.DrawFilledRect(screen,
 						float32(box.X+offsetX), float32(absY),
 						float32(box.W), float32(box.H),
-						cs.BackgroundColor, false)
+						fade(cs.BackgroundColor), false)
+				}
+				if tag != "body" && tag != "html" {
+					drawBackgroundGradient(screen, cs, box.X+offsetX, absY, box.W, box.H, box.Opacity)
+					drawBackgroundImage(screen, a.browser.images, cs, documentURL(box.Node), box.X+offsetX, absY, box.W, box.H, box.Opacity)
+				}
+				if tag != "fieldset" {
+					drawBorders(screen, cs, box.X+offsetX, absY, box.W, box.H, box.Opacity)
 				}
 			}
 		}
@@ -691,28 +1228,33 @@ func (a *App) renderNode(screen *ebiten.Image, box *layout.RenderBox, offsetX, o
 		switch box.Node.Tag {
 		case "table":
 			render.DrawRoundedRect(screen,
-				float32(offsetX), float32(absY),
-				float32(box.W), float32(box.H+20),
-				8, ColorSurface)
-		case "td":
+				float32(box.X+offsetX), float32(absY),
+				float32(box.W), float32(box.H),
+				8, fade(ColorSurface))
+		case "td", "th":
 			vector.DrawFilledRect(screen,
 				float32(box.X+offsetX), float32(absY),
 				1, float32(box.H),
-				ColorBorder, false)
+				fade(ColorBorder), false)
+			a.drawSortIndicator(screen, box, offsetX, absY)
 		case "tr":
 			rowColor := ColorTableRow1
 			if box.RowIndex%2 == 0 {
 				rowColor = ColorTableRow2
 			}
 			vector.DrawFilledRect(screen,
-				float32(offsetX), float32(absY),
-				float32(box.W), float32(box.H+8),
-				rowColor, false)
+				float32(box.X+offsetX), float32(absY),
+				float32(box.W), float32(box.H),
+				fade(rowColor), false)
 		case "hr":
 			vector.DrawFilledRect(screen,
 				float32(offsetX), float32(absY),
 				float32(box.W), 2,
-				ColorHR, false)
+				fade(ColorHR), false)
+		case "li":
+			a.drawListMarker(screen, box, offsetX, absY)
+		case "fieldset":
+			drawFieldset(screen, box, offsetX, absY)
 		case "input", "button", "select", "textarea":
 			// Render form elements using tag handlers
 			if handler := forms.GetHandler(box.Node.Tag); handler != nil {
@@ -725,6 +1267,13 @@ func (a *App) renderNode(screen *ebiten.Image, box *layout.RenderBox, offsetX, o
 					H:    box.H,
 				}
 				handler.Render(screen, tempBox, box.Node, a.FormState)
+				if forms.IsDisabled(box.Node) {
+					// Disabled controls are drawn washed out
+					vector.DrawFilledRect(screen,
+						float32(tempBox.X), float32(tempBox.Y),
+						float32(tempBox.W), float32(tempBox.H),
+						ColorDisabledVeil, false)
+				}
 				// Don't render children of form elements - handler does that
 				return
 			}
@@ -756,7 +1305,7 @@ func (a *App) renderNode(screen *ebiten.Image, box *layout.RenderBox, offsetX, o
 			vector.DrawFilledRect(screen,
 				float32(box.X+offsetX), float32(absY),
 				float32(box.W), float32(box.H),
-				*box.BgColor, false)
+				fade(*box.BgColor), false)
 		}
 
 		// Draw button background
@@ -768,24 +1317,20 @@ func (a *App) renderNode(screen *ebiten.Image, box *layout.RenderBox, offsetX, o
 			btnX := float32(box.X+offsetX) - btnPadX
 			btnY := float32(absY) - btnPadY + 4
 
-			render.DrawRoundedRect(screen, btnX, btnY, btnW, btnH, 6, ColorButtonPrimary)
+			render.DrawRoundedRect(screen, btnX, btnY, btnW, btnH, 6, fade(ColorButtonPrimary))
 			textColor = ColorButtonText
 		}
+		textColor = fade(textColor)
 
-		if absY > NavBarHeight-30 && absY < WindowHeight+30 {
-			// Calculate text X position based on text-align
-			textX := box.X + offsetX
+		if bounds := screen.Bounds(); absY > float64(bounds.Min.Y)-30 && absY < float64(bounds.Max.Y)+30 {
+			textX := alignedTextX(box, fontSize, offsetX, a.layoutWidth())
 
-			if box.TextAlign == "center" {
-				// Estimate text width and center it
-				textWidth := float64(len(box.Text)) * fontSize * 0.55
-				textX = offsetX + (WindowWidth-Padding*2-textWidth)/2
-			} else if box.TextAlign == "right" {
-				textWidth := float64(len(box.Text)) * fontSize * 0.55
-				textX = offsetX + WindowWidth - Padding*2 - textWidth
+			render.DrawSpacedTextAtBaseline(screen, box.Text, textX, absY+box.Baseline, fontSize, box.LetterSpacing, box.WordSpacing, box.Monospace, textColor)
+			decorationColor := textColor
+			if box.DecorationColor != nil {
+				decorationColor = fade(*box.DecorationColor)
 			}
-
-			render.DrawText(screen, box.Text, textX, absY+fontSize, fontSize, textColor)
+			render.DrawTextDecoration(screen, box.TextDecoration, box.DecorationStyle, textX, absY+box.Baseline, box.W, fontSize, box.DecorationWidth, decorationColor)
 		}
 	}
 
@@ -796,7 +1341,8 @@ func (a *App) renderNode(screen *ebiten.Image, box *layout.RenderBox, offsetX, o
 		imgW := float32(box.W)
 		imgH := float32(box.H)
 
-		img, loaded, failed := render.Cache.Get(box.ImageURL)
+		src := spidergopher.ResolveURL(box.ImageURL, documentBaseURL(box.Node))
+		img, loaded, failed := a.browser.images.Cache.Get(src)
 
 		if loaded && img != nil {
 			bounds := img.Bounds()
@@ -810,6 +1356,7 @@ func (a *App) renderNode(screen *ebiten.Image, box *layout.RenderBox, offsetX, o
 			op := &ebiten.DrawImageOptions{}
 			op.GeoM.Scale(scale, scale)
 			op.GeoM.Translate(float64(imgX), float64(imgY))
+			op.ColorScale.ScaleAlpha(float32(box.Opacity))
 			screen.DrawImage(img, op)
 		} else if failed {
 			vector.DrawFilledRect(screen, imgX, imgY, imgW, imgH, ColorImageBg, false)
@@ -817,7 +1364,33 @@ func (a *App) renderNode(screen *ebiten.Image, box *layout.RenderBox, offsetX, o
 		} else {
 			vector.DrawFilledRect(screen, imgX, imgY, imgW, imgH, ColorImageBg, false)
 			render.DrawTextCentered(screen, "◌", float64(imgX+imgW/2), float64(imgY+imgH/2+8), 24, ColorTextMuted)
-			render.LoadImageAsync(box.ImageURL, render.CurrentBaseURL)
+			if bounds := screen.Bounds(); imgY+imgH >= float32(bounds.Min.Y) && imgY <= float32(bounds.Max.Y) {
+				// On screen: ahead of everything queued
+				a.browser.images.RequestImage(src, documentURL(box.Node), 0)
+			}
+		}
+	}
+
+	// Draw audio and video elements with their controls. The element's own
+	// box holds the one laid out at the element's size.
+	if isMediaNode(box.Node) && box.Text == "" && len(box.Children) == 0 {
+		a.drawMedia(screen, box, box.X+offsetX, absY)
+	}
+
+	// Draw canvas bitmaps
+	if box.Node != nil && box.Node.Tag == "canvas" && box.Text == "" && len(box.Children) == 0 {
+		a.drawCanvas(screen, box, box.X+offsetX, absY)
+	}
+
+	// Draw <progress> and <meter> bars
+	if box.Node != nil && (box.Node.Tag == "progress" || box.Node.Tag == "meter") && box.Text == "" && len(box.Children) == 0 {
+		a.drawGauge(screen, box, box.X+offsetX, absY)
+	}
+
+	// Draw the disclosure triangle of a <details>' summary
+	if box.Node != nil && box.Node.Tag == "summary" && box.Text == "" && box.Node.Parent != nil && box.Node.Parent.Tag == "details" {
+		if cs := styleOf(box.Node); cs != nil && layout.DetailsSummary(box.Node.Parent) == box.Node {
+			drawSummaryMarker(screen, box, cs, box.X+offsetX, absY)
 		}
 	}
 
@@ -829,12 +1402,22 @@ func (a *App) renderNode(screen *ebiten.Image, box *layout.RenderBox, offsetX, o
 
 // HandleClick handles click on URL bar
 func (n *NavBar) HandleClick(app *App, mx, my int) {
+	// The padlock toggles the security panel; any other click closes it
+	wasOpen := n.securityOpen
+	n.securityOpen = false
+	if n.securityIconContains(mx, my) {
+		n.securityOpen = !wasOpen
+		return
+	}
+
 	if float32(mx) >= n.URLBarX && float32(mx) <= n.URLBarX+n.URLBarW &&
 		float32(my) >= n.URLBarY && float32(my) <= n.URLBarY+URLBarHeight {
 		if !n.IsEditing {
-			n.IsEditing = true
-			n.URLText = app.URL
-			n.CursorPos = len(n.URLText)
+			n.Focus(app)
+		} else {
+			// Click places the cursor (Shift+click extends); dragging selects
+			n.moveCursor(n.indexAt(float64(mx)), ebiten.IsKeyPressed(ebiten.KeyShift))
+			n.dragging = true
 		}
 	} else if float32(my) < NavBarHeight {
 		// Button positions matching Draw function
@@ -846,11 +1429,7 @@ func (n *NavBar) HandleClick(app *App, mx, my int) {
 		// Back button
 		if float32(mx) >= startX && float32(mx) <= startX+btnSize &&
 			float32(my) >= btnY && float32(my) <= btnY+btnSize {
-			if app.HistoryPos > 0 {
-				app.HistoryPos--
-				app.URL = app.History[app.HistoryPos]
-				app.LoadFromURL(app.URL)
-			}
+			app.GoBack()
 			return
 		}
 
@@ -858,11 +1437,7 @@ func (n *NavBar) HandleClick(app *App, mx, my int) {
 		fwdX := startX + btnSize + btnSpacing
 		if float32(mx) >= fwdX && float32(mx) <= fwdX+btnSize &&
 			float32(my) >= btnY && float32(my) <= btnY+btnSize {
-			if app.HistoryPos < len(app.History)-1 {
-				app.HistoryPos++
-				app.URL = app.History[app.HistoryPos]
-				app.LoadFromURL(app.URL)
-			}
+			app.GoForward()
 			return
 		}
 
@@ -870,7 +1445,7 @@ func (n *NavBar) HandleClick(app *App, mx, my int) {
 		refreshX := fwdX + btnSize + btnSpacing
 		if float32(mx) >= refreshX && float32(mx) <= refreshX+btnSize &&
 			float32(my) >= btnY && float32(my) <= btnY+btnSize {
-			app.LoadFromURL(app.URL)
+			app.Reload()
 			return
 		}
 
@@ -886,6 +1461,16 @@ func (n *NavBar) HandleClick(app *App, mx, my int) {
 	}
 }
 
+// Focus starts editing the URL bar with the current URL selected
+func (n *NavBar) Focus(app *App) {
+	app.Find.Close()
+	n.IsEditing = true
+	n.URLText = app.URL
+	n.ScrollX = 0
+	n.selectAll()
+	n.scrollToCursor()
+}
+
 // HandleInput handles keyboard input for URL bar
 func (n *NavBar) HandleInput(app *App) {
 	if !n.IsEditing {
@@ -893,40 +1478,88 @@ func (n *NavBar) HandleInput(app *App) {
 	}
 
 	n.CursorBlink++
+	n.handleDrag()
+	n.handleSuggestionKeys(app)
+
+	ctrl := ebiten.IsKeyPressed(ebiten.KeyControl) || ebiten.IsKeyPressed(ebiten.KeyMeta)
+	shift := ebiten.IsKeyPressed(ebiten.KeyShift)
+
+	// Clipboard and select-all
+	if ctrl {
+		switch {
+		case inpututil.IsKeyJustPressed(ebiten.KeyA):
+			n.selectAll()
+		case inpututil.IsKeyJustPressed(ebiten.KeyC):
+			if n.hasSelection() {
+				clipboard.WriteText(n.selectedText())
+			}
+		case inpututil.IsKeyJustPressed(ebiten.KeyX):
+			if n.hasSelection() {
+				clipboard.WriteText(n.selectedText())
+				n.deleteSelection()
+			}
+		case inpututil.IsKeyJustPressed(ebiten.KeyV):
+			n.insertText(clipboard.ReadText())
+		}
+	}
 
-	runes := ebiten.AppendInputChars(nil)
-	for _, r := range runes {
-		n.URLText = n.URLText[:n.CursorPos] + string(r) + n.URLText[n.CursorPos:]
-		n.CursorPos++
+	if runes := ebiten.AppendInputChars(nil); len(runes) > 0 {
+		n.insertText(string(runes))
 	}
 
-	if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) {
-		if n.CursorPos > 0 {
-			_, size := utf8.DecodeLastRuneInString(n.URLText[:n.CursorPos])
-			n.URLText = n.URLText[:n.CursorPos-size] + n.URLText[n.CursorPos:]
-			n.CursorPos -= size
+	// Deletion: the selection, else a rune (a word with Ctrl)
+	if keyRepeated(ebiten.KeyBackspace) {
+		if n.hasSelection() {
+			n.deleteSelection()
+		} else if ctrl {
+			n.deleteRange(prevWordBoundary(n.URLText, n.CursorPos), n.CursorPos)
+		} else {
+			n.deleteRange(n.prevRune(), n.CursorPos)
+		}
+	}
+	if keyRepeated(ebiten.KeyDelete) {
+		if n.hasSelection() {
+			n.deleteSelection()
+		} else if ctrl {
+			n.deleteRange(n.CursorPos, nextWordBoundary(n.URLText, n.CursorPos))
+		} else {
+			n.deleteRange(n.CursorPos, n.nextRune())
 		}
 	}
 
-	if inpututil.IsKeyJustPressed(ebiten.KeyLeft) && n.CursorPos > 0 {
-		_, size := utf8.DecodeLastRuneInString(n.URLText[:n.CursorPos])
-		n.CursorPos -= size
+	// Cursor movement: Shift extends the selection, Ctrl jumps by word
+	if keyRepeated(ebiten.KeyLeft) {
+		start, _ := n.selection()
+		switch {
+		case ctrl:
+			n.moveCursor(prevWordBoundary(n.URLText, n.CursorPos), shift)
+		case n.hasSelection() && !shift:
+			n.moveCursor(start, false)
+		default:
+			n.moveCursor(n.prevRune(), shift)
+		}
+	}
+	if keyRepeated(ebiten.KeyRight) {
+		_, end := n.selection()
+		switch {
+		case ctrl:
+			n.moveCursor(nextWordBoundary(n.URLText, n.CursorPos), shift)
+		case n.hasSelection() && !shift:
+			n.moveCursor(end, false)
+		default:
+			n.moveCursor(n.nextRune(), shift)
+		}
 	}
-	if inpututil.IsKeyJustPressed(ebiten.KeyRight) && n.CursorPos < len(n.URLText) {
-		_, size := utf8.DecodeRuneInString(n.URLText[n.CursorPos:])
-		n.CursorPos += size
+	if inpututil.IsKeyJustPressed(ebiten.KeyHome) {
+		n.moveCursor(0, shift)
 	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnd) {
+		n.moveCursor(len(n.URLText), shift)
+	}
+	n.scrollToCursor()
 
 	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) {
-		n.IsEditing = false
-		url := strings.TrimSpace(n.URLText)
-		if url != "" {
-			if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
-				url = "https://" + url
-			}
-			app.URL = url
-			app.LoadFromURL(url)
-		}
+		n.submitInput(app)
 	}
 
 	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
@@ -939,6 +1572,9 @@ func (n *NavBar) HandleInput(app *App) {
 func (n *NavBar) Draw(screen *ebiten.Image, app *App) {
 	// Draw navbar background - dark gray
 	navBg := color.RGBA{38, 38, 42, 255}
+	if app.private {
+		navBg = ColorPrivateNavBar
+	}
 	vector.DrawFilledRect(screen, 0, 0, WindowWidth, NavBarHeight, navBg, false)
 
 	// Navigation buttons - pill style with clear visibility
@@ -966,12 +1602,12 @@ func (n *NavBar) Draw(screen *ebiten.Image, app *App) {
 	// Refresh button - centered text
 	startX += btnSize + btnSpacing
 	render.DrawRoundedRect(screen, startX, btnY, btnSize, btnSize, 6, btnColor)
-	refreshIcon := "R"
+	btnCenterX = float64(startX) + float64(btnSize)/2
 	if app.IsLoading {
-		refreshIcon = "..."
+		render.DrawSpinner(screen, float32(btnCenterX), btnY+btnSize/2, 8, app.frame, btnTextColor)
+	} else {
+		render.DrawTextCentered(screen, "R", btnCenterX, btnCenterY, 14, btnTextColor)
 	}
-	btnCenterX = float64(startX) + float64(btnSize)/2
-	render.DrawTextCentered(screen, refreshIcon, btnCenterX, btnCenterY, 14, btnTextColor)
 
 	// Capture/Screenshot button
 	startX += btnSize + btnSpacing
@@ -987,6 +1623,10 @@ func (n *NavBar) Draw(screen *ebiten.Image, app *App) {
 	urlBarMargin := float32(12)
 	n.URLBarX = startX + btnSize + urlBarMargin
 	n.URLBarW = float32(WindowWidth) - n.URLBarX - 12
+	if app.private {
+		n.URLBarW -= privateBadgeWidth + 8
+		drawPrivateBadge(screen, n.URLBarX+n.URLBarW+8)
+	}
 	n.URLBarY = float32((NavBarHeight - URLBarHeight) / 2)
 
 	// Much lighter URL bar for text readability
@@ -1000,108 +1640,39 @@ func (n *NavBar) Draw(screen *ebiten.Image, app *App) {
 	// Draw pill-shaped URL bar with rounded corners
 	render.DrawRoundedRect(screen, n.URLBarX, n.URLBarY, n.URLBarW, URLBarHeight, 8, urlBarColor)
 
-	// URL text - dark text on light background
-	displayURL := app.URL
-	if n.IsEditing {
-		displayURL = n.URLText
-	}
-
-	// Truncate URL for display
-	maxChars := int((n.URLBarW - 30) / 8)
-	if len(displayURL) > maxChars && maxChars > 0 {
-		displayURL = displayURL[:maxChars] + "…"
-	}
-
-	// Dark text for contrast - vertically centered in URL bar
-	urlTextColor := color.RGBA{50, 50, 55, 255}
 	textY := float64(n.URLBarY) + float64(URLBarHeight)/2 - 2 // Align with cursor
-	render.DrawText(screen, displayURL, float64(n.URLBarX+12), textY, FontSizeUI, urlTextColor)
-
-	// Blinking cursor when editing
-	if n.IsEditing && (n.CursorBlink/30)%2 == 0 {
-		// Measure actual text width up to cursor position for accurate placement
-		textBeforeCursor := n.URLText[:n.CursorPos]
-		cursorOffset := render.MeasureText(textBeforeCursor, FontSizeUI)
-
-		maxOffset := float64(n.URLBarW - 24)
-		if cursorOffset > maxOffset {
-			cursorOffset = maxOffset
+	if n.IsEditing {
+		n.drawEditingText(screen, textY)
+	} else {
+		// While loading, the page title (once parsed) sits at the right of the URL
+		titleWidth := float32(0)
+		if app.IsLoading && app.PageTitle != "" {
+			title := render.TruncateText(app.PageTitle, float64(n.URLBarW)/3, 12)
+			titleWidth = float32(render.MeasureText(title, 12)) + 16
+			titleX := float64(n.URLBarX+n.URLBarW-titleWidth) + 4
+			render.DrawText(screen, title, titleX, float64(n.URLBarY)+float64(URLBarHeight)/2-1, 12, ColorTextMuted)
 		}
-		cursorX := float32(float64(n.URLBarX) + 12 + cursorOffset)
-		cursorY := n.URLBarY + 10
-		cursorH := float32(URLBarHeight - 20)
-		cursorColor := color.RGBA{30, 120, 210, 255} // Blue cursor
-		vector.DrawFilledRect(screen, cursorX, cursorY, 2, cursorH, cursorColor, false)
-	}
-}
 
-// initJSEngine initializes SpiderGopher and executes <script> tags
-func (a *App) initJSEngine() {
-	if a.DOMRoot == nil {
-		return
+		iconW := n.drawSecurityIcon(screen, app.Security)
+		displayURL := render.TruncateText(app.URL, n.textViewWidth()-float64(titleWidth)-iconW, FontSizeUI)
+		render.DrawText(screen, displayURL, float64(n.URLBarX)+urlTextPadding+iconW, textY, FontSizeUI, ColorURLText)
 	}
 
-	// Create new engine for each page load
-	a.JSEngine = spidergopher.NewEngine()
-
-	// Connect to the real DOM
-	a.JSEngine.SetDOM(a.DOMRoot)
-
-	// Start the event loop for async operations (setTimeout, fetch, etc.)
-	a.JSEngine.Start()
-
-	// Extract and execute all <script> tags
-	scripts := extractScripts(a.DOMRoot)
-	fmt.Printf("[initJSEngine] Found %d script(s) to execute\n", len(scripts))
-	for i, script := range scripts {
-		if script != "" {
-			fmt.Printf("[initJSEngine] Executing script #%d (%d chars)\n", i+1, len(script))
-			_, err := a.JSEngine.Run(script)
-			if err != nil {
-				fmt.Printf("[JS Error] %v\n", err)
-			}
-		}
+	// Progress bar along the bottom edge of the nav bar
+	if app.IsLoading {
+		vector.DrawFilledRect(screen, 0, NavBarHeight-2, float32(WindowWidth*app.Progress), 2, ColorAccent, false)
 	}
-
-	// IMPORTANT: Rebuild render tree AFTER JS execution
-	// This ensures DOM modifications made by JS are visible
-	a.RenderTree = layout.BuildRenderTree(a.DOMRoot, WindowWidth-(Padding*2))
 }
 
-// extractScripts extracts script content from <script> tags in the DOM
-func extractScripts(node *dom.Node) []string {
-	var scripts []string
-	if node == nil {
-		return scripts
-	}
-
-	if node.Tag == "script" {
-		// Get text content from script tag
-		for _, child := range node.Children {
-			if child.Type == dom.NodeText && child.Content != "" {
-				scripts = append(scripts, child.Content)
-			}
+// extractScripts collects the <script> tags in the DOM in document order,
+// fetching external ones from their src resolved against baseURL through
+// client, except http scripts on https pages
+func extractScripts(node *dom.Node, baseURL string, client *http.Client) []spidergopher.PageScript {
+	return spidergopher.PageScripts(node, baseURL, client, func(scriptURL string) bool {
+		if IsMixedContent(baseURL, scriptURL) {
+			logging.Net.Warn("blocked mixed content script", "url", scriptURL)
+			return false
 		}
-	}
-
-	// Recursively search children
-	for _, child := range node.Children {
-		scripts = append(scripts, extractScripts(child)...)
-	}
-
-	return scripts
-}
-
-// dispatchJSClickEvent fires click event listeners registered via JavaScript
-func (a *App) dispatchJSClickEvent(node *dom.Node) {
-	if a.JSEngine == nil || node == nil {
-		return
-	}
-
-	// Import the JSNode package to access event listeners
-	// Get listeners for this specific node
-	spiderdom.DispatchClickEvent(node, a.JSEngine.GetVM())
-
-	// Rebuild render tree to reflect any DOM changes made by the handler
-	a.RenderTree = layout.BuildRenderTree(a.DOMRoot, WindowWidth-(Padding*2))
+		return true
+	})
 }
@@ -0,0 +1,38 @@
+package browser
+
+import (
+	"net/http"
+
+	"go-browser/render"
+	"go-browser/spidergopher/webapi"
+)
+
+// =============================================================================
+// BROWSER STATE
+// What the tabs of one App share and no other App in the process sees: the
+// HTTP client their requests go through, with its cookie jar, the loader and
+// cache of their images, which follow the App's settings, and the store of
+// their localStorage. Frames and the tabs a tab opens share their tab's.
+// =============================================================================
+
+// browserState is what the tabs of one App share
+type browserState struct {
+	client  *http.Client       // pages, stylesheets, scripts, media and fetch()
+	images  *render.Images     // images, through client
+	storage *webapi.LocalStore // localStorage
+}
+
+// newBrowserState creates the state of an App with settings whose requests
+// go through client and whose localStorage is kept in storage
+func newBrowserState(settings *Settings, client *http.Client, storage *webapi.LocalStore) *browserState {
+	budget := int64(render.DefaultImageCacheBudget)
+	if settings.ImageCacheMB > 0 {
+		budget = int64(settings.ImageCacheMB) << 20
+	}
+	images := render.NewImages(client, render.NewImageCache(budget))
+	images.Allowed = settings.ImagesAllowed
+	if settings.ImageDownloads > 0 {
+		images.Downloads = settings.ImageDownloads
+	}
+	return &browserState{client: client, images: images, storage: storage}
+}
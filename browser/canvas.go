@@ -0,0 +1,412 @@
+package browser
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"sync"
+
+	"go-browser/css"
+	"go-browser/dom"
+	"go-browser/layout"
+	"go-browser/render"
+	"go-browser/spidergopher"
+	spiderdom "go-browser/spidergopher/dom"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// =============================================================================
+// CANVAS
+// Each <canvas> a script draws on gets an offscreen ebiten image, its
+// bitmap, which the page paints scaled to the canvas box. Scripts draw
+// through the spiderdom.CanvasContext the canvas's surface implements.
+// =============================================================================
+
+// canvasHost holds the bitmaps of a tab's canvases. It is the
+// spiderdom.CanvasHandler the page's engine asks for 2D contexts.
+type canvasHost struct {
+	mu       sync.Mutex
+	baseURL  string         // URL image sources resolve against
+	pageURL  string         // page the images load for
+	viewport css.Viewport   // the page's viewport, for fonts sized in viewport units
+	images   *render.Images // loads the images drawn on the canvases
+	surfaces map[*dom.Node]*canvasSurface
+}
+
+func newCanvasHost(images *render.Images) *canvasHost {
+	return &canvasHost{images: images, surfaces: make(map[*dom.Node]*canvasSurface)}
+}
+
+// reset drops the canvases of the old page; baseURL and pageURL are the
+// new page's
+func (h *canvasHost) reset(baseURL, pageURL string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, s := range h.surfaces {
+		s.dispose()
+	}
+	h.surfaces = make(map[*dom.Node]*canvasSurface)
+	h.baseURL, h.pageURL = baseURL, pageURL
+}
+
+// setViewport tells the canvases the size of the page's viewport
+func (h *canvasHost) setViewport(viewport css.Viewport) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.viewport = viewport
+}
+
+// CanvasContext returns the drawing surface of node
+func (h *canvasHost) CanvasContext(node *dom.Node) spiderdom.CanvasContext {
+	return h.surface(node)
+}
+
+func (h *canvasHost) surface(node *dom.Node) *canvasSurface {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.surfaces[node]
+	if s == nil {
+		s = &canvasSurface{host: h, node: node}
+		h.surfaces[node] = s
+	}
+	return s
+}
+
+// image returns the bitmap of node, or nil when no script drew on it
+func (h *canvasHost) image(node *dom.Node) *ebiten.Image {
+	h.mu.Lock()
+	s := h.surfaces[node]
+	h.mu.Unlock()
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.img
+}
+
+// canvasSurface is the bitmap of one canvas and the path being built on it
+type canvasSurface struct {
+	host *canvasHost
+	node *dom.Node
+
+	mu  sync.Mutex
+	img *ebiten.Image
+	w   int
+	h   int
+
+	path vector.Path
+}
+
+// bitmap returns the canvas's image, sized by its width and height
+// attributes. Resizing the canvas clears it, as in other browsers.
+func (s *canvasSurface) bitmap() *ebiten.Image {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w, h := spiderdom.CanvasSize(s.node)
+	if s.img != nil && w == s.w && h == s.h {
+		return s.img
+	}
+	if s.img != nil {
+		s.img.Deallocate()
+	}
+	s.img, s.w, s.h = nil, w, h
+	if w > 0 && h > 0 {
+		s.img = ebiten.NewImage(w, h)
+	}
+	return s.img
+}
+
+func (s *canvasSurface) dispose() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.img != nil {
+		s.img.Deallocate()
+		s.img = nil
+	}
+}
+
+// canvasColor parses a fill or stroke style, faded by the global alpha.
+// Styles that aren't colors (gradients, patterns) paint black.
+func canvasColor(value string, alpha float64) color.RGBA {
+	c, ok := css.ParseColor(value)
+	if !ok {
+		c = color.RGBA{0, 0, 0, 255}
+	}
+	return render.Fade(c, alpha)
+}
+
+// pathOptions returns the options painting a path in clr
+func pathOptions(clr color.RGBA) *vector.DrawPathOptions {
+	op := &vector.DrawPathOptions{AntiAlias: true}
+	op.ColorScale.ScaleWithColor(clr)
+	return op
+}
+
+// ---- Rectangles ----
+
+func (s *canvasSurface) FillRect(style spiderdom.CanvasStyle, x, y, w, h float64) {
+	if img := s.bitmap(); img != nil {
+		x, y, w, h = normalizeRect(x, y, w, h)
+		vector.FillRect(img, float32(x), float32(y), float32(w), float32(h), canvasColor(style.FillStyle, style.GlobalAlpha), true)
+	}
+}
+
+func (s *canvasSurface) StrokeRect(style spiderdom.CanvasStyle, x, y, w, h float64) {
+	if img := s.bitmap(); img != nil {
+		x, y, w, h = normalizeRect(x, y, w, h)
+		vector.StrokeRect(img, float32(x), float32(y), float32(w), float32(h), float32(style.LineWidth), canvasColor(style.StrokeStyle, style.GlobalAlpha), true)
+	}
+}
+
+func (s *canvasSurface) ClearRect(x, y, w, h float64) {
+	img := s.bitmap()
+	if img == nil {
+		return
+	}
+	x, y, w, h = normalizeRect(x, y, w, h)
+	r := image.Rect(int(math.Floor(x)), int(math.Floor(y)), int(math.Ceil(x+w)), int(math.Ceil(y+h))).Intersect(img.Bounds())
+	if !r.Empty() {
+		img.SubImage(r).(*ebiten.Image).Clear()
+	}
+}
+
+// normalizeRect flips a rectangle given a negative width or height
+func normalizeRect(x, y, w, h float64) (float64, float64, float64, float64) {
+	if w < 0 {
+		x, w = x+w, -w
+	}
+	if h < 0 {
+		y, h = y+h, -h
+	}
+	return x, y, w, h
+}
+
+// ---- Paths ----
+
+func (s *canvasSurface) BeginPath() {
+	s.path.Reset()
+}
+
+func (s *canvasSurface) MoveTo(x, y float64) {
+	s.path.MoveTo(float32(x), float32(y))
+}
+
+func (s *canvasSurface) LineTo(x, y float64) {
+	s.path.LineTo(float32(x), float32(y))
+}
+
+// Arc adds an arc to the path. An arc sweeping a full turn or more is a
+// whole circle, which vector.Path would collapse to nothing, so it is drawn
+// as two halves.
+func (s *canvasSurface) Arc(x, y, radius, startAngle, endAngle float64, counterclockwise bool) {
+	if radius < 0 {
+		return
+	}
+	dir := vector.Clockwise
+	sweep := endAngle - startAngle
+	if counterclockwise {
+		dir, sweep = vector.CounterClockwise, -sweep
+	}
+	cx, cy, r := float32(x), float32(y), float32(radius)
+	if sweep >= 2*math.Pi {
+		half := float32(math.Pi)
+		if counterclockwise {
+			half = -half
+		}
+		start := float32(startAngle)
+		s.path.Arc(cx, cy, r, start, start+half, dir)
+		s.path.Arc(cx, cy, r, start+half, start+2*half, dir)
+		return
+	}
+	s.path.Arc(cx, cy, r, float32(startAngle), float32(endAngle), dir)
+}
+
+func (s *canvasSurface) Rect(x, y, w, h float64) {
+	s.path.MoveTo(float32(x), float32(y))
+	s.path.LineTo(float32(x+w), float32(y))
+	s.path.LineTo(float32(x+w), float32(y+h))
+	s.path.LineTo(float32(x), float32(y+h))
+	s.path.Close()
+}
+
+func (s *canvasSurface) ClosePath() {
+	s.path.Close()
+}
+
+func (s *canvasSurface) Fill(style spiderdom.CanvasStyle) {
+	if img := s.bitmap(); img != nil {
+		vector.FillPath(img, &s.path, &vector.FillOptions{FillRule: vector.FillRuleNonZero}, pathOptions(canvasColor(style.FillStyle, style.GlobalAlpha)))
+	}
+}
+
+func (s *canvasSurface) Stroke(style spiderdom.CanvasStyle) {
+	if img := s.bitmap(); img != nil && style.LineWidth > 0 {
+		op := &vector.StrokeOptions{Width: float32(style.LineWidth), LineJoin: vector.LineJoinMiter, MiterLimit: 10}
+		vector.StrokePath(img, &s.path, op, pathOptions(canvasColor(style.StrokeStyle, style.GlobalAlpha)))
+	}
+}
+
+// ---- Text ----
+
+// fontSize returns the size in a CSS font shorthand such as
+// "bold 16px sans-serif"
+func (s *canvasSurface) fontSize(font string) float64 {
+	s.host.mu.Lock()
+	viewport := s.host.viewport
+	s.host.mu.Unlock()
+	for _, field := range strings.Fields(font) {
+		// "16px/20px" gives a line height too
+		field, _, _ = strings.Cut(field, "/")
+		if num, unit, ok := css.ParseLength(field); ok && num > 0 {
+			return css.LengthToPx(num, unit, 10, 10, viewport)
+		}
+	}
+	return 10
+}
+
+func (s *canvasSurface) FillText(style spiderdom.CanvasStyle, text string, x, y float64) {
+	img := s.bitmap()
+	if img == nil {
+		return
+	}
+	size := s.fontSize(style.Font)
+	switch style.TextAlign {
+	case "center":
+		x -= render.MeasureText(text, size) / 2
+	case "right", "end":
+		x -= render.MeasureText(text, size)
+	}
+	clr := canvasColor(style.FillStyle, style.GlobalAlpha)
+	switch style.TextBaseline {
+	case "top", "hanging":
+		render.DrawText(img, text, x, y, size, clr)
+	case "middle":
+		render.DrawText(img, text, x, y-size/2, size, clr)
+	case "bottom", "ideographic":
+		render.DrawText(img, text, x, y-size, size, clr)
+	default:
+		render.DrawTextAtBaseline(img, text, x, y, size, clr)
+	}
+}
+
+func (s *canvasSurface) MeasureText(style spiderdom.CanvasStyle, text string) float64 {
+	return render.MeasureText(text, s.fontSize(style.Font))
+}
+
+// ---- Images ----
+
+// DrawImage draws an <img> that has loaded, or another canvas, onto the
+// canvas. NaN sizes stand for the source's own.
+func (s *canvasSurface) DrawImage(source *dom.Node, sx, sy, sw, sh, dx, dy, dw, dh float64) {
+	img := s.bitmap()
+	if img == nil {
+		return
+	}
+	var src *ebiten.Image
+	switch source.Tag {
+	case "img":
+		if url := source.GetAttr("src"); url != "" {
+			src = s.host.images.CachedImage(spidergopher.ResolveURL(url, s.host.baseURL), s.host.pageURL)
+		}
+	case "canvas":
+		if source != s.node {
+			src = s.host.image(source)
+		}
+	}
+	if src == nil {
+		return
+	}
+
+	b := src.Bounds()
+	if math.IsNaN(sw) || math.IsNaN(sh) {
+		sw, sh = float64(b.Dx()), float64(b.Dy())
+	}
+	if math.IsNaN(dw) || math.IsNaN(dh) {
+		dw, dh = sw, sh
+	}
+	r := image.Rect(int(sx), int(sy), int(sx+sw), int(sy+sh)).Add(b.Min).Intersect(b)
+	if r.Empty() || dw == 0 || dh == 0 {
+		return
+	}
+	op := &ebiten.DrawImageOptions{Filter: ebiten.FilterLinear}
+	op.GeoM.Scale(dw/sw, dh/sh)
+	op.GeoM.Translate(dx, dy)
+	img.DrawImage(src.SubImage(r).(*ebiten.Image), op)
+}
+
+// ---- Pixels ----
+
+// GetImageData returns the RGBA pixels of a rectangle of the canvas, not
+// premultiplied; pixels outside the canvas are transparent black
+func (s *canvasSurface) GetImageData(x, y, w, h int) []byte {
+	out := make([]byte, 4*w*h)
+	img := s.bitmap()
+	if img == nil {
+		return out
+	}
+	r := image.Rect(x, y, x+w, y+h).Intersect(img.Bounds())
+	if r.Empty() {
+		return out
+	}
+	pixels := make([]byte, 4*r.Dx()*r.Dy())
+	img.SubImage(r).(*ebiten.Image).ReadPixels(pixels)
+	for row := 0; row < r.Dy(); row++ {
+		src := pixels[4*row*r.Dx() : 4*(row+1)*r.Dx()]
+		dst := out[4*((r.Min.Y-y+row)*w+r.Min.X-x):]
+		for i := 0; i < len(src); i += 4 {
+			a := src[i+3]
+			if a == 0 {
+				continue
+			}
+			for c := 0; c < 3; c++ {
+				dst[i+c] = uint8(min(255, int(src[i+c])*255/int(a)))
+			}
+			dst[i+3] = a
+		}
+	}
+	return out
+}
+
+// PutImageData replaces a rectangle of the canvas with RGBA pixels that
+// are not premultiplied
+func (s *canvasSurface) PutImageData(data []byte, x, y, w, h int) {
+	img := s.bitmap()
+	if img == nil {
+		return
+	}
+	r := image.Rect(x, y, x+w, y+h).Intersect(img.Bounds())
+	if r.Empty() {
+		return
+	}
+	pixels := make([]byte, 4*r.Dx()*r.Dy())
+	for row := 0; row < r.Dy(); row++ {
+		src := data[4*((r.Min.Y-y+row)*w+r.Min.X-x):]
+		dst := pixels[4*row*r.Dx() : 4*(row+1)*r.Dx()]
+		for i := 0; i < len(dst); i += 4 {
+			a := int(src[i+3])
+			for c := 0; c < 3; c++ {
+				dst[i+c] = uint8(int(src[i+c]) * a / 255)
+			}
+			dst[i+3] = uint8(a)
+		}
+	}
+	img.SubImage(r).(*ebiten.Image).WritePixels(pixels)
+}
+
+// drawCanvas paints a canvas's bitmap scaled to its box at x, y
+func (a *App) drawCanvas(screen *ebiten.Image, box *layout.RenderBox, x, y float64) {
+	img := a.canvases.image(box.Node)
+	if img == nil {
+		return
+	}
+	b := img.Bounds()
+	op := &ebiten.DrawImageOptions{Filter: ebiten.FilterLinear}
+	op.GeoM.Scale(box.W/float64(b.Dx()), box.H/float64(b.Dy()))
+	op.GeoM.Translate(x, y)
+	op.ColorScale.ScaleAlpha(float32(box.Opacity))
+	screen.DrawImage(img, op)
+}
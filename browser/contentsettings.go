@@ -0,0 +1,244 @@
+package browser
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go-browser/logging"
+)
+
+// =============================================================================
+// CONTENT SETTINGS
+// JavaScript and images can be turned off for every site or per host, and a
+// blocklist of ad/tracker domains is refused for every network request
+// =============================================================================
+
+// SiteSettings overrides the content defaults for one host and its
+// subdomains; nil fields follow the defaults
+type SiteSettings struct {
+	JavaScript *bool `json:"javascript,omitempty"`
+	Images     *bool `json:"images,omitempty"`
+}
+
+// urlHost returns the lowercased host of a page URL, without the port
+func urlHost(pageURL string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// hostAndParents lists host followed by each parent domain:
+// "a.b.example.com", "b.example.com", "example.com", "com"
+func hostAndParents(host string) []string {
+	var hosts []string
+	for host != "" {
+		hosts = append(hosts, host)
+		i := strings.IndexByte(host, '.')
+		if i == -1 {
+			break
+		}
+		host = host[i+1:]
+	}
+	return hosts
+}
+
+// allowed resolves one content setting for a page: the most specific site
+// entry that sets it wins, else the default
+func (s *Settings) allowed(pageURL string, def bool, field func(*SiteSettings) *bool) bool {
+	for _, host := range hostAndParents(urlHost(pageURL)) {
+		if site := s.Sites[host]; site != nil {
+			if v := field(site); v != nil {
+				return *v
+			}
+		}
+	}
+	return def
+}
+
+// JavaScriptAllowed reports whether scripts run on the page at pageURL
+func (s *Settings) JavaScriptAllowed(pageURL string) bool {
+	if s == nil {
+		return true
+	}
+	return s.allowed(pageURL, s.JavaScript, func(site *SiteSettings) *bool { return site.JavaScript })
+}
+
+// ImagesAllowed reports whether images load on the page at pageURL
+func (s *Settings) ImagesAllowed(pageURL string) bool {
+	if s == nil {
+		return true
+	}
+	return s.allowed(pageURL, s.Images, func(site *SiteSettings) *bool { return site.Images })
+}
+
+// site returns the entry for host, creating it
+func (s *Settings) site(host string) *SiteSettings {
+	if s.Sites == nil {
+		s.Sites = map[string]*SiteSettings{}
+	}
+	if s.Sites[host] == nil {
+		s.Sites[host] = &SiteSettings{}
+	}
+	return s.Sites[host]
+}
+
+// SetSiteJavaScript allows or blocks scripts on host and its subdomains
+func (s *Settings) SetSiteJavaScript(host string, allowed bool) {
+	s.site(strings.ToLower(host)).JavaScript = &allowed
+}
+
+// SetSiteImages allows or blocks images on host and its subdomains
+func (s *Settings) SetSiteImages(host string, allowed bool) {
+	s.site(strings.ToLower(host)).Images = &allowed
+}
+
+// toggleSiteContent flips a content setting for the active page's host,
+// saves the settings and reloads so the page picks the change up
+func (a *App) toggleSiteContent(name string, allowed func(string) bool, set func(string, bool)) {
+	host := urlHost(a.URL)
+	if host == "" {
+		return
+	}
+	enable := !allowed(a.URL)
+	set(host, enable)
+	verb := "Blocked"
+	if enable {
+		verb = "Allowed"
+	}
+	logging.App.Info(verb+" "+name, "host", host)
+	if err := a.Settings.Save(SettingsPath()); err != nil {
+		logging.App.Error("saving settings", "err", err)
+	}
+	a.Reload()
+}
+
+// toggleSetting flips a browser-wide setting, saves the settings and lays
+// the page out again in case the setting shapes it
+func (a *App) toggleSetting(name string, setting *bool) {
+	*setting = !*setting
+	state := "off"
+	if *setting {
+		state = "on"
+	}
+	logging.App.Info("turned " + name + " " + state)
+	if err := a.Settings.Save(SettingsPath()); err != nil {
+		logging.App.Error("saving settings", "err", err)
+	}
+	a.relayout()
+}
+
+// =============================================================================
+// BLOCKLIST
+// =============================================================================
+
+// Blocklist is a set of domains refused for every request; a listed domain
+// blocks its subdomains too
+type Blocklist struct {
+	domains map[string]bool
+}
+
+// ParseBlocklist reads one domain per line. Lines in hosts-file form
+// ("0.0.0.0 ads.example.com") and adblock form ("||ads.example.com^") are
+// accepted; # starts a comment.
+func ParseBlocklist(r io.Reader) (*Blocklist, error) {
+	b := &Blocklist{domains: map[string]bool{}}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i != -1 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) > 1 && net.ParseIP(fields[0]) != nil {
+			fields = fields[1:]
+		}
+		for _, domain := range fields {
+			domain = strings.TrimSuffix(strings.TrimPrefix(domain, "||"), "^")
+			domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+			if domain != "" && domain != "localhost" {
+				b.domains[domain] = true
+			}
+		}
+	}
+	return b, scanner.Err()
+}
+
+// LoadBlocklist reads the blocklist file at path; a missing file blocks nothing
+func LoadBlocklist(path string) (*Blocklist, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &Blocklist{}, nil
+	}
+	if err != nil {
+		return &Blocklist{}, err
+	}
+	defer f.Close()
+	b, err := ParseBlocklist(f)
+	if err != nil {
+		return b, fmt.Errorf("%s: %w", path, err)
+	}
+	return b, nil
+}
+
+// Blocks reports whether requests to host are refused
+func (b *Blocklist) Blocks(host string) bool {
+	if b == nil || len(b.domains) == 0 {
+		return false
+	}
+	for _, domain := range hostAndParents(strings.ToLower(host)) {
+		if b.domains[domain] {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of listed domains
+func (b *Blocklist) Len() int {
+	if b == nil {
+		return 0
+	}
+	return len(b.domains)
+}
+
+// BlocklistPath returns where the blocklist lives: $GOBROWSER_BLOCKLIST,
+// or gobrowser/blocklist.txt in the config directory
+func BlocklistPath() string {
+	if path := os.Getenv("GOBROWSER_BLOCKLIST"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gobrowser", "blocklist.txt")
+}
+
+// ErrBlocked is the error requests to blocklisted hosts fail with
+var ErrBlocked = errors.New("on the blocklist")
+
+// blockingTransport refuses requests to blocklisted hosts before they leave
+// the process
+type blockingTransport struct {
+	next http.RoundTripper
+	list *Blocklist
+}
+
+func (t *blockingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.list.Blocks(req.URL.Hostname()) {
+		logging.Net.Info("blocked by blocklist", "url", req.URL)
+		return nil, fmt.Errorf("%s is %w", req.URL.Hostname(), ErrBlocked)
+	}
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,173 @@
+package browser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// =============================================================================
+// COOKIES
+// Every tab shares one cookie jar. The jar remembers, besides, each cookie
+// set with an expiry date and the URL that set it, so that those outlive the
+// browser: they are written to the profile's cookies.json and given to the
+// jar again on the next start. Session cookies, and every cookie of a
+// private window, die with the process.
+// =============================================================================
+
+// savedCookie is a persistent cookie as cookies.json keeps it
+type savedCookie struct {
+	URL      string    `json:"url"` // the page or request that set it
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain,omitempty"`
+	Path     string    `json:"path,omitempty"`
+	Expires  time.Time `json:"expires"`
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"http_only,omitempty"`
+}
+
+// cookie returns the cookie to give the jar again
+func (c savedCookie) cookie() *http.Cookie {
+	return &http.Cookie{
+		Name: c.Name, Value: c.Value, Domain: c.Domain, Path: c.Path,
+		Expires: c.Expires, Secure: c.Secure, HttpOnly: c.HttpOnly,
+	}
+}
+
+// cookieStore is a cookie jar that keeps its persistent cookies for saving
+type cookieStore struct {
+	jar *cookiejar.Jar
+
+	mu         sync.Mutex
+	persistent map[string]savedCookie // by host, domain, path and name
+	changed    bool                   // persistent cookies changed since saved
+	saving     sync.Mutex             // keeps autosaves and the save on exit apart
+}
+
+// newCookieStore creates an empty store
+func newCookieStore() *cookieStore {
+	jar, _ := cookiejar.New(nil)
+	return &cookieStore{jar: jar, persistent: make(map[string]savedCookie)}
+}
+
+// SetCookies stores the cookies a response from u set
+func (s *cookieStore) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	s.jar.SetCookies(u, cookies)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for _, c := range cookies {
+		key := u.Hostname() + "|" + c.Domain + "|" + c.Path + "|" + c.Name
+		expires := c.Expires
+		if c.MaxAge > 0 {
+			expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		}
+		if c.MaxAge < 0 || expires.IsZero() || expires.Before(now) {
+			// Deleted, or now a session cookie
+			if _, ok := s.persistent[key]; ok {
+				delete(s.persistent, key)
+				s.changed = true
+			}
+			continue
+		}
+		s.persistent[key] = savedCookie{
+			URL: (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String(), Name: c.Name, Value: c.Value,
+			Domain: c.Domain, Path: c.Path, Expires: expires, Secure: c.Secure, HttpOnly: c.HttpOnly,
+		}
+		s.changed = true
+	}
+}
+
+// Cookies returns the cookies to send in a request to u
+func (s *cookieStore) Cookies(u *url.URL) []*http.Cookie {
+	return s.jar.Cookies(u)
+}
+
+// Load gives the jar the unexpired cookies saved at path; a missing file
+// loads none
+func (s *cookieStore) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var saved []savedCookie
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, c := range saved {
+		u, err := url.Parse(c.URL)
+		if err != nil || c.Expires.Before(time.Now()) {
+			continue
+		}
+		s.SetCookies(u, []*http.Cookie{c.cookie()})
+	}
+	s.mu.Lock()
+	s.changed = false
+	s.mu.Unlock()
+	return nil
+}
+
+// Save writes the unexpired persistent cookies to path, if they changed
+// since the last save
+func (s *cookieStore) Save(path string) error {
+	s.saving.Lock()
+	defer s.saving.Unlock()
+	s.mu.Lock()
+	if !s.changed {
+		s.mu.Unlock()
+		return nil
+	}
+	saved := make([]savedCookie, 0, len(s.persistent))
+	now := time.Now()
+	for _, c := range s.persistent {
+		if c.Expires.After(now) {
+			saved = append(saved, c)
+		}
+	}
+	s.changed = false
+	s.mu.Unlock()
+
+	err := writeCookies(path, saved)
+	if err != nil {
+		// Try again next time
+		s.mu.Lock()
+		s.changed = true
+		s.mu.Unlock()
+	}
+	return err
+}
+
+// writeCookies writes saved cookies to path, creating its directory. Only
+// this browser should read them, and a crash mid-write leaves the last ones
+// whole.
+func writeCookies(path string, saved []savedCookie) error {
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// CookiesPath returns where the profile's persistent cookies are saved
+func CookiesPath() string {
+	return profilePath("cookies.json")
+}
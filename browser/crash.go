@@ -0,0 +1,69 @@
+package browser
+
+import (
+	"fmt"
+	"html"
+	"runtime/debug"
+
+	"go-browser/css"
+	"go-browser/logging"
+)
+
+// =============================================================================
+// CRASH RECOVERY
+// A page that makes the parser, the style engine, layout, its scripts'
+// bindings or painting panic takes down only itself. Each of those phases
+// recovers; the tab drops the page, its scripts and its images, and shows
+// an "Aw, snap!" page with the panic and its stack in its place, while the
+// chrome and the other tabs carry on. Reloading tries the page again.
+// =============================================================================
+
+// recoverPage, deferred by a phase of showing the tab's page on the main
+// thread, replaces the page with the crash page if the phase panicked
+func (t *Tab) recoverPage(phase string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	stack := debug.Stack()
+	logging.App.Error("page crashed", "phase", phase, "url", t.URL, "panic", r, "stack", string(stack))
+	t.unload()
+	t.IsLoading = false
+	if t.crashing {
+		// The crash page crashed too: show the bare error
+		t.Document, t.RenderTree = nil, nil
+		t.ErrorMsg = fmt.Sprintf("The page crashed while %s: %v", phase, r)
+		return
+	}
+	t.crashing = true
+	defer func() { t.crashing = false }()
+	t.Security = nil
+	t.ErrorMsg = ""
+	t.pendingScroll = 0
+	t.LoadContent(crashPage(t.URL, phase, r, stack))
+}
+
+// recoverPrepare, deferred by the goroutine fetching, parsing and styling
+// load of urlStr, offers the crash page instead if it panicked
+func (t *Tab) recoverPrepare(urlStr string, load int64, media css.Media) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	stack := debug.Stack()
+	logging.App.Error("page crashed", "phase", "parsing", "url", urlStr, "panic", r, "stack", string(stack))
+	page := preparePage(crashPage(urlStr, "parsing", r, stack), urlStr, nil, media, t.browser.client)
+	page.load = load
+	t.offerPage(page)
+}
+
+// crashPage writes the page shown in place of one that crashed while phase
+func crashPage(urlStr, phase string, r any, stack []byte) string {
+	return "<html><head><title>Aw, snap!</title>" + internalPageStyle + "</head><body>" +
+		"<h1>Aw, snap!</h1>" +
+		fmt.Sprintf("<p>Something went wrong while %s <code>%s</code>. ", html.EscapeString(phase), html.EscapeString(urlStr)) +
+		"The rest of the browser is fine; reload (Ctrl+R) to try the page again.</p>" +
+		fmt.Sprintf(`<h2>%s</h2>`, html.EscapeString(fmt.Sprint(r))) +
+		`<pre class="muted">` + html.EscapeString(string(stack)) + "</pre>" +
+		"</body></html>"
+}
@@ -0,0 +1,160 @@
+package browser
+
+import (
+	"go-browser/css"
+	"go-browser/dom"
+	"go-browser/layout"
+	"go-browser/render"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// =============================================================================
+// CSS IMAGES
+// background-image and cursor: url(...) assets; the URLs arrive absolute from
+// the cascade and load through the tab's images like <img> sources. Gradients
+// are painted here too.
+// =============================================================================
+
+// repeatAxes maps background-repeat to whether the image tiles along x and y
+func repeatAxes(repeat string) (bool, bool) {
+	switch repeat {
+	case "no-repeat":
+		return false, false
+	case "repeat-x":
+		return true, false
+	case "repeat-y":
+		return false, true
+	}
+	return true, true
+}
+
+// drawBackgroundImage paints an element's background-image inside its box,
+// loading it through images for the page at pageURL
+func drawBackgroundImage(screen *ebiten.Image, images *render.Images, cs *css.ComputedStyle, pageURL string, x, y, w, h, opacity float64) {
+	if cs.BackgroundImage == "" {
+		return
+	}
+	if img := images.CachedImage(cs.BackgroundImage, pageURL); img != nil {
+		repeatX, repeatY := repeatAxes(cs.BackgroundRepeat)
+		render.DrawBackgroundImage(screen, img, x, y, w, h, repeatX, repeatY, opacity)
+	}
+}
+
+// drawBackgroundGradient paints an element's background gradient inside its
+// box, over its background-color
+func drawBackgroundGradient(screen *ebiten.Image, cs *css.ComputedStyle, x, y, w, h, opacity float64) {
+	g := cs.BackgroundGradient
+	if g == nil || len(g.Stops) < 2 {
+		return
+	}
+	stops := make([]render.GradientStop, len(g.Stops))
+	for i, s := range g.Stops {
+		stops[i] = render.GradientStop{
+			R:        float64(s.Color.R),
+			G:        float64(s.Color.G),
+			B:        float64(s.Color.B),
+			A:        float64(s.Color.A),
+			Position: s.Position,
+		}
+	}
+	shape := render.GradientShape{
+		Radial:    !g.IsLinear,
+		Repeating: g.Repeating,
+		Angle:     g.Angle,
+		Circle:    g.Shape == "circle",
+		Extent:    g.Size,
+		CenterX:   g.CenterX,
+		CenterY:   g.CenterY,
+	}
+	render.DrawGradient(screen, x, y, w, h, shape, stops, opacity)
+}
+
+// drawPageBackgroundImage paints the body's (else the root's) background-image
+// over the whole viewport, anchored at the top of the page so it scrolls with it
+func (a *App) drawPageBackgroundImage(screen *ebiten.Image) {
+	if a.Document == nil {
+		return
+	}
+	for _, node := range []*dom.Node{a.Document.Body, a.Document.DocumentElement} {
+		if cs, ok := node.ComputedStyle.(*css.ComputedStyle); ok && cs.BackgroundImage != "" {
+			top := ContentTop + a.ScrollY
+			drawBackgroundImage(screen, a.browser.images, cs, a.Document.URL, 0, top, WindowWidth, WindowHeight-top, 1)
+			return
+		}
+	}
+}
+
+// findBoxAt returns the deepest box with a DOM node under the point, in
+// render tree coordinates; later siblings paint on top so they win
+func findBoxAt(box *layout.RenderBox, x, y float64) *layout.RenderBox {
+	if box == nil {
+		return nil
+	}
+	x, y, ok := box.Untransform(x, y)
+	if !ok {
+		return nil
+	}
+	for i := len(box.Children) - 1; i >= 0; i-- {
+		if hit := findBoxAt(box.Children[i], x, y); hit != nil {
+			return hit
+		}
+	}
+	if box.Node != nil && x >= box.X && x <= box.X+box.W && y >= box.Y && y <= box.Y+box.H {
+		return box
+	}
+	return nil
+}
+
+// styleOf returns the computed style governing node; text takes its parent's
+func styleOf(node *dom.Node) *css.ComputedStyle {
+	for ; node != nil; node = node.Parent {
+		if cs, ok := node.ComputedStyle.(*css.ComputedStyle); ok {
+			return cs
+		}
+	}
+	return nil
+}
+
+// customCursor is a loaded cursor: url(...) image and its hotspot
+type customCursor struct {
+	img    *ebiten.Image
+	hx, hy float64
+}
+
+// updateCustomCursor picks the cursor image of the element under the mouse.
+// While one is shown, or hide is set for cursor: none, the OS cursor is
+// hidden; drawCustomCursor paints the image.
+func (a *App) updateCustomCursor(mx, my int, hide bool) {
+	a.cursor = customCursor{}
+	if my > int(ChromeHeight) && !a.devToolsContains(mx) && !a.NavBar.showsSuggestions(a) && a.RenderTree != nil {
+		x, y := a.pagePoint(mx, my)
+		if box := findBoxAt(a.RenderTree, x, y); box != nil {
+			if cs := styleOf(box.Node); cs != nil && cs.CursorImage != "" {
+				// Until the image loads, the OS cursor stands in for it
+				if img := a.browser.images.CachedImage(cs.CursorImage, documentURL(box.Node)); img != nil {
+					a.cursor = customCursor{img: img, hx: cs.CursorHotspotX, hy: cs.CursorHotspotY}
+				}
+			}
+		}
+	}
+
+	mode := ebiten.CursorModeVisible
+	if a.cursor.img != nil || hide {
+		mode = ebiten.CursorModeHidden
+	}
+	if ebiten.CursorMode() != mode {
+		ebiten.SetCursorMode(mode)
+	}
+}
+
+// drawCustomCursor paints the cursor image at the mouse position
+func (a *App) drawCustomCursor(screen *ebiten.Image) {
+	if a.cursor.img == nil {
+		return
+	}
+	mx, my := ebiten.CursorPosition()
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(float64(mx)-a.cursor.hx, float64(my)-a.cursor.hy)
+	screen.DrawImage(a.cursor.img, op)
+}
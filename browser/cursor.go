@@ -0,0 +1,113 @@
+package browser
+
+import (
+	"strings"
+
+	"go-browser/dom"
+	"go-browser/layout"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// =============================================================================
+// CURSOR
+// The computed cursor of the element under the mouse picks the OS cursor shape
+// =============================================================================
+
+// cursorShapes maps CSS cursor keywords to the closest OS cursor. Keywords with
+// no counterpart (help, wait, copy, zoom-in, ...) fall back to the arrow.
+var cursorShapes = map[string]ebiten.CursorShapeType{
+	"default":       ebiten.CursorShapeDefault,
+	"pointer":       ebiten.CursorShapePointer,
+	"text":          ebiten.CursorShapeText,
+	"vertical-text": ebiten.CursorShapeText,
+	"crosshair":     ebiten.CursorShapeCrosshair,
+	"cell":          ebiten.CursorShapeCrosshair,
+	"move":          ebiten.CursorShapeMove,
+	"all-scroll":    ebiten.CursorShapeMove,
+	"grab":          ebiten.CursorShapeMove,
+	"grabbing":      ebiten.CursorShapeMove,
+	"not-allowed":   ebiten.CursorShapeNotAllowed,
+	"no-drop":       ebiten.CursorShapeNotAllowed,
+	"ew-resize":     ebiten.CursorShapeEWResize,
+	"e-resize":      ebiten.CursorShapeEWResize,
+	"w-resize":      ebiten.CursorShapeEWResize,
+	"col-resize":    ebiten.CursorShapeEWResize,
+	"ns-resize":     ebiten.CursorShapeNSResize,
+	"n-resize":      ebiten.CursorShapeNSResize,
+	"s-resize":      ebiten.CursorShapeNSResize,
+	"row-resize":    ebiten.CursorShapeNSResize,
+	"nesw-resize":   ebiten.CursorShapeNESWResize,
+	"ne-resize":     ebiten.CursorShapeNESWResize,
+	"sw-resize":     ebiten.CursorShapeNESWResize,
+	"nwse-resize":   ebiten.CursorShapeNWSEResize,
+	"nw-resize":     ebiten.CursorShapeNWSEResize,
+	"se-resize":     ebiten.CursorShapeNWSEResize,
+}
+
+// textInputTypes are the <input> types edited as text
+var textInputTypes = map[string]bool{
+	"": true, "text": true, "search": true, "email": true, "url": true,
+	"tel": true, "password": true, "number": true,
+}
+
+// pageCursor returns the cursor shape for the page point under the mouse and
+// whether the OS cursor should be hidden (cursor: none)
+func (a *App) pageCursor(mx, my int) (ebiten.CursorShapeType, bool) {
+	if len(a.frames) > 0 {
+		return a.frameCursor(float64(mx), float64(my)-ChromeHeight), false
+	}
+	return a.pointCursor(a.pagePoint(mx, my))
+}
+
+// pointCursor returns the cursor shape over a page point and whether the OS
+// cursor should be hidden
+func (a *App) pointCursor(x, y float64) (ebiten.CursorShapeType, bool) {
+	if a.overColumnBoundary(x, y) {
+		return ebiten.CursorShapeEWResize, false
+	}
+	box := findBoxAt(a.RenderTree, x, y)
+	if box != nil {
+		if cs := styleOf(box.Node); cs != nil {
+			if cs.Cursor == "none" {
+				return ebiten.CursorShapeDefault, true
+			}
+			if shape, ok := cursorShapes[cs.Cursor]; ok {
+				return shape, false
+			}
+		}
+	}
+
+	// cursor: auto
+	if a.findClickedLink(a.RenderTree, x, y) != "" {
+		return ebiten.CursorShapePointer, false
+	}
+	if (box != nil && isTextField(box.Node)) || overText(a.RenderTree, x, y) {
+		return ebiten.CursorShapeText, false
+	}
+	return ebiten.CursorShapeDefault, false
+}
+
+// overText reports whether the point is on a laid-out line of text
+func overText(box *layout.RenderBox, x, y float64) bool {
+	if box.Text != "" && !box.IsButton && x >= box.X && x <= box.X+box.W && y >= box.Y && y <= box.Y+box.H {
+		return true
+	}
+	for _, child := range box.Children {
+		if overText(child, x, y) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTextField reports whether node is a control edited as text
+func isTextField(node *dom.Node) bool {
+	switch node.Tag {
+	case "textarea":
+		return true
+	case "input":
+		return textInputTypes[strings.ToLower(node.GetAttr("type"))]
+	}
+	return false
+}
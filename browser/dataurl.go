@@ -0,0 +1,130 @@
+package browser
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go-browser/dom"
+)
+
+// =============================================================================
+// DATA AND ABOUT URLS
+// A data: URL carries its content in the address; it is a scheme like any
+// registered one, so HTML in it shows as a page, an image or text on its
+// own, and pages' images, stylesheets and scripts may use it. about:blank
+// is an empty page with a document scripts can use like any other.
+// =============================================================================
+
+// AboutBlank is the address of the empty page
+const AboutBlank = "about:blank"
+
+// blankPage is the HTML of about:blank
+const blankPage = "<html><head></head><body></body></html>"
+
+// serveData returns the content of a data: URL
+func serveData(u *url.URL) (string, []byte, error) {
+	return parseDataURL(u.String())
+}
+
+// parseDataURL returns the media type and content of a data: URL. A URL
+// without a type holds US-ASCII text; a # starts the fragment, not data.
+func parseDataURL(dataURL string) (string, []byte, error) {
+	if len(dataURL) < 5 || !strings.EqualFold(dataURL[:5], "data:") {
+		return "", nil, errors.New("not a data: URL")
+	}
+	dataURL, _, _ = strings.Cut(dataURL, "#")
+	header, payload, ok := strings.Cut(dataURL[5:], ",")
+	if !ok {
+		return "", nil, errors.New("data: URL without a comma")
+	}
+
+	header = strings.TrimSpace(header)
+	isBase64 := false
+	if i := strings.LastIndexByte(header, ';'); i >= 0 && strings.EqualFold(strings.TrimSpace(header[i+1:]), "base64") {
+		isBase64 = true
+		header = strings.TrimSpace(header[:i])
+	}
+	mediaType := header
+	switch {
+	case mediaType == "":
+		mediaType = "text/plain;charset=US-ASCII"
+	case strings.HasPrefix(mediaType, ";"):
+		mediaType = "text/plain" + mediaType
+	}
+
+	data := percentDecode(payload)
+	if !isBase64 {
+		return mediaType, data, nil
+	}
+	// Space is allowed anywhere in the base64, and padding may be left out
+	encoded := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f' {
+			return -1
+		}
+		return r
+	}, string(data))
+	decoded, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return "", nil, fmt.Errorf("data: URL: %w", err)
+	}
+	return mediaType, decoded, nil
+}
+
+// percentDecode decodes the %XX escapes of s, leaving a % that starts none
+// as it is
+func percentDecode(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) {
+			if b, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+				out = append(out, byte(b))
+				i += 2
+				continue
+			}
+		}
+		out = append(out, s[i])
+	}
+	return out
+}
+
+// loadAboutPage shows the about: page urlStr names
+func (t *Tab) loadAboutPage(urlStr string) {
+	t.BaseURL = urlStr
+	t.Security = nil
+	// Like a data: URL, about:blank may carry a query or fragment
+	name, _, _ := strings.Cut(strings.ToLower(urlStr), "#")
+	name, _, _ = strings.Cut(name, "?")
+	if name != AboutBlank {
+		t.ErrorMsg = "Unknown page: " + urlStr
+		return
+	}
+	t.ErrorMsg = ""
+	t.LoadContent(blankPage)
+}
+
+// imageDocument builds the page showing the image at imageURL on its own
+func imageDocument(imageURL string) *dom.Document {
+	doc := dom.ParseDocument(`<html><head><style>body { margin: 0; background: #202124; text-align: center; }</style></head><body></body></html>`)
+	doc.SetURL(imageURL)
+	if doc.Body != nil {
+		img := dom.NewElement("img")
+		img.SetAttr("src", imageURL)
+		doc.Body.AppendChild(img)
+	}
+	return doc
+}
+
+// textDocument builds the page showing text as written, at pageURL
+func textDocument(text, pageURL string) *dom.Document {
+	doc := dom.ParseDocument(`<html><head><style>body { margin: 8px; } pre { margin: 0; white-space: pre-wrap; }</style></head><body><pre></pre></body></html>`)
+	doc.SetURL(pageURL)
+	if pres := doc.Node.GetElementsByTagName("pre"); len(pres) > 0 {
+		// A text node rather than markup, so < and & show as written
+		pres[0].AppendChild(dom.NewText(text))
+	}
+	return doc
+}
@@ -0,0 +1,222 @@
+package browser
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+
+	"go-browser/css"
+	"go-browser/dom"
+	"go-browser/render"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// =============================================================================
+// DEVICE MODE
+// Ctrl+Shift+M shows the tab as a phone would. The page lays out in the
+// layout viewport its <meta name="viewport"> asks for and is drawn zoomed
+// into the device's screen; pages without one get the 980px viewport mobile
+// browsers assume, shrunk to fit the device's width.
+// =============================================================================
+
+// Device is a screen a tab can be emulated on, sized in CSS pixels
+type Device struct {
+	Name          string
+	Width, Height float64
+}
+
+// DefaultDevice is the phone device mode shows pages on
+var DefaultDevice = Device{Name: "Phone", Width: 375, Height: 667}
+
+// legacyViewportWidth is the layout width mobile browsers give pages that
+// declare no viewport
+const legacyViewportWidth = 980.0
+
+// deviceMargin is the space kept around the device's screen
+const deviceMargin = 24.0
+
+var ColorDeviceBackdrop = color.RGBA{60, 62, 68, 255}
+
+// viewport is the layout viewport of a page on a device and the zoom from
+// its CSS pixels to the device's
+type viewport struct {
+	width, zoom float64
+}
+
+// parseViewport reads the content of <meta name="viewport">, such as
+// "width=device-width, initial-scale=1", for a device deviceWidth wide
+func parseViewport(content string, deviceWidth float64) viewport {
+	props := map[string]string{}
+	for _, item := range strings.FieldsFunc(content, func(r rune) bool { return r == ',' || r == ';' }) {
+		key, value, _ := strings.Cut(item, "=")
+		props[strings.ToLower(strings.TrimSpace(key))] = strings.ToLower(strings.TrimSpace(value))
+	}
+	number := func(key string) float64 {
+		n, err := strconv.ParseFloat(props[key], 64)
+		if err != nil || n <= 0 {
+			return 0
+		}
+		return n
+	}
+
+	width := number("width")
+	if props["width"] == "device-width" {
+		width = deviceWidth
+	}
+	width = min(width, 10000)
+	scale := number("initial-scale")
+	switch {
+	case width == 0 && scale == 0:
+		width = legacyViewportWidth
+	case width == 0:
+		width = deviceWidth / scale
+	case scale > 0:
+		// The viewport is never narrower than the device at that zoom
+		width = max(width, deviceWidth/scale)
+	}
+
+	zoom := scale
+	if zoom == 0 {
+		zoom = deviceWidth / width
+	}
+	if s := number("minimum-scale"); s > 0 {
+		zoom = max(zoom, s)
+	}
+	if s := number("maximum-scale"); s > 0 {
+		zoom = min(zoom, s)
+	}
+	return viewport{width: width, zoom: min(max(zoom, 0.1), 10)}
+}
+
+// pageMedia returns the medium the page is shown on: the viewport it lays
+// out in, on the window's screen, in the user's color scheme
+func (t *Tab) pageMedia() css.Media {
+	m := css.Media{
+		Type:        "screen",
+		PixelRatio:  ebiten.Monitor().DeviceScaleFactor(),
+		ColorScheme: DefaultColorScheme,
+	}
+	m.Width, m.Height = t.viewportSize()
+	if t.printPreview {
+		m.Type = "print"
+	}
+	if t.Settings != nil && t.Settings.ColorScheme == "dark" {
+		m.ColorScheme = "dark"
+	}
+	return m
+}
+
+// restyleForMedia styles the page again when the medium it is shown on is
+// no longer the one it was styled for, and reports whether it did. Its
+// vw and vh units, media queries and srcset then follow the viewport it
+// lays out in, on the window's screen.
+func (t *Tab) restyleForMedia() bool {
+	return t.restyleFor(t.pageMedia())
+}
+
+// restyleFor styles the page for m unless it already is, and reports whether
+// it did
+func (t *Tab) restyleFor(m css.Media) bool {
+	if t.Document == nil || m == t.styledMedia {
+		return false
+	}
+	t.styledMedia = m
+	t.canvases.setViewport(m.Viewport)
+	css.ApplyStylesToTree(t.Document.Node, t.Stylesheets, m)
+	return true
+}
+
+// updateViewport works out the page's layout viewport on the tab's device
+func (t *Tab) updateViewport() {
+	t.viewport = t.viewportOf(t.Document)
+}
+
+// viewportOf works out the layout viewport of doc, which may be nil, on the
+// tab's device
+func (t *Tab) viewportOf(doc *dom.Document) viewport {
+	if t.device == nil {
+		return viewport{}
+	}
+	content := ""
+	if doc != nil {
+		content, _ = doc.Meta("viewport")
+	}
+	return parseViewport(content, t.device.Width)
+}
+
+// toggleDevice turns device mode on or off for the tab and lays the page
+// out again in its new viewport
+func (t *Tab) toggleDevice() {
+	if t.device == nil {
+		device := DefaultDevice
+		t.device = &device
+	} else {
+		t.device = nil
+	}
+	t.updateViewport()
+	if t.Document != nil && len(t.frames) == 0 {
+		t.relayout()
+		t.jumpTo(t.ScrollY)
+	}
+}
+
+// deviceScreen returns where the device's screen sits in the window: centered
+// under the chrome, and shortened to fit the window if the device is taller
+func deviceScreen(d *Device) (x, y, w, h float64) {
+	w = min(d.Width, WindowWidth-deviceMargin*2)
+	h = min(d.Height, WindowHeight-ChromeHeight-deviceMargin*2)
+	return math.Round((WindowWidth - w) / 2), ChromeHeight + deviceMargin, w, h
+}
+
+// devicePageSize returns the size of the part of the page the device shows
+func (t *Tab) devicePageSize() (float64, float64) {
+	_, _, w, h := deviceScreen(t.device)
+	return w / t.viewport.zoom, h / t.viewport.zoom
+}
+
+// pagePoint returns the page point under a screen point in the content area
+func (a *App) pagePoint(mx, my int) (float64, float64) {
+	if a.device != nil {
+		x, y, _, _ := deviceScreen(a.device)
+		zoom := a.viewport.zoom
+		return (float64(mx)-x)/zoom - Padding, (float64(my)-y)/zoom - (ContentTop - ChromeHeight) - a.ScrollY
+	}
+	return float64(mx) - Padding, float64(my) - ContentTop - a.ScrollY
+}
+
+// drawDevice draws the page zoomed into the device's screen. The page is
+// rendered at its own scale below a strip as tall as the chrome, so that
+// fixed boxes and text clipping line up as they do in the window.
+func (a *App) drawDevice(screen *ebiten.Image) {
+	x, y, w, h := deviceScreen(a.device)
+	vector.DrawFilledRect(screen, 0, ChromeHeight, WindowWidth, WindowHeight-ChromeHeight, ColorDeviceBackdrop, false)
+
+	pw, ph := a.devicePageSize()
+	iw, ih := int(math.Ceil(pw)), int(math.Ceil(ChromeHeight+ph))
+	if a.deviceImage == nil || a.deviceImage.Bounds().Dx() != iw || a.deviceImage.Bounds().Dy() != ih {
+		if a.deviceImage != nil {
+			a.deviceImage.Deallocate()
+		}
+		a.deviceImage = ebiten.NewImage(iw, ih)
+	}
+	a.deviceImage.Fill(a.getPageBackground())
+	a.renderNode(a.deviceImage, a.RenderTree, Padding, ContentTop+a.ScrollY)
+	if a.FormState.SelectOpen != "" {
+		a.renderSelectOverlay(a.deviceImage, a.RenderTree, Padding, ContentTop+a.ScrollY)
+	}
+
+	page := a.deviceImage.SubImage(image.Rect(0, int(ChromeHeight), iw, ih)).(*ebiten.Image)
+	op := &ebiten.DrawImageOptions{Filter: ebiten.FilterLinear}
+	op.GeoM.Scale(a.viewport.zoom, a.viewport.zoom)
+	op.GeoM.Translate(x, y)
+	screen.SubImage(image.Rect(int(x), int(y), int(x+w), int(y+h))).(*ebiten.Image).DrawImage(page, op)
+	vector.StrokeRect(screen, float32(x)-0.5, float32(y)-0.5, float32(w)+1, float32(h)+1, 1, ColorBorder, false)
+
+	label := fmt.Sprintf("%s %.0f×%.0f · viewport %.0fpx · %.0f%%", a.device.Name, a.device.Width, a.device.Height, a.viewport.width, a.viewport.zoom*100)
+	render.DrawText(screen, label, x, y-8, FontSizeUI, ColorDevToolsText)
+}
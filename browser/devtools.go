@@ -0,0 +1,89 @@
+package browser
+
+import (
+	"image/color"
+
+	"go-browser/a11y"
+	"go-browser/dom"
+	"go-browser/render"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// =============================================================================
+// DEVTOOLS: ACCESSIBILITY PANEL
+// F12 toggles a side panel listing the page's accessibility tree
+// =============================================================================
+
+const (
+	DevToolsWidth      = 380.0
+	DevToolsLineHeight = 18.0
+)
+
+var (
+	ColorDevToolsBg     = color.RGBA{32, 33, 38, 245}
+	ColorDevToolsText   = color.RGBA{220, 220, 228, 255}
+	ColorDevToolsHeader = color.RGBA{130, 180, 255, 255}
+)
+
+// DevTools holds the state of the devtools panel
+type DevTools struct {
+	Visible bool
+	ScrollY float64
+
+	// Cached tree dump, rebuilt when the document changes
+	lines   []string
+	builtOn *dom.Node
+	builtAt int // the tab's changes count the dump was built at
+}
+
+// devToolsContains reports whether screen x falls inside the open panel
+func (a *App) devToolsContains(x int) bool {
+	return a.DevTools.Visible && float64(x) >= WindowWidth-DevToolsWidth
+}
+
+// accessibilityLines returns the text dump of the current accessibility tree
+func (a *App) accessibilityLines() []string {
+	if a.DevTools.builtOn != a.root() || a.DevTools.builtAt != a.changes || a.DevTools.lines == nil {
+		checked := formValues{a.FormState}.Checked
+		a.DevTools.lines = a11y.BuildWith(a.root(), checked).Lines()
+		a.DevTools.builtOn = a.root()
+		a.DevTools.builtAt = a.changes
+	}
+	return a.DevTools.lines
+}
+
+// drawDevTools renders the accessibility panel on the right side of the content area
+func (a *App) drawDevTools(screen *ebiten.Image) {
+	if !a.DevTools.Visible {
+		return
+	}
+
+	x := float32(WindowWidth - DevToolsWidth)
+	y := float32(ChromeHeight)
+	vector.DrawFilledRect(screen, x, y, DevToolsWidth, WindowHeight-ChromeHeight, ColorDevToolsBg, false)
+	render.DrawText(screen, "Accessibility tree", float64(x)+12, float64(y)+22, FontSizeUI, ColorDevToolsHeader)
+
+	if a.Document == nil {
+		return
+	}
+
+	top := float64(y) + 40
+	maxLines := int((WindowHeight - top) / DevToolsLineHeight)
+	lines := a.accessibilityLines()
+
+	first := int(-a.DevTools.ScrollY / DevToolsLineHeight)
+	if first > len(lines)-maxLines {
+		first = len(lines) - maxLines
+	}
+	if first < 0 {
+		first = 0
+	}
+	a.DevTools.ScrollY = -float64(first) * DevToolsLineHeight
+
+	for i := 0; i < maxLines && first+i < len(lines); i++ {
+		line := render.TruncateText(lines[first+i], DevToolsWidth-24, 12)
+		render.DrawText(screen, line, float64(x)+12, top+float64(i)*DevToolsLineHeight+12, 12, ColorDevToolsText)
+	}
+}
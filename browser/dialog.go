@@ -0,0 +1,151 @@
+package browser
+
+import (
+	"image/color"
+
+	"go-browser/css"
+	"go-browser/dom"
+	"go-browser/layout"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// =============================================================================
+// DETAILS AND DIALOGS
+// Clicking a <summary> opens or closes its <details>. A dialog a page opens
+// with showModal() is laid out on its own and drawn centered over a backdrop
+// dimming the page; while it is open only it takes clicks, and Escape
+// cancels it.
+// =============================================================================
+
+// Modal dialog metrics
+const (
+	dialogMaxWidth = 560 // width of a dialog whose CSS width is auto
+	dialogMargin   = 32  // space kept between a dialog and the page's edges
+)
+
+// ColorDialogBackdrop dims the page behind a modal dialog
+var ColorDialogBackdrop = color.RGBA{0, 0, 0, 100}
+
+// layoutDialog lays out the tab's modal dialog
+func (t *Tab) layoutDialog() {
+	if t.modalDialog == nil {
+		t.dialogTree = nil
+		return
+	}
+	width := float64(WindowWidth - 2*dialogMargin)
+	if cs := styleOf(t.modalDialog); cs == nil || cs.Width.IsAuto() {
+		width = min(width, dialogMaxWidth)
+	}
+	t.dialogTree = layout.BuildRenderTree(t.modalDialog, width, t.userColumnWidths)
+}
+
+// setModalDialog shows node as the modal dialog, or none for nil. The page's
+// engine calls it when the topmost modal dialog changes.
+func (t *Tab) setModalDialog(node *dom.Node) {
+	t.modalDialog = node
+	if t.Document != nil {
+		t.relayout()
+	}
+}
+
+// dialogOrigin returns where the modal dialog is drawn on the window
+func (a *App) dialogOrigin() (x, y float64) {
+	x = (WindowWidth - a.dialogTree.W) / 2
+	y = ChromeHeight + max(dialogMargin, (WindowHeight-ChromeHeight-a.dialogTree.H)/2)
+	return x, y
+}
+
+// handleDialogKeys cancels the modal dialog on Escape. It returns true when
+// it used the key.
+func (a *App) handleDialogKeys() bool {
+	if a.dialogTree == nil || a.JSEngine == nil || !inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		return false
+	}
+	a.JSEngine.CancelDialog()
+	return true
+}
+
+// handleDialogClick hands a click to the modal dialog; clicks on the
+// backdrop do nothing
+func (a *App) handleDialogClick(mx, my int) {
+	ox, oy := a.dialogOrigin()
+	x, y := float64(mx)-ox, float64(my)-oy
+	a.activate()
+	switch {
+	case a.handleFormClick(a.dialogTree, x, y):
+	case a.handleMediaClick(a.dialogTree, x, y):
+	case a.handleSummaryClick(a.dialogTree, x, y):
+	default:
+		if link := a.findLinkBox(a.dialogTree, x, y); link != nil {
+			a.followLink(link.LinkURL)
+		}
+	}
+}
+
+// drawDialog dims the page and draws the modal dialog over it
+func (a *App) drawDialog(screen *ebiten.Image) {
+	vector.DrawFilledRect(screen, 0, ChromeHeight, WindowWidth, WindowHeight-ChromeHeight, ColorDialogBackdrop, false)
+	x, y := a.dialogOrigin()
+	a.renderNode(screen, a.dialogTree, x, y)
+}
+
+// handleSummaryClick opens or closes the <details> whose summary is under
+// the point, in render tree coordinates. It returns true when there was
+// one.
+func (a *App) handleSummaryClick(tree *layout.RenderBox, x, y float64) bool {
+	box := findBoxAt(tree, x, y)
+	if box == nil {
+		return false
+	}
+	for node := box.Node; node != nil; node = node.Parent {
+		if node.Tag != "summary" || node.Parent == nil || node.Parent.Tag != "details" {
+			continue
+		}
+		details := node.Parent
+		if layout.DetailsSummary(details) != node {
+			return false
+		}
+		switch {
+		case a.JSEngine != nil:
+			a.JSEngine.ToggleDetails(details)
+		case details.HasAttr("open"):
+			details.RemoveAttr("open")
+			a.attributeChanged(details, "open", "", "")
+		default:
+			details.SetAttr("open", "")
+			a.attributeChanged(details, "open", "", "")
+		}
+		return true
+	}
+	return false
+}
+
+// drawSummaryMarker draws the disclosure triangle of a <summary> in its left
+// padding: pointing right while its <details> is closed, down when open
+func drawSummaryMarker(screen *ebiten.Image, box *layout.RenderBox, cs *css.ComputedStyle, x, y float64) {
+	if cs.ListStyleType == "none" {
+		return
+	}
+	open := box.Node.Parent != nil && box.Node.Parent.HasAttr("open")
+	size := float32(cs.FontSize * 0.5)
+	left := float32(x + cs.BorderLeftWidth + 4)
+	top := float32(y+cs.BorderTopWidth+cs.PaddingTop) + (float32(cs.FontSize)*1.2-size)/2
+
+	var path vector.Path
+	if open {
+		path.MoveTo(left, top)
+		path.LineTo(left+size, top)
+		path.LineTo(left+size/2, top+size)
+	} else {
+		path.MoveTo(left, top)
+		path.LineTo(left+size, top+size/2)
+		path.LineTo(left, top+size)
+	}
+	path.Close()
+	op := &vector.DrawPathOptions{AntiAlias: true}
+	op.ColorScale.ScaleWithColor(cs.Color)
+	vector.FillPath(screen, &path, nil, op)
+}
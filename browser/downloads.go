@@ -0,0 +1,226 @@
+package browser
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"go-browser/logging"
+	"go-browser/render"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// =============================================================================
+// DOWNLOADS
+// A response the browser cannot show as a page (an attachment, or anything
+// but text and markup) is saved to the download directory instead and the
+// tab keeps its page. With "ask_download_location" a prompt asks where to
+// save each file first: Enter saves, Escape cancels.
+// =============================================================================
+
+const savePromptWidth = 520.0
+
+// download is a response waiting for the user to choose where it goes
+type download struct {
+	name string      // file name the server suggested
+	path string      // where it would be saved without asking
+	dest chan string // receives the chosen path; "" cancels
+}
+
+// SavePrompt asks where to save a download
+type SavePrompt struct {
+	Visible bool
+	Path    string
+
+	pending *download
+}
+
+// isDownload reports whether resp is a file to save rather than a page
+func isDownload(resp *http.Response) bool {
+	if disposition, _, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && disposition == "attachment" {
+		return true
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"), strings.HasSuffix(mediaType, "xml"),
+		mediaType == "application/json", mediaType == "application/javascript":
+		return false
+	}
+	return true
+}
+
+// downloadName picks the file name for a download: the one in its
+// Content-Disposition, else the last segment of its URL
+func downloadName(resp *http.Response) string {
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil {
+		if name := filepath.Base(params["filename"]); params["filename"] != "" && name != "." && name != string(filepath.Separator) {
+			return name
+		}
+	}
+	if name := path.Base(resp.Request.URL.Path); name != "/" && name != "." {
+		return name
+	}
+	return "download"
+}
+
+// download saves resp's body, asking the user where first if the settings
+// say so. It runs on the loading goroutine and blocks until it is done.
+func (t *Tab) download(resp *http.Response) error {
+	name := downloadName(resp)
+	dest := filepath.Join(t.Settings.DownloadLocation(), name)
+	if t.Settings != nil && t.Settings.AskDownloadLocation {
+		if dest = t.askDestination(name, dest); dest == "" {
+			logging.App.Info("download cancelled", "file", name)
+			return nil
+		}
+	}
+	if err := saveDownload(resp.Body, dest); err != nil {
+		return fmt.Errorf("Download failed: %w", err)
+	}
+	logging.App.Info("downloaded", "url", resp.Request.URL, "path", dest)
+	return nil
+}
+
+// askDestination asks the user where to save the file name, suggesting
+// path, and returns their answer; "" when they cancel. It blocks until they
+// answer, so it runs off the main thread.
+func (t *Tab) askDestination(name, path string) string {
+	d := &download{name: name, path: path, dest: make(chan string, 1)}
+	select {
+	case t.downloads <- d:
+		return <-d.dest
+	default:
+		return ""
+	}
+}
+
+// saveDownload writes body to dest, creating its directory. A partial file
+// is removed when the transfer fails.
+func saveDownload(body io.Reader, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, body)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dest)
+	}
+	return err
+}
+
+// keepPage undoes what loading urlStr changed on the tab once it turned out
+// to be a download: the page, its address and history stay as they were.
+// A reload (urlStr is the page's own URL) added no history entry to drop.
+func (t *Tab) keepPage(urlStr, baseURL string, security *PageSecurity) {
+	if n := len(t.History); n > 0 && urlStr != baseURL && t.HistoryPos == n-1 && t.History[n-1] == urlStr {
+		t.History = t.History[:n-1]
+		t.HistoryPos--
+	}
+	t.URL = ""
+	if t.HistoryPos >= 0 {
+		t.URL = t.History[t.HistoryPos]
+	}
+	t.BaseURL, t.Security = baseURL, security
+}
+
+// cancelDownloads cancels the downloads still waiting to be asked about
+func (t *Tab) cancelDownloads() {
+	for {
+		select {
+		case d := <-t.downloads:
+			d.dest <- ""
+		default:
+			return
+		}
+	}
+}
+
+// askDownloads shows the save prompt for the next download waiting on any tab
+func (a *App) askDownloads() {
+	p := &a.SavePrompt
+	if p.Visible {
+		return
+	}
+	for _, tab := range a.Tabs {
+		select {
+		case d := <-tab.downloads:
+			p.Visible, p.Path, p.pending = true, d.path, d
+			a.NavBar.IsEditing = false
+			a.Find.Close()
+			return
+		default:
+		}
+	}
+}
+
+// answer closes the prompt, saving the download to path or cancelling it
+// for ""
+func (p *SavePrompt) answer(path string) {
+	p.pending.dest <- path
+	p.Visible, p.pending = false, nil
+}
+
+// handleSavePromptInput edits the path while the prompt is open. It
+// reports whether the prompt consumed the keyboard this frame.
+func (a *App) handleSavePromptInput() bool {
+	p := &a.SavePrompt
+	if !p.Visible {
+		return false
+	}
+	for _, r := range ebiten.AppendInputChars(nil) {
+		p.Path += string(r)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && p.Path != "" {
+		_, size := utf8.DecodeLastRuneInString(p.Path)
+		p.Path = p.Path[:len(p.Path)-size]
+	}
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		p.answer("")
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter) && strings.TrimSpace(p.Path) != "":
+		p.answer(strings.TrimSpace(p.Path))
+	}
+	return true
+}
+
+// drawSavePrompt renders the prompt under the chrome
+func (a *App) drawSavePrompt(screen *ebiten.Image) {
+	p := &a.SavePrompt
+	if !p.Visible {
+		return
+	}
+	x := float32((WindowWidth - savePromptWidth) / 2)
+	y := float32(ChromeHeight + 6)
+	h := float32(findBarHeight * 2)
+	render.DrawRoundedRect(screen, x, y, savePromptWidth, h, 6, ColorFindBar)
+
+	title := render.TruncateText("Save "+p.pending.name+" as (Enter saves, Esc cancels):", savePromptWidth-24, FontSizeUI)
+	render.DrawText(screen, title, float64(x)+12, float64(y)+findBarHeight/2-2, FontSizeUI, ColorTabTextMuted)
+
+	path := p.Path
+	if (a.frame/30)%2 == 0 {
+		path += "|"
+	}
+	render.DrawText(screen, render.TruncateText(path, savePromptWidth-24, FontSizeUI), float64(x)+12, float64(y)+findBarHeight*1.5-2, FontSizeUI, ColorFindText)
+}
@@ -0,0 +1,204 @@
+package browser
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+	"unicode/utf8"
+
+	"go-browser/layout"
+	"go-browser/render"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// =============================================================================
+// FIND IN PAGE
+// Ctrl+F opens a bar that highlights every case-insensitive match of its query
+// in the page text; Enter / Shift+Enter step through them, Escape closes it
+// =============================================================================
+
+const (
+	findBarWidth  = 320.0
+	findBarHeight = 36.0
+)
+
+var (
+	ColorFindBar     = color.RGBA{45, 45, 52, 245}
+	ColorFindText    = color.RGBA{230, 230, 235, 255}
+	ColorFindMatch   = color.RGBA{255, 235, 59, 140}
+	ColorFindCurrent = color.RGBA{255, 152, 0, 180}
+)
+
+// FindBar holds the find-in-page query and its matches
+type FindBar struct {
+	Visible bool
+	Query   string
+	Current int // index of the selected match
+
+	matches []findMatch
+	builtOn *layout.RenderBox // render tree the matches were computed on
+	builtQ  string
+}
+
+// findMatch is one occurrence of the query inside a text box
+type findMatch struct {
+	box  *layout.RenderBox
+	x, w float64 // horizontal extent relative to the box
+}
+
+// Open shows the find bar, keeping the previous query
+func (f *FindBar) Open() {
+	f.Visible = true
+}
+
+// Close hides the find bar and drops its highlights
+func (f *FindBar) Close() {
+	f.Visible = false
+	f.matches = nil
+	f.builtOn = nil
+}
+
+// handleFindInput edits the query while the bar is open. It reports whether
+// the bar consumed the keyboard this frame.
+func (a *App) handleFindInput() bool {
+	f := &a.Find
+	if !f.Visible {
+		return false
+	}
+
+	for _, r := range ebiten.AppendInputChars(nil) {
+		f.Query += string(r)
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && f.Query != "" {
+		_, size := utf8.DecodeLastRuneInString(f.Query)
+		f.Query = f.Query[:len(f.Query)-size]
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		f.Close()
+		return true
+	}
+
+	queryChanged := f.Query != f.builtQ
+	a.updateFindMatches()
+	if queryChanged {
+		f.Current = 0
+		a.scrollToFindMatch()
+	}
+
+	if inpututil.IsKeyJustPressed(ebiten.KeyEnter) && len(f.matches) > 0 {
+		if ebiten.IsKeyPressed(ebiten.KeyShift) {
+			f.Current = (f.Current + len(f.matches) - 1) % len(f.matches)
+		} else {
+			f.Current = (f.Current + 1) % len(f.matches)
+		}
+		a.scrollToFindMatch()
+	}
+	return true
+}
+
+// updateFindMatches recomputes the matches when the query or the page changed
+func (a *App) updateFindMatches() {
+	f := &a.Find
+	if f.builtOn == a.RenderTree && f.builtQ == f.Query {
+		return
+	}
+	f.matches = nil
+	f.builtOn, f.builtQ = a.RenderTree, f.Query
+	if f.Query != "" {
+		collectFindMatches(a.RenderTree, strings.ToLower(f.Query), &f.matches)
+	}
+	if f.Current >= len(f.matches) {
+		f.Current = 0
+	}
+}
+
+func collectFindMatches(box *layout.RenderBox, query string, out *[]findMatch) {
+	if box == nil {
+		return
+	}
+	if box.Text != "" {
+		size := box.FontSize
+		if size == 0 {
+			size = FontSizeBody
+		}
+		lower := strings.ToLower(box.Text)
+		for from := 0; ; {
+			idx := strings.Index(lower[from:], query)
+			if idx == -1 {
+				break
+			}
+			start := from + idx
+			m := findMatch{box: box, w: box.W}
+			// Lowercasing can change byte offsets; then highlight the whole box
+			if len(lower) == len(box.Text) {
+				m.x = render.MeasureText(box.Text[:start], size)
+				m.w = render.MeasureText(box.Text[start:start+len(query)], size)
+			}
+			*out = append(*out, m)
+			from = start + len(query)
+		}
+	}
+	for _, child := range box.Children {
+		collectFindMatches(child, query, out)
+	}
+}
+
+// scrollToFindMatch scrolls the selected match into view
+func (a *App) scrollToFindMatch() {
+	f := &a.Find
+	if f.Current >= len(f.matches) {
+		return
+	}
+	box := f.matches[f.Current].box
+	viewH := float64(WindowHeight) - ContentTop
+	top := box.Y + a.ScrollY
+	if top < findBarHeight || top+box.H > viewH {
+		a.scrollTo(viewH/3 - box.Y)
+	}
+}
+
+// drawFindHighlights paints the matches over the page text
+func (a *App) drawFindHighlights(screen *ebiten.Image) {
+	f := &a.Find
+	if !f.Visible {
+		return
+	}
+	a.updateFindMatches()
+	for i, m := range f.matches {
+		clr := ColorFindMatch
+		if i == f.Current {
+			clr = ColorFindCurrent
+		}
+		x := Padding + m.box.X + m.x
+		y := ContentTop + a.ScrollY + m.box.Y
+		vector.DrawFilledRect(screen, float32(x), float32(y), float32(m.w), float32(m.box.H), clr, false)
+	}
+}
+
+// drawFindBar renders the query box at the top right of the page
+func (a *App) drawFindBar(screen *ebiten.Image) {
+	f := &a.Find
+	if !f.Visible {
+		return
+	}
+	x := float32(WindowWidth - findBarWidth - 12)
+	y := float32(ChromeHeight + 6)
+	render.DrawRoundedRect(screen, x, y, findBarWidth, findBarHeight, 6, ColorFindBar)
+
+	query := f.Query
+	if (a.frame/30)%2 == 0 {
+		query += "|"
+	}
+	render.DrawText(screen, render.TruncateText(query, findBarWidth-110, FontSizeUI), float64(x)+12, float64(y)+findBarHeight/2-2, FontSizeUI, ColorFindText)
+
+	count := "No results"
+	if len(f.matches) > 0 {
+		count = fmt.Sprintf("%d of %d", f.Current+1, len(f.matches))
+	} else if f.Query == "" {
+		count = ""
+	}
+	render.DrawText(screen, count, float64(x)+findBarWidth-90, float64(y)+findBarHeight/2-2, 12, ColorTabTextMuted)
+}
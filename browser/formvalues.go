@@ -0,0 +1,65 @@
+package browser
+
+import (
+	"go-browser/dom"
+	"go-browser/gocko/forms"
+)
+
+// =============================================================================
+// FORM VALUES FOR SCRIPTS
+// A page's scripts read input.value, textarea.value and select.value from
+// what the user typed or picked, and setting them changes what the control
+// shows. Checkboxes and radios keep their checked state apart, so their value
+// stays the attribute's.
+// =============================================================================
+
+// formValues is the tab's form state as the page's engine sees it
+type formValues struct {
+	state *forms.FormState
+}
+
+func (f formValues) FormValue(node *dom.Node) (string, bool) {
+	if !holdsFormValue(node) {
+		return "", false
+	}
+	value, ok := f.state.Values[forms.GetElementID(node)]
+	return value, ok
+}
+
+// SetFormValue replaces node's value; a focused field's caret moves to the
+// end, as in other browsers
+func (f formValues) SetFormValue(node *dom.Node, value string) {
+	if !holdsFormValue(node) {
+		return
+	}
+	id := forms.GetElementID(node)
+	f.state.SetValue(id, value)
+	if f.state.IsFocused(id) {
+		f.state.SetFocus(id)
+	}
+}
+
+// Checked reports the checked state the user gave a checkbox or radio, for
+// the accessibility tree; ok is false for controls not clicked yet
+func (f formValues) Checked(node *dom.Node) (checked, ok bool) {
+	if node.Tag != "input" {
+		return false, false
+	}
+	switch node.GetAttr("type") {
+	case "checkbox", "radio":
+		checked, ok = f.state.CheckedState[forms.GetElementID(node)]
+	}
+	return checked, ok
+}
+
+// holdsFormValue reports whether the form state holds node's value
+func holdsFormValue(node *dom.Node) bool {
+	if node.Tag != "input" {
+		return node.Tag == "textarea" || node.Tag == "select"
+	}
+	switch node.GetAttr("type") {
+	case "checkbox", "radio", "submit", "button", "reset", "image", "hidden":
+		return false
+	}
+	return true
+}
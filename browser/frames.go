@@ -0,0 +1,274 @@
+package browser
+
+import (
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"go-browser/dom"
+	"go-browser/render"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// =============================================================================
+// FRAMES
+// A <frameset> document splits the page area by its rows and cols lists.
+// Each <frame> is a nested tab of its own that loads, lays out and scrolls
+// within its rectangle, and may hold a frameset in turn. Links in a frame
+// navigate it, the frame their target names, or the whole tab for _top.
+// =============================================================================
+
+// frame is one <frame> of a frameset document: its rectangle in the area of
+// the tab holding it and the nested tab showing its page
+type frame struct {
+	node       *dom.Node
+	x, y, w, h float64
+	tab        *Tab
+}
+
+// isFrameset reports whether the document is a frameset document
+func (t *Tab) isFrameset() bool {
+	return t.Document != nil && t.Document.Body != nil && t.Document.Body.Tag == "frameset"
+}
+
+// loadFrames lays out the frames of a frameset document over the page area
+// and starts loading each one's page
+func (t *Tab) loadFrames() {
+	for _, f := range t.frames {
+		f.tab.unload()
+	}
+	t.frames = nil
+	if !t.isFrameset() {
+		return
+	}
+	w, h := float64(WindowWidth), float64(WindowHeight-ChromeHeight)
+	if t.frameW > 0 {
+		w, h = t.frameW, t.frameH
+	}
+	t.frames = layoutFrameset(t.Document.Body, 0, 0, w, h, nil)
+	for _, f := range t.frames {
+		f.tab = newTab(t.Settings, t.browser)
+		f.tab.frameW, f.tab.frameH = f.w, f.h
+		if src := strings.TrimSpace(f.node.GetAttr("src")); src != "" {
+			f.tab.Navigate(t.resolveLink(src))
+		}
+	}
+}
+
+// layoutFrameset shares a frameset's rectangle among its frames and nested
+// framesets, row by row, and appends the frames to out
+func layoutFrameset(set *dom.Node, x, y, w, h float64, out []*frame) []*frame {
+	rows := frameSizes(set.GetAttr("rows"), h)
+	cols := frameSizes(set.GetAttr("cols"), w)
+	cell := 0
+	for _, child := range set.Children {
+		if child.Tag != "frame" && child.Tag != "frameset" {
+			continue
+		}
+		if cell >= len(rows)*len(cols) {
+			break
+		}
+		r, c := cell/len(cols), cell%len(cols)
+		cx, cy := x, y
+		for _, cw := range cols[:c] {
+			cx += cw
+		}
+		for _, rh := range rows[:r] {
+			cy += rh
+		}
+		if child.Tag == "frameset" {
+			out = layoutFrameset(child, cx, cy, cols[c], rows[r], out)
+		} else {
+			out = append(out, &frame{node: child, x: cx, y: cy, w: cols[c], h: rows[r]})
+		}
+		cell++
+	}
+	return out
+}
+
+// frameSizes splits total among the entries of a rows or cols list: pixels,
+// percentages of total, and "*" or "2*" shares of what those leave
+func frameSizes(spec string, total float64) []float64 {
+	parts := strings.Split(spec, ",")
+	if strings.TrimSpace(spec) == "" {
+		parts = []string{"*"}
+	}
+	sizes := make([]float64, len(parts))
+	shares := make([]float64, len(parts))
+	fixed, shared := 0.0, 0.0
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if n, ok := strings.CutSuffix(part, "*"); ok {
+			shares[i] = 1
+			if v, err := strconv.ParseFloat(n, 64); err == nil && v > 0 {
+				shares[i] = v
+			}
+		} else if n, ok := strings.CutSuffix(part, "%"); ok {
+			v, _ := strconv.ParseFloat(n, 64)
+			sizes[i] = max(v, 0) / 100 * total
+		} else if v, err := strconv.ParseFloat(part, 64); err == nil {
+			sizes[i] = max(v, 0)
+		} else {
+			shares[i] = 1
+		}
+		fixed += sizes[i]
+		shared += shares[i]
+	}
+
+	switch {
+	case shared > 0:
+		// Shares get the space left, and fixed sizes shrink if there is none
+		if fixed > total {
+			scaleSizes(sizes, total/fixed)
+			fixed = total
+		}
+		for i := range sizes {
+			if shares[i] > 0 {
+				sizes[i] = (total - fixed) * shares[i] / shared
+			}
+		}
+	case fixed > 0:
+		// Without shares the fixed sizes stretch or shrink to fill total
+		scaleSizes(sizes, total/fixed)
+	}
+	return sizes
+}
+
+// scaleSizes multiplies every size by f
+func scaleSizes(sizes []float64, f float64) {
+	for i := range sizes {
+		sizes[i] *= f
+	}
+}
+
+// frameAt returns the innermost frame under a point of the tab's area, and
+// the point in that frame's page coordinates
+func (t *Tab) frameAt(x, y float64) (*frame, float64, float64) {
+	for _, f := range t.frames {
+		if x < f.x || x >= f.x+f.w || y < f.y || y >= f.y+f.h {
+			continue
+		}
+		if len(f.tab.frames) > 0 {
+			return f.tab.frameAt(x-f.x, y-f.y)
+		}
+		return f, x - f.x - Padding, y - f.y - Padding - f.tab.ScrollY
+	}
+	return nil, 0, 0
+}
+
+// screenFrameAt returns the frame under a screen point and the point in its
+// page coordinates
+func (a *App) screenFrameAt(mx, my int) (*frame, float64, float64) {
+	return a.frameAt(float64(mx), float64(my)-ChromeHeight)
+}
+
+// namedFrame returns the frame, at any depth, with the given name attribute
+func (t *Tab) namedFrame(name string) *frame {
+	for _, f := range t.frames {
+		if f.node.GetAttr("name") == name {
+			return f
+		}
+		if named := f.tab.namedFrame(name); named != nil {
+			return named
+		}
+	}
+	return nil
+}
+
+// handleFrameClick follows a link clicked at a point of the tab's frame area
+func (a *App) handleFrameClick(areaX, areaY float64) {
+	f, x, y := a.frameAt(areaX, areaY)
+	if f == nil || f.tab.RenderTree == nil {
+		return
+	}
+	f.tab.activate()
+	link := a.findLinkBox(f.tab.RenderTree, x, y)
+	if link == nil {
+		return
+	}
+	target := ""
+	if link.LinkNode != nil {
+		target = strings.TrimSpace(link.LinkNode.GetAttr("target"))
+	}
+	switch strings.ToLower(target) {
+	case "_top", "_parent":
+		a.Navigate(f.tab.resolveLink(link.LinkURL))
+	case "_blank":
+		a.OpenInBackgroundTab(f.tab.resolveLink(link.LinkURL))
+	case "", "_self":
+		f.tab.followLink(link.LinkURL)
+	default:
+		if named := a.namedFrame(target); named != nil {
+			named.tab.Navigate(f.tab.resolveLink(link.LinkURL))
+		} else {
+			a.OpenInBackgroundTab(f.tab.resolveLink(link.LinkURL))
+		}
+	}
+}
+
+// frameCursor returns the cursor shape over a point of the tab's frame area:
+// a pointer over links
+func (a *App) frameCursor(areaX, areaY float64) ebiten.CursorShapeType {
+	f, x, y := a.frameAt(areaX, areaY)
+	if f == nil || f.tab.RenderTree == nil {
+		return ebiten.CursorShapeDefault
+	}
+	if a.findClickedLink(f.tab.RenderTree, x, y) != "" {
+		return ebiten.CursorShapePointer
+	}
+	return ebiten.CursorShapeDefault
+}
+
+// stepFrames advances the layout and scroll animation of every frame
+func (t *Tab) stepFrames() {
+	for _, f := range t.frames {
+		f.tab.stepLayout()
+		f.tab.stepMutations()
+		f.tab.stepScroll()
+		f.tab.stepMedia()
+		f.tab.stepFrames()
+	}
+}
+
+// drawFrames draws the page of each of t's frames clipped to its rectangle,
+// t's area starting at screen point (ox, oy). A line outlines each frame
+// unless frameborder="0" turns it off.
+func (a *App) drawFrames(screen *ebiten.Image, t *Tab, ox, oy float64) {
+	for _, f := range t.frames {
+		x, y := ox+f.x, oy+f.y
+		clip := image.Rect(int(x), int(y), int(x+f.w), int(y+f.h))
+		sub := screen.SubImage(clip).(*ebiten.Image)
+		switch {
+		case len(f.tab.frames) > 0:
+			a.drawFrames(sub, f.tab, x, y)
+		case f.tab.IsLoading:
+			drawFrameMessage(sub, "Loading...", x, y, ColorTextMuted)
+		case f.tab.ErrorMsg != "":
+			drawFrameMessage(sub, "Error: "+f.tab.ErrorMsg, x, y, color.RGBA{255, 100, 100, 255})
+		case f.tab.RenderTree != nil:
+			a.renderNode(sub, f.tab.RenderTree, x+Padding, y+Padding+f.tab.ScrollY)
+		}
+		if frameBorder(f.node) {
+			vector.StrokeRect(screen, float32(x)+0.5, float32(y)+0.5, float32(f.w)-1, float32(f.h)-1, 1, ColorBorder, false)
+		}
+	}
+}
+
+// drawFrameMessage shows a loading or error message in a frame
+func drawFrameMessage(screen *ebiten.Image, msg string, x, y float64, c color.Color) {
+	render.DrawText(screen, msg, x+Padding, y+Padding+10, FontSizeBody, c)
+}
+
+// frameBorder reports whether a frame is drawn with a border: frameborder
+// on the frame or its nearest frameset saying otherwise turns it off
+func frameBorder(node *dom.Node) bool {
+	for n := node; n != nil && (n.Tag == "frame" || n.Tag == "frameset"); n = n.Parent {
+		if v := strings.TrimSpace(n.GetAttr("frameborder")); v != "" {
+			return v != "0" && !strings.EqualFold(v, "no")
+		}
+	}
+	return true
+}
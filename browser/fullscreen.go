@@ -0,0 +1,109 @@
+package browser
+
+import (
+	"go-browser/dom"
+	"go-browser/layout"
+	"go-browser/render"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// =============================================================================
+// FULLSCREEN
+// F11 makes the OS window fullscreen. A page's element.requestFullscreen()
+// goes further: the element is laid out over the whole window, hiding the
+// chrome, until Escape, F11, navigation or a tab switch ends it.
+// =============================================================================
+
+// fullscreenHintFrames is how long the "press Esc" hint stays up, in ticks
+const fullscreenHintFrames = 180
+
+// layoutFullscreen lays out the tab's fullscreen element at the window's size
+func (t *Tab) layoutFullscreen() {
+	if t.fullscreen == nil {
+		t.fullscreenTree = nil
+		return
+	}
+	t.fullscreenTree = layout.BuildRenderTree(t.fullscreen, WindowWidth, t.userColumnWidths)
+}
+
+// setFullscreen shows node fullscreen, or leaves fullscreen for nil. The
+// page's engine calls it after running its fullscreen algorithm.
+func (t *Tab) setFullscreen(node *dom.Node) {
+	t.fullscreen = node
+	t.layoutFullscreen()
+}
+
+// exitFullscreen takes the tab out of element fullscreen, telling the page
+func (t *Tab) exitFullscreen() {
+	if t.fullscreen == nil {
+		return
+	}
+	if t.JSEngine != nil && t.JSEngine.FullscreenElement() != nil {
+		t.JSEngine.ExitFullscreen()
+	}
+	t.setFullscreen(nil)
+}
+
+// toggleFullscreen is F11: it ends element fullscreen if a page is in it,
+// and otherwise switches the OS window in or out of fullscreen
+func (a *App) toggleFullscreen() {
+	if a.fullscreen != nil {
+		a.exitFullscreen()
+		return
+	}
+	a.windowFullscreen = !a.windowFullscreen
+}
+
+// syncFullscreen makes the OS window fullscreen while F11 or a page asks
+// for it, and notes when an element went fullscreen for the hint
+func (a *App) syncFullscreen() {
+	if want := a.windowFullscreen || a.fullscreen != nil; want != a.osFullscreen {
+		ebiten.SetFullscreen(want)
+		a.osFullscreen = want
+	}
+	if a.fullscreen != nil && a.fullscreen != a.hintedFullscreen {
+		a.fullscreenSince = a.frame
+	}
+	a.hintedFullscreen = a.fullscreen
+}
+
+// handleFullscreenKeys ends element fullscreen on Escape. It returns true
+// when the key was consumed.
+func (a *App) handleFullscreenKeys() bool {
+	if a.fullscreen == nil || !inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
+		return false
+	}
+	a.exitFullscreen()
+	return true
+}
+
+// handleFullscreenClick handles a click on the fullscreen element: its
+// controls and scripts get it, and a link leaves fullscreen to open
+func (a *App) handleFullscreenClick(mx, my int) {
+	x, y := float64(mx), float64(my)
+	a.activate()
+	if a.handleFormClick(a.fullscreenTree, x, y) {
+		return
+	}
+	if link := a.findLinkBox(a.fullscreenTree, x, y); link != nil {
+		a.exitFullscreen()
+		a.followLink(link.LinkURL)
+	}
+}
+
+// drawFullscreen draws the fullscreen element over the whole window, on the
+// page's background, with a hint on how to leave for the first seconds
+func (a *App) drawFullscreen(screen *ebiten.Image) {
+	screen.Fill(a.getPageBackground())
+	a.renderNode(screen, a.fullscreenTree, 0, 0)
+
+	if a.frame-a.fullscreenSince < fullscreenHintFrames {
+		const hint = "Press Esc to exit full screen"
+		w, h := float32(260), float32(36)
+		x := (float32(WindowWidth) - w) / 2
+		render.DrawRoundedRect(screen, x, 24, w, h, 8, ColorFindBar)
+		render.DrawTextCentered(screen, hint, WindowWidth/2, 24+(float64(h)-FontSizeUI)/2, FontSizeUI, ColorFindText)
+	}
+}
@@ -0,0 +1,58 @@
+package browser
+
+import (
+	"image/color"
+
+	"go-browser/layout"
+	"go-browser/render"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// =============================================================================
+// GAUGES
+// A <progress> fills its track with the accent color; without a value it is
+// indeterminate and a segment slides along it. A <meter> is green while its
+// value is in the optimum region, yellow next to it and red beyond.
+// =============================================================================
+
+// Gauge colors
+var (
+	ColorGaugeTrack  = color.RGBA{228, 228, 232, 255}
+	ColorGaugeBorder = color.RGBA{170, 170, 178, 255}
+	ColorMeterLevels = [3]color.RGBA{
+		{56, 142, 60, 255},  // optimum
+		{251, 192, 45, 255}, // suboptimal
+		{211, 47, 47, 255},  // even less good
+	}
+)
+
+// gaugeSlideFrames is how long the indeterminate segment takes to cross a
+// <progress>, in ticks
+const gaugeSlideFrames = 90
+
+// drawGauge draws a <progress> or <meter> box at x, y
+func (a *App) drawGauge(screen *ebiten.Image, box *layout.RenderBox, x, y float64) {
+	fade := func(c color.RGBA) color.RGBA { return render.Fade(c, box.Opacity) }
+	gauge := layout.GaugeOf(box.Node)
+	bx, by, bw, bh := float32(x), float32(y), float32(box.W), float32(box.H)
+
+	vector.FillRect(screen, bx, by, bw, bh, fade(ColorGaugeTrack), false)
+	switch {
+	case box.Node.Tag == "meter":
+		fill := bw * float32(gauge.Fraction())
+		vector.FillRect(screen, bx, by, fill, bh, fade(ColorMeterLevels[gauge.Level()]), false)
+	case gauge.Indeterminate:
+		// A segment a third of the track wide slides across and wraps
+		seg := bw / 3
+		pos := float32(a.frame%gaugeSlideFrames)/gaugeSlideFrames*(bw+seg) - seg
+		left, right := max(pos, 0), min(pos+seg, bw)
+		if right > left {
+			vector.FillRect(screen, bx+left, by, right-left, bh, fade(ColorAccent), false)
+		}
+	default:
+		vector.FillRect(screen, bx, by, bw*float32(gauge.Fraction()), bh, fade(ColorAccent), false)
+	}
+	vector.StrokeRect(screen, bx, by, bw, bh, 1, fade(ColorGaugeBorder), false)
+}
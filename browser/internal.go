@@ -0,0 +1,253 @@
+package browser
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"runtime"
+	"strings"
+	"time"
+
+	"go-browser/conformance"
+	"go-browser/perf"
+	"go-browser/spidergopher"
+)
+
+// =============================================================================
+// INTERNAL PAGES
+// gobrowser:// addresses are pages the browser writes itself, such as
+// gobrowser://api listing the scripting API, gobrowser://conformance
+// scoring what the engines support, gobrowser://wpt running the curated
+// Web Platform Tests, gobrowser://memory reporting what the browser holds in
+// memory and gobrowser://timings how long pages take to show
+// =============================================================================
+
+// InternalScheme starts the address of every internal page
+const InternalScheme = "gobrowser://"
+
+// internalPages writes each internal page's HTML, keyed by its name
+var internalPages = map[string]func(t *Tab) string{
+	"api":         apiPage,
+	"conformance": conformancePage,
+	"memory":      memoryPage,
+	"timings":     timingsPage,
+	"wpt":         wptPage,
+}
+
+// slowInternalPages are written off the main thread, like the pages of
+// registered schemes, as they take a while
+var slowInternalPages = map[string]bool{"wpt": true}
+
+// internalPageStyle is the stylesheet internal pages share
+const internalPageStyle = `<style>
+body { font-family: sans-serif; margin: 24px; color: #222; }
+h2 { margin-top: 28px; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: 4px 12px 4px 0; border-bottom: 1px solid #e0e0e0; }
+code { font-family: monospace; }
+.muted { color: #777; }
+.pass { color: #1b7f3b; }
+.fail { color: #c62828; }
+</style>`
+
+// loadInternalPage shows the internal page urlStr names
+func (t *Tab) loadInternalPage(urlStr string) {
+	t.BaseURL = urlStr
+	t.Security = nil
+	name := strings.TrimSuffix(strings.ToLower(strings.TrimPrefix(urlStr, InternalScheme)), "/")
+	page, ok := internalPages[name]
+	if !ok {
+		t.ErrorMsg = "Unknown page: " + urlStr
+		return
+	}
+	t.ErrorMsg = ""
+	if slowInternalPages[name] {
+		t.loadSchemeURL(urlStr, func(*url.URL) (string, []byte, error) {
+			return "text/html", []byte(page(t)), nil
+		})
+		return
+	}
+	t.LoadContent(page(t))
+}
+
+// apiPage lists every interface of the scripting API with its members
+func apiPage(*Tab) string {
+	var sb strings.Builder
+	sb.WriteString("<html><head><title>Scripting API</title>" + internalPageStyle + "</head><body>")
+	sb.WriteString("<h1>Scripting API</h1>")
+	sb.WriteString(`<p class="muted">The web APIs SpiderGopher gives page scripts. ` +
+		"Run <code>gobrowser --api-manifest</code> for this list as JSON.</p>")
+
+	for _, iface := range spidergopher.GenerateAPIManifest().Interfaces {
+		fmt.Fprintf(&sb, "<h2><code>%s</code></h2>", html.EscapeString(iface.Name))
+		if len(iface.Members) == 0 {
+			sb.WriteString(`<p class="muted">No members.</p>`)
+			continue
+		}
+		sb.WriteString("<table><tr><th>Member</th><th>Kind</th><th>Signature or type</th></tr>")
+		for _, m := range iface.Members {
+			detail := m.Type
+			if m.Kind == "method" {
+				detail = m.Name + m.Signature
+			}
+			fmt.Fprintf(&sb, "<tr><td><code>%s</code></td><td>%s</td><td><code>%s</code></td></tr>",
+				html.EscapeString(m.Name), m.Kind, html.EscapeString(detail))
+		}
+		sb.WriteString("</table>")
+	}
+	sb.WriteString("</body></html>")
+	return sb.String()
+}
+
+// conformancePage runs the built-in capability tests and shows the score of
+// each area followed by every test's outcome
+func conformancePage(*Tab) string {
+	report := conformance.Run()
+	total := report.Total()
+
+	var sb strings.Builder
+	sb.WriteString("<html><head><title>Conformance</title>" + internalPageStyle + "</head><body>")
+	sb.WriteString("<h1>Conformance</h1>")
+	fmt.Fprintf(&sb, `<p class="muted">%d of %d capability tests pass (%.0f%%). `+
+		"They run in the live CSS, layout and JavaScript engines each time this page loads. "+
+		`<a href="gobrowser://wpt">gobrowser://wpt</a> runs the curated Web Platform Tests.</p>`,
+		total.Passed, total.Total, total.Percent())
+
+	sb.WriteString("<table><tr><th>Area</th><th>Passed</th><th>Score</th></tr>")
+	for _, s := range report.Scores() {
+		fmt.Fprintf(&sb, "<tr><td>%s</td><td>%d / %d</td><td>%.0f%%</td></tr>",
+			html.EscapeString(s.Area), s.Passed, s.Total, s.Percent())
+	}
+	sb.WriteString("</table>")
+
+	area := ""
+	for _, r := range report.Results {
+		if r.Area != area {
+			if area != "" {
+				sb.WriteString("</table>")
+			}
+			area = r.Area
+			fmt.Fprintf(&sb, "<h2>%s</h2><table><tr><th>Test</th><th>Result</th><th>Detail</th></tr>", html.EscapeString(area))
+		}
+		result := `<span class="pass">pass</span>`
+		if !r.Passed {
+			result = `<span class="fail">fail</span>`
+		}
+		fmt.Fprintf(&sb, "<tr><td><code>%s</code></td><td>%s</td><td>%s</td></tr>",
+			html.EscapeString(r.Name), result, html.EscapeString(r.Detail))
+	}
+	if area != "" {
+		sb.WriteString("</table>")
+	}
+	sb.WriteString("</body></html>")
+	return sb.String()
+}
+
+// wptPage runs the curated Web Platform Tests and shows the score of each
+// area followed by every file's subtests
+func wptPage(*Tab) string {
+	report := conformance.RunWPT()
+	total := report.Total()
+
+	var sb strings.Builder
+	sb.WriteString("<html><head><title>Web Platform Tests</title>" + internalPageStyle + "</head><body>")
+	sb.WriteString("<h1>Web Platform Tests</h1>")
+	fmt.Fprintf(&sb, `<p class="muted">%d of %d subtests pass (%.0f%%). `+
+		"A curated subset of the suite runs headless against SpiderGopher and the DOM each time this page loads. "+
+		"Run <code>gobrowser --wpt DIR</code> on a checkout of the suite for more.</p>",
+		total.Passed, total.Total, total.Percent())
+
+	sb.WriteString("<table><tr><th>Area</th><th>Passed</th><th>Score</th></tr>")
+	for _, s := range report.Scores() {
+		fmt.Fprintf(&sb, "<tr><td>%s</td><td>%d / %d</td><td>%.0f%%</td></tr>",
+			html.EscapeString(s.Area), s.Passed, s.Total, s.Percent())
+	}
+	sb.WriteString("</table>")
+
+	for _, f := range report.Files {
+		fmt.Fprintf(&sb, "<h2><code>%s</code> %d / %d</h2>", html.EscapeString(f.Path), f.Passed(), len(f.Subtests))
+		if f.Error != "" {
+			fmt.Fprintf(&sb, `<p class="fail">Harness error: %s</p>`, html.EscapeString(f.Error))
+		}
+		sb.WriteString("<table><tr><th>Subtest</th><th>Result</th><th>Message</th></tr>")
+		for _, s := range f.Subtests {
+			class := "fail"
+			if s.Status == conformance.WPTPass {
+				class = "pass"
+			}
+			fmt.Fprintf(&sb, `<tr><td>%s</td><td><span class="%s">%s</span></td><td>%s</td></tr>`,
+				html.EscapeString(s.Name), class, strings.ToLower(s.Status), html.EscapeString(s.Message))
+		}
+		sb.WriteString("</table>")
+	}
+	sb.WriteString("</body></html>")
+	return sb.String()
+}
+
+// memoryPage reports the memory the Go heap, the image cache and the page
+// scripts' engines hold, as they are when the page loads
+func memoryPage(t *Tab) string {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	images := t.browser.images.Cache.Stats()
+	mb := func(bytes uint64) string { return fmt.Sprintf("%.1f MB", float64(bytes)/(1<<20)) }
+
+	var sb strings.Builder
+	sb.WriteString("<html><head><title>Memory</title>" + internalPageStyle + "</head><body>")
+	sb.WriteString("<h1>Memory</h1>")
+	sb.WriteString(`<p class="muted">Reload this page to measure again.</p>`)
+	row := func(name, value string) {
+		fmt.Fprintf(&sb, "<tr><td>%s</td><td>%s</td></tr>", name, html.EscapeString(value))
+	}
+
+	sb.WriteString("<h2>Go heap</h2><table>")
+	row("In use", mb(mem.HeapAlloc))
+	row("Reserved", mb(mem.HeapSys))
+	row("Obtained from the system", mb(mem.Sys))
+	row("Garbage collections", fmt.Sprint(mem.NumGC))
+	row("Goroutines", fmt.Sprint(runtime.NumGoroutine()))
+	sb.WriteString("</table>")
+
+	sb.WriteString("<h2>Image cache</h2><table>")
+	row("Decoded images", fmt.Sprint(images.Images))
+	row("Size", mb(uint64(images.Bytes)))
+	budget := "none"
+	if images.Budget > 0 {
+		budget = mb(uint64(images.Budget))
+	}
+	row("Budget", budget)
+	row("Failed to load", fmt.Sprint(images.Failed))
+	sb.WriteString("</table>")
+
+	sb.WriteString("<h2>Scripts</h2><table>")
+	row("Running engines", fmt.Sprint(spidergopher.LiveEngines()))
+	row("Element event listeners", fmt.Sprint(spidergopher.LiveListeners()))
+	sb.WriteString("</table>")
+	sb.WriteString("</body></html>")
+	return sb.String()
+}
+
+// timingsPage reports how long each stage of showing pages has taken since
+// the browser started
+func timingsPage(*Tab) string {
+	ms := func(d time.Duration) string { return fmt.Sprintf("%.2f ms", float64(d)/float64(time.Millisecond)) }
+
+	var sb strings.Builder
+	sb.WriteString("<html><head><title>Timings</title>" + internalPageStyle + "</head><body>")
+	sb.WriteString("<h1>Timings</h1>")
+	sb.WriteString(`<p class="muted">How long parsing, styling, layout and painting have taken since the browser started. ` +
+		"A layout slice is the part of a page's layout run in one frame. " +
+		"Run <code>go test ./perf -bench .</code> to benchmark the engines on fixed pages.</p>")
+	sb.WriteString("<table><tr><th>Stage</th><th>Runs</th><th>Last</th><th>Mean</th><th>Max</th></tr>")
+	for _, s := range perf.Stats() {
+		if s.Count == 0 {
+			fmt.Fprintf(&sb, `<tr><td>%s</td><td>0</td><td class="muted" colspan="3">not run yet</td></tr>`, s.Stage)
+			continue
+		}
+		fmt.Fprintf(&sb, "<tr><td>%s</td><td>%d</td><td>%s</td><td>%s</td><td>%s</td></tr>",
+			s.Stage, s.Count, ms(s.Last), ms(s.Mean), ms(s.Max))
+	}
+	sb.WriteString("</table>")
+	sb.WriteString("</body></html>")
+	return sb.String()
+}
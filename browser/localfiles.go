@@ -0,0 +1,112 @@
+package browser
+
+import (
+	"fmt"
+	"html"
+	"mime"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// =============================================================================
+// LOCAL FILES
+// file:// addresses and local paths load from disk. A page's base URL is
+// the file:// URL of its file, so its relative images, stylesheets and
+// scripts resolve next to it and load through the file: scheme's handler.
+// A folder shows an index of its contents, folders first, linking to each
+// entry.
+// =============================================================================
+
+// serveFile returns the content of the file a file:// URL names
+func serveFile(u *url.URL) (string, []byte, error) {
+	path := fileURLPath(u.String())
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return "", nil, fmt.Errorf("%s is a folder", path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	mediaType := mime.TypeByExtension(filepath.Ext(path))
+	if mediaType == "" {
+		mediaType = "application/octet-stream"
+	}
+	return mediaType, content, nil
+}
+
+// fileURL returns the file:// URL of a local path
+func fileURL(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	path = filepath.ToSlash(path)
+	if !strings.HasPrefix(path, "/") {
+		// C:/dir becomes /C:/dir
+		path = "/" + path
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// fileURLPath returns the local path a file:// URL names, with its escapes
+// such as %20 decoded
+func fileURLPath(fileURL string) string {
+	u, err := url.Parse(fileURL)
+	if err != nil {
+		return strings.TrimPrefix(fileURL, "file://")
+	}
+	path := u.Path
+	if u.Host != "" && u.Host != "localhost" {
+		// file://server/share names a network share
+		path = "//" + u.Host + path
+	}
+	if runtime.GOOS == "windows" && len(path) > 2 && path[0] == '/' && path[2] == ':' {
+		path = path[1:]
+	}
+	return filepath.FromSlash(path)
+}
+
+// directoryListing writes the index page of the folder at path
+func directoryListing(path string) (string, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return "", err
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].IsDir() != entries[j].IsDir() {
+			return entries[i].IsDir()
+		}
+		return strings.ToLower(entries[i].Name()) < strings.ToLower(entries[j].Name())
+	})
+
+	title := "Index of " + filepath.ToSlash(path)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<html><head><title>%s</title>%s</head><body>", html.EscapeString(title), internalPageStyle)
+	fmt.Fprintf(&sb, "<h1>%s</h1>", html.EscapeString(title))
+	sb.WriteString("<table><tr><th>Name</th><th>Size</th><th>Modified</th></tr>")
+	if filepath.Dir(path) != path {
+		sb.WriteString(`<tr><td><a href="../">..</a></td><td></td><td></td></tr>`)
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		name, href, size := entry.Name(), url.PathEscape(entry.Name()), formatBytes(info.Size())
+		if entry.IsDir() {
+			name, href, size = name+"/", href+"/", ""
+		}
+		fmt.Fprintf(&sb, `<tr><td><a href="%s">%s</a></td><td class="muted">%s</td><td class="muted">%s</td></tr>`,
+			html.EscapeString(href), html.EscapeString(name), size, info.ModTime().Format(time.DateTime))
+	}
+	sb.WriteString("</table>")
+	if len(entries) == 0 {
+		sb.WriteString(`<p class="muted">This folder is empty.</p>`)
+	}
+	sb.WriteString("</body></html>")
+	return sb.String(), nil
+}
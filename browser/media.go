@@ -0,0 +1,553 @@
+package browser
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go-browser/dom"
+	"go-browser/layout"
+	"go-browser/logging"
+	"go-browser/render"
+	"go-browser/spidergopher"
+	spiderdom "go-browser/spidergopher/dom"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/audio"
+	"github.com/hajimehoshi/ebiten/v2/audio/mp3"
+	"github.com/hajimehoshi/ebiten/v2/audio/vorbis"
+	"github.com/hajimehoshi/ebiten/v2/audio/wav"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// =============================================================================
+// MEDIA
+// <audio> and <video> elements. Audio in WAV, MP3 and Ogg Vorbis plays
+// through ebiten/audio. Video frames can't be decoded, so a video shows its
+// poster and controls, and playing it fails with NotSupportedError.
+// =============================================================================
+
+// mediaSampleRate is the sample rate media audio is resampled to
+const mediaSampleRate = 44100
+
+// mediaControlsHeight is the height of a media element's controls bar
+const mediaControlsHeight = 32.0
+
+var (
+	ColorMediaBackground = color.RGBA{0, 0, 0, 255}
+	ColorMediaControls   = color.RGBA{20, 20, 24, 200}
+	ColorMediaText       = color.RGBA{235, 235, 240, 255}
+	ColorMediaTrack      = color.RGBA{120, 120, 130, 255}
+)
+
+// audioContext is the process's one audio context, created on first use
+var audioContext = sync.OnceValue(func() *audio.Context {
+	return audio.NewContext(mediaSampleRate)
+})
+
+// mediaElement is the playback state of one <audio> or <video>
+type mediaElement struct {
+	src      string // absolute URL of the media resource; empty when none
+	paused   bool
+	ended    bool
+	muted    bool
+	volume   float64
+	duration float64 // seconds; NaN until the media has loaded
+	seekTo   float64 // where playback starts once the media has loaded
+	loading  bool
+	failure  string        // why the media can't play, shown over it
+	player   *audio.Player // nil until the audio has loaded
+}
+
+// position returns how far into the media playback is, in seconds
+func (el *mediaElement) position() float64 {
+	if el.player != nil {
+		return el.player.Position().Seconds()
+	}
+	return el.seekTo
+}
+
+// applyVolume gives the audio player the element's volume, or silence
+// while it is muted
+func (el *mediaElement) applyVolume() {
+	if el.player == nil {
+		return
+	}
+	if el.muted {
+		el.player.SetVolume(0)
+	} else {
+		el.player.SetVolume(el.volume)
+	}
+}
+
+// mediaPlayer plays the <audio> and <video> elements of a tab's page. It is
+// the spiderdom.MediaHandler the page's engine hands playback to.
+type mediaPlayer struct {
+	mu       sync.Mutex
+	baseURL  string       // URL media sources resolve against
+	client   *http.Client // the client media downloads through
+	elements map[*dom.Node]*mediaElement
+}
+
+func newMediaPlayer(client *http.Client) *mediaPlayer {
+	return &mediaPlayer{client: client, elements: make(map[*dom.Node]*mediaElement)}
+}
+
+// reset stops every element of the old page; baseURL is the new page's
+func (m *mediaPlayer) reset(baseURL string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, el := range m.elements {
+		if el.player != nil {
+			el.player.Close()
+		}
+	}
+	m.elements = make(map[*dom.Node]*mediaElement)
+	m.baseURL = baseURL
+}
+
+// element returns the state of node, set up from its attributes the first
+// time. The caller holds m.mu.
+func (m *mediaPlayer) element(node *dom.Node) *mediaElement {
+	if el := m.elements[node]; el != nil {
+		return el
+	}
+	el := &mediaElement{paused: true, muted: node.HasAttr("muted"), volume: 1, duration: math.NaN()}
+	if src := mediaSource(node); src != "" {
+		el.src = spidergopher.ResolveURL(src, m.baseURL)
+	}
+	m.elements[node] = el
+	return el
+}
+
+// mediaSource returns the element's src, else that of its first <source>
+func mediaSource(node *dom.Node) string {
+	if src := node.GetAttr("src"); src != "" {
+		return src
+	}
+	for _, child := range node.Children {
+		if child.Tag == "source" && child.GetAttr("src") != "" {
+			return child.GetAttr("src")
+		}
+	}
+	return ""
+}
+
+// notSupported fails el with message and returns the error play() rejects with
+func notSupported(el *mediaElement, message string) error {
+	el.failure = message
+	el.paused = true
+	return &spiderdom.MediaError{Name: "NotSupportedError", Message: message}
+}
+
+// PlayMedia starts node playing, loading its audio the first time
+func (m *mediaPlayer) PlayMedia(node *dom.Node) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el := m.element(node)
+	switch {
+	case node.Tag == "video":
+		return notSupported(el, "Video playback is not supported")
+	case el.src == "":
+		return notSupported(el, "No media source")
+	case el.failure != "":
+		return notSupported(el, el.failure)
+	}
+
+	if el.ended {
+		el.ended = false
+		el.seekTo = 0
+		if el.player != nil {
+			el.player.SetPosition(0)
+		}
+	}
+	el.paused = false
+	switch {
+	case el.player != nil:
+		el.player.Play()
+	case !el.loading:
+		el.loading = true
+		go m.load(node, el)
+	}
+	return nil
+}
+
+// load fetches and decodes el's audio, then starts it if it is still wanted
+func (m *mediaPlayer) load(node *dom.Node, el *mediaElement) {
+	player, duration, err := loadAudio(m.client, el.src)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el.loading = false
+	if m.elements[node] != el {
+		// The page was left while the media loaded
+		if player != nil {
+			player.Close()
+		}
+		return
+	}
+	if err != nil {
+		logging.Paint.Warn("cannot play media", "url", el.src, "err", err)
+		el.failure = "Can't play this media"
+		el.paused = true
+		return
+	}
+	el.player, el.duration = player, duration
+	el.applyVolume()
+	if el.seekTo > 0 {
+		player.SetPosition(time.Duration(el.seekTo * float64(time.Second)))
+	}
+	if !el.paused {
+		player.Play()
+	}
+}
+
+// PauseMedia pauses node
+func (m *mediaPlayer) PauseMedia(node *dom.Node) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el := m.element(node)
+	el.paused = true
+	if el.player != nil {
+		el.player.Pause()
+	}
+}
+
+// MediaState returns the playback state of node
+func (m *mediaPlayer) MediaState(node *dom.Node) spiderdom.MediaState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el := m.element(node)
+	return spiderdom.MediaState{
+		Paused:      el.paused,
+		Ended:       el.ended,
+		Muted:       el.muted,
+		CurrentTime: el.position(),
+		Duration:    el.duration,
+		Volume:      el.volume,
+	}
+}
+
+// SeekMedia moves node's playback to seconds
+func (m *mediaPlayer) SeekMedia(node *dom.Node, seconds float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el := m.element(node)
+	seconds = max(seconds, 0)
+	if !math.IsNaN(el.duration) {
+		seconds = min(seconds, el.duration)
+	}
+	el.seekTo, el.ended = seconds, false
+	if el.player != nil {
+		el.player.SetPosition(time.Duration(seconds * float64(time.Second)))
+	}
+}
+
+// SetMediaMuted mutes or unmutes node
+func (m *mediaPlayer) SetMediaMuted(node *dom.Node, muted bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el := m.element(node)
+	el.muted = muted
+	el.applyVolume()
+}
+
+// SetMediaVolume sets node's volume, from 0 to 1
+func (m *mediaPlayer) SetMediaVolume(node *dom.Node, volume float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el := m.element(node)
+	el.volume = volume
+	el.applyVolume()
+}
+
+// status returns why node can't play, or "Loading…" while its media loads
+func (m *mediaPlayer) status(node *dom.Node) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el := m.element(node)
+	if el.loading {
+		return "Loading…"
+	}
+	return el.failure
+}
+
+// step finds the elements that played to their end since the last tick.
+// Looping ones start over; the others pause and are returned.
+func (m *mediaPlayer) step() []*dom.Node {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ended []*dom.Node
+	for node, el := range m.elements {
+		if el.paused || el.player == nil || el.player.IsPlaying() {
+			continue
+		}
+		if node.HasAttr("loop") {
+			el.player.SetPosition(0)
+			el.player.Play()
+			continue
+		}
+		el.paused, el.ended = true, true
+		ended = append(ended, node)
+	}
+	return ended
+}
+
+// =============================================================================
+// AUDIO DECODING
+// =============================================================================
+
+// audioStream is decoded 16-bit stereo audio at mediaSampleRate
+type audioStream interface {
+	io.ReadSeeker
+	Length() int64
+}
+
+// loadAudio fetches the audio at src through client and returns a paused
+// player for it with its duration in seconds
+func loadAudio(client *http.Client, src string) (*audio.Player, float64, error) {
+	data, err := fetchMedia(client, src)
+	if err != nil {
+		return nil, 0, err
+	}
+	stream, err := decodeAudio(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	player, err := audioContext().NewPlayer(stream)
+	if err != nil {
+		return nil, 0, err
+	}
+	// 16-bit stereo takes four bytes a frame
+	return player, float64(stream.Length()) / (4 * mediaSampleRate), nil
+}
+
+// fetchMedia reads a media resource from the web through client, sharing
+// the browser's cookies, or from a local file
+func fetchMedia(client *http.Client, src string) ([]byte, error) {
+	if !strings.HasPrefix(src, "http://") && !strings.HasPrefix(src, "https://") {
+		if strings.HasPrefix(strings.ToLower(src), "file://") {
+			src = fileURLPath(src)
+		}
+		return os.ReadFile(src)
+	}
+	resp, err := client.Get(src)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// decodeAudio decodes WAV, Ogg Vorbis or MP3 data, told apart by their
+// first bytes
+func decodeAudio(data []byte) (audioStream, error) {
+	r := bytes.NewReader(data)
+	var stream audioStream
+	var err error
+	switch {
+	case bytes.HasPrefix(data, []byte("RIFF")):
+		stream, err = wav.DecodeWithSampleRate(mediaSampleRate, r)
+	case bytes.HasPrefix(data, []byte("OggS")):
+		stream, err = vorbis.DecodeWithSampleRate(mediaSampleRate, r)
+	case bytes.HasPrefix(data, []byte("ID3")) || (len(data) > 1 && data[0] == 0xFF && data[1]&0xE0 == 0xE0):
+		stream, err = mp3.DecodeWithSampleRate(mediaSampleRate, r)
+	default:
+		return nil, errors.New("unsupported audio format")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+// =============================================================================
+// TAB AND APP HOOKS
+// =============================================================================
+
+// mediaHandler returns what plays the tab's media: the page's engine, which
+// applies the autoplay policy and tells scripts, or the player itself when
+// JavaScript is off
+func (t *Tab) mediaHandler() spiderdom.MediaHandler {
+	if t.JSEngine != nil {
+		return t.JSEngine
+	}
+	return t.media
+}
+
+// autoplayMedia starts the page's autoplay elements. Muted media always
+// starts; audible media only once the user has interacted with the page.
+func (t *Tab) autoplayMedia() {
+	if t.Document == nil {
+		return
+	}
+	for _, tag := range []string{"audio", "video"} {
+		for _, node := range t.Document.Node.GetElementsByTagName(tag) {
+			if !node.HasAttr("autoplay") {
+				continue
+			}
+			if t.JSEngine == nil && !node.HasAttr("muted") {
+				continue
+			}
+			t.mediaHandler().PlayMedia(node)
+		}
+	}
+}
+
+// stepMedia tells the page about media that played to its end
+func (t *Tab) stepMedia() {
+	for _, node := range t.media.step() {
+		if t.JSEngine != nil {
+			t.JSEngine.MediaEnded(node)
+		}
+	}
+}
+
+// isMediaNode reports whether node is an <audio> or <video> element
+func isMediaNode(node *dom.Node) bool {
+	return node != nil && (node.Tag == "audio" || node.Tag == "video")
+}
+
+// mediaTrack returns the progress track of a media box drawn at x, y
+func mediaTrack(box *layout.RenderBox, x, y float64) (tx, ty, tw float64) {
+	barY := y + box.H - mediaControlsHeight
+	return x + mediaControlsHeight, barY + mediaControlsHeight/2, max(box.W-mediaControlsHeight-96, 0)
+}
+
+// handleMediaClick plays or pauses the media element under the point, in
+// render tree coordinates, or seeks it when the click is on its progress
+// track. It returns true when there was a media element there.
+func (a *App) handleMediaClick(tree *layout.RenderBox, x, y float64) bool {
+	box := findBoxAt(tree, x, y)
+	if box == nil || !isMediaNode(box.Node) {
+		return false
+	}
+	node := box.Node
+	handler := a.mediaHandler()
+	state := handler.MediaState(node)
+
+	tx, ty, tw := mediaTrack(box, box.X, box.Y)
+	onTrack := x >= tx && x <= tx+tw && math.Abs(y-ty) <= mediaControlsHeight/2
+	if node.HasAttr("controls") && onTrack && !math.IsNaN(state.Duration) && tw > 0 {
+		handler.SeekMedia(node, (x-tx)/tw*state.Duration)
+		return true
+	}
+	if state.Paused {
+		handler.PlayMedia(node)
+	} else {
+		handler.PauseMedia(node)
+	}
+	return true
+}
+
+// =============================================================================
+// DRAWING
+// =============================================================================
+
+// drawMedia draws a media element's box at x, y: a video's poster with a
+// play button while paused, and the controls bar when it has controls
+func (a *App) drawMedia(screen *ebiten.Image, box *layout.RenderBox, x, y float64) {
+	node := box.Node
+	state := a.media.MediaState(node)
+	fade := func(c color.RGBA) color.RGBA { return render.Fade(c, box.Opacity) }
+
+	if node.Tag == "video" {
+		vector.DrawFilledRect(screen, float32(x), float32(y), float32(box.W), float32(box.H), fade(ColorMediaBackground), false)
+		if poster := node.GetAttr("poster"); poster != "" {
+			if img := a.browser.images.CachedImage(spidergopher.ResolveURL(poster, documentBaseURL(node)), documentURL(node)); img != nil {
+				drawImageContained(screen, img, x, y, box.W, box.H, box.Opacity)
+			}
+		}
+		if state.Paused {
+			cx, cy := float32(x+box.W/2), float32(y+box.H/2)
+			vector.DrawFilledCircle(screen, cx, cy, 24, fade(ColorMediaControls), true)
+			drawPlayIcon(screen, float64(cx)+2, float64(cy), 12, fade(ColorMediaText))
+		}
+	} else {
+		render.DrawRoundedRect(screen, float32(x), float32(y), float32(box.W), float32(box.H), 8, fade(ColorMediaControls))
+	}
+
+	if status := a.media.status(node); status != "" {
+		render.DrawTextCentered(screen, status, x+box.W/2, y+8, FontSizeUI, fade(ColorMediaText))
+	}
+	if node.HasAttr("controls") {
+		a.drawMediaControls(screen, box, x, y, state, fade)
+	}
+}
+
+// drawMediaControls draws the play/pause button, progress track and time
+// along the bottom of a media box
+func (a *App) drawMediaControls(screen *ebiten.Image, box *layout.RenderBox, x, y float64, state spiderdom.MediaState, fade func(color.RGBA) color.RGBA) {
+	barY := y + box.H - mediaControlsHeight
+	if box.Node.Tag == "video" {
+		vector.DrawFilledRect(screen, float32(x), float32(barY), float32(box.W), mediaControlsHeight, fade(ColorMediaControls), false)
+	}
+
+	// Play or pause button
+	bx, by := x+mediaControlsHeight/2, barY+mediaControlsHeight/2
+	if state.Paused {
+		drawPlayIcon(screen, bx+1, by, 7, fade(ColorMediaText))
+	} else {
+		vector.DrawFilledRect(screen, float32(bx-5), float32(by-6), 3, 12, fade(ColorMediaText), false)
+		vector.DrawFilledRect(screen, float32(bx+2), float32(by-6), 3, 12, fade(ColorMediaText), false)
+	}
+
+	// Progress track
+	tx, ty, tw := mediaTrack(box, x, y)
+	vector.DrawFilledRect(screen, float32(tx), float32(ty-2), float32(tw), 4, fade(ColorMediaTrack), false)
+	if state.Duration > 0 {
+		done := tw * min(state.CurrentTime/state.Duration, 1)
+		vector.DrawFilledRect(screen, float32(tx), float32(ty-2), float32(done), 4, fade(ColorAccent), false)
+		vector.DrawFilledCircle(screen, float32(tx+done), float32(ty), 6, fade(ColorMediaText), true)
+	}
+
+	// Elapsed and total time
+	label := formatMediaTime(state.CurrentTime) + " / " + formatMediaTime(state.Duration)
+	render.DrawText(screen, label, tx+tw+10, ty-FontSizeUI/2-2, FontSizeUI-2, fade(ColorMediaText))
+}
+
+// drawPlayIcon draws a play triangle of radius r centered at cx, cy
+func drawPlayIcon(screen *ebiten.Image, cx, cy, r float64, clr color.RGBA) {
+	var path vector.Path
+	path.MoveTo(float32(cx-r*0.6), float32(cy-r))
+	path.LineTo(float32(cx+r), float32(cy))
+	path.LineTo(float32(cx-r*0.6), float32(cy+r))
+	path.Close()
+	op := &vector.DrawPathOptions{AntiAlias: true}
+	op.ColorScale.ScaleWithColor(clr)
+	vector.FillPath(screen, &path, nil, op)
+}
+
+// drawImageContained draws img scaled to fit inside the box, centered
+func drawImageContained(screen, img *ebiten.Image, x, y, w, h, opacity float64) {
+	iw, ih := float64(img.Bounds().Dx()), float64(img.Bounds().Dy())
+	if iw == 0 || ih == 0 {
+		return
+	}
+	scale := min(w/iw, h/ih)
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Scale(scale, scale)
+	op.GeoM.Translate(x+(w-iw*scale)/2, y+(h-ih*scale)/2)
+	op.ColorScale.ScaleAlpha(float32(opacity))
+	screen.DrawImage(img, op)
+}
+
+// formatMediaTime shows seconds as m:ss, or --:-- while unknown
+func formatMediaTime(seconds float64) string {
+	if math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return "--:--"
+	}
+	s := int(seconds)
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
@@ -0,0 +1,104 @@
+package browser
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// =============================================================================
+// NETWORK LOG
+// Each App has an HTTP client of its own that its pages, stylesheets,
+// scripts, images and fetch() go through. Its transport serves the
+// registered schemes, refuses blocklisted hosts and records every request
+// with the bytes it received or the fact it was blocked; its jar keeps the
+// App's cookies. net/http's default client and transport are left alone.
+// =============================================================================
+
+// NetworkEntry is what the log knows about one URL
+type NetworkEntry struct {
+	URL     string
+	Bytes   int64 // response body bytes read so far
+	Blocked bool  // refused by the blocklist
+}
+
+// NetworkLog records the requests made by the browser, by URL
+type NetworkLog struct {
+	mu      sync.Mutex
+	entries map[string]*NetworkEntry
+}
+
+// NewNetworkLog creates an empty log
+func NewNetworkLog() *NetworkLog {
+	return &NetworkLog{entries: make(map[string]*NetworkEntry)}
+}
+
+// Lookup returns the entry recorded for url
+func (l *NetworkLog) Lookup(url string) (NetworkEntry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if e, ok := l.entries[url]; ok {
+		return *e, true
+	}
+	return NetworkEntry{}, false
+}
+
+// entry returns the entry for url, starting a new one for a new request
+func (l *NetworkLog) entry(url string) *NetworkEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	e := &NetworkEntry{URL: url}
+	l.entries[url] = e
+	return e
+}
+
+// recordingTransport records each request in the log
+type recordingTransport struct {
+	next http.RoundTripper
+	log  *NetworkLog
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	e := t.log.entry(req.URL.String())
+	resp, err := t.next.RoundTrip(req)
+	if errors.Is(err, ErrBlocked) {
+		t.log.mu.Lock()
+		e.Blocked = true
+		t.log.mu.Unlock()
+	}
+	if resp != nil {
+		resp.Body = &countingBody{ReadCloser: resp.Body, log: t.log, entry: e}
+	}
+	return resp, err
+}
+
+// countingBody adds the bytes read from a response body to its entry
+type countingBody struct {
+	io.ReadCloser
+	log   *NetworkLog
+	entry *NetworkEntry
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.log.mu.Lock()
+	b.entry.Bytes += int64(n)
+	b.log.mu.Unlock()
+	return n, err
+}
+
+// NewClient returns an HTTP client of the browser's own: its requests load
+// the registered schemes, are refused when list blocks their host, and are
+// recorded in log, blocked ones included; jar keeps their cookies. A nil
+// list blocks nothing and a nil log records nothing.
+func NewClient(list *Blocklist, log *NetworkLog, jar http.CookieJar) *http.Client {
+	var transport http.RoundTripper = &schemeTransport{next: http.DefaultTransport.(*http.Transport).Clone()}
+	if list != nil {
+		transport = &blockingTransport{next: transport, list: list}
+	}
+	if log != nil {
+		transport = &recordingTransport{next: transport, log: log}
+	}
+	return &http.Client{Transport: transport, Jar: jar}
+}
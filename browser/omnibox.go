@@ -0,0 +1,243 @@
+package browser
+
+import (
+	"image/color"
+	"net"
+	"os"
+	"strings"
+
+	"go-browser/render"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// =============================================================================
+// OMNIBOX
+// URL bar input is either an address or a web search; while editing, a
+// dropdown lists what Enter will do plus matching history entries
+// =============================================================================
+
+const (
+	maxSuggestions   = 6
+	suggestionHeight = 30.0
+)
+
+var (
+	ColorSuggestBg       = color.RGBA{255, 255, 255, 250}
+	ColorSuggestSelected = color.RGBA{232, 240, 254, 255}
+	ColorSuggestHint     = color.RGBA{120, 120, 130, 255}
+)
+
+// suggestion is one row of the URL bar dropdown
+type suggestion struct {
+	Text string // what the row shows: the address or the query
+	Hint string // what picking it does, e.g. "Search with DuckDuckGo"
+	URL  string // the address loaded when picked
+}
+
+// hasScheme reports whether input starts with a URL scheme such as
+// "https://", "file://", "about:" or a registered one like "data:"
+func hasScheme(input string) bool {
+	if strings.Contains(input, "://") {
+		return !strings.ContainsAny(input[:strings.Index(input, "://")], " /.?#")
+	}
+	lower := strings.ToLower(input)
+	return strings.HasPrefix(lower, "about:") || strings.HasPrefix(lower, "view-source:") ||
+		schemeHandler(input) != nil
+}
+
+// looksLikeURL reports whether URL bar input names an address rather than a
+// search query: it has a scheme, is a local file, or is a single word
+// whose host is localhost, an IP or contains a dot
+func looksLikeURL(input string) bool {
+	if hasScheme(input) {
+		return true
+	}
+	if strings.ContainsAny(input, " \t") {
+		return false
+	}
+	if _, err := os.Stat(input); err == nil {
+		return true
+	}
+
+	host := input
+	if i := strings.IndexAny(host, "/?#"); i != -1 {
+		host = host[:i]
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "localhost" || net.ParseIP(host) != nil {
+		return true
+	}
+	return strings.Contains(host, ".") && !strings.HasPrefix(host, ".") && !strings.HasSuffix(host, ".")
+}
+
+// resolveInput turns URL bar input into the address to load
+func (a *App) resolveInput(input string) string {
+	if !looksLikeURL(input) {
+		return a.Settings.Search().URL(input)
+	}
+	if hasScheme(input) {
+		return input
+	}
+	if _, err := os.Stat(input); err == nil {
+		return input
+	}
+	return "https://" + input
+}
+
+// buildSuggestions lists the dropdown rows for input: the default action
+// first, the alternative second, then history entries containing the input
+func (a *App) buildSuggestions(input string) []suggestion {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return nil
+	}
+
+	engine := a.Settings.Search()
+	search := suggestion{Text: input, Hint: "Search with " + engine.Name, URL: engine.URL(input)}
+	var list []suggestion
+	if looksLikeURL(input) {
+		target := a.resolveInput(input)
+		list = append(list, suggestion{Text: target, Hint: "Go to address", URL: target}, search)
+	} else {
+		list = append(list, search)
+	}
+
+	seen := map[string]bool{}
+	for _, s := range list {
+		seen[s.URL] = true
+	}
+	lower := strings.ToLower(input)
+	for _, tab := range a.Tabs {
+		for i := len(tab.History) - 1; i >= 0 && len(list) < maxSuggestions; i-- {
+			entry := tab.History[i]
+			if seen[entry] || !strings.Contains(strings.ToLower(entry), lower) {
+				continue
+			}
+			seen[entry] = true
+			list = append(list, suggestion{Text: entry, Hint: "History", URL: entry})
+		}
+	}
+	return list
+}
+
+// updateSuggestions recomputes the dropdown when the text changed, putting the
+// highlight back on the first row
+func (n *NavBar) updateSuggestions(app *App) {
+	if n.suggestFor == n.URLText && n.suggestions != nil {
+		return
+	}
+	n.suggestFor = n.URLText
+	n.suggestions = app.buildSuggestions(n.URLText)
+	n.suggestSel = 0
+}
+
+// showsSuggestions reports whether the dropdown is open. Right after focusing,
+// the whole current URL is selected and nothing has been typed, so it stays
+// closed until the text is edited.
+func (n *NavBar) showsSuggestions(app *App) bool {
+	return n.IsEditing && len(n.suggestions) > 0 && n.URLText != app.URL
+}
+
+// handleSuggestionKeys moves the dropdown highlight with Up/Down
+func (n *NavBar) handleSuggestionKeys(app *App) {
+	n.updateSuggestions(app)
+	if !n.showsSuggestions(app) {
+		return
+	}
+	if keyRepeated(ebiten.KeyDown) {
+		n.suggestSel = (n.suggestSel + 1) % len(n.suggestions)
+	}
+	if keyRepeated(ebiten.KeyUp) {
+		n.suggestSel = (n.suggestSel + len(n.suggestions) - 1) % len(n.suggestions)
+	}
+}
+
+// submit leaves editing and loads target
+func (n *NavBar) submit(app *App, target string) {
+	n.IsEditing = false
+	n.suggestions = nil
+	app.URL = target
+	app.LoadFromURL(target)
+}
+
+// submitInput loads the highlighted suggestion, or resolves the typed text
+func (n *NavBar) submitInput(app *App) {
+	input := strings.TrimSpace(n.URLText)
+	if input == "" {
+		return
+	}
+	if n.showsSuggestions(app) && n.suggestSel < len(n.suggestions) {
+		n.submit(app, n.suggestions[n.suggestSel].URL)
+		return
+	}
+	n.submit(app, app.resolveInput(input))
+}
+
+// suggestionRect returns the screen rectangle of dropdown row i
+func (n *NavBar) suggestionRect(i int) (x, y, w, h float32) {
+	return n.URLBarX, n.URLBarY + URLBarHeight + 4 + float32(i)*suggestionHeight, n.URLBarW, suggestionHeight
+}
+
+// handleSuggestionClick loads the row under the mouse; it reports whether the
+// click landed on the dropdown
+func (n *NavBar) handleSuggestionClick(app *App, mx, my int) bool {
+	if !n.showsSuggestions(app) {
+		return false
+	}
+	for i, s := range n.suggestions {
+		x, y, w, h := n.suggestionRect(i)
+		if float32(mx) >= x && float32(mx) <= x+w && float32(my) >= y && float32(my) < y+h {
+			n.submit(app, s.URL)
+			return true
+		}
+	}
+	return false
+}
+
+// hoverSuggestion highlights the row under the mouse
+func (n *NavBar) hoverSuggestion(app *App, mx, my int) {
+	if !n.mouseMoved(mx, my) || !n.showsSuggestions(app) {
+		return
+	}
+	for i := range n.suggestions {
+		x, y, w, h := n.suggestionRect(i)
+		if float32(mx) >= x && float32(mx) <= x+w && float32(my) >= y && float32(my) < y+h {
+			n.suggestSel = i
+		}
+	}
+}
+
+// mouseMoved reports whether the cursor moved since the last call, so a
+// still mouse doesn't fight the keyboard for the highlight
+func (n *NavBar) mouseMoved(mx, my int) bool {
+	moved := mx != n.lastMouseX || my != n.lastMouseY
+	n.lastMouseX, n.lastMouseY = mx, my
+	return moved
+}
+
+// drawSuggestions renders the dropdown under the URL bar
+func (n *NavBar) drawSuggestions(screen *ebiten.Image, app *App) {
+	if !n.showsSuggestions(app) {
+		return
+	}
+	x, y, w, _ := n.suggestionRect(0)
+	total := float32(len(n.suggestions)) * suggestionHeight
+	render.DrawRoundedRect(screen, x-1, y-1, w+2, total+2, 6, ColorBorder)
+	render.DrawRoundedRect(screen, x, y, w, total, 6, ColorSuggestBg)
+
+	for i, s := range n.suggestions {
+		rx, ry, rw, rh := n.suggestionRect(i)
+		if i == n.suggestSel {
+			vector.DrawFilledRect(screen, rx, ry, rw, rh, ColorSuggestSelected, false)
+		}
+		textY := float64(ry) + suggestionHeight/2 - 2
+		hintW := render.MeasureText(s.Hint, 12)
+		text := render.TruncateText(s.Text, float64(rw)-hintW-urlTextPadding*3, FontSizeUI)
+		render.DrawText(screen, text, float64(rx)+urlTextPadding, textY, FontSizeUI, ColorURLText)
+		render.DrawText(screen, s.Hint, float64(rx+rw)-hintW-urlTextPadding, textY, 12, ColorSuggestHint)
+	}
+}
@@ -0,0 +1,90 @@
+package browser
+
+import (
+	"slices"
+	"strings"
+
+	"go-browser/layout"
+	"go-browser/render"
+	"go-browser/spidergopher"
+)
+
+// =============================================================================
+// PAGE LOAD
+// The page's images are asked for as it is laid out and scrolled, those
+// nearest the viewport first. Images with loading="lazy" wait until they
+// come near it, and don't hold up load. The page's scripts hear
+// DOMContentLoaded once they have all run, and load once its other images
+// have loaded or failed. Stylesheets don't hold load up: LoadContent fetches
+// them before any script runs.
+// =============================================================================
+
+// lazyImageMargin is how near the viewport, in CSS pixels, an image with
+// loading="lazy" comes before it loads
+const lazyImageMargin = 1250.0
+
+// stepPageLoad asks for the page's images when its layout or scroll position
+// changed, and fires load at the page once they have settled
+func (t *Tab) stepPageLoad() {
+	if t.RenderTree == nil || t.Document == nil {
+		return
+	}
+	if t.RenderTree != t.imagesTree || t.ScrollY != t.imagesScroll {
+		t.imagesTree, t.imagesScroll = t.RenderTree, t.ScrollY
+		t.requestImages()
+	}
+	if !t.loadPending || t.JSEngine == nil || !imagesSettled(t.browser.images.Cache, t.eagerImages) {
+		return
+	}
+	t.loadPending = false
+	t.JSEngine.DocumentLoaded()
+}
+
+// requestImages queues the images of the render tree by their distance from
+// the viewport, noting those that hold up load
+func (t *Tab) requestImages() {
+	t.eagerImages = t.eagerImages[:0]
+	viewTop := -t.ScrollY
+	viewBottom := viewTop + t.viewHeight()
+	var walk func(box *layout.RenderBox)
+	walk = func(box *layout.RenderBox) {
+		if box.IsImage && box.ImageURL != "" && box.Node != nil {
+			distance := 0.0
+			if !box.IsFixed {
+				distance = max(viewTop-(box.Y+box.H), box.Y-viewBottom, 0)
+			}
+			lazy := strings.EqualFold(strings.TrimSpace(box.Node.GetAttr("loading")), "lazy")
+			if !lazy || distance <= lazyImageMargin {
+				imgURL := spidergopher.ResolveURL(box.ImageURL, documentBaseURL(box.Node))
+				t.browser.images.RequestImage(imgURL, documentURL(box.Node), distance)
+				if !lazy && !slices.Contains(t.eagerImages, imgURL) {
+					t.eagerImages = append(t.eagerImages, imgURL)
+				}
+			}
+		}
+		for _, child := range box.Children {
+			walk(child)
+		}
+	}
+	walk(t.RenderTree)
+}
+
+// cancelImages stops loading the images of the page being left
+func (t *Tab) cancelImages() {
+	if t.Document != nil {
+		t.browser.images.CancelImages(t.Document.URL)
+	}
+	// Until the next page is laid out, the one being left asks for nothing
+	t.imagesTree, t.imagesScroll, t.eagerImages = t.RenderTree, t.ScrollY, nil
+}
+
+// imagesSettled reports whether each image has loaded into cache, failed or
+// been blocked
+func imagesSettled(cache *render.ImageCache, urls []string) bool {
+	for _, imgURL := range urls {
+		if img, loading, _ := cache.Get(imgURL); img == nil && loading {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,178 @@
+package browser
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"go-browser/render"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// =============================================================================
+// COMMAND PALETTE
+// Ctrl+Shift+P lists every action the shortcut manager can run, filtered by
+// what is typed; Up/Down pick one, Enter or a click runs it, Escape closes
+// =============================================================================
+
+const (
+	paletteWidth     = 460.0
+	paletteRowHeight = 30.0
+	paletteMaxRows   = 10
+)
+
+// CommandPalette holds the palette's query and selected row
+type CommandPalette struct {
+	Visible  bool
+	Query    string
+	Selected int
+}
+
+// Toggle opens the palette with an empty query, or closes it
+func (p *CommandPalette) Toggle() {
+	p.Visible = !p.Visible
+	p.Query, p.Selected = "", 0
+}
+
+// paletteActions returns the actions whose title matches every word of the
+// query, actions whose title starts with the query first
+func (a *App) paletteActions() []string {
+	words := strings.Fields(strings.ToLower(a.Palette.Query))
+	var prefixed, others []string
+	for _, action := range a.Shortcuts.Actions() {
+		title := strings.ToLower(actionTitle(action))
+		matches := true
+		for _, word := range words {
+			if !strings.Contains(title, word) && !strings.Contains(action, word) {
+				matches = false
+				break
+			}
+		}
+		switch {
+		case !matches || action == ActionPalette:
+		case len(words) > 0 && strings.HasPrefix(title, words[0]):
+			prefixed = append(prefixed, action)
+		default:
+			others = append(others, action)
+		}
+	}
+	return append(prefixed, others...)
+}
+
+// actionTitle returns the palette title of action, its name if it has none
+func actionTitle(action string) string {
+	if title, ok := ActionTitles[action]; ok {
+		return title
+	}
+	return action
+}
+
+// runPaletteAction closes the palette and runs action
+func (a *App) runPaletteAction(action string) {
+	a.Palette.Visible = false
+	a.Shortcuts.Run(action)
+}
+
+// handlePaletteInput edits the query and moves the selection while the
+// palette is open. It reports whether the palette consumed the keyboard.
+func (a *App) handlePaletteInput() bool {
+	p := &a.Palette
+	if !p.Visible {
+		return false
+	}
+	for _, r := range ebiten.AppendInputChars(nil) {
+		p.Query += string(r)
+		p.Selected = 0
+	}
+	if inpututil.IsKeyJustPressed(ebiten.KeyBackspace) && p.Query != "" {
+		_, size := utf8.DecodeLastRuneInString(p.Query)
+		p.Query = p.Query[:len(p.Query)-size]
+		p.Selected = 0
+	}
+
+	actions := a.paletteActions()
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyEscape):
+		p.Visible = false
+	case inpututil.IsKeyJustPressed(ebiten.KeyDown) && len(actions) > 0:
+		p.Selected = (p.Selected + 1) % min(len(actions), paletteMaxRows)
+	case inpututil.IsKeyJustPressed(ebiten.KeyUp) && len(actions) > 0:
+		n := min(len(actions), paletteMaxRows)
+		p.Selected = (p.Selected + n - 1) % n
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter) && p.Selected < len(actions):
+		a.runPaletteAction(actions[p.Selected])
+	}
+	return true
+}
+
+// paletteRect returns the position of the palette's query box; row i of
+// the results sits below it
+func paletteRect() (x, y float32) {
+	return float32((WindowWidth - paletteWidth) / 2), float32(ChromeHeight + 12)
+}
+
+// handlePaletteClick runs the action clicked in the palette and closes it
+// on a click elsewhere. It reports whether the palette took the click.
+func (a *App) handlePaletteClick(mx, my int) bool {
+	if !a.Palette.Visible {
+		return false
+	}
+	x, y := paletteRect()
+	row := int((float32(my) - y - findBarHeight) / paletteRowHeight)
+	actions := a.paletteActions()
+	if float32(mx) >= x && float32(mx) <= x+paletteWidth && float32(my) >= y+findBarHeight &&
+		row < min(len(actions), paletteMaxRows) {
+		a.runPaletteAction(actions[row])
+		return true
+	}
+	a.Palette.Visible = false
+	return true
+}
+
+// drawPalette renders the query box and the matching actions with their
+// shortcuts
+func (a *App) drawPalette(screen *ebiten.Image) {
+	p := &a.Palette
+	if !p.Visible {
+		return
+	}
+	actions := a.paletteActions()
+	rows := min(len(actions), paletteMaxRows)
+	x, y := paletteRect()
+	h := float32(findBarHeight + float64(max(rows, 1))*paletteRowHeight)
+	render.DrawRoundedRect(screen, x-1, y-1, paletteWidth+2, h+2, 6, ColorBorder)
+	render.DrawRoundedRect(screen, x, y, paletteWidth, h, 6, ColorSuggestBg)
+
+	query := p.Query
+	if (a.frame/30)%2 == 0 {
+		query += "|"
+	}
+	if p.Query == "" {
+		render.DrawText(screen, "Type a command", float64(x)+urlTextPadding, float64(y)+findBarHeight/2-2, FontSizeUI, ColorSuggestHint)
+	}
+	render.DrawText(screen, query, float64(x)+urlTextPadding, float64(y)+findBarHeight/2-2, FontSizeUI, ColorURLText)
+	vector.DrawFilledRect(screen, x, y+findBarHeight-1, paletteWidth, 1, ColorBorder, false)
+
+	if rows == 0 {
+		render.DrawText(screen, "No matching commands", float64(x)+urlTextPadding, float64(y)+findBarHeight+paletteRowHeight/2-2, FontSizeUI, ColorSuggestHint)
+		return
+	}
+	for i, action := range actions[:rows] {
+		ry := y + findBarHeight + float32(i)*paletteRowHeight
+		if i == p.Selected {
+			vector.DrawFilledRect(screen, x, ry, paletteWidth, paletteRowHeight, ColorSuggestSelected, false)
+		}
+		var keys []string
+		for _, acc := range a.Shortcuts.Accelerators(action) {
+			keys = append(keys, acc.String())
+		}
+		hint := strings.Join(keys, ", ")
+		hintW := render.MeasureText(hint, 12)
+		textY := float64(ry) + paletteRowHeight/2 - 2
+		title := render.TruncateText(actionTitle(action), paletteWidth-hintW-urlTextPadding*3, FontSizeUI)
+		render.DrawText(screen, title, float64(x)+urlTextPadding, textY, FontSizeUI, ColorURLText)
+		render.DrawText(screen, hint, float64(x)+paletteWidth-hintW-urlTextPadding, textY, 12, ColorSuggestHint)
+	}
+}
@@ -0,0 +1,242 @@
+package browser
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"go-browser/css"
+	"go-browser/dom"
+	"go-browser/layout"
+	"go-browser/perf"
+)
+
+// =============================================================================
+// LOAD PIPELINE
+// A page fetched from the network is parsed and styled, with its external
+// stylesheets fetched, in the goroutine that downloaded it. Nothing it
+// builds is shared until the main thread takes it: there it is laid out a
+// few milliseconds a frame, so the window keeps drawing the old page, and
+// swapped in whole once laid out. Local files and internal pages load in
+// one go through LoadContent. The goroutine reports its progress and errors
+// through a loadReport the main thread takes up each frame.
+// =============================================================================
+
+// layoutFrameBudget is the time a frame gives the layout of a loading page,
+// leaving the rest of a 60fps frame to drawing
+const layoutFrameBudget = 8 * time.Millisecond
+
+// preparedPage is a page parsed and styled, ready to lay out
+type preparedPage struct {
+	load        int64 // the tab's load it belongs to
+	doc         *dom.Document
+	stylesheets []*css.Stylesheet
+	ruleDeps    *css.RuleDependencies
+	media       css.Media // the medium it was styled for
+	security    *PageSecurity
+}
+
+// pageLayout is a prepared page being laid out over several frames
+type pageLayout struct {
+	page     *preparedPage
+	layout   *layout.Layout
+	viewport viewport      // the page's layout viewport
+	spent    time.Duration // time laid out so far
+}
+
+// preparePage parses rawHTML fetched from baseURL and styles it for media.
+// It touches no tab, so it can run off the main thread.
+func preparePage(rawHTML, baseURL string, security *PageSecurity, media css.Media, client *http.Client) *preparedPage {
+	start := time.Now()
+	doc := dom.ParseDocument(rawHTML)
+	doc.SetURL(baseURL)
+	perf.Since(perf.StageParse, start)
+	return prepareDocument(doc, security, media, client)
+}
+
+// prepareDocument styles doc, a page parsed or built off the main thread,
+// for media, fetching its stylesheets through client
+func prepareDocument(doc *dom.Document, security *PageSecurity, media css.Media, client *http.Client) *preparedPage {
+	// Extract <style> blocks, then fetch <link rel="stylesheet"> and the
+	// sheets they @import; the user's stylesheet applies to every page
+	stylesheets := css.ExtractStylesheets(doc.Node)
+	stylesheets = append(stylesheets, css.FetchExternalStylesheets(doc.Node, doc.BaseURL, client)...)
+	css.LoadImports(stylesheets, doc.BaseURL, client)
+	if user := css.UserStylesheet(); user != nil {
+		stylesheets = append(stylesheets, user)
+	}
+
+	// Apply CSS to DOM tree
+	start := time.Now()
+	css.ApplyStylesToTree(doc.Node, stylesheets, media)
+	ruleDeps := css.BuildRuleDependencies(stylesheets)
+	perf.Since(perf.StageStyle, start)
+	if security != nil && security.HTTPS {
+		security.MixedContent = findMixedContent(doc)
+	}
+	return &preparedPage{
+		doc:         doc,
+		stylesheets: stylesheets,
+		ruleDeps:    ruleDeps,
+		media:       media,
+		security:    security,
+	}
+}
+
+// startLoad begins a new load of the tab, abandoning any still in progress,
+// and returns its number
+func (t *Tab) startLoad() int64 {
+	if t.pageLayout != nil {
+		t.pageLayout.layout.Stop()
+		t.pageLayout = nil
+	}
+	t.prepared.Store(nil)
+	t.IsLoading = false
+	return t.loads.Add(1)
+}
+
+// loadReport is what a load running off the main thread has told the tab
+// since the main thread last looked
+type loadReport struct {
+	load     int64   // the load reporting
+	progress float64 // how far it got, from 0 to 1; 0 when it has not said
+	ended    bool    // it ended without a page: it failed or became a download
+	err      string  // why it failed, shown in place of the page
+	keep     func()  // keeps the page shown when the load became a download
+}
+
+// loadReports guards the report of the tab's latest load
+type loadReports struct {
+	mu     sync.Mutex
+	report loadReport
+}
+
+// reportLoad lets load update its report with fn, unless another load has
+// started since. It is safe to call off the main thread.
+func (t *Tab) reportLoad(load int64, fn func(r *loadReport)) {
+	t.reports.mu.Lock()
+	defer t.reports.mu.Unlock()
+	if load != t.loads.Load() {
+		return
+	}
+	if t.reports.report.load != load {
+		t.reports.report = loadReport{load: load}
+	}
+	fn(&t.reports.report)
+}
+
+// reportProgress tells the tab how far load has got
+func (t *Tab) reportProgress(load int64, progress float64) {
+	t.reportLoad(load, func(r *loadReport) { r.progress = progress })
+}
+
+// failLoad tells the tab that load failed with err
+func (t *Tab) failLoad(load int64, err string) {
+	t.reportLoad(load, func(r *loadReport) { r.ended, r.err = true, err })
+}
+
+// stepLoadReport takes up on the main thread what the tab's latest load has
+// reported since the last frame
+func (t *Tab) stepLoadReport() {
+	t.reports.mu.Lock()
+	r := t.reports.report
+	t.reports.report = loadReport{load: r.load}
+	t.reports.mu.Unlock()
+	if r.load != t.loads.Load() {
+		return
+	}
+	if r.progress > 0 {
+		t.Progress = r.progress
+	}
+	if r.keep != nil {
+		r.keep()
+	}
+	if r.ended {
+		t.IsLoading = false
+	}
+	if r.err != "" {
+		t.ErrorMsg = r.err
+	}
+}
+
+// offerPage hands a page prepared off the main thread to the tab, unless
+// another load has started since
+func (t *Tab) offerPage(page *preparedPage) {
+	if page.load == t.loads.Load() {
+		t.prepared.Store(page)
+	}
+}
+
+// stepLayout lays out the page the tab is loading for this frame's share of
+// time, and shows it once it is laid out
+func (t *Tab) stepLayout() {
+	defer t.recoverPage("laying out")
+	t.stepLoadReport()
+	if page := t.prepared.Swap(nil); page != nil && page.load == t.loads.Load() {
+		vp := t.viewportOf(page.doc)
+		t.pageLayout = &pageLayout{page: page, viewport: vp}
+		t.inViewport(vp, func() {
+			t.pageLayout.layout = layout.NewLayout(page.doc.Node, t.layoutWidth(), t.userColumnWidths)
+		})
+		t.Progress = 0.85
+	}
+	pl := t.pageLayout
+	if pl == nil {
+		return
+	}
+	done := false
+	start := time.Now()
+	t.inViewport(pl.viewport, func() {
+		done = pl.layout.Step(layoutFrameBudget)
+	})
+	slice := time.Since(start)
+	perf.Record(perf.StageLayoutSlice, slice)
+	pl.spent += slice
+	if !done {
+		return
+	}
+	perf.Record(perf.StageLayout, pl.spent)
+	t.pageLayout = nil
+	t.cancelImages()
+	t.Security = pl.page.security
+	t.showPage(pl.page, pl.viewport, pl.layout.Tree())
+	t.IsLoading = false
+}
+
+// inViewport runs fn with the tab laid out in vp, the viewport of a page
+// not shown yet
+func (t *Tab) inViewport(vp viewport, fn func()) {
+	shown := t.viewport
+	t.viewport = vp
+	defer func() { t.viewport = shown }()
+	fn()
+}
+
+// showPage swaps a prepared page, laid out as tree, in for the one shown
+// and runs its scripts
+func (t *Tab) showPage(page *preparedPage, vp viewport, tree *layout.RenderBox) {
+	t.Document = page.doc
+	t.tableSorts, t.columnWidths, t.columnDrag = nil, nil, nil
+	t.media.reset(t.Document.BaseURL)
+	t.canvases.reset(t.Document.BaseURL, t.Document.URL)
+	t.scheduleRefresh()
+	t.loadFrames()
+	t.PageTitle = t.Document.Title()
+	t.Stylesheets = page.stylesheets
+	t.RuleDeps = page.ruleDeps
+	t.styledMedia = page.media
+	t.Progress = 0.9
+
+	t.viewport = vp
+	if t.restyleForMedia() {
+		tree = layout.BuildRenderTree(t.Document.Node, t.layoutWidth(), t.userColumnWidths)
+	}
+	t.RenderTree = tree
+	t.jumpTo(t.pendingScroll)
+	t.pendingScroll = 0
+
+	// Initialize SpiderGopher and connect to DOM
+	t.initJSEngine()
+	t.autoplayMedia()
+	t.Progress = 1
+}
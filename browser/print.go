@@ -0,0 +1,382 @@
+package browser
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go-browser/css"
+	"go-browser/dom"
+	"go-browser/layout"
+	"go-browser/logging"
+	"go-browser/pdf"
+	"go-browser/render"
+	"go-browser/spidergopher"
+
+	"golang.org/x/image/font/gofont/gomono"
+)
+
+// =============================================================================
+// PRINT PREVIEW
+// Print preview shows the tab as it would print: media queries, media
+// attributes and @import conditions are evaluated for print media, so
+// print-only stylesheets apply and screen-only ones drop out.
+// =============================================================================
+
+// togglePrintPreview turns print preview on or off for the tab and styles
+// the page again for the new media
+func (t *Tab) togglePrintPreview() {
+	t.printPreview = !t.printPreview
+	if t.Document != nil && len(t.frames) == 0 {
+		t.relayout()
+		t.jumpTo(t.ScrollY)
+	}
+}
+
+// =============================================================================
+// PRINT TO PDF
+// Ctrl+P prints the page to a PDF in the download directory. The page is
+// styled for print media and laid out again at the width of an A4 sheet
+// inside its margins, then cut into pages between lines of text and images
+// rather than through them. Text is set in the fonts the page is drawn in,
+// embedded, so it can be selected and copied.
+// =============================================================================
+
+// printMargin is the blank edge around each printed page, half an inch
+const printMargin = 48.0
+
+// PrintFont is the TrueType font printed text is set in, the one pages are
+// drawn in; main sets it
+var PrintFont []byte
+
+// printer paints a render tree onto the pages of a PDF
+type printer struct {
+	doc        *pdf.Document
+	page       *pdf.Page
+	font, mono *pdf.Font
+	width      float64                // width the page is laid out in
+	cache      *render.ImageCache     // the tab's images
+	images     map[string]image.Image // images read back from the cache, by URL
+}
+
+// printToPDF prints the page to a PDF in the download directory
+func (t *Tab) printToPDF() {
+	if t.Document == nil || len(t.frames) > 0 {
+		logging.App.Info("nothing to print")
+		return
+	}
+	start := time.Now()
+	doc, err := t.printDocument(pdf.A4Width, pdf.A4Height)
+	if err != nil {
+		logging.App.Error("printing page", "err", err)
+		return
+	}
+	var out bytes.Buffer
+	if err := doc.Write(&out); err != nil {
+		logging.App.Error("printing page", "err", err)
+		return
+	}
+	dest := filepath.Join(t.Settings.DownloadLocation(), pageFileName(t.PageTitle)+".pdf")
+	if err := saveDownload(&out, dest); err != nil {
+		logging.App.Error("saving PDF", "err", err)
+		return
+	}
+	logging.App.Info("printed page", "pages", doc.PageCount(), "path", dest, "took", time.Since(start).Round(time.Millisecond))
+}
+
+// pageFileName names the files a page is saved in after its title,
+// without an extension
+func pageFileName(title string) string {
+	name := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(title))
+	if name == "" {
+		name = "page"
+	}
+	return name
+}
+
+// printDocument lays the page out for print on paper width by height
+// pixels and paints it into a PDF, then lays it out for the screen again
+func (t *Tab) printDocument(width, height float64) (*pdf.Document, error) {
+	if PrintFont == nil {
+		return nil, fmt.Errorf("no font to print with")
+	}
+	font, err := pdf.ParseFont(PrintFont, false)
+	if err != nil {
+		return nil, err
+	}
+	mono, err := pdf.ParseFont(gomono.TTF, true)
+	if err != nil {
+		return nil, err
+	}
+
+	contentW, contentH := width-printMargin*2, height-printMargin*2
+	media := t.pageMedia()
+	media.Type = "print"
+	media.Width, media.Height = contentW, contentH
+	t.restyleFor(media)
+	tree := layout.BuildRenderTree(t.Document.Node, contentW, t.userColumnWidths)
+	background := t.getPageBackground()
+	defer t.relayout()
+
+	doc := pdf.New(width, height)
+	doc.Title = t.PageTitle
+	doc.AddFont(font)
+	doc.AddFont(mono)
+	p := &printer{doc: doc, font: font, mono: mono, width: contentW, cache: t.browser.images.Cache, images: make(map[string]image.Image)}
+	breaks := pageBreaks(tree, contentH)
+	for i, top := range breaks {
+		bottom := treeBottom(tree)
+		if i+1 < len(breaks) {
+			bottom = breaks[i+1]
+		}
+		p.page = doc.AddPage()
+		p.page.FillRect(0, 0, width, height, background)
+		p.page.Save()
+		p.page.Clip(printMargin, printMargin, contentW, bottom-top)
+		p.paint(tree, printMargin, printMargin-top, top, bottom)
+		p.page.Restore()
+	}
+	return doc, nil
+}
+
+// treeBottom returns the bottom edge of the lowest box of a render tree
+func treeBottom(box *layout.RenderBox) float64 {
+	bottom := box.Y + box.H
+	for _, child := range box.Children {
+		bottom = max(bottom, treeBottom(child))
+	}
+	return bottom
+}
+
+// unbreakable reports whether a page break must not cut through box: a
+// line of text, an image or a form control
+func unbreakable(box *layout.RenderBox) bool {
+	if box.Text != "" || box.IsImage {
+		return true
+	}
+	if box.Node == nil {
+		return false
+	}
+	switch box.Node.Tag {
+	case "input", "button", "select", "textarea", "canvas", "video", "audio", "progress", "meter":
+		return true
+	}
+	return false
+}
+
+// pageBreaks returns where each page of a tree begins, pageHeight apart
+// unless that would cut through a line or an image, which then starts the
+// next page. Boxes taller than a page are cut anyway.
+func pageBreaks(tree *layout.RenderBox, pageHeight float64) []float64 {
+	var spans [][2]float64
+	var collect func(box *layout.RenderBox)
+	collect = func(box *layout.RenderBox) {
+		if unbreakable(box) && box.H < pageHeight {
+			spans = append(spans, [2]float64{box.Y, box.Y + box.H})
+			return
+		}
+		for _, child := range box.Children {
+			collect(child)
+		}
+	}
+	collect(tree)
+
+	bottom := treeBottom(tree)
+	breaks := []float64{0}
+	for top := 0.0; top+pageHeight < bottom; {
+		next := top + pageHeight
+		for moved := true; moved; {
+			moved = false
+			for _, span := range spans {
+				if span[0] > top && span[0] < next && span[1] > next {
+					next, moved = span[0], true
+				}
+			}
+		}
+		breaks = append(breaks, next)
+		top = next
+	}
+	return breaks
+}
+
+// paint draws box and its subtree, offset by offsetX and offsetY, on the
+// page showing the part of the tree from top to bottom
+func (p *printer) paint(box *layout.RenderBox, offsetX, offsetY, top, bottom float64) {
+	if box.Opacity <= 0 || (box.Y >= bottom && !box.IsFixed) {
+		return
+	}
+	absY := box.Y + offsetY
+	// Lines and images go on the page they start on
+	onPage := box.Y+box.H > top && (!unbreakable(box) || box.Y >= top || box.H >= bottom-top)
+	fade := func(c color.RGBA) color.RGBA { return render.Fade(c, box.Opacity) }
+
+	if onPage && !box.Hidden && box.Node != nil {
+		if cs, ok := box.Node.ComputedStyle.(*css.ComputedStyle); ok && box.Node.Tag != "body" && box.Node.Tag != "html" {
+			p.page.FillRect(box.X+offsetX, absY, box.W, box.H, fade(cs.BackgroundColor))
+			p.borders(cs, box.X+offsetX, absY, box.W, box.H, box.Opacity)
+		}
+		switch box.Node.Tag {
+		case "hr":
+			p.page.FillRect(offsetX, absY, box.W, 2, fade(ColorHR))
+		case "li":
+			p.listMarker(box, offsetX, absY)
+		}
+		if isFormControl(box.Node) {
+			p.formControl(box, box.X+offsetX, absY)
+		}
+	}
+	if box.Node != nil && isFormControl(box.Node) {
+		// The control draws its own contents
+		return
+	}
+
+	if onPage && !box.Hidden && box.Text != "" {
+		p.text(box, offsetX, absY)
+	}
+	if onPage && !box.Hidden && box.IsImage && box.ImageURL != "" {
+		if img := p.image(spidergopher.ResolveURL(box.ImageURL, documentBaseURL(box.Node))); img != nil {
+			p.page.Image(img, box.X+offsetX, absY, box.W, box.H)
+		}
+	}
+
+	for _, child := range box.Children {
+		p.paint(child, offsetX, offsetY, top, bottom)
+	}
+}
+
+// text sets the text of box, with its decorations
+func (p *printer) text(box *layout.RenderBox, offsetX, absY float64) {
+	fontSize := box.FontSize
+	if fontSize == 0 {
+		fontSize = FontSizeBody
+	}
+	textColor := ColorText
+	if box.TextColor != nil {
+		textColor = *box.TextColor
+	} else if box.IsLink && !box.IsButton {
+		textColor = ColorLink
+	}
+	textColor = render.Fade(textColor, box.Opacity)
+
+	font := p.font
+	if box.Monospace {
+		font = p.mono
+	}
+	x := alignedTextX(box, fontSize, offsetX, p.width)
+	baseline := absY + box.Baseline
+	p.page.Text(font, box.Text, x, baseline, fontSize, box.LetterSpacing, box.WordSpacing, textColor)
+
+	decorationColor := textColor
+	if box.DecorationColor != nil {
+		decorationColor = render.Fade(*box.DecorationColor, box.Opacity)
+	}
+	thickness := box.DecorationWidth
+	if thickness <= 0 {
+		thickness = max(1, fontSize/14)
+	}
+	for _, line := range strings.Fields(box.TextDecoration) {
+		switch line {
+		case "underline":
+			p.page.FillRect(x, baseline+thickness*1.5, box.W, thickness, decorationColor)
+		case "line-through":
+			p.page.FillRect(x, baseline-fontSize*0.27, box.W, thickness, decorationColor)
+		case "overline":
+			p.page.FillRect(x, baseline-fontSize*0.9, box.W, thickness, decorationColor)
+		}
+	}
+}
+
+// borders draws the borders of a box, as the screen does
+func (p *printer) borders(cs *css.ComputedStyle, x, y, w, h, opacity float64) {
+	bc := cs.BorderColor
+	if bc.A == 0 {
+		bc = cs.Color
+	}
+	bc = render.Fade(bc, opacity)
+	p.page.FillRect(x, y, w, cs.BorderTopWidth, bc)
+	p.page.FillRect(x, y+h-cs.BorderBottomWidth, w, cs.BorderBottomWidth, bc)
+	p.page.FillRect(x, y, cs.BorderLeftWidth, h, bc)
+	p.page.FillRect(x+w-cs.BorderRightWidth, y, cs.BorderRightWidth, h, bc)
+}
+
+// listMarker sets the bullet or number of a list item before its first line
+func (p *printer) listMarker(box *layout.RenderBox, offsetX, absY float64) {
+	marker, markerColor, fontSize := listMarker(box.Node)
+	if marker == "" {
+		return
+	}
+	markerX := box.X + offsetX - 6 - render.MeasureText(marker, fontSize)
+	baseline := absY + fontSize
+	if line := firstTextLine(box); line != nil {
+		baseline = absY - box.Y + line.Y + line.Baseline
+	}
+	p.page.Text(p.font, marker, markerX, baseline, fontSize, 0, 0, render.Fade(markerColor, box.Opacity))
+}
+
+// isFormControl reports whether node is drawn as a form control
+func isFormControl(node *dom.Node) bool {
+	switch node.Tag {
+	case "input", "textarea", "select", "button":
+		return true
+	}
+	return false
+}
+
+// formControl draws a form control as an outlined box holding its value
+func (p *printer) formControl(box *layout.RenderBox, x, y float64) {
+	node := box.Node
+	p.page.FillRect(x, y, box.W, 1, ColorBorder)
+	p.page.FillRect(x, y+box.H-1, box.W, 1, ColorBorder)
+	p.page.FillRect(x, y, 1, box.H, ColorBorder)
+	p.page.FillRect(x+box.W-1, y, 1, box.H, ColorBorder)
+
+	var label string
+	switch node.Tag {
+	case "input":
+		if node.GetAttr("type") != "password" {
+			label = node.GetAttr("value")
+		}
+	case "select":
+		for _, option := range node.GetElementsByTagName("option") {
+			if label == "" || option.HasAttr("selected") {
+				label = option.TextContent()
+			}
+		}
+	default:
+		label = node.TextContent()
+	}
+	label = strings.TrimSpace(strings.Join(strings.Fields(label), " "))
+	size := float64(FontSizeBody)
+	if cs, ok := node.ComputedStyle.(*css.ComputedStyle); ok && cs.FontSize > 0 {
+		size = cs.FontSize
+	}
+	p.page.Text(p.font, label, x+6, y+(box.H+size*0.7)/2, size, 0, 0, ColorText)
+}
+
+// image returns the loaded image at src as read back from the image cache,
+// or nil while it isn't loaded
+func (p *printer) image(src string) image.Image {
+	if img, ok := p.images[src]; ok {
+		return img
+	}
+	var rgba *image.RGBA
+	if cached, loaded, _ := p.cache.Get(src); loaded && cached != nil {
+		rgba = image.NewRGBA(cached.Bounds())
+		cached.ReadPixels(rgba.Pix)
+	}
+	var img image.Image
+	if rgba != nil {
+		img = rgba
+	}
+	p.images[src] = img
+	return img
+}
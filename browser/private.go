@@ -0,0 +1,68 @@
+package browser
+
+import (
+	"image/color"
+	"os"
+	"os/exec"
+
+	"go-browser/logging"
+	"go-browser/render"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// =============================================================================
+// PRIVATE BROWSING
+// A private window is a browser process of its own, started with --private
+// or by Ctrl+Shift+N from any window. Its cookies, tab history, image cache
+// and localStorage live only in its memory, and it neither restores nor
+// saves the session, so closing it leaves nothing behind. A purple nav bar
+// and a "Private" badge tell it apart from a normal window.
+// =============================================================================
+
+// PrivateFlag is the command line flag that starts a private window
+const PrivateFlag = "--private"
+
+// privateBadgeWidth is the width of the "Private" badge in the nav bar
+const privateBadgeWidth = 64
+
+// Colors of a private window's chrome
+var (
+	ColorPrivateNavBar = color.RGBA{52, 34, 74, 255}
+	ColorPrivateBadge  = color.RGBA{128, 86, 178, 255}
+)
+
+// NewPrivateApp creates a browser application for a private window
+func NewPrivateApp() *App {
+	return newApp(true)
+}
+
+// Private reports whether the app is a private window
+func (a *App) Private() bool {
+	return a.private
+}
+
+// openPrivateWindow starts a private window: the browser again, in a
+// process of its own
+func (a *App) openPrivateWindow() {
+	args := []string{PrivateFlag}
+	if profile != "" {
+		// With the settings of this window's profile
+		args = append(args, ProfileFlag, profile)
+	}
+	exe, err := os.Executable()
+	if err == nil {
+		err = exec.Command(exe, args...).Start()
+	}
+	if err != nil {
+		logging.App.Error("opening private window", "err", err)
+	}
+}
+
+// drawPrivateBadge draws the "Private" badge at x in the nav bar
+func drawPrivateBadge(screen *ebiten.Image, x float32) {
+	h := float32(22)
+	y := float32((NavBarHeight - h) / 2)
+	render.DrawRoundedRect(screen, x, y, privateBadgeWidth, h, h/2, ColorPrivateBadge)
+	render.DrawTextCentered(screen, "Private", float64(x+privateBadgeWidth/2), float64(y+h/2)+2, FontSizeUI-2, color.White)
+}
@@ -0,0 +1,112 @@
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go-browser/logging"
+	"go-browser/spidergopher/webapi"
+)
+
+// =============================================================================
+// PROFILES
+// A profile is a directory of its own for what the browser remembers about
+// its user: settings, the session's tabs and history, persistent cookies and
+// localStorage. Started with --profile NAME, the browser keeps them in
+// gobrowser/profiles/NAME in the config directory, so one identity (or test
+// fixture) never sees another's. Without the flag it uses the default
+// profile: the files directly in gobrowser, and ~/.spidergopher/storage.db.
+// The blocklist, user stylesheet and shortcuts are shared by all profiles.
+// =============================================================================
+
+// ProfileFlag is the command line flag that selects a profile
+const ProfileFlag = "--profile"
+
+// profile is the name of the profile in use, "" for the default
+var profile string
+
+// SetProfile selects the profile the browser keeps its data in; call it
+// before NewApp. Names are made of letters, digits, '-', '_' and '.'.
+func SetProfile(name string) error {
+	if !validProfileName(name) {
+		return fmt.Errorf("invalid profile name %q", name)
+	}
+	profile = name
+	return nil
+}
+
+// LocalStoragePath returns the database the profile's localStorage is kept
+// in
+func LocalStoragePath() string {
+	if profile == "" {
+		return webapi.DefaultLocalStorePath()
+	}
+	return profilePath("storage.db")
+}
+
+// ProfileName returns the name of the profile in use, "" for the default
+func ProfileName() string {
+	return profile
+}
+
+// ProfileDir returns the directory of the profile in use
+func ProfileDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	if profile == "" {
+		return filepath.Join(dir, "gobrowser")
+	}
+	return filepath.Join(dir, "gobrowser", "profiles", profile)
+}
+
+// profilePath returns where the profile in use keeps file
+func profilePath(file string) string {
+	dir := ProfileDir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, file)
+}
+
+// validProfileName reports whether name can name a profile directory
+func validProfileName(name string) bool {
+	if name == "." || name == ".." {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-' || r == '_' || r == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// SaveProfile writes what the profile keeps for the next start, the session
+// and the persistent cookies; a private window keeps nothing
+func (a *App) SaveProfile() error {
+	if a.private {
+		return nil
+	}
+	err := a.SaveSession()
+	if a.cookies != nil {
+		err = errors.Join(err, a.cookies.Save(CookiesPath()))
+	}
+	return err
+}
+
+// saveCookies writes the persistent cookies if they changed
+func (a *App) saveCookies() {
+	if a.cookies == nil {
+		return
+	}
+	if err := a.cookies.Save(CookiesPath()); err != nil {
+		logging.App.Error("saving cookies", "err", err)
+	}
+}
@@ -0,0 +1,287 @@
+package browser
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"go-browser/dom"
+	"go-browser/logging"
+	"go-browser/spidergopher"
+)
+
+// =============================================================================
+// READER MODE
+// F9 shows the article of the page on its own: the block holding most of
+// the page's prose is picked out, scored by the paragraphs it holds, the
+// commas in them and how little of their text is links, with class and id
+// names such as "article" or "sidebar" tipping the balance. A cleaned copy
+// of it, without scripts, forms, navigation or the page's styles, is laid
+// out through the normal pipeline in the font size, column width and theme
+// the settings give. F9 again reloads the page as it was.
+// =============================================================================
+
+// readerThemes are the page, text, link and muted colors of each theme
+var readerThemes = map[string][4]string{
+	"light": {"#ffffff", "#1b1b1b", "#0b57d0", "#6b6b6b"},
+	"dark":  {"#1c1b22", "#e8e6e3", "#8ab4f8", "#9a9a9a"},
+	"sepia": {"#f4ecd8", "#5b4636", "#8a4b12", "#8f7b66"},
+}
+
+// minArticleLength is the text an article must have for reader mode
+const minArticleLength = 250
+
+// readerPositive and readerNegative match class and id names of elements
+// likely to be or not to be the article
+var (
+	readerPositive = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|story|text|blog`)
+	readerNegative = regexp.MustCompile(`(?i)comment|combx|footer|foot|masthead|menu|nav|related|share|shoutbox|sidebar|social|sponsor|promo|banner|widget|advert|\bad-|popup|cookie|subscribe`)
+)
+
+// readerDropped are elements left out of a reader page with their content
+var readerDropped = map[string]bool{
+	"script": true, "style": true, "noscript": true, "link": true, "meta": true,
+	"nav": true, "aside": true, "footer": true, "header": true, "form": true,
+	"button": true, "input": true, "select": true, "textarea": true,
+	"iframe": true, "frame": true, "embed": true, "object": true, "canvas": true, "svg": true,
+	"dialog": true, "template": true,
+}
+
+// readerAttributes are the attributes a reader page keeps, by element
+var readerAttributes = map[string][]string{
+	"a":   {"href"},
+	"img": {"src", "alt", "width", "height"},
+	"td":  {"colspan", "rowspan"},
+	"th":  {"colspan", "rowspan"},
+	"ol":  {"start"},
+}
+
+// readerArticle is what reader mode shows of a page
+type readerArticle struct {
+	title   string
+	byline  string
+	content *dom.Node // a cleaned copy of the main content
+}
+
+// toggleReaderMode shows the article of the page in reader mode, or the
+// page again when it is already shown so
+func (t *Tab) toggleReaderMode() {
+	if t.readerMode {
+		t.pendingScroll = t.savedScroll(t.HistoryPos)
+		t.LoadFromURL(t.URL)
+		return
+	}
+	if t.Document == nil || t.IsLoading || len(t.frames) > 0 {
+		return
+	}
+	article := extractArticle(t.Document)
+	if article == nil {
+		logging.App.Info("no article found on this page")
+		return
+	}
+	t.saveScroll()
+	t.pendingScroll = 0
+	t.readerMode = true
+	t.LoadContent(readerPage(article, t.URL, t.Settings))
+}
+
+// extractArticle picks out the main content of doc; nil when the page has
+// no article long enough to read
+func extractArticle(doc *dom.Document) *readerArticle {
+	if doc.Body == nil {
+		return nil
+	}
+	scores := make(map[*dom.Node]float64)
+	var order []*dom.Node
+	addScore := func(node *dom.Node, score float64) {
+		if node == nil || node.Type != dom.NodeElement {
+			return
+		}
+		if _, seen := scores[node]; !seen {
+			order = append(order, node)
+		}
+		scores[node] += score
+	}
+
+	var walk func(node *dom.Node)
+	walk = func(node *dom.Node) {
+		if node.Type != dom.NodeElement || readerDropped[node.Tag] {
+			return
+		}
+		switch node.Tag {
+		case "p", "pre", "blockquote", "td":
+			text := strings.TrimSpace(collapseSpace(node.TextContent()))
+			if len(text) >= 25 {
+				// Longer paragraphs with more clauses weigh more; the
+				// grandparent shares in them too
+				score := 1 + float64(strings.Count(text, ",")) + min(float64(len(text))/100, 3)
+				addScore(node.Parent, score)
+				if node.Parent != nil {
+					addScore(node.Parent.Parent, score/2)
+				}
+			}
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	walk(doc.Body)
+
+	var best *dom.Node
+	bestScore := 0.0
+	for _, node := range order {
+		score := scores[node]*(1-linkDensity(node)) + classWeight(node)
+		if node.Tag == "article" || node.Tag == "main" {
+			score += 25
+		}
+		if score > bestScore {
+			best, bestScore = node, score
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	// A lone <article> beats the block chosen inside it
+	if article := best.ClosestAncestor("article"); article != nil {
+		best = article
+	}
+	if len(strings.TrimSpace(collapseSpace(best.TextContent()))) < minArticleLength {
+		return nil
+	}
+
+	content := best.Clone()
+	cleanArticle(content, doc.BaseURL)
+	byline, _ := doc.Meta("author")
+	return &readerArticle{title: doc.Title(), byline: byline, content: content}
+}
+
+// collapseSpace turns every run of whitespace in s into one space
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// linkDensity returns the share of node's text that is link text
+func linkDensity(node *dom.Node) float64 {
+	total := len(collapseSpace(node.TextContent()))
+	if total == 0 {
+		return 0
+	}
+	links := 0
+	for _, a := range node.GetElementsByTagName("a") {
+		links += len(collapseSpace(a.TextContent()))
+	}
+	return min(float64(links)/float64(total), 1)
+}
+
+// classWeight scores node's class and id names: 25 for names of content,
+// -25 for names of page furniture
+func classWeight(node *dom.Node) float64 {
+	weight := 0.0
+	for _, name := range []string{node.GetAttr("class"), node.GetAttr("id")} {
+		if name == "" {
+			continue
+		}
+		if readerNegative.MatchString(name) {
+			weight -= 25
+		}
+		if readerPositive.MatchString(name) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// cleanArticle strips node's subtree down to its text, links, images and
+// structure, with URLs made absolute against baseURL
+func cleanArticle(node *dom.Node, baseURL string) {
+	for i := 0; i < len(node.Children); i++ {
+		child := node.Children[i]
+		if child.Type == dom.NodeElement {
+			text := len(collapseSpace(child.TextContent()))
+			furniture := classWeight(child) < 0 && (text < 200 || linkDensity(child) > 0.3)
+			if readerDropped[child.Tag] || furniture {
+				node.RemoveChild(child)
+				i--
+				continue
+			}
+		}
+		cleanArticle(child, baseURL)
+	}
+	if node.Type != dom.NodeElement {
+		return
+	}
+
+	kept := make(map[string]string)
+	for _, name := range readerAttributes[node.Tag] {
+		if value := node.GetAttr(name); value != "" {
+			kept[name] = value
+		}
+	}
+	if node.Tag == "img" && kept["src"] == "" {
+		// Lazy-loaded images keep their picture in a data- attribute
+		for _, name := range []string{"data-src", "data-original", "data-lazy-src"} {
+			if value := node.GetAttr(name); value != "" {
+				kept["src"] = value
+				break
+			}
+		}
+	}
+	for _, name := range []string{"href", "src"} {
+		if value, ok := kept[name]; ok && !strings.HasPrefix(value, "#") {
+			kept[name] = spidergopher.ResolveURL(value, baseURL)
+		}
+	}
+	node.Attributes = kept
+}
+
+// readerPage builds the reader page of an article from pageURL, styled by
+// the settings
+func readerPage(article *readerArticle, pageURL string, settings *Settings) string {
+	fontSize, width, theme := DefaultReaderFontSize, DefaultReaderWidth, DefaultReaderTheme
+	if settings != nil {
+		if settings.ReaderFontSize > 0 {
+			fontSize = settings.ReaderFontSize
+		}
+		if settings.ReaderWidth > 0 {
+			width = settings.ReaderWidth
+		}
+		if _, ok := readerThemes[settings.ReaderTheme]; ok {
+			theme = settings.ReaderTheme
+		}
+	}
+	colors := readerThemes[theme]
+
+	byline := ""
+	if u, err := url.Parse(pageURL); err == nil && u.Host != "" {
+		byline = u.Host
+	}
+	if article.byline != "" {
+		byline = strings.TrimPrefix(byline+" · "+article.byline, " · ")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("<!DOCTYPE html><html><head><meta charset=\"utf-8\">")
+	fmt.Fprintf(&sb, "<title>%s</title>", html.EscapeString(article.title))
+	fmt.Fprintf(&sb, `<style>
+html, body { background: %[1]s; color: %[2]s; margin: 0; }
+body { font-size: %[5]dpx; line-height: 1.6; }
+.reader { max-width: %[6]dpx; margin: 0 auto; padding: 32px 24px; }
+.reader h1 { font-size: 1.6em; line-height: 1.25; margin: 0 0 8px; }
+.reader .byline { color: %[4]s; font-size: 0.8em; margin: 0 0 24px; }
+.reader a { color: %[3]s; }
+.reader img { max-width: 100%%; }
+.reader pre, .reader code { font-size: 0.85em; }
+.reader blockquote { color: %[4]s; margin-left: 0; padding-left: 16px; border-left: 3px solid %[4]s; }
+</style></head><body><div class="reader">`, colors[0], colors[1], colors[2], colors[3], fontSize, width)
+	if article.title != "" {
+		fmt.Fprintf(&sb, "<h1>%s</h1>", html.EscapeString(article.title))
+	}
+	if byline != "" {
+		fmt.Fprintf(&sb, "<p class=\"byline\">%s</p>", html.EscapeString(byline))
+	}
+	sb.WriteString(article.content.InnerHTML())
+	sb.WriteString("</div></body></html>")
+	return sb.String()
+}
@@ -0,0 +1,62 @@
+package browser
+
+import (
+	"time"
+
+	"go-browser/dom"
+)
+
+// =============================================================================
+// BASE URL AND REFRESH
+// A page's relative links, images, media and stylesheets resolve against its
+// document's base URL, which <base href> may move away from the page's own.
+// <meta http-equiv="refresh"> reloads the page, or goes to another, after a
+// delay; the new page replaces it in history as browsers do.
+// =============================================================================
+
+// documentBaseURL returns the base URL of the document node belongs to, or
+// "" for a detached node
+func documentBaseURL(node *dom.Node) string {
+	if doc := node.OwnerDocument(); doc != nil {
+		return doc.BaseURL
+	}
+	return ""
+}
+
+// documentURL returns the address of the document node belongs to, the
+// page its images load for, or "" for a detached node
+func documentURL(node *dom.Node) string {
+	if doc := node.OwnerDocument(); doc != nil {
+		return doc.URL
+	}
+	return ""
+}
+
+// scheduleRefresh reads the new document's refresh, replacing any the last
+// page asked for
+func (t *Tab) scheduleRefresh() {
+	t.refreshAt, t.refreshURL = time.Time{}, ""
+	delay, target, ok := t.Document.Refresh()
+	if !ok {
+		return
+	}
+	t.refreshURL = t.BaseURL
+	if target != "" {
+		t.refreshURL = t.Document.ResolveURL(target)
+	}
+	t.refreshAt = time.Now().Add(time.Duration(delay * float64(time.Second)))
+}
+
+// stepRefresh follows the page's refresh once its delay is up
+func (t *Tab) stepRefresh() {
+	if t.refreshAt.IsZero() || time.Now().Before(t.refreshAt) || t.IsLoading {
+		return
+	}
+	target := t.refreshURL
+	t.refreshAt, t.refreshURL = time.Time{}, ""
+	if t.HistoryPos >= 0 {
+		t.History[t.HistoryPos] = target
+	}
+	t.URL = target
+	t.LoadFromURL(target)
+}
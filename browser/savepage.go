@@ -0,0 +1,330 @@
+package browser
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"go-browser/dom"
+	"go-browser/logging"
+	"go-browser/spidergopher"
+)
+
+// =============================================================================
+// SAVE PAGE
+// Ctrl+S saves the page as it stands, with the changes its scripts made,
+// where the user chooses: NAME.html beside a NAME_files folder holding the
+// stylesheets and images it uses. Their URLs are rewritten to point into
+// the folder, as are the url()s and @imports inside the stylesheets, so the
+// copy opens offline through file://. Links, forms, frames and scripts are
+// made absolute and keep pointing at the web.
+// =============================================================================
+
+// Limits on what one saved page fetches
+const (
+	maxSavedResources    = 500
+	maxSavedResourceSize = 20 << 20
+)
+
+// cssReference matches a url() or a quoted @import in CSS
+var cssReference = regexp.MustCompile(`(?i)url\(\s*(?:"([^"]*)"|'([^']*)'|([^)'"\s]*))\s*\)|@import\s+(?:"([^"]*)"|'([^']*)')`)
+
+// cssImportBefore matches the @import a url() belongs to
+var cssImportBefore = regexp.MustCompile(`(?i)@import\s*$`)
+
+// fileNameUnsafe matches what a saved resource's file name may not contain
+var fileNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// pageSaver fetches the resources of a page being saved into its folder
+type pageSaver struct {
+	client *http.Client
+	dir    string            // the folder on disk
+	folder string            // its name escaped, as the page refers to it
+	saved  map[string]string // file name each URL was saved as; "" when it wasn't
+	taken  map[string]bool   // file names used in the folder
+}
+
+// savePage asks where to save the page and saves it there with its
+// resources, off the main thread
+func (t *Tab) savePage() {
+	if t.Document == nil || t.Document.DocumentElement == nil || len(t.frames) > 0 {
+		logging.App.Info("nothing to save")
+		return
+	}
+	name := pageFileName(t.PageTitle) + ".html"
+	root := t.Document.DocumentElement.Clone()
+	doctype, baseURL := t.Document.Doctype, t.Document.BaseURL
+	go func() {
+		dest := t.askDestination(name, filepath.Join(t.Settings.DownloadLocation(), name))
+		if dest == "" {
+			logging.App.Info("save cancelled", "file", name)
+			return
+		}
+		start := time.Now()
+		count, err := savePageTo(t.browser.client, root, doctype, baseURL, dest)
+		if err != nil {
+			logging.App.Error("saving page", "err", err)
+			return
+		}
+		logging.App.Info("saved page", "url", baseURL, "resources", count, "path", dest, "took", time.Since(start).Round(time.Millisecond))
+	}()
+}
+
+// savePageTo writes root, the <html> element of a page at baseURL, to dest
+// with its resources, downloaded through client, in a folder beside it, and
+// returns how many it saved
+func savePageTo(client *http.Client, root *dom.Node, doctype, baseURL, dest string) (int, error) {
+	folder := strings.TrimSuffix(filepath.Base(dest), filepath.Ext(dest)) + "_files"
+	timed := *client
+	timed.Timeout = 20 * time.Second
+	s := &pageSaver{
+		client: &timed,
+		dir:    filepath.Join(filepath.Dir(dest), folder),
+		folder: url.PathEscape(folder),
+		saved:  make(map[string]string),
+		taken:  make(map[string]bool),
+	}
+	s.rewriteTree(root, baseURL)
+
+	html := root.OuterHTML()
+	if doctype != "" {
+		html = "<!DOCTYPE " + doctype + ">\n" + html
+	}
+	count := 0
+	for _, name := range s.saved {
+		if name != "" {
+			count++
+		}
+	}
+	return count, saveDownload(strings.NewReader(html), dest)
+}
+
+// rewriteTree points the references of node and its subtree at the saved
+// copies of what they refer to, or makes them absolute
+func (s *pageSaver) rewriteTree(node *dom.Node, base string) {
+	for i := 0; i < len(node.Children); i++ {
+		child := node.Children[i]
+		// A <base> would redirect the folder's relative references
+		if child.Type == dom.NodeElement && child.Tag == "base" {
+			node.RemoveChild(child)
+			i--
+			continue
+		}
+		s.rewriteTree(child, base)
+	}
+	if node.Type != dom.NodeElement {
+		return
+	}
+
+	if style := node.GetAttr("style"); style != "" {
+		node.SetAttr("style", s.rewriteCSS(style, base, s.folder+"/"))
+	}
+	switch node.Tag {
+	case "img":
+		s.rewriteAttr(node, "src", base)
+		s.rewriteSrcset(node, base)
+	case "source":
+		if node.Parent != nil && node.Parent.Tag == "picture" {
+			s.rewriteAttr(node, "src", base)
+			s.rewriteSrcset(node, base)
+		}
+	case "input":
+		if strings.EqualFold(node.GetAttr("type"), "image") {
+			s.rewriteAttr(node, "src", base)
+		}
+	case "video":
+		s.rewriteAttr(node, "poster", base)
+	case "body", "table", "td", "th":
+		s.rewriteAttr(node, "background", base)
+	case "link":
+		rel := " " + strings.ToLower(node.GetAttr("rel")) + " "
+		switch {
+		case strings.Contains(rel, " stylesheet "):
+			s.rewriteStylesheetLink(node, base)
+		case strings.Contains(rel, " icon "):
+			s.rewriteAttr(node, "href", base)
+		default:
+			absolutize(node, "href", base)
+		}
+	case "style":
+		for _, child := range node.Children {
+			if child.Type == dom.NodeText {
+				child.Content = s.rewriteCSS(child.Content, base, s.folder+"/")
+			}
+		}
+	case "meta":
+		// The copy is written in UTF-8 whatever the page was sent in
+		if node.HasAttr("charset") {
+			node.SetAttr("charset", "utf-8")
+		} else if strings.EqualFold(node.GetAttr("http-equiv"), "content-type") {
+			node.SetAttr("content", "text/html; charset=utf-8")
+		}
+	case "a", "area":
+		if !strings.HasPrefix(node.GetAttr("href"), "#") {
+			absolutize(node, "href", base)
+		}
+	case "form":
+		absolutize(node, "action", base)
+	case "script", "iframe", "frame", "audio", "embed":
+		absolutize(node, "src", base)
+	}
+}
+
+// absolutize resolves a URL attribute of node against base
+func absolutize(node *dom.Node, attr, base string) {
+	if ref := node.GetAttr(attr); ref != "" {
+		node.SetAttr(attr, spidergopher.ResolveURL(ref, base))
+	}
+}
+
+// reference saves the resource ref refers to and returns what the page
+// refers to it by: its path in the folder, or its absolute URL when it
+// couldn't be saved
+func (s *pageSaver) reference(ref, base, prefix string, css bool) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "data:") || strings.HasPrefix(ref, "#") {
+		return ref
+	}
+	abs := spidergopher.ResolveURL(ref, base)
+	if name := s.fetch(abs, css); name != "" {
+		return prefix + name
+	}
+	return abs
+}
+
+// rewriteAttr points a URL attribute of node at the saved resource
+func (s *pageSaver) rewriteAttr(node *dom.Node, attr, base string) {
+	if ref := node.GetAttr(attr); ref != "" {
+		node.SetAttr(attr, s.reference(ref, base, s.folder+"/", false))
+	}
+}
+
+// rewriteSrcset points every candidate of node's srcset at its saved image
+func (s *pageSaver) rewriteSrcset(node *dom.Node, base string) {
+	srcset := node.GetAttr("srcset")
+	if srcset == "" {
+		return
+	}
+	candidates := strings.Split(srcset, ",")
+	for i, candidate := range candidates {
+		fields := strings.Fields(candidate)
+		if len(fields) == 0 {
+			continue
+		}
+		fields[0] = s.reference(fields[0], base, s.folder+"/", false)
+		candidates[i] = strings.Join(fields, " ")
+	}
+	node.SetAttr("srcset", strings.Join(candidates, ", "))
+}
+
+// rewriteStylesheetLink points a <link rel="stylesheet"> at the saved sheet
+func (s *pageSaver) rewriteStylesheetLink(node *dom.Node, base string) {
+	if ref := node.GetAttr("href"); ref != "" {
+		node.SetAttr("href", s.reference(ref, base, s.folder+"/", true))
+		// The saved sheet's references differ from the original's
+		node.RemoveAttr("integrity")
+	}
+}
+
+// rewriteCSS points the url()s and @imports of cssText, a stylesheet at
+// base, at saved copies whose paths start with prefix
+func (s *pageSaver) rewriteCSS(cssText, base, prefix string) string {
+	var out strings.Builder
+	last := 0
+	for _, m := range cssReference.FindAllStringSubmatchIndex(cssText, -1) {
+		out.WriteString(cssText[last:m[0]])
+		last = m[1]
+		ref, group := "", 0
+		for g := 1; g <= 5; g++ {
+			if m[2*g] >= 0 {
+				ref, group = cssText[m[2*g]:m[2*g+1]], g
+				break
+			}
+		}
+		isImport := group >= 4 || cssImportBefore.MatchString(cssText[:m[0]])
+		target := s.reference(ref, base, prefix, isImport)
+		if group >= 4 {
+			out.WriteString(`@import "` + target + `"`)
+		} else {
+			out.WriteString(`url("` + target + `")`)
+		}
+	}
+	out.WriteString(cssText[last:])
+	return out.String()
+}
+
+// fetch saves the resource at u in the folder, rewriting it first when it
+// is a stylesheet, and returns its file name there; "" when it can't
+func (s *pageSaver) fetch(u string, css bool) string {
+	if name, ok := s.saved[u]; ok {
+		return name
+	}
+	// Claimed before fetching, so a stylesheet importing itself stops
+	s.saved[u] = ""
+	if (!strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") && !strings.HasPrefix(u, "file://")) || len(s.saved) > maxSavedResources {
+		return ""
+	}
+	resp, err := s.client.Get(u)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSavedResourceSize))
+	if err != nil {
+		return ""
+	}
+
+	name := s.fileName(resp.Request.URL.Path, resp.Header.Get("Content-Type"), css)
+	if css {
+		// The sheet's own references sit beside it in the folder
+		body = []byte(s.rewriteCSS(string(body), resp.Request.URL.String(), ""))
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return ""
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, name), body, 0o644); err != nil {
+		return ""
+	}
+	s.saved[u] = name
+	return name
+}
+
+// fileName picks an unused name in the folder for a resource at urlPath,
+// with an extension for its content type when the path has none
+func (s *pageSaver) fileName(urlPath, contentType string, css bool) string {
+	name := fileNameUnsafe.ReplaceAllString(path.Base(urlPath), "_")
+	name = strings.Trim(name, "._")
+	if name == "" {
+		name = "file"
+	}
+	ext := path.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	switch {
+	case css && ext != ".css":
+		ext = ".css"
+	case ext == "":
+		if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+			if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+				// Sorted, so image/jpeg would be .jfif
+				ext = exts[len(exts)-1]
+			}
+		}
+	}
+	name = stem + ext
+	for n := 2; s.taken[strings.ToLower(name)]; n++ {
+		name = fmt.Sprintf("%s-%d%s", stem, n, ext)
+	}
+	s.taken[strings.ToLower(name)] = true
+	return name
+}
@@ -0,0 +1,194 @@
+package browser
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"go-browser/css"
+	"go-browser/spidergopher/webapi"
+)
+
+// =============================================================================
+// URL SCHEMES
+// URLs of schemes other than http and https load through a handler
+// registered for the scheme: file: and data: are built in, and embedders
+// add their own with RegisterScheme (gopher://, ipfs://, app:...). A
+// handler returns the content of a URL with its media type, and serves
+// every way a URL is loaded: navigating to it, where HTML shows as a page
+// and images and text on their own, the images, stylesheets and scripts of
+// pages, and fetch().
+// =============================================================================
+
+// SchemeHandler returns the content at u, a URL of the scheme it was
+// registered for, and its media type such as "text/html"
+type SchemeHandler func(u *url.URL) (mediaType string, content []byte, err error)
+
+// reservedSchemes are the schemes whose loading RegisterScheme can't change
+var reservedSchemes = map[string]bool{
+	"http": true, "https": true, "file": true, "about": true, "view-source": true, "gobrowser": true,
+}
+
+// schemes are the handlers of the schemes that have one
+var schemes = struct {
+	sync.RWMutex
+	handlers map[string]SchemeHandler
+}{handlers: map[string]SchemeHandler{
+	"file": serveFile,
+	"data": serveData,
+}}
+
+// RegisterScheme makes URLs of scheme load through handler, in every tab
+// and in the fetch() of page scripts; a nil handler removes the scheme.
+// Register schemes before NewApp. http, https, file, about, view-source
+// and gobrowser are the browser's own.
+func RegisterScheme(scheme string, handler SchemeHandler) error {
+	scheme = strings.ToLower(scheme)
+	if !validScheme(scheme) {
+		return fmt.Errorf("invalid URL scheme %q", scheme)
+	}
+	if reservedSchemes[scheme] {
+		return fmt.Errorf("the %s: scheme can't be registered", scheme)
+	}
+	schemes.Lock()
+	defer schemes.Unlock()
+	if handler == nil {
+		delete(schemes.handlers, scheme)
+		webapi.AllowFetchScheme(scheme, false)
+		return nil
+	}
+	schemes.handlers[scheme] = handler
+	webapi.AllowFetchScheme(scheme, true)
+	return nil
+}
+
+// validScheme reports whether s is a URL scheme: a letter followed by
+// letters, digits, + - or .
+func validScheme(s string) bool {
+	for i, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z':
+		case i > 0 && (c >= '0' && c <= '9' || c == '+' || c == '-' || c == '.'):
+		default:
+			return false
+		}
+	}
+	return s != ""
+}
+
+// urlScheme returns the lowercase scheme rawURL starts with, or ""
+func urlScheme(rawURL string) string {
+	i := strings.IndexByte(rawURL, ':')
+	if i <= 0 {
+		return ""
+	}
+	if scheme := strings.ToLower(rawURL[:i]); validScheme(scheme) {
+		return scheme
+	}
+	return ""
+}
+
+// schemeHandler returns the handler of rawURL's scheme, or nil
+func schemeHandler(rawURL string) SchemeHandler {
+	return lookupScheme(urlScheme(rawURL))
+}
+
+// lookupScheme returns the handler registered for scheme, or nil
+func lookupScheme(scheme string) SchemeHandler {
+	schemes.RLock()
+	defer schemes.RUnlock()
+	return schemes.handlers[scheme]
+}
+
+// schemeTransport serves requests of the registered schemes and passes the
+// rest on
+type schemeTransport struct {
+	next http.RoundTripper
+}
+
+func (t *schemeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	handler := lookupScheme(req.URL.Scheme)
+	if handler == nil {
+		return t.next.RoundTrip(req)
+	}
+	mediaType, content, err := handler(req.URL)
+	if err != nil {
+		return nil, err
+	}
+	resp := &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.0",
+		ProtoMajor:    1,
+		Header:        make(http.Header),
+		Body:          io.NopCloser(bytes.NewReader(content)),
+		ContentLength: int64(len(content)),
+		Request:       req,
+	}
+	if mediaType != "" {
+		resp.Header.Set("Content-Type", mediaType)
+	}
+	return resp, nil
+}
+
+func init() {
+	// Pages may fetch() data: URLs, but not local files
+	webapi.AllowFetchScheme("data", true)
+}
+
+// loadSchemeURL shows the content handler returns for urlStr, off the main
+// thread as a handler may take a while
+func (t *Tab) loadSchemeURL(urlStr string, handler SchemeHandler) {
+	t.BaseURL = urlStr
+	t.Security = nil
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		t.ErrorMsg = "Invalid address: " + err.Error()
+		return
+	}
+	t.ErrorMsg = ""
+
+	load := t.startLoad()
+	t.IsLoading = true
+	t.Progress = 0.1
+	media := t.pageMedia()
+	go func() {
+		mediaType, content, err := handler(u)
+		if err == nil {
+			t.reportProgress(load, 0.7)
+			var page *preparedPage
+			if page, err = prepareContent(mediaType, content, urlStr, media, t.browser.client); err == nil {
+				page.load = load
+				t.reportProgress(load, 0.75)
+				t.offerPage(page)
+				return
+			}
+		}
+		t.failLoad(load, err.Error())
+	}()
+}
+
+// prepareContent prepares the page showing content of mediaType from
+// pageURL, styled for media with stylesheets fetched through client: HTML
+// as a page, an image or text on its own. Content without a type is taken
+// for HTML.
+func prepareContent(mediaType string, content []byte, pageURL string, media css.Media, client *http.Client) (*preparedPage, error) {
+	mt := "text/html"
+	if mediaType != "" {
+		mt, _, _ = mime.ParseMediaType(mediaType)
+	}
+	switch {
+	case mt == "text/html" || mt == "application/xhtml+xml":
+		return preparePage(string(content), pageURL, nil, media, client), nil
+	case strings.HasPrefix(mt, "image/"):
+		return prepareDocument(imageDocument(pageURL), nil, media, client), nil
+	case strings.HasPrefix(mt, "text/") || mt == "application/json" || mt == "application/javascript" || mt == "application/xml":
+		return prepareDocument(textDocument(string(content), pageURL), nil, media, client), nil
+	}
+	return nil, fmt.Errorf("Cannot show %s: content of type %s", urlScheme(pageURL), mediaType)
+}
@@ -0,0 +1,75 @@
+package browser
+
+import (
+	"fmt"
+	"image/color"
+	"time"
+
+	"go-browser/render"
+	"go-browser/spidergopher"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// =============================================================================
+// SCRIPT ERRORS
+// Errors a page's scripts leave uncaught are logged to the console by the
+// engine; the tab also shows the latest for a few seconds in a toast at the
+// bottom of the page, with the file and line it was thrown at
+// =============================================================================
+
+const (
+	errorToastDuration = 6 * time.Second
+	errorToastWidth    = 520
+	errorToastHeight   = 36
+)
+
+// ColorErrorToastText is the color of the error in the toast
+var ColorErrorToastText = color.RGBA{255, 140, 140, 255}
+
+// scriptError notes an error the page didn't handle. The engine may call it
+// from its event loop, so it only queues the error for the next update.
+func (t *Tab) scriptError(err spidergopher.ScriptError) {
+	select {
+	case t.scriptErrors <- err:
+	default:
+	}
+}
+
+// takeScriptErrors moves the errors queued since the last update to the toast
+func (t *Tab) takeScriptErrors() {
+	for len(t.scriptErrors) > 0 {
+		err := <-t.scriptErrors
+		if time.Now().After(t.errorToastUntil) {
+			t.errorCount = 0
+		}
+		t.errorCount++
+		t.errorToast = err.String()
+		t.errorToastUntil = time.Now().Add(errorToastDuration)
+	}
+}
+
+// clearScriptErrors drops the errors of the page being left
+func (t *Tab) clearScriptErrors() {
+	for len(t.scriptErrors) > 0 {
+		<-t.scriptErrors
+	}
+	t.errorToast, t.errorCount, t.errorToastUntil = "", 0, time.Time{}
+}
+
+// drawErrorToast draws the latest script error of the current tab while it
+// is fresh
+func (a *App) drawErrorToast(screen *ebiten.Image) {
+	if a.errorToast == "" || time.Now().After(a.errorToastUntil) {
+		return
+	}
+	text := a.errorToast
+	if a.errorCount > 1 {
+		text = fmt.Sprintf("(%d errors) %s", a.errorCount, text)
+	}
+	x := float32(Padding)
+	y := float32(WindowHeight - errorToastHeight - 12)
+	render.DrawRoundedRect(screen, x, y, errorToastWidth, errorToastHeight, 8, ColorFindBar)
+	text = render.TruncateText(text, errorToastWidth-24, FontSizeUI)
+	render.DrawText(screen, text, float64(x)+12, float64(y)+(errorToastHeight-FontSizeUI)/2, FontSizeUI, ColorErrorToastText)
+}
@@ -0,0 +1,113 @@
+package browser
+
+import (
+	"time"
+
+	"go-browser/dom"
+	"go-browser/layout"
+	"go-browser/perf"
+)
+
+// =============================================================================
+// SCROLL ANCHORING
+// A relayout keeps the element at the top of the viewport where it was, and
+// history navigation returns to the scroll position the page was left at
+// =============================================================================
+
+// scrollAnchor is an element near the top of the viewport and how far below
+// the viewport top its box started
+type scrollAnchor struct {
+	node   *dom.Node
+	offset float64
+}
+
+// findScrollAnchor picks the anchor for a viewport whose top is at viewTop in
+// render tree coordinates: the first box whose top is visible, or the
+// deepest box straddling the viewport top
+func findScrollAnchor(box *layout.RenderBox, viewTop float64) *scrollAnchor {
+	for _, child := range box.Children {
+		if child.Node == nil || child.IsFixed || child.H <= 0 || child.Y+child.H <= viewTop {
+			continue
+		}
+		if child.Y >= viewTop {
+			return &scrollAnchor{node: child.Node, offset: child.Y - viewTop}
+		}
+		if inner := findScrollAnchor(child, viewTop); inner != nil {
+			return inner
+		}
+		return &scrollAnchor{node: child.Node, offset: child.Y - viewTop}
+	}
+	return nil
+}
+
+// maxScroll returns the most negative ScrollY that still shows content
+func (t *Tab) maxScroll() float64 {
+	if t.RenderTree == nil {
+		return 0
+	}
+	return min(0, t.viewHeight()-t.RenderTree.H)
+}
+
+// layoutWidth returns the width the page lays out in: the window's, the
+// frame's for a nested tab, or the layout viewport's in device mode
+func (t *Tab) layoutWidth() float64 {
+	if t.device != nil {
+		return t.viewport.width - Padding*2
+	}
+	if t.frameW > 0 {
+		return t.frameW - Padding*2
+	}
+	return WindowWidth - (Padding * 2)
+}
+
+// viewHeight returns the height of the viewport showing the page
+func (t *Tab) viewHeight() float64 {
+	if t.device != nil {
+		_, h := t.devicePageSize()
+		return h - (ContentTop - ChromeHeight)
+	}
+	if t.frameH > 0 {
+		return t.frameH - Padding
+	}
+	return float64(WindowHeight) - ContentTop
+}
+
+// relayout rebuilds the render tree, scrolling so the element that was at the
+// top of the viewport stays there
+func (t *Tab) relayout() {
+	var anchor *scrollAnchor
+	if t.RenderTree != nil && t.ScrollY < 0 {
+		anchor = findScrollAnchor(t.RenderTree, -t.ScrollY)
+	}
+	t.restyleForMedia()
+	start := time.Now()
+	t.RenderTree = layout.BuildRenderTree(t.Document.Node, t.layoutWidth(), t.userColumnWidths)
+	perf.Since(perf.StageLayout, start)
+	t.layoutFullscreen()
+	t.layoutDialog()
+	if anchor == nil {
+		return
+	}
+	if box := findBoxForNode(t.RenderTree, anchor.node); box != nil {
+		t.shiftScroll(anchor.offset - box.Y - t.ScrollY)
+	}
+}
+
+// saveScroll records the scroll position of the current history entry
+func (t *Tab) saveScroll() {
+	if t.HistoryPos < 0 {
+		return
+	}
+	for len(t.historyScroll) <= t.HistoryPos {
+		t.historyScroll = append(t.historyScroll, 0)
+	}
+	t.historyScroll[t.HistoryPos] = t.ScrollY
+}
+
+// savedScroll returns the scroll position recorded for history entry i
+func (t *Tab) savedScroll(i int) float64 {
+	if i < 0 || i >= len(t.historyScroll) {
+		return 0
+	}
+	return t.historyScroll[i]
+}
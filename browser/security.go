@@ -0,0 +1,291 @@
+package browser
+
+import (
+	"crypto/tls"
+	"fmt"
+	"image/color"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-browser/css"
+	"go-browser/dom"
+	"go-browser/render"
+	"go-browser/spidergopher"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// =============================================================================
+// CONNECTION SECURITY
+// A padlock in the URL bar shows whether the page came over HTTPS; clicking
+// it opens a panel with the TLS details, any mixed content found and the
+// site information of siteinfo.go
+// =============================================================================
+
+const (
+	securityIconWidth   = 22.0
+	securityPanelWidth  = 460.0
+	securityPanelLineH  = 20.0
+	securityPanelMargin = 12.0
+)
+
+var (
+	ColorSecure      = color.RGBA{60, 60, 66, 255}
+	ColorNotSecure   = color.RGBA{200, 60, 50, 255}
+	ColorMixedWarn   = color.RGBA{230, 140, 20, 255}
+	ColorSecurityBg  = color.RGBA{255, 255, 255, 250}
+	ColorSecurityDim = color.RGBA{110, 110, 120, 255}
+)
+
+// PageSecurity describes how the current page was delivered
+type PageSecurity struct {
+	HTTPS        bool
+	TLSVersion   string
+	CipherSuite  string
+	Certificates []CertificateInfo // chain as sent by the server, leaf first
+	MixedContent []MixedContent    // http:// subresources of an https:// page
+}
+
+// CertificateInfo summarizes one certificate of the chain
+type CertificateInfo struct {
+	Subject  string
+	Issuer   string
+	NotAfter time.Time
+}
+
+// MixedContent is an insecure subresource of a secure page. Scripts,
+// stylesheets and frames are blocked; images and media are only flagged.
+type MixedContent struct {
+	Kind    string // script, stylesheet, frame, image, media
+	URL     string
+	Blocked bool
+}
+
+// securityFromResponse records the connection details of a page response
+func securityFromResponse(resp *http.Response) *PageSecurity {
+	sec := &PageSecurity{}
+	if resp.TLS == nil {
+		return sec
+	}
+	sec.HTTPS = true
+	sec.TLSVersion = tls.VersionName(resp.TLS.Version)
+	sec.CipherSuite = tls.CipherSuiteName(resp.TLS.CipherSuite)
+	for _, cert := range resp.TLS.PeerCertificates {
+		sec.Certificates = append(sec.Certificates, CertificateInfo{
+			Subject:  certName(cert.Subject.CommonName, cert.Subject.Organization),
+			Issuer:   certName(cert.Issuer.CommonName, cert.Issuer.Organization),
+			NotAfter: cert.NotAfter,
+		})
+	}
+	return sec
+}
+
+// certName prefers the common name, falling back to the organization
+func certName(commonName string, org []string) string {
+	if commonName != "" {
+		return commonName
+	}
+	if len(org) > 0 {
+		return org[0]
+	}
+	return "(unnamed)"
+}
+
+// IsMixedContent reports whether loading resourceURL from pageURL would pull
+// insecure content into a secure page
+func IsMixedContent(pageURL, resourceURL string) bool {
+	return strings.HasPrefix(pageURL, "https://") && strings.HasPrefix(strings.ToLower(resourceURL), "http://")
+}
+
+// mixedContentKinds maps subresource tags to their kind and whether loading
+// them over http:// from an https:// page is blocked
+var mixedContentKinds = map[string]struct {
+	kind    string
+	blocked bool
+}{
+	"script": {"script", true},
+	"link":   {"stylesheet", true},
+	"iframe": {"frame", true},
+	"img":    {"image", false},
+	"video":  {"media", false},
+	"audio":  {"media", false},
+	"source": {"media", false},
+}
+
+// subresource is a resource a document loads besides itself
+type subresource struct {
+	kind           string // script, stylesheet, frame, image, media
+	url            string // absolute
+	blockedIfMixed bool   // refused over http:// from an https:// page
+}
+
+// subresources lists the resources the document loads, each once: tags with
+// src/href and CSS images from the computed styles
+func subresources(doc *dom.Document) []subresource {
+	var found []subresource
+	seen := map[string]bool{}
+	add := func(kind, ref string, blocked bool) {
+		abs := spidergopher.ResolveURL(ref, doc.BaseURL)
+		if seen[abs] {
+			return
+		}
+		seen[abs] = true
+		found = append(found, subresource{kind: kind, url: abs, blockedIfMixed: blocked})
+	}
+
+	var walk func(n *dom.Node)
+	walk = func(n *dom.Node) {
+		if info, ok := mixedContentKinds[n.Tag]; ok {
+			if n.Tag == "link" {
+				if strings.Contains(strings.ToLower(n.GetAttr("rel")), "stylesheet") {
+					add(info.kind, n.GetAttr("href"), info.blocked)
+				}
+			} else if src := n.GetAttr("src"); src != "" {
+				add(info.kind, src, info.blocked)
+			}
+		}
+		if cs, ok := n.ComputedStyle.(*css.ComputedStyle); ok {
+			if cs.BackgroundImage != "" {
+				add("image", cs.BackgroundImage, false)
+			}
+			if cs.CursorImage != "" {
+				add("image", cs.CursorImage, false)
+			}
+		}
+		for _, child := range n.Children {
+			walk(child)
+		}
+	}
+	walk(doc.Node)
+	return found
+}
+
+// findMixedContent lists the insecure subresources of the document
+func findMixedContent(doc *dom.Document) []MixedContent {
+	var found []MixedContent
+	for _, r := range subresources(doc) {
+		if IsMixedContent(doc.BaseURL, r.url) {
+			found = append(found, MixedContent{Kind: r.kind, URL: r.url, Blocked: r.blockedIfMixed})
+		}
+	}
+	return found
+}
+
+// securityIconContains reports whether the point is on the padlock area
+func (n *NavBar) securityIconContains(mx, my int) bool {
+	return !n.IsEditing && n.securityW > 0 &&
+		float32(mx) >= n.URLBarX && float32(mx) <= n.URLBarX+8+float32(n.securityW) &&
+		float32(my) >= n.URLBarY && float32(my) <= n.URLBarY+URLBarHeight
+}
+
+// drawSecurityIcon draws the padlock at the start of the URL bar, open and
+// followed by "Not secure" for plain http. It returns the width taken.
+func (n *NavBar) drawSecurityIcon(screen *ebiten.Image, sec *PageSecurity) float64 {
+	n.securityW = 0
+	if sec == nil {
+		return 0
+	}
+	clr := ColorSecure
+	if !sec.HTTPS {
+		clr = ColorNotSecure
+	} else if len(sec.MixedContent) > 0 {
+		clr = ColorMixedWarn
+	}
+
+	cx := n.URLBarX + 8 + securityIconWidth/2 - 2
+	cy := n.URLBarY + URLBarHeight/2
+	vector.DrawFilledRect(screen, cx-6, cy-2, 12, 9, clr, false)
+	vector.StrokeLine(screen, cx-4, cy-2, cx-4, cy-7, 2, clr, true)
+	vector.StrokeLine(screen, cx-5, cy-8, cx+5, cy-8, 2, clr, true)
+	if sec.HTTPS {
+		vector.StrokeLine(screen, cx+4, cy-2, cx+4, cy-7, 2, clr, true)
+	} else {
+		// Open shackle: the right leg doesn't reach the body
+		vector.StrokeLine(screen, cx+4, cy-5, cx+4, cy-7, 2, clr, true)
+	}
+
+	n.securityW = securityIconWidth
+	if !sec.HTTPS {
+		label := "Not secure"
+		render.DrawText(screen, label, float64(n.URLBarX)+8+securityIconWidth, float64(cy)-2, 12, ColorNotSecure)
+		n.securityW += render.MeasureText(label, 12) + 8
+	}
+	return n.securityW
+}
+
+// securityLine is one row of the security panel
+type securityLine struct {
+	text string
+	clr  color.RGBA
+}
+
+// securityLines describes the connection for the panel
+func securityLines(sec *PageSecurity) []securityLine {
+	if !sec.HTTPS {
+		return []securityLine{
+			{"Connection is not secure", ColorNotSecure},
+			{"Anything sent to or received from this site can be read in transit.", ColorSecurityDim},
+		}
+	}
+
+	lines := []securityLine{{"Connection is secure", ColorSecure}}
+	if len(sec.MixedContent) > 0 {
+		lines[0] = securityLine{"Connection is secure, but parts of this page are not", ColorMixedWarn}
+	}
+	lines = append(lines,
+		securityLine{"Protocol: " + sec.TLSVersion, ColorURLText},
+		securityLine{"Cipher suite: " + sec.CipherSuite, ColorURLText},
+		securityLine{"Certificate chain:", ColorURLText},
+	)
+	for _, cert := range sec.Certificates {
+		lines = append(lines, securityLine{
+			fmt.Sprintf("  %s, issued by %s, expires %s", cert.Subject, cert.Issuer, cert.NotAfter.Format("2006-01-02")),
+			ColorSecurityDim,
+		})
+	}
+	if len(sec.MixedContent) > 0 {
+		lines = append(lines, securityLine{"Mixed content:", ColorMixedWarn})
+		for _, mc := range sec.MixedContent {
+			verb := "Insecure"
+			if mc.Blocked {
+				verb = "Blocked"
+			}
+			lines = append(lines, securityLine{fmt.Sprintf("  %s %s: %s", verb, mc.Kind, mc.URL), ColorSecurityDim})
+		}
+	}
+	return lines
+}
+
+// securityPanelRect returns the panel's screen rectangle
+func (n *NavBar) securityPanelRect(app *App) (x, y, w, h float32) {
+	lines := len(app.siteInfoLines())
+	return n.URLBarX, n.URLBarY + URLBarHeight + 4, securityPanelWidth, float32(lines)*securityPanelLineH + securityPanelMargin*2
+}
+
+// securityPanelContains reports whether the open panel covers the point
+func (n *NavBar) securityPanelContains(app *App, mx, my int) bool {
+	if !n.securityOpen || app.Security == nil {
+		return false
+	}
+	x, y, w, h := n.securityPanelRect(app)
+	return float32(mx) >= x && float32(mx) <= x+w && float32(my) >= y && float32(my) <= y+h
+}
+
+// drawSecurityPanel renders the connection details under the padlock
+func (n *NavBar) drawSecurityPanel(screen *ebiten.Image, app *App) {
+	if !n.securityOpen || app.Security == nil {
+		return
+	}
+	x, y, w, h := n.securityPanelRect(app)
+	render.DrawRoundedRect(screen, x-1, y-1, w+2, h+2, 6, ColorBorder)
+	render.DrawRoundedRect(screen, x, y, w, h, 6, ColorSecurityBg)
+
+	ty := float64(y) + securityPanelMargin + securityPanelLineH/2 - 2
+	for _, line := range app.siteInfoLines() {
+		text := render.TruncateText(line.text, float64(w)-securityPanelMargin*2, 12)
+		render.DrawText(screen, text, float64(x)+securityPanelMargin, ty, 12, line.clr)
+		ty += securityPanelLineH
+	}
+}
@@ -0,0 +1,243 @@
+package browser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go-browser/css"
+)
+
+// =============================================================================
+// SETTINGS
+// User preferences, kept as JSON next to the shortcut overrides
+// =============================================================================
+
+// SearchEngine is a named URL template; %s is replaced by the escaped query
+type SearchEngine struct {
+	Name     string
+	Template string
+}
+
+// URL returns the search results address for query
+func (e SearchEngine) URL(query string) string {
+	return strings.Replace(e.Template, "%s", url.QueryEscape(query), 1)
+}
+
+// SearchEngines are the built-in engines, keyed by the name used in settings
+var SearchEngines = map[string]SearchEngine{
+	"duckduckgo": {Name: "DuckDuckGo", Template: "https://duckduckgo.com/?q=%s"},
+	"google":     {Name: "Google", Template: "https://www.google.com/search?q=%s"},
+	"bing":       {Name: "Bing", Template: "https://www.bing.com/search?q=%s"},
+}
+
+// DefaultSearchEngine is used when the settings name no usable engine
+const DefaultSearchEngine = "duckduckgo"
+
+// DefaultScrollSpeed is the wheel notch distance used when none is set
+const DefaultScrollSpeed = 40.0
+
+// DefaultImageDownloads is how many images download at once when no other
+// number is set
+const DefaultImageDownloads = 6
+
+// DefaultImageCacheMB is the megabytes of decoded images kept when no other
+// budget is set
+const DefaultImageCacheMB = 256
+
+// Reader mode's defaults: text size and line length in pixels, and theme
+const (
+	DefaultReaderFontSize = 20
+	DefaultReaderWidth    = 680
+	DefaultReaderTheme    = "light"
+)
+
+// DefaultColorScheme is the color scheme pages are asked for when none is set
+const DefaultColorScheme = "light"
+
+// DefaultHomepage is the home page used when none is set
+const DefaultHomepage = "https://example.com"
+
+// What the browser opens on startup when no URL is given
+const (
+	StartupHomepage = "homepage" // the home page
+	StartupRestore  = "restore"  // the tabs open when the browser last closed
+	StartupBlank    = "blank"    // an empty tab with the URL bar focused
+)
+
+// Settings holds the user's browser preferences
+type Settings struct {
+	// SearchEngine is a SearchEngines key, or a custom template such as
+	// "https://search.example/?q=%s"
+	SearchEngine string `json:"search_engine"`
+
+	// JavaScript and Images are the content defaults for every site; Sites
+	// overrides them per host (see contentsettings.go)
+	JavaScript bool                     `json:"javascript"`
+	Images     bool                     `json:"images"`
+	Sites      map[string]*SiteSettings `json:"sites,omitempty"`
+
+	// ImageDownloads is how many images download at once
+	ImageDownloads int `json:"image_downloads"`
+
+	// ImageCacheMB is the megabytes of decoded images kept in memory; the
+	// least recently drawn are dropped past it
+	ImageCacheMB int `json:"image_cache_mb"`
+
+	// ScrollSpeed is how far one wheel notch scrolls, in pixels;
+	// SmoothScrolling animates the page there instead of jumping
+	ScrollSpeed     float64 `json:"scroll_speed"`
+	SmoothScrolling bool    `json:"smooth_scrolling"`
+
+	// TableEnhancements makes every table sortable by its header cells and
+	// its columns resizable by dragging (see tables.go)
+	TableEnhancements bool `json:"table_enhancements"`
+
+	// Startup is one of the Startup constants; Homepage is what
+	// StartupHomepage and Alt+Home open
+	Startup  string `json:"startup"`
+	Homepage string `json:"homepage"`
+
+	// Downloads are saved to DownloadDir, by default the Downloads folder
+	// in the home directory; AskDownloadLocation asks for each file's path
+	DownloadDir         string `json:"download_dir,omitempty"`
+	AskDownloadLocation bool   `json:"ask_download_location"`
+
+	// Reader mode sets articles at ReaderFontSize pixels in a column
+	// ReaderWidth pixels wide, in the light, dark or sepia ReaderTheme
+	ReaderFontSize int    `json:"reader_font_size"`
+	ReaderWidth    int    `json:"reader_width"`
+	ReaderTheme    string `json:"reader_theme"`
+
+	// ColorScheme, "light" or "dark", is what the prefers-color-scheme
+	// media feature reports to pages' stylesheets and scripts
+	ColorScheme string `json:"color_scheme"`
+
+	// Log sets how much each log category prints, as logging.Configure
+	// reads it, such as "info,js=debug"; the --log flag overrides it
+	Log string `json:"log,omitempty"`
+}
+
+// DefaultSettings returns the preferences used before any are saved
+func DefaultSettings() *Settings {
+	return &Settings{
+		SearchEngine:    DefaultSearchEngine,
+		JavaScript:      true,
+		Images:          true,
+		ImageDownloads:  DefaultImageDownloads,
+		ImageCacheMB:    DefaultImageCacheMB,
+		ScrollSpeed:     DefaultScrollSpeed,
+		SmoothScrolling: true,
+		Startup:         StartupHomepage,
+		Homepage:        DefaultHomepage,
+		ReaderFontSize:  DefaultReaderFontSize,
+		ReaderWidth:     DefaultReaderWidth,
+		ReaderTheme:     DefaultReaderTheme,
+		ColorScheme:     DefaultColorScheme,
+	}
+}
+
+// LoadSettings reads settings from a JSON file; a missing file yields the
+// defaults, and fields absent from the file keep their default values
+func LoadSettings(path string) (*Settings, error) {
+	s := DefaultSettings()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return DefaultSettings(), fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Save writes the settings to path, creating its directory
+func (s *Settings) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Search returns the configured search engine. A custom template is named
+// after its host.
+func (s *Settings) Search() SearchEngine {
+	if engine, ok := SearchEngines[strings.ToLower(s.SearchEngine)]; ok {
+		return engine
+	}
+	if strings.Contains(s.SearchEngine, "%s") {
+		if u, err := url.Parse(s.SearchEngine); err == nil && u.Host != "" {
+			return SearchEngine{Name: strings.TrimPrefix(u.Host, "www."), Template: s.SearchEngine}
+		}
+	}
+	return SearchEngines[DefaultSearchEngine]
+}
+
+// HomepageURL returns the home page, DefaultHomepage when none is set
+func (s *Settings) HomepageURL() string {
+	if s == nil || strings.TrimSpace(s.Homepage) == "" {
+		return DefaultHomepage
+	}
+	return strings.TrimSpace(s.Homepage)
+}
+
+// DownloadLocation returns the directory downloads are saved to: the
+// configured one, else ~/Downloads, else the temporary directory
+func (s *Settings) DownloadLocation() string {
+	if s != nil && s.DownloadDir != "" {
+		return s.DownloadDir
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, "Downloads")
+	}
+	return os.TempDir()
+}
+
+// SettingsPath returns where the user's settings live: $GOBROWSER_SETTINGS,
+// or settings.json in the profile's directory
+func SettingsPath() string {
+	if path := os.Getenv("GOBROWSER_SETTINGS"); path != "" {
+		return path
+	}
+	return profilePath("settings.json")
+}
+
+// UserStylesheetPath returns where the user's stylesheet lives:
+// $GOBROWSER_USER_CSS, or gobrowser/user.css in the config directory
+func UserStylesheetPath() string {
+	if path := os.Getenv("GOBROWSER_USER_CSS"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gobrowser", "user.css")
+}
+
+// LoadUserStylesheet installs the CSS file at path as the user's
+// stylesheet, applied to every page; a missing file installs none
+func LoadUserStylesheet(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		css.SetUserStylesheet("")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	css.SetUserStylesheet(string(data))
+	return nil
+}
@@ -0,0 +1,365 @@
+package browser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// =============================================================================
+// KEYBOARD SHORTCUTS
+// Browser accelerators are matched here before any key reaches the page,
+// the URL bar or a form control
+// =============================================================================
+
+// Modifiers is a set of modifier keys
+type Modifiers uint8
+
+const (
+	ModCtrl  Modifiers = 1 << iota // Ctrl, or Cmd on macOS
+	ModShift                       // Shift
+	ModAlt                         // Alt / Option
+)
+
+// Accelerator is a key with the exact modifiers that must be held with it
+type Accelerator struct {
+	Key  ebiten.Key
+	Mods Modifiers
+}
+
+// ParseAccelerator parses names like "Ctrl+R", "Alt+Left" or "F5". "Cmd" and
+// "Meta" are accepted as aliases of Ctrl.
+func ParseAccelerator(s string) (Accelerator, error) {
+	var acc Accelerator
+	parts := strings.Split(s, "+")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if i < len(parts)-1 {
+			switch strings.ToLower(part) {
+			case "ctrl", "control", "cmd", "meta":
+				acc.Mods |= ModCtrl
+			case "shift":
+				acc.Mods |= ModShift
+			case "alt", "option":
+				acc.Mods |= ModAlt
+			default:
+				return Accelerator{}, fmt.Errorf("unknown modifier %q in %q", part, s)
+			}
+			continue
+		}
+		if err := acc.Key.UnmarshalText([]byte(part)); err != nil {
+			return Accelerator{}, fmt.Errorf("unknown key %q in %q", part, s)
+		}
+	}
+	return acc, nil
+}
+
+// String formats the accelerator the way ParseAccelerator reads it
+func (acc Accelerator) String() string {
+	var sb strings.Builder
+	if acc.Mods&ModCtrl != 0 {
+		sb.WriteString("Ctrl+")
+	}
+	if acc.Mods&ModAlt != 0 {
+		sb.WriteString("Alt+")
+	}
+	if acc.Mods&ModShift != 0 {
+		sb.WriteString("Shift+")
+	}
+	sb.WriteString(strings.TrimPrefix(acc.Key.String(), "Arrow"))
+	return sb.String()
+}
+
+// typesText reports whether the accelerator is a plain key that produces
+// text, which must reach the URL bar or a focused field instead
+func (acc Accelerator) typesText() bool {
+	if acc.Mods&(ModCtrl|ModAlt) != 0 {
+		return false
+	}
+	name := acc.Key.String()
+	return len(name) == 1 || strings.HasPrefix(name, "Digit") || acc.Key == ebiten.KeySpace
+}
+
+// Shortcut actions
+const (
+	ActionReload       = "reload"
+	ActionFocusURLBar  = "focus-url-bar"
+	ActionBack         = "back"
+	ActionForward      = "forward"
+	ActionHome         = "home"
+	ActionNewTab       = "new-tab"
+	ActionCloseTab     = "close-tab"
+	ActionNextTab      = "next-tab"
+	ActionPrevTab      = "previous-tab"
+	ActionFind         = "find"
+	ActionDevTools     = "devtools"
+	ActionDeviceMode   = "device-mode"
+	ActionFullscreen   = "fullscreen"
+	ActionPalette      = "command-palette"
+	ActionScreenshot   = "screenshot"
+	ActionPrintPreview = "print-preview"
+	ActionPrint        = "print"
+	ActionSavePage     = "save-page"
+	ActionReaderMode   = "reader-mode"
+	ActionViewSource   = "view-source"
+	ActionPrivate      = "private-window"
+
+	ActionToggleJavaScript = "toggle-site-javascript"
+	ActionToggleImages     = "toggle-site-images"
+	ActionToggleSmooth     = "toggle-smooth-scrolling"
+	ActionToggleTables     = "toggle-table-enhancements"
+)
+
+// ActionTitles name each action in the command palette
+var ActionTitles = map[string]string{
+	ActionReload:       "Reload page",
+	ActionFocusURLBar:  "Focus URL bar",
+	ActionBack:         "Go back",
+	ActionForward:      "Go forward",
+	ActionHome:         "Go to home page",
+	ActionNewTab:       "New tab",
+	ActionCloseTab:     "Close tab",
+	ActionNextTab:      "Next tab",
+	ActionPrevTab:      "Previous tab",
+	ActionFind:         "Find in page",
+	ActionDevTools:     "Toggle accessibility panel",
+	ActionDeviceMode:   "Toggle device mode",
+	ActionFullscreen:   "Toggle full screen",
+	ActionPalette:      "Command palette",
+	ActionScreenshot:   "Capture screenshot",
+	ActionPrintPreview: "Toggle print preview",
+	ActionPrint:        "Print to PDF",
+	ActionSavePage:     "Save page as",
+	ActionReaderMode:   "Toggle reader mode",
+	ActionViewSource:   "View page source",
+	ActionPrivate:      "New private window",
+
+	ActionToggleJavaScript: "Toggle JavaScript for this site",
+	ActionToggleImages:     "Toggle images for this site",
+	ActionToggleSmooth:     "Toggle smooth scrolling",
+	ActionToggleTables:     "Toggle table sorting and resizing",
+}
+
+// DefaultShortcuts are the accelerators bound to each action out of the box
+var DefaultShortcuts = map[string][]string{
+	ActionReload:      {"Ctrl+R", "F5"},
+	ActionFocusURLBar: {"Ctrl+L", "F6"},
+	ActionBack:        {"Alt+Left"},
+	ActionForward:     {"Alt+Right"},
+	ActionHome:        {"Alt+Home"},
+	ActionNewTab:      {"Ctrl+T"},
+	ActionCloseTab:    {"Ctrl+W"},
+	ActionNextTab:     {"Ctrl+Tab", "Ctrl+PageDown"},
+	ActionPrevTab:     {"Ctrl+Shift+Tab", "Ctrl+PageUp"},
+	ActionFind:        {"Ctrl+F"},
+	ActionDevTools:    {"F12"},
+	ActionDeviceMode:  {"Ctrl+Shift+M"},
+	ActionFullscreen:  {"F11"},
+	ActionPalette:     {"Ctrl+Shift+P"},
+	ActionPrint:       {"Ctrl+P"},
+	ActionSavePage:    {"Ctrl+S"},
+	ActionReaderMode:  {"F9"},
+	ActionViewSource:  {"Ctrl+U"},
+	ActionPrivate:     {"Ctrl+Shift+N"},
+
+	ActionToggleJavaScript: {"Alt+J"},
+	ActionToggleImages:     {"Alt+I"},
+}
+
+// ShortcutManager maps accelerators to named actions and runs their handlers
+type ShortcutManager struct {
+	bindings map[Accelerator]string
+	handlers map[string]func()
+}
+
+// NewShortcutManager creates a manager with the DefaultShortcuts bound
+func NewShortcutManager() *ShortcutManager {
+	m := &ShortcutManager{
+		bindings: make(map[Accelerator]string),
+		handlers: make(map[string]func()),
+	}
+	for action, accs := range DefaultShortcuts {
+		for _, acc := range accs {
+			if err := m.Bind(acc, action); err != nil {
+				panic(err)
+			}
+		}
+	}
+	return m
+}
+
+// Bind makes accelerator trigger action, replacing any previous binding
+func (m *ShortcutManager) Bind(accelerator, action string) error {
+	acc, err := ParseAccelerator(accelerator)
+	if err != nil {
+		return err
+	}
+	m.bindings[acc] = action
+	return nil
+}
+
+// Unbind removes every accelerator bound to action
+func (m *ShortcutManager) Unbind(action string) {
+	for acc, a := range m.bindings {
+		if a == action {
+			delete(m.bindings, acc)
+		}
+	}
+}
+
+// Handle registers the function run when action is triggered
+func (m *ShortcutManager) Handle(action string, fn func()) {
+	m.handlers[action] = fn
+}
+
+// Accelerators returns the accelerators bound to action, in a stable order
+func (m *ShortcutManager) Accelerators(action string) []Accelerator {
+	var accs []Accelerator
+	for acc, a := range m.bindings {
+		if a == action {
+			accs = append(accs, acc)
+		}
+	}
+	sort.Slice(accs, func(i, j int) bool { return accs[i].String() < accs[j].String() })
+	return accs
+}
+
+// Actions returns the actions that have a handler, sorted
+func (m *ShortcutManager) Actions() []string {
+	actions := make([]string, 0, len(m.handlers))
+	for action := range m.handlers {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+// Run runs action's handler, reporting whether it has one
+func (m *ShortcutManager) Run(action string) bool {
+	fn := m.handlers[action]
+	if fn != nil {
+		fn()
+	}
+	return fn != nil
+}
+
+// LoadConfig applies a JSON file mapping actions to accelerator lists, e.g.
+// {"reload": ["Ctrl+R"], "find": []}. Listed actions replace their default
+// bindings; a missing file is not an error.
+func (m *ShortcutManager) LoadConfig(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var config map[string][]string
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for action, accs := range config {
+		m.Unbind(action)
+		for _, acc := range accs {
+			if err := m.Bind(acc, action); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ShortcutConfigPath returns where the user's shortcut overrides live:
+// $GOBROWSER_SHORTCUTS, or gobrowser/shortcuts.json in the config directory
+func ShortcutConfigPath() string {
+	if path := os.Getenv("GOBROWSER_SHORTCUTS"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "gobrowser", "shortcuts.json")
+}
+
+// Dispatch runs the action of an accelerator pressed this frame. textFocused
+// says a text field or the URL bar has focus, so plain typing keys are left
+// alone. It reports whether a shortcut consumed the keystroke.
+func (m *ShortcutManager) Dispatch(textFocused bool) bool {
+	var mods Modifiers
+	if ebiten.IsKeyPressed(ebiten.KeyControl) || ebiten.IsKeyPressed(ebiten.KeyMeta) {
+		mods |= ModCtrl
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyShift) {
+		mods |= ModShift
+	}
+	if ebiten.IsKeyPressed(ebiten.KeyAlt) {
+		mods |= ModAlt
+	}
+
+	for _, key := range inpututil.AppendJustPressedKeys(nil) {
+		acc := Accelerator{Key: key, Mods: mods}
+		action, ok := m.bindings[acc]
+		if !ok || (textFocused && acc.typesText()) {
+			continue
+		}
+		if m.Run(action) {
+			return true
+		}
+	}
+	return false
+}
+
+// registerShortcuts wires the browser actions to the app. The page actions
+// are closures so they act on the tab shown when they run: a method value
+// such as a.Reload would stay bound to the tab shown now.
+func (a *App) registerShortcuts() {
+	a.Shortcuts.Handle(ActionReload, func() { a.Reload() })
+	a.Shortcuts.Handle(ActionFocusURLBar, func() { a.NavBar.Focus(a) })
+	a.Shortcuts.Handle(ActionBack, func() { a.GoBack() })
+	a.Shortcuts.Handle(ActionForward, func() { a.GoForward() })
+	a.Shortcuts.Handle(ActionHome, a.GoHome)
+	a.Shortcuts.Handle(ActionNewTab, a.NewForegroundTab)
+	a.Shortcuts.Handle(ActionCloseTab, func() { a.CloseTab(a.activeTabIndex()) })
+	a.Shortcuts.Handle(ActionNextTab, func() { a.SwitchTab((a.activeTabIndex() + 1) % len(a.Tabs)) })
+	a.Shortcuts.Handle(ActionPrevTab, func() { a.SwitchTab((a.activeTabIndex() + len(a.Tabs) - 1) % len(a.Tabs)) })
+	a.Shortcuts.Handle(ActionFind, a.Find.Open)
+	a.Shortcuts.Handle(ActionDevTools, func() {
+		a.DevTools.Visible = !a.DevTools.Visible
+		a.DevTools.ScrollY = 0
+	})
+	a.Shortcuts.Handle(ActionDeviceMode, func() { a.toggleDevice() })
+	a.Shortcuts.Handle(ActionFullscreen, a.toggleFullscreen)
+	a.Shortcuts.Handle(ActionPalette, func() {
+		a.NavBar.IsEditing = false
+		a.Palette.Toggle()
+	})
+	a.Shortcuts.Handle(ActionScreenshot, func() { a.captureScreenshot = true })
+	a.Shortcuts.Handle(ActionPrintPreview, func() { a.togglePrintPreview() })
+	a.Shortcuts.Handle(ActionPrint, func() { a.printToPDF() })
+	a.Shortcuts.Handle(ActionSavePage, func() { a.savePage() })
+	a.Shortcuts.Handle(ActionReaderMode, func() { a.toggleReaderMode() })
+	a.Shortcuts.Handle(ActionViewSource, func() { a.viewSource() })
+	a.Shortcuts.Handle(ActionPrivate, a.openPrivateWindow)
+	a.Shortcuts.Handle(ActionToggleJavaScript, func() {
+		a.toggleSiteContent("JavaScript", a.Settings.JavaScriptAllowed, a.Settings.SetSiteJavaScript)
+	})
+	a.Shortcuts.Handle(ActionToggleImages, func() {
+		a.toggleSiteContent("images", a.Settings.ImagesAllowed, a.Settings.SetSiteImages)
+	})
+	a.Shortcuts.Handle(ActionToggleSmooth, func() {
+		a.toggleSetting("smooth scrolling", &a.Settings.SmoothScrolling)
+	})
+	a.Shortcuts.Handle(ActionToggleTables, func() {
+		a.toggleSetting("table enhancements", &a.Settings.TableEnhancements)
+	})
+}
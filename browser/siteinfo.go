@@ -0,0 +1,145 @@
+package browser
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// =============================================================================
+// SITE INFORMATION
+// Below the connection details the padlock panel sums up the site: the
+// cookies it has in the jar, what its content settings allow, the requests
+// that were blocked and what the page downloaded
+// =============================================================================
+
+// siteInfoListMax is how many cookies or blocked requests the panel names
+// before summing up the rest
+const siteInfoListMax = 5
+
+// siteInfoLines describes the connection and the site for the panel
+func (a *App) siteInfoLines() []securityLine {
+	lines := securityLines(a.Security)
+	if a.Document == nil {
+		return lines
+	}
+	lines = append(lines, a.cookieLines()...)
+	lines = append(lines, a.permissionLines()...)
+	lines = append(lines, a.resourceLines()...)
+	return lines
+}
+
+// cookieLines names the cookies the jar sends to the page
+func (a *App) cookieLines() []securityLine {
+	u, err := url.Parse(a.BaseURL)
+	if err != nil || a.Cookies == nil {
+		return nil
+	}
+	cookies := a.Cookies.Cookies(u)
+	if len(cookies) == 0 {
+		return []securityLine{{"Cookies: none in use", ColorURLText}}
+	}
+	names := make([]string, len(cookies))
+	for i, c := range cookies {
+		names[i] = "  " + c.Name
+	}
+	sort.Strings(names)
+	return append([]securityLine{{fmt.Sprintf("Cookies: %d in use", len(cookies)), ColorURLText}},
+		listLines(names)...)
+}
+
+// permissionLines shows what the site's content settings allow
+func (a *App) permissionLines() []securityLine {
+	state := func(allowed bool) string {
+		if allowed {
+			return "allowed"
+		}
+		return "blocked"
+	}
+	return []securityLine{
+		{"Permissions:", ColorURLText},
+		{"  JavaScript: " + state(a.Settings.JavaScriptAllowed(a.BaseURL)), ColorSecurityDim},
+		{"  Images: " + state(a.Settings.ImagesAllowed(a.BaseURL)), ColorSecurityDim},
+	}
+}
+
+// resourceLines counts the page's subresources by kind with the bytes
+// received for the page and them, and names the requests that were blocked
+func (a *App) resourceLines() []securityLine {
+	var bytes int64
+	if e, ok := a.lookupRequest(a.BaseURL); ok {
+		bytes = e.Bytes
+	}
+	counts := map[string]int{}
+	var blocked []string
+	for _, r := range subresources(a.Document) {
+		counts[r.kind]++
+		e, _ := a.lookupRequest(r.url)
+		bytes += e.Bytes
+		switch {
+		case e.Blocked:
+			blocked = append(blocked, "  Blocklist: "+r.url)
+		case r.blockedIfMixed && IsMixedContent(a.BaseURL, r.url):
+			blocked = append(blocked, "  Mixed content: "+r.url)
+		}
+	}
+
+	kinds := make([]string, 0, len(counts))
+	for kind, n := range counts {
+		kinds = append(kinds, plural(n, kind))
+	}
+	sort.Strings(kinds)
+	summary := "Resources: none"
+	if len(kinds) > 0 {
+		summary = "Resources: " + strings.Join(kinds, ", ")
+	}
+	lines := []securityLine{{summary + ", " + formatBytes(bytes) + " received", ColorURLText}}
+
+	lines = append(lines, securityLine{fmt.Sprintf("Blocked requests: %d", len(blocked)), ColorURLText})
+	return append(lines, listLines(blocked)...)
+}
+
+// lookupRequest returns what the network log recorded for url
+func (a *App) lookupRequest(url string) (NetworkEntry, bool) {
+	if a.Network == nil {
+		return NetworkEntry{}, false
+	}
+	return a.Network.Lookup(url)
+}
+
+// listLines turns up to siteInfoListMax items into dim panel lines and sums
+// up the rest
+func listLines(items []string) []securityLine {
+	var lines []securityLine
+	for i, item := range items {
+		if i == siteInfoListMax {
+			lines = append(lines, securityLine{fmt.Sprintf("  and %d more", len(items)-i), ColorSecurityDim})
+			break
+		}
+		lines = append(lines, securityLine{item, ColorSecurityDim})
+	}
+	return lines
+}
+
+// plural formats a count of things, "1 script" or "3 scripts"
+func plural(n int, thing string) string {
+	if n == 1 {
+		return "1 " + thing
+	}
+	if thing == "media" {
+		return fmt.Sprintf("%d %s", n, thing)
+	}
+	return fmt.Sprintf("%d %ss", n, thing)
+}
+
+// formatBytes formats a byte count for people
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
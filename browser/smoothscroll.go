@@ -0,0 +1,126 @@
+package browser
+
+import (
+	"math"
+	"strings"
+
+	"go-browser/dom"
+)
+
+// =============================================================================
+// SMOOTH SCROLLING
+// Wheel notches, scrollIntoView and #fragment jumps set a scroll target that
+// the frame loop eases towards; trackpad pixel deltas follow the fingers
+// =============================================================================
+
+const (
+	scrollEase     = 0.25 // fraction of the remaining distance covered per frame
+	scrollSnapDist = 0.5  // closer than this to the target, the animation ends
+)
+
+// scrollSpeed returns the wheel notch distance from the settings
+func (t *Tab) scrollSpeed() float64 {
+	if t.Settings == nil || t.Settings.ScrollSpeed <= 0 {
+		return DefaultScrollSpeed
+	}
+	return t.Settings.ScrollSpeed
+}
+
+// smooth reports whether scrolling should animate
+func (t *Tab) smooth() bool {
+	return t.Settings == nil || t.Settings.SmoothScrolling
+}
+
+// clampScrollY limits a scroll position to the page
+func (t *Tab) clampScrollY(y float64) float64 {
+	return max(t.maxScroll(), min(0, y))
+}
+
+// wheelScroll handles a wheel delta. Whole notches come from a mouse wheel
+// and animate; fractional deltas come from a trackpad that already reports
+// smooth motion, so the page follows them directly.
+func (t *Tab) wheelScroll(dy float64) {
+	delta := dy * t.scrollSpeed()
+	if dy == math.Trunc(dy) {
+		// Successive notches add up while the animation is still running
+		t.scrollTo(t.scrollTarget + delta)
+		return
+	}
+	t.jumpTo(t.ScrollY + delta)
+}
+
+// scrollTo scrolls the page to y, animated when smooth scrolling is on
+func (t *Tab) scrollTo(y float64) {
+	if !t.scrolling {
+		t.scrollTarget = t.ScrollY
+	}
+	t.scrollTarget = t.clampScrollY(y)
+	t.scrolling = t.smooth() && t.scrollTarget != t.ScrollY
+	if !t.scrolling {
+		t.ScrollY = t.scrollTarget
+	}
+}
+
+// jumpTo scrolls the page to y immediately, ending any animation
+func (t *Tab) jumpTo(y float64) {
+	t.ScrollY = t.clampScrollY(y)
+	t.scrollTarget = t.ScrollY
+	t.scrolling = false
+}
+
+// shiftScroll moves the page and any running animation by delta, so content
+// that moved under the viewport stays put
+func (t *Tab) shiftScroll(delta float64) {
+	t.ScrollY = t.clampScrollY(t.ScrollY + delta)
+	t.scrollTarget = t.clampScrollY(t.scrollTarget + delta)
+}
+
+// stepScroll advances the scroll animation by one frame
+func (t *Tab) stepScroll() {
+	if !t.scrolling {
+		return
+	}
+	t.scrollTarget = t.clampScrollY(t.scrollTarget)
+	remaining := t.scrollTarget - t.ScrollY
+	if math.Abs(remaining) < scrollSnapDist {
+		t.ScrollY = t.scrollTarget
+		t.scrolling = false
+		return
+	}
+	t.ScrollY += remaining * scrollEase
+}
+
+// scrollToFragment scrolls to the element a #fragment names: the element with
+// that id, else the first <a name="...">
+func (t *Tab) scrollToFragment(fragment string) {
+	if t.Document == nil {
+		return
+	}
+	if fragment == "" || strings.EqualFold(fragment, "top") {
+		t.scrollTo(0)
+		return
+	}
+	target := t.Document.GetElementById(fragment)
+	if target == nil {
+		target = findNamedAnchor(t.Document.Node, fragment)
+	}
+	if target == nil {
+		return
+	}
+	if box := findBoxForNode(t.RenderTree, target); box != nil {
+		t.scrollTo(-box.Y)
+	}
+}
+
+// findNamedAnchor returns the first <a> whose name attribute is name
+func findNamedAnchor(node *dom.Node, name string) *dom.Node {
+	if node.Tag == "a" && node.GetAttr("name") == name {
+		return node
+	}
+	for _, child := range node.Children {
+		if found := findNamedAnchor(child, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
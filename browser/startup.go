@@ -0,0 +1,199 @@
+package browser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go-browser/logging"
+)
+
+// =============================================================================
+// STARTUP
+// Without a URL on the command line the browser opens the home page, an
+// empty tab, or the tabs that were open when it last closed, as the
+// "startup" setting says. The open tabs and how far each was scrolled are
+// written to session.json on exit, and every half minute while they change
+// so a crash loses little; restored tabs scroll back once laid out.
+// =============================================================================
+
+// sessionSaveInterval is how often the session is saved while browsing
+const sessionSaveInterval = 30 * time.Second
+
+// Session is the set of tabs saved on exit
+type Session struct {
+	Tabs   []string  `json:"tabs"`             // URL of each tab, in tab strip order
+	Scroll []float64 `json:"scroll,omitempty"` // pixels each tab was scrolled down
+	Active int       `json:"active"`           // index of the tab that was shown
+}
+
+// SessionPath returns where the session is saved: $GOBROWSER_SESSION, or
+// session.json in the profile's directory
+func SessionPath() string {
+	if path := os.Getenv("GOBROWSER_SESSION"); path != "" {
+		return path
+	}
+	return profilePath("session.json")
+}
+
+// LoadSession reads a saved session; a missing file yields an empty one
+func LoadSession(path string) (*Session, error) {
+	s := &Session{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return &Session{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Save writes the session to path, creating its directory
+func (s *Session) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeSession(path, append(data, '\n'))
+}
+
+// sessionWrites keeps autosaves and the save on exit from writing at once
+var sessionWrites sync.Mutex
+
+// writeSession writes an encoded session to path, creating its directory.
+// It writes a temporary file first, so a crash mid-write leaves the last
+// session whole.
+func writeSession(path string, data []byte) error {
+	sessionWrites.Lock()
+	defer sessionWrites.Unlock()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Start opens what the startup setting asks for
+func (a *App) Start() {
+	switch a.Settings.Startup {
+	case StartupBlank:
+		a.NavBar.Focus(a)
+		return
+	case StartupRestore:
+		if a.private {
+			// A private window starts afresh
+			break
+		}
+		session, err := LoadSession(SessionPath())
+		if err != nil {
+			logging.App.Error("loading session", "err", err)
+		}
+		if a.restoreSession(session) {
+			return
+		}
+	}
+	a.Navigate(a.Settings.HomepageURL())
+}
+
+// restoreSession reopens the session's tabs, reporting false when it had none
+func (a *App) restoreSession(s *Session) bool {
+	var tabs []*Tab
+	active := 0
+	for i, url := range s.Tabs {
+		if url == "" {
+			continue
+		}
+		if i <= s.Active {
+			active = len(tabs)
+		}
+		tab := a.Tab
+		if len(tabs) > 0 {
+			tab = newTab(a.Settings, a.browser)
+		}
+		scroll := 0.0
+		if i < len(s.Scroll) {
+			scroll = s.Scroll[i]
+		}
+		tab.reopen(url, scroll)
+		tabs = append(tabs, tab)
+	}
+	if len(tabs) == 0 {
+		return false
+	}
+	a.Tabs = tabs
+	a.SwitchTab(active)
+	return true
+}
+
+// reopen loads url as the first page of the tab's history, scrolled down by
+// scroll pixels once it is laid out
+func (t *Tab) reopen(url string, scroll float64) {
+	t.History = []string{url}
+	t.historyScroll = nil
+	t.HistoryPos = 0
+	t.URL = url
+	t.pendingScroll = -max(scroll, 0)
+	t.LoadFromURL(url)
+}
+
+// GoHome opens the home page in the current tab
+func (a *App) GoHome() {
+	a.Navigate(a.Settings.HomepageURL())
+}
+
+// session returns the open tabs as a session
+func (a *App) session() *Session {
+	s := &Session{Active: a.activeTabIndex()}
+	for _, tab := range a.Tabs {
+		// A tab still loading will scroll to where it was left
+		scroll := tab.ScrollY
+		if tab.IsLoading {
+			scroll = tab.pendingScroll
+		}
+		s.Tabs = append(s.Tabs, tab.URL)
+		s.Scroll = append(s.Scroll, math.Round(-scroll))
+	}
+	return s
+}
+
+// SaveSession writes the open tabs to the session file, for the next start;
+// a private window's tabs aren't saved
+func (a *App) SaveSession() error {
+	if a.private {
+		return nil
+	}
+	return a.session().Save(SessionPath())
+}
+
+// autosaveSession writes the session, and the cookies, every
+// sessionSaveInterval while they change, off the main thread
+func (a *App) autosaveSession() {
+	if a.private || time.Since(a.sessionSavedAt) < sessionSaveInterval {
+		return
+	}
+	a.sessionSavedAt = time.Now()
+	go a.saveCookies()
+	data, err := json.MarshalIndent(a.session(), "", "  ")
+	if err != nil || string(data) == a.sessionSaved {
+		return
+	}
+	a.sessionSaved = string(data)
+	go func() {
+		if err := writeSession(SessionPath(), append(data, '\n')); err != nil {
+			logging.App.Error("saving session", "err", err)
+		}
+	}()
+}
@@ -0,0 +1,713 @@
+package browser
+
+import (
+	"image/color"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-browser/clipboard"
+	"go-browser/css"
+	"go-browser/dom"
+	"go-browser/gocko/forms"
+	"go-browser/layout"
+	"go-browser/logging"
+	"go-browser/perf"
+	"go-browser/render"
+	"go-browser/spidergopher"
+	"go-browser/spidergopher/webapi"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// =============================================================================
+// TABS
+// Each tab owns a page: its document, layout, scripts, history and form state
+// =============================================================================
+
+// Tab holds the state of one open page
+type Tab struct {
+	URL         string
+	BaseURL     string // URL the current document was fetched from
+	Document    *dom.Document
+	RenderTree  *layout.RenderBox
+	Stylesheets []*css.Stylesheet
+	RuleDeps    *css.RuleDependencies // which mutations can change which styles
+	PageTitle   string                // <title> of the loaded document
+	ScrollY     float64
+	IsLoading   bool
+	Progress    float64 // load progress from 0 to 1, meaningful while IsLoading
+	ErrorMsg    string
+	History     []string             // Browser history
+	HistoryPos  int                  // Current position in history
+	FormState   *forms.FormState     // Form element state
+	JSEngine    *spidergopher.Engine // SpiderGopher JavaScript engine
+	Security    *PageSecurity        // how the page was delivered; nil for local files
+	Settings    *Settings            // content settings; nil allows everything
+
+	browser *browserState // what the tab shares with the other tabs of its App
+
+	historyScroll []float64 // ScrollY of each History entry, saved when leaving it
+	pendingScroll float64   // ScrollY to apply once the loading page is laid out
+	scrollTarget  float64   // where a smooth scroll is heading
+	scrolling     bool      // whether a smooth scroll is in progress
+
+	tableSorts   map[*dom.Node]tableSort // column each sorted table is sorted by
+	columnWidths map[*dom.Node][]float64 // column widths of tables the user resized
+	columnDrag   *columnDrag             // column boundary being dragged
+
+	frames         []*frame // frames of a frameset document
+	frameW, frameH float64  // size of the frame a nested tab shows in; 0 for a window's tab
+
+	device   *Device  // device the tab is emulated on; nil shows it in the window
+	viewport viewport // the page's layout viewport on device
+
+	printPreview bool      // whether the page is shown with print media
+	readerMode   bool      // whether the page's article is shown in reader mode
+	styledMedia  css.Media // the medium the page was last styled for
+	changes      int       // counts script changes to the DOM and user changes to form controls
+
+	popups    chan string    // URLs the page's window.open calls may open
+	downloads chan *download // downloads waiting for the user to choose a path
+
+	fullscreen     *dom.Node         // element the page shows fullscreen; nil when none
+	fullscreenTree *layout.RenderBox // the fullscreen element laid out over the window
+
+	modalDialog *dom.Node         // dialog the page shows modally; nil when none
+	dialogTree  *layout.RenderBox // the modal dialog laid out on its own
+
+	refreshAt  time.Time // when the page's <meta> refresh is due; zero when none
+	refreshURL string    // where the refresh goes
+
+	media    *mediaPlayer // playback of the page's <audio> and <video>
+	canvases *canvasHost  // bitmaps of the page's <canvas> elements
+
+	scriptErrors    chan spidergopher.ScriptError // errors the page's scripts didn't handle
+	errorToast      string                        // latest of them, shown at the bottom of the page
+	errorCount      int                           // how many errors the toast stands for
+	errorToastUntil time.Time                     // when the toast goes away
+
+	loadPending  bool              // the page's scripts wait for its images to fire load
+	mutationsMu  sync.Mutex        // guards mutations, which the page's event loop adds to
+	mutations    []domMutation     // changes the page's scripts made, restyled in Update
+	imagesTree   *layout.RenderBox // render tree the page's images were last asked for from
+	imagesScroll float64           // ScrollY they were asked for at
+	eagerImages  []string          // URLs of the images that hold up load
+
+	loads      atomic.Int64                 // loads started, numbering the latest
+	reports    loadReports                  // what the latest load reported from off the main thread
+	prepared   atomic.Pointer[preparedPage] // page prepared off the main thread, not yet laid out
+	pageLayout *pageLayout                  // page being laid out, shown once it is
+	crashing   bool                         // the crash page is being loaded
+}
+
+// NewTab creates an empty tab that loads pages under settings, through an
+// HTTP client of its own, keeping localStorage in memory
+func NewTab(settings *Settings) *Tab {
+	return newTab(settings, newBrowserState(settings, NewClient(nil, nil, newCookieStore()), webapi.NewMemoryLocalStore()))
+}
+
+// newTab creates an empty tab that loads pages under settings and shares
+// browser with the other tabs of its App
+func newTab(settings *Settings, browser *browserState) *Tab {
+	return &Tab{
+		Settings:     settings,
+		browser:      browser,
+		History:      []string{},
+		HistoryPos:   -1,
+		FormState:    forms.NewFormState(),
+		popups:       make(chan string, 8),
+		downloads:    make(chan *download, 8),
+		scriptErrors: make(chan spidergopher.ScriptError, 16),
+		media:        newMediaPlayer(browser.client),
+		canvases:     newCanvasHost(browser.images),
+	}
+}
+
+// Title returns the page <title>, falling back to the URL
+func (t *Tab) Title() string {
+	if t.PageTitle != "" {
+		return t.PageTitle
+	}
+	if t.URL == "" {
+		return "New Tab"
+	}
+	return t.URL
+}
+
+// root returns the document node of the page, or nil before anything loaded
+func (t *Tab) root() *dom.Node {
+	if t.Document == nil {
+		return nil
+	}
+	return t.Document.Node
+}
+
+// Navigate navigates to a URL and adds it to history
+func (t *Tab) Navigate(urlStr string) {
+	t.saveScroll()
+	// Truncate forward history if we were in the middle
+	if t.HistoryPos < len(t.History)-1 {
+		t.History = t.History[:t.HistoryPos+1]
+	}
+	if len(t.historyScroll) > len(t.History) {
+		t.historyScroll = t.historyScroll[:len(t.History)]
+	}
+	// Add to history
+	t.History = append(t.History, urlStr)
+	t.HistoryPos = len(t.History) - 1
+	t.URL = urlStr
+	t.pendingScroll = 0
+	t.LoadFromURL(urlStr)
+}
+
+// LoadContent parses and renders HTML content in one go
+func (t *Tab) LoadContent(rawHTML string) {
+	defer t.recoverPage("loading")
+	t.startLoad()
+	t.cancelImages()
+	page := preparePage(rawHTML, t.BaseURL, t.Security, t.pageMedia(), t.browser.client)
+	t.Progress = 0.85
+
+	// Build render tree with computed styles
+	vp := t.viewportOf(page.doc)
+	var tree *layout.RenderBox
+	start := time.Now()
+	t.inViewport(vp, func() {
+		tree = layout.BuildRenderTree(page.doc.Node, t.layoutWidth(), t.userColumnWidths)
+	})
+	perf.Since(perf.StageLayout, start)
+	t.showPage(page, vp, tree)
+}
+
+// LoadFromURL fetches and loads content from a URL
+func (t *Tab) LoadFromURL(urlStr string) {
+	t.refreshAt = time.Time{}
+	t.readerMode = false
+	t.cancelImages()
+	if strings.HasPrefix(strings.ToLower(urlStr), InternalScheme) {
+		t.loadInternalPage(urlStr)
+		return
+	}
+	if strings.HasPrefix(strings.ToLower(urlStr), ViewSourceScheme) {
+		t.loadViewSource(urlStr)
+		return
+	}
+	if strings.HasPrefix(strings.ToLower(urlStr), "about:") {
+		t.loadAboutPage(urlStr)
+		return
+	}
+
+	// Handle file:// protocol for local files
+	if strings.HasPrefix(strings.ToLower(urlStr), "file://") {
+		t.LoadFromFile(fileURLPath(urlStr))
+		return
+	}
+	if handler := schemeHandler(urlStr); handler != nil {
+		t.loadSchemeURL(urlStr, handler)
+		return
+	}
+
+	// Handle relative paths (no protocol) as local files
+	if !strings.HasPrefix(urlStr, "http://") && !strings.HasPrefix(urlStr, "https://") {
+		// Check if it's a local file path
+		if _, err := os.Stat(urlStr); err == nil {
+			t.LoadFromFile(urlStr)
+			return
+		}
+		// Otherwise assume https
+		urlStr = "https://" + urlStr
+	}
+
+	prevBaseURL, prevSecurity := t.BaseURL, t.Security
+	load := t.startLoad()
+	t.IsLoading = true
+	t.Progress = 0.1
+	t.BaseURL = urlStr
+	t.Security = nil
+	media := t.pageMedia()
+	go func() {
+		defer t.recoverPrepare(urlStr, load, media)
+		resp, err := t.browser.client.Get(urlStr)
+		if err != nil {
+			t.failLoad(load, err.Error())
+			return
+		}
+		defer resp.Body.Close()
+		if isDownload(resp) {
+			t.reportLoad(load, func(r *loadReport) {
+				r.ended = true
+				r.keep = func() { t.keepPage(urlStr, prevBaseURL, prevSecurity) }
+			})
+			if err := t.download(resp); err != nil {
+				t.failLoad(load, err.Error())
+			}
+			return
+		}
+		security := securityFromResponse(resp)
+		t.reportProgress(load, 0.3)
+
+		// The download takes progress from 30% to 70%
+		body, _ := io.ReadAll(&progressReader{r: resp.Body, total: resp.ContentLength, onRead: func(done float64) {
+			t.reportProgress(load, 0.3+0.4*done)
+		}})
+		page := preparePage(string(body), urlStr, security, media, t.browser.client)
+		page.load = load
+		t.reportProgress(load, 0.75)
+		t.offerPage(page)
+	}()
+}
+
+// progressReader reports the fraction of an HTTP body read so far. Bodies
+// without a Content-Length report nothing.
+type progressReader struct {
+	r      io.Reader
+	total  int64
+	read   int64
+	onRead func(done float64)
+}
+
+func (p *progressReader) Read(buf []byte) (int, error) {
+	n, err := p.r.Read(buf)
+	p.read += int64(n)
+	if p.total > 0 && p.read <= p.total {
+		p.onRead(float64(p.read) / float64(p.total))
+	}
+	return n, err
+}
+
+// LoadFromFile loads HTML from a local file, or the index of a folder. Its
+// relative references resolve next to it.
+func (t *Tab) LoadFromFile(path string) {
+	t.Security = nil
+	info, err := os.Stat(path)
+	if err != nil {
+		t.ErrorMsg = "File not found: " + err.Error()
+		return
+	}
+	t.ErrorMsg = ""
+	if info.IsDir() {
+		listing, err := directoryListing(path)
+		if err != nil {
+			t.ErrorMsg = "Cannot open folder: " + err.Error()
+			return
+		}
+		t.BaseURL = strings.TrimSuffix(fileURL(path), "/") + "/"
+		t.LoadContent(listing)
+		return
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.ErrorMsg = "File not found: " + err.Error()
+		return
+	}
+	t.BaseURL = fileURL(path)
+	t.LoadContent(string(content))
+}
+
+// unload tears down the page shown: its scripts' engine, the images it is
+// still loading and the pages of its frames
+func (t *Tab) unload() {
+	t.startLoad()
+	if t.JSEngine != nil {
+		t.JSEngine.Stop()
+		t.JSEngine = nil
+	}
+	t.cancelImages()
+	for _, f := range t.frames {
+		f.tab.unload()
+	}
+	t.frames = nil
+}
+
+// initJSEngine initializes SpiderGopher and executes <script> tags
+func (t *Tab) initJSEngine() {
+	if t.Document == nil {
+		return
+	}
+
+	if t.JSEngine != nil {
+		t.JSEngine.Stop()
+		t.JSEngine = nil
+	}
+	t.setFullscreen(nil)
+	t.modalDialog, t.dialogTree = nil, nil
+	t.clearScriptErrors()
+	t.loadPending = false
+	if !t.Settings.JavaScriptAllowed(t.BaseURL) {
+		logging.JS.Info("JavaScript is disabled", "url", t.BaseURL)
+		return
+	}
+
+	// Create new engine for each page load
+	t.JSEngine = spidergopher.NewEngine()
+	t.JSEngine.OnAttributeChanged(t.attributeChanged)
+	t.JSEngine.OnChildListChanged(t.childListChanged)
+	t.JSEngine.OnTitleChanged(func(title string) { t.PageTitle = title })
+	t.JSEngine.OnWindowOpen(func(url string) {
+		// Scripts may call window.open off the UI thread; the app opens the tab
+		select {
+		case t.popups <- url:
+		default:
+		}
+	})
+	t.JSEngine.OnClipboardRead(clipboard.ReadText)
+	t.JSEngine.OnFullscreenChange(t.setFullscreen)
+	t.JSEngine.OnMedia(t.media)
+	t.JSEngine.OnCanvas(t.canvases)
+	t.JSEngine.OnFormValues(formValues{t.FormState})
+	t.JSEngine.OnModalDialog(t.setModalDialog)
+	t.JSEngine.OnError(t.scriptError)
+	t.JSEngine.SetClient(t.browser.client)
+	t.JSEngine.SetLocalStore(t.browser.storage)
+
+	// Connect to the real DOM
+	t.JSEngine.SetDocument(t.Document)
+	t.JSEngine.SetViewport(t.viewportSize())
+
+	// Start the event loop for async operations (setTimeout, fetch, etc.)
+	t.JSEngine.Start()
+
+	// Extract and execute all <script> tags
+	scripts := extractScripts(t.Document.Node, t.Document.BaseURL, t.browser.client)
+	logging.JS.Debug("found scripts", "count", len(scripts))
+	for i, script := range scripts {
+		if script.Source != "" {
+			logging.JS.Debug("executing script", "n", i+1, "chars", len(script.Source), "url", script.URL)
+			// What the script leaves uncaught, or a syntax error that kept it
+			// from running, reaches the console through OnError; the scripts
+			// after it run all the same
+			if script.Line > 0 {
+				t.JSEngine.RunInlineScript(script.Source, script.Line, script.Column)
+			} else {
+				t.JSEngine.RunScript(script.Source, script.URL)
+			}
+		}
+	}
+	t.JSEngine.DocumentParsed()
+	t.loadPending = true
+
+	// IMPORTANT: Rebuild render tree AFTER JS execution
+	// This ensures DOM modifications made by JS are visible
+	t.restyleMutations()
+	t.relayout()
+}
+
+// domMutation is a change a script made to the page: an attribute changed
+// on node when name is set, else children added to or removed from it
+type domMutation struct {
+	node                     *dom.Node
+	name, oldValue, newValue string
+	added                    []*dom.Node
+}
+
+// attributeChanged records a script's attribute change. It runs on the
+// page's event loop; Update restyles.
+func (t *Tab) attributeChanged(node *dom.Node, name, oldValue, newValue string) {
+	t.mutationsMu.Lock()
+	defer t.mutationsMu.Unlock()
+	t.mutations = append(t.mutations, domMutation{node: node, name: name, oldValue: oldValue, newValue: newValue})
+}
+
+// childListChanged records a script adding or removing children of parent.
+// It runs on the page's event loop; Update styles the nodes added.
+func (t *Tab) childListChanged(parent *dom.Node, added []*dom.Node) {
+	t.mutationsMu.Lock()
+	defer t.mutationsMu.Unlock()
+	t.mutations = append(t.mutations, domMutation{node: parent, added: added})
+}
+
+// stepMutations restyles what the page's scripts changed since the last
+// frame and relays the page out once for all of it
+func (t *Tab) stepMutations() {
+	if t.restyleMutations() {
+		t.relayout()
+	}
+}
+
+// restyleMutations restyles only the elements the recorded mutations can
+// affect, and the nodes inserted, and reports whether the page needs laying
+// out again
+func (t *Tab) restyleMutations() bool {
+	t.mutationsMu.Lock()
+	mutations := t.mutations
+	t.mutations = nil
+	t.mutationsMu.Unlock()
+	if len(mutations) == 0 || t.Document == nil {
+		return false
+	}
+	t.changes++
+	if t.RuleDeps == nil {
+		t.RuleDeps = css.BuildRuleDependencies(t.Stylesheets)
+	}
+	changed := false
+	for _, m := range mutations {
+		inv := css.Invalidation{Subtrees: m.added}
+		if m.name != "" {
+			inv = t.RuleDeps.AttributeChanged(m.node, m.name, m.oldValue, m.newValue)
+			if inv.Empty() {
+				continue
+			}
+		}
+		css.ApplyInvalidation(inv, t.Stylesheets, t.styledMedia)
+		changed = true
+	}
+	return changed
+}
+
+// viewportSize returns the size of the viewport the page's scripts see
+func (t *Tab) viewportSize() (width, height float64) {
+	return t.layoutWidth() + Padding*2, t.viewHeight()
+}
+
+// stepViewport tells the page's scripts, and those of its frames, of the
+// viewport resizing, the page scrolling, it gaining or losing focus and the
+// medium its media queries test changing
+func (t *Tab) stepViewport(focused bool) {
+	if t.JSEngine != nil {
+		t.JSEngine.SetViewport(t.viewportSize())
+		t.JSEngine.SetMedia(t.pageMedia())
+		t.JSEngine.SetScroll(0, -t.ScrollY)
+		t.JSEngine.SetFocused(focused)
+	}
+	for _, f := range t.frames {
+		f.tab.stepViewport(focused)
+	}
+}
+
+// Reload loads the current URL again, keeping the scroll position
+func (t *Tab) Reload() {
+	if t.URL != "" {
+		t.pendingScroll = t.ScrollY
+		t.LoadFromURL(t.URL)
+	}
+}
+
+// GoBack loads the previous history entry, if any, at the scroll position it
+// was left at
+func (t *Tab) GoBack() {
+	if t.HistoryPos > 0 {
+		t.saveScroll()
+		t.HistoryPos--
+		t.pendingScroll = t.savedScroll(t.HistoryPos)
+		t.URL = t.History[t.HistoryPos]
+		t.LoadFromURL(t.URL)
+	}
+}
+
+// GoForward loads the next history entry, if any, at the scroll position it
+// was left at
+func (t *Tab) GoForward() {
+	if t.HistoryPos < len(t.History)-1 {
+		t.saveScroll()
+		t.HistoryPos++
+		t.pendingScroll = t.savedScroll(t.HistoryPos)
+		t.URL = t.History[t.HistoryPos]
+		t.LoadFromURL(t.URL)
+	}
+}
+
+// activate tells the page's scripts that the user clicked or typed in it,
+// which lets them open popups and read the clipboard for a few seconds
+func (t *Tab) activate() {
+	if t.JSEngine != nil {
+		t.JSEngine.Activate()
+	}
+}
+
+// dispatchJSClickEvent fires click event listeners registered via JavaScript.
+// They run on the page's event loop; stepMutations lays the page out again
+// for whatever they change.
+func (t *Tab) dispatchJSClickEvent(node *dom.Node) {
+	if t.JSEngine == nil || node == nil {
+		return
+	}
+	t.JSEngine.DispatchClick(node)
+}
+
+// =============================================================================
+// TAB MANAGEMENT
+// =============================================================================
+
+// OpenInBackgroundTab loads url in a new tab without switching to it. A
+// BrowserView has a single tab, which loads url instead.
+func (a *App) OpenInBackgroundTab(url string) *Tab {
+	if a.embedded {
+		a.Navigate(url)
+		return a.Tab
+	}
+	tab := newTab(a.Settings, a.browser)
+	a.Tabs = append(a.Tabs, tab)
+	tab.Navigate(url)
+	return tab
+}
+
+// openPopups opens, in foreground tabs, the popups pages' scripts were
+// allowed to open since the last frame
+func (a *App) openPopups() {
+	var tabs []*Tab
+	for _, tab := range a.Tabs {
+		tabs = append(tabs, tab)
+		for _, f := range tab.frames {
+			tabs = append(tabs, f.tab)
+		}
+	}
+	for _, tab := range tabs {
+		for len(tab.popups) > 0 {
+			url := <-tab.popups
+			a.OpenInBackgroundTab(url)
+			a.SwitchTab(len(a.Tabs) - 1)
+		}
+	}
+}
+
+// NewForegroundTab opens an empty tab, switches to it and focuses the URL bar
+func (a *App) NewForegroundTab() {
+	tab := newTab(a.Settings, a.browser)
+	a.Tabs = append(a.Tabs, tab)
+	a.SwitchTab(len(a.Tabs) - 1)
+	a.NavBar.Focus(a)
+}
+
+// SwitchTab makes the tab at index i the active one
+func (a *App) SwitchTab(i int) {
+	if i < 0 || i >= len(a.Tabs) {
+		return
+	}
+	// A page stays fullscreen only while its tab is shown
+	a.exitFullscreen()
+	a.Tab = a.Tabs[i]
+	a.NavBar.IsEditing = false
+}
+
+// CloseTab closes the tab at index i. Closing the last tab leaves an empty one.
+func (a *App) CloseTab(i int) {
+	if i < 0 || i >= len(a.Tabs) {
+		return
+	}
+	closing := a.Tabs[i]
+	closing.unload()
+	closing.cancelDownloads()
+	closing.media.reset("")
+	closing.canvases.reset("", "")
+	a.Tabs = append(a.Tabs[:i], a.Tabs[i+1:]...)
+
+	if len(a.Tabs) == 0 {
+		a.Tabs = []*Tab{newTab(a.Settings, a.browser)}
+		a.SwitchTab(0)
+		return
+	}
+	if closing == a.Tab {
+		if i >= len(a.Tabs) {
+			i = len(a.Tabs) - 1
+		}
+		a.SwitchTab(i)
+	}
+}
+
+// activeTabIndex returns the index of the active tab
+func (a *App) activeTabIndex() int {
+	for i, tab := range a.Tabs {
+		if tab == a.Tab {
+			return i
+		}
+	}
+	return 0
+}
+
+// =============================================================================
+// TAB STRIP
+// =============================================================================
+
+const (
+	tabMaxWidth    = 200.0
+	tabNewBtnWidth = 30.0
+	tabCloseWidth  = 18.0
+)
+
+var (
+	ColorTabStrip     = color.RGBA{28, 28, 32, 255}
+	ColorTabActive    = color.RGBA{55, 55, 64, 255}
+	ColorTabInactive  = color.RGBA{38, 38, 42, 255}
+	ColorTabText      = color.RGBA{220, 220, 225, 255}
+	ColorTabTextMuted = color.RGBA{150, 150, 160, 255}
+)
+
+// tabWidth returns the width of each tab so they all fit in the strip
+func (a *App) tabWidth() float64 {
+	w := (WindowWidth - tabNewBtnWidth - 8) / float64(len(a.Tabs))
+	if w > tabMaxWidth {
+		w = tabMaxWidth
+	}
+	return w
+}
+
+// tabAt returns the index of the tab under screen x and whether x is over its
+// close button, or -1 when x is past the last tab
+func (a *App) tabAt(mx int) (int, bool) {
+	w := a.tabWidth()
+	i := int(float64(mx) / w)
+	if mx < 0 || i >= len(a.Tabs) {
+		return -1, false
+	}
+	overClose := float64(mx) > float64(i+1)*w-tabCloseWidth-4
+	return i, overClose
+}
+
+// handleTabStripClick selects or closes tabs, or opens a new one with "+"
+func (a *App) handleTabStripClick(mx int) {
+	i, overClose := a.tabAt(mx)
+	if i == -1 {
+		newX := float64(len(a.Tabs)) * a.tabWidth()
+		if float64(mx) >= newX && float64(mx) <= newX+tabNewBtnWidth {
+			a.NewForegroundTab()
+		}
+		return
+	}
+	if overClose {
+		a.CloseTab(i)
+		return
+	}
+	a.SwitchTab(i)
+}
+
+// handleTabStripMiddleClick closes the tab under the cursor
+func (a *App) handleTabStripMiddleClick(mx int) {
+	if i, _ := a.tabAt(mx); i != -1 {
+		a.CloseTab(i)
+	}
+}
+
+// drawTabStrip renders the row of tabs below the nav bar
+func (a *App) drawTabStrip(screen *ebiten.Image) {
+	y := float32(NavBarHeight)
+	vector.DrawFilledRect(screen, 0, y, WindowWidth, TabBarHeight, ColorTabStrip, false)
+
+	w := a.tabWidth()
+	for i, tab := range a.Tabs {
+		x := float32(float64(i) * w)
+		bg, fg := ColorTabInactive, ColorTabTextMuted
+		if tab == a.Tab {
+			bg, fg = ColorTabActive, ColorTabText
+		}
+		render.DrawRoundedRect(screen, x+2, y+3, float32(w)-4, TabBarHeight-6, 6, bg)
+
+		// Loading tabs show a spinner before the title and their progress below it
+		titleX := float64(x) + 10
+		if tab.IsLoading {
+			render.DrawSpinner(screen, x+16, y+TabBarHeight/2, 5, a.frame, ColorTabText)
+			titleX += 16
+			vector.DrawFilledRect(screen, x+2, y+TabBarHeight-5, (float32(w)-4)*float32(tab.Progress), 2, ColorAccent, false)
+		}
+		title := render.TruncateText(tab.Title(), float64(x)+w-tabCloseWidth-10-titleX, 12)
+		render.DrawText(screen, title, titleX, float64(y)+TabBarHeight/2+4, 12, fg)
+		render.DrawTextCentered(screen, "×", float64(x)+w-tabCloseWidth/2-6, float64(y)+TabBarHeight/2+5, 14, fg)
+	}
+
+	newX := float32(float64(len(a.Tabs)) * w)
+	render.DrawTextCentered(screen, "+", float64(newX)+tabNewBtnWidth/2, float64(y)+TabBarHeight/2+6, 18, ColorTabText)
+}
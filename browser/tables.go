@@ -0,0 +1,352 @@
+package browser
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go-browser/css"
+	"go-browser/dom"
+	"go-browser/layout"
+	"go-browser/render"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// =============================================================================
+// TABLE ENHANCEMENTS
+// With table_enhancements on, clicking a header cell sorts the table's rows by
+// that column, again to reverse, and dragging a column boundary resizes the
+// columns on either side. Both work on any table, without page script.
+// =============================================================================
+
+const (
+	columnGrab     = 4.0  // pixels either side of a column boundary that grab it
+	minColumnWidth = 24.0 // a drag never makes a column narrower than this
+)
+
+// tableSort is the column a table was last sorted by
+type tableSort struct {
+	col  int
+	desc bool
+}
+
+// columnDrag is a column boundary being dragged: the boundary after column
+// col, where the drag started and the widths at that moment
+type columnDrag struct {
+	table  *dom.Node
+	col    int
+	startX float64
+	widths []float64
+}
+
+// tableEnhancements reports whether the settings turn the enhancements on
+func (t *Tab) tableEnhancements() bool {
+	return t.Settings != nil && t.Settings.TableEnhancements
+}
+
+// userColumnWidths returns the widths the user dragged a table's columns
+// to; the tab lays its pages out with it
+func (t *Tab) userColumnWidths(table *dom.Node) []float64 {
+	return t.columnWidths[table]
+}
+
+// handleTableMouseDown starts a column drag or sorts by a header cell at the
+// page point. It reports whether the press was used.
+func (t *Tab) handleTableMouseDown(x, y float64) bool {
+	if !t.tableEnhancements() || t.RenderTree == nil {
+		return false
+	}
+	table := findTableBoxAt(t.RenderTree, x, y)
+	if table == nil {
+		return false
+	}
+	lefts, rights := columnEdges(table)
+	if col, ok := columnBoundaryAt(rights, x); ok {
+		widths := make([]float64, len(lefts))
+		for i := range widths {
+			widths[i] = rights[i] - lefts[i]
+		}
+		t.columnDrag = &columnDrag{table: table.Node, col: col, startX: x, widths: widths}
+		return true
+	}
+	if cell, col := headerCellAt(table, x, y); cell != nil {
+		t.sortTable(table.Node, col)
+		return true
+	}
+	return false
+}
+
+// updateColumnDrag resizes the dragged columns to follow the mouse, and ends
+// the drag once the button is released
+func (t *Tab) updateColumnDrag(x float64) {
+	d := t.columnDrag
+	if d == nil {
+		return
+	}
+	if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		t.columnDrag = nil
+		return
+	}
+	// The column after the boundary gives up what the one before it gains
+	dx := x - d.startX
+	dx = max(dx, minColumnWidth-d.widths[d.col])
+	dx = min(dx, d.widths[d.col+1]-minColumnWidth)
+	widths := slices.Clone(d.widths)
+	widths[d.col] += dx
+	widths[d.col+1] -= dx
+	if slices.Equal(widths, t.columnWidths[d.table]) {
+		return
+	}
+	if t.columnWidths == nil {
+		t.columnWidths = map[*dom.Node][]float64{}
+	}
+	t.columnWidths[d.table] = widths
+	t.relayout()
+}
+
+// overColumnBoundary reports whether the page point is on a column boundary
+// that can be dragged
+func (t *Tab) overColumnBoundary(x, y float64) bool {
+	if !t.tableEnhancements() || t.RenderTree == nil {
+		return false
+	}
+	if t.columnDrag != nil {
+		return true
+	}
+	table := findTableBoxAt(t.RenderTree, x, y)
+	if table == nil {
+		return false
+	}
+	_, rights := columnEdges(table)
+	_, ok := columnBoundaryAt(rights, x)
+	return ok
+}
+
+// sortTable orders a table's body rows by the text of column col, ascending
+// on the first click and reversing on the next
+func (t *Tab) sortTable(table *dom.Node, col int) {
+	if t.tableSorts == nil {
+		t.tableSorts = map[*dom.Node]tableSort{}
+	}
+	prev, sorted := t.tableSorts[table]
+	order := tableSort{col: col, desc: sorted && prev.col == col && !prev.desc}
+	t.tableSorts[table] = order
+
+	for _, section := range bodySections(table) {
+		var rows []*dom.Node
+		var slots []int
+		for i, child := range section.Children {
+			if child.Tag == "tr" && !isHeaderRow(child) {
+				rows = append(rows, child)
+				slots = append(slots, i)
+			}
+		}
+		slices.SortStableFunc(rows, func(a, b *dom.Node) int {
+			c := compareCellText(cellText(a, col), cellText(b, col))
+			if order.desc {
+				return -c
+			}
+			return c
+		})
+		// Rows go back into the places rows held, between any other nodes
+		for i, slot := range slots {
+			section.Children[slot] = rows[i]
+		}
+	}
+
+	// Row order changes what :nth-child() and friends match
+	css.ApplyStylesToTree(t.Document.Node, t.Stylesheets, t.styledMedia)
+	t.relayout()
+}
+
+// bodySections returns the parts of a table whose rows sort: its tbody
+// elements and the table itself for rows outside any section
+func bodySections(table *dom.Node) []*dom.Node {
+	sections := []*dom.Node{table}
+	for _, child := range table.Children {
+		if child.Tag == "tbody" {
+			sections = append(sections, child)
+		}
+	}
+	return sections
+}
+
+// isHeaderRow reports whether a row heads its table: it is in a thead, or
+// all its cells are th
+func isHeaderRow(tr *dom.Node) bool {
+	if tr.Parent != nil && tr.Parent.Tag == "thead" {
+		return true
+	}
+	cells := 0
+	for _, child := range tr.Children {
+		switch child.Tag {
+		case "td":
+			return false
+		case "th":
+			cells++
+		}
+	}
+	return cells > 0
+}
+
+// gridCell is a td or th with the column it starts at and how many it spans
+type gridCell struct {
+	node      *dom.Node
+	col, span int
+}
+
+// rowCells returns a row's cells placed on the column grid
+func rowCells(tr *dom.Node) []gridCell {
+	var cells []gridCell
+	col := 0
+	for _, child := range tr.Children {
+		if child.Tag != "td" && child.Tag != "th" {
+			continue
+		}
+		span, err := strconv.Atoi(strings.TrimSpace(child.GetAttr("colspan")))
+		if err != nil || span < 1 {
+			span = 1
+		}
+		cells = append(cells, gridCell{node: child, col: col, span: span})
+		col += span
+	}
+	return cells
+}
+
+// cellOf returns where node sits in its row
+func cellOf(cells []gridCell, node *dom.Node) (gridCell, bool) {
+	for _, cell := range cells {
+		if cell.node == node {
+			return cell, true
+		}
+	}
+	return gridCell{}, false
+}
+
+// cellText returns the text of the cell covering column col of a row, ""
+// when the row has none there
+func cellText(tr *dom.Node, col int) string {
+	for _, cell := range rowCells(tr) {
+		if col >= cell.col && col < cell.col+cell.span {
+			return strings.TrimSpace(cell.node.InnerText())
+		}
+	}
+	return ""
+}
+
+// compareCellText compares cells as numbers when both read as one, ignoring
+// currency signs, thousands separators and percent signs, and as
+// case-insensitive text otherwise
+func compareCellText(a, b string) int {
+	na, okA := cellNumber(a)
+	nb, okB := cellNumber(b)
+	if okA && okB {
+		return cmp.Compare(na, nb)
+	}
+	return cmp.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// cellNumber reads a cell's text as a number
+func cellNumber(s string) (float64, bool) {
+	s = strings.NewReplacer(",", "", "$", "", "€", "", "£", "", "%", "").Replace(s)
+	n, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return n, err == nil && !math.IsNaN(n)
+}
+
+// findTableBoxAt returns the innermost table box containing the point
+func findTableBoxAt(box *layout.RenderBox, x, y float64) *layout.RenderBox {
+	x, y, ok := box.Untransform(x, y)
+	if !ok {
+		return nil
+	}
+	var found *layout.RenderBox
+	if box.Node != nil && box.Node.Tag == "table" && x >= box.X && x <= box.X+box.W && y >= box.Y && y <= box.Y+box.H {
+		found = box
+	}
+	for _, child := range box.Children {
+		if inner := findTableBoxAt(child, x, y); inner != nil {
+			return inner
+		}
+	}
+	return found
+}
+
+// columnEdges returns the left and right edge of each column of a laid-out
+// table, taken from cells that span a single column
+func columnEdges(table *layout.RenderBox) (lefts, rights []float64) {
+	for _, row := range table.Children {
+		if row.Node == nil || row.Node.Tag != "tr" {
+			continue
+		}
+		cells := rowCells(row.Node)
+		for _, cellBox := range row.Children {
+			cell, ok := cellOf(cells, cellBox.Node)
+			if !ok || cell.span != 1 {
+				continue
+			}
+			for len(lefts) <= cell.col {
+				lefts, rights = append(lefts, 0), append(rights, 0)
+			}
+			lefts[cell.col], rights[cell.col] = cellBox.X, cellBox.X+cellBox.W
+		}
+	}
+	return lefts, rights
+}
+
+// columnBoundaryAt returns the column whose right edge is under x. The last
+// column's right edge is the table's and doesn't drag.
+func columnBoundaryAt(rights []float64, x float64) (int, bool) {
+	for col := 0; col < len(rights)-1; col++ {
+		if rights[col] > 0 && math.Abs(x-rights[col]) <= columnGrab {
+			return col, true
+		}
+	}
+	return 0, false
+}
+
+// headerCellAt returns the header cell of the table at the point and the
+// column it starts at
+func headerCellAt(table *layout.RenderBox, x, y float64) (*dom.Node, int) {
+	for _, row := range table.Children {
+		if row.Node == nil || row.Node.Tag != "tr" || !isHeaderRow(row.Node) {
+			continue
+		}
+		cells := rowCells(row.Node)
+		for _, cellBox := range row.Children {
+			if x >= cellBox.X && x <= cellBox.X+cellBox.W && y >= cellBox.Y && y <= cellBox.Y+cellBox.H {
+				if cell, ok := cellOf(cells, cellBox.Node); ok {
+					return cell.node, cell.col
+				}
+			}
+		}
+	}
+	return nil, 0
+}
+
+// drawSortIndicator marks the header cell of the column a table is sorted by
+// with an arrow pointing the way it is sorted
+func (a *App) drawSortIndicator(screen *ebiten.Image, box *layout.RenderBox, offsetX, absY float64) {
+	if len(a.tableSorts) == 0 || box.Node.Parent == nil || !isHeaderRow(box.Node.Parent) {
+		return
+	}
+	table := box.Node.Parent
+	for table != nil && table.Tag != "table" {
+		table = table.Parent
+	}
+	order, ok := a.tableSorts[table]
+	if !ok {
+		return
+	}
+	if cell, ok := cellOf(rowCells(box.Node.Parent), box.Node); !ok || cell.col != order.col {
+		return
+	}
+	arrow := "▲"
+	if order.desc {
+		arrow = "▼"
+	}
+	size := float64(FontSizeBody) * 0.75
+	render.DrawText(screen, arrow, box.X+offsetX+box.W-size-4, absY+4, size, ColorTextMuted)
+}
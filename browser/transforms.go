@@ -0,0 +1,96 @@
+package browser
+
+import (
+	"math"
+
+	"go-browser/css"
+	"go-browser/layout"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// =============================================================================
+// TRANSFORMS
+// An element with a transform is painted with its subtree to a layer of its
+// own, which is drawn through the transform's matrix. Hit testing maps
+// points back through the inverse (see RenderBox.Untransform).
+// =============================================================================
+
+// maxLayerSize caps a transform layer's sides, in pixels
+const maxLayerSize = 4096
+
+// drawTransformed paints box and its subtree through box's transform
+func (a *App) drawTransformed(screen *ebiten.Image, box *layout.RenderBox, offsetX, offsetY float64) {
+	m := box.TransformMatrix()
+	x0, y0, x1, y1 := subtreeBounds(box)
+
+	// Only the part of the subtree that lands on screen needs painting
+	if inv, ok := m.Invert(); ok {
+		bounds := screen.Bounds()
+		vx0, vy0, vx1, vy1 := transformedRect(inv,
+			float64(bounds.Min.X)-offsetX, float64(bounds.Min.Y)-offsetY,
+			float64(bounds.Max.X)-offsetX, float64(bounds.Max.Y)-offsetY)
+		x0, y0 = max(x0, vx0), max(y0, vy0)
+		x1, y1 = min(x1, vx1), min(y1, vy1)
+	}
+	x0, y0 = math.Floor(x0), math.Floor(y0)
+	w := min(int(math.Ceil(x1-x0)), maxLayerSize)
+	h := min(int(math.Ceil(y1-y0)), maxLayerSize)
+	if w <= 0 || h <= 0 {
+		return
+	}
+
+	layer := ebiten.NewImage(w, h)
+	defer layer.Deallocate()
+	outer := a.transformLayer
+	a.transformLayer = box
+	a.renderNode(layer, box, -x0, -y0)
+	a.transformLayer = outer
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(x0, y0)
+	op.GeoM.Concat(geoM(m))
+	op.GeoM.Translate(offsetX, offsetY)
+	op.Filter = ebiten.FilterLinear
+	screen.DrawImage(layer, op)
+}
+
+// subtreeBounds returns the rectangle covering box and its descendants, in
+// render tree coordinates, with room for focus rings and glyph overhang
+func subtreeBounds(box *layout.RenderBox) (x0, y0, x1, y1 float64) {
+	const margin = 4
+	x0, y0, x1, y1 = box.X, box.Y, box.X+box.W, box.Y+box.H
+	for _, child := range box.Children {
+		cx0, cy0, cx1, cy1 := subtreeBounds(child)
+		if child.Transform != nil {
+			cx0, cy0, cx1, cy1 = transformedRect(child.TransformMatrix(), cx0, cy0, cx1, cy1)
+		}
+		x0, y0 = min(x0, cx0), min(y0, cy0)
+		x1, y1 = max(x1, cx1), max(y1, cy1)
+	}
+	return x0 - margin, y0 - margin, x1 + margin, y1 + margin
+}
+
+// transformedRect returns the bounding rectangle of a rectangle mapped by m
+func transformedRect(m css.Matrix, x0, y0, x1, y1 float64) (float64, float64, float64, float64) {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, corner := range [][2]float64{{x0, y0}, {x1, y0}, {x0, y1}, {x1, y1}} {
+		x, y := m.Apply(corner[0], corner[1])
+		minX, minY = min(minX, x), min(minY, y)
+		maxX, maxY = max(maxX, x), max(maxY, y)
+	}
+	return minX, minY, maxX, maxY
+}
+
+// geoM converts a CSS matrix to ebiten's
+func geoM(m css.Matrix) ebiten.GeoM {
+	var g ebiten.GeoM
+	g.SetElement(0, 0, m.A)
+	g.SetElement(0, 1, m.C)
+	g.SetElement(0, 2, m.E)
+	g.SetElement(1, 0, m.B)
+	g.SetElement(1, 1, m.D)
+	g.SetElement(1, 2, m.F)
+	return g
+}
@@ -0,0 +1,225 @@
+package browser
+
+import (
+	"image"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go-browser/render"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+)
+
+// =============================================================================
+// URL BAR EDITING
+// Selection, word jumps, clipboard and horizontal scrolling for the URL bar.
+// The selection runs between SelAnchor and CursorPos (byte offsets).
+// =============================================================================
+
+const urlTextPadding = 12.0
+
+// selection returns the selected byte range; start == end when nothing is selected
+func (n *NavBar) selection() (int, int) {
+	if n.SelAnchor < n.CursorPos {
+		return n.SelAnchor, n.CursorPos
+	}
+	return n.CursorPos, n.SelAnchor
+}
+
+func (n *NavBar) hasSelection() bool {
+	return n.SelAnchor != n.CursorPos
+}
+
+func (n *NavBar) selectedText() string {
+	start, end := n.selection()
+	return n.URLText[start:end]
+}
+
+// selectAll selects the whole URL text
+func (n *NavBar) selectAll() {
+	n.SelAnchor = 0
+	n.CursorPos = len(n.URLText)
+}
+
+// moveCursor moves the cursor to pos, extending the selection when extend is set
+func (n *NavBar) moveCursor(pos int, extend bool) {
+	n.CursorPos = max(0, min(pos, len(n.URLText)))
+	if !extend {
+		n.SelAnchor = n.CursorPos
+	}
+	n.CursorBlink = 0
+}
+
+// deleteSelection removes the selected text
+func (n *NavBar) deleteSelection() {
+	start, end := n.selection()
+	n.URLText = n.URLText[:start] + n.URLText[end:]
+	n.moveCursor(start, false)
+}
+
+// deleteRange removes URLText[start:end] and leaves the cursor at start
+func (n *NavBar) deleteRange(start, end int) {
+	if start >= end {
+		return
+	}
+	n.URLText = n.URLText[:start] + n.URLText[end:]
+	n.moveCursor(start, false)
+}
+
+// insertText replaces the selection with s; line breaks are dropped since a
+// URL is a single line
+func (n *NavBar) insertText(s string) {
+	s = strings.NewReplacer("\r", "", "\n", "").Replace(s)
+	if s == "" {
+		return
+	}
+	n.deleteSelection()
+	n.URLText = n.URLText[:n.CursorPos] + s + n.URLText[n.CursorPos:]
+	n.moveCursor(n.CursorPos+len(s), false)
+}
+
+// prevRune and nextRune return the offset of the neighbouring rune boundary
+func (n *NavBar) prevRune() int {
+	if n.CursorPos == 0 {
+		return 0
+	}
+	_, size := utf8.DecodeLastRuneInString(n.URLText[:n.CursorPos])
+	return n.CursorPos - size
+}
+
+func (n *NavBar) nextRune() int {
+	if n.CursorPos >= len(n.URLText) {
+		return len(n.URLText)
+	}
+	_, size := utf8.DecodeRuneInString(n.URLText[n.CursorPos:])
+	return n.CursorPos + size
+}
+
+// isWordRune reports whether r belongs to a word; URL punctuation such as
+// / . : ? & = separates words
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// prevWordBoundary returns the start of the word before pos
+func prevWordBoundary(text string, pos int) int {
+	for pos > 0 {
+		r, size := utf8.DecodeLastRuneInString(text[:pos])
+		if isWordRune(r) {
+			break
+		}
+		pos -= size
+	}
+	for pos > 0 {
+		r, size := utf8.DecodeLastRuneInString(text[:pos])
+		if !isWordRune(r) {
+			break
+		}
+		pos -= size
+	}
+	return pos
+}
+
+// nextWordBoundary returns the end of the word after pos
+func nextWordBoundary(text string, pos int) int {
+	for pos < len(text) {
+		r, size := utf8.DecodeRuneInString(text[pos:])
+		if isWordRune(r) {
+			break
+		}
+		pos += size
+	}
+	for pos < len(text) {
+		r, size := utf8.DecodeRuneInString(text[pos:])
+		if !isWordRune(r) {
+			break
+		}
+		pos += size
+	}
+	return pos
+}
+
+// textViewWidth is the width available to the URL text inside the bar
+func (n *NavBar) textViewWidth() float64 {
+	return float64(n.URLBarW) - urlTextPadding*2
+}
+
+// indexAt returns the rune boundary closest to screen x
+func (n *NavBar) indexAt(x float64) int {
+	rel := x - float64(n.URLBarX) - urlTextPadding + n.ScrollX
+	best, bestDist := 0, math.Abs(rel)
+	for i := range n.URLText {
+		if i == 0 {
+			continue
+		}
+		if d := math.Abs(rel - render.MeasureText(n.URLText[:i], FontSizeUI)); d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	if d := math.Abs(rel - render.MeasureText(n.URLText, FontSizeUI)); d < bestDist {
+		best = len(n.URLText)
+	}
+	return best
+}
+
+// scrollToCursor scrolls the text horizontally so the cursor stays visible
+func (n *NavBar) scrollToCursor() {
+	cursor := render.MeasureText(n.URLText[:n.CursorPos], FontSizeUI)
+	view := n.textViewWidth()
+	if cursor-n.ScrollX > view {
+		n.ScrollX = cursor - view
+	}
+	if cursor < n.ScrollX {
+		n.ScrollX = cursor
+	}
+	// Don't leave empty space after the end of a text that fits
+	if total := render.MeasureText(n.URLText, FontSizeUI); total-n.ScrollX < view {
+		n.ScrollX = max(0, total-view)
+	}
+}
+
+// handleDrag extends the selection while the mouse is dragged across the bar
+func (n *NavBar) handleDrag() {
+	if !n.dragging {
+		return
+	}
+	if !ebiten.IsMouseButtonPressed(ebiten.MouseButtonLeft) {
+		n.dragging = false
+		return
+	}
+	mx, _ := ebiten.CursorPosition()
+	n.moveCursor(n.indexAt(float64(mx)), true)
+}
+
+// keyRepeated reports a key press, repeating while the key is held down
+func keyRepeated(key ebiten.Key) bool {
+	d := inpututil.KeyPressDuration(key)
+	return d == 1 || (d >= 30 && (d-30)%4 == 0)
+}
+
+// drawEditingText draws the text being edited with its selection and cursor,
+// clipped to the bar and scrolled by ScrollX
+func (n *NavBar) drawEditingText(screen *ebiten.Image, textY float64) {
+	clipRect := image.Rect(int(n.URLBarX)+int(urlTextPadding)-2, int(n.URLBarY),
+		int(n.URLBarX+n.URLBarW)-int(urlTextPadding)+2, int(n.URLBarY+URLBarHeight))
+	clip := screen.SubImage(clipRect).(*ebiten.Image)
+
+	x := float64(n.URLBarX) + urlTextPadding - n.ScrollX
+	if n.hasSelection() {
+		start, end := n.selection()
+		sx := x + render.MeasureText(n.URLText[:start], FontSizeUI)
+		sw := render.MeasureText(n.URLText[start:end], FontSizeUI)
+		vector.DrawFilledRect(clip, float32(sx), n.URLBarY+8, float32(sw), URLBarHeight-16, ColorURLSelection, false)
+	}
+	render.DrawText(clip, n.URLText, x, textY, FontSizeUI, ColorURLText)
+
+	// Blinking cursor
+	if (n.CursorBlink/30)%2 == 0 {
+		cx := x + render.MeasureText(n.URLText[:n.CursorPos], FontSizeUI)
+		vector.DrawFilledRect(clip, float32(cx), n.URLBarY+10, 2, URLBarHeight-20, ColorCursor, false)
+	}
+}
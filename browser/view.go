@@ -0,0 +1,215 @@
+package browser
+
+import (
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+)
+
+// =============================================================================
+// BROWSER VIEW
+// A BrowserView is a web page panel for other ebiten programs: a single tab
+// without the browser's chrome that lays out, scrolls and draws within the
+// rectangle its host gives it each frame. The host calls Update and Draw
+// from its own Game; the view takes the wheel while the mouse is over it,
+// and the keyboard from a click in it until a click elsewhere. Links that
+// would open a tab or window load in the view instead.
+// =============================================================================
+
+// BrowserView is an embeddable web page panel
+type BrowserView struct {
+	app     *App
+	focused bool          // the last click landed in the view
+	image   *ebiten.Image // the page at its own scale, below a chrome-sized strip
+}
+
+// NewBrowserView creates an empty view; nil settings mean the defaults
+func NewBrowserView(settings *Settings) *BrowserView {
+	if settings == nil {
+		settings = DefaultSettings()
+	}
+	return &BrowserView{app: buildApp(settings, false, true)}
+}
+
+// Navigate loads url and adds it to the view's history
+func (v *BrowserView) Navigate(url string) {
+	v.app.Navigate(url)
+}
+
+// LoadHTML shows a page made of html
+func (v *BrowserView) LoadHTML(html string) {
+	v.app.LoadContent(html)
+}
+
+// GoBack loads the previous page of the view's history
+func (v *BrowserView) GoBack() {
+	v.app.GoBack()
+}
+
+// GoForward loads the next page of the view's history
+func (v *BrowserView) GoForward() {
+	v.app.GoForward()
+}
+
+// Reload loads the current page again
+func (v *BrowserView) Reload() {
+	v.app.Reload()
+}
+
+// URL returns the address of the page shown
+func (v *BrowserView) URL() string {
+	return v.app.URL
+}
+
+// Title returns the page's title, or its URL when it has none
+func (v *BrowserView) Title() string {
+	return v.app.Title()
+}
+
+// Loading reports whether a page is being loaded
+func (v *BrowserView) Loading() bool {
+	return v.app.IsLoading
+}
+
+// Focused reports whether keys go to the view
+func (v *BrowserView) Focused() bool {
+	return v.focused
+}
+
+// Update advances the page by one frame with the view at rect, in the
+// host's screen coordinates, and handles the input aimed at it
+func (v *BrowserView) Update(rect image.Rectangle) error {
+	a := v.app
+	a.frame++
+	v.resize(rect)
+
+	mx, my := ebiten.CursorPosition()
+	over := image.Pt(mx, my).In(rect)
+	x, y := float64(mx-rect.Min.X), float64(my-rect.Min.Y)
+	if _, dy := ebiten.Wheel(); dy != 0 && over {
+		if f, _, _ := a.frameAt(x, y); f != nil {
+			f.tab.wheelScroll(dy)
+		} else {
+			a.wheelScroll(dy)
+		}
+	}
+	a.stepScroll()
+	a.stepFrames()
+	a.stepLayout()
+	a.stepMedia()
+	a.stepRefresh()
+	a.takeScriptErrors()
+	a.stepPageLoad()
+	a.FormState.CursorBlink++
+
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		v.focused = over
+		if over {
+			v.click(x, y)
+		}
+	}
+	if v.focused {
+		a.handlePageKeys()
+	}
+	a.openPopups()
+
+	if over {
+		ebiten.SetCursorShape(v.cursor(x, y))
+	}
+	return nil
+}
+
+// resize lays the page out again when the view's size changed
+func (v *BrowserView) resize(rect image.Rectangle) {
+	a := v.app
+	w, h := float64(rect.Dx()), float64(rect.Dy())
+	if w <= 0 || h <= 0 || (w == a.frameW && h == a.frameH) {
+		return
+	}
+	a.frameW, a.frameH = w, h
+	switch {
+	case a.isFrameset():
+		a.loadFrames()
+	case a.Document != nil && a.pageLayout == nil:
+		a.relayout()
+		a.jumpTo(a.ScrollY)
+	}
+}
+
+// click handles a left click at a point of the view
+func (v *BrowserView) click(x, y float64) {
+	a := v.app
+	switch {
+	case len(a.frames) > 0:
+		a.handleFrameClick(x, y)
+	case a.RenderTree != nil:
+		a.activate()
+		a.handlePageClick(x-Padding, y-Padding-a.ScrollY)
+	}
+}
+
+// cursor returns the cursor shape over a point of the view
+func (v *BrowserView) cursor(x, y float64) ebiten.CursorShapeType {
+	a := v.app
+	switch {
+	case len(a.frames) > 0:
+		return a.frameCursor(x, y)
+	case a.RenderTree != nil:
+		shape, _ := a.pointCursor(x-Padding, y-Padding-a.ScrollY)
+		return shape
+	}
+	return ebiten.CursorShapeDefault
+}
+
+// Draw draws the view's page onto dst at rect
+func (v *BrowserView) Draw(dst *ebiten.Image, rect image.Rectangle) {
+	a := v.app
+	defer a.recoverPage("painting")
+	area := dst.SubImage(rect).(*ebiten.Image)
+	x, y := float64(rect.Min.X), float64(rect.Min.Y)
+	switch {
+	case a.IsLoading:
+		area.Fill(ColorBackground)
+		drawFrameMessage(area, "Loading...", x, y, ColorTextMuted)
+	case a.ErrorMsg != "":
+		area.Fill(ColorBackground)
+		drawFrameMessage(area, "Error: "+a.ErrorMsg, x, y, color.RGBA{255, 100, 100, 255})
+	case len(a.frames) > 0:
+		area.Fill(ColorBackground)
+		a.drawFrames(area, a.Tab, x, y)
+	case a.RenderTree != nil:
+		v.drawPage(area, x, y, rect.Dx(), rect.Dy())
+	default:
+		area.Fill(ColorBackground)
+	}
+}
+
+// drawPage draws the page at (x, y). Like device mode, the page is rendered
+// below a strip so that fixed boxes, which paint at ContentTop, land at the
+// view's top padding.
+func (v *BrowserView) drawPage(dst *ebiten.Image, x, y float64, w, h int) {
+	a := v.app
+	strip := int(math.Ceil(ContentTop - Padding))
+	if v.image == nil || v.image.Bounds().Dx() != w || v.image.Bounds().Dy() != strip+h {
+		if v.image != nil {
+			v.image.Deallocate()
+		}
+		v.image = ebiten.NewImage(w, strip+h)
+	}
+	v.image.Fill(a.getPageBackground())
+	if cs := a.getBodyStyle(); cs != nil {
+		drawBackgroundGradient(v.image, cs, 0, float64(strip), float64(w), float64(h), 1)
+	}
+	a.renderNode(v.image, a.RenderTree, Padding, ContentTop+a.ScrollY)
+	if a.FormState.SelectOpen != "" {
+		a.renderSelectOverlay(v.image, a.RenderTree, Padding, ContentTop+a.ScrollY)
+	}
+
+	page := v.image.SubImage(image.Rect(0, strip, w, strip+h)).(*ebiten.Image)
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(x, y)
+	dst.DrawImage(page, op)
+}
@@ -0,0 +1,410 @@
+package browser
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go-browser/dom"
+	"go-browser/spidergopher"
+)
+
+// =============================================================================
+// VIEW SOURCE
+// view-source:URL, which Ctrl+U opens in a new tab for the page shown, lists
+// the HTML of URL as the server sent it: numbered lines in a monospace font,
+// wrapped to the window, with tags, attributes, values, comments and entities
+// in their own colors. The URLs of href and src attributes link to their own
+// source. The page is built as a DOM rather than HTML, so the markup shows as
+// written, and find-in-page searches it like any other page.
+// =============================================================================
+
+// ViewSourceScheme starts the address of a page's source
+const ViewSourceScheme = "view-source:"
+
+// viewSourceStyle lays out and colors a source listing
+const viewSourceStyle = `
+body { margin: 0; padding: 8px 0; background: #ffffff; color: #1b1b1b; font-family: monospace; font-size: 13px; line-height: 1.5; }
+.line { display: block; min-height: 19px; }
+.num { float: left; width: 48px; text-align: right; color: #9a9a9a; }
+.code { display: block; margin-left: 64px; margin-right: 12px; white-space: pre-wrap; }
+.tag { color: #881280; }
+.attr { color: #994500; }
+.value { color: #1a1aa6; }
+.comment { color: #236e25; }
+.doctype { color: #8a8a8a; }
+.entity { color: #b03a2e; }
+a { color: inherit; }
+`
+
+// sourceEntity matches a character reference at the start of a string
+var sourceEntity = regexp.MustCompile(`^&(?:#[0-9]+|#[xX][0-9a-fA-F]+|[A-Za-z][A-Za-z0-9]*);?`)
+
+// sourceRawText are elements whose content is text, not markup
+var sourceRawText = map[string]bool{
+	"script": true, "style": true, "textarea": true, "title": true, "xmp": true, "plaintext": true,
+}
+
+// sourceSpan is a run of source shown in one color; href is set on
+// attribute values that link to another source
+type sourceSpan struct {
+	class string
+	text  string
+	href  string
+}
+
+// viewSource opens the source of the page shown in a new tab
+func (a *App) viewSource() {
+	if a.URL == "" || strings.HasPrefix(strings.ToLower(a.URL), ViewSourceScheme) {
+		return
+	}
+	a.OpenInBackgroundTab(ViewSourceScheme + a.URL)
+	a.SwitchTab(len(a.Tabs) - 1)
+}
+
+// loadViewSource shows the source of the page urlStr, a view-source: address,
+// names. It is fetched off the main thread like any page.
+func (t *Tab) loadViewSource(urlStr string) {
+	target := strings.TrimSpace(urlStr[len(ViewSourceScheme):])
+	t.BaseURL = urlStr
+	t.Security = nil
+	t.ErrorMsg = ""
+
+	// Internal pages are written by the tab, on the main thread
+	var internal func(*Tab) string
+	if strings.HasPrefix(strings.ToLower(target), InternalScheme) {
+		name := strings.TrimSuffix(strings.ToLower(strings.TrimPrefix(target, InternalScheme)), "/")
+		if internal = internalPages[name]; internal == nil {
+			t.ErrorMsg = "Unknown page: " + target
+			return
+		}
+	}
+	internalSource := ""
+	if internal != nil {
+		internalSource = internal(t)
+	}
+
+	load := t.startLoad()
+	t.IsLoading = true
+	t.Progress = 0.1
+	media := t.pageMedia()
+	go func() {
+		source, base, err := internalSource, target, error(nil)
+		if internal == nil {
+			source, base, err = fetchSource(t.browser.client, target)
+		}
+		if err != nil {
+			t.failLoad(load, err.Error())
+			return
+		}
+		t.reportProgress(load, 0.7)
+		page := prepareDocument(sourceDocument(source, base, urlStr), nil, media, t.browser.client)
+		page.load = load
+		t.reportProgress(load, 0.75)
+		t.offerPage(page)
+	}()
+}
+
+// fetchSource reads the HTML at target, a URL of any scheme or a local
+// file, and returns it with the URL its relative references resolve
+// against. Web pages are fetched through client.
+func fetchSource(client *http.Client, target string) (source, base string, err error) {
+	lower := strings.ToLower(target)
+	handler := schemeHandler(target)
+	switch {
+	case strings.HasPrefix(lower, "file://"):
+		content, err := os.ReadFile(fileURLPath(target))
+		if err != nil {
+			return "", "", fmt.Errorf("File not found: %w", err)
+		}
+		return string(content), target, nil
+	case handler != nil:
+		u, err := url.Parse(target)
+		if err != nil {
+			return "", "", err
+		}
+		_, content, err := handler(u)
+		if err != nil {
+			return "", "", err
+		}
+		return string(content), target, nil
+	case !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://"):
+		if content, err := os.ReadFile(target); err == nil {
+			return string(content), fileURL(target), nil
+		}
+		target = "https://" + target
+	}
+
+	resp, err := client.Get(target)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", err
+	}
+	return string(body), resp.Request.URL.String(), nil
+}
+
+// sourceDocument builds the page listing source, the HTML at base, under
+// the address pageURL
+func sourceDocument(source, base, pageURL string) *dom.Document {
+	doc := dom.ParseDocument(`<!DOCTYPE html><html><head><meta charset="utf-8"><title></title>` +
+		"<style>" + viewSourceStyle + `</style></head><body></body></html>`)
+	doc.SetURL(pageURL)
+	if titles := doc.Node.GetElementsByTagName("title"); len(titles) > 0 {
+		titles[0].AppendChild(dom.NewText(pageURL))
+	}
+	if doc.Body == nil {
+		return doc
+	}
+
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	lines := strings.Count(source, "\n") + 1
+	if strings.HasSuffix(source, "\n") {
+		lines--
+	}
+	codes := make([]*dom.Node, 0, lines)
+	for i := 1; i <= lines; i++ {
+		// Spans, as divs come with space between them
+		line := dom.NewElement("span")
+		line.SetAttr("class", "line")
+		num := dom.NewElement("span")
+		num.SetAttr("class", "num")
+		num.AppendChild(dom.NewText(strconv.Itoa(i)))
+		code := dom.NewElement("span")
+		code.SetAttr("class", "code")
+		line.AppendChild(num)
+		line.AppendChild(code)
+		doc.Body.AppendChild(line)
+		codes = append(codes, code)
+	}
+
+	// Spans running over several lines are cut at each newline
+	row := 0
+	for _, span := range highlightSource(source, base) {
+		for i, part := range strings.Split(span.text, "\n") {
+			if i > 0 {
+				row++
+			}
+			if part == "" || row >= len(codes) {
+				continue
+			}
+			codes[row].AppendChild(span.node(part))
+		}
+	}
+	return doc
+}
+
+// node returns the DOM of text shown as span
+func (span sourceSpan) node(text string) *dom.Node {
+	if span.class == "" {
+		return dom.NewText(text)
+	}
+	el := dom.NewElement("span")
+	el.SetAttr("class", span.class)
+	el.AppendChild(dom.NewText(text))
+	if span.href == "" {
+		return el
+	}
+	link := dom.NewElement("a")
+	link.SetAttr("href", span.href)
+	link.AppendChild(el)
+	return link
+}
+
+// highlightSource splits source, HTML at base, into runs of the colors of
+// what they are
+func highlightSource(source, base string) []sourceSpan {
+	var spans []sourceSpan
+	emit := func(span sourceSpan) {
+		if span.text == "" {
+			return
+		}
+		if n := len(spans); n > 0 && spans[n-1].class == span.class && spans[n-1].href == "" && span.href == "" {
+			spans[n-1].text += span.text
+			return
+		}
+		spans = append(spans, span)
+	}
+
+	rawText := "" // the element whose text content comes next, if any
+	for i := 0; i < len(source); {
+		rest := source[i:]
+		switch {
+		case rawText != "":
+			end := indexFold(rest, "</"+rawText)
+			if end < 0 {
+				end = len(rest)
+			}
+			emit(sourceSpan{text: rest[:end]})
+			i += end
+			rawText = ""
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				end = len(rest)
+			} else {
+				end += 4 + 3
+			}
+			emit(sourceSpan{class: "comment", text: rest[:end]})
+			i += end
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+			end := strings.IndexByte(rest, '>') + 1
+			if end <= 0 {
+				end = len(rest)
+			}
+			emit(sourceSpan{class: "doctype", text: rest[:end]})
+			i += end
+		case isTagStart(rest):
+			name, n := highlightTag(rest, base, emit)
+			if !strings.HasPrefix(rest, "</") && sourceRawText[name] && !strings.HasSuffix(rest[:n], "/>") {
+				rawText = name
+			}
+			i += n
+		case rest[0] == '&':
+			if m := sourceEntity.FindString(rest); m != "" {
+				emit(sourceSpan{class: "entity", text: m})
+				i += len(m)
+				continue
+			}
+			emit(sourceSpan{text: "&"})
+			i++
+		default:
+			end := strings.IndexAny(rest[1:], "<&") + 1
+			if end <= 0 {
+				end = len(rest)
+			}
+			emit(sourceSpan{text: rest[:end]})
+			i += end
+		}
+	}
+	return spans
+}
+
+// isTagStart reports whether s starts with a start or end tag
+func isTagStart(s string) bool {
+	if !strings.HasPrefix(s, "<") {
+		return false
+	}
+	s = strings.TrimPrefix(s[1:], "/")
+	return len(s) > 0 && (s[0] >= 'a' && s[0] <= 'z' || s[0] >= 'A' && s[0] <= 'Z')
+}
+
+// indexFold returns the index of the first instance of substr, which starts
+// with "<", in s, ignoring ASCII case, or -1
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		next := strings.IndexByte(s[i:], '<')
+		if next < 0 {
+			break
+		}
+		i += next
+		if i+len(substr) <= len(s) && strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+// highlightTag emits the runs of the tag s starts with, and returns the
+// tag's lowercase name and its length
+func highlightTag(s, base string, emit func(sourceSpan)) (string, int) {
+	i := 1
+	if strings.HasPrefix(s, "</") {
+		i = 2
+	}
+	for i < len(s) && !isTagSpace(s[i]) && s[i] != '>' && s[i] != '/' {
+		i++
+	}
+	name := strings.ToLower(strings.TrimLeft(s[:i], "</"))
+	emit(sourceSpan{class: "tag", text: s[:i]})
+
+	for i < len(s) {
+		start := i
+		switch c := s[i]; {
+		case c == '>':
+			emit(sourceSpan{class: "tag", text: ">"})
+			return name, i + 1
+		case isTagSpace(c):
+			for i < len(s) && isTagSpace(s[i]) {
+				i++
+			}
+			emit(sourceSpan{text: s[start:i]})
+		case c == '/':
+			emit(sourceSpan{class: "tag", text: "/"})
+			i++
+		default:
+			for i < len(s) && !isTagSpace(s[i]) && s[i] != '=' && s[i] != '>' && (s[i] != '/' || i == start) {
+				i++
+			}
+			attr := strings.ToLower(s[start:i])
+			emit(sourceSpan{class: "attr", text: s[start:i]})
+
+			// An = and the value may have space around it
+			j := i
+			for j < len(s) && isTagSpace(s[j]) {
+				j++
+			}
+			if j >= len(s) || s[j] != '=' {
+				continue
+			}
+			j++
+			for j < len(s) && isTagSpace(s[j]) {
+				j++
+			}
+			emit(sourceSpan{text: s[i:j]})
+			i = j
+
+			value, raw := "", ""
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				// An unclosed quote runs to the end of the source
+				if end := strings.IndexByte(s[i+1:], s[i]); end >= 0 {
+					raw, value = s[i:i+end+2], s[i+1:i+end+1]
+				} else {
+					raw, value = s[i:], s[i+1:]
+				}
+			} else {
+				end := i
+				for end < len(s) && !isTagSpace(s[end]) && s[end] != '>' {
+					end++
+				}
+				raw = s[i:end]
+				value = raw
+			}
+			emit(sourceSpan{class: "value", text: raw, href: sourceLink(attr, value, base)})
+			i += len(raw)
+		}
+	}
+	return name, len(s)
+}
+
+// isTagSpace reports whether c separates the parts of a tag
+func isTagSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// sourceLink returns the view-source: address an attribute's value links
+// to, or "" when it is not a link to a document
+func sourceLink(attr, value, base string) string {
+	if attr != "href" && attr != "src" {
+		return ""
+	}
+	value = strings.TrimSpace(html.UnescapeString(value))
+	if value == "" || strings.HasPrefix(value, "#") {
+		return ""
+	}
+	abs := spidergopher.ResolveURL(value, base)
+	lower := strings.ToLower(abs)
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") && !strings.HasPrefix(lower, "file://") {
+		return ""
+	}
+	return ViewSourceScheme + abs
+}
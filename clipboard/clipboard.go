@@ -0,0 +1,94 @@
+// Package clipboard reads and writes the system clipboard through the
+// platform's command-line tools, falling back to a process-local buffer when
+// none is available
+package clipboard
+
+import (
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// local holds the last copied text, used when no system tool works
+var local struct {
+	sync.Mutex
+	text string
+}
+
+// command is a clipboard tool invocation
+type command struct {
+	name string
+	args []string
+}
+
+// copyCommands lists the tools tried, in order, to write the clipboard
+func copyCommands() []command {
+	switch runtime.GOOS {
+	case "darwin":
+		return []command{{"pbcopy", nil}}
+	case "windows":
+		return []command{{"clip", nil}}
+	default:
+		return []command{
+			{"wl-copy", nil},
+			{"xclip", []string{"-selection", "clipboard"}},
+			{"xsel", []string{"--clipboard", "--input"}},
+		}
+	}
+}
+
+// pasteCommands lists the tools tried, in order, to read the clipboard
+func pasteCommands() []command {
+	switch runtime.GOOS {
+	case "darwin":
+		return []command{{"pbpaste", nil}}
+	case "windows":
+		return []command{{"powershell", []string{"-NoProfile", "-Command", "Get-Clipboard"}}}
+	default:
+		return []command{
+			{"wl-paste", []string{"--no-newline"}},
+			{"xclip", []string{"-selection", "clipboard", "-o"}},
+			{"xsel", []string{"--clipboard", "--output"}},
+		}
+	}
+}
+
+// WriteText puts text on the clipboard
+func WriteText(text string) {
+	local.Lock()
+	local.text = text
+	local.Unlock()
+
+	for _, c := range copyCommands() {
+		if _, err := exec.LookPath(c.name); err != nil {
+			continue
+		}
+		cmd := exec.Command(c.name, c.args...)
+		cmd.Stdin = strings.NewReader(text)
+		if cmd.Run() == nil {
+			return
+		}
+	}
+}
+
+// ReadText returns the clipboard's text
+func ReadText() string {
+	for _, c := range pasteCommands() {
+		if _, err := exec.LookPath(c.name); err != nil {
+			continue
+		}
+		out, err := exec.Command(c.name, c.args...).Output()
+		if err == nil {
+			// Get-Clipboard terminates its output with a newline
+			if runtime.GOOS == "windows" {
+				return strings.TrimRight(string(out), "\r\n")
+			}
+			return string(out)
+		}
+	}
+
+	local.Lock()
+	defer local.Unlock()
+	return local.text
+}
@@ -0,0 +1,108 @@
+// Command webview is a minimal example of embedding a browser.BrowserView in
+// an ebiten program: a sidebar of bookmarks next to a web page panel.
+//
+//	go run ./cmd/webview
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"log"
+
+	"go-browser/browser"
+	"go-browser/render"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/inpututil"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+const (
+	screenWidth  = 1024
+	screenHeight = 720
+	sidebarWidth = 200
+	rowHeight    = 32
+)
+
+// welcome is the page the view starts on
+const welcome = `<html><body style="font-family: sans-serif">
+<h1>Embedded view</h1>
+<p>This panel is a <code>browser.BrowserView</code> inside another ebiten
+program. Pick a bookmark on the left, or follow a link:
+<a href="https://example.com/">example.com</a>.</p>
+<p><input type="text" placeholder="Click here and type"></p>
+</body></html>`
+
+// bookmarks are the sidebar's entries
+var bookmarks = []struct{ title, url string }{
+	{"Example", "https://example.com/"},
+	{"Go", "https://go.dev/"},
+	{"Wikipedia", "https://en.wikipedia.org/wiki/Go_(programming_language)"},
+}
+
+// game is the host program: a sidebar and the view beside it
+type game struct {
+	view *browser.BrowserView
+}
+
+// viewRect is where the view sits on screen
+func viewRect() image.Rectangle {
+	return image.Rect(sidebarWidth, 0, screenWidth, screenHeight)
+}
+
+func (g *game) Update() error {
+	if inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+		mx, my := ebiten.CursorPosition()
+		if i := my / rowHeight; mx < sidebarWidth && i < len(bookmarks) {
+			g.view.Navigate(bookmarks[i].url)
+		}
+	}
+	return g.view.Update(viewRect())
+}
+
+func (g *game) Draw(screen *ebiten.Image) {
+	screen.Fill(color.RGBA{32, 33, 36, 255})
+	for i, b := range bookmarks {
+		y := float64(i * rowHeight)
+		if b.url == g.view.URL() {
+			vector.DrawFilledRect(screen, 0, float32(y), sidebarWidth, rowHeight, color.RGBA{60, 64, 72, 255}, false)
+		}
+		render.DrawText(screen, b.title, 12, y+(rowHeight-browser.FontSizeUI)/2, browser.FontSizeUI, color.White)
+	}
+	render.DrawText(screen, g.view.Title(), 12, screenHeight-24, browser.FontSizeUI-2, color.RGBA{150, 150, 160, 255})
+	g.view.Draw(screen, viewRect())
+}
+
+func (g *game) Layout(int, int) (int, int) {
+	return screenWidth, screenHeight
+}
+
+func main() {
+	loadFonts()
+	view := browser.NewBrowserView(nil)
+	view.LoadHTML(welcome)
+
+	ebiten.SetWindowSize(screenWidth, screenHeight)
+	ebiten.SetWindowTitle("BrowserView example")
+	if err := ebiten.RunGame(&game{view: view}); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// loadFonts gives the renderer its text faces
+func loadFonts() {
+	regular, err := text.NewGoTextFaceSource(bytes.NewReader(goregular.TTF))
+	if err != nil {
+		log.Fatal("Error loading font:", err)
+	}
+	render.SetFontSource(regular)
+	mono, err := text.NewGoTextFaceSource(bytes.NewReader(gomono.TTF))
+	if err != nil {
+		log.Fatal("Error loading monospace font:", err)
+	}
+	render.SetMonoFontSource(mono)
+}
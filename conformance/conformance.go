@@ -0,0 +1,477 @@
+// Package conformance runs a battery of small capability tests against the
+// live CSS, layout and JavaScript engines and scores what works, by area
+package conformance
+
+import (
+	"fmt"
+	"image/color"
+
+	"go-browser/css"
+	"go-browser/dom"
+	"go-browser/layout"
+	"go-browser/spidergopher"
+)
+
+// Areas the tests are grouped in, in the order they are reported
+const (
+	AreaCSS       = "CSS properties"
+	AreaSelectors = "Selectors"
+	AreaLayout    = "Layout"
+	AreaJS        = "JavaScript APIs"
+	AreaEvents    = "DOM events"
+)
+
+// Result is the outcome of one test
+type Result struct {
+	Area   string
+	Name   string
+	Passed bool
+	Detail string // what went wrong when the test failed
+}
+
+// Score sums up the results of one area
+type Score struct {
+	Area   string
+	Passed int
+	Total  int
+}
+
+// Percent returns the share of the area's tests that passed
+func (s Score) Percent() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return 100 * float64(s.Passed) / float64(s.Total)
+}
+
+// Report holds the results of a run
+type Report struct {
+	Results []Result
+}
+
+// Scores returns the score of every area with tests, in report order
+func (r *Report) Scores() []Score {
+	var scores []Score
+	for _, area := range []string{AreaCSS, AreaSelectors, AreaLayout, AreaJS, AreaEvents} {
+		score := Score{Area: area}
+		for _, res := range r.Results {
+			if res.Area != area {
+				continue
+			}
+			score.Total++
+			if res.Passed {
+				score.Passed++
+			}
+		}
+		if score.Total > 0 {
+			scores = append(scores, score)
+		}
+	}
+	return scores
+}
+
+// Total returns the score over every test
+func (r *Report) Total() Score {
+	total := Score{Area: "Total"}
+	for _, s := range r.Scores() {
+		total.Passed += s.Passed
+		total.Total += s.Total
+	}
+	return total
+}
+
+// test is one capability test; it returns "" when it passes and what went
+// wrong otherwise
+type test struct {
+	area string
+	name string
+	run  func() string
+}
+
+// Run executes every test. A test that panics fails instead of stopping
+// the run.
+func Run() *Report {
+	report := &Report{}
+	for _, t := range tests() {
+		detail := runTest(t)
+		report.Results = append(report.Results, Result{Area: t.area, Name: t.name, Passed: detail == "", Detail: detail})
+	}
+	return report
+}
+
+// runTest runs t, turning a panic into a failure
+func runTest(t test) (detail string) {
+	defer func() {
+		if r := recover(); r != nil {
+			detail = fmt.Sprintf("panic: %v", r)
+		}
+	}()
+	return t.run()
+}
+
+// tests returns the whole battery, area by area
+func tests() []test {
+	var all []test
+	all = append(all, cssTests()...)
+	all = append(all, selectorTests()...)
+	all = append(all, layoutTests()...)
+	all = append(all, jsTests()...)
+	all = append(all, eventTests()...)
+	return all
+}
+
+// =============================================================================
+// CSS PROPERTIES
+// Each test styles <div id="t"> and checks its computed style
+// =============================================================================
+
+// styled applies stylesheet to html and returns the computed style of #t
+func styled(stylesheet, html string) *css.ComputedStyle {
+	doc := dom.ParseDocument(html)
+	sheets := append(css.ExtractStylesheets(doc.Node), css.ParseStylesheet(stylesheet))
+	css.ApplyStylesToTree(doc.Node, sheets, css.DefaultMedia())
+	if node := doc.Node.GetElementById("t"); node != nil {
+		if cs, ok := node.ComputedStyle.(*css.ComputedStyle); ok {
+			return cs
+		}
+	}
+	return css.NewComputedStyle()
+}
+
+// cssTest checks a property of #t styled by rule
+func cssTest(name, rule string, check func(*css.ComputedStyle) string) test {
+	return test{AreaCSS, name, func() string {
+		return check(styled("#t { "+rule+" }", `<div id="t">text</div>`))
+	}}
+}
+
+// want reports a mismatch between a computed value and the expected one
+func want[T comparable](got, expected T) string {
+	if got == expected {
+		return ""
+	}
+	return fmt.Sprintf("got %v, want %v", got, expected)
+}
+
+func cssTests() []test {
+	red := color.RGBA{255, 0, 0, 255}
+	return []test{
+		cssTest("color", "color: red", func(cs *css.ComputedStyle) string { return want(cs.Color, red) }),
+		cssTest("background-color", "background-color: #ff0000", func(cs *css.ComputedStyle) string { return want(cs.BackgroundColor, red) }),
+		cssTest("rgb() colors", "color: rgb(255, 0, 0)", func(cs *css.ComputedStyle) string { return want(cs.Color, red) }),
+		cssTest("font-size in px", "font-size: 20px", func(cs *css.ComputedStyle) string { return want(cs.FontSize, 20.0) }),
+		cssTest("font-weight: bold", "font-weight: bold", func(cs *css.ComputedStyle) string { return want(cs.FontWeight, 700) }),
+		cssTest("display: none", "display: none", func(cs *css.ComputedStyle) string { return want(cs.Display, "none") }),
+		cssTest("margin shorthand", "margin: 1px 2px 3px 4px", func(cs *css.ComputedStyle) string {
+			return want([4]float64{cs.MarginTop, cs.MarginRight, cs.MarginBottom, cs.MarginLeft}, [4]float64{1, 2, 3, 4})
+		}),
+		cssTest("padding shorthand", "padding: 5px 6px", func(cs *css.ComputedStyle) string {
+			return want([4]float64{cs.PaddingTop, cs.PaddingRight, cs.PaddingBottom, cs.PaddingLeft}, [4]float64{5, 6, 5, 6})
+		}),
+		cssTest("border shorthand", "border: 2px solid red", func(cs *css.ComputedStyle) string {
+			return want([2]any{cs.BorderTopWidth, cs.BorderColor}, [2]any{2.0, red})
+		}),
+		cssTest("width in %", "width: 50%", func(cs *css.ComputedStyle) string { return want(cs.Width, css.Percent(50)) }),
+		cssTest("text-align", "text-align: center", func(cs *css.ComputedStyle) string { return want(cs.TextAlign, "center") }),
+		cssTest("text-decoration", "text-decoration: underline", func(cs *css.ComputedStyle) string { return want(cs.TextDecoration, "underline") }),
+		cssTest("text-transform", "text-transform: uppercase", func(cs *css.ComputedStyle) string { return want(cs.TextTransform, "uppercase") }),
+		cssTest("opacity", "opacity: 0.5", func(cs *css.ComputedStyle) string { return want(cs.Opacity, 0.5) }),
+		cssTest("visibility", "visibility: hidden", func(cs *css.ComputedStyle) string { return want(cs.Visibility, "hidden") }),
+		cssTest("transform", "transform: rotate(10deg)", func(cs *css.ComputedStyle) string { return want(len(cs.TransformFunctions), 1) }),
+		cssTest("calc()", "width: calc(100% - 20px)", func(cs *css.ComputedStyle) string {
+			return want(cs.Width.Resolve(16, 200, cs.Media.Viewport), 180.0)
+		}),
+		cssTest("custom properties and var()", "--accent: red; color: var(--accent)", func(cs *css.ComputedStyle) string { return want(cs.Color, red) }),
+		cssTest("inherit keyword", "border-color: red; color: inherit", func(cs *css.ComputedStyle) string {
+			return want(cs.Color, styled("", `<div id="t">x</div>`).Color)
+		}),
+		{AreaCSS, "inherited color", func() string {
+			cs := styled("#p { color: red }", `<div id="p"><span id="t">x</span></div>`)
+			return want(cs.Color, red)
+		}},
+		{AreaCSS, "!important wins", func() string {
+			cs := styled("#t { color: red !important } div { color: blue }", `<div id="t" style="color: blue">x</div>`)
+			return want(cs.Color, red)
+		}},
+		{AreaCSS, "later rule wins at equal specificity", func() string {
+			cs := styled("div { color: blue } div { color: red }", `<div id="t">x</div>`)
+			return want(cs.Color, red)
+		}},
+		{AreaCSS, "<style> elements", func() string {
+			cs := styled("", `<style>#t { color: red }</style><div id="t">x</div>`)
+			return want(cs.Color, red)
+		}},
+		{AreaCSS, "style attribute", func() string {
+			cs := styled("", `<div id="t" style="color: red">x</div>`)
+			return want(cs.Color, red)
+		}},
+	}
+}
+
+// =============================================================================
+// SELECTORS
+// Each test checks that a selector matches one element and not another (when
+// one is given) in the same document
+// =============================================================================
+
+const selectorDoc = `<ul id="list" class="menu">
+<li id="first" class="item a" data-kind="x-one">1</li>
+<li id="second" class="item b" lang="en-US">2</li>
+<li id="third" class="item" title="third item"><em>3</em></li>
+<li id="empty"></li>
+</ul>
+<p id="para"><input id="box" type="checkbox" checked><input id="off" type="text" disabled></p>`
+
+func selectorTest(selector, match, miss string) test {
+	return test{AreaSelectors, selector, func() string {
+		doc := dom.ParseDocument(selectorDoc)
+		sel := css.ParseSelector(selector)
+		if node := doc.Node.GetElementById(match); node == nil || !sel.Matches(node) {
+			return "does not match #" + match
+		}
+		if miss == "" {
+			return ""
+		}
+		if node := doc.Node.GetElementById(miss); node != nil && sel.Matches(node) {
+			return "also matches #" + miss
+		}
+		return ""
+	}}
+}
+
+func selectorTests() []test {
+	return []test{
+		selectorTest("li", "first", "list"),
+		selectorTest(".item", "first", "empty"),
+		selectorTest("#second", "second", "first"),
+		selectorTest("li.item.a", "first", "second"),
+		selectorTest("*", "first", ""),
+		selectorTest("[title]", "third", "first"),
+		selectorTest(`[data-kind="x-one"]`, "first", "second"),
+		selectorTest(`[data-kind^="x-"]`, "first", "second"),
+		selectorTest(`[title$="item"]`, "third", "first"),
+		selectorTest(`[title*="rd i"]`, "third", "first"),
+		selectorTest(`[class~="b"]`, "second", "first"),
+		selectorTest(`[lang|="en"]`, "second", "first"),
+		selectorTest("ul li", "second", "list"),
+		selectorTest("ul > li", "second", "box"),
+		selectorTest("#first + li", "second", "third"),
+		selectorTest("#first ~ li", "third", "first"),
+		selectorTest("li:first-child", "first", "second"),
+		selectorTest("li:last-child", "empty", "third"),
+		selectorTest("li:nth-child(2n+1)", "third", "second"),
+		selectorTest("li:nth-last-child(1)", "empty", "first"),
+		selectorTest("li:not(.item)", "empty", "first"),
+		selectorTest("li:empty", "empty", "first"),
+		selectorTest("li:has(em)", "third", "first"),
+		selectorTest(":is(#first, #second)", "second", "third"),
+		selectorTest(":where(.b)", "second", "first"),
+		selectorTest("input:checked", "box", "off"),
+		selectorTest("input:disabled", "off", "box"),
+		{AreaSelectors, ":root", func() string {
+			doc := dom.ParseDocument(selectorDoc)
+			sel := css.ParseSelector(":root")
+			if !sel.Matches(doc.DocumentElement) {
+				return "does not match <html>"
+			}
+			if sel.Matches(doc.Node.GetElementById("list")) {
+				return "also matches #list"
+			}
+			return ""
+		}},
+	}
+}
+
+// =============================================================================
+// LAYOUT
+// Tests lay out a snippet 400px wide and check the boxes of its elements
+// =============================================================================
+
+// laidOut lays out html with stylesheet and returns the boxes of its
+// elements by id
+func laidOut(stylesheet, html string) map[string]*layout.RenderBox {
+	doc := dom.ParseDocument(html)
+	css.ApplyStylesToTree(doc.Node, []*css.Stylesheet{css.ParseStylesheet(stylesheet)}, css.DefaultMedia())
+	boxes := map[string]*layout.RenderBox{}
+	var walk func(box *layout.RenderBox)
+	walk = func(box *layout.RenderBox) {
+		if box.Node != nil {
+			if id := box.Node.GetAttr("id"); id != "" && boxes[id] == nil {
+				boxes[id] = box
+			}
+		}
+		for _, child := range box.Children {
+			walk(child)
+		}
+	}
+	walk(layout.BuildRenderTree(doc.Node, 400, nil))
+	return boxes
+}
+
+func layoutTest(name, stylesheet, html string, check func(map[string]*layout.RenderBox) string) test {
+	return test{AreaLayout, name, func() string {
+		boxes := laidOut(stylesheet, html)
+		for _, id := range []string{"a", "b"} {
+			if boxes[id] == nil {
+				return "#" + id + " was not laid out"
+			}
+		}
+		return check(boxes)
+	}}
+}
+
+func layoutTests() []test {
+	return []test{
+		layoutTest("blocks stack vertically", "", `<div id="a">1</div><div id="b">2</div>`, func(b map[string]*layout.RenderBox) string {
+			if b["b"].Y < b["a"].Y+b["a"].H {
+				return "the second block overlaps the first"
+			}
+			return ""
+		}),
+		layoutTest("width in %", "#a { width: 50% }", `<div id="a">1</div><div id="b">2</div>`, func(b map[string]*layout.RenderBox) string {
+			return want(b["a"].W, b["b"].W/2)
+		}),
+		layoutTest("display: none", "#a { display: none }", `<div id="a">1</div><div id="b">2</div><div id="c">3</div>`, func(b map[string]*layout.RenderBox) string {
+			if b["a"].H > 0 {
+				return "the hidden block takes space"
+			}
+			return ""
+		}),
+		layoutTest("flex row", "#f { display: flex } #a, #b { width: 100px }", `<div id="f"><div id="a">1</div><div id="b">2</div></div>`, func(b map[string]*layout.RenderBox) string {
+			if b["a"].Y != b["b"].Y || b["b"].X < b["a"].X+b["a"].W {
+				return "the items are not side by side"
+			}
+			return ""
+		}),
+		layoutTest("float: left", "#a { float: left; width: 100px; height: 50px }", `<div id="a">1</div><p id="b">text</p>`, func(b map[string]*layout.RenderBox) string {
+			if b["b"].Y >= b["a"].Y+b["a"].H {
+				return "the text does not flow beside the float"
+			}
+			return ""
+		}),
+		layoutTest("padding widens the box", "#a { padding: 10px; width: 100px } #b { width: 100px }", `<div id="a">1</div><div id="b">2</div>`, func(b map[string]*layout.RenderBox) string {
+			return want(b["a"].W-b["b"].W, 20.0)
+		}),
+		layoutTest("box-sizing: border-box", "#a { padding: 10px; width: 100px; box-sizing: border-box } #b { width: 100px }", `<div id="a">1</div><div id="b">2</div>`, func(b map[string]*layout.RenderBox) string {
+			return want(b["a"].W, b["b"].W)
+		}),
+	}
+}
+
+// =============================================================================
+// JAVASCRIPT
+// Scripts run in an engine attached to a small document; a test passes when
+// its expression is true
+// =============================================================================
+
+const scriptDoc = `<div id="parent"><button id="button">Go</button></div>`
+
+// evaluate runs script in a fresh engine on scriptDoc and reports whether it
+// returned true
+func evaluate(script string) string {
+	engine := spidergopher.NewEngine()
+	defer engine.Stop()
+	engine.SetDocument(dom.ParseDocument(scriptDoc))
+	v, err := engine.Run(script)
+	if err != nil {
+		return err.Error()
+	}
+	if !v.ToBoolean() {
+		return fmt.Sprintf("returned %v", v)
+	}
+	return ""
+}
+
+func jsTest(area, name, script string) test {
+	return test{area, name, func() string { return evaluate(script) }}
+}
+
+// apiTest checks that expression is defined
+func apiTest(expression string) test {
+	return jsTest(AreaJS, expression, "typeof "+expression+" !== 'undefined'")
+}
+
+func jsTests() []test {
+	return []test{
+		apiTest("document.querySelector"),
+		apiTest("document.createElement"),
+		apiTest("document.getElementById"),
+		apiTest("document.title"),
+		apiTest("document.URL"),
+		apiTest("document.characterSet"),
+		apiTest("console.log"),
+		apiTest("setTimeout"),
+		apiTest("setInterval"),
+		apiTest("requestAnimationFrame"),
+		apiTest("queueMicrotask"),
+		apiTest("fetch"),
+		apiTest("AbortController"),
+		apiTest("localStorage"),
+		apiTest("sessionStorage"),
+		apiTest("history"),
+		apiTest("location"),
+		apiTest("matchMedia"),
+		apiTest("MutationObserver"),
+		apiTest("URL"),
+		apiTest("TextEncoder"),
+		apiTest("Worker"),
+		apiTest("navigator.userActivation"),
+		apiTest("navigator.clipboard"),
+		jsTest(AreaJS, "Promise", "typeof Promise === 'function'"),
+		jsTest(AreaJS, "JSON round trip", `JSON.parse(JSON.stringify({a: [1]})).a[0] === 1`),
+		jsTest(AreaJS, "document.title setter", `document.title = 'Renamed'; document.title === 'Renamed'`),
+		jsTest(AreaJS, "attribute selectors", `document.querySelector('[id="button"]').id === 'button'`),
+		jsTest(AreaJS, "element.classList", `document.getElementById('button').classList.add('x'); document.getElementById('button').className === 'x'`),
+		jsTest(AreaJS, "element.dataset", `typeof document.getElementById('button').dataset === 'object'`),
+		jsTest(AreaJS, "appendChild", `var p = document.getElementById('parent'); p.appendChild(document.createElement('span')); p.children.length === 2`),
+		jsTest(AreaJS, "textContent", `document.getElementById('button').textContent === 'Go'`),
+		jsTest(AreaJS, "tagName is upper case", `document.getElementById('button').tagName === 'BUTTON'`),
+		jsTest(AreaJS, "insertAdjacentHTML", `typeof document.getElementById('parent').insertAdjacentHTML === 'function'`),
+	}
+}
+
+// =============================================================================
+// EVENTS
+// =============================================================================
+
+func eventTests() []test {
+	return []test{
+		jsTest(AreaEvents, "click() fires click listeners", `
+			var fired = false;
+			var b = document.getElementById('button');
+			b.addEventListener('click', function () { fired = true; });
+			b.click();
+			fired`),
+		jsTest(AreaEvents, "clicks bubble to ancestors", `
+			var seen = false;
+			document.getElementById('parent').addEventListener('click', function () { seen = true; });
+			document.getElementById('button').click();
+			seen`),
+		jsTest(AreaEvents, "event.target", `
+			var target = null;
+			var b = document.getElementById('button');
+			b.addEventListener('click', function (e) { target = e.target; });
+			b.click();
+			target !== null && target.id === 'button'`),
+		jsTest(AreaEvents, "window custom events", `
+			var got = false;
+			window.addEventListener('ping', function () { got = true; });
+			window.dispatchEvent({type: 'ping'});
+			got`),
+		jsTest(AreaEvents, "removeEventListener", `
+			var count = 0;
+			function h() { count++; }
+			window.addEventListener('ping', h);
+			window.removeEventListener('ping', h);
+			window.dispatchEvent({type: 'ping'});
+			count === 0`),
+		jsTest(AreaEvents, "event.preventDefault", `
+			var ok = false;
+			var b = document.getElementById('button');
+			b.addEventListener('click', function (e) { ok = typeof e.preventDefault === 'function'; });
+			b.click();
+			ok`),
+		jsTest(AreaEvents, "Event constructor", `typeof Event === 'function' && new Event('x').type === 'x'`),
+		jsTest(AreaEvents, "CustomEvent detail", `typeof CustomEvent === 'function' && new CustomEvent('x', {detail: 1}).detail === 1`),
+	}
+}
@@ -0,0 +1,247 @@
+// A small stand-in for WPT's testharness.js: the test(), async_test() and
+// promise_test() functions and the assert_* family, recording each test's
+// status in __wpt.results for the Go runner to read.
+(function (global) {
+    var PASS = "PASS", FAIL = "FAIL", TIMEOUT = "TIMEOUT", NOTRUN = "NOTRUN";
+    var tests = [];
+    var promiseQueue = [];
+    var promiseRunning = false;
+
+    function AssertionError(message) {
+        this.message = message;
+    }
+    AssertionError.prototype.toString = function () { return this.message; };
+
+    function format(v) {
+        if (typeof v === "string") return JSON.stringify(v);
+        if (v === null) return "null";
+        if (typeof v === "number" && v === 0 && 1 / v < 0) return "-0";
+        if (typeof v === "object" || typeof v === "function") {
+            try {
+                if (v.nodeType === 1 && v.tagName) return "Element <" + String(v.tagName).toLowerCase() + ">";
+                if (Array.isArray(v)) return "[" + v.map(format).join(", ") + "]";
+            } catch (e) {}
+            return String(v);
+        }
+        return String(v);
+    }
+
+    function assert(cond, fn, desc, message) {
+        if (!cond) {
+            throw new AssertionError(fn + ": " + (desc ? desc + " " : "") + message);
+        }
+    }
+
+    function sameValue(a, b) {
+        if (a !== a && b !== b) return true; // NaN
+        if (a === 0 && b === 0) return 1 / a === 1 / b;
+        return a === b;
+    }
+
+    function Test(name) {
+        this.name = name;
+        this.status = null;
+        this.message = "";
+        this.cleanups = [];
+        tests.push(this);
+    }
+    Test.prototype.step = function (fn, thisObj) {
+        if (this.status !== null) return;
+        try {
+            return fn.apply(thisObj || this, Array.prototype.slice.call(arguments, 2));
+        } catch (e) {
+            this.fail(e);
+        }
+    };
+    Test.prototype.step_func = function (fn, thisObj) {
+        var t = this;
+        return function () {
+            return t.step.apply(t, [fn, thisObj || this].concat(Array.prototype.slice.call(arguments)));
+        };
+    };
+    Test.prototype.step_func_done = function (fn, thisObj) {
+        var t = this;
+        return function () {
+            if (fn) t.step.apply(t, [fn, thisObj || this].concat(Array.prototype.slice.call(arguments)));
+            t.done();
+        };
+    };
+    Test.prototype.unreached_func = function (description) {
+        var t = this;
+        return t.step_func(function () { assert_unreached(description); });
+    };
+    Test.prototype.step_timeout = function (fn, ms) {
+        var t = this;
+        return setTimeout(function () { t.step(fn); }, ms);
+    };
+    Test.prototype.add_cleanup = function (fn) {
+        this.cleanups.push(fn);
+    };
+    Test.prototype.fail = function (e) {
+        if (this.status !== null) return;
+        this.finish(FAIL, e instanceof AssertionError ? e.message : String(e && e.name ? e.name + ": " + e.message : e));
+    };
+    Test.prototype.done = function () {
+        if (this.status === null) this.finish(PASS, "");
+    };
+    Test.prototype.finish = function (status, message) {
+        this.status = status;
+        this.message = message;
+        for (var i = 0; i < this.cleanups.length; i++) {
+            try { this.cleanups[i](); } catch (e) {}
+        }
+    };
+
+    global.test = function (fn, name) {
+        var t = new Test(name || "test " + (tests.length + 1));
+        t.step(fn);
+        t.done();
+    };
+
+    global.async_test = function (fn, name) {
+        if (typeof fn === "string") {
+            return new Test(fn);
+        }
+        var t = new Test(name || "test " + (tests.length + 1));
+        t.step(fn, t, t);
+        return t;
+    };
+
+    global.promise_test = function (fn, name) {
+        var t = new Test(name || "test " + (tests.length + 1));
+        promiseQueue.push({ test: t, fn: fn });
+        if (!promiseRunning) {
+            promiseRunning = true;
+            setTimeout(nextPromiseTest, 0);
+        }
+    };
+
+    function nextPromiseTest() {
+        var next = promiseQueue.shift();
+        if (!next) {
+            promiseRunning = false;
+            return;
+        }
+        var t = next.test;
+        var p;
+        try {
+            p = next.fn(t);
+        } catch (e) {
+            t.fail(e);
+            nextPromiseTest();
+            return;
+        }
+        if (!p || typeof p.then !== "function") {
+            t.fail("promise_test: test body did not return a promise");
+            nextPromiseTest();
+            return;
+        }
+        p.then(function () { t.done(); }, function (e) { t.fail(e); })
+            .then(nextPromiseTest, nextPromiseTest);
+    }
+
+    global.setup = function () {};
+    global.done = function () {};
+    global.step_timeout = function (fn, ms) { return setTimeout(fn, ms); };
+
+    global.assert_true = function (actual, desc) {
+        assert(actual === true, "assert_true", desc, "expected true got " + format(actual));
+    };
+    global.assert_false = function (actual, desc) {
+        assert(actual === false, "assert_false", desc, "expected false got " + format(actual));
+    };
+    global.assert_equals = function (actual, expected, desc) {
+        assert(sameValue(actual, expected), "assert_equals", desc,
+            "expected " + format(expected) + " but got " + format(actual));
+    };
+    global.assert_not_equals = function (actual, expected, desc) {
+        assert(!sameValue(actual, expected), "assert_not_equals", desc, "got disallowed value " + format(actual));
+    };
+    global.assert_in_array = function (actual, expected, desc) {
+        assert(expected.indexOf(actual) !== -1, "assert_in_array", desc,
+            "value " + format(actual) + " not in array " + format(expected));
+    };
+    global.assert_array_equals = function (actual, expected, desc) {
+        assert(actual !== null && actual !== undefined && typeof actual.length === "number",
+            "assert_array_equals", desc, "value is " + format(actual) + ", expected array");
+        assert(actual.length === expected.length, "assert_array_equals", desc,
+            "lengths differ, expected array " + format(expected) + " length " + expected.length +
+            ", got " + format(actual) + " length " + actual.length);
+        for (var i = 0; i < expected.length; i++) {
+            assert(sameValue(actual[i], expected[i]), "assert_array_equals", desc,
+                "expected property " + i + " to be " + format(expected[i]) + " but got " + format(actual[i]));
+        }
+    };
+    global.assert_greater_than = function (actual, expected, desc) {
+        assert(actual > expected, "assert_greater_than", desc, "expected a number greater than " + format(expected) + " but got " + format(actual));
+    };
+    global.assert_less_than = function (actual, expected, desc) {
+        assert(actual < expected, "assert_less_than", desc, "expected a number less than " + format(expected) + " but got " + format(actual));
+    };
+    global.assert_greater_than_equal = function (actual, expected, desc) {
+        assert(actual >= expected, "assert_greater_than_equal", desc, "expected a number greater than or equal to " + format(expected) + " but got " + format(actual));
+    };
+    global.assert_less_than_equal = function (actual, expected, desc) {
+        assert(actual <= expected, "assert_less_than_equal", desc, "expected a number less than or equal to " + format(expected) + " but got " + format(actual));
+    };
+    global.assert_regexp_match = function (actual, expected, desc) {
+        assert(expected.test(actual), "assert_regexp_match", desc, "expected " + format(expected) + " but got " + format(actual));
+    };
+    global.assert_own_property = function (object, name, desc) {
+        assert(object !== null && object !== undefined && Object.prototype.hasOwnProperty.call(object, name),
+            "assert_own_property", desc, "expected property " + format(name) + " missing");
+    };
+    global.assert_implements = function (condition, desc) {
+        assert(!!condition, "assert_implements", desc, "not implemented");
+    };
+    global.assert_unreached = function (desc) {
+        assert(false, "assert_unreached", desc, "reached unreachable code");
+    };
+    global.assert_throws_js = function (constructor, fn, desc) {
+        try {
+            fn();
+        } catch (e) {
+            assert(e instanceof constructor || (e && e.name === constructor.name), "assert_throws_js", desc,
+                fn + " threw " + format(e) + ", expected " + constructor.name);
+            return;
+        }
+        assert(false, "assert_throws_js", desc, fn + " did not throw");
+    };
+    global.assert_throws_dom = function (name, fn, desc) {
+        try {
+            fn();
+        } catch (e) {
+            assert(e && e.name === name, "assert_throws_dom", desc,
+                fn + " threw " + format(e) + " with name " + format(e && e.name) + ", expected " + format(name));
+            return;
+        }
+        assert(false, "assert_throws_dom", desc, fn + " did not throw");
+    };
+
+    global.__wpt = {
+        // finished reports whether every test has a status
+        finished: function () {
+            if (promiseRunning) return false;
+            for (var i = 0; i < tests.length; i++) {
+                if (tests[i].status === null) return false;
+            }
+            return true;
+        },
+        // results gives up on the tests still running and returns them all
+        results: function () {
+            for (var q = 0; q < promiseQueue.length; q++) {
+                promiseQueue[q].test.finish(NOTRUN, "");
+            }
+            promiseQueue = [];
+            var out = [];
+            for (var i = 0; i < tests.length; i++) {
+                var t = tests[i];
+                if (t.status === null) {
+                    t.finish(TIMEOUT, "");
+                }
+                out.push({ name: String(t.name), status: t.status, message: t.message });
+            }
+            return JSON.stringify(out);
+        }
+    };
+})(this);
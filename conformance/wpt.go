@@ -0,0 +1,221 @@
+package conformance
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go-browser/dom"
+	"go-browser/headless"
+)
+
+// =============================================================================
+// WEB PLATFORM TESTS
+// Test files in the style of the Web Platform Tests run in a headless page:
+// a stand-in for testharness.js takes the place of the page's harness
+// scripts, the page's own scripts declare tests with test(), async_test()
+// and promise_test(), and each subtest passes, fails, times out or doesn't
+// run. A curated subset ships in wpt/, one directory per area; RunWPTDir
+// runs the files of a checkout of the real suite the same way.
+// =============================================================================
+
+// wptTimeout is how long a file's tests may take before those still
+// running time out
+const wptTimeout = 5 * time.Second
+
+// WPT subtest statuses
+const (
+	WPTPass    = "PASS"
+	WPTFail    = "FAIL"
+	WPTTimeout = "TIMEOUT"
+	WPTNotRun  = "NOTRUN"
+)
+
+//go:embed testharness.js
+var testharness string
+
+//go:embed wpt
+var curatedWPT embed.FS
+
+// WPTSubtest is the outcome of one test() of a file
+type WPTSubtest struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// WPTFile is the outcome of one test file
+type WPTFile struct {
+	Path     string // slash-separated, relative to the suite's root
+	Subtests []WPTSubtest
+	Error    string // why the file's tests couldn't all run, if so
+}
+
+// Passed returns how many of the file's subtests passed
+func (f WPTFile) Passed() int {
+	passed := 0
+	for _, s := range f.Subtests {
+		if s.Status == WPTPass {
+			passed++
+		}
+	}
+	return passed
+}
+
+// WPTReport holds the outcome of every file of a run, in path order
+type WPTReport struct {
+	Files []WPTFile
+}
+
+// Scores returns the score of every area, the files' top directory, in
+// name order. A file whose harness failed counts as one failed test.
+func (r *WPTReport) Scores() []Score {
+	byArea := make(map[string]*Score)
+	var areas []string
+	for _, f := range r.Files {
+		area, _, _ := strings.Cut(f.Path, "/")
+		score := byArea[area]
+		if score == nil {
+			score = &Score{Area: area}
+			byArea[area] = score
+			areas = append(areas, area)
+		}
+		score.Passed += f.Passed()
+		score.Total += len(f.Subtests)
+		if f.Error != "" {
+			score.Total++
+		}
+	}
+	sort.Strings(areas)
+	scores := make([]Score, len(areas))
+	for i, area := range areas {
+		scores[i] = *byArea[area]
+	}
+	return scores
+}
+
+// Total returns the score over every file
+func (r *WPTReport) Total() Score {
+	total := Score{Area: "Total"}
+	for _, s := range r.Scores() {
+		total.Passed += s.Passed
+		total.Total += s.Total
+	}
+	return total
+}
+
+// RunWPT runs the curated subset of the Web Platform Tests
+func RunWPT() *WPTReport {
+	suite, err := fs.Sub(curatedWPT, "wpt")
+	if err != nil {
+		return &WPTReport{}
+	}
+	report, _ := runWPT(suite, func(p string) string { return "http://web-platform.test/" + p })
+	return report
+}
+
+// RunWPTDir runs the test files under dir, such as a checkout of the
+// suite or one of its directories
+func RunWPTDir(dir string) (*WPTReport, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	return runWPT(os.DirFS(abs), func(p string) string {
+		return "file://" + filepath.ToSlash(filepath.Join(abs, filepath.FromSlash(p)))
+	})
+}
+
+// runWPT runs every .html file of suite outside resources directories;
+// pageURL gives the address a file's page loads at
+func runWPT(suite fs.FS, pageURL func(p string) string) (*WPTReport, error) {
+	report := &WPTReport{}
+	err := fs.WalkDir(suite, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == "resources" {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if ext := path.Ext(p); ext != ".html" && ext != ".htm" {
+			return nil
+		}
+		html, err := fs.ReadFile(suite, p)
+		if err != nil {
+			report.Files = append(report.Files, WPTFile{Path: p, Error: err.Error()})
+			return nil
+		}
+		file := runWPTFile(string(html), pageURL(p))
+		file.Path = p
+		report.Files = append(report.Files, file)
+		return nil
+	})
+	return report, err
+}
+
+// runWPTFile loads a test file's page with the stand-in harness, runs its
+// scripts and waits for its tests, at most wptTimeout
+func runWPTFile(html, pageURL string) (file WPTFile) {
+	defer func() {
+		if r := recover(); r != nil {
+			file.Error = fmt.Sprintf("panic: %v", r)
+		}
+	}()
+	page := headless.LoadHTML(html, pageURL)
+	defer page.Close()
+	removeHarnessScripts(page.Document.Node)
+	if _, err := page.Eval(testharness); err != nil {
+		file.Error = "harness: " + err.Error()
+		return file
+	}
+	page.RunScripts()
+
+	deadline := time.Now().Add(wptTimeout)
+	for time.Now().Before(deadline) {
+		if done, _ := page.Eval("__wpt.finished()"); done == true {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	results, err := page.Eval("__wpt.results()")
+	if err != nil {
+		file.Error = "harness: " + err.Error()
+		return file
+	}
+	if err := json.Unmarshal([]byte(fmt.Sprint(results)), &file.Subtests); err != nil {
+		file.Error = "harness: " + err.Error()
+		return file
+	}
+	if errs := page.ScriptErrors(); len(errs) > 0 {
+		// An error outside any test is the file's: WPT reports it as a
+		// harness error
+		file.Error = errs[0].Message
+	} else if len(file.Subtests) == 0 {
+		file.Error = "no tests"
+	}
+	return file
+}
+
+// removeHarnessScripts drops the page's <script src> tags that load the
+// suite's harness from /resources/, which the stand-in replaces
+func removeHarnessScripts(node *dom.Node) {
+	for i := 0; i < len(node.Children); i++ {
+		child := node.Children[i]
+		if child.Tag == "script" && strings.Contains(child.GetAttr("src"), "/resources/") {
+			node.RemoveChild(child)
+			i--
+			continue
+		}
+		removeHarnessScripts(child)
+	}
+}
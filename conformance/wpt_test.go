@@ -0,0 +1,77 @@
+package conformance
+
+import "testing"
+
+// statuses returns each subtest's status by name
+func statuses(f WPTFile) map[string]string {
+	out := make(map[string]string)
+	for _, s := range f.Subtests {
+		out[s.Name] = s.Status
+	}
+	return out
+}
+
+func TestWPTHarness(t *testing.T) {
+	f := runWPTFile(`<!DOCTYPE html>
+<script src="/resources/testharness.js"></script>
+<script src="/resources/testharnessreport.js"></script>
+<div id="d">x</div>
+<script>
+test(function() { assert_equals(document.getElementById("d").textContent, "x"); }, "passes");
+test(function() { assert_equals(1, 2); }, "fails");
+test(function() { assert_array_equals([1, NaN], [1, NaN]); }, "NaN equals NaN");
+test(function() { assert_not_equals(0, -0); }, "0 is not -0");
+async_test(function(t) { setTimeout(t.step_func_done(), 0); }, "async passes");
+promise_test(function() { return Promise.reject(new Error("no")); }, "promise rejects");
+promise_test(function() { return Promise.resolve(); }, "promise resolves");
+</script>`, "http://web-platform.test/harness.html")
+
+	if f.Error != "" {
+		t.Fatalf("harness error: %s", f.Error)
+	}
+	want := map[string]string{
+		"passes":           WPTPass,
+		"fails":            WPTFail,
+		"NaN equals NaN":   WPTPass,
+		"0 is not -0":      WPTPass,
+		"async passes":     WPTPass,
+		"promise rejects":  WPTFail,
+		"promise resolves": WPTPass,
+	}
+	got := statuses(f)
+	for name, status := range want {
+		if got[name] != status {
+			t.Errorf("%q: status %q, want %q", name, got[name], status)
+		}
+	}
+	if f.Passed() != 5 {
+		t.Errorf("Passed() = %d, want 5", f.Passed())
+	}
+}
+
+func TestWPTScores(t *testing.T) {
+	r := &WPTReport{Files: []WPTFile{
+		{Path: "dom/a.html", Subtests: []WPTSubtest{{Status: WPTPass}, {Status: WPTFail}}},
+		{Path: "css/b.html", Subtests: []WPTSubtest{{Status: WPTPass}}},
+		{Path: "dom/c.html", Error: "no tests"},
+	}}
+	scores := r.Scores()
+	if len(scores) != 2 || scores[0] != (Score{"css", 1, 1}) || scores[1] != (Score{"dom", 1, 3}) {
+		t.Errorf("Scores() = %v", scores)
+	}
+	if total := r.Total(); total.Passed != 2 || total.Total != 4 {
+		t.Errorf("Total() = %v", total)
+	}
+}
+
+func TestCuratedWPTRuns(t *testing.T) {
+	r := RunWPT()
+	if len(r.Files) == 0 {
+		t.Fatal("no curated test files")
+	}
+	for _, f := range r.Files {
+		if len(f.Subtests) == 0 {
+			t.Errorf("%s ran no subtests: %s", f.Path, f.Error)
+		}
+	}
+}
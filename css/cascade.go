@@ -2,6 +2,7 @@ package css
 
 import (
 	"go-browser/dom"
+	"image/color"
 	"io"
 	"net/http"
 	"net/url"
@@ -18,47 +19,223 @@ import (
 // StyleEntry represents a matched rule with its specificity and order
 type StyleEntry struct {
 	Declarations []Declaration
+	Origin       Origin
 	Specificity  Specificity
 	Order        int
 	Important    bool
 }
 
-// ComputeStyles calculates the final computed style for a DOM node
-func ComputeStyles(node *dom.Node, stylesheets []*Stylesheet) *ComputedStyle {
+// Origin is where a stylesheet comes from. Normal declarations of the user
+// agent lose to the user's, which lose to the page author's; !important
+// turns the order around, so a user's !important rule beats the page's.
+// Inline styles are the author's, more specific than any selector.
+type Origin int
+
+const (
+	OriginAuthor    Origin = iota // the page's <style>, <link> and style attributes
+	OriginUser                    // the user's own stylesheet
+	OriginUserAgent               // the browser's defaults
+)
+
+// cascadeLevel ranks an entry's origin and importance, lowest first; within
+// a level specificity and then source order decide
+func (e StyleEntry) cascadeLevel() int {
+	switch {
+	case !e.Important && e.Origin == OriginUserAgent:
+		return 0
+	case !e.Important && e.Origin == OriginUser:
+		return 1
+	case !e.Important:
+		return 2
+	case e.Origin == OriginAuthor:
+		return 3
+	case e.Origin == OriginUser:
+		return 4
+	}
+	return 5
+}
+
+// matchedSelector is a selector found to match an element, from the
+// stylesheet numbered sheet
+type matchedSelector struct {
+	sheet    int
+	origin   Origin
+	selector *indexedSelector
+}
+
+// ComputeStyles calculates the final computed style for a DOM node shown on
+// media
+func ComputeStyles(node *dom.Node, stylesheets []*Stylesheet, media Media) *ComputedStyle {
+	return computeStyles(node, stylesheets, media, nil)
+}
+
+// computeStyles computes node's style; with a styler, selectors are ruled
+// out by its ancestor filter and the style is shared with elements that
+// cascade the same
+func computeStyles(node *dom.Node, stylesheets []*Stylesheet, media Media, st *styler) *ComputedStyle {
 	if node == nil || node.Type != dom.NodeElement {
 		return NewComputedStyle()
 	}
 
-	// Start with defaults for the tag
-	style := DefaultForTag(node.Tag)
+	var filter *ancestorFilter
+	if st != nil {
+		filter = st.ancestors
+	}
+	matched := matchSelectors(node, stylesheets, media, "", filter)
+	inline := node.GetAttr("style")
+	var parent *ComputedStyle
+	if node.Parent != nil {
+		parent, _ = node.Parent.ComputedStyle.(*ComputedStyle)
+	}
+	var key sharedStyleKey
+	if st != nil {
+		key = sharedStyleKey{parent: parent, cascade: shareKey(node, matched, inline)}
+		if shared := st.shared[key]; shared != nil {
+			style := *shared
+			return &style
+		}
+	}
+
+	// Inherited properties start from the parent's values, which the
+	// defaults for the tag and then the author's rules override
+	style := NewComputedStyle()
+	style.Media = media
+	if parent != nil {
+		inheritProperties(style, parent)
+	}
+	applyTagDefaults(style, node.Tag)
+	if style.FontSizeScale > 0 && parent != nil {
+		scale := style.FontSizeScale
+		// Monospace text inside monospace text, as <code> in <pre>, is
+		// not made smaller twice
+		if scale == MonospaceScale && IsMonospace(parent.FontFamily) {
+			scale = 1
+		}
+		style.FontSize = parent.FontSize * scale
+	}
+	// UA stylesheet rules that depend on attributes: links get the pointing
+	// hand and an underline, abbreviations with an expansion a dotted
+	// underline, images aligned left or right float to that side, and
+	// hidden elements and closed dialogs aren't rendered
+	if node.Tag == "a" && node.GetAttr("href") != "" {
+		style.Cursor = "pointer"
+		style.TextDecoration = "underline"
+	}
+	if (node.Tag == "abbr" || node.Tag == "acronym") && node.GetAttr("title") != "" {
+		style.TextDecoration, style.TextDecorationStyle = "underline", "dotted"
+	}
+	if align := strings.ToLower(node.GetAttr("align")); node.Tag == "img" && (align == "left" || align == "right") {
+		style.Float = align
+	}
+	if node.HasAttr("hidden") || (node.Tag == "dialog" && !node.HasAttr("open")) {
+		style.Display = "none"
+	}
+
+	// Apply in order (later declarations override earlier); inherit and
+	// relative font sizes take the parent's values
+	style.parent = parent
+	for _, entry := range styleEntries(node, matched, inline) {
+		ApplyDeclarations(style, entry.Declarations)
+	}
+	style.parent = nil
+	style.resolveCurrentColor()
+
+	if st != nil {
+		shared := *style
+		st.shared[key] = &shared
+	}
+	return style
+}
+
+// PseudoElements lists the pseudo-elements whose styles are computed per element
+var PseudoElements = []string{"selection", "placeholder", "marker"}
 
-	// Collect all matching rules
+// ComputePseudoElementStyle computes the style of a ::pseudo-element originating
+// at node. It returns nil when no rule targets that pseudo-element. Colors left
+// unset by the rules stay fully transparent so callers can keep their defaults.
+func ComputePseudoElementStyle(node *dom.Node, stylesheets []*Stylesheet, media Media, pseudo string) *ComputedStyle {
+	return computePseudoElementStyle(node, stylesheets, media, pseudo, nil)
+}
+
+// computePseudoElementStyle is ComputePseudoElementStyle ruling selectors
+// out with an ancestor filter, when given
+func computePseudoElementStyle(node *dom.Node, stylesheets []*Stylesheet, media Media, pseudo string, filter *ancestorFilter) *ComputedStyle {
+	if node == nil || node.Type != dom.NodeElement {
+		return nil
+	}
+
+	entries := styleEntries(node, matchSelectors(node, stylesheets, media, pseudo, filter), "")
+	if len(entries) == 0 {
+		return nil
+	}
+
+	style := NewComputedStyle()
+	style.Media = media
+	style.Color = color.RGBA{}
+	for _, entry := range entries {
+		ApplyDeclarations(style, entry.Declarations)
+	}
+	style.resolveCurrentColor()
+	return style
+}
+
+// matchSelectors finds the selectors of the stylesheets matching node, or
+// one of its pseudo-elements when pseudo is set, in stylesheet order. Sheets
+// and rules whose media queries don't match media are skipped. A filter of
+// node's ancestors, when given, rules out selectors quickly.
+func matchSelectors(node *dom.Node, stylesheets []*Stylesheet, media Media, pseudo string, filter *ancestorFilter) []matchedSelector {
+	var matched []matchedSelector
+	for i, stylesheet := range stylesheets {
+		if stylesheet.Media != "" && !media.Match(stylesheet.Media) {
+			continue
+		}
+		for _, candidate := range stylesheet.ruleIndex().candidates(node) {
+			if candidate.pseudo != pseudo {
+				continue
+			}
+			if filter != nil && !filter.mayHaveAll(candidate.ancestors) {
+				continue
+			}
+			if len(candidate.rule.Media) > 0 && !candidate.rule.matchesMedia(media) {
+				continue
+			}
+			if candidate.selector.Matches(node) {
+				matched = append(matched, matchedSelector{sheet: i, origin: stylesheet.Origin, selector: candidate})
+			}
+		}
+	}
+	return matched
+}
+
+// styleEntries gathers the declarations of the matched selectors and of the
+// inline style, sorted in cascade order
+func styleEntries(node *dom.Node, matched []matchedSelector, inlineStyle string) []StyleEntry {
 	var entries []StyleEntry
 	order := 0
 
 	// From stylesheets
-	for _, stylesheet := range stylesheets {
-		for _, rule := range stylesheet.Rules {
-			for _, selector := range rule.Selectors {
-				if selector.Matches(node) {
-					for _, decl := range rule.Declarations {
-						entries = append(entries, StyleEntry{
-							Declarations: []Declaration{decl},
-							Specificity:  selector.CalculateSpecificity(),
-							Order:        order,
-							Important:    decl.Important,
-						})
-						order++
-					}
-				}
-			}
+	for _, m := range matched {
+		for _, decl := range m.selector.rule.Declarations {
+			entries = append(entries, StyleEntry{
+				Declarations: []Declaration{decl},
+				Origin:       m.origin,
+				Specificity:  m.selector.specificity,
+				Order:        order,
+				Important:    decl.Important,
+			})
+			order++
 		}
 	}
 
-	// From inline style attribute
-	inlineStyle := node.GetAttr("style")
+	// From inline style attribute (never applies to pseudo-elements)
 	if inlineStyle != "" {
 		declarations := ParseInlineStyle(inlineStyle)
+		// Inline url()s resolve against the page itself
+		if doc := node.OwnerDocument(); doc != nil {
+			for i := range declarations {
+				declarations[i].Value = ResolveCSSURLs(declarations[i].Value, doc.BaseURL)
+			}
+		}
 		for _, decl := range declarations {
 			entries = append(entries, StyleEntry{
 				Declarations: []Declaration{decl},
@@ -70,11 +247,10 @@ func ComputeStyles(node *dom.Node, stylesheets []*Stylesheet) *ComputedStyle {
 		}
 	}
 
-	// Sort by cascade order: important, specificity, source order
+	// Sort by cascade order: origin and importance, specificity, source order
 	sort.SliceStable(entries, func(i, j int) bool {
-		// Important declarations win
-		if entries[i].Important != entries[j].Important {
-			return !entries[i].Important // non-important comes first
+		if li, lj := entries[i].cascadeLevel(), entries[j].cascadeLevel(); li != lj {
+			return li < lj
 		}
 		// Then by specificity
 		cmp := entries[i].Specificity.Compare(entries[j].Specificity)
@@ -85,75 +261,121 @@ func ComputeStyles(node *dom.Node, stylesheets []*Stylesheet) *ComputedStyle {
 		return entries[i].Order < entries[j].Order
 	})
 
-	// Apply in order (later declarations override earlier)
-	for _, entry := range entries {
-		ApplyDeclarations(style, entry.Declarations)
-	}
-
-	return style
+	return entries
 }
 
-// ApplyStylesToTree applies computed styles to all nodes in a DOM tree
-func ApplyStylesToTree(root *dom.Node, stylesheets []*Stylesheet) {
-	applyStylesRecursive(root, stylesheets)
+// ApplyStylesToTree applies computed styles to all nodes in a DOM tree,
+// styling it for media
+func ApplyStylesToTree(root *dom.Node, stylesheets []*Stylesheet, media Media) {
+	// The whole tree is restyled, so earlier :has() answers can't be trusted
+	ResetHasCache()
+	applyStylesRecursive(root, stylesheets, media)
 }
 
-func applyStylesRecursive(node *dom.Node, stylesheets []*Stylesheet) {
+// applyStylesRecursive styles node and its descendants
+func applyStylesRecursive(node *dom.Node, stylesheets []*Stylesheet, media Media) {
 	if node == nil {
 		return
 	}
+	newStyler(node, stylesheets, media).apply(node)
+}
 
+func (st *styler) apply(node *dom.Node) {
+	stylesheets := st.stylesheets
 	if node.Type == dom.NodeElement {
-		node.ComputedStyle = ComputeStyles(node, stylesheets)
+		node.ComputedStyle = computeStyles(node, stylesheets, st.media, st)
 
-		// Inherit from parent if available
-		if node.Parent != nil && node.Parent.ComputedStyle != nil {
-			if parentStyle, ok := node.Parent.ComputedStyle.(*ComputedStyle); ok {
-				if childStyle, ok := node.ComputedStyle.(*ComputedStyle); ok {
-					InheritFromParent(childStyle, parentStyle)
-				}
+		applyPseudoElementStyles(node, stylesheets, st.media, st.ancestors)
+	}
+
+	st.ancestors.push(node)
+	for _, child := range node.Children {
+		st.apply(child)
+	}
+	st.ancestors.pop(node)
+}
+
+// applyPseudoElementStyles attaches ::selection/::placeholder/::marker styles to
+// the node's computed style. ::selection is inherited by descendants that
+// don't declare their own.
+func applyPseudoElementStyles(node *dom.Node, stylesheets []*Stylesheet, media Media, filter *ancestorFilter) {
+	style, ok := node.ComputedStyle.(*ComputedStyle)
+	if !ok {
+		return
+	}
+
+	for _, pseudo := range PseudoElements {
+		if ps := computePseudoElementStyle(node, stylesheets, media, pseudo, filter); ps != nil {
+			if style.PseudoElements == nil {
+				style.PseudoElements = make(map[string]*ComputedStyle)
 			}
+			style.PseudoElements[pseudo] = ps
 		}
 	}
 
-	for _, child := range node.Children {
-		applyStylesRecursive(child, stylesheets)
+	if style.PseudoStyle("selection") == nil && node.Parent != nil {
+		if parentStyle, ok := node.Parent.ComputedStyle.(*ComputedStyle); ok {
+			if sel := parentStyle.PseudoStyle("selection"); sel != nil {
+				if style.PseudoElements == nil {
+					style.PseudoElements = make(map[string]*ComputedStyle)
+				}
+				style.PseudoElements["selection"] = sel
+			}
+		}
 	}
 }
 
 // InheritableProperties lists CSS properties that inherit from parent
 var InheritableProperties = map[string]bool{
-	"color":       true,
-	"font-family": true,
-	"font-size":   true,
-	"font-weight": true,
-	"line-height": true,
-	"text-align":  true,
-	"visibility":  true,
+	"color":               true,
+	"font-family":         true,
+	"font-size":           true,
+	"font-weight":         true,
+	"line-height":         true,
+	"text-align":          true,
+	"visibility":          true,
+	"white-space":         true,
+	"cursor":              true,
+	"font-style":          true,
+	"text-transform":      true,
+	"letter-spacing":      true,
+	"word-spacing":        true,
+	"list-style-position": true,
+	"border-collapse":     true,
+	"border-spacing":      true,
 }
 
-// InheritFromParent applies inherited properties from parent style
-func InheritFromParent(child, parent *ComputedStyle) {
-	if parent == nil || child == nil {
-		return
+// inheritProperties gives style the parent's values of the inherited
+// properties
+func inheritProperties(style, parent *ComputedStyle) {
+	for property := range InheritableProperties {
+		propertyFields[property](style, parent)
 	}
+}
 
-	// Only inherit if child hasn't set its own value
-	// For now, inherit key properties
-	if child.FontSize == 16 && parent.FontSize != 16 {
-		child.FontSize = parent.FontSize
-	}
-	if child.FontWeight == 400 && parent.FontWeight != 400 {
-		child.FontWeight = parent.FontWeight
-	}
-	// Color inherits
-	child.Color = parent.Color
+// sameInherited reports whether two styles pass the same values down to
+// their children
+func sameInherited(a, b *ComputedStyle) bool {
+	return a.Color == b.Color && a.FontFamily == b.FontFamily && a.FontSize == b.FontSize &&
+		a.FontWeight == b.FontWeight && a.FontStyle == b.FontStyle && a.LineHeight == b.LineHeight &&
+		a.TextAlign == b.TextAlign && a.Visibility == b.Visibility && a.WhiteSpace == b.WhiteSpace &&
+		a.TextTransform == b.TextTransform && a.LetterSpacing == b.LetterSpacing &&
+		a.WordSpacing == b.WordSpacing && a.Cursor == b.Cursor && a.CursorImage == b.CursorImage &&
+		a.CursorHotspotX == b.CursorHotspotX && a.CursorHotspotY == b.CursorHotspotY &&
+		a.ListStylePosition == b.ListStylePosition && a.BorderCollapse == b.BorderCollapse &&
+		a.BorderSpacing == b.BorderSpacing
 }
 
-// ExtractStylesheets finds and parses all <style> blocks in a DOM tree
+// ExtractStylesheets finds and parses all <style> blocks in a DOM tree. Their
+// url() references resolve against the document's base URL.
 func ExtractStylesheets(root *dom.Node) []*Stylesheet {
 	var stylesheets []*Stylesheet
 	extractStylesRecursive(root, &stylesheets)
+	if doc := root.OwnerDocument(); doc != nil {
+		for _, sheet := range stylesheets {
+			sheet.ResolveURLs(doc.BaseURL)
+		}
+	}
 	return stylesheets
 }
 
@@ -171,7 +393,9 @@ func extractStylesRecursive(node *dom.Node, stylesheets *[]*Stylesheet) {
 			}
 		}
 		if cssText != "" {
-			*stylesheets = append(*stylesheets, ParseStylesheet(cssText))
+			sheet := ParseStylesheet(cssText)
+			sheet.Media = strings.TrimSpace(node.GetAttr("media"))
+			*stylesheets = append(*stylesheets, sheet)
 		}
 	}
 
@@ -184,13 +408,15 @@ func extractStylesRecursive(node *dom.Node, stylesheets *[]*Stylesheet) {
 // EXTERNAL CSS FETCHING
 // ======================================================================================
 
-// FetchExternalStylesheets finds <link rel="stylesheet"> tags and fetches CSS
-func FetchExternalStylesheets(root *dom.Node, baseURL string) []*Stylesheet {
+// FetchExternalStylesheets finds <link rel="stylesheet"> tags and fetches
+// CSS through client, net/http's default client when nil. Sheets for other
+// media are fetched too, to apply when the media changes.
+func FetchExternalStylesheets(root *dom.Node, baseURL string, client *http.Client) []*Stylesheet {
 	// Find all link tags with rel="stylesheet"
-	var cssURLs []string
-	findStylesheetLinks(root, &cssURLs)
+	var links []*dom.Node
+	findStylesheetLinks(root, &links)
 
-	if len(cssURLs) == 0 {
+	if len(links) == 0 {
 		return nil
 	}
 
@@ -199,39 +425,25 @@ func FetchExternalStylesheets(root *dom.Node, baseURL string) []*Stylesheet {
 	var mu sync.Mutex
 	var stylesheets []*Stylesheet
 
-	// Create HTTP client with timeout
-	client := &http.Client{Timeout: 10 * time.Second}
+	client = stylesheetClient(client)
 
-	for _, cssURL := range cssURLs {
+	for _, link := range links {
 		// Resolve relative URL
-		fullURL := resolveURL(cssURL, baseURL)
+		fullURL := resolveURL(link.GetAttr("href"), baseURL)
+		media := strings.TrimSpace(link.GetAttr("media"))
 		if fullURL == "" {
 			continue
 		}
+		// A secure page never loads stylesheets over plain http
+		if strings.HasPrefix(baseURL, "https://") && strings.HasPrefix(strings.ToLower(fullURL), "http://") {
+			continue
+		}
 
 		wg.Add(1)
 		go func(u string) {
 			defer wg.Done()
-
-			resp, err := client.Get(u)
-			if err != nil {
-				return
-			}
-			defer resp.Body.Close()
-
-			if resp.StatusCode != 200 {
-				return
-			}
-
-			// Read CSS content
-			body, err := io.ReadAll(resp.Body)
-			if err != nil {
-				return
-			}
-
-			// Parse stylesheet
-			stylesheet := ParseStylesheet(string(body))
-			if stylesheet != nil && len(stylesheet.Rules) > 0 {
+			if stylesheet := fetchStylesheet(client, u); stylesheet != nil {
+				stylesheet.Media = media
 				mu.Lock()
 				stylesheets = append(stylesheets, stylesheet)
 				mu.Unlock()
@@ -243,22 +455,62 @@ func FetchExternalStylesheets(root *dom.Node, baseURL string) []*Stylesheet {
 	return stylesheets
 }
 
+// stylesheetClient returns client, or net/http's default client when nil,
+// giving up on a stylesheet after 10 seconds
+func stylesheetClient(client *http.Client) *http.Client {
+	timed := http.Client{}
+	if client != nil {
+		timed = *client
+	}
+	timed.Timeout = 10 * time.Second
+	return &timed
+}
+
+// fetchStylesheet downloads and parses the stylesheet at u, or returns nil
+// when it can't be fetched or holds nothing
+func fetchStylesheet(client *http.Client, u string) *Stylesheet {
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil
+	}
+
+	// Read CSS content
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil
+	}
+
+	// Parse stylesheet
+	stylesheet := ParseStylesheet(string(body))
+	if len(stylesheet.Rules) == 0 && len(stylesheet.Imports) == 0 {
+		return nil
+	}
+	// Relative url()s point next to the sheet, not the page
+	stylesheet.URL = u
+	stylesheet.ResolveURLs(u)
+	return stylesheet
+}
+
 // findStylesheetLinks recursively finds all <link rel="stylesheet" href="...">
-func findStylesheetLinks(node *dom.Node, urls *[]string) {
+func findStylesheetLinks(node *dom.Node, links *[]*dom.Node) {
 	if node == nil {
 		return
 	}
 
 	if node.Tag == "link" {
 		rel := strings.ToLower(node.GetAttr("rel"))
-		href := node.GetAttr("href")
-		if rel == "stylesheet" && href != "" {
-			*urls = append(*urls, href)
+		if rel == "stylesheet" && node.GetAttr("href") != "" {
+			*links = append(*links, node)
 		}
 	}
 
 	for _, child := range node.Children {
-		findStylesheetLinks(child, urls)
+		findStylesheetLinks(child, links)
 	}
 }
 
@@ -0,0 +1,307 @@
+package css
+
+import (
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// ======================================================================================
+// COLOR PARSING
+// Named colors, #hex with 3, 4, 6 or 8 digits, and rgb()/rgba()/hsl()/hsla() in
+// both the comma and the space-and-slash syntax (CSS Color Level 4)
+// ======================================================================================
+
+// CSS named colors
+var namedColors = map[string]color.RGBA{
+	// Basic colors
+	"transparent": {0, 0, 0, 0},
+	"black":       {0, 0, 0, 255},
+	"white":       {255, 255, 255, 255},
+	"red":         {255, 0, 0, 255},
+	"green":       {0, 128, 0, 255},
+	"blue":        {0, 0, 255, 255},
+	"yellow":      {255, 255, 0, 255},
+	"cyan":        {0, 255, 255, 255},
+	"magenta":     {255, 0, 255, 255},
+
+	// Extended colors
+	"gray":          {128, 128, 128, 255},
+	"grey":          {128, 128, 128, 255},
+	"silver":        {192, 192, 192, 255},
+	"maroon":        {128, 0, 0, 255},
+	"olive":         {128, 128, 0, 255},
+	"lime":          {0, 255, 0, 255},
+	"aqua":          {0, 255, 255, 255},
+	"teal":          {0, 128, 128, 255},
+	"navy":          {0, 0, 128, 255},
+	"fuchsia":       {255, 0, 255, 255},
+	"purple":        {128, 0, 128, 255},
+	"rebeccapurple": {102, 51, 153, 255},
+	"orange":        {255, 165, 0, 255},
+	"pink":          {255, 192, 203, 255},
+	"brown":         {165, 42, 42, 255},
+	"coral":         {255, 127, 80, 255},
+	"crimson":       {220, 20, 60, 255},
+	"gold":          {255, 215, 0, 255},
+	"indigo":        {75, 0, 130, 255},
+	"khaki":         {240, 230, 140, 255},
+	"lavender":      {230, 230, 250, 255},
+	"salmon":        {250, 128, 114, 255},
+	"skyblue":       {135, 206, 235, 255},
+	"slategray":     {112, 128, 144, 255},
+	"steelblue":     {70, 130, 180, 255},
+	"tomato":        {255, 99, 71, 255},
+	"turquoise":     {64, 224, 208, 255},
+	"violet":        {238, 130, 238, 255},
+	"wheat":         {245, 222, 179, 255},
+
+	// Gray scale
+	"dimgray":    {105, 105, 105, 255},
+	"darkgray":   {169, 169, 169, 255},
+	"lightgray":  {211, 211, 211, 255},
+	"gainsboro":  {220, 220, 220, 255},
+	"whitesmoke": {245, 245, 245, 255},
+
+	// Additional modern colors
+	"aliceblue":         {240, 248, 255, 255},
+	"antiquewhite":      {250, 235, 215, 255},
+	"azure":             {240, 255, 255, 255},
+	"beige":             {245, 245, 220, 255},
+	"bisque":            {255, 228, 196, 255},
+	"blanchedalmond":    {255, 235, 205, 255},
+	"blueviolet":        {138, 43, 226, 255},
+	"burlywood":         {222, 184, 135, 255},
+	"cadetblue":         {95, 158, 160, 255},
+	"chartreuse":        {127, 255, 0, 255},
+	"chocolate":         {210, 105, 30, 255},
+	"cornflowerblue":    {100, 149, 237, 255},
+	"cornsilk":          {255, 248, 220, 255},
+	"darkblue":          {0, 0, 139, 255},
+	"darkcyan":          {0, 139, 139, 255},
+	"darkgoldenrod":     {184, 134, 11, 255},
+	"darkgreen":         {0, 100, 0, 255},
+	"darkkhaki":         {189, 183, 107, 255},
+	"darkmagenta":       {139, 0, 139, 255},
+	"darkolivegreen":    {85, 107, 47, 255},
+	"darkorange":        {255, 140, 0, 255},
+	"darkorchid":        {153, 50, 204, 255},
+	"darkred":           {139, 0, 0, 255},
+	"darksalmon":        {233, 150, 122, 255},
+	"darkseagreen":      {143, 188, 143, 255},
+	"darkslateblue":     {72, 61, 139, 255},
+	"darkslategray":     {47, 79, 79, 255},
+	"darkturquoise":     {0, 206, 209, 255},
+	"darkviolet":        {148, 0, 211, 255},
+	"deeppink":          {255, 20, 147, 255},
+	"deepskyblue":       {0, 191, 255, 255},
+	"dodgerblue":        {30, 144, 255, 255},
+	"firebrick":         {178, 34, 34, 255},
+	"floralwhite":       {255, 250, 240, 255},
+	"forestgreen":       {34, 139, 34, 255},
+	"ghostwhite":        {248, 248, 255, 255},
+	"goldenrod":         {218, 165, 32, 255},
+	"greenyellow":       {173, 255, 47, 255},
+	"honeydew":          {240, 255, 240, 255},
+	"hotpink":           {255, 105, 180, 255},
+	"indianred":         {205, 92, 92, 255},
+	"ivory":             {255, 255, 240, 255},
+	"lawngreen":         {124, 252, 0, 255},
+	"lemonchiffon":      {255, 250, 205, 255},
+	"lightblue":         {173, 216, 230, 255},
+	"lightcoral":        {240, 128, 128, 255},
+	"lightcyan":         {224, 255, 255, 255},
+	"lightgreen":        {144, 238, 144, 255},
+	"lightpink":         {255, 182, 193, 255},
+	"lightsalmon":       {255, 160, 122, 255},
+	"lightseagreen":     {32, 178, 170, 255},
+	"lightskyblue":      {135, 206, 250, 255},
+	"lightslategray":    {119, 136, 153, 255},
+	"lightsteelblue":    {176, 196, 222, 255},
+	"lightyellow":       {255, 255, 224, 255},
+	"limegreen":         {50, 205, 50, 255},
+	"linen":             {250, 240, 230, 255},
+	"mediumaquamarine":  {102, 205, 170, 255},
+	"mediumblue":        {0, 0, 205, 255},
+	"mediumorchid":      {186, 85, 211, 255},
+	"mediumpurple":      {147, 112, 219, 255},
+	"mediumseagreen":    {60, 179, 113, 255},
+	"mediumslateblue":   {123, 104, 238, 255},
+	"mediumspringgreen": {0, 250, 154, 255},
+	"mediumturquoise":   {72, 209, 204, 255},
+	"mediumvioletred":   {199, 21, 133, 255},
+	"midnightblue":      {25, 25, 112, 255},
+	"mintcream":         {245, 255, 250, 255},
+	"mistyrose":         {255, 228, 225, 255},
+	"moccasin":          {255, 228, 181, 255},
+	"navajowhite":       {255, 222, 173, 255},
+	"oldlace":           {253, 245, 230, 255},
+	"olivedrab":         {107, 142, 35, 255},
+	"orangered":         {255, 69, 0, 255},
+	"orchid":            {218, 112, 214, 255},
+	"palegoldenrod":     {238, 232, 170, 255},
+	"palegreen":         {152, 251, 152, 255},
+	"paleturquoise":     {175, 238, 238, 255},
+	"palevioletred":     {219, 112, 147, 255},
+	"papayawhip":        {255, 239, 213, 255},
+	"peachpuff":         {255, 218, 185, 255},
+	"peru":              {205, 133, 63, 255},
+	"plum":              {221, 160, 221, 255},
+	"powderblue":        {176, 224, 230, 255},
+	"rosybrown":         {188, 143, 143, 255},
+	"royalblue":         {65, 105, 225, 255},
+	"saddlebrown":       {139, 69, 19, 255},
+	"sandybrown":        {244, 164, 96, 255},
+	"seagreen":          {46, 139, 87, 255},
+	"seashell":          {255, 245, 238, 255},
+	"sienna":            {160, 82, 45, 255},
+	"slateblue":         {106, 90, 205, 255},
+	"snow":              {255, 250, 250, 255},
+	"springgreen":       {0, 255, 127, 255},
+	"tan":               {210, 180, 140, 255},
+	"thistle":           {216, 191, 216, 255},
+	"yellowgreen":       {154, 205, 50, 255},
+
+	// Spellings the old table accepted, and inherit, which resolves to
+	// transparent until the cascade replaces it
+	"lightgrey": {211, 211, 211, 255},
+	"darkgrey":  {169, 169, 169, 255},
+	"inherit":   {0, 0, 0, 0},
+}
+
+// ParseColor parses a CSS color value
+func ParseColor(value string) (color.RGBA, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	if c, ok := namedColors[value]; ok {
+		return c, true
+	}
+	switch {
+	case strings.HasPrefix(value, "#"):
+		return parseHexColor(value[1:])
+	case strings.HasPrefix(value, "rgb(") || strings.HasPrefix(value, "rgba("):
+		return parseColorFunction(value, false)
+	case strings.HasPrefix(value, "hsl(") || strings.HasPrefix(value, "hsla("):
+		return parseColorFunction(value, true)
+	}
+	return color.RGBA{}, false
+}
+
+// parseHexColor parses the digits of #RGB, #RGBA, #RRGGBB or #RRGGBBAA
+func parseHexColor(hex string) (color.RGBA, bool) {
+	if _, err := strconv.ParseUint(hex, 16, 32); err != nil {
+		return color.RGBA{}, false
+	}
+	if len(hex) == 3 || len(hex) == 4 {
+		// Each digit stands for a doubled pair
+		long := make([]byte, 0, 8)
+		for i := range len(hex) {
+			long = append(long, hex[i], hex[i])
+		}
+		hex = string(long)
+	}
+	if len(hex) != 6 && len(hex) != 8 {
+		return color.RGBA{}, false
+	}
+	channel := func(i int) uint8 {
+		v, _ := strconv.ParseUint(hex[i:i+2], 16, 8)
+		return uint8(v)
+	}
+	c := color.RGBA{channel(0), channel(2), channel(4), 255}
+	if len(hex) == 8 {
+		c.A = channel(6)
+	}
+	return c, true
+}
+
+// parseColorFunction parses rgb()/rgba(), or hsl()/hsla() when hsl is set:
+// "rgb(255, 0, 0, 0.5)" as well as "rgb(255 0 0 / 50%)"
+func parseColorFunction(value string, hsl bool) (color.RGBA, bool) {
+	open := strings.Index(value, "(")
+	if !strings.HasSuffix(value, ")") {
+		return color.RGBA{}, false
+	}
+	args := value[open+1 : len(value)-1]
+
+	alpha := 1.0
+	if before, after, ok := strings.Cut(args, "/"); ok {
+		args = before
+		alpha = parseAlpha(after)
+	}
+	parts := strings.Fields(strings.ReplaceAll(args, ",", " "))
+	if len(parts) < 3 {
+		return color.RGBA{}, false
+	}
+	if len(parts) >= 4 {
+		alpha = parseAlpha(parts[3])
+	}
+	a := uint8(max(0, min(alpha, 1)) * 255)
+
+	if hsl {
+		h, _ := strconv.ParseFloat(strings.TrimSuffix(parts[0], "deg"), 64)
+		s, _ := strconv.ParseFloat(strings.TrimSuffix(parts[1], "%"), 64)
+		l, _ := strconv.ParseFloat(strings.TrimSuffix(parts[2], "%"), 64)
+		r, g, b := hslToRGB(h/360, s/100, l/100)
+		return color.RGBA{r, g, b, a}, true
+	}
+	return color.RGBA{colorChannel(parts[0]), colorChannel(parts[1]), colorChannel(parts[2]), a}, true
+}
+
+// parseAlpha reads an alpha value: a number from 0 to 1 or a percentage
+func parseAlpha(s string) float64 {
+	s = strings.TrimSpace(s)
+	if pct, ok := strings.CutSuffix(s, "%"); ok {
+		v, _ := strconv.ParseFloat(pct, 64)
+		return v / 100
+	}
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
+// colorChannel reads an rgb() channel: 0-255 or a percentage
+func colorChannel(s string) uint8 {
+	if pct, ok := strings.CutSuffix(s, "%"); ok {
+		v, _ := strconv.ParseFloat(pct, 64)
+		return uint8(max(0, min(v, 100)) * 255 / 100)
+	}
+	v, _ := strconv.ParseFloat(s, 64)
+	return uint8(max(0, min(v, 255)))
+}
+
+// hslToRGB converts a hue, saturation and lightness, each from 0 to 1
+func hslToRGB(h, s, l float64) (uint8, uint8, uint8) {
+	if s == 0 {
+		v := uint8(l * 255)
+		return v, v, v
+	}
+	var q float64
+	if l < 0.5 {
+		q = l * (1 + s)
+	} else {
+		q = l + s - l*s
+	}
+	p := 2*l - q
+	r := hueToRGB(p, q, h+1.0/3.0)
+	g := hueToRGB(p, q, h)
+	b := hueToRGB(p, q, h-1.0/3.0)
+	return uint8(r * 255), uint8(g * 255), uint8(b * 255)
+}
+
+func hueToRGB(p, q, t float64) float64 {
+	if t < 0 {
+		t += 1
+	}
+	if t > 1 {
+		t -= 1
+	}
+	if t < 1.0/6.0 {
+		return p + (q-p)*6*t
+	}
+	if t < 0.5 {
+		return q
+	}
+	if t < 2.0/3.0 {
+		return p + (q-p)*(2.0/3.0-t)*6
+	}
+	return p
+}
@@ -0,0 +1,185 @@
+package css
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ======================================================================================
+// @IMPORT
+// A stylesheet may start with @import rules naming other stylesheets, with
+// media queries they apply under. LoadImports fetches them, and the sheets
+// they import in turn, and splices their rules in ahead of the importing
+// sheet's own, in the order of the @imports. Import chains stop at
+// maxImportDepth sheets deep and at a sheet importing itself. layer() and
+// supports() conditions are read past and not applied.
+// ======================================================================================
+
+// Import is an @import rule: the stylesheet at URL, under the media query
+// list Media ("" for all media)
+type Import struct {
+	URL   string
+	Media string
+}
+
+// maxImportDepth is how many sheets deep imports are followed
+const maxImportDepth = 5
+
+// statementAtRuleEnd returns the index of the semicolon ending the at-rule
+// at pos, or -1 when the at-rule has a block
+func statementAtRuleEnd(css string, pos int) int {
+	depth := 0
+	var quote byte
+	for i := pos; i < len(css); i++ {
+		c := css[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '"', '\'':
+			quote = c
+		case '(':
+			depth++
+		case ')':
+			depth = max(depth-1, 0)
+		case '{':
+			return -1
+		case ';':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parseImport reads an @import rule, without its semicolon:
+// @import url("a.css") screen, or @import "a.css"
+func parseImport(rule string) (Import, bool) {
+	rest, ok := strings.CutPrefix(rule, "@import")
+	if !ok || rest == "" || !(isWhitespace(rest[0]) || rest[0] == '"' || rest[0] == '\'') {
+		return Import{}, false
+	}
+	rest = strings.TrimSpace(rest)
+
+	var imp Import
+	switch {
+	case rest == "":
+		return Import{}, false
+	case rest[0] == '"' || rest[0] == '\'':
+		end := strings.IndexByte(rest[1:], rest[0])
+		if end == -1 {
+			return Import{}, false
+		}
+		imp.URL, rest = rest[1:end+1], rest[end+2:]
+	case strings.HasPrefix(strings.ToLower(rest), "url("):
+		end := strings.IndexByte(rest, ')')
+		if end == -1 {
+			return Import{}, false
+		}
+		imp.URL, rest = ExtractURL(rest[:end+1]), rest[end+1:]
+	default:
+		return Import{}, false
+	}
+	if imp.URL == "" {
+		return Import{}, false
+	}
+
+	var media []string
+	for _, part := range splitSelectorFields(strings.TrimSpace(rest)) {
+		lower := strings.ToLower(part)
+		if lower == "layer" || strings.HasPrefix(lower, "layer(") || strings.HasPrefix(lower, "supports(") {
+			continue
+		}
+		media = append(media, part)
+	}
+	imp.Media = strings.Join(media, " ")
+	return imp, true
+}
+
+// LoadImports fetches the stylesheets the sheets @import through client,
+// net/http's default client when nil, and splices their rules in. Sheets
+// from <style> blocks import relative to baseURL, fetched sheets relative to
+// themselves.
+func LoadImports(stylesheets []*Stylesheet, baseURL string, client *http.Client) {
+	client = stylesheetClient(client)
+	var wg sync.WaitGroup
+	for _, sheet := range stylesheets {
+		if len(sheet.Imports) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			base := sheet.URL
+			if base == "" {
+				base = baseURL
+			}
+			spliceImports(client, sheet, base, []string{sheet.URL})
+		}()
+	}
+	wg.Wait()
+}
+
+// spliceImports fetches sheet's imports, with theirs in turn, and puts their
+// rules ahead of sheet's own. chain holds the URLs of the sheets importing
+// sheet, itself included.
+func spliceImports(client *http.Client, sheet *Stylesheet, base string, chain []string) {
+	imported := make([]*Stylesheet, len(sheet.Imports))
+	var wg sync.WaitGroup
+	for i, imp := range sheet.Imports {
+		u := resolveURL(imp.URL, base)
+		if u == "" || len(chain) >= maxImportDepth || slices.Contains(chain, u) {
+			continue
+		}
+		// A secure page never loads stylesheets over plain http
+		if strings.HasPrefix(base, "https://") && strings.HasPrefix(strings.ToLower(u), "http://") {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			child := fetchStylesheet(client, u)
+			if child == nil {
+				return
+			}
+			spliceImports(client, child, u, append(chain[:len(chain):len(chain)], u))
+			imported[i] = child
+		}()
+	}
+	wg.Wait()
+
+	var rules []Rule
+	for i, child := range imported {
+		if child == nil {
+			continue
+		}
+		media := strings.TrimSpace(sheet.Imports[i].Media)
+		for _, rule := range child.Rules {
+			if media != "" {
+				rule.Media = append([]string{media}, rule.Media...)
+			}
+			rules = append(rules, rule)
+		}
+	}
+	sheet.Rules = append(rules, sheet.Rules...)
+	sheet.Imports = nil
+	sheet.index = nil
+}
+
+// matchesMedia reports whether every media query list of a rule matches on m
+func (r *Rule) matchesMedia(m Media) bool {
+	for _, query := range r.Media {
+		if !m.Match(query) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,214 @@
+package css
+
+import (
+	"strings"
+
+	"go-browser/dom"
+)
+
+// ======================================================================================
+// STYLE INVALIDATION
+// Maps the classes, ids and attributes selectors depend on to the part of the
+// tree a change to them can restyle, so a mutation doesn't restyle the document
+// ======================================================================================
+
+// invalidationScope says which elements around a mutated node may match differently
+type invalidationScope struct {
+	Self        bool // the feature is on a selector's subject
+	Descendants bool // the feature is on an ancestor compound (div.open p)
+	Ancestors   bool // the feature appears inside :has()
+}
+
+func (s *invalidationScope) merge(other invalidationScope) {
+	s.Self = s.Self || other.Self
+	s.Descendants = s.Descendants || other.Descendants
+	s.Ancestors = s.Ancestors || other.Ancestors
+}
+
+// RuleDependencies is the rule-dependency map of a set of stylesheets:
+// "class:name", "id:name" and "attr:name" keys to the scope they invalidate
+type RuleDependencies struct {
+	features map[string]invalidationScope
+}
+
+// Invalidation lists what needs restyling after a mutation. Subtrees are
+// restyled with their descendants (inherited values flow down); Elements
+// recompute their own style, and their descendants' only when the values
+// they pass down changed.
+type Invalidation struct {
+	Subtrees []*dom.Node
+	Elements []*dom.Node
+}
+
+// Empty reports whether nothing needs restyling
+func (inv Invalidation) Empty() bool {
+	return len(inv.Subtrees) == 0 && len(inv.Elements) == 0
+}
+
+// uaAttributes are the attributes the UA rules of ComputeStyles depend on
+var uaAttributes = []string{"href", "title", "align", "hidden", "open"}
+
+// BuildRuleDependencies indexes every selector of the stylesheets by the
+// features it depends on
+func BuildRuleDependencies(stylesheets []*Stylesheet) *RuleDependencies {
+	deps := &RuleDependencies{features: make(map[string]invalidationScope)}
+	for _, name := range uaAttributes {
+		deps.add("attr:"+name, invalidationScope{Self: true})
+	}
+	for _, sheet := range stylesheets {
+		for _, rule := range sheet.Rules {
+			for _, sel := range rule.Selectors {
+				deps.addSelector(sel, invalidationScope{Self: true})
+			}
+		}
+	}
+	return deps
+}
+
+// addSelector records the features of sel. scope is what a feature on the
+// selector's subject invalidates.
+func (d *RuleDependencies) addSelector(sel Selector, scope invalidationScope) {
+	if sel.Type != SelectorDescendant && sel.Type != SelectorChild {
+		d.addCompound(sel, scope)
+		return
+	}
+	for i, part := range sel.Parts {
+		if i == len(sel.Parts)-1 {
+			d.addCompound(part, scope)
+		} else {
+			// Changing an ancestor compound can only change its descendants
+			ancestor := scope
+			if ancestor.Self {
+				ancestor = invalidationScope{Descendants: true, Ancestors: scope.Ancestors}
+			}
+			d.addCompound(part, ancestor)
+		}
+	}
+}
+
+func (d *RuleDependencies) addCompound(sel Selector, scope invalidationScope) {
+	if sel.ID != "" {
+		d.add("id:"+sel.ID, scope)
+	}
+	if sel.Class != "" {
+		for _, class := range strings.Split(sel.Class, ".") {
+			if class != "" {
+				d.add("class:"+class, scope)
+			}
+		}
+	}
+	for _, attr := range sel.Attrs {
+		d.add("attr:"+attr.Name, scope)
+	}
+
+	// Features inside :has() change the match of the subject's ancestors
+	if strings.HasPrefix(sel.PseudoClass, "has(") && strings.HasSuffix(sel.PseudoClass, ")") {
+		for _, rel := range parseRelativeSelectors(sel.PseudoClass[4 : len(sel.PseudoClass)-1]) {
+			d.addSelector(rel.Selector, invalidationScope{Ancestors: true})
+		}
+	}
+}
+
+func (d *RuleDependencies) add(key string, scope invalidationScope) {
+	existing := d.features[key]
+	existing.merge(scope)
+	d.features[key] = existing
+}
+
+// AttributeChanged returns what must be restyled after node's attribute name
+// changed from oldValue to newValue. Changes no selector depends on return an
+// empty Invalidation, except the style attribute which always restyles node.
+func (d *RuleDependencies) AttributeChanged(node *dom.Node, name, oldValue, newValue string) Invalidation {
+	name = strings.ToLower(name)
+	// Empty values on both sides may be a boolean attribute such as open or
+	// hidden appearing or going away
+	if node == nil || node.Type != dom.NodeElement || (oldValue == newValue && newValue != "") {
+		return Invalidation{}
+	}
+
+	var scope invalidationScope
+	if name == "style" {
+		scope.Self = true
+	}
+	lookup := func(key string) {
+		if s, ok := d.features[key]; ok {
+			scope.merge(s)
+		}
+	}
+
+	lookup("attr:" + name)
+	switch name {
+	case "class":
+		for _, class := range changedTokens(oldValue, newValue) {
+			lookup("class:" + class)
+		}
+	case "id":
+		lookup("id:" + oldValue)
+		lookup("id:" + newValue)
+	}
+
+	// :has() answers cached for the ancestors may now be stale
+	if scope.Ancestors {
+		InvalidateHasCache(node)
+	}
+
+	var inv Invalidation
+	if scope.Self || scope.Descendants {
+		inv.Subtrees = append(inv.Subtrees, node)
+	}
+	if scope.Ancestors {
+		for p := node.Parent; p != nil; p = p.Parent {
+			if p.Type == dom.NodeElement {
+				inv.Elements = append(inv.Elements, p)
+			}
+		}
+	}
+	return inv
+}
+
+// changedTokens returns the class names present in only one of the two lists
+func changedTokens(oldValue, newValue string) []string {
+	before := make(map[string]bool)
+	for _, c := range strings.Fields(oldValue) {
+		before[c] = true
+	}
+	var changed []string
+	after := make(map[string]bool)
+	for _, c := range strings.Fields(newValue) {
+		after[c] = true
+		if !before[c] {
+			changed = append(changed, c)
+		}
+	}
+	for c := range before {
+		if !after[c] {
+			changed = append(changed, c)
+		}
+	}
+	return changed
+}
+
+// ApplyInvalidation restyles the parts of the tree listed in inv for media
+func ApplyInvalidation(inv Invalidation, stylesheets []*Stylesheet, media Media) {
+	for _, node := range inv.Elements {
+		restyleElement(node, stylesheets, media)
+	}
+	for _, node := range inv.Subtrees {
+		applyStylesRecursive(node, stylesheets, media)
+	}
+}
+
+// restyleElement recomputes a single element's style, and its children's
+// only when the values they inherit changed
+func restyleElement(node *dom.Node, stylesheets []*Stylesheet, media Media) {
+	old, _ := node.ComputedStyle.(*ComputedStyle)
+	style := ComputeStyles(node, stylesheets, media)
+	node.ComputedStyle = style
+	applyPseudoElementStyles(node, stylesheets, media, nil)
+	if old != nil && sameInherited(old, style) {
+		return
+	}
+	for _, child := range node.Children {
+		applyStylesRecursive(child, stylesheets, media)
+	}
+}
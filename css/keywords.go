@@ -0,0 +1,250 @@
+package css
+
+import (
+	"image/color"
+	"strings"
+)
+
+// ======================================================================================
+// CSS-WIDE KEYWORDS & CURRENTCOLOR
+// Any property may be set to inherit, initial or unset. inherit takes the
+// parent element's value, initial the property's initial value, and unset
+// acts as inherit for inherited properties and initial for the others. A
+// shorthand sets each of its longhands to the keyword. currentColor is the
+// element's color once the cascade is done, so color: red written after
+// border-color: currentColor still colors the border red.
+// ======================================================================================
+
+// cssWideKeywords are the values every property accepts
+var cssWideKeywords = map[string]bool{"inherit": true, "initial": true, "unset": true}
+
+// initialStyle holds the initial value of every property
+var initialStyle = NewComputedStyle()
+
+// propertyFields copies the fields a property sets from one style to
+// another, to take a value from the parent or the initial style
+var propertyFields = map[string]func(dst, src *ComputedStyle){
+	"display":    func(dst, src *ComputedStyle) { dst.Display = src.Display },
+	"visibility": func(dst, src *ComputedStyle) { dst.Visibility = src.Visibility },
+	"opacity":    func(dst, src *ComputedStyle) { dst.Opacity = src.Opacity },
+	"overflow": func(dst, src *ComputedStyle) {
+		dst.OverflowX, dst.OverflowY = src.OverflowX, src.OverflowY
+	},
+	"overflow-x": func(dst, src *ComputedStyle) { dst.OverflowX = src.OverflowX },
+	"overflow-y": func(dst, src *ComputedStyle) { dst.OverflowY = src.OverflowY },
+	"box-shadow": func(dst, src *ComputedStyle) { dst.BoxShadow = src.BoxShadow },
+	"transform": func(dst, src *ComputedStyle) {
+		dst.Transform, dst.TransformFunctions = src.Transform, src.TransformFunctions
+	},
+	"transform-origin": func(dst, src *ComputedStyle) {
+		dst.TransformOriginX, dst.TransformOriginY = src.TransformOriginX, src.TransformOriginY
+	},
+
+	"color": func(dst, src *ComputedStyle) { dst.Color = src.Color },
+	"background-color": func(dst, src *ComputedStyle) {
+		dst.BackgroundColor = src.BackgroundColor
+		dst.currentColor = dst.currentColor&^currentBackground | src.currentColor&currentBackground
+	},
+	"background-image": func(dst, src *ComputedStyle) {
+		dst.BackgroundImage, dst.BackgroundGradient = src.BackgroundImage, src.BackgroundGradient
+	},
+	"background-repeat":   func(dst, src *ComputedStyle) { dst.BackgroundRepeat = src.BackgroundRepeat },
+	"background-size":     func(dst, src *ComputedStyle) { dst.BackgroundSize = src.BackgroundSize },
+	"background-position": func(dst, src *ComputedStyle) { dst.BackgroundPosition = src.BackgroundPosition },
+	"cursor": func(dst, src *ComputedStyle) {
+		dst.Cursor, dst.CursorImage = src.Cursor, src.CursorImage
+		dst.CursorHotspotX, dst.CursorHotspotY = src.CursorHotspotX, src.CursorHotspotY
+	},
+
+	// An inherited font size is the parent's size, not its scale again
+	"font-size":      func(dst, src *ComputedStyle) { dst.FontSize, dst.FontSizeScale = src.FontSize, 0 },
+	"font-weight":    func(dst, src *ComputedStyle) { dst.FontWeight = src.FontWeight },
+	"font-family":    func(dst, src *ComputedStyle) { dst.FontFamily = src.FontFamily },
+	"font-style":     func(dst, src *ComputedStyle) { dst.FontStyle = src.FontStyle },
+	"vertical-align": func(dst, src *ComputedStyle) { dst.VerticalAlign = src.VerticalAlign },
+	"text-transform": func(dst, src *ComputedStyle) { dst.TextTransform = src.TextTransform },
+	"text-decoration": func(dst, src *ComputedStyle) {
+		dst.TextDecoration, dst.TextDecorationStyle = src.TextDecoration, src.TextDecorationStyle
+		dst.TextDecorationColor, dst.TextDecorationThickness = src.TextDecorationColor, src.TextDecorationThickness
+	},
+	"text-decoration-line":      func(dst, src *ComputedStyle) { dst.TextDecoration = src.TextDecoration },
+	"text-decoration-style":     func(dst, src *ComputedStyle) { dst.TextDecorationStyle = src.TextDecorationStyle },
+	"text-decoration-color":     func(dst, src *ComputedStyle) { dst.TextDecorationColor = src.TextDecorationColor },
+	"text-decoration-thickness": func(dst, src *ComputedStyle) { dst.TextDecorationThickness = src.TextDecorationThickness },
+	"letter-spacing":            func(dst, src *ComputedStyle) { dst.LetterSpacing = src.LetterSpacing },
+	"word-spacing":              func(dst, src *ComputedStyle) { dst.WordSpacing = src.WordSpacing },
+	"text-align":                func(dst, src *ComputedStyle) { dst.TextAlign = src.TextAlign },
+	"white-space":               func(dst, src *ComputedStyle) { dst.WhiteSpace = src.WhiteSpace },
+	"line-height":               func(dst, src *ComputedStyle) { dst.LineHeight = src.LineHeight },
+
+	"width":      func(dst, src *ComputedStyle) { dst.Width = src.Width },
+	"min-width":  func(dst, src *ComputedStyle) { dst.MinWidth = src.MinWidth },
+	"max-width":  func(dst, src *ComputedStyle) { dst.MaxWidth = src.MaxWidth },
+	"height":     func(dst, src *ComputedStyle) { dst.Height = src.Height },
+	"min-height": func(dst, src *ComputedStyle) { dst.MinHeight = src.MinHeight },
+	"max-height": func(dst, src *ComputedStyle) { dst.MaxHeight = src.MaxHeight },
+	"box-sizing": func(dst, src *ComputedStyle) { dst.BoxSizing = src.BoxSizing },
+
+	"margin-top": func(dst, src *ComputedStyle) {
+		dst.MarginTop, dst.MarginPercent.Top, dst.MarginMath.Top = src.MarginTop, src.MarginPercent.Top, src.MarginMath.Top
+	},
+	"margin-right": func(dst, src *ComputedStyle) {
+		dst.MarginRight, dst.MarginPercent.Right, dst.MarginMath.Right, dst.MarginRightAuto = src.MarginRight, src.MarginPercent.Right, src.MarginMath.Right, src.MarginRightAuto
+	},
+	"margin-bottom": func(dst, src *ComputedStyle) {
+		dst.MarginBottom, dst.MarginPercent.Bottom, dst.MarginMath.Bottom = src.MarginBottom, src.MarginPercent.Bottom, src.MarginMath.Bottom
+	},
+	"margin-left": func(dst, src *ComputedStyle) {
+		dst.MarginLeft, dst.MarginPercent.Left, dst.MarginMath.Left, dst.MarginLeftAuto = src.MarginLeft, src.MarginPercent.Left, src.MarginMath.Left, src.MarginLeftAuto
+	},
+	"padding-top": func(dst, src *ComputedStyle) {
+		dst.PaddingTop, dst.PaddingPercent.Top, dst.PaddingMath.Top = src.PaddingTop, src.PaddingPercent.Top, src.PaddingMath.Top
+	},
+	"padding-right": func(dst, src *ComputedStyle) {
+		dst.PaddingRight, dst.PaddingPercent.Right, dst.PaddingMath.Right = src.PaddingRight, src.PaddingPercent.Right, src.PaddingMath.Right
+	},
+	"padding-bottom": func(dst, src *ComputedStyle) {
+		dst.PaddingBottom, dst.PaddingPercent.Bottom, dst.PaddingMath.Bottom = src.PaddingBottom, src.PaddingPercent.Bottom, src.PaddingMath.Bottom
+	},
+	"padding-left": func(dst, src *ComputedStyle) {
+		dst.PaddingLeft, dst.PaddingPercent.Left, dst.PaddingMath.Left = src.PaddingLeft, src.PaddingPercent.Left, src.PaddingMath.Left
+	},
+
+	"border-radius":       func(dst, src *ComputedStyle) { dst.BorderRadius = src.BorderRadius },
+	"border-top-width":    func(dst, src *ComputedStyle) { dst.BorderTopWidth = src.BorderTopWidth },
+	"border-right-width":  func(dst, src *ComputedStyle) { dst.BorderRightWidth = src.BorderRightWidth },
+	"border-bottom-width": func(dst, src *ComputedStyle) { dst.BorderBottomWidth = src.BorderBottomWidth },
+	"border-left-width":   func(dst, src *ComputedStyle) { dst.BorderLeftWidth = src.BorderLeftWidth },
+	"border-top-style":    copyBorderStyle,
+	"border-right-style":  copyBorderStyle,
+	"border-bottom-style": copyBorderStyle,
+	"border-left-style":   copyBorderStyle,
+	"border-top-color":    copyBorderColor,
+	"border-right-color":  copyBorderColor,
+	"border-bottom-color": copyBorderColor,
+	"border-left-color":   copyBorderColor,
+
+	"position": func(dst, src *ComputedStyle) { dst.Position = src.Position },
+	"float":    func(dst, src *ComputedStyle) { dst.Float = src.Float },
+	"clear":    func(dst, src *ComputedStyle) { dst.Clear = src.Clear },
+	"top":      func(dst, src *ComputedStyle) { dst.Top = src.Top },
+	"right":    func(dst, src *ComputedStyle) { dst.Right = src.Right },
+	"bottom":   func(dst, src *ComputedStyle) { dst.Bottom = src.Bottom },
+	"left":     func(dst, src *ComputedStyle) { dst.Left = src.Left },
+	"z-index":  func(dst, src *ComputedStyle) { dst.ZIndex = src.ZIndex },
+
+	"flex-direction":  func(dst, src *ComputedStyle) { dst.FlexDirection = src.FlexDirection },
+	"justify-content": func(dst, src *ComputedStyle) { dst.JustifyContent = src.JustifyContent },
+	"align-items":     func(dst, src *ComputedStyle) { dst.AlignItems = src.AlignItems },
+	"align-content":   func(dst, src *ComputedStyle) { dst.AlignContent = src.AlignContent },
+	"align-self":      func(dst, src *ComputedStyle) { dst.AlignSelf = src.AlignSelf },
+	"flex-wrap":       func(dst, src *ComputedStyle) { dst.FlexWrap = src.FlexWrap },
+	"flex-flow": func(dst, src *ComputedStyle) {
+		dst.FlexDirection, dst.FlexWrap = src.FlexDirection, src.FlexWrap
+	},
+	"gap": func(dst, src *ComputedStyle) {
+		dst.Gap, dst.RowGap, dst.ColumnGap = src.Gap, src.RowGap, src.ColumnGap
+	},
+	"row-gap":     func(dst, src *ComputedStyle) { dst.RowGap = src.RowGap },
+	"column-gap":  func(dst, src *ComputedStyle) { dst.ColumnGap = src.ColumnGap },
+	"flex-grow":   func(dst, src *ComputedStyle) { dst.FlexGrow = src.FlexGrow },
+	"flex-shrink": func(dst, src *ComputedStyle) { dst.FlexShrink = src.FlexShrink },
+	"flex-basis":  func(dst, src *ComputedStyle) { dst.FlexBasis = src.FlexBasis },
+	"order":       func(dst, src *ComputedStyle) { dst.Order = src.Order },
+	"flex": func(dst, src *ComputedStyle) {
+		dst.FlexGrow, dst.FlexShrink, dst.FlexBasis = src.FlexGrow, src.FlexShrink, src.FlexBasis
+	},
+
+	"list-style-type":     func(dst, src *ComputedStyle) { dst.ListStyleType = src.ListStyleType },
+	"list-style-position": func(dst, src *ComputedStyle) { dst.ListStylePosition = src.ListStylePosition },
+	"list-style": func(dst, src *ComputedStyle) {
+		dst.ListStyleType, dst.ListStylePosition = src.ListStyleType, src.ListStylePosition
+	},
+	"border-collapse": func(dst, src *ComputedStyle) { dst.BorderCollapse = src.BorderCollapse },
+	"border-spacing":  func(dst, src *ComputedStyle) { dst.BorderSpacing = src.BorderSpacing },
+
+	"grid-template-columns": func(dst, src *ComputedStyle) {
+		dst.GridTemplateColumns, dst.GridColumnCount = src.GridTemplateColumns, src.GridColumnCount
+	},
+	"grid-template-rows": func(dst, src *ComputedStyle) { dst.GridTemplateRows = src.GridTemplateRows },
+	"grid-column":        func(dst, src *ComputedStyle) { dst.GridColumn = src.GridColumn },
+	"grid-row":           func(dst, src *ComputedStyle) { dst.GridRow = src.GridRow },
+}
+
+// copyBorderStyle copies the line style a box draws on every side
+func copyBorderStyle(dst, src *ComputedStyle) {
+	dst.BorderStyle = src.BorderStyle
+}
+
+// copyBorderColor copies the color a box draws its border in
+func copyBorderColor(dst, src *ComputedStyle) {
+	dst.BorderColor = src.BorderColor
+	dst.currentColor = dst.currentColor&^currentBorder | src.currentColor&currentBorder
+}
+
+// applyCSSWideKeyword sets a longhand property to inherit, initial or
+// unset. Without a parent, inherit takes the initial value.
+func applyCSSWideKeyword(style *ComputedStyle, property, keyword string) {
+	copyFields := propertyFields[property]
+	if copyFields == nil {
+		return
+	}
+	if keyword == "unset" {
+		keyword = "initial"
+		if InheritableProperties[property] {
+			keyword = "inherit"
+		}
+	}
+	if keyword == "inherit" && style.parent != nil {
+		copyFields(style, style.parent)
+		return
+	}
+	copyFields(style, initialStyle)
+}
+
+// currentColorUses marks the colors of a style set to currentColor
+type currentColorUses uint8
+
+const (
+	currentBackground currentColorUses = 1 << iota // background-color
+	currentBorder                                  // border colors
+)
+
+// colorValue parses a color property's value for the use given. currentColor
+// is the color so far, and marked to follow the final one.
+func (cs *ComputedStyle) colorValue(value string, use currentColorUses) (color.RGBA, bool) {
+	if isCurrentColor(value) {
+		cs.currentColor |= use
+		return cs.Color, true
+	}
+	c, ok := ParseColor(value)
+	if ok {
+		cs.currentColor &^= use
+	}
+	return c, ok
+}
+
+// resolveCurrentColor sets the colors given as currentColor to the color the
+// cascade and inheritance left the element with
+func (cs *ComputedStyle) resolveCurrentColor() {
+	if cs.currentColor&currentBackground != 0 {
+		cs.BackgroundColor = cs.Color
+	}
+	if cs.currentColor&currentBorder != 0 {
+		cs.BorderColor = cs.Color
+	}
+}
+
+// isCurrentColor reports whether value is the currentColor keyword
+func isCurrentColor(value string) bool {
+	return strings.EqualFold(value, "currentcolor")
+}
+
+// isColorValue reports whether value is a color or currentColor
+func isColorValue(value string) bool {
+	if isCurrentColor(value) {
+		return true
+	}
+	_, ok := ParseColor(value)
+	return ok
+}
@@ -0,0 +1,76 @@
+package css
+
+import "strings"
+
+// ======================================================================================
+// LENGTHS
+// A Length keeps its unit until layout knows what percentages and ems are
+// relative to. The zero Length is unset.
+// ======================================================================================
+
+// Length is a CSS length: a number and unit, auto, or a min()/max()/clamp()
+// expression mixing units
+type Length struct {
+	Value float64
+	Unit  Unit
+	Expr  *MathLength // set for math functions resolved at layout
+}
+
+// Px returns a length in pixels
+func Px(v float64) Length {
+	return Length{Value: v, Unit: UnitPx}
+}
+
+// Percent returns a percentage length
+func Percent(v float64) Length {
+	return Length{Value: v, Unit: UnitPercent}
+}
+
+// Auto returns the auto length
+func Auto() Length {
+	return Length{Unit: UnitAuto}
+}
+
+// IsAuto reports whether the length is auto
+func (l Length) IsAuto() bool {
+	return l.Unit == UnitAuto && l.Expr == nil
+}
+
+// IsSet reports whether the length has a size: it is neither unset nor auto
+func (l Length) IsSet() bool {
+	return l.Expr != nil || (l.Unit != UnitNone && l.Unit != UnitAuto)
+}
+
+// Resolve returns the length in pixels, ems taken against fontSize,
+// percentages against percentBase and viewport units against viewport.
+// Unset and auto lengths are 0.
+func (l Length) Resolve(fontSize, percentBase float64, viewport Viewport) float64 {
+	if l.Expr != nil {
+		return l.Expr.Resolve(fontSize, percentBase, viewport)
+	}
+	if !l.IsSet() {
+		return 0
+	}
+	return LengthToPx(l.Value, l.Unit, fontSize, percentBase, viewport)
+}
+
+// ParseLengthValue parses a length keeping its unit. auto gives Auto() and
+// none (for max-width and max-height) an unset length.
+func ParseLengthValue(value string) (Length, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "auto":
+		return Auto(), true
+	case "none":
+		return Length{}, true
+	}
+	// Math functions are kept whole, as their arguments can't be combined
+	// before layout knows the font size, containing block and viewport
+	if expr, ok := ParseMathLength(value); ok {
+		return Length{Expr: expr}, true
+	}
+	if n, unit, ok := ParseLength(value); ok {
+		return Length{Value: n, Unit: unit}, true
+	}
+	return Length{}, false
+}
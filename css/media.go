@@ -0,0 +1,213 @@
+package css
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ======================================================================================
+// MEDIA QUERIES
+// Media queries, as in <picture><source media>, sizes and media attributes
+// and window.matchMedia, are evaluated against the viewport the page lays
+// out in, the pixel ratio of the screen and the user's color scheme. A
+// query with a feature this engine doesn't know doesn't match, as in
+// browsers.
+// ======================================================================================
+
+// Media is a medium pages are styled for and media queries are evaluated
+// against. The browser says which its pages are shown on, and it travels
+// with the styles computed for it.
+type Media struct {
+	Type string // screen or print
+	Viewport
+	PixelRatio  float64 // device pixels per CSS pixel
+	ColorScheme string  // the user's preferred color scheme, light or dark
+}
+
+// DefaultMedia returns the medium pages are styled for when no browser says
+// otherwise: a 1024x768 screen at one device pixel per CSS pixel, in the
+// light color scheme
+func DefaultMedia() Media {
+	return Media{
+		Type:        "screen",
+		Viewport:    Viewport{Width: 1024, Height: 768},
+		PixelRatio:  1,
+		ColorScheme: "light",
+	}
+}
+
+// Match reports whether a media query list, such as
+// "screen and (min-width: 600px), print", matches on m. An empty list
+// matches.
+func (m Media) Match(query string) bool {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return true
+	}
+	for _, q := range mediaParts(strings.ToLower(query), ',') {
+		if m.matchQuery(q) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchQuery evaluates one query of a list: [not|only] [type] [and
+// (feature)]..., or a condition of features alone
+func (m Media) matchQuery(q string) bool {
+	negate := false
+	if rest, ok := strings.CutPrefix(q, "not "); ok {
+		negate, q = true, strings.TrimSpace(rest)
+	} else if rest, ok := strings.CutPrefix(q, "only "); ok {
+		q = strings.TrimSpace(rest)
+	}
+
+	matches := true
+	if q != "" && q[0] != '(' {
+		mediaType, rest, _ := strings.Cut(q, " ")
+		switch mediaType {
+		case "all", m.Type:
+		default:
+			matches = false
+		}
+		q = strings.TrimSpace(rest)
+		if q != "" {
+			var ok bool
+			if q, ok = strings.CutPrefix(q, "and "); !ok {
+				return false
+			}
+		}
+	}
+	if matches && q != "" {
+		matches = m.MatchCondition(q)
+	}
+	return matches != negate
+}
+
+// MatchCondition evaluates on m a condition of parenthesized features joined
+// by "and", "or" or preceded by "not", as sizes and (min-width: 600px) are
+func (m Media) MatchCondition(cond string) bool {
+	cond = strings.ToLower(strings.TrimSpace(cond))
+	if rest, ok := strings.CutPrefix(cond, "not "); ok {
+		return !m.MatchCondition(rest)
+	}
+	terms := mediaParts(cond, ' ')
+	result, op := true, "and"
+	for i, term := range terms {
+		if i%2 == 1 {
+			op = term
+			continue
+		}
+		if !strings.HasPrefix(term, "(") || !strings.HasSuffix(term, ")") {
+			return false
+		}
+		inner := strings.TrimSpace(term[1 : len(term)-1])
+		var value bool
+		if strings.HasPrefix(inner, "(") || strings.HasPrefix(inner, "not ") {
+			value = m.MatchCondition(inner)
+		} else {
+			value = m.matchFeature(inner)
+		}
+		if i == 0 {
+			result = value
+		} else if op == "or" {
+			result = result || value
+		} else {
+			result = result && value
+		}
+	}
+	return result
+}
+
+// matchFeature evaluates a feature such as min-width: 600px, or one named
+// alone such as color
+func (m Media) matchFeature(feature string) bool {
+	name, value, hasValue := strings.Cut(feature, ":")
+	name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+	prefix := ""
+	if rest, ok := strings.CutPrefix(name, "min-"); ok {
+		prefix, name = "min", rest
+	} else if rest, ok := strings.CutPrefix(name, "max-"); ok {
+		prefix, name = "max", rest
+	}
+	name = strings.TrimPrefix(name, "-webkit-")
+
+	compare := func(actual, wanted float64) bool {
+		switch prefix {
+		case "min":
+			return actual >= wanted
+		case "max":
+			return actual <= wanted
+		}
+		return actual == wanted
+	}
+
+	switch name {
+	case "width", "height", "device-width", "device-height":
+		actual := m.Width
+		if strings.HasSuffix(name, "height") {
+			actual = m.Height
+		}
+		if !hasValue {
+			return actual > 0
+		}
+		num, unit, ok := parseLength(value, 16, m.Viewport)
+		return ok && compare(actual, LengthToPx(num, unit, 16, 0, m.Viewport))
+	case "aspect-ratio":
+		w, h, ok := strings.Cut(value, "/")
+		wn, err1 := strconv.ParseFloat(strings.TrimSpace(w), 64)
+		hn, err2 := strconv.ParseFloat(strings.TrimSpace(h), 64)
+		return ok && err1 == nil && err2 == nil && hn > 0 && compare(m.Width/m.Height, wn/hn)
+	case "orientation":
+		if m.Height >= m.Width {
+			return value == "portrait"
+		}
+		return value == "landscape"
+	case "resolution", "device-pixel-ratio":
+		ratio, ok := parseResolution(value)
+		return ok && compare(m.PixelRatio, ratio)
+	case "color":
+		return !hasValue || prefix != "" || value == "8"
+	case "hover", "any-hover":
+		return !hasValue || value == "hover"
+	case "pointer", "any-pointer":
+		return !hasValue || value == "fine"
+	case "prefers-color-scheme":
+		return value == m.ColorScheme
+	case "prefers-reduced-motion":
+		return value == "no-preference"
+	case "scripting":
+		return value == "enabled"
+	}
+	return false
+}
+
+// parseResolution reads a resolution as device pixels per CSS pixel: 2dppx,
+// 2x, 192dpi or, as -webkit-device-pixel-ratio takes it, a bare 2
+func parseResolution(value string) (float64, bool) {
+	scale := 1.0
+	switch {
+	case strings.HasSuffix(value, "dppx"):
+		value = strings.TrimSuffix(value, "dppx")
+	case strings.HasSuffix(value, "dpi"):
+		value, scale = strings.TrimSuffix(value, "dpi"), 1.0/96
+	case strings.HasSuffix(value, "dpcm"):
+		value, scale = strings.TrimSuffix(value, "dpcm"), 2.54/96
+	case strings.HasSuffix(value, "x"):
+		value = strings.TrimSuffix(value, "x")
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	return n * scale, err == nil
+}
+
+// mediaParts splits s at sep outside parentheses, trimming the parts and
+// dropping empty ones
+func mediaParts(s string, sep byte) []string {
+	var parts []string
+	for _, part := range splitTopLevel(s, sep) {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
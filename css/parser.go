@@ -1,6 +1,8 @@
 package css
 
 import (
+	"image/color"
+	"strconv"
 	"strings"
 )
 
@@ -19,19 +21,26 @@ type Declaration struct {
 type Rule struct {
 	Selectors    []Selector
 	Declarations []Declaration
+	Media        []string // media query lists that must all match, from the @imports it came through
 }
 
 // Stylesheet represents a collection of CSS rules
 type Stylesheet struct {
-	Rules []Rule
+	Rules   []Rule
+	Imports []Import // @import rules, spliced into Rules by LoadImports
+	URL     string   // where an external sheet was fetched from; "" for <style> blocks
+	Media   string   // the media attribute of its <style> or <link>; "" for all media
+	Origin  Origin   // the page's, unless set otherwise
+
+	index *ruleIndex // Rules' selectors by what they need, built when first styling
 }
 
 // ParseInlineStyle parses a style attribute value like "color: red; font-size: 16px;"
 func ParseInlineStyle(styleAttr string) []Declaration {
 	var declarations []Declaration
 
-	// Split by semicolon
-	parts := strings.Split(styleAttr, ";")
+	// Split by semicolon, keeping url(data:...;base64,...) in one piece
+	parts := splitTopLevel(styleAttr, ';')
 	for _, part := range parts {
 		part = strings.TrimSpace(part)
 		if part == "" {
@@ -87,6 +96,18 @@ func ParseStylesheet(css string) *Stylesheet {
 			break
 		}
 
+		// At-rules without a block, such as @import and @charset, end at
+		// a semicolon; @import only counts before any rule
+		if css[pos] == '@' {
+			if end := statementAtRuleEnd(css, pos); end != -1 {
+				if imp, ok := parseImport(css[pos:end]); ok && len(stylesheet.Rules) == 0 {
+					stylesheet.Imports = append(stylesheet.Imports, imp)
+				}
+				pos = end + 1
+				continue
+			}
+		}
+
 		// Find selector (everything before {)
 		braceStart := strings.Index(css[pos:], "{")
 		if braceStart == -1 {
@@ -127,6 +148,114 @@ func ParseStylesheet(css string) *Stylesheet {
 	return stylesheet
 }
 
+// borderStyles are the line styles a border may be drawn with
+var borderStyles = map[string]bool{
+	"solid": true, "dashed": true, "dotted": true, "double": true,
+	"groove": true, "ridge": true, "inset": true, "outset": true,
+}
+
+// borderKeywordWidths maps thin, medium and thick to pixels
+var borderKeywordWidths = map[string]string{"thin": "1px", "medium": "3px", "thick": "5px"}
+
+// setRelativeFontSize sets the font size to scale times the parent's, or
+// times 16px outside the cascade
+func (cs *ComputedStyle) setRelativeFontSize(scale float64) {
+	base := 16.0
+	if cs.parent != nil {
+		base = cs.parent.FontSize
+	}
+	cs.FontSize, cs.FontSizeScale = base*scale, scale
+}
+
+// parseBorderWidth parses a border side's width: a length, or thin, medium
+// or thick
+func parseBorderWidth(value string) (float64, bool) {
+	if w, ok := borderKeywordWidths[strings.ToLower(value)]; ok {
+		value = w
+	}
+	l, _, ok := ParseLength(value)
+	return l, ok
+}
+
+// fontSizeKeywords maps the absolute font-size keywords to pixels
+var fontSizeKeywords = map[string]float64{
+	"xx-small": 9, "x-small": 10, "small": 13, "medium": 16,
+	"large": 18, "x-large": 24, "xx-large": 32, "xxx-large": 48,
+}
+
+// applyTextDecoration reads text-decoration or one of its -line, -style,
+// -color and -thickness longhands
+func applyTextDecoration(style *ComputedStyle, property, value string) {
+	var lines []string
+	lineStyle := ""
+	var lineColor color.RGBA
+	var thickness Length
+	for _, part := range splitSelectorFields(strings.ToLower(value)) {
+		switch part {
+		case "underline", "overline", "line-through":
+			lines = append(lines, part)
+		case "solid", "double", "dotted", "dashed", "wavy":
+			lineStyle = part
+		case "none", "auto", "from-font", "currentcolor":
+		default:
+			if c, ok := ParseColor(part); ok {
+				lineColor = c
+			} else if l, ok := ParseLengthValue(part); ok {
+				thickness = l
+			}
+		}
+	}
+	shorthand := property == "text-decoration"
+	if shorthand || property == "text-decoration-line" {
+		style.TextDecoration = strings.Join(lines, " ")
+	}
+	if shorthand || property == "text-decoration-style" {
+		style.TextDecorationStyle = lineStyle
+	}
+	if shorthand || property == "text-decoration-color" {
+		style.TextDecorationColor = lineColor
+	}
+	if shorthand || property == "text-decoration-thickness" {
+		style.TextDecorationThickness = thickness
+	}
+}
+
+// noneAsEmpty returns value, or "" for none
+func noneAsEmpty(value string) string {
+	if value == "none" {
+		return ""
+	}
+	return value
+}
+
+// applyCursor reads "cursor: url(a.png) 4 12, url(b.cur), pointer": the first
+// url() image with its optional hotspot, and the keyword used as fallback
+func applyCursor(style *ComputedStyle, value string) {
+	style.CursorImage = ""
+	style.CursorHotspotX, style.CursorHotspotY = 0, 0
+	for _, item := range splitTopLevel(value, ',') {
+		fields := splitSelectorFields(strings.TrimSpace(item))
+		if len(fields) == 0 {
+			continue
+		}
+		if !strings.HasPrefix(strings.ToLower(fields[0]), "url(") {
+			style.Cursor = strings.ToLower(fields[0])
+			continue
+		}
+		if style.CursorImage != "" {
+			continue
+		}
+		style.CursorImage = ExtractURL(fields[0])
+		if len(fields) == 3 {
+			x, errX := strconv.ParseFloat(fields[1], 64)
+			y, errY := strconv.ParseFloat(fields[2], 64)
+			if errX == nil && errY == nil {
+				style.CursorHotspotX, style.CursorHotspotY = x, y
+			}
+		}
+	}
+}
+
 func removeComments(css string) string {
 	result := strings.Builder{}
 	i := 0
@@ -175,6 +304,18 @@ func ApplyDeclarations(style *ComputedStyle, declarations []Declaration) {
 // ApplyProperty applies a single CSS property to a ComputedStyle
 func ApplyProperty(style *ComputedStyle, property, value string) {
 	value = strings.TrimSpace(value)
+	if longhands, ok := expandShorthand(property, value); ok {
+		ApplyDeclarations(style, longhands)
+		return
+	}
+	keyword := strings.ToLower(value)
+	if property == "color" && keyword == "currentcolor" {
+		keyword = "inherit"
+	}
+	if cssWideKeywords[keyword] {
+		applyCSSWideKeyword(style, property, keyword)
+		return
+	}
 
 	switch property {
 	// Display
@@ -182,31 +323,104 @@ func ApplyProperty(style *ComputedStyle, property, value string) {
 		style.Display = value
 	case "visibility":
 		style.Visibility = value
+	case "opacity":
+		if n, unit, ok := style.parseLength(value); ok {
+			if unit == UnitPercent {
+				n /= 100
+			}
+			style.Opacity = max(0, min(n, 1))
+		}
+	case "overflow":
+		if parts := strings.Fields(value); len(parts) > 0 {
+			style.OverflowX, style.OverflowY = parts[0], parts[len(parts)-1]
+		}
+	case "overflow-x":
+		style.OverflowX = value
+	case "overflow-y":
+		style.OverflowY = value
+	case "box-shadow":
+		style.BoxShadow = noneAsEmpty(value)
+	case "transform":
+		if fns, ok := ParseTransform(value); ok {
+			style.Transform = noneAsEmpty(value)
+			style.TransformFunctions = fns
+		}
+	case "transform-origin":
+		if x, y, ok := ParseTransformOrigin(value); ok {
+			style.TransformOriginX, style.TransformOriginY = x, y
+		}
 
 	// Colors
 	case "color":
 		if c, ok := ParseColor(value); ok {
 			style.Color = c
 		}
-	case "background-color", "background":
-		// Check for gradient first
-		if strings.Contains(value, "gradient") {
+	case "background-color":
+		if c, ok := style.colorValue(value, currentBackground); ok {
+			style.BackgroundColor = c
+		}
+	case "background-image":
+		switch {
+		case value == "none":
+			style.BackgroundImage = ""
+			style.BackgroundGradient = nil
+		case strings.Contains(value, "gradient"):
 			if g, ok := ParseGradient(value); ok {
+				style.BackgroundImage = ""
 				style.BackgroundGradient = g
 			}
-		} else if c, ok := ParseColor(value); ok {
-			style.BackgroundColor = c
+		default:
+			style.BackgroundImage = ExtractURL(value)
+			style.BackgroundGradient = nil
+		}
+	case "background-repeat":
+		if value == "repeat" {
+			value = ""
+		}
+		style.BackgroundRepeat = value
+	case "background-size":
+		if value == "auto" || value == "auto auto" {
+			value = ""
 		}
+		style.BackgroundSize = value
+	case "background-position":
+		if value == "0% 0%" {
+			value = ""
+		}
+		style.BackgroundPosition = value
+	case "cursor":
+		applyCursor(style, value)
 
 	// Typography
 	case "font-size":
-		if l, unit, ok := ParseLength(value); ok {
-			if unit == UnitPx {
-				style.FontSize = l
-			} else if unit == UnitEm || unit == UnitRem {
-				style.FontSize = l * 16 // base font size
+		switch {
+		case value == "smaller":
+			style.setRelativeFontSize(FontSizeSmaller)
+		case value == "larger":
+			style.setRelativeFontSize(FontSizeLarger)
+		case strings.HasSuffix(value, "%"):
+			if pct, err := strconv.ParseFloat(strings.TrimSuffix(value, "%"), 64); err == nil && pct > 0 {
+				style.setRelativeFontSize(pct / 100)
+			}
+		case fontSizeKeywords[value] > 0:
+			style.FontSize, style.FontSizeScale = fontSizeKeywords[value], 0
+		default:
+			if l, unit, ok := style.parseLength(value); ok {
+				switch unit {
+				case UnitPx:
+					style.FontSize, style.FontSizeScale = l, 0
+				case UnitEm:
+					style.setRelativeFontSize(l)
+				case UnitRem:
+					style.FontSize, style.FontSizeScale = l*16, 0 // root font size
+				}
 			}
 		}
+	case "vertical-align":
+		switch value {
+		case "baseline", "sub", "super", "top", "text-top", "middle", "bottom", "text-bottom":
+			style.VerticalAlign = value
+		}
 	case "font-weight":
 		switch value {
 		case "normal":
@@ -218,16 +432,55 @@ func ApplyProperty(style *ComputedStyle, property, value string) {
 		case "bolder":
 			style.FontWeight = 800
 		default:
-			if w, _, ok := ParseLength(value); ok {
+			if w, _, ok := style.parseLength(value); ok {
 				style.FontWeight = int(w)
 			}
 		}
 	case "font-family":
 		style.FontFamily = value
+	case "font-style":
+		switch value {
+		case "normal", "italic", "oblique":
+			style.FontStyle = value
+		}
+	case "text-transform":
+		switch value {
+		case "none":
+			style.TextTransform = ""
+		case "uppercase", "lowercase", "capitalize":
+			style.TextTransform = value
+		}
+	case "text-decoration", "text-decoration-line", "text-decoration-style",
+		"text-decoration-color", "text-decoration-thickness":
+		applyTextDecoration(style, property, value)
+	case "letter-spacing":
+		if value == "normal" {
+			style.LetterSpacing = Length{}
+		} else if l, ok := ParseLengthValue(value); ok {
+			style.LetterSpacing = l
+		}
+	case "word-spacing":
+		if value == "normal" {
+			style.WordSpacing = Length{}
+		} else if l, ok := ParseLengthValue(value); ok {
+			style.WordSpacing = l
+		}
 	case "text-align":
 		style.TextAlign = value
+	case "white-space":
+		switch value {
+		case "normal", "nowrap", "pre", "pre-wrap", "pre-line":
+			style.WhiteSpace = value
+		case "break-spaces":
+			style.WhiteSpace = "pre-wrap"
+		}
 	case "line-height":
-		if l, unit, ok := ParseLength(value); ok {
+		if value == "normal" {
+			style.LineHeight = 1.2
+		} else if n, err := strconv.ParseFloat(value, 64); err == nil {
+			// A bare number multiplies the font size
+			style.LineHeight = n
+		} else if l, unit, ok := style.parseLength(value); ok {
 			if unit == UnitPx {
 				style.LineHeight = l / style.FontSize
 			} else {
@@ -237,102 +490,147 @@ func ApplyProperty(style *ComputedStyle, property, value string) {
 
 	// Box Model - Width/Height
 	case "width":
-		if l, unit, ok := ParseLength(value); ok && unit == UnitPx {
+		if l, ok := ParseLengthValue(value); ok {
 			style.Width = l
 		}
+	case "min-width":
+		if l, ok := ParseLengthValue(value); ok {
+			style.MinWidth = l
+		}
+	case "max-width":
+		if l, ok := ParseLengthValue(value); ok {
+			style.MaxWidth = l
+		}
 	case "height":
-		if l, unit, ok := ParseLength(value); ok && unit == UnitPx {
+		if l, ok := ParseLengthValue(value); ok {
 			style.Height = l
 		}
+	case "min-height":
+		if l, ok := ParseLengthValue(value); ok {
+			style.MinHeight = l
+		}
+	case "max-height":
+		if l, ok := ParseLengthValue(value); ok {
+			style.MaxHeight = l
+		}
+	case "box-sizing":
+		if v := strings.ToLower(strings.TrimSpace(value)); v == "content-box" || v == "border-box" {
+			style.BoxSizing = v
+		}
 
 	// Margins
-	case "margin":
-		applyBoxShorthand(value, func(top, right, bottom, left float64) {
-			style.MarginTop = top
-			style.MarginRight = right
-			style.MarginBottom = bottom
-			style.MarginLeft = left
-		})
 	case "margin-top":
-		if l, _, ok := ParseLength(value); ok {
-			style.MarginTop = l
+		if value == "auto" {
+			style.MarginTop, style.MarginPercent.Top, style.MarginMath.Top = 0, 0, nil
+		} else if l, ok := style.parseBoxLength(value); ok {
+			style.MarginTop, style.MarginPercent.Top, style.MarginMath.Top = l.px, l.percent, l.expr
 		}
 	case "margin-right":
-		if l, _, ok := ParseLength(value); ok {
-			style.MarginRight = l
+		if value == "auto" {
+			style.MarginRight, style.MarginPercent.Right, style.MarginMath.Right, style.MarginRightAuto = 0, 0, nil, true
+		} else if l, ok := style.parseBoxLength(value); ok {
+			style.MarginRight, style.MarginPercent.Right, style.MarginMath.Right, style.MarginRightAuto = l.px, l.percent, l.expr, false
 		}
 	case "margin-bottom":
-		if l, _, ok := ParseLength(value); ok {
-			style.MarginBottom = l
+		if value == "auto" {
+			style.MarginBottom, style.MarginPercent.Bottom, style.MarginMath.Bottom = 0, 0, nil
+		} else if l, ok := style.parseBoxLength(value); ok {
+			style.MarginBottom, style.MarginPercent.Bottom, style.MarginMath.Bottom = l.px, l.percent, l.expr
 		}
 	case "margin-left":
-		if l, _, ok := ParseLength(value); ok {
-			style.MarginLeft = l
+		if value == "auto" {
+			style.MarginLeft, style.MarginPercent.Left, style.MarginMath.Left, style.MarginLeftAuto = 0, 0, nil, true
+		} else if l, ok := style.parseBoxLength(value); ok {
+			style.MarginLeft, style.MarginPercent.Left, style.MarginMath.Left, style.MarginLeftAuto = l.px, l.percent, l.expr, false
 		}
 
 	// Padding
-	case "padding":
-		applyBoxShorthand(value, func(top, right, bottom, left float64) {
-			style.PaddingTop = top
-			style.PaddingRight = right
-			style.PaddingBottom = bottom
-			style.PaddingLeft = left
-		})
 	case "padding-top":
-		if l, _, ok := ParseLength(value); ok {
-			style.PaddingTop = l
+		if l, ok := style.parseBoxLength(value); ok {
+			style.PaddingTop, style.PaddingPercent.Top, style.PaddingMath.Top = l.px, l.percent, l.expr
 		}
 	case "padding-right":
-		if l, _, ok := ParseLength(value); ok {
-			style.PaddingRight = l
+		if l, ok := style.parseBoxLength(value); ok {
+			style.PaddingRight, style.PaddingPercent.Right, style.PaddingMath.Right = l.px, l.percent, l.expr
 		}
 	case "padding-bottom":
-		if l, _, ok := ParseLength(value); ok {
-			style.PaddingBottom = l
+		if l, ok := style.parseBoxLength(value); ok {
+			style.PaddingBottom, style.PaddingPercent.Bottom, style.PaddingMath.Bottom = l.px, l.percent, l.expr
 		}
 	case "padding-left":
-		if l, _, ok := ParseLength(value); ok {
-			style.PaddingLeft = l
+		if l, ok := style.parseBoxLength(value); ok {
+			style.PaddingLeft, style.PaddingPercent.Left, style.PaddingMath.Left = l.px, l.percent, l.expr
 		}
 
 	// Border
 	case "border-radius":
-		if l, _, ok := ParseLength(value); ok {
+		if l, _, ok := style.parseLength(value); ok {
 			style.BorderRadius = l
 		}
-	case "border-width":
-		if l, _, ok := ParseLength(value); ok {
+	case "border-top-width":
+		if l, ok := parseBorderWidth(value); ok {
 			style.BorderTopWidth = l
+		}
+	case "border-right-width":
+		if l, ok := parseBorderWidth(value); ok {
 			style.BorderRightWidth = l
+		}
+	case "border-bottom-width":
+		if l, ok := parseBorderWidth(value); ok {
 			style.BorderBottomWidth = l
+		}
+	case "border-left-width":
+		if l, ok := parseBorderWidth(value); ok {
 			style.BorderLeftWidth = l
 		}
-	case "border-color":
-		if c, ok := ParseColor(value); ok {
+	case "border-top-style", "border-right-style", "border-bottom-style", "border-left-style":
+		// A box draws one line style, the last a side set; none and hidden
+		// draw no border on the side
+		switch value = strings.ToLower(value); {
+		case value == "none" || value == "hidden":
+			ApplyProperty(style, strings.TrimSuffix(property, "style")+"width", "0")
+		case borderStyles[value]:
+			style.BorderStyle = value
+		}
+	case "border-top-color", "border-right-color", "border-bottom-color", "border-left-color":
+		// A box draws one border color, the last a side set
+		if c, ok := style.colorValue(value, currentBorder); ok {
 			style.BorderColor = c
 		}
 
 	// Position
 	case "position":
 		style.Position = value
+	case "float":
+		if value == "left" || value == "right" {
+			style.Float = value
+		} else if value == "none" {
+			style.Float = ""
+		}
+	case "clear":
+		if value == "left" || value == "right" || value == "both" {
+			style.Clear = value
+		} else if value == "none" {
+			style.Clear = ""
+		}
 	case "top":
-		if l, _, ok := ParseLength(value); ok {
+		if l, _, ok := style.parseLength(value); ok {
 			style.Top = l
 		}
 	case "right":
-		if l, _, ok := ParseLength(value); ok {
+		if l, _, ok := style.parseLength(value); ok {
 			style.Right = l
 		}
 	case "bottom":
-		if l, _, ok := ParseLength(value); ok {
+		if l, _, ok := style.parseLength(value); ok {
 			style.Bottom = l
 		}
 	case "left":
-		if l, _, ok := ParseLength(value); ok {
+		if l, _, ok := style.parseLength(value); ok {
 			style.Left = l
 		}
 	case "z-index":
-		if l, _, ok := ParseLength(value); ok {
+		if l, _, ok := style.parseLength(value); ok {
 			style.ZIndex = int(l)
 		}
 
@@ -349,55 +647,89 @@ func ApplyProperty(style *ComputedStyle, property, value string) {
 		style.AlignSelf = value
 	case "flex-wrap":
 		style.FlexWrap = value
+	case "flex-flow":
+		for _, part := range strings.Fields(value) {
+			if strings.HasPrefix(part, "row") || strings.HasPrefix(part, "column") {
+				style.FlexDirection = part
+			} else {
+				style.FlexWrap = part
+			}
+		}
 	case "gap":
-		if l, _, ok := ParseLength(value); ok {
+		if l, _, ok := style.parseLength(value); ok {
 			style.Gap = l
 			style.RowGap = l
 			style.ColumnGap = l
 		}
 	case "row-gap":
-		if l, _, ok := ParseLength(value); ok {
+		if l, _, ok := style.parseLength(value); ok {
 			style.RowGap = l
 		}
 	case "column-gap":
-		if l, _, ok := ParseLength(value); ok {
+		if l, _, ok := style.parseLength(value); ok {
 			style.ColumnGap = l
 		}
 	case "flex-grow":
-		if l, _, ok := ParseLength(value); ok {
+		if l, _, ok := style.parseLength(value); ok {
 			style.FlexGrow = l
 		}
 	case "flex-shrink":
-		if l, _, ok := ParseLength(value); ok {
+		if l, _, ok := style.parseLength(value); ok {
 			style.FlexShrink = l
 		}
 	case "flex-basis":
-		if l, _, ok := ParseLength(value); ok {
+		if l, _, ok := style.parseLength(value); ok {
 			style.FlexBasis = l
 		}
 	case "order":
-		if l, _, ok := ParseLength(value); ok {
+		if l, _, ok := style.parseLength(value); ok {
 			style.Order = int(l)
 		}
 	case "flex":
 		// Shorthand: flex: grow shrink basis OR flex: grow
 		parts := strings.Fields(value)
 		if len(parts) >= 1 {
-			if l, _, ok := ParseLength(parts[0]); ok {
+			if l, _, ok := style.parseLength(parts[0]); ok {
 				style.FlexGrow = l
 			}
 		}
 		if len(parts) >= 2 {
-			if l, _, ok := ParseLength(parts[1]); ok {
+			if l, _, ok := style.parseLength(parts[1]); ok {
 				style.FlexShrink = l
 			}
 		}
 		if len(parts) >= 3 {
-			if l, _, ok := ParseLength(parts[2]); ok {
+			if l, _, ok := style.parseLength(parts[2]); ok {
 				style.FlexBasis = l
 			}
 		}
 
+	// Lists
+	case "list-style-type":
+		style.ListStyleType = value
+	case "list-style-position":
+		style.ListStylePosition = value
+	case "list-style":
+		for _, part := range strings.Fields(value) {
+			if part == "inside" || part == "outside" {
+				style.ListStylePosition = part
+			} else if !strings.HasPrefix(part, "url(") {
+				style.ListStyleType = part
+			}
+		}
+
+	// Tables
+	case "border-collapse":
+		if value == "separate" || value == "collapse" {
+			style.BorderCollapse = value
+		}
+	case "border-spacing":
+		if parts := strings.Fields(value); len(parts) > 0 {
+			if l, ok := ParseLengthValue(parts[0]); ok {
+				style.BorderSpacing = l.Resolve(style.FontSize, 0, style.Media.Viewport)
+			}
+		}
+
 	// CSS Grid properties
 	case "grid-template-columns":
 		style.GridTemplateColumns = value
@@ -432,25 +764,31 @@ func countGridColumns(value string) int {
 	return len(parts)
 }
 
-// applyBoxShorthand handles margin/padding shorthand (1, 2, 3, or 4 values)
-func applyBoxShorthand(value string, apply func(top, right, bottom, left float64)) {
-	parts := strings.Fields(value)
-	values := make([]float64, 0, 4)
+// boxLength is one side of a margin or padding: pixels, a percentage of the
+// containing block's width, or a math expression with a percentage in it
+type boxLength struct {
+	px, percent float64
+	expr        *MathLength
+}
 
-	for _, p := range parts {
-		if l, _, ok := ParseLength(p); ok {
-			values = append(values, l)
-		}
-	}
+// parseLength parses a length, evaluating min(), max() and clamp() mixing
+// units for the style's font size and viewport
+func (cs *ComputedStyle) parseLength(value string) (float64, Unit, bool) {
+	return parseLength(value, cs.FontSize, cs.Media.Viewport)
+}
 
-	switch len(values) {
-	case 1:
-		apply(values[0], values[0], values[0], values[0])
-	case 2:
-		apply(values[0], values[1], values[0], values[1])
-	case 3:
-		apply(values[0], values[1], values[2], values[1])
-	case 4:
-		apply(values[0], values[1], values[2], values[3])
+// parseBoxLength parses a margin or padding side; ems are taken against the
+// style's font size and viewport units against its viewport
+func (cs *ComputedStyle) parseBoxLength(value string) (boxLength, bool) {
+	if expr, ok := ParseMathLength(value); ok && expr.hasPercent() {
+		return boxLength{expr: expr}, true
+	}
+	l, unit, ok := cs.parseLength(value)
+	if !ok {
+		return boxLength{}, false
+	}
+	if unit == UnitPercent {
+		return boxLength{percent: l}, true
 	}
+	return boxLength{px: LengthToPx(l, unit, cs.FontSize, 0, cs.Media.Viewport)}, true
 }
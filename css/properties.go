@@ -3,6 +3,7 @@ package css
 
 import (
 	"image/color"
+	"math"
 	"strconv"
 	"strings"
 )
@@ -22,6 +23,15 @@ const (
 	UnitPercent
 	UnitVw
 	UnitVh
+	UnitVmin
+	UnitVmax
+	UnitCh // width of "0", taken as the average glyph width
+	UnitEx // x-height
+	UnitPt
+	UnitCm
+	UnitMm
+	UnitIn
+	UnitAuto // the auto keyword
 )
 
 // Value represents a CSS value
@@ -46,6 +56,139 @@ const (
 	ValueInitial
 )
 
+// ======================================================================================
+// CSS MATH FUNCTIONS
+// ======================================================================================
+
+// Viewport is the size, in CSS pixels, of the viewport vw, vh, vmin and
+// vmax are relative to
+type Viewport struct {
+	Width, Height float64
+}
+
+// MathLength is a min(), max() or clamp() expression whose arguments mix units
+// that can only be converted to pixels once the containing block is known
+type MathLength struct {
+	Func string  // min, max, clamp
+	Args []Value // ValueLength arguments
+}
+
+// Resolve evaluates the expression; percentages are taken against percentBase
+// and viewport units against viewport
+func (m *MathLength) Resolve(fontSize, percentBase float64, viewport Viewport) float64 {
+	if m == nil || len(m.Args) == 0 {
+		return 0
+	}
+	resolved := make([]float64, len(m.Args))
+	for i, arg := range m.Args {
+		resolved[i] = LengthToPx(arg.Number, arg.Unit, fontSize, percentBase, viewport)
+	}
+	return evalMathFunction(m.Func, resolved)
+}
+
+// LengthToPx converts a length to pixels
+func LengthToPx(num float64, unit Unit, fontSize, percentBase float64, viewport Viewport) float64 {
+	switch unit {
+	case UnitEm:
+		return num * fontSize
+	case UnitRem:
+		return num * 16
+	case UnitPercent:
+		return num / 100 * percentBase
+	case UnitVw:
+		return num / 100 * viewport.Width
+	case UnitVh:
+		return num / 100 * viewport.Height
+	case UnitVmin:
+		return num / 100 * min(viewport.Width, viewport.Height)
+	case UnitVmax:
+		return num / 100 * max(viewport.Width, viewport.Height)
+	case UnitCh:
+		return num * fontSize * 0.55
+	case UnitEx:
+		return num * fontSize * 0.5
+	case UnitPt:
+		return num * 96 / 72
+	case UnitCm:
+		return num * 96 / 2.54
+	case UnitMm:
+		return num * 96 / 25.4
+	case UnitIn:
+		return num * 96
+	}
+	return num
+}
+
+// ParseMathLength parses min(), max() and clamp() into an unresolved expression
+func ParseMathLength(value string) (*MathLength, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	open := strings.Index(value, "(")
+	if open <= 0 || !strings.HasSuffix(value, ")") {
+		return nil, false
+	}
+	fn := value[:open]
+	if fn != "min" && fn != "max" && fn != "clamp" {
+		return nil, false
+	}
+
+	parts := splitGradientParts(value[open+1 : len(value)-1])
+	if len(parts) == 0 || (fn == "clamp" && len(parts) != 3) {
+		return nil, false
+	}
+
+	expr := &MathLength{Func: fn}
+	for _, part := range parts {
+		num, unit, ok := ParseLength(part)
+		if !ok {
+			return nil, false
+		}
+		expr.Args = append(expr.Args, Value{Type: ValueLength, Number: num, Unit: unit})
+	}
+	return expr, true
+}
+
+// hasPercent reports whether any argument is a percentage, which only the
+// containing block can resolve
+func (m *MathLength) hasPercent() bool {
+	for _, arg := range m.Args {
+		if arg.Unit == UnitPercent {
+			return true
+		}
+	}
+	return false
+}
+
+// evalMathFunction applies min/max/clamp to already resolved arguments
+func evalMathFunction(fn string, args []float64) float64 {
+	result := args[0]
+	switch fn {
+	case "min":
+		for _, v := range args[1:] {
+			if v < result {
+				result = v
+			}
+		}
+	case "max":
+		for _, v := range args[1:] {
+			if v > result {
+				result = v
+			}
+		}
+	case "clamp":
+		// clamp(MIN, VAL, MAX) = max(MIN, min(VAL, MAX))
+		if len(args) == 3 {
+			result = args[1]
+			if result > args[2] {
+				result = args[2]
+			}
+			if result < args[0] {
+				result = args[0]
+			}
+		}
+	}
+	return result
+}
+
 // ======================================================================================
 // CSS GRADIENTS
 // ======================================================================================
@@ -58,9 +201,15 @@ type GradientStop struct {
 
 // Gradient represents a CSS gradient (linear or radial)
 type Gradient struct {
-	IsLinear bool    // true for linear-gradient, false for radial
-	Angle    float64 // degrees (0 = to top, 90 = to right, etc.)
-	Stops    []GradientStop
+	IsLinear  bool    // true for linear-gradient, false for radial
+	Repeating bool    // repeating-*-gradient: the stops tile past the last one
+	Angle     float64 // degrees (0 = to top, 90 = to right, etc.)
+	Stops     []GradientStop
+
+	// Radial gradients only
+	Shape            string  // "circle" or "ellipse"
+	Size             string  // extent keyword such as "farthest-corner"
+	CenterX, CenterY float64 // center as a fraction of the box, 0.5 by default
 }
 
 // ======================================================================================
@@ -71,7 +220,7 @@ type Gradient struct {
 type ComputedStyle struct {
 	// Display
 	Display    string // block, inline, none, flex, grid, inline-flex, inline-block
-	Visibility string // visible, hidden
+	Visibility string // visible, hidden, collapse ("" = the parent's)
 
 	// Flexbox
 	FlexDirection  string  // row, row-reverse, column, column-reverse
@@ -99,21 +248,53 @@ type ComputedStyle struct {
 	Color              color.RGBA
 	BackgroundColor    color.RGBA
 	BackgroundGradient *Gradient // For linear-gradient, radial-gradient
+	BackgroundImage    string    // absolute URL from url(), "" = none
+	BackgroundRepeat   string    // repeat, repeat-x, repeat-y, no-repeat ("" = repeat)
+	BackgroundSize     string    // cover, contain or lengths ("" = auto)
+	BackgroundPosition string    // keywords or lengths ("" = 0% 0%)
+
+	// Effects
+	Opacity   float64 // 0 to 1
+	BoxShadow string  // the box-shadow value as written ("" = none)
+	Transform string  // the transform value as written ("" = none)
+	OverflowX string  // visible, hidden, scroll, auto ("" = visible)
+	OverflowY string
+
+	// TransformFunctions is Transform parsed; the transform-origin lengths
+	// are unset for the default, the center of the box
+	TransformFunctions []TransformFunction
+	TransformOriginX   Length
+	TransformOriginY   Length
 
 	// Typography
-	FontSize   float64
-	FontWeight int // 100-900
-	FontFamily string
-	TextAlign  string // left, center, right, justify
-	LineHeight float64
-
-	// Box Model (in pixels)
-	Width     float64
-	Height    float64
-	MinWidth  float64
-	MinHeight float64
-	MaxWidth  float64
-	MaxHeight float64
+	FontSize            float64
+	FontSizeScale       float64 // font-size relative to the parent's (smaller, larger, %); 0 = absolute
+	FontWeight          int     // 100-900
+	FontFamily          string
+	TextAlign           string // left, center, right, justify
+	LineHeight          float64
+	WhiteSpace          string // normal, nowrap, pre, pre-wrap, pre-line ("" = normal)
+	VerticalAlign       string // baseline, sub, super, top, middle, bottom, ... ("" = baseline)
+	TextDecoration      string // underline, line-through, overline, space separated ("" = none)
+	TextDecorationStyle string // solid, double, dotted, dashed, wavy ("" = solid)
+	// TextDecorationColor is the decoration lines' color; A == 0 draws them
+	// in the text color
+	TextDecorationColor     color.RGBA
+	TextDecorationThickness Length // unset = auto, scaled to the font
+	FontStyle               string // normal, italic, oblique ("" = normal)
+	TextTransform           string // uppercase, lowercase, capitalize ("" = none)
+	LetterSpacing           Length // unset = normal
+	WordSpacing             Length
+
+	// Box Model. Sizes keep their unit until layout resolves them against the
+	// containing block; unset and auto sizes are sized by the content.
+	Width     Length
+	Height    Length
+	MinWidth  Length
+	MinHeight Length
+	MaxWidth  Length
+	MaxHeight Length
+	BoxSizing string // content-box, border-box ("" = content-box)
 
 	// Margins
 	MarginTop    float64
@@ -121,18 +302,35 @@ type ComputedStyle struct {
 	MarginBottom float64
 	MarginLeft   float64
 
+	// Horizontal margins set to auto, which share the width a block leaves
+	// free; the side's margin above is 0
+	MarginLeftAuto  bool
+	MarginRightAuto bool
+
 	// Padding
 	PaddingTop    float64
 	PaddingRight  float64
 	PaddingBottom float64
 	PaddingLeft   float64
 
+	// Margins and padding given in percent of the containing block's width,
+	// resolved at layout. A side set in another unit is 0 here.
+	MarginPercent  BoxSides
+	PaddingPercent BoxSides
+
+	// Margins and padding given as min(), max() or clamp() with a percentage
+	// among the arguments, resolved at layout like the percentages above. A
+	// side set this way is 0 in the pixel and percent fields.
+	MarginMath  BoxMath
+	PaddingMath BoxMath
+
 	// Borders
 	BorderTopWidth    float64
 	BorderRightWidth  float64
 	BorderBottomWidth float64
 	BorderLeftWidth   float64
 	BorderColor       color.RGBA
+	BorderStyle       string // solid, dashed, dotted, ... ("" = solid where a width is set)
 	BorderRadius      float64
 
 	// Position
@@ -142,13 +340,60 @@ type ComputedStyle struct {
 	Bottom   float64
 	Left     float64
 	ZIndex   int
+
+	// Floats
+	Float string // left, right ("" = none)
+	Clear string // left, right, both ("" = none)
+
+	// Lists
+	ListStyleType     string // disc, circle, square, decimal, none ("" = by list type)
+	ListStylePosition string // inside, outside ("" = outside)
+
+	// Tables
+	BorderCollapse string  // separate, collapse ("" = separate)
+	BorderSpacing  float64 // pixels between cells when separate
+
+	// Cursor
+	Cursor         string  // keyword: auto, pointer, text, ... ("" = inherit from parent)
+	CursorImage    string  // absolute URL of a cursor: url(...) image, "" = none
+	CursorHotspotX float64 // point of the image that tracks the mouse
+	CursorHotspotY float64
+
+	// Pseudo-element styles keyed by name (selection, placeholder, marker)
+	PseudoElements map[string]*ComputedStyle
+
+	// Media is the medium the style was computed for; viewport units are
+	// taken against its viewport
+	Media Media
+
+	parent       *ComputedStyle   // the parent's style, while the cascade applies
+	currentColor currentColorUses // colors set to currentColor
+}
+
+// BoxSides holds a value for each side of a box
+type BoxSides struct {
+	Top, Right, Bottom, Left float64
+}
+
+// BoxMath holds the math expression of each side of a margin or padding;
+// nil for sides given otherwise
+type BoxMath struct {
+	Top, Right, Bottom, Left *MathLength
+}
+
+// PseudoStyle returns the computed style of a pseudo-element, or nil if unstyled
+func (cs *ComputedStyle) PseudoStyle(name string) *ComputedStyle {
+	if cs == nil || cs.PseudoElements == nil {
+		return nil
+	}
+	return cs.PseudoElements[name]
 }
 
 // NewComputedStyle creates a ComputedStyle with default values
 func NewComputedStyle() *ComputedStyle {
 	return &ComputedStyle{
 		Display:         "inline",
-		Visibility:      "visible",
+		Opacity:         1,
 		Color:           color.RGBA{0, 0, 0, 255},
 		BackgroundColor: color.RGBA{0, 0, 0, 0}, // transparent
 		FontSize:        16,
@@ -157,17 +402,118 @@ func NewComputedStyle() *ComputedStyle {
 		TextAlign:       "left",
 		LineHeight:      1.2,
 		Position:        "static",
+		FlexShrink:      1,
+		Media:           DefaultMedia(),
 	}
 }
 
+// FontSizeSmaller and FontSizeLarger are the factors of font-size: smaller
+// and larger, one step of the font size table
+const (
+	FontSizeSmaller = 5.0 / 6.0
+	FontSizeLarger  = 1.2
+)
+
+// MonospaceScale is the factor monospace text is set smaller by, as
+// browsers draw it at 13px by default
+const MonospaceScale = 13.0 / 16.0
+
+// monospaceFamilies are the font families drawn with the monospace font
+var monospaceFamilies = map[string]bool{
+	"monospace": true, "ui-monospace": true, "courier": true, "courier new": true,
+	"consolas": true, "menlo": true, "monaco": true, "sfmono-regular": true,
+	"dejavu sans mono": true, "liberation mono": true, "source code pro": true,
+	"fira code": true, "fira mono": true, "jetbrains mono": true, "roboto mono": true,
+}
+
+// IsMonospace reports whether the first family of a font-family list the
+// browser can draw is a monospace one
+func IsMonospace(family string) bool {
+	for _, name := range strings.Split(family, ",") {
+		name = strings.ToLower(strings.Trim(strings.TrimSpace(name), `"'`))
+		switch {
+		case monospaceFamilies[name]:
+			return true
+		case name == "serif" || name == "sans-serif" || name == "system-ui" || name == "cursive" || name == "fantasy":
+			return false
+		}
+	}
+	return false
+}
+
 // DefaultForTag returns default styles for HTML tags
 func DefaultForTag(tag string) *ComputedStyle {
 	style := NewComputedStyle()
+	applyTagDefaults(style, tag)
+	return style
+}
 
+// applyTagDefaults sets the user agent's styles for tag on style. Relative
+// font sizes are taken against 16px, for the cascade to resolve against the
+// parent's.
+func applyTagDefaults(style *ComputedStyle, tag string) {
 	switch tag {
 	case "div", "section", "article", "header", "footer", "nav", "main",
-		"ul", "ol", "li", "form", "table", "tr", "blockquote", "pre":
+		"ul", "ol", "li", "form", "table", "tr":
+		style.Display = "block"
+	case "pre":
+		style.Display = "block"
+		style.WhiteSpace = "pre"
+		style.FontFamily = "monospace"
+		style.FontSize, style.FontSizeScale = 16*MonospaceScale, MonospaceScale
+	case "code", "kbd", "samp", "tt":
+		style.FontFamily = "monospace"
+		style.FontSize, style.FontSizeScale = 16*MonospaceScale, MonospaceScale
+	case "blockquote", "figure":
+		style.Display = "block"
+		style.MarginTop, style.MarginBottom = 16, 16
+		style.MarginLeft, style.MarginRight = 40, 40
+	case "fieldset":
+		style.Display = "block"
+		style.MarginLeft, style.MarginRight = 2, 2
+		style.PaddingTop, style.PaddingBottom = 6, 10
+		style.PaddingLeft, style.PaddingRight = 12, 12
+		style.BorderTopWidth, style.BorderRightWidth = 2, 2
+		style.BorderBottomWidth, style.BorderLeftWidth = 2, 2
+		style.BorderColor = color.RGBA{192, 192, 192, 255}
+	case "legend":
+		style.Display = "block"
+		style.PaddingLeft, style.PaddingRight = 2, 2
+	case "details":
+		style.Display = "block"
+	case "summary":
+		// The disclosure triangle is painted in the left padding
+		style.Display = "block"
+		style.Cursor = "pointer"
+		style.PaddingLeft = 18
+	case "dialog":
 		style.Display = "block"
+		style.PaddingTop, style.PaddingBottom = 16, 16
+		style.PaddingLeft, style.PaddingRight = 16, 16
+		style.BorderTopWidth, style.BorderRightWidth = 2, 2
+		style.BorderBottomWidth, style.BorderLeftWidth = 2, 2
+		style.BorderColor = color.RGBA{0, 0, 0, 255}
+		style.BackgroundColor = color.RGBA{255, 255, 255, 255}
+		style.Color = color.RGBA{0, 0, 0, 255}
+	case "caption":
+		style.Display = "block"
+		style.TextAlign = "center"
+	case "th":
+		style.FontWeight = 700
+	case "figcaption":
+		style.Display = "block"
+		style.TextAlign = "center"
+	case "dl":
+		style.Display = "block"
+		style.MarginTop, style.MarginBottom = 16, 16
+	case "dt":
+		style.Display = "block"
+		style.FontWeight = 700
+	case "dd":
+		style.Display = "block"
+		style.MarginLeft = 40
+	case "textarea":
+		style.WhiteSpace = "pre-wrap"
 	case "h1":
 		style.Display = "block"
 		style.FontSize = 32
@@ -200,6 +546,20 @@ func DefaultForTag(tag string) *ComputedStyle {
 		style.Color = color.RGBA{0, 0, 238, 255} // blue
 	case "b", "strong":
 		style.FontWeight = 700
+	case "small":
+		style.FontSize, style.FontSizeScale = 16*FontSizeSmaller, FontSizeSmaller
+	case "sub":
+		style.FontSize, style.FontSizeScale = 16*FontSizeSmaller, FontSizeSmaller
+		style.VerticalAlign = "sub"
+	case "sup":
+		style.FontSize, style.FontSizeScale = 16*FontSizeSmaller, FontSizeSmaller
+		style.VerticalAlign = "super"
+	case "mark":
+		style.BackgroundColor = color.RGBA{255, 255, 0, 255}
+	case "del", "s", "strike":
+		style.TextDecoration = "line-through"
+	case "ins", "u":
+		style.TextDecoration = "underline"
 	case "i", "em":
 		// italic would be handled separately
 	case "button":
@@ -211,124 +571,62 @@ func DefaultForTag(tag string) *ComputedStyle {
 		style.BackgroundColor = color.RGBA{240, 240, 240, 255}
 		style.BorderRadius = 4
 	}
-
-	return style
 }
 
-// ======================================================================================
-// COLOR PARSING
-// ======================================================================================
-
-// Named colors (subset of CSS named colors)
-var namedColors = map[string]color.RGBA{
-	// Basic colors
-	"black":       {0, 0, 0, 255},
-	"white":       {255, 255, 255, 255},
-	"red":         {255, 0, 0, 255},
-	"green":       {0, 128, 0, 255},
-	"blue":        {0, 0, 255, 255},
-	"yellow":      {255, 255, 0, 255},
-	"cyan":        {0, 255, 255, 255},
-	"magenta":     {255, 0, 255, 255},
-	"gray":        {128, 128, 128, 255},
-	"grey":        {128, 128, 128, 255},
-	"transparent": {0, 0, 0, 0},
-
-	// Extended colors
-	"orange":     {255, 165, 0, 255},
-	"purple":     {128, 0, 128, 255},
-	"pink":       {255, 192, 203, 255},
-	"brown":      {165, 42, 42, 255},
-	"navy":       {0, 0, 128, 255},
-	"teal":       {0, 128, 128, 255},
-	"olive":      {128, 128, 0, 255},
-	"maroon":     {128, 0, 0, 255},
-	"lime":       {0, 255, 0, 255},
-	"aqua":       {0, 255, 255, 255},
-	"silver":     {192, 192, 192, 255},
-	"fuchsia":    {255, 0, 255, 255},
-	"lightgray":  {211, 211, 211, 255},
-	"lightgrey":  {211, 211, 211, 255},
-	"darkgray":   {169, 169, 169, 255},
-	"darkgrey":   {169, 169, 169, 255},
-	"whitesmoke": {245, 245, 245, 255},
-	"inherit":    {0, 0, 0, 0}, // special handling
+// ParseLength parses a CSS length value (e.g., "16px", "1.5em"). min(),
+// max() and clamp() mixing units are evaluated for a 16px font in the
+// default viewport.
+func ParseLength(value string) (float64, Unit, bool) {
+	return parseLength(value, 16, DefaultMedia().Viewport)
 }
 
-// ParseColor parses a CSS color value
-func ParseColor(value string) (color.RGBA, bool) {
+// parseLength parses a length, evaluating min(), max() and clamp() mixing
+// units with ems taken against fontSize and viewport units against viewport
+func parseLength(value string, fontSize float64, viewport Viewport) (float64, Unit, bool) {
 	value = strings.ToLower(strings.TrimSpace(value))
 
-	// Named color
-	if c, ok := namedColors[value]; ok {
-		return c, true
+	if value == "0" {
+		return 0, UnitPx, true
 	}
 
-	// Hex color #RGB or #RRGGBB
-	if strings.HasPrefix(value, "#") {
-		hex := value[1:]
-		if len(hex) == 3 {
-			// #RGB -> #RRGGBB
-			hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
-		}
-		if len(hex) == 6 {
-			r, _ := strconv.ParseUint(hex[0:2], 16, 8)
-			g, _ := strconv.ParseUint(hex[2:4], 16, 8)
-			b, _ := strconv.ParseUint(hex[4:6], 16, 8)
-			return color.RGBA{uint8(r), uint8(g), uint8(b), 255}, true
-		}
-		if len(hex) == 8 {
-			r, _ := strconv.ParseUint(hex[0:2], 16, 8)
-			g, _ := strconv.ParseUint(hex[2:4], 16, 8)
-			b, _ := strconv.ParseUint(hex[4:6], 16, 8)
-			a, _ := strconv.ParseUint(hex[6:8], 16, 8)
-			return color.RGBA{uint8(r), uint8(g), uint8(b), uint8(a)}, true
+	// min(), max(), clamp(): evaluate now when no containing block is needed
+	if expr, ok := ParseMathLength(value); ok {
+		unit := expr.Args[0].Unit
+		sameUnit := true
+		for _, arg := range expr.Args {
+			if arg.Unit == UnitPercent {
+				return 0, UnitNone, false
+			}
+			if arg.Unit != unit {
+				sameUnit = false
+			}
 		}
-	}
-
-	// rgb(r, g, b) or rgba(r, g, b, a)
-	if strings.HasPrefix(value, "rgb") {
-		start := strings.Index(value, "(")
-		end := strings.LastIndex(value, ")")
-		if start != -1 && end > start {
-			parts := strings.Split(value[start+1:end], ",")
-			if len(parts) >= 3 {
-				r, _ := strconv.Atoi(strings.TrimSpace(parts[0]))
-				g, _ := strconv.Atoi(strings.TrimSpace(parts[1]))
-				b, _ := strconv.Atoi(strings.TrimSpace(parts[2]))
-				a := 255
-				if len(parts) >= 4 {
-					af, _ := strconv.ParseFloat(strings.TrimSpace(parts[3]), 64)
-					if af <= 1 {
-						a = int(af * 255)
-					} else {
-						a = int(af)
-					}
-				}
-				return color.RGBA{uint8(r), uint8(g), uint8(b), uint8(a)}, true
+		if sameUnit {
+			nums := make([]float64, len(expr.Args))
+			for i, arg := range expr.Args {
+				nums[i] = arg.Number
 			}
+			return evalMathFunction(expr.Func, nums), unit, true
 		}
-	}
-
-	return color.RGBA{}, false
-}
-
-// ParseLength parses a CSS length value (e.g., "16px", "1.5em")
-func ParseLength(value string) (float64, Unit, bool) {
-	value = strings.ToLower(strings.TrimSpace(value))
-
-	if value == "0" {
-		return 0, UnitPx, true
+		return expr.Resolve(fontSize, 0, viewport), UnitPx, true
 	}
 
 	// Try different units
 	units := map[string]Unit{
-		"px":  UnitPx,
-		"em":  UnitEm,
-		"rem": UnitRem,
-		"%":   UnitPercent,
-		"vw":  UnitVw,
-		"vh":  UnitVh,
+		"px":   UnitPx,
+		"em":   UnitEm,
+		"rem":  UnitRem,
+		"%":    UnitPercent,
+		"vw":   UnitVw,
+		"vh":   UnitVh,
+		"vmin": UnitVmin,
+		"vmax": UnitVmax,
+		"ch":   UnitCh,
+		"ex":   UnitEx,
+		"pt":   UnitPt,
+		"cm":   UnitCm,
+		"mm":   UnitMm,
+		"in":   UnitIn,
 	}
 
 	for suffix, unit := range units {
@@ -349,112 +647,184 @@ func ParseLength(value string) (float64, Unit, bool) {
 	return 0, UnitNone, false
 }
 
-// ParseGradient parses a CSS gradient value (linear-gradient, radial-gradient)
+// ParseGradient parses a CSS gradient value: linear-gradient(),
+// radial-gradient() or their repeating- forms
 func ParseGradient(value string) (*Gradient, bool) {
-	value = strings.TrimSpace(value)
-
-	// Check for linear-gradient
-	if strings.HasPrefix(value, "linear-gradient(") {
-		inner := strings.TrimPrefix(value, "linear-gradient(")
-		inner = strings.TrimSuffix(inner, ")")
-		return parseLinearGradient(inner)
+	value = strings.ToLower(strings.TrimSpace(value))
+	open := strings.Index(value, "(")
+	if open <= 0 || !strings.HasSuffix(value, ")") {
+		return nil, false
 	}
+	fn, inner := value[:open], value[open+1:len(value)-1]
 
-	return nil, false
-}
-
-// parseLinearGradient parses the inner content of linear-gradient()
-func parseLinearGradient(inner string) (*Gradient, bool) {
-	gradient := &Gradient{
-		IsLinear: true,
-		Angle:    180, // default: to bottom
-		Stops:    []GradientStop{},
+	gradient := &Gradient{}
+	if rest, ok := strings.CutPrefix(fn, "repeating-"); ok {
+		gradient.Repeating = true
+		fn = rest
 	}
-
-	// Split by comma, but respect nested parentheses
 	parts := splitGradientParts(inner)
 	if len(parts) == 0 {
 		return nil, false
 	}
 
-	startIdx := 0
+	// The first part is the geometry when it isn't a color stop
+	first := strings.TrimSpace(parts[0])
+	switch fn {
+	case "linear-gradient":
+		gradient.IsLinear = true
+		gradient.Angle = 180 // to bottom
+		if angle, ok := parseGradientDirection(first); ok {
+			gradient.Angle = angle
+			parts = parts[1:]
+		}
+	case "radial-gradient":
+		gradient.Shape, gradient.Size = "ellipse", "farthest-corner"
+		gradient.CenterX, gradient.CenterY = 0.5, 0.5
+		if parseRadialShape(gradient, first) {
+			parts = parts[1:]
+		}
+	default:
+		return nil, false
+	}
+
+	gradient.Stops = parseColorStops(parts)
+	if len(gradient.Stops) < 2 {
+		return nil, false
+	}
+	return gradient, true
+}
 
-	// Check if first part is a direction/angle
-	firstPart := strings.TrimSpace(parts[0])
-	if strings.HasPrefix(firstPart, "to ") {
-		// Direction keywords
-		direction := strings.TrimPrefix(firstPart, "to ")
-		switch direction {
+// parseGradientDirection reads a linear gradient's "to <side>" or angle into
+// degrees (0 = to top, 90 = to right)
+func parseGradientDirection(value string) (float64, bool) {
+	if side, ok := strings.CutPrefix(value, "to "); ok {
+		switch strings.Join(strings.Fields(side), " ") {
 		case "top":
-			gradient.Angle = 0
+			return 0, true
 		case "right":
-			gradient.Angle = 90
+			return 90, true
 		case "bottom":
-			gradient.Angle = 180
+			return 180, true
 		case "left":
-			gradient.Angle = 270
+			return 270, true
 		case "top right", "right top":
-			gradient.Angle = 45
+			return 45, true
 		case "bottom right", "right bottom":
-			gradient.Angle = 135
+			return 135, true
 		case "bottom left", "left bottom":
-			gradient.Angle = 225
+			return 225, true
 		case "top left", "left top":
-			gradient.Angle = 315
-		}
-		startIdx = 1
-	} else if strings.HasSuffix(firstPart, "deg") {
-		// Angle in degrees
-		angleStr := strings.TrimSuffix(firstPart, "deg")
-		if angle, err := strconv.ParseFloat(angleStr, 64); err == nil {
-			gradient.Angle = angle
+			return 315, true
 		}
-		startIdx = 1
+		return 0, false
 	}
+	if radians, ok := ParseAngle(value); ok {
+		return radians * 180 / math.Pi, true
+	}
+	return 0, false
+}
 
-	// Parse color stops
-	numStops := len(parts) - startIdx
-	for i := startIdx; i < len(parts); i++ {
-		stop := parseColorStop(strings.TrimSpace(parts[i]), i-startIdx, numStops)
-		if stop != nil {
-			gradient.Stops = append(gradient.Stops, *stop)
+// parseRadialShape reads "[circle|ellipse] [extent] [at <position>]" into
+// gradient, reporting false when value is a color stop instead
+func parseRadialShape(gradient *Gradient, value string) bool {
+	shape, position, hasAt := strings.Cut(value, "at ")
+	if hasAt {
+		x, y, ok := ParseTransformOrigin(position)
+		if !ok {
+			return false
+		}
+		// Only percentages and keywords place the center; lengths keep it
+		// in the middle
+		if x.Unit == UnitPercent && y.Unit == UnitPercent {
+			gradient.CenterX, gradient.CenterY = x.Value/100, y.Value/100
 		}
 	}
-
-	if len(gradient.Stops) < 2 {
-		return nil, false
+	for _, word := range strings.Fields(shape) {
+		switch word {
+		case "circle", "ellipse":
+			gradient.Shape = word
+		case "closest-side", "closest-corner", "farthest-side", "farthest-corner":
+			gradient.Size = word
+		default:
+			return false
+		}
 	}
-
-	return gradient, true
+	return true
 }
 
-// parseColorStop parses a gradient color stop
-func parseColorStop(value string, index, total int) *GradientStop {
-	parts := strings.Fields(value)
-	if len(parts) == 0 {
-		return nil
+// parseColorStops reads gradient color stops. Stops without a position are
+// spread evenly between their neighbours, a stop with two positions becomes
+// two stops, and a position before the previous one is moved up to it so
+// the colors meet in a hard edge.
+func parseColorStops(parts []string) []GradientStop {
+	var stops []GradientStop
+	var placed []bool
+	for _, part := range parts {
+		fields := splitSelectorFields(strings.TrimSpace(part))
+		if len(fields) == 0 {
+			continue
+		}
+		c, ok := ParseColor(fields[0])
+		if !ok {
+			continue
+		}
+		if len(fields) == 1 {
+			stops = append(stops, GradientStop{Color: c})
+			placed = append(placed, false)
+			continue
+		}
+		for _, pos := range fields[1:] {
+			if p, ok := parseStopPosition(pos); ok {
+				stops = append(stops, GradientStop{Color: c, Position: p})
+				placed = append(placed, true)
+			}
+		}
 	}
-
-	color, ok := ParseColor(parts[0])
-	if !ok {
-		return nil
+	if len(stops) < 2 {
+		return stops
 	}
 
-	position := float64(index) / float64(total-1)
-	if len(parts) > 1 {
-		// Has explicit position
-		posStr := parts[1]
-		if strings.HasSuffix(posStr, "%") {
-			if p, err := strconv.ParseFloat(strings.TrimSuffix(posStr, "%"), 64); err == nil {
-				position = p / 100
-			}
+	if !placed[0] {
+		stops[0].Position, placed[0] = 0, true
+	}
+	if last := len(stops) - 1; !placed[last] {
+		stops[last].Position, placed[last] = 1, true
+	}
+	for i := 1; i < len(stops); i++ {
+		if placed[i] && stops[i].Position < stops[i-1].Position {
+			stops[i].Position = stops[i-1].Position
 		}
 	}
+	for i := 1; i < len(stops); {
+		if placed[i] {
+			i++
+			continue
+		}
+		// Spread the run of unplaced stops between the placed ones around it
+		end := i
+		for !placed[end] {
+			end++
+		}
+		from, to := stops[i-1].Position, math.Max(stops[end].Position, stops[i-1].Position)
+		for j := i; j < end; j++ {
+			stops[j].Position = from + (to-from)*float64(j-i+1)/float64(end-i+1)
+			placed[j] = true
+		}
+		i = end
+	}
+	return stops
+}
 
-	return &GradientStop{
-		Color:    color,
-		Position: position,
+// parseStopPosition reads a stop position as a fraction of the gradient line
+func parseStopPosition(value string) (float64, bool) {
+	if num, ok := strings.CutSuffix(value, "%"); ok {
+		p, err := strconv.ParseFloat(num, 64)
+		return p / 100, err == nil
+	}
+	if value == "0" {
+		return 0, true
 	}
+	return 0, false
 }
 
 // splitGradientParts splits gradient parts respecting nested parentheses
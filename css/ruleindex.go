@@ -0,0 +1,258 @@
+package css
+
+import (
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go-browser/dom"
+)
+
+// ======================================================================================
+// RULE INDEX & STYLE SHARING
+// Each stylesheet files its selectors under the id, class or tag their
+// rightmost compound needs, so an element is only tested against the
+// selectors that could match it. Selectors with ancestor parts are first
+// checked against a bloom filter of the ids, classes and tags of the
+// element's ancestors, which rules most of them out without walking up the
+// tree. Elements of the same tag matching the same rules, with the same
+// inline style and UA attributes, share the style the first one computed.
+// ======================================================================================
+
+// indexedSelector is a selector of a stylesheet's rule
+type indexedSelector struct {
+	rule        *Rule
+	selector    *Selector
+	position    int // in the stylesheet, across rules and their selector lists
+	specificity Specificity
+	pseudo      string   // the pseudo-element it styles; "" for the element
+	ancestors   []uint32 // hashes its ancestor parts need on the element's ancestors
+}
+
+// ruleIndex is a stylesheet's selectors by the key their rightmost compound needs
+type ruleIndex struct {
+	rules     int // rules indexed; the index is rebuilt when the sheet grows
+	byID      map[string][]*indexedSelector
+	byClass   map[string][]*indexedSelector
+	byTag     map[string][]*indexedSelector
+	universal []*indexedSelector
+}
+
+// ruleIndex returns the stylesheet's index, building it the first time
+func (s *Stylesheet) ruleIndex() *ruleIndex {
+	if s.index != nil && s.index.rules == len(s.Rules) {
+		return s.index
+	}
+	idx := &ruleIndex{
+		rules:   len(s.Rules),
+		byID:    make(map[string][]*indexedSelector),
+		byClass: make(map[string][]*indexedSelector),
+		byTag:   make(map[string][]*indexedSelector),
+	}
+	position := 0
+	for i := range s.Rules {
+		rule := &s.Rules[i]
+		for j := range rule.Selectors {
+			sel := &rule.Selectors[j]
+			entry := &indexedSelector{
+				rule:        rule,
+				selector:    sel,
+				position:    position,
+				specificity: sel.CalculateSpecificity(),
+				pseudo:      sel.PseudoElementName(),
+			}
+			position++
+
+			subject := *sel
+			if n := len(sel.Parts); n > 0 {
+				subject = sel.Parts[n-1]
+				for _, part := range sel.Parts[:n-1] {
+					entry.ancestors = append(entry.ancestors, compoundHashes(part)...)
+				}
+			}
+			switch id, class, tag := compoundKeys(subject); {
+			case id != "":
+				idx.byID[id] = append(idx.byID[id], entry)
+			case class != "":
+				idx.byClass[class] = append(idx.byClass[class], entry)
+			case tag != "":
+				idx.byTag[tag] = append(idx.byTag[tag], entry)
+			default:
+				idx.universal = append(idx.universal, entry)
+			}
+		}
+	}
+	s.index = idx
+	return idx
+}
+
+// candidates returns the selectors of the index that may match node, in
+// stylesheet order
+func (idx *ruleIndex) candidates(node *dom.Node) []*indexedSelector {
+	found := slices.Clone(idx.universal)
+	found = append(found, idx.byTag[strings.ToLower(node.Tag)]...)
+	if id := node.GetAttr("id"); id != "" {
+		found = append(found, idx.byID[id]...)
+	}
+	classes := strings.Fields(node.GetAttr("class"))
+	for i, class := range classes {
+		if !slices.Contains(classes[:i], class) {
+			found = append(found, idx.byClass[class]...)
+		}
+	}
+	slices.SortFunc(found, func(a, b *indexedSelector) int { return a.position - b.position })
+	return found
+}
+
+// compoundKeys returns the id, class and lowercase tag a compound selector
+// needs an element to have, each "" when it needs none
+func compoundKeys(sel Selector) (id, class, tag string) {
+	switch sel.Type {
+	case SelectorID:
+		return sel.ID, "", ""
+	case SelectorClass:
+		return "", sel.Class, ""
+	case SelectorElement:
+		if sel.Element != "*" {
+			tag = strings.ToLower(sel.Element)
+		}
+		return sel.ID, sel.Class, tag
+	}
+	return "", "", ""
+}
+
+// compoundHashes returns the bloom filter hashes of what a compound selector
+// needs an element to have
+func compoundHashes(sel Selector) []uint32 {
+	var hashes []uint32
+	id, class, tag := compoundKeys(sel)
+	if id != "" {
+		hashes = append(hashes, filterHash("#"+id))
+	}
+	if class != "" {
+		hashes = append(hashes, filterHash("."+class))
+	}
+	if tag != "" {
+		hashes = append(hashes, filterHash(tag))
+	}
+	return hashes
+}
+
+// filterHash hashes an id ("#id"), class (".class") or tag for the ancestor
+// filter
+func filterHash(key string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return h.Sum32()
+}
+
+// ancestorFilterBits is the size of the ancestor filter; two bits are set
+// per key
+const ancestorFilterBits = 1 << 12
+
+// ancestorFilter is a counting bloom filter of the ids, classes and tags of
+// the elements above the one being styled. It may say an element has an
+// ancestor it lacks, never the other way round.
+type ancestorFilter struct {
+	counts [ancestorFilterBits]uint16
+}
+
+// newAncestorFilter returns a filter holding the ancestors of node
+func newAncestorFilter(node *dom.Node) *ancestorFilter {
+	f := &ancestorFilter{}
+	for p := node.Parent; p != nil; p = p.Parent {
+		f.push(p)
+	}
+	return f
+}
+
+// push adds an element's keys as an ancestor
+func (f *ancestorFilter) push(node *dom.Node) {
+	f.update(node, 1)
+}
+
+// pop removes an element pushed before
+func (f *ancestorFilter) pop(node *dom.Node) {
+	f.update(node, -1)
+}
+
+func (f *ancestorFilter) update(node *dom.Node, delta int) {
+	if node.Type != dom.NodeElement {
+		return
+	}
+	add := func(key string) {
+		h := filterHash(key)
+		f.counts[h%ancestorFilterBits] += uint16(delta)
+		f.counts[(h>>16)%ancestorFilterBits] += uint16(delta)
+	}
+	add(strings.ToLower(node.Tag))
+	if id := node.GetAttr("id"); id != "" {
+		add("#" + id)
+	}
+	for _, class := range strings.Fields(node.GetAttr("class")) {
+		add("." + class)
+	}
+}
+
+// mayHaveAll reports whether every hash may be on an ancestor
+func (f *ancestorFilter) mayHaveAll(hashes []uint32) bool {
+	for _, h := range hashes {
+		if f.counts[h%ancestorFilterBits] == 0 || f.counts[(h>>16)%ancestorFilterBits] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// styler styles a subtree for a medium, with the ancestor filter of the
+// element it is at and the styles its elements share
+type styler struct {
+	stylesheets []*Stylesheet
+	media       Media
+	ancestors   *ancestorFilter
+	shared      map[sharedStyleKey]*ComputedStyle
+}
+
+// sharedStyleKey identifies the elements that share a style: children of
+// the same parent that cascade the same
+type sharedStyleKey struct {
+	parent  *ComputedStyle
+	cascade string // the shareKey
+}
+
+// newStyler returns a styler for the subtree at root
+func newStyler(root *dom.Node, stylesheets []*Stylesheet, media Media) *styler {
+	return &styler{
+		stylesheets: stylesheets,
+		media:       media,
+		ancestors:   newAncestorFilter(root),
+		shared:      make(map[sharedStyleKey]*ComputedStyle),
+	}
+}
+
+// shareKey identifies what an element's cascaded style depends on: its tag,
+// the attributes the UA stylesheet reads, its inline style and the
+// selectors it matched, numbered across stylesheets
+func shareKey(node *dom.Node, matched []matchedSelector, inline string) string {
+	var b strings.Builder
+	b.WriteString(node.Tag)
+	for _, set := range []bool{
+		node.GetAttr("href") != "",
+		node.GetAttr("title") != "",
+		node.HasAttr("hidden"),
+		node.HasAttr("open"),
+	} {
+		b.WriteString(strconv.FormatBool(set)[:1])
+	}
+	b.WriteString(strings.ToLower(node.GetAttr("align")))
+	b.WriteByte('|')
+	b.WriteString(inline)
+	for _, m := range matched {
+		b.WriteByte('|')
+		b.WriteString(strconv.Itoa(m.sheet))
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(m.selector.position))
+	}
+	return b.String()
+}
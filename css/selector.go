@@ -3,6 +3,7 @@ package css
 import (
 	"strconv"
 	"strings"
+	"sync"
 
 	"go-browser/dom"
 )
@@ -29,14 +30,27 @@ const (
 // Selector represents a CSS selector
 type Selector struct {
 	Type        SelectorType
-	Element     string     // tag name for element selector
-	Class       string     // class name for class selector
-	ID          string     // id for id selector
-	Attr        string     // attribute name
-	AttrVal     string     // attribute value
-	Parts       []Selector // for compound selectors
-	PseudoClass string     // :first-child, :last-child, etc.
-	IsChild     bool       // true if this is a child combinator (>)
+	Element     string         // tag name for element selector
+	Class       string         // class name for class selector
+	ID          string         // id for id selector
+	Attr        string         // attribute name
+	AttrVal     string         // attribute value
+	Attrs       []AttrSelector // every [attr...] condition on this compound
+	Parts       []Selector     // for compound selectors
+	PseudoClass string         // :first-child, :last-child, etc.
+	IsChild     bool           // true if this is a child combinator (>)
+
+	// PseudoElement names the ::selection, ::placeholder or ::marker box this
+	// selector styles; such selectors never style the element itself
+	PseudoElement string
+}
+
+// AttrSelector is one [name op "value" flag] condition
+type AttrSelector struct {
+	Name            string
+	Op              string // "", "=", "~=", "|=", "^=", "$=", "*="
+	Value           string
+	CaseInsensitive bool // the i flag
 }
 
 // Specificity represents CSS specificity (a, b, c, d)
@@ -80,7 +94,7 @@ func (s Specificity) Compare(other Specificity) int {
 // ParseSelectors parses a selector list (comma-separated)
 func ParseSelectors(text string) []Selector {
 	var selectors []Selector
-	parts := strings.Split(text, ",")
+	parts := splitTopLevel(text, ',')
 	for _, part := range parts {
 		part = strings.TrimSpace(part)
 		if part != "" {
@@ -97,8 +111,7 @@ func ParseSelector(text string) Selector {
 	text = strings.TrimSpace(text)
 
 	// Check for child combinator (>)
-	if strings.Contains(text, ">") {
-		parts := strings.Split(text, ">")
+	if parts := splitTopLevel(text, '>'); len(parts) > 1 {
 		var selectorParts []Selector
 		for _, p := range parts {
 			p = strings.TrimSpace(p)
@@ -116,8 +129,8 @@ func ParseSelector(text string) Selector {
 	}
 
 	// Check for combinators (space, +, ~)
-	if strings.Contains(text, " ") && !strings.HasPrefix(text, ".") && !strings.HasPrefix(text, "#") {
-		parts := strings.Fields(text)
+	if strings.ContainsAny(text, " \t\n") {
+		parts := splitSelectorFields(text)
 		if len(parts) > 1 {
 			var selectorParts []Selector
 			for _, p := range parts {
@@ -139,17 +152,134 @@ func ParseSelector(text string) Selector {
 	return parseSimpleSelector(text)
 }
 
+// splitSelectorFields splits on whitespace outside of (...), [...] and quotes
+// so that arguments like :nth-child(2n + 1) or [title="a b"] stay in one piece
+func splitSelectorFields(text string) []string {
+	var fields []string
+	start := -1
+	scanSelector(text, func(i int, c byte, depth int) {
+		if (c == ' ' || c == '\t' || c == '\n') && depth == 0 {
+			if start != -1 {
+				fields = append(fields, text[start:i])
+				start = -1
+			}
+			return
+		}
+		if start == -1 {
+			start = i
+		}
+	})
+	if start != -1 {
+		fields = append(fields, text[start:])
+	}
+	return fields
+}
+
+// splitTopLevel splits text on sep where it appears outside of brackets,
+// parentheses and quoted strings
+func splitTopLevel(text string, sep byte) []string {
+	var parts []string
+	start := 0
+	scanSelector(text, func(i int, c byte, depth int) {
+		if c == sep && depth == 0 {
+			parts = append(parts, text[start:i])
+			start = i + 1
+		}
+	})
+	return append(parts, text[start:])
+}
+
+// indexTopLevel returns the first index of sub outside of brackets,
+// parentheses and quoted strings, or -1
+func indexTopLevel(text, sub string) int {
+	idx := -1
+	scanSelector(text, func(i int, c byte, depth int) {
+		if idx == -1 && depth == 0 && strings.HasPrefix(text[i:], sub) {
+			idx = i
+		}
+	})
+	return idx
+}
+
+// scanSelector calls fn for every byte of text that is not inside a quoted
+// string, along with the current ([ nesting depth. Brackets themselves are
+// reported at the outer depth.
+func scanSelector(text string, fn func(i int, c byte, depth int)) {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if quote != 0 {
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '"', '\'':
+			if depth > 0 {
+				quote = c
+				continue
+			}
+		case '(', '[':
+			fn(i, c, depth)
+			depth++
+			continue
+		case ')', ']':
+			if depth > 0 {
+				depth--
+			}
+		}
+		fn(i, c, depth)
+	}
+}
+
 // parseSimpleSelector parses a simple selector (element, class, id, or universal)
+// with an optional trailing ::pseudo-element
 func parseSimpleSelector(text string) Selector {
 	text = strings.TrimSpace(text)
 
+	pseudoElement := ""
+	if idx := indexTopLevel(text, "::"); idx != -1 {
+		pseudoElement = strings.ToLower(text[idx+2:])
+		text = text[:idx]
+	}
+
+	sel := parseCompoundSelector(text)
+	sel.PseudoElement = pseudoElement
+	return sel
+}
+
+// parseCompoundSelector parses the part of a simple selector before any pseudo-element
+func parseCompoundSelector(text string) Selector {
+
 	// Check for pseudo-class
 	pseudoClass := ""
-	if idx := strings.Index(text, ":"); idx != -1 {
+	if idx := indexTopLevel(text, ":"); idx != -1 {
 		pseudoClass = text[idx+1:]
 		text = text[:idx]
 	}
 
+	// Pull out attribute conditions: a[href$=".pdf" i][target]
+	text, attrs := extractAttrSelectors(text)
+
+	sel := parseTypeSelector(text, pseudoClass)
+	if len(attrs) > 0 {
+		sel.Attrs = attrs
+		if sel.Type == SelectorUniversal && text == "" {
+			sel.Type = SelectorAttribute
+			sel.Element = ""
+		}
+		sel.Attr = attrs[0].Name
+		sel.AttrVal = attrs[0].Value
+	}
+	return sel
+}
+
+// parseTypeSelector parses the element/class/id part of a compound selector
+func parseTypeSelector(text, pseudoClass string) Selector {
 	if text == "*" || text == "" {
 		return Selector{Type: SelectorUniversal, Element: "*", PseudoClass: pseudoClass}
 	}
@@ -184,19 +314,128 @@ func parseSimpleSelector(text string) Selector {
 		}
 	}
 
-	// Attribute selector [attr] or [attr=value]
-	if strings.HasPrefix(text, "[") && strings.HasSuffix(text, "]") {
-		content := text[1 : len(text)-1]
-		if eqIdx := strings.Index(content, "="); eqIdx != -1 {
-			attr := content[:eqIdx]
-			val := strings.Trim(content[eqIdx+1:], "\"'")
-			return Selector{Type: SelectorAttribute, Attr: attr, AttrVal: val, PseudoClass: pseudoClass}
+	// Simple element selector
+	return Selector{Type: SelectorElement, Element: strings.ToLower(text), PseudoClass: pseudoClass}
+}
+
+// extractAttrSelectors removes every top-level [...] group from text and
+// returns the remaining text with the parsed conditions
+func extractAttrSelectors(text string) (string, []AttrSelector) {
+	var attrs []AttrSelector
+	var rest strings.Builder
+	start := -1
+	scanSelector(text, func(i int, c byte, depth int) {
+		switch {
+		case c == '[' && depth == 0:
+			start = i + 1
+		case c == ']' && depth == 0 && start != -1:
+			if attr, ok := parseAttrSelector(text[start:i]); ok {
+				attrs = append(attrs, attr)
+			}
+			start = -1
+		case depth == 0 && start == -1:
+			rest.WriteByte(c)
+		}
+	})
+	return rest.String(), attrs
+}
+
+// parseAttrSelector parses the inside of [...]: name, optional operator and
+// value, and an optional i/s case flag. A namespace prefix (ns|name, *|name)
+// is accepted and ignored since HTML attributes carry no namespace.
+func parseAttrSelector(content string) (AttrSelector, bool) {
+	content = strings.TrimSpace(content)
+
+	opIdx := strings.IndexAny(content, "=~|^$*")
+	// ns|name: a | not followed by = is a namespace separator
+	for opIdx != -1 && content[opIdx] == '|' && (opIdx+1 >= len(content) || content[opIdx+1] != '=') {
+		content = content[opIdx+1:]
+		opIdx = strings.IndexAny(content, "=~|^$*")
+	}
+	if opIdx != -1 && content[opIdx] == '*' && opIdx+1 < len(content) && content[opIdx+1] == '|' {
+		content = content[opIdx+2:]
+		opIdx = strings.IndexAny(content, "=~|^$*")
+	}
+
+	if opIdx == -1 {
+		name := strings.ToLower(strings.TrimSpace(content))
+		return AttrSelector{Name: name}, name != ""
+	}
+
+	attr := AttrSelector{Name: strings.ToLower(strings.TrimSpace(content[:opIdx]))}
+	rest := content[opIdx:]
+	if rest[0] == '=' {
+		attr.Op = "="
+	} else if len(rest) > 1 && rest[1] == '=' {
+		attr.Op = rest[:2]
+	} else {
+		return AttrSelector{}, false
+	}
+	rest = strings.TrimSpace(rest[len(attr.Op):])
+
+	// Quoted or bare value, then an optional flag
+	if rest != "" && (rest[0] == '"' || rest[0] == '\'') {
+		end := strings.IndexByte(rest[1:], rest[0])
+		if end == -1 {
+			return AttrSelector{}, false
+		}
+		attr.Value = rest[1 : end+1]
+		rest = rest[end+2:]
+	} else {
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			return AttrSelector{}, false
 		}
-		return Selector{Type: SelectorAttribute, Attr: content, PseudoClass: pseudoClass}
+		attr.Value = fields[0]
+		rest = strings.TrimPrefix(rest, fields[0])
 	}
 
-	// Simple element selector
-	return Selector{Type: SelectorElement, Element: strings.ToLower(text), PseudoClass: pseudoClass}
+	switch strings.ToLower(strings.TrimSpace(rest)) {
+	case "i":
+		attr.CaseInsensitive = true
+	case "", "s":
+	default:
+		return AttrSelector{}, false
+	}
+	return attr, attr.Name != ""
+}
+
+// Matches checks whether the node satisfies the attribute condition
+func (a AttrSelector) Matches(node *dom.Node) bool {
+	actual, ok := node.Attributes[a.Name]
+	if !ok {
+		return false
+	}
+	if a.Op == "" {
+		return true
+	}
+
+	want := a.Value
+	if a.CaseInsensitive {
+		actual = strings.ToLower(actual)
+		want = strings.ToLower(want)
+	}
+
+	switch a.Op {
+	case "=":
+		return actual == want
+	case "~=":
+		for _, word := range strings.Fields(actual) {
+			if word == want {
+				return true
+			}
+		}
+		return false
+	case "|=":
+		return actual == want || strings.HasPrefix(actual, want+"-")
+	case "^=":
+		return want != "" && strings.HasPrefix(actual, want)
+	case "$=":
+		return want != "" && strings.HasSuffix(actual, want)
+	case "*=":
+		return want != "" && strings.Contains(actual, want)
+	}
+	return false
 }
 
 // Matches checks if a selector matches a DOM node
@@ -210,6 +449,13 @@ func (s Selector) Matches(node *dom.Node) bool {
 		return false
 	}
 
+	// Every attribute condition on the compound must hold
+	for _, attr := range s.Attrs {
+		if !attr.Matches(node) {
+			return false
+		}
+	}
+
 	switch s.Type {
 	case SelectorUniversal:
 		return true
@@ -231,11 +477,7 @@ func (s Selector) Matches(node *dom.Node) bool {
 		return node.GetAttr("id") == s.ID
 
 	case SelectorAttribute:
-		attrVal := node.GetAttr(s.Attr)
-		if s.AttrVal != "" {
-			return attrVal == s.AttrVal
-		}
-		return attrVal != ""
+		return len(s.Attrs) > 0
 
 	case SelectorChild:
 		// Child combinator: each part must be a direct child of the previous
@@ -322,29 +564,47 @@ func matchesPseudoClass(node *dom.Node, pseudoClass string) bool {
 			}
 		}
 		return count == 1
+	case "first-of-type":
+		return getTypeIndex(node) == 1
+	case "last-of-type":
+		return getTypeIndexFromEnd(node) == 1
+	case "only-of-type":
+		return getTypeIndex(node) == 1 && getTypeIndexFromEnd(node) == 1
+	case "empty":
+		// Comments are already dropped by the parser, so any child disqualifies
+		return len(node.Children) == 0
 	}
 
-	// Handle :nth-child(n), :even, :odd
-	if strings.HasPrefix(pseudoClass, "nth-child") {
-		// Get element index among siblings
-		idx := getElementIndex(node)
+	// Handle :has(<relative selector list>)
+	if strings.HasPrefix(pseudoClass, "has(") && strings.HasSuffix(pseudoClass, ")") {
+		return matchesHas(node, pseudoClass[4:len(pseudoClass)-1])
+	}
 
-		// Parse the argument: nth-child(2), nth-child(odd), nth-child(even), nth-child(2n+1)
-		inner := strings.TrimPrefix(pseudoClass, "nth-child(")
-		inner = strings.TrimSuffix(inner, ")")
+	// Handle :nth-child(an+b), :nth-of-type(an+b) and their -last- variants
+	if strings.HasPrefix(pseudoClass, "nth-") {
+		open := strings.Index(pseudoClass, "(")
+		if open == -1 || !strings.HasSuffix(pseudoClass, ")") {
+			return false
+		}
+		a, b, ok := parseNth(pseudoClass[open+1 : len(pseudoClass)-1])
+		if !ok {
+			return false
+		}
 
-		switch inner {
-		case "odd":
-			return idx%2 == 1
-		case "even":
-			return idx%2 == 0
+		var idx int
+		switch pseudoClass[:open] {
+		case "nth-child":
+			idx = getElementIndex(node)
+		case "nth-last-child":
+			idx = getElementIndexFromEnd(node)
+		case "nth-of-type":
+			idx = getTypeIndex(node)
+		case "nth-last-of-type":
+			idx = getTypeIndexFromEnd(node)
 		default:
-			// Try parsing as a number
-			if n, err := strconv.Atoi(inner); err == nil {
-				return idx == n
-			}
+			return false
 		}
-		return false
+		return matchesNth(a, b, idx)
 	}
 
 	if pseudoClass == "even" {
@@ -357,6 +617,68 @@ func matchesPseudoClass(node *dom.Node, pseudoClass string) bool {
 	return true // Unknown pseudo-classes pass through
 }
 
+// parseNth parses an an+b expression such as "odd", "3", "2n+1", "-n+3" or "n"
+func parseNth(expr string) (a, b int, ok bool) {
+	expr = strings.ToLower(strings.ReplaceAll(expr, " ", ""))
+
+	switch expr {
+	case "odd":
+		return 2, 1, true
+	case "even":
+		return 2, 0, true
+	case "":
+		return 0, 0, false
+	}
+
+	nIdx := strings.Index(expr, "n")
+	if nIdx == -1 {
+		n, err := strconv.Atoi(expr)
+		if err != nil {
+			return 0, 0, false
+		}
+		return 0, n, true
+	}
+
+	// Coefficient before "n"
+	switch coef := expr[:nIdx]; coef {
+	case "", "+":
+		a = 1
+	case "-":
+		a = -1
+	default:
+		v, err := strconv.Atoi(coef)
+		if err != nil {
+			return 0, 0, false
+		}
+		a = v
+	}
+
+	// Offset after "n"
+	if rest := expr[nIdx+1:]; rest != "" {
+		v, err := strconv.Atoi(strings.TrimPrefix(rest, "+"))
+		if err != nil {
+			return 0, 0, false
+		}
+		b = v
+	}
+	return a, b, true
+}
+
+// matchesNth reports whether the 1-based index idx equals a*n+b for some n >= 0
+func matchesNth(a, b, idx int) bool {
+	if idx <= 0 {
+		return false
+	}
+	if a == 0 {
+		return idx == b
+	}
+	diff := idx - b
+	if diff%a != 0 {
+		return false
+	}
+	return diff/a >= 0
+}
+
 // getElementIndex returns 1-based index of node among element siblings
 func getElementIndex(node *dom.Node) int {
 	if node.Parent == nil {
@@ -374,6 +696,59 @@ func getElementIndex(node *dom.Node) int {
 	return 0
 }
 
+// getElementIndexFromEnd returns 1-based index of node counting from the last element sibling
+func getElementIndexFromEnd(node *dom.Node) int {
+	if node.Parent == nil {
+		return 1
+	}
+	idx := 0
+	for i := len(node.Parent.Children) - 1; i >= 0; i-- {
+		child := node.Parent.Children[i]
+		if child.Type == dom.NodeElement {
+			idx++
+			if child == node {
+				return idx
+			}
+		}
+	}
+	return 0
+}
+
+// getTypeIndex returns 1-based index of node among siblings with the same tag
+func getTypeIndex(node *dom.Node) int {
+	if node.Parent == nil {
+		return 1
+	}
+	idx := 0
+	for _, child := range node.Parent.Children {
+		if child.Type == dom.NodeElement && strings.EqualFold(child.Tag, node.Tag) {
+			idx++
+			if child == node {
+				return idx
+			}
+		}
+	}
+	return 0
+}
+
+// getTypeIndexFromEnd returns 1-based index of node among same-tag siblings counting from the end
+func getTypeIndexFromEnd(node *dom.Node) int {
+	if node.Parent == nil {
+		return 1
+	}
+	idx := 0
+	for i := len(node.Parent.Children) - 1; i >= 0; i-- {
+		child := node.Parent.Children[i]
+		if child.Type == dom.NodeElement && strings.EqualFold(child.Tag, node.Tag) {
+			idx++
+			if child == node {
+				return idx
+			}
+		}
+	}
+	return 0
+}
+
 // nodeHasClass checks if a node has a specific class
 func nodeHasClass(node *dom.Node, className string) bool {
 	classAttr := node.GetAttr("class")
@@ -389,6 +764,15 @@ func nodeHasClass(node *dom.Node, className string) bool {
 	return false
 }
 
+// PseudoElementName returns the pseudo-element targeted by the selector, if any.
+// For compound selectors it is carried by the rightmost part.
+func (s Selector) PseudoElementName() string {
+	if len(s.Parts) > 0 {
+		return s.Parts[len(s.Parts)-1].PseudoElement
+	}
+	return s.PseudoElement
+}
+
 // CalculateSpecificity returns the specificity of a selector
 func (s Selector) CalculateSpecificity() Specificity {
 	spec := Specificity{}
@@ -396,7 +780,7 @@ func (s Selector) CalculateSpecificity() Specificity {
 	switch s.Type {
 	case SelectorID:
 		spec.IDs = 1
-	case SelectorClass, SelectorAttribute:
+	case SelectorClass:
 		spec.Classes = 1
 	case SelectorElement:
 		if s.Element != "" && s.Element != "*" {
@@ -408,7 +792,7 @@ func (s Selector) CalculateSpecificity() Specificity {
 		if s.ID != "" {
 			spec.IDs = 1
 		}
-	case SelectorDescendant:
+	case SelectorDescendant, SelectorChild:
 		for _, part := range s.Parts {
 			partSpec := part.CalculateSpecificity()
 			spec.IDs += partSpec.IDs
@@ -417,5 +801,189 @@ func (s Selector) CalculateSpecificity() Specificity {
 		}
 	}
 
+	spec.Classes += len(s.Attrs)
+	if s.PseudoElement != "" {
+		spec.Elements++
+	}
+
 	return spec
 }
+
+// ======================================================================================
+// RELATIONAL PSEUDO-CLASS :has()
+// ======================================================================================
+
+// relativeSelector is one entry of a :has() argument list, e.g. "> img"
+type relativeSelector struct {
+	Combinator string // "" (descendant), ">", "+" or "~"
+	Selector   Selector
+}
+
+// hasCache memoizes :has() results per subject node and argument. Answers
+// only depend on the subject's subtree and following siblings, so a DOM
+// change only invalidates the entries of the changed node's ancestors (and
+// their preceding siblings); see InvalidateHasCache.
+var hasCache = struct {
+	sync.Mutex
+	parsed  map[string][]relativeSelector
+	results map[*dom.Node]map[string]bool
+}{
+	parsed:  make(map[string][]relativeSelector),
+	results: make(map[*dom.Node]map[string]bool),
+}
+
+// ResetHasCache drops every memoized :has() result
+func ResetHasCache() {
+	hasCache.Lock()
+	hasCache.results = make(map[*dom.Node]map[string]bool)
+	hasCache.Unlock()
+}
+
+// InvalidateHasCache drops memoized :has() results that may depend on node:
+// those of its ancestors and of the siblings preceding each of them
+func InvalidateHasCache(node *dom.Node) {
+	hasCache.Lock()
+	defer hasCache.Unlock()
+	for current := node; current != nil; current = current.Parent {
+		delete(hasCache.results, current)
+		if current.Parent == nil {
+			continue
+		}
+		for _, sib := range current.Parent.Children {
+			if sib == current {
+				break
+			}
+			delete(hasCache.results, sib)
+		}
+	}
+}
+
+// matchesHas reports whether any relative selector in args matches relative to node
+func matchesHas(node *dom.Node, args string) bool {
+	hasCache.Lock()
+	if cached, ok := hasCache.results[node][args]; ok {
+		hasCache.Unlock()
+		return cached
+	}
+	selectors, ok := hasCache.parsed[args]
+	if !ok {
+		selectors = parseRelativeSelectors(args)
+		hasCache.parsed[args] = selectors
+	}
+	hasCache.Unlock()
+
+	result := false
+	for _, rel := range selectors {
+		if matchesRelative(node, rel) {
+			result = true
+			break
+		}
+	}
+
+	hasCache.Lock()
+	if hasCache.results[node] == nil {
+		hasCache.results[node] = make(map[string]bool)
+	}
+	hasCache.results[node][args] = result
+	hasCache.Unlock()
+	return result
+}
+
+// parseRelativeSelectors parses a :has() argument list. Nested :has() is
+// invalid per spec and such entries are dropped.
+func parseRelativeSelectors(args string) []relativeSelector {
+	var out []relativeSelector
+	for _, part := range splitTopLevel(args, ',') {
+		part = strings.TrimSpace(part)
+		if part == "" || strings.Contains(part, ":has(") {
+			continue
+		}
+		rel := relativeSelector{}
+		if c := part[0]; c == '>' || c == '+' || c == '~' {
+			rel.Combinator = string(c)
+			part = strings.TrimSpace(part[1:])
+		}
+		if part == "" {
+			continue
+		}
+		rel.Selector = ParseSelector(part)
+		out = append(out, rel)
+	}
+	return out
+}
+
+// matchesRelative checks one relative selector against the elements reachable
+// from scope through its leading combinator
+func matchesRelative(scope *dom.Node, rel relativeSelector) bool {
+	switch rel.Combinator {
+	case ">":
+		for _, child := range scope.Children {
+			if matchesScoped(rel.Selector, child, scope) {
+				return true
+			}
+		}
+	case "+":
+		if next := scope.NextSibling(); next != nil {
+			for next != nil && next.Type != dom.NodeElement {
+				next = next.NextSibling()
+			}
+			return next != nil && rel.Selector.Matches(next)
+		}
+	case "~":
+		for next := scope.NextSibling(); next != nil; next = next.NextSibling() {
+			if rel.Selector.Matches(next) {
+				return true
+			}
+		}
+	default:
+		return anyDescendantMatches(scope, scope, rel.Selector)
+	}
+	return false
+}
+
+func anyDescendantMatches(node, scope *dom.Node, sel Selector) bool {
+	for _, child := range node.Children {
+		if child.Type != dom.NodeElement {
+			continue
+		}
+		if matchesScoped(sel, child, scope) || anyDescendantMatches(child, scope, sel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesScoped is Matches for a selector anchored at scope: ancestors
+// required by descendant/child combinators must lie inside scope
+func matchesScoped(s Selector, node, scope *dom.Node) bool {
+	if s.Type != SelectorDescendant && s.Type != SelectorChild {
+		return s.Matches(node)
+	}
+	if len(s.Parts) == 0 || !s.Parts[len(s.Parts)-1].Matches(node) {
+		return false
+	}
+
+	current := node.Parent
+	for i := len(s.Parts) - 2; i >= 0; i-- {
+		if s.Type == SelectorChild {
+			if current == nil || current == scope || !s.Parts[i].Matches(current) {
+				return false
+			}
+			current = current.Parent
+			continue
+		}
+		found := false
+		for current != nil && current != scope {
+			matched := s.Parts[i].Matches(current)
+			current = current.Parent
+			if matched {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
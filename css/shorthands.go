@@ -0,0 +1,342 @@
+package css
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ======================================================================================
+// SHORTHANDS
+// Shorthand properties are expanded into their longhands before they apply,
+// so a shorthand and the longhands written after it combine in order. As in
+// browsers, a shorthand resets the longhands it leaves out to their initial
+// values: background: red removes a background image. An invalid shorthand
+// is dropped whole. Logical properties map to the physical sides of
+// left-to-right horizontal text.
+// ======================================================================================
+
+// boxSideNames are the sides of a box in the order box shorthands list them
+var boxSideNames = [4]string{"top", "right", "bottom", "left"}
+
+// fontSystemKeywords are the system fonts font may name; they keep the
+// page's font
+var fontSystemKeywords = map[string]bool{
+	"caption": true, "icon": true, "menu": true,
+	"message-box": true, "small-caption": true, "status-bar": true,
+}
+
+// fontStretchKeywords are the widths font may give, which aren't drawn
+var fontStretchKeywords = map[string]bool{
+	"ultra-condensed": true, "extra-condensed": true, "condensed": true, "semi-condensed": true,
+	"semi-expanded": true, "expanded": true, "extra-expanded": true, "ultra-expanded": true,
+}
+
+// expandShorthand returns the longhand declarations a shorthand property
+// stands for, and false when property is not a shorthand. An invalid value
+// gives no longhands.
+func expandShorthand(property, value string) ([]Declaration, bool) {
+	switch property {
+	case "font":
+		return expandFont(value), true
+	case "background":
+		return expandBackground(value), true
+	case "margin", "padding":
+		return expandBoxSides(property+"-%s", value), true
+	case "inset":
+		return expandBoxSides("%s", value), true
+	case "border-width", "border-style", "border-color":
+		return expandBoxSides("border-%s-"+strings.TrimPrefix(property, "border-"), value), true
+	case "border":
+		var decls []Declaration
+		for _, side := range boxSideNames {
+			decls = append(decls, expandBorderSide(side, value)...)
+		}
+		return decls, true
+	case "border-top", "border-right", "border-bottom", "border-left":
+		return expandBorderSide(strings.TrimPrefix(property, "border-"), value), true
+	case "margin-inline", "padding-inline", "margin-block", "padding-block":
+		return expandLogicalPair(property, value), true
+	case "margin-inline-start", "margin-inline-end", "margin-block-start", "margin-block-end",
+		"padding-inline-start", "padding-inline-end", "padding-block-start", "padding-block-end",
+		"inset-inline-start", "inset-inline-end", "inset-block-start", "inset-block-end":
+		return []Declaration{{Property: physicalSide(property), Value: value}}, true
+	case "inset-inline", "inset-block":
+		return expandLogicalPair(property, value), true
+	}
+	return nil, false
+}
+
+// keywordLonghands sets each of the longhands to a CSS-wide keyword
+func keywordLonghands(keyword string, longhands ...string) []Declaration {
+	decls := make([]Declaration, len(longhands))
+	for i, longhand := range longhands {
+		decls[i] = Declaration{Property: longhand, Value: keyword}
+	}
+	return decls
+}
+
+// expandBoxSides expands the one to four values of a box shorthand, such as
+// margin: 0 auto, into a longhand per side named by format
+func expandBoxSides(format, value string) []Declaration {
+	values := splitSelectorFields(value)
+	var sides [4]string
+	switch len(values) {
+	case 1:
+		sides = [4]string{values[0], values[0], values[0], values[0]}
+	case 2:
+		sides = [4]string{values[0], values[1], values[0], values[1]}
+	case 3:
+		sides = [4]string{values[0], values[1], values[2], values[1]}
+	case 4:
+		sides = [4]string{values[0], values[1], values[2], values[3]}
+	default:
+		return nil
+	}
+	decls := make([]Declaration, 4)
+	for i, side := range boxSideNames {
+		decls[i] = Declaration{Property: strings.Replace(format, "%s", side, 1), Value: sides[i]}
+	}
+	return decls
+}
+
+// expandLogicalPair expands margin-inline and its like, one value for both
+// ends or start then end, into the physical sides
+func expandLogicalPair(property, value string) []Declaration {
+	values := splitSelectorFields(value)
+	if len(values) == 0 || len(values) > 2 {
+		return nil
+	}
+	start, end := values[0], values[len(values)-1]
+	return []Declaration{
+		{Property: physicalSide(property + "-start"), Value: start},
+		{Property: physicalSide(property + "-end"), Value: end},
+	}
+}
+
+// physicalSide maps a logical property, such as padding-inline-start, to its
+// physical side in horizontal left-to-right text
+func physicalSide(property string) string {
+	replacer := strings.NewReplacer(
+		"-inline-start", "-left", "-inline-end", "-right",
+		"-block-start", "-top", "-block-end", "-bottom",
+	)
+	property = replacer.Replace(property)
+	return strings.TrimPrefix(property, "inset-")
+}
+
+// expandBorderSide expands border-top and its like, a width, a line style
+// and a color in any order. A line style without a width is medium.
+func expandBorderSide(side, value string) []Declaration {
+	prefix := "border-" + side + "-"
+	if cssWideKeywords[strings.ToLower(value)] {
+		return keywordLonghands(value, prefix+"width", prefix+"style", prefix+"color")
+	}
+	width, lineStyle, lineColor := "", "", ""
+	for _, part := range splitSelectorFields(strings.ToLower(value)) {
+		switch {
+		case part == "none" || part == "hidden" || borderStyles[part]:
+			lineStyle = part
+		case borderKeywordWidths[part] != "":
+			width = part
+		default:
+			if _, _, ok := ParseLength(part); ok {
+				width = part
+			} else if isColorValue(part) {
+				lineColor = part
+			} else {
+				return nil
+			}
+		}
+	}
+	if width == "" && borderStyles[lineStyle] {
+		width = "medium"
+	}
+
+	var decls []Declaration
+	if width != "" {
+		decls = append(decls, Declaration{Property: prefix + "width", Value: width})
+	}
+	if lineStyle != "" {
+		decls = append(decls, Declaration{Property: prefix + "style", Value: lineStyle})
+	}
+	if lineColor != "" {
+		decls = append(decls, Declaration{Property: prefix + "color", Value: lineColor})
+	}
+	return decls
+}
+
+// expandFont expands font: [style] [variant] [weight] [stretch] size[/line-height]
+// family, as in font: italic bold 16px/1.5 Arial, sans-serif. The style,
+// weight and line height it leaves out go back to normal.
+func expandFont(value string) []Declaration {
+	if cssWideKeywords[strings.ToLower(value)] {
+		return keywordLonghands(value, "font-style", "font-weight", "font-size", "line-height", "font-family")
+	}
+	parts := splitSelectorFields(value)
+	if len(parts) == 1 && fontSystemKeywords[strings.ToLower(parts[0])] {
+		return nil
+	}
+
+	fontStyle, weight := "normal", "normal"
+	for i, part := range parts {
+		lower := strings.ToLower(part)
+		switch {
+		case lower == "normal" || lower == "small-caps" || fontStretchKeywords[lower]:
+			continue
+		case lower == "italic" || lower == "oblique":
+			fontStyle = lower
+			continue
+		case lower == "bold" || lower == "bolder" || lower == "lighter":
+			weight = lower
+			continue
+		case isFontWeightNumber(lower):
+			weight = lower
+			continue
+		}
+
+		// The size, with the line height after a slash, then the family
+		size, lineHeight, hasSlash := strings.Cut(lower, "/")
+		rest := parts[i+1:]
+		if !hasSlash && len(rest) > 0 && strings.HasPrefix(rest[0], "/") {
+			hasSlash = true
+			lineHeight, rest = strings.TrimPrefix(rest[0], "/"), rest[1:]
+			if lineHeight == "" && len(rest) > 0 {
+				lineHeight, rest = rest[0], rest[1:]
+			}
+		}
+		if !isFontSize(size) || (hasSlash && lineHeight == "") || len(rest) == 0 {
+			return nil
+		}
+		if lineHeight == "" {
+			lineHeight = "normal"
+		}
+		return []Declaration{
+			{Property: "font-style", Value: fontStyle},
+			{Property: "font-weight", Value: weight},
+			{Property: "font-size", Value: size},
+			{Property: "line-height", Value: lineHeight},
+			{Property: "font-family", Value: strings.Join(rest, " ")},
+		}
+	}
+	return nil
+}
+
+// isFontWeightNumber reports whether s is a numeric font weight, 1 to 1000
+func isFontWeightNumber(s string) bool {
+	n, err := strconv.ParseFloat(s, 64)
+	return err == nil && n >= 1 && n <= 1000
+}
+
+// isDimension reports whether s is a length or percentage; a bare number
+// other than 0 is not
+func isDimension(s string) bool {
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return s == "0"
+	}
+	_, _, ok := ParseLength(s)
+	return ok
+}
+
+// isFontSize reports whether s is a font size: a length, a percentage or a
+// size keyword
+func isFontSize(s string) bool {
+	return fontSizeKeywords[s] > 0 || s == "smaller" || s == "larger" || isDimension(s)
+}
+
+// expandBackground expands background: a color, an image, a repeat and a
+// position with an optional / size, in any order. Of several comma
+// separated layers the first gives the image and the last the color.
+func expandBackground(value string) []Declaration {
+	if cssWideKeywords[strings.ToLower(value)] {
+		return keywordLonghands(value, "background-color", "background-image", "background-repeat",
+			"background-position", "background-size")
+	}
+	layers := splitTopLevel(value, ',')
+	image, repeat, size, bgColor := "none", "repeat", "auto", "transparent"
+	var position []string
+
+	for i, layer := range layers {
+		last := i == len(layers)-1
+		parts := splitSelectorFields(strings.TrimSpace(layer))
+		for j := 0; j < len(parts); j++ {
+			part := parts[j]
+			lower := strings.ToLower(part)
+			switch {
+			case lower == "none":
+			case strings.HasPrefix(lower, "url(") || strings.Contains(lower, "gradient("):
+				if i == 0 {
+					image = part
+				}
+			case lower == "repeat" || lower == "repeat-x" || lower == "repeat-y" || lower == "no-repeat" ||
+				lower == "space" || lower == "round":
+				if i == 0 {
+					repeat = lower
+				}
+			case lower == "scroll" || lower == "fixed" || lower == "local" ||
+				lower == "border-box" || lower == "padding-box" || lower == "content-box" || lower == "text":
+				// Attachment, origin and clip aren't drawn
+			case isBackgroundPosition(lower):
+				if i == 0 {
+					position = append(position, lower)
+				}
+			case lower == "/" || strings.HasPrefix(lower, "/"):
+				// The size follows the position after a slash
+				sizeParts := []string{strings.TrimPrefix(lower, "/")}
+				if sizeParts[0] == "" {
+					sizeParts = nil
+				}
+				for j+1 < len(parts) && len(sizeParts) < 2 && isBackgroundSize(strings.ToLower(parts[j+1])) {
+					j++
+					sizeParts = append(sizeParts, strings.ToLower(parts[j]))
+				}
+				if len(sizeParts) == 0 {
+					return nil
+				}
+				if i == 0 {
+					size = strings.Join(sizeParts, " ")
+				}
+			default:
+				if pos, sz, ok := strings.Cut(lower, "/"); ok && isBackgroundPosition(pos) {
+					// A position and size written together: center/cover
+					if i == 0 {
+						position = append(position, pos)
+						size = sz
+					}
+					continue
+				}
+				if !isColorValue(part) || !last {
+					return nil
+				}
+				bgColor = part
+			}
+		}
+	}
+	if len(position) == 0 {
+		position = []string{"0%", "0%"}
+	}
+	return []Declaration{
+		{Property: "background-color", Value: bgColor},
+		{Property: "background-image", Value: image},
+		{Property: "background-repeat", Value: repeat},
+		{Property: "background-position", Value: strings.Join(position, " ")},
+		{Property: "background-size", Value: size},
+	}
+}
+
+// isBackgroundPosition reports whether s is one part of a background position
+func isBackgroundPosition(s string) bool {
+	switch s {
+	case "left", "right", "top", "bottom", "center":
+		return true
+	}
+	return isDimension(s)
+}
+
+// isBackgroundSize reports whether s is one part of a background size
+func isBackgroundSize(s string) bool {
+	switch s {
+	case "auto", "cover", "contain":
+		return true
+	}
+	return isDimension(s)
+}
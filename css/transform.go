@@ -0,0 +1,290 @@
+package css
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ======================================================================================
+// TRANSFORMS
+// transform is parsed into a list of 2D functions. Translations keep their
+// lengths, since percentages refer to the box's own size, and the list is
+// turned into one Matrix at layout.
+// ======================================================================================
+
+// Matrix is a 2D affine transform, as in matrix(A, B, C, D, E, F): a point
+// (x, y) maps to (A*x + C*y + E, B*x + D*y + F)
+type Matrix struct {
+	A, B, C, D, E, F float64
+}
+
+// Identity returns the matrix that leaves points where they are
+func Identity() Matrix {
+	return Matrix{A: 1, D: 1}
+}
+
+// Translation returns the matrix moving points by (x, y)
+func Translation(x, y float64) Matrix {
+	return Matrix{A: 1, D: 1, E: x, F: y}
+}
+
+// Multiply returns m·n, the transform applying n first and then m
+func (m Matrix) Multiply(n Matrix) Matrix {
+	return Matrix{
+		A: m.A*n.A + m.C*n.B,
+		B: m.B*n.A + m.D*n.B,
+		C: m.A*n.C + m.C*n.D,
+		D: m.B*n.C + m.D*n.D,
+		E: m.A*n.E + m.C*n.F + m.E,
+		F: m.B*n.E + m.D*n.F + m.F,
+	}
+}
+
+// Apply maps the point (x, y)
+func (m Matrix) Apply(x, y float64) (float64, float64) {
+	return m.A*x + m.C*y + m.E, m.B*x + m.D*y + m.F
+}
+
+// Invert returns the inverse transform; ok is false when m flattens the
+// plane (scale(0)) and has none
+func (m Matrix) Invert() (inv Matrix, ok bool) {
+	det := m.A*m.D - m.B*m.C
+	if det == 0 || math.IsNaN(det) {
+		return Matrix{}, false
+	}
+	return Matrix{
+		A: m.D / det,
+		B: -m.B / det,
+		C: -m.C / det,
+		D: m.A / det,
+		E: (m.C*m.F - m.D*m.E) / det,
+		F: (m.B*m.E - m.A*m.F) / det,
+	}, true
+}
+
+// IsIdentity reports whether m leaves every point where it is
+func (m Matrix) IsIdentity() bool {
+	return m == Identity()
+}
+
+// TransformFunction is one function of a transform list. translateX(),
+// scaleY(), skewX() and the like are stored as their two-axis forms.
+type TransformFunction struct {
+	Name   string    // translate, scale, rotate, skew or matrix
+	X, Y   Length    // translate
+	Values []float64 // scale factors, angles in radians, or the six of matrix()
+}
+
+// ParseTransform parses a transform value; none yields an empty list. The
+// whole value is invalid when one of its functions is.
+func ParseTransform(value string) ([]TransformFunction, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "none" || value == "" {
+		return nil, true
+	}
+	var fns []TransformFunction
+	for _, field := range splitSelectorFields(value) {
+		open := strings.IndexByte(field, '(')
+		if open <= 0 || !strings.HasSuffix(field, ")") {
+			return nil, false
+		}
+		fn, ok := parseTransformFunction(field[:open], splitTopLevel(field[open+1:len(field)-1], ','))
+		if !ok {
+			return nil, false
+		}
+		fns = append(fns, fn)
+	}
+	return fns, true
+}
+
+// parseTransformFunction parses one function from its name and arguments
+func parseTransformFunction(name string, args []string) (TransformFunction, bool) {
+	for i := range args {
+		args[i] = strings.TrimSpace(args[i])
+	}
+	switch name {
+	case "translate", "translatex", "translatey":
+		if len(args) < 1 || len(args) > 2 || (name != "translate" && len(args) != 1) {
+			return TransformFunction{}, false
+		}
+		lengths := make([]Length, len(args))
+		for i, arg := range args {
+			n, unit, ok := ParseLength(arg)
+			if !ok {
+				return TransformFunction{}, false
+			}
+			lengths[i] = Length{Value: n, Unit: unit}
+		}
+		fn := TransformFunction{Name: "translate", X: lengths[0], Y: Px(0)}
+		switch {
+		case name == "translatey":
+			fn.X, fn.Y = Px(0), lengths[0]
+		case len(lengths) == 2:
+			fn.Y = lengths[1]
+		}
+		return fn, true
+
+	case "scale", "scalex", "scaley":
+		nums, ok := parseTransformNumbers(args, 1, 2, parseScale)
+		if !ok || (name != "scale" && len(nums) != 1) {
+			return TransformFunction{}, false
+		}
+		switch {
+		case name == "scalex":
+			nums = []float64{nums[0], 1}
+		case name == "scaley":
+			nums = []float64{1, nums[0]}
+		case len(nums) == 1:
+			nums = []float64{nums[0], nums[0]}
+		}
+		return TransformFunction{Name: "scale", Values: nums}, true
+
+	case "rotate":
+		nums, ok := parseTransformNumbers(args, 1, 1, ParseAngle)
+		return TransformFunction{Name: "rotate", Values: nums}, ok
+
+	case "skew", "skewx", "skewy":
+		nums, ok := parseTransformNumbers(args, 1, 2, ParseAngle)
+		if !ok || (name != "skew" && len(nums) != 1) {
+			return TransformFunction{}, false
+		}
+		switch {
+		case name == "skewy":
+			nums = []float64{0, nums[0]}
+		case len(nums) == 1:
+			nums = []float64{nums[0], 0}
+		}
+		return TransformFunction{Name: "skew", Values: nums}, true
+
+	case "matrix":
+		nums, ok := parseTransformNumbers(args, 6, 6, func(s string) (float64, bool) {
+			n, err := strconv.ParseFloat(s, 64)
+			return n, err == nil
+		})
+		return TransformFunction{Name: "matrix", Values: nums}, ok
+	}
+	return TransformFunction{}, false
+}
+
+// parseTransformNumbers parses between least and most arguments with parse
+func parseTransformNumbers(args []string, least, most int, parse func(string) (float64, bool)) ([]float64, bool) {
+	if len(args) < least || len(args) > most {
+		return nil, false
+	}
+	nums := make([]float64, len(args))
+	for i, arg := range args {
+		n, ok := parse(arg)
+		if !ok {
+			return nil, false
+		}
+		nums[i] = n
+	}
+	return nums, true
+}
+
+// parseScale parses a scale factor, a number or a percentage
+func parseScale(s string) (float64, bool) {
+	if pct, ok := strings.CutSuffix(s, "%"); ok {
+		n, err := strconv.ParseFloat(pct, 64)
+		return n / 100, err == nil
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	return n, err == nil
+}
+
+// ParseAngle parses an angle in deg, rad, grad or turn into radians; a bare
+// 0 is allowed
+func ParseAngle(s string) (float64, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "0" {
+		return 0, true
+	}
+	units := []struct {
+		suffix  string
+		radians float64
+	}{
+		{"grad", math.Pi / 200},
+		{"deg", math.Pi / 180},
+		{"rad", 1},
+		{"turn", 2 * math.Pi},
+	}
+	for _, u := range units {
+		if num, ok := strings.CutSuffix(s, u.suffix); ok {
+			n, err := strconv.ParseFloat(num, 64)
+			return n * u.radians, err == nil
+		}
+	}
+	return 0, false
+}
+
+// TransformMatrix combines fns into one matrix for a box of size w × h,
+// translations taking ems against fontSize, percentages against the box and
+// viewport units against viewport. The matrix works around the box's
+// top-left corner.
+func TransformMatrix(fns []TransformFunction, w, h, fontSize float64, viewport Viewport) Matrix {
+	m := Identity()
+	for _, fn := range fns {
+		var step Matrix
+		switch fn.Name {
+		case "translate":
+			step = Translation(fn.X.Resolve(fontSize, w, viewport), fn.Y.Resolve(fontSize, h, viewport))
+		case "scale":
+			step = Matrix{A: fn.Values[0], D: fn.Values[1]}
+		case "rotate":
+			sin, cos := math.Sincos(fn.Values[0])
+			step = Matrix{A: cos, B: sin, C: -sin, D: cos}
+		case "skew":
+			step = Matrix{A: 1, B: math.Tan(fn.Values[1]), C: math.Tan(fn.Values[0]), D: 1}
+		case "matrix":
+			v := fn.Values
+			step = Matrix{A: v[0], B: v[1], C: v[2], D: v[3], E: v[4], F: v[5]}
+		default:
+			continue
+		}
+		m = m.Multiply(step)
+	}
+	return m
+}
+
+// ParseTransformOrigin parses transform-origin into its x and y; a third
+// (z) value is ignored
+func ParseTransformOrigin(value string) (x, y Length, ok bool) {
+	fields := strings.Fields(strings.ToLower(value))
+	if len(fields) == 0 || len(fields) > 3 {
+		return Length{}, Length{}, false
+	}
+	keyword := map[string]Length{
+		"left": Percent(0), "center": Percent(50), "right": Percent(100),
+		"top": Percent(0), "bottom": Percent(100),
+	}
+	parse := func(s string) (Length, bool) {
+		if l, ok := keyword[s]; ok {
+			return l, true
+		}
+		n, unit, ok := ParseLength(s)
+		return Length{Value: n, Unit: unit}, ok
+	}
+
+	x, y = Percent(50), Percent(50)
+	first, ok := parse(fields[0])
+	if !ok {
+		return Length{}, Length{}, false
+	}
+	// A lone top or bottom, or a vertical keyword first, names the y
+	vertical := fields[0] == "top" || fields[0] == "bottom"
+	if len(fields) == 1 {
+		if vertical {
+			return x, first, true
+		}
+		return first, y, true
+	}
+	second, ok := parse(fields[1])
+	if !ok {
+		return Length{}, Length{}, false
+	}
+	if vertical || fields[1] == "left" || fields[1] == "right" {
+		return second, first, true
+	}
+	return first, second, true
+}
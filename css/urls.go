@@ -0,0 +1,68 @@
+package css
+
+import (
+	"strings"
+)
+
+// ======================================================================================
+// URL RESOLUTION
+// url() references resolve against the stylesheet they appear in: an external
+// sheet's own URL, or the document's base URL for <style> blocks
+// ======================================================================================
+
+// ResolveURLs rewrites every relative url() in the sheet's declarations to an
+// absolute URL, resolved against base
+func (s *Stylesheet) ResolveURLs(base string) {
+	if s == nil || base == "" {
+		return
+	}
+	for i := range s.Rules {
+		decls := s.Rules[i].Declarations
+		for j := range decls {
+			decls[j].Value = ResolveCSSURLs(decls[j].Value, base)
+		}
+	}
+}
+
+// ResolveCSSURLs resolves the url() references in a property value against
+// base. data: URLs and references that can't be resolved are left untouched.
+func ResolveCSSURLs(value, base string) string {
+	lower := strings.ToLower(value)
+	if base == "" || !strings.Contains(lower, "url(") {
+		return value
+	}
+
+	var sb strings.Builder
+	for {
+		start := strings.Index(lower, "url(")
+		if start == -1 {
+			break
+		}
+		end := strings.IndexByte(lower[start:], ')')
+		if end == -1 {
+			break
+		}
+		end += start
+
+		ref := ExtractURL(value[start : end+1])
+		sb.WriteString(value[:start])
+		if abs := resolveURL(ref, base); abs != "" && !strings.HasPrefix(ref, "data:") {
+			sb.WriteString(`url("` + abs + `")`)
+		} else {
+			sb.WriteString(value[start : end+1])
+		}
+		value, lower = value[end+1:], lower[end+1:]
+	}
+	sb.WriteString(value)
+	return sb.String()
+}
+
+// ExtractURL returns the reference inside url(...), without its quotes
+func ExtractURL(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) < 5 || !strings.EqualFold(value[:4], "url(") || !strings.HasSuffix(value, ")") {
+		return ""
+	}
+	ref := strings.TrimSpace(value[4 : len(value)-1])
+	return strings.Trim(ref, `"'`)
+}
@@ -0,0 +1,32 @@
+package css
+
+import "sync/atomic"
+
+// ======================================================================================
+// USER STYLESHEET
+// The user may keep a stylesheet of their own that applies to every page,
+// in the user origin: their normal rules give way to the page's, their
+// !important ones win over it.
+// ======================================================================================
+
+// userStylesheet is the user's stylesheet; nil for none
+var userStylesheet atomic.Pointer[Stylesheet]
+
+// SetUserStylesheet parses cssText as the user's stylesheet for the pages
+// styled from now on; "" removes it
+func SetUserStylesheet(cssText string) {
+	if cssText == "" {
+		userStylesheet.Store(nil)
+		return
+	}
+	sheet := ParseStylesheet(cssText)
+	sheet.Origin = OriginUser
+	// Built now, since pages are styled from several goroutines
+	sheet.ruleIndex()
+	userStylesheet.Store(sheet)
+}
+
+// UserStylesheet returns the user's stylesheet, or nil when there is none
+func UserStylesheet() *Stylesheet {
+	return userStylesheet.Load()
+}
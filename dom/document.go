@@ -0,0 +1,361 @@
+package dom
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ======================================================================================
+// DOCUMENT
+// ======================================================================================
+
+// Compatibility modes reported by Document.CompatMode
+const (
+	CompatStandards = "CSS1Compat" // <!DOCTYPE html>
+	CompatQuirks    = "BackCompat" // missing or legacy doctype
+)
+
+// Document.ReadyState values, in the order a page load goes through them
+const (
+	ReadyLoading     = "loading"     // being parsed, its scripts running
+	ReadyInteractive = "interactive" // parsed and its scripts run
+	ReadyComplete    = "complete"    // its images and stylesheets loaded too
+)
+
+// CharacterSet is the encoding documents are decoded with: every page is
+// read as UTF-8
+const CharacterSet = "UTF-8"
+
+// Document owns a DOM tree and the document-level state. Its Node is the
+// root of the tree (a NodeDocument) whose only element child is <html>.
+type Document struct {
+	Node            *Node
+	Doctype         string // doctype name ("html"), empty when missing
+	DocumentElement *Node  // <html>
+	Head            *Node
+	Body            *Node  // <body>, or <frameset> for frame documents
+	URL             string // address the document was loaded from
+	BaseURL         string // URL relative references resolve against; see SetURL
+	CompatMode      string
+	ReadyState      string // document.readyState; see ReadyLoading
+
+	nextNodeID int
+	nodesByID  map[int]*Node
+}
+
+// NewDocument creates an empty document with <html>, <head> and <body>
+func NewDocument() *Document {
+	doc := &Document{
+		Node:       &Node{Type: NodeDocument, Tag: "#document", Children: []*Node{}},
+		CompatMode: CompatQuirks,
+		ReadyState: ReadyLoading,
+	}
+	doc.Node.document = doc
+	doc.normalize()
+	doc.AssignNodeIDs(doc.Node)
+	return doc
+}
+
+// ParseDocument parses an HTML string into a Document. Missing <html>,
+// <head> and <body> elements are created, as browsers do.
+func ParseDocument(html string) *Document {
+	doc := &Document{
+		Node:       &Node{Type: NodeDocument, Tag: "#document", Children: []*Node{}},
+		ReadyState: ReadyLoading,
+	}
+	doc.Node.document = doc
+
+	doc.Doctype, doc.CompatMode = parseDoctype(html)
+	parseInto(doc.Node, html)
+	doc.normalize()
+	doc.AssignNodeIDs(doc.Node)
+	return doc
+}
+
+// OwnerDocument returns the Document whose tree contains n, or nil for
+// detached nodes
+func (n *Node) OwnerDocument() *Document {
+	for n != nil {
+		if n.document != nil {
+			return n.document
+		}
+		n = n.Parent
+	}
+	return nil
+}
+
+// AssignNodeIDs gives node and its descendants the next free node IDs, in
+// document order. Nodes that already have one keep it, so IDs stay stable
+// across mutations; call it for nodes inserted after parsing.
+func (d *Document) AssignNodeIDs(node *Node) {
+	if node == nil {
+		return
+	}
+	if d.nodesByID == nil {
+		d.nodesByID = make(map[int]*Node)
+	}
+	if node.NodeID == 0 {
+		d.nextNodeID++
+		node.NodeID = d.nextNodeID
+	}
+	d.nodesByID[node.NodeID] = node
+	for _, child := range node.Children {
+		d.AssignNodeIDs(child)
+	}
+}
+
+// NodeByID returns the node with the given node ID, or nil if it doesn't
+// exist or was removed from the document
+func (d *Document) NodeByID(id int) *Node {
+	node := d.nodesByID[id]
+	if node == nil || node.OwnerDocument() != d {
+		return nil
+	}
+	return node
+}
+
+// Title returns the text of the document's <title>, whitespace-collapsed
+func (d *Document) Title() string {
+	titles := d.Node.GetElementsByTagName("title")
+	if len(titles) == 0 {
+		return ""
+	}
+	return strings.Join(strings.Fields(titles[0].TextContent()), " ")
+}
+
+// SetTitle replaces the text of the document's <title>, adding one to the
+// <head> if there is none
+func (d *Document) SetTitle(title string) {
+	var el *Node
+	if titles := d.Node.GetElementsByTagName("title"); len(titles) > 0 {
+		el = titles[0]
+	} else if d.Head != nil {
+		el = NewElement("title")
+		d.Head.AppendChild(el)
+		d.AssignNodeIDs(el)
+	} else {
+		return
+	}
+	el.Children = nil
+	if title != "" {
+		text := NewText(title)
+		el.AppendChild(text)
+		d.AssignNodeIDs(text)
+	}
+}
+
+// Meta returns the content of the first <meta> with the given name, and
+// whether there is one
+func (d *Document) Meta(name string) (string, bool) {
+	for _, meta := range d.Node.GetElementsByTagName("meta") {
+		if strings.EqualFold(strings.TrimSpace(meta.GetAttr("name")), name) {
+			return meta.GetAttr("content"), true
+		}
+	}
+	return "", false
+}
+
+// SetURL records the URL the document was loaded from: its base URL is
+// that URL, or the href of its first <base> with one resolved against it
+func (d *Document) SetURL(docURL string) {
+	d.URL, d.BaseURL = docURL, docURL
+	for _, base := range d.Node.GetElementsByTagName("base") {
+		if base.HasAttr("href") {
+			d.BaseURL = resolveReference(strings.TrimSpace(base.GetAttr("href")), docURL)
+			return
+		}
+	}
+}
+
+// ResolveURL makes ref absolute against the document's base URL
+func (d *Document) ResolveURL(ref string) string {
+	return resolveReference(strings.TrimSpace(ref), d.BaseURL)
+}
+
+// resolveReference resolves ref against base; either failing to parse
+// leaves ref as it is
+func resolveReference(ref, base string) string {
+	b, err := url.Parse(base)
+	if err != nil || base == "" {
+		return ref
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return b.ResolveReference(r).String()
+}
+
+// Refresh returns what the document's <meta http-equiv="refresh"> asks
+// for: going to target, or reloading when it is empty, after delay
+// seconds. ok is false when there is none or its content can't be parsed.
+func (d *Document) Refresh() (delay float64, target string, ok bool) {
+	for _, meta := range d.Node.GetElementsByTagName("meta") {
+		if strings.EqualFold(strings.TrimSpace(meta.GetAttr("http-equiv")), "refresh") {
+			return parseRefresh(meta.GetAttr("content"))
+		}
+	}
+	return 0, "", false
+}
+
+// parseRefresh parses a refresh content value such as "5", "0; url=/next"
+// or "3;URL='page.html'"
+func parseRefresh(content string) (delay float64, target string, ok bool) {
+	content = strings.TrimSpace(content)
+	end := strings.IndexFunc(content, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if end < 0 {
+		end = len(content)
+	}
+	delay, err := strconv.ParseFloat(content[:end], 64)
+	if err != nil {
+		return 0, "", false
+	}
+	rest := strings.TrimLeft(content[end:], " \t\n;,")
+	if len(rest) >= 3 && strings.EqualFold(rest[:3], "url") {
+		if after := strings.TrimSpace(rest[3:]); strings.HasPrefix(after, "=") {
+			rest = strings.TrimSpace(after[1:])
+		}
+	}
+	if len(rest) > 0 && (rest[0] == '"' || rest[0] == '\'') {
+		quote := rest[0]
+		rest = rest[1:]
+		if i := strings.IndexByte(rest, quote); i >= 0 {
+			rest = rest[:i]
+		}
+	}
+	return delay, strings.TrimSpace(rest), true
+}
+
+// GetElementById finds the element with the given id in the document
+func (d *Document) GetElementById(id string) *Node {
+	return d.Node.GetElementById(id)
+}
+
+// normalize gives the tree the html > head + body shape: stray top-level
+// nodes move into <html>, metadata elements into <head> and everything else
+// into <body>
+func (d *Document) normalize() {
+	var html *Node
+	for _, child := range d.Node.Children {
+		if child.Type == NodeElement && child.Tag == "html" {
+			html = child
+			break
+		}
+	}
+	if html == nil {
+		html = NewElement("html")
+	}
+	for _, child := range append([]*Node(nil), d.Node.Children...) {
+		if child != html {
+			html.AppendChild(child)
+		}
+	}
+	d.Node.Children = []*Node{}
+	d.Node.AppendChild(html)
+	d.DocumentElement = html
+
+	var head, body *Node
+	for _, child := range html.Children {
+		if child.Type != NodeElement {
+			continue
+		}
+		if child.Tag == "head" && head == nil {
+			head = child
+		} else if (child.Tag == "body" || child.Tag == "frameset") && body == nil {
+			body = child
+		}
+	}
+	if head == nil {
+		head = NewElement("head")
+	}
+	if body == nil {
+		body = NewElement("body")
+	}
+
+	// Redistribute everything that isn't head or body itself
+	var before, after []*Node
+	seenBody := false
+	for _, child := range html.Children {
+		switch {
+		case child == head:
+		case child == body:
+			seenBody = true
+		case child.IsWhiteSpaceText():
+			// Inter-element white-space outside head and body is dropped
+		case child.Type == NodeElement && isMetadataElement(child.Tag) && !seenBody:
+			head.AppendChild(child)
+		case seenBody:
+			after = append(after, child)
+		default:
+			before = append(before, child)
+		}
+	}
+	if len(before) > 0 {
+		kids := body.Children
+		body.Children = []*Node{}
+		for _, child := range before {
+			body.AppendChild(child)
+		}
+		body.Children = append(body.Children, kids...)
+	}
+	for _, child := range after {
+		body.AppendChild(child)
+	}
+
+	html.Children = []*Node{}
+	html.AppendChild(head)
+	html.AppendChild(body)
+	d.Head, d.Body = head, body
+}
+
+// isMetadataElement reports whether tag belongs in <head> when it appears
+// outside <body>
+func isMetadataElement(tag string) bool {
+	switch tag {
+	case "title", "meta", "link", "style", "base", "script", "noscript":
+		return true
+	}
+	return false
+}
+
+// parseDoctype reads the <!DOCTYPE> at the start of html (after whitespace
+// and comments) and returns its name and the compatibility mode it selects
+func parseDoctype(html string) (string, string) {
+	rest := html
+	for {
+		rest = strings.TrimLeft(rest, " \t\r\n\f\ufeff")
+		if !strings.HasPrefix(rest, "<!--") {
+			break
+		}
+		end := strings.Index(rest, "-->")
+		if end == -1 {
+			return "", CompatQuirks
+		}
+		rest = rest[end+3:]
+	}
+
+	if len(rest) < 9 || !strings.EqualFold(rest[:9], "<!doctype") {
+		return "", CompatQuirks
+	}
+	end := strings.IndexByte(rest, '>')
+	if end == -1 {
+		return "", CompatQuirks
+	}
+	fields := strings.Fields(rest[9:end])
+	if len(fields) == 0 {
+		return "", CompatQuirks
+	}
+
+	name := strings.ToLower(fields[0])
+	if name != "html" {
+		return name, CompatQuirks
+	}
+	// Legacy transitional/frameset doctypes without a system identifier
+	// trigger quirks mode
+	decl := strings.ToLower(rest[9:end])
+	if (strings.Contains(decl, "transitional") || strings.Contains(decl, "frameset")) && !strings.Contains(decl, "http://") {
+		return name, CompatQuirks
+	}
+	return name, CompatStandards
+}
@@ -2,6 +2,8 @@ package dom
 
 import (
 	"html"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -96,6 +98,11 @@ func (n *Node) Clone() *Node {
 // SERIALIZATION
 // ======================================================================================
 
+// DebugNodeIDs makes OuterHTML emit each element's node ID as a
+// data-node-id attribute, so serialized markup can be mapped back to nodes.
+// The --debug-node-ids flag sets it.
+var DebugNodeIDs bool
+
 // OuterHTML returns the HTML representation of the node
 func (n *Node) OuterHTML() string {
 	if n == nil {
@@ -105,6 +112,15 @@ func (n *Node) OuterHTML() string {
 	if n.Type == NodeText {
 		return EncodeEntities(n.Content)
 	}
+	if n.Type == NodeDocument {
+		if doc := n.OwnerDocument(); doc != nil && doc.Doctype != "" {
+			return "<!DOCTYPE " + doc.Doctype + ">" + n.InnerHTML()
+		}
+		return n.InnerHTML()
+	}
+	if n.Type == NodeDocumentFragment {
+		return n.InnerHTML()
+	}
 
 	var sb strings.Builder
 
@@ -112,12 +128,22 @@ func (n *Node) OuterHTML() string {
 	sb.WriteString("<")
 	sb.WriteString(n.Tag)
 
-	// Attributes
-	for k, v := range n.Attributes {
+	// Attributes, sorted so the output is stable
+	if DebugNodeIDs && n.NodeID != 0 {
+		sb.WriteString(" data-node-id=\"")
+		sb.WriteString(strconv.Itoa(n.NodeID))
+		sb.WriteString("\"")
+	}
+	names := make([]string, 0, len(n.Attributes))
+	for k := range n.Attributes {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
 		sb.WriteString(" ")
 		sb.WriteString(k)
 		sb.WriteString("=\"")
-		sb.WriteString(EncodeEntities(v))
+		sb.WriteString(EncodeEntities(n.Attributes[k]))
 		sb.WriteString("\"")
 	}
 
@@ -130,9 +156,7 @@ func (n *Node) OuterHTML() string {
 	sb.WriteString(">")
 
 	// Children
-	for _, child := range n.Children {
-		sb.WriteString(child.OuterHTML())
-	}
+	sb.WriteString(n.InnerHTML())
 
 	// Closing tag
 	sb.WriteString("</")
@@ -150,11 +174,26 @@ func (n *Node) InnerHTML() string {
 
 	var sb strings.Builder
 	for _, child := range n.Children {
+		// The text of <script> and <style> is not markup, so it is written as is
+		if child.Type == NodeText && isRawTextElement(n.Tag) {
+			sb.WriteString(child.Content)
+			continue
+		}
 		sb.WriteString(child.OuterHTML())
 	}
 	return sb.String()
 }
 
+// isRawTextElement reports whether an element's text is written without
+// escaping
+func isRawTextElement(tag string) bool {
+	switch strings.ToLower(tag) {
+	case "script", "style", "xmp", "iframe", "noembed", "noframes", "noscript", "plaintext":
+		return true
+	}
+	return false
+}
+
 // isVoidElement returns true for HTML void elements
 func isVoidElement(tag string) bool {
 	voidElements := map[string]bool{
@@ -188,6 +227,10 @@ func (n *Node) debugStringIndent(indent int) string {
 		sb.WriteString(prefix)
 		sb.WriteString("<")
 		sb.WriteString(n.Tag)
+		if n.NodeID != 0 {
+			sb.WriteString(" #")
+			sb.WriteString(strconv.Itoa(n.NodeID))
+		}
 
 		if id := n.GetAttr("id"); id != "" {
 			sb.WriteString(" id=\"")
@@ -214,7 +257,19 @@ func (n *Node) debugStringIndent(indent int) string {
 			sb.WriteString(prefix)
 			sb.WriteString("\"")
 			sb.WriteString(text)
-			sb.WriteString("\"\n")
+			sb.WriteString("\"")
+			if n.NodeID != 0 {
+				sb.WriteString(" #")
+				sb.WriteString(strconv.Itoa(n.NodeID))
+			}
+			sb.WriteString("\n")
+		}
+
+	case NodeDocument, NodeDocumentFragment:
+		sb.WriteString(prefix)
+		sb.WriteString(n.Tag + "\n")
+		for _, child := range n.Children {
+			sb.WriteString(child.debugStringIndent(indent + 1))
 		}
 	}
 
@@ -13,6 +13,7 @@ const (
 	NodeDocument NodeType = iota
 	NodeElement
 	NodeText
+	NodeDocumentFragment // built off the tree; inserting one inserts its children
 )
 
 // DisplayMode represents CSS display property
@@ -26,18 +27,25 @@ const (
 
 // Node represents a node in the DOM tree
 type Node struct {
+	NodeID        int // stable per-document id (document order), 0 until assigned
 	Type          NodeType
 	Tag           string
 	Content       string // Only for NodeText
+	Line, Column  int    // where the text of a <script> or <style> starts in the HTML; 0 otherwise
 	Children      []*Node
 	Parent        *Node
 	Display       DisplayMode
 	Attributes    map[string]string
 	ComputedStyle interface{} // *css.ComputedStyle (interface to avoid circular import)
+	TopLayer      bool        // shown over the page by itself, as a modal <dialog> is
+
+	document *Document // set on the document node only, see OwnerDocument
 }
 
-// NewElement creates a new element node
+// NewElement creates a new element node. Tags are stored in lower case, as
+// HTML's are case-insensitive.
 func NewElement(tag string) *Node {
+	tag = strings.ToLower(tag)
 	return &Node{
 		Type:       NodeElement,
 		Tag:        tag,
@@ -47,6 +55,11 @@ func NewElement(tag string) *Node {
 	}
 }
 
+// NewDocumentFragment creates an empty document fragment
+func NewDocumentFragment() *Node {
+	return &Node{Type: NodeDocumentFragment, Tag: "#document-fragment", Children: []*Node{}}
+}
+
 // NewText creates a new text node
 func NewText(content string) *Node {
 	return &Node{Type: NodeText, Content: content, Display: DisplayInline}
@@ -66,6 +79,28 @@ func (n *Node) GetAttr(name string) string {
 	return n.Attributes[name]
 }
 
+// HasAttr reports whether the attribute is present, even if empty
+func (n *Node) HasAttr(name string) bool {
+	if n.Attributes == nil {
+		return false
+	}
+	_, ok := n.Attributes[name]
+	return ok
+}
+
+// SetAttr sets an attribute's value
+func (n *Node) SetAttr(name, value string) {
+	if n.Attributes == nil {
+		n.Attributes = make(map[string]string)
+	}
+	n.Attributes[name] = value
+}
+
+// RemoveAttr removes an attribute, if present
+func (n *Node) RemoveAttr(name string) {
+	delete(n.Attributes, name)
+}
+
 // GetDefaultDisplay returns the default display mode for a tag
 func GetDefaultDisplay(tag string) DisplayMode {
 	switch tag {
@@ -82,17 +117,30 @@ func GetDefaultDisplay(tag string) DisplayMode {
 	}
 }
 
-// Attribute parsing regex
-var attrRegex = regexp.MustCompile(`(\w+)\s*=\s*["']([^"']*)["']`)
+// Attribute parsing regex: name, then an optional double-quoted, single-quoted
+// or unquoted value. Names may contain hyphens (aria-label, data-*) and
+// attributes without a value (checked, disabled) are kept with an empty value.
+var attrRegex = regexp.MustCompile(`([^\s"'>/=]+)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'=<>` + "`" + `]+)))?`)
 
 // ParseAttributes extracts attributes from a tag string
 func ParseAttributes(tagContent string) map[string]string {
 	attrs := make(map[string]string)
+
+	// Skip the tag name itself
+	tagContent = strings.TrimSpace(tagContent)
+	if idx := strings.IndexAny(tagContent, " \t\n\r\f"); idx != -1 {
+		tagContent = tagContent[idx:]
+	} else {
+		return attrs
+	}
+
 	matches := attrRegex.FindAllStringSubmatch(tagContent, -1)
 	for _, m := range matches {
-		if len(m) >= 3 {
-			attrs[strings.ToLower(m[1])] = m[2]
+		name := strings.ToLower(m[1])
+		if _, seen := attrs[name]; seen {
+			continue // first occurrence wins, as in HTML
 		}
+		attrs[name] = m[2] + m[3] + m[4]
 	}
 	return attrs
 }
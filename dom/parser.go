@@ -1,11 +1,18 @@
 package dom
 
-import "strings"
+import (
+	"strings"
+	"unicode/utf8"
+)
 
 // Tokenizer splits HTML into tokens
 type Tokenizer struct {
 	Raw string
 	Pos int
+
+	// Lines counted so far by Position: line starts at lineStart, and
+	// Raw[:counted] has been scanned
+	line, lineStart, counted int
 }
 
 // NewTokenizer creates a new HTML tokenizer
@@ -13,6 +20,21 @@ func NewTokenizer(html string) *Tokenizer {
 	return &Tokenizer{Raw: html, Pos: 0}
 }
 
+// Position returns the 1-based line and column of the byte at pos. Calls
+// must not go backwards.
+func (t *Tokenizer) Position(pos int) (line, column int) {
+	if t.line == 0 {
+		t.line = 1
+	}
+	for ; t.counted < pos; t.counted++ {
+		if t.Raw[t.counted] == '\n' {
+			t.line++
+			t.lineStart = t.counted + 1
+		}
+	}
+	return t.line, utf8.RuneCountInString(t.Raw[t.lineStart:pos]) + 1
+}
+
 // HasMore returns true if there are more tokens
 func (t *Tokenizer) HasMore() bool { return t.Pos < len(t.Raw) }
 
@@ -82,9 +104,26 @@ func (t *Tokenizer) NextToken() (tagName string, fullTag string, isTag bool, isC
 	return
 }
 
-// ParseHTML parses HTML string into a DOM tree
+// ParseHTML parses an HTML string into a DOM tree rooted at a document node.
+// Use ParseDocument to also get the document-level state.
 func ParseHTML(html string) *Node {
-	root := NewElement("root")
+	return ParseDocument(html).Node
+}
+
+// ParseFragment parses html as the contents of an element, the way
+// innerHTML and insertAdjacentHTML do, and returns its top-level nodes,
+// detached
+func ParseFragment(html string) []*Node {
+	container := NewElement("template")
+	parseInto(container, html)
+	for _, child := range container.Children {
+		child.Parent = nil
+	}
+	return container.Children
+}
+
+// parseInto tokenizes html and appends the resulting nodes under root
+func parseInto(root *Node, html string) {
 	current := root
 	tokenizer := NewTokenizer(html)
 
@@ -154,7 +193,9 @@ func ParseHTML(html string) *Node {
 							// Found closing tag, extract content
 							content := tokenizer.Raw[startPos:tokenizer.Pos]
 							if strings.TrimSpace(content) != "" {
-								newNode.AppendChild(NewText(content))
+								text := NewText(content)
+								text.Line, text.Column = tokenizer.Position(startPos)
+								newNode.AppendChild(text)
 							}
 							// Skip past the closing tag
 							for tokenizer.Pos < len(tokenizer.Raw) && tokenizer.Raw[tokenizer.Pos] != '>' {
@@ -199,12 +240,15 @@ func ParseHTML(html string) *Node {
 				current = newNode
 			}
 		} else {
-			// Text node - skip if only whitespace
-			text := strings.TrimSpace(token)
+			// Text node - kept verbatim, white-space is collapsed at layout time
+			text := token
+			if len(current.Children) == 0 && (current.Tag == "pre" || current.Tag == "textarea" || current.Tag == "listing") {
+				// A newline right after the start tag is not content
+				text = strings.TrimPrefix(strings.TrimPrefix(text, "\r"), "\n")
+			}
 			if len(text) > 0 {
 				current.AppendChild(NewText(text))
 			}
 		}
 	}
-	return root
 }
@@ -33,7 +33,7 @@ func (n *Node) getElementsByTagNameRecursive(tag string, results *[]*Node) {
 	if n == nil {
 		return
 	}
-	if n.Type == NodeElement && strings.ToLower(n.Tag) == tag {
+	if n.Type == NodeElement && (tag == "*" || n.Tag == tag) {
 		*results = append(*results, n)
 	}
 	for _, child := range n.Children {
@@ -99,31 +99,40 @@ func (n *Node) TextContent() string {
 	var sb strings.Builder
 	for _, child := range n.Children {
 		sb.WriteString(child.TextContent())
-		sb.WriteString(" ")
 	}
-	return strings.TrimSpace(sb.String())
+	return sb.String()
 }
 
-// InnerText returns visible text content (excludes hidden elements)
+// IsWhiteSpace reports whether s holds nothing but HTML white-space
+// (space, tab, line feed, form feed and carriage return)
+func IsWhiteSpace(s string) bool {
+	return strings.Trim(s, " \t\n\f\r") == ""
+}
+
+// IsWhiteSpaceText reports whether n is a text node with only white-space
+func (n *Node) IsWhiteSpaceText() bool {
+	return n != nil && n.Type == NodeText && IsWhiteSpace(n.Content)
+}
+
+// InnerText returns visible text content (excludes hidden elements) with
+// white-space collapsed
 func (n *Node) InnerText() string {
-	if n == nil {
-		return ""
+	var sb strings.Builder
+	n.writeVisibleText(&sb)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+func (n *Node) writeVisibleText(sb *strings.Builder) {
+	if n == nil || n.Display == DisplayNone {
+		return
 	}
 	if n.Type == NodeText {
-		return n.Content
-	}
-	if n.Display == DisplayNone {
-		return ""
+		sb.WriteString(n.Content)
+		return
 	}
-	var sb strings.Builder
 	for _, child := range n.Children {
-		text := child.InnerText()
-		if text != "" {
-			sb.WriteString(text)
-			sb.WriteString(" ")
-		}
+		child.writeVisibleText(sb)
 	}
-	return strings.TrimSpace(sb.String())
 }
 
 // ======================================================================================
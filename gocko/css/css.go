@@ -3,7 +3,7 @@
 package css
 
 import (
-	"go-browser/gocko/css/properties"
+	"go-browser/css"
 	"go-browser/gocko/css/values"
 )
 
@@ -127,7 +127,13 @@ func DefaultContext() ResolveContext {
 	return values.DefaultContext()
 }
 
-// ParseProperty parses a CSS property and applies it to the style
+// FromComputed returns the view of a style computed by the css package
+func FromComputed(cs *css.ComputedStyle) *ComputedStyle {
+	return values.FromComputed(cs)
+}
+
+// ParseProperty parses a CSS property with the css package's parser and
+// applies it to the style
 func ParseProperty(style *ComputedStyle, property, value string) {
-	properties.ParseProperty(style, property, value)
+	style.ApplyProperty(property, value)
 }
@@ -4,12 +4,16 @@ package values
 
 import (
 	"image/color"
+
+	"go-browser/css"
 )
 
 // =============================================================================
 // COMPUTED STYLE
 // The final resolved style for an element after cascade, inheritance, and
-// value computation. This is what's used for layout and painting.
+// value computation. This is what's used for layout and painting. It is a
+// view of a css.ComputedStyle, which parses the properties, with sizes kept
+// as unit-aware Lengths and per-side borders.
 // =============================================================================
 
 // ComputedStyle contains all computed CSS property values for an element
@@ -152,61 +156,176 @@ type ComputedStyle struct {
 	// =========================
 	BorderCollapse string // separate, collapse
 	BorderSpacing  Length
+
+	// source is the style this one views; properties are applied to it
+	source *css.ComputedStyle
 }
 
 // NewComputedStyle creates a new computed style with default values
 func NewComputedStyle() *ComputedStyle {
+	return FromComputed(css.NewComputedStyle())
+}
+
+// FromComputed returns the view of a style computed by the css package.
+// Keywords it leaves empty get their initial values, and sides it stores
+// in pixels or percentages become Lengths.
+func FromComputed(cs *css.ComputedStyle) *ComputedStyle {
+	borderColor := fromRGBA(cs.BorderColor)
+	borderStyle := keyword(cs.BorderStyle, "none")
+	if cs.BorderStyle == "" && (cs.BorderTopWidth > 0 || cs.BorderRightWidth > 0 ||
+		cs.BorderBottomWidth > 0 || cs.BorderLeftWidth > 0) {
+		borderStyle = "solid"
+	}
+	lineHeight, lineHeightUnit := cs.LineHeight, "number"
+	if lineHeight <= 0 {
+		lineHeight = 1.2
+	}
 	return &ComputedStyle{
-		// Box Model defaults
-		Width:     Auto(),
-		Height:    Auto(),
-		MinWidth:  Zero(),
-		MaxWidth:  Length{Unit: UnitNone},
-		MinHeight: Zero(),
-		MaxHeight: Length{Unit: UnitNone},
-		BoxSizing: "content-box",
-
-		// Layout defaults
-		Display:        "inline",
-		Position:       "static",
-		FlexDirection:  "row",
-		FlexWrap:       "nowrap",
-		JustifyContent: "flex-start",
-		AlignItems:     "stretch",
-		AlignContent:   "stretch",
-		FlexGrow:       0,
-		FlexShrink:     1,
-		FlexBasis:      Auto(),
-		AlignSelf:      "auto",
-		OverflowX:      "visible",
-		OverflowY:      "visible",
-
-		// Typography defaults
-		Color:          Black(),
-		FontFamily:     "sans-serif",
-		FontSize:       16,
-		FontWeight:     400,
-		FontStyle:      "normal",
-		LineHeight:     1.2,
-		LineHeightUnit: "number",
-		TextAlign:      "start",
-		TextDecoration: "none",
-		TextTransform:  "none",
-		WhiteSpace:     "normal",
-
-		// Visual defaults
-		BackgroundColor: Transparent(),
-		Opacity:         1.0,
-		Visibility:      "visible",
-		Cursor:          "auto",
-
-		// List defaults
-		ListStyleType:     "disc",
-		ListStylePosition: "outside",
-
-		// Table defaults
-		BorderCollapse: "separate",
+		Width:     lengthOr(cs.Width, Auto()),
+		Height:    lengthOr(cs.Height, Auto()),
+		MinWidth:  lengthOr(cs.MinWidth, Zero()),
+		MaxWidth:  lengthOr(cs.MaxWidth, Length{Unit: UnitNone}),
+		MinHeight: lengthOr(cs.MinHeight, Zero()),
+		MaxHeight: lengthOr(cs.MaxHeight, Length{Unit: UnitNone}),
+
+		MarginTop:    sideLength(cs.MarginTop, cs.MarginPercent.Top, cs.MarginMath.Top),
+		MarginRight:  sideLength(cs.MarginRight, cs.MarginPercent.Right, cs.MarginMath.Right),
+		MarginBottom: sideLength(cs.MarginBottom, cs.MarginPercent.Bottom, cs.MarginMath.Bottom),
+		MarginLeft:   sideLength(cs.MarginLeft, cs.MarginPercent.Left, cs.MarginMath.Left),
+
+		PaddingTop:    sideLength(cs.PaddingTop, cs.PaddingPercent.Top, cs.PaddingMath.Top),
+		PaddingRight:  sideLength(cs.PaddingRight, cs.PaddingPercent.Right, cs.PaddingMath.Right),
+		PaddingBottom: sideLength(cs.PaddingBottom, cs.PaddingPercent.Bottom, cs.PaddingMath.Bottom),
+		PaddingLeft:   sideLength(cs.PaddingLeft, cs.PaddingPercent.Left, cs.PaddingMath.Left),
+
+		BorderTopWidth:    Px(cs.BorderTopWidth),
+		BorderRightWidth:  Px(cs.BorderRightWidth),
+		BorderBottomWidth: Px(cs.BorderBottomWidth),
+		BorderLeftWidth:   Px(cs.BorderLeftWidth),
+
+		BorderTopColor:    borderColor,
+		BorderRightColor:  borderColor,
+		BorderBottomColor: borderColor,
+		BorderLeftColor:   borderColor,
+
+		BorderTopStyle:    borderStyle,
+		BorderRightStyle:  borderStyle,
+		BorderBottomStyle: borderStyle,
+		BorderLeftStyle:   borderStyle,
+
+		BorderTopLeftRadius:     Px(cs.BorderRadius),
+		BorderTopRightRadius:    Px(cs.BorderRadius),
+		BorderBottomRightRadius: Px(cs.BorderRadius),
+		BorderBottomLeftRadius:  Px(cs.BorderRadius),
+
+		BoxSizing: keyword(cs.BoxSizing, "content-box"),
+
+		Display:  keyword(cs.Display, "inline"),
+		Position: keyword(cs.Position, "static"),
+		Top:      Px(cs.Top),
+		Right:    Px(cs.Right),
+		Bottom:   Px(cs.Bottom),
+		Left:     Px(cs.Left),
+
+		FlexDirection:  keyword(cs.FlexDirection, "row"),
+		FlexWrap:       keyword(cs.FlexWrap, "nowrap"),
+		JustifyContent: keyword(cs.JustifyContent, "flex-start"),
+		AlignItems:     keyword(cs.AlignItems, "stretch"),
+		AlignContent:   keyword(cs.AlignContent, "stretch"),
+		Gap:            Px(cs.Gap),
+		FlexGrow:       cs.FlexGrow,
+		FlexShrink:     cs.FlexShrink,
+		FlexBasis:      flexBasis(cs.FlexBasis),
+		AlignSelf:      keyword(cs.AlignSelf, "auto"),
+		Order:          cs.Order,
+
+		OverflowX: keyword(cs.OverflowX, "visible"),
+		OverflowY: keyword(cs.OverflowY, "visible"),
+
+		ZIndex:    cs.ZIndex,
+		ZIndexSet: cs.ZIndex != 0,
+
+		Color:          fromRGBA(cs.Color),
+		FontFamily:     keyword(cs.FontFamily, "sans-serif"),
+		FontSize:       cs.FontSize,
+		FontWeight:     cs.FontWeight,
+		FontStyle:      keyword(cs.FontStyle, "normal"),
+		LineHeight:     lineHeight,
+		LineHeightUnit: lineHeightUnit,
+		TextAlign:      keyword(cs.TextAlign, "start"),
+		TextDecoration: keyword(cs.TextDecoration, "none"),
+		TextTransform:  keyword(cs.TextTransform, "none"),
+		LetterSpacing:  lengthOr(cs.LetterSpacing, Zero()),
+		WordSpacing:    lengthOr(cs.WordSpacing, Zero()),
+		WhiteSpace:     keyword(cs.WhiteSpace, "normal"),
+
+		BackgroundColor:    fromRGBA(cs.BackgroundColor),
+		BackgroundImage:    cs.BackgroundImage,
+		BackgroundSize:     cs.BackgroundSize,
+		BackgroundPosition: cs.BackgroundPosition,
+		BackgroundRepeat:   cs.BackgroundRepeat,
+
+		Opacity:    cs.Opacity,
+		Visibility: keyword(cs.Visibility, "visible"),
+		BoxShadow:  cs.BoxShadow,
+		Cursor:     keyword(cs.Cursor, "auto"),
+		Transform:  cs.Transform,
+
+		ListStyleType:     keyword(cs.ListStyleType, "disc"),
+		ListStylePosition: keyword(cs.ListStylePosition, "outside"),
+
+		BorderCollapse: keyword(cs.BorderCollapse, "separate"),
+		BorderSpacing:  Px(cs.BorderSpacing),
+
+		source: cs,
+	}
+}
+
+// ApplyProperty parses a CSS property into the underlying css.ComputedStyle
+// and refreshes this view of it
+func (cs *ComputedStyle) ApplyProperty(property, value string) {
+	if cs.source == nil {
+		cs.source = css.NewComputedStyle()
+	}
+	css.ApplyProperty(cs.source, property, value)
+	*cs = *FromComputed(cs.source)
+}
+
+// keyword returns value, or def when the css package left it empty
+func keyword(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
+
+// lengthOr converts a css.Length, giving unset a default of its own
+func lengthOr(l css.Length, unset Length) Length {
+	if !l.IsSet() && !l.IsAuto() {
+		return unset
+	}
+	return fromLength(l)
+}
+
+// sideLength returns a margin or padding side: its math expression or its
+// percentage of the containing block when it was given so, otherwise its
+// pixels
+func sideLength(px, percent float64, expr *css.MathLength) Length {
+	if expr != nil {
+		return fromLength(css.Length{Expr: expr})
+	}
+	if percent != 0 {
+		return Percent(percent)
+	}
+	return Px(px)
+}
+
+// flexBasis returns flex-basis in pixels, or auto when it is not set
+func flexBasis(v float64) Length {
+	if v == 0 {
+		return Auto()
 	}
+	return Px(v)
 }
 
 // Clone creates a deep copy of the computed style
@@ -4,8 +4,9 @@ package values
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
+
+	"go-browser/css"
 )
 
 // =============================================================================
@@ -33,12 +34,17 @@ const (
 	UnitIn                        // Inches
 	UnitAuto                      // auto keyword
 	UnitNone                      // none/unset
+	UnitMath                      // min(), max() or clamp() expression
 )
 
 // Length represents a CSS length value with unit
 type Length struct {
 	Value float64
 	Unit  LengthUnit
+
+	// Math function data (only for UnitMath)
+	Func string   // "min", "max" or "clamp"
+	Args []Length // function arguments, resolved lazily
 }
 
 // Zero creates a zero length
@@ -91,7 +97,10 @@ func (l Length) IsZero() bool {
 	return l.Value == 0 && l.Unit != UnitAuto
 }
 
-// ResolveContext contains the context needed to resolve relative lengths
+// ResolveContext contains the context needed to resolve relative lengths.
+// Lengths resolve as the css package resolves them, rem against 16px and ch
+// and ex from the font size, so RootFontSize, CharWidth and XHeight are only
+// kept for callers that set them.
 type ResolveContext struct {
 	FontSize       float64 // Current element font-size in px
 	RootFontSize   float64 // :root font-size in px (default 16)
@@ -117,57 +126,19 @@ func DefaultContext() ResolveContext {
 	}
 }
 
+// viewport returns the viewport vw and vh units are taken against
+func (ctx ResolveContext) viewport() css.Viewport {
+	return css.Viewport{Width: ctx.ViewportWidth, Height: ctx.ViewportHeight}
+}
+
 // Resolve converts any length to pixels using the given context
 func (l Length) Resolve(ctx ResolveContext) float64 {
-	switch l.Unit {
-	case UnitPx:
-		return l.Value
-	case UnitEm:
-		return l.Value * ctx.FontSize
-	case UnitRem:
-		return l.Value * ctx.RootFontSize
-	case UnitPercent:
-		return l.Value / 100 * ctx.ParentWidth
-	case UnitVw:
-		return l.Value / 100 * ctx.ViewportWidth
-	case UnitVh:
-		return l.Value / 100 * ctx.ViewportHeight
-	case UnitVmin:
-		min := ctx.ViewportWidth
-		if ctx.ViewportHeight < min {
-			min = ctx.ViewportHeight
-		}
-		return l.Value / 100 * min
-	case UnitVmax:
-		max := ctx.ViewportWidth
-		if ctx.ViewportHeight > max {
-			max = ctx.ViewportHeight
-		}
-		return l.Value / 100 * max
-	case UnitCh:
-		return l.Value * ctx.CharWidth
-	case UnitEx:
-		return l.Value * ctx.XHeight
-	case UnitPt:
-		return l.Value * 96 / 72 // 96 DPI standard
-	case UnitCm:
-		return l.Value * 96 / 2.54
-	case UnitMm:
-		return l.Value * 96 / 25.4
-	case UnitIn:
-		return l.Value * 96
-	case UnitAuto, UnitNone:
-		return 0
-	}
-	return l.Value
+	return l.toLength().Resolve(ctx.FontSize, ctx.ParentWidth, ctx.viewport())
 }
 
 // ResolveHeight resolves a length using parent height (for height percentages)
 func (l Length) ResolveHeight(ctx ResolveContext) float64 {
-	if l.Unit == UnitPercent {
-		return l.Value / 100 * ctx.ParentHeight
-	}
-	return l.Resolve(ctx)
+	return l.toLength().Resolve(ctx.FontSize, ctx.ParentHeight, ctx.viewport())
 }
 
 // String returns a CSS representation of the length
@@ -178,6 +149,13 @@ func (l Length) String() string {
 	if l.Unit == UnitNone {
 		return "none"
 	}
+	if l.Unit == UnitMath {
+		args := make([]string, len(l.Args))
+		for i, arg := range l.Args {
+			args[i] = arg.String()
+		}
+		return l.Func + "(" + strings.Join(args, ", ") + ")"
+	}
 	units := []string{"px", "em", "rem", "%", "vw", "vh", "vmin", "vmax", "ch", "ex", "pt", "cm", "mm", "in"}
 	if int(l.Unit) < len(units) {
 		return fmt.Sprintf("%g%s", l.Value, units[l.Unit])
@@ -185,54 +163,52 @@ func (l Length) String() string {
 	return fmt.Sprintf("%gpx", l.Value)
 }
 
-// ParseLength parses a CSS length string
+// ParseLength parses a CSS length string with the css package's parser
 func ParseLength(s string) (Length, error) {
-	s = strings.TrimSpace(strings.ToLower(s))
-
-	if s == "" || s == "0" {
+	if strings.TrimSpace(s) == "" {
 		return Zero(), nil
 	}
-	if s == "auto" {
-		return Auto(), nil
+	l, ok := css.ParseLengthValue(s)
+	if !ok {
+		return Zero(), fmt.Errorf("invalid length: %s", strings.TrimSpace(s))
 	}
-	if s == "none" {
+	if !l.IsSet() && !l.IsAuto() {
 		return Length{Unit: UnitNone}, nil
 	}
+	return fromLength(l), nil
+}
 
-	// Unit mappings
-	units := map[string]LengthUnit{
-		"px":   UnitPx,
-		"em":   UnitEm,
-		"rem":  UnitRem,
-		"%":    UnitPercent,
-		"vw":   UnitVw,
-		"vh":   UnitVh,
-		"vmin": UnitVmin,
-		"vmax": UnitVmax,
-		"ch":   UnitCh,
-		"ex":   UnitEx,
-		"pt":   UnitPt,
-		"cm":   UnitCm,
-		"mm":   UnitMm,
-		"in":   UnitIn,
-	}
-
-	// Try to find and parse unit
-	for suffix, unit := range units {
-		if strings.HasSuffix(s, suffix) {
-			numStr := strings.TrimSuffix(s, suffix)
-			value, err := strconv.ParseFloat(numStr, 64)
-			if err != nil {
-				return Zero(), fmt.Errorf("invalid length value: %s", s)
-			}
-			return Length{Value: value, Unit: unit}, nil
+// fromLength converts a css.Length, whose units count from UnitNone, to a
+// Length; unset lengths become auto
+func fromLength(l css.Length) Length {
+	if l.Expr != nil {
+		m := Length{Unit: UnitMath, Func: l.Expr.Func}
+		for _, arg := range l.Expr.Args {
+			m.Args = append(m.Args, fromLength(css.Length{Value: arg.Number, Unit: arg.Unit}))
 		}
+		return m
 	}
+	switch l.Unit {
+	case css.UnitNone, css.UnitAuto:
+		return Auto()
+	}
+	return Length{Value: l.Value, Unit: LengthUnit(l.Unit - css.UnitPx)}
+}
 
-	// Try parsing as number (implicit px)
-	value, err := strconv.ParseFloat(s, 64)
-	if err != nil {
-		return Zero(), fmt.Errorf("invalid length: %s", s)
+// toLength converts l back to the css.Length it stands for, which resolves it
+func (l Length) toLength() css.Length {
+	switch l.Unit {
+	case UnitAuto:
+		return css.Auto()
+	case UnitNone:
+		return css.Length{}
+	case UnitMath:
+		expr := &css.MathLength{Func: l.Func}
+		for _, arg := range l.Args {
+			a := arg.toLength()
+			expr.Args = append(expr.Args, css.Value{Type: css.ValueLength, Number: a.Value, Unit: a.Unit})
+		}
+		return css.Length{Expr: expr}
 	}
-	return Px(value), nil
+	return css.Length{Value: l.Value, Unit: css.UnitPx + css.Unit(l.Unit)}
 }
@@ -45,10 +45,8 @@ func (h *ButtonHandler) Render(screen *ebiten.Image, box *layout.RenderBox, node
 
 func getButtonText(node *dom.Node) string {
 	// Get text from children
-	for _, child := range node.Children {
-		if child.Tag == "" && child.Content != "" {
-			return child.Content
-		}
+	if text := node.InnerText(); text != "" {
+		return text
 	}
 	// Fallback
 	if node.Attributes["value"] != "" {
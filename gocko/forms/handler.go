@@ -3,7 +3,9 @@ package forms
 
 import (
 	"fmt"
+	"image/color"
 
+	"go-browser/css"
 	"go-browser/dom"
 	"go-browser/layout"
 
@@ -73,21 +75,115 @@ func GetElementID(node *dom.Node) string {
 	if id := node.Attributes["id"]; id != "" {
 		return id
 	}
-	if name := node.Attributes["name"]; name != "" {
+	// Radios share their name with the rest of the group, so it can't identify them
+	if name := node.Attributes["name"]; name != "" && node.Attributes["type"] != "radio" {
 		return name
 	}
 	// Check if we already assigned an ID to this node
 	if cachedID, ok := elementCounter[node]; ok {
 		return cachedID
 	}
-	// Generate a unique ID based on tag and counter
-	idCounter++
-	newID := fmt.Sprintf("%s_%d", node.Tag, idCounter)
+	// Generate a unique ID from the document's node ID, or a counter for
+	// detached nodes
+	var newID string
+	if node.NodeID != 0 {
+		newID = fmt.Sprintf("%s_node%d", node.Tag, node.NodeID)
+	} else {
+		idCounter++
+		newID = fmt.Sprintf("%s_%d", node.Tag, idCounter)
+	}
 	elementCounter[node] = newID
 	return newID
 }
 
+// MatchesElementID reports whether id identifies node, without generating a
+// new ID for nodes that never had one
+func MatchesElementID(node *dom.Node, id string) bool {
+	if node == nil || id == "" {
+		return false
+	}
+	if nodeID := node.Attributes["id"]; nodeID != "" {
+		return nodeID == id
+	}
+	if name := node.Attributes["name"]; name != "" && node.Attributes["type"] != "radio" {
+		return name == id
+	}
+	return elementCounter[node] == id
+}
+
+// =============================================================================
+// DISABLED CONTROLS
+// =============================================================================
+
+// IsDisabled reports whether a control is disabled, by its own disabled
+// attribute or by a <fieldset disabled> around it. Controls in the fieldset's
+// first <legend> stay enabled.
+func IsDisabled(node *dom.Node) bool {
+	if node == nil {
+		return false
+	}
+	if node.HasAttr("disabled") {
+		return true
+	}
+	for child, p := node, node.Parent; p != nil; child, p = p, p.Parent {
+		if p.Tag == "fieldset" && p.HasAttr("disabled") && !(child.Tag == "legend" && child == firstLegend(p)) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstLegend returns the fieldset's first <legend> child
+func firstLegend(fieldset *dom.Node) *dom.Node {
+	for _, child := range fieldset.Children {
+		if child.Type == dom.NodeElement && child.Tag == "legend" {
+			return child
+		}
+	}
+	return nil
+}
+
 // GetValueByID is an alias for GetValue for compatibility
 func (fs *FormState) GetValueByID(id string) string {
 	return fs.GetValue(id)
 }
+
+// =============================================================================
+// PSEUDO-ELEMENT STYLES
+// =============================================================================
+
+// DefaultSelectionBg is the highlight used when no ::selection rule applies
+var DefaultSelectionBg = color.RGBA{179, 215, 255, 255}
+
+// pseudoStyle returns the node's computed ::name style, or nil if unstyled
+func pseudoStyle(node *dom.Node, name string) *css.ComputedStyle {
+	if node == nil {
+		return nil
+	}
+	cs, ok := node.ComputedStyle.(*css.ComputedStyle)
+	if !ok {
+		return nil
+	}
+	return cs.PseudoStyle(name)
+}
+
+// placeholderColor returns the ::placeholder color, falling back to fallback
+func placeholderColor(node *dom.Node, fallback color.RGBA) color.RGBA {
+	if ps := pseudoStyle(node, "placeholder"); ps != nil && ps.Color.A > 0 {
+		return ps.Color
+	}
+	return fallback
+}
+
+// selectionColors returns the ::selection background and text colors.
+// A zero text color means the text keeps its normal color.
+func selectionColors(node *dom.Node) (bg, fg color.RGBA) {
+	bg = DefaultSelectionBg
+	if ps := pseudoStyle(node, "selection"); ps != nil {
+		if ps.BackgroundColor.A > 0 {
+			bg = ps.BackgroundColor
+		}
+		fg = ps.Color
+	}
+	return bg, fg
+}
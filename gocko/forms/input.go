@@ -67,10 +67,28 @@ func (h *InputHandler) renderTextInput(screen *ebiten.Image, x, y, w, bh float32
 
 	if displayValue == "" && placeholder != "" {
 		displayValue = placeholder
-		textColor = color.RGBA{150, 150, 160, 255}
+		textColor = placeholderColor(node, color.RGBA{150, 150, 160, 255})
 	}
 
-	render.DrawText(screen, displayValue, float64(x+8), float64(y+20), 14, textColor)
+	// Selection highlight
+	if state.IsFocused(id) && state.HasSelection() && value != "" {
+		start, end := state.selectionRange(value)
+		// Password masks use one bullet per rune, so rune offsets work for both
+		shown := []rune(displayValue)
+		start, end = len([]rune(value[:start])), len([]rune(value[:end]))
+		before := string(shown[:start])
+		selected := string(shown[start:end])
+		selBg, selFg := selectionColors(node)
+		selX := float64(x) + 8 + render.MeasureText(before, 14)
+		selW := render.MeasureText(selected, 14)
+		vector.DrawFilledRect(screen, float32(selX), y+5, float32(selW), bh-10, selBg, false)
+		render.DrawText(screen, displayValue, float64(x+8), float64(y+20), 14, textColor)
+		if selFg.A > 0 {
+			render.DrawText(screen, selected, selX, float64(y+20), 14, selFg)
+		}
+	} else {
+		render.DrawText(screen, displayValue, float64(x+8), float64(y+20), 14, textColor)
+	}
 
 	// Cursor when focused
 	if state.IsFocused(id) && (state.CursorBlink/30)%2 == 0 {
@@ -162,15 +180,16 @@ func (h *InputHandler) HandleClick(box *layout.RenderBox, node *dom.Node, x, y f
 		return true
 
 	case "radio":
-		// Uncheck all radios with same name
-		name := node.Attributes["name"]
-		for k := range state.CheckedState {
-			// Simple approach - clear all and set this one
-			if name != "" {
-				state.CheckedState[k] = false
-			}
+		// Only radios sharing the name within the same form are exclusive
+		var members []string
+		for _, radio := range radioGroupMembers(node) {
+			members = append(members, GetElementID(radio))
+		}
+		value := node.Attributes["value"]
+		if value == "" {
+			value = "on"
 		}
-		state.SetChecked(id, true)
+		state.SelectRadio(RadioGroupKey(node), id, value, members)
 		return true
 
 	case "submit":
@@ -181,6 +200,47 @@ func (h *InputHandler) HandleClick(box *layout.RenderBox, node *dom.Node, x, y f
 	return false
 }
 
+// RadioGroupKey returns the key identifying the radio group of a node:
+// its owning form (or the document) plus the name attribute
+func RadioGroupKey(node *dom.Node) string {
+	scope := "document"
+	if form := node.ClosestAncestor("form"); form != nil {
+		scope = GetElementID(form)
+	}
+	return scope + "/" + node.Attributes["name"]
+}
+
+// radioGroupMembers returns all radios in the same group as node (including node)
+func radioGroupMembers(node *dom.Node) []*dom.Node {
+	name := node.Attributes["name"]
+	if name == "" {
+		return []*dom.Node{node}
+	}
+
+	form := node.ClosestAncestor("form")
+	scope := form
+	if scope == nil {
+		// Document scope: walk up to the root
+		scope = node
+		for scope.Parent != nil {
+			scope = scope.Parent
+		}
+	}
+
+	var members []*dom.Node
+	for _, input := range scope.GetElementsByTagName("input") {
+		if input.Attributes["type"] != "radio" || input.Attributes["name"] != name {
+			continue
+		}
+		// Radios outside any form only group with other form-less radios
+		if input.ClosestAncestor("form") != form {
+			continue
+		}
+		members = append(members, input)
+	}
+	return members
+}
+
 // HandleInput processes keyboard input
 func (h *InputHandler) HandleInput(node *dom.Node, runes []rune, keys []ebiten.Key, state *FormState) bool {
 	id := GetElementID(node)
@@ -191,6 +251,13 @@ func (h *InputHandler) HandleInput(node *dom.Node, runes []rune, keys []ebiten.K
 	value := state.GetValue(id)
 	changed := false
 
+	// Typing or deleting replaces the current selection
+	if state.HasSelection() && (len(runes) > 0 || containsKey(keys, ebiten.KeyBackspace) || containsKey(keys, ebiten.KeyDelete)) {
+		value = state.DeleteSelection(value)
+		changed = true
+		keys = withoutKeys(keys, ebiten.KeyBackspace, ebiten.KeyDelete)
+	}
+
 	// Handle character input
 	for _, r := range runes {
 		if state.CursorPos <= len(value) {
@@ -260,3 +327,24 @@ func (h *InputHandler) GetValue(node *dom.Node, state *FormState) string {
 func (h *InputHandler) IsFocusable() bool {
 	return true
 }
+
+// containsKey reports whether key is in keys
+func containsKey(keys []ebiten.Key, key ebiten.Key) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutKeys returns keys with every occurrence of the given keys removed
+func withoutKeys(keys []ebiten.Key, remove ...ebiten.Key) []ebiten.Key {
+	var out []ebiten.Key
+	for _, k := range keys {
+		if !containsKey(remove, k) {
+			out = append(out, k)
+		}
+	}
+	return out
+}
@@ -40,6 +40,11 @@ type FormState struct {
 	// CheckedState tracks checkbox/radio states
 	CheckedState map[string]bool
 
+	// RadioGroups maps a radio group key (see RadioGroupKey) to the checked radio's ID
+	RadioGroups map[string]string
+	// RadioValues maps a radio group key to the checked radio's value
+	RadioValues map[string]string
+
 	// Focus state
 	FocusedID string
 	CursorPos int
@@ -73,6 +78,8 @@ func NewFormState() *FormState {
 	return &FormState{
 		Values:           make(map[string]string),
 		CheckedState:     make(map[string]bool),
+		RadioGroups:      make(map[string]string),
+		RadioValues:      make(map[string]string),
 		ValidationErrors: make(map[string]string),
 		Files:            make(map[string][]FileInfo),
 	}
@@ -98,6 +105,21 @@ func (fs *FormState) SetChecked(id string, checked bool) {
 	fs.CheckedState[id] = checked
 }
 
+// SelectRadio checks the radio id and unchecks the other members of its group
+func (fs *FormState) SelectRadio(group, id, value string, members []string) {
+	for _, member := range members {
+		fs.CheckedState[member] = false
+	}
+	fs.CheckedState[id] = true
+	fs.RadioGroups[group] = id
+	fs.RadioValues[group] = value
+}
+
+// GetRadioGroupValue returns the value of the checked radio in a group
+func (fs *FormState) GetRadioGroupValue(group string) string {
+	return fs.RadioValues[group]
+}
+
 // IsFocused returns true if element has focus
 func (fs *FormState) IsFocused(id string) bool {
 	return fs.FocusedID == id
@@ -148,3 +170,34 @@ func (fs *FormState) SelectAll() {
 	fs.SelectionStart = 0
 	fs.SelectionEnd = len(fs.Values[fs.FocusedID])
 }
+
+// selectionRange returns the ordered selection bounds clamped to value
+func (fs *FormState) selectionRange(value string) (int, int) {
+	start, end := fs.SelectionStart, fs.SelectionEnd
+	if start > end {
+		start, end = end, start
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end > len(value) {
+		end = len(value)
+	}
+	if start > end {
+		start = end
+	}
+	return start, end
+}
+
+// DeleteSelection removes the selected text from value, moving the cursor to
+// where the selection started. It returns the updated value.
+func (fs *FormState) DeleteSelection(value string) string {
+	if !fs.HasSelection() {
+		return value
+	}
+	start, end := fs.selectionRange(value)
+	fs.SelectionStart = 0
+	fs.SelectionEnd = 0
+	fs.CursorPos = start
+	return value[:start] + value[end:]
+}
@@ -41,7 +41,7 @@ func (h *TextareaHandler) Render(screen *ebiten.Image, box *layout.RenderBox, no
 	textColor := color.RGBA{33, 33, 33, 255}
 	if value == "" && placeholder != "" {
 		lines = []string{placeholder}
-		textColor = color.RGBA{150, 150, 160, 255}
+		textColor = placeholderColor(node, color.RGBA{150, 150, 160, 255})
 	}
 
 	// Draw lines
@@ -71,7 +71,7 @@ func (h *TextareaHandler) HandleClick(box *layout.RenderBox, node *dom.Node, x,
 		// Get default content from children
 		for _, child := range node.Children {
 			if child.Tag == "" && child.Content != "" {
-				state.SetValue(id, strings.TrimSpace(child.Content))
+				state.SetValue(id, child.Content)
 				break
 			}
 		}
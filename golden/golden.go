@@ -0,0 +1,119 @@
+// Package golden renders pages offscreen and compares the pixels with
+// stored images, so that changes to the CSS engine, layout or painting
+// can't change how a page looks without a test noticing
+package golden
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+
+	"go-browser/browser"
+	"go-browser/render"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// ======================================================================================
+// RENDERING AND COMPARING
+// A page is drawn by a BrowserView into an offscreen image of a fixed size
+// with the Go fonts, which ship with the module, so its pixels depend on the
+// engines alone. Render needs ebiten's graphics, so it must run on the game
+// loop, from an Update or Draw. Compare tolerates the small differences
+// anti-aliasing makes between graphics drivers.
+// ======================================================================================
+
+// UseGoFonts makes pages render with the Go fonts, regular and mono
+func UseGoFonts() error {
+	regular, err := text.NewGoTextFaceSource(bytes.NewReader(goregular.TTF))
+	if err != nil {
+		return err
+	}
+	mono, err := text.NewGoTextFaceSource(bytes.NewReader(gomono.TTF))
+	if err != nil {
+		return err
+	}
+	render.SetFontSource(regular)
+	render.SetMonoFontSource(mono)
+	return nil
+}
+
+// Render lays html out in a viewport width by height pixels and draws it
+func Render(html string, width, height int) *image.RGBA {
+	rect := image.Rect(0, 0, width, height)
+	view := browser.NewBrowserView(nil)
+	view.Update(rect) // the view takes its size before the page is laid out
+	view.LoadHTML(html)
+
+	screen := ebiten.NewImage(width, height)
+	defer screen.Deallocate()
+	view.Draw(screen, rect)
+	out := image.NewRGBA(rect)
+	screen.ReadPixels(out.Pix)
+	return out
+}
+
+// Diff is how two renderings differ
+type Diff struct {
+	Pixels int         // pixels with a channel further apart than the tolerance
+	Image  *image.RGBA // the wanted image faded, with differing pixels in red
+}
+
+// Compare counts the pixels of got that differ from want by more than
+// tolerance in any channel. Images of different sizes differ everywhere.
+func Compare(got, want image.Image, tolerance uint8) Diff {
+	bounds := want.Bounds()
+	diff := Diff{Image: image.NewRGBA(bounds)}
+	if got.Bounds() != bounds {
+		diff.Pixels = bounds.Dx() * bounds.Dy()
+		return diff
+	}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			g := color.RGBAModel.Convert(got.At(x, y)).(color.RGBA)
+			w := color.RGBAModel.Convert(want.At(x, y)).(color.RGBA)
+			if apart(g.R, w.R) > tolerance || apart(g.G, w.G) > tolerance ||
+				apart(g.B, w.B) > tolerance || apart(g.A, w.A) > tolerance {
+				diff.Pixels++
+				diff.Image.SetRGBA(x, y, color.RGBA{255, 0, 0, 255})
+				continue
+			}
+			gray := uint8((uint16(w.R) + uint16(w.G) + uint16(w.B)) / 3)
+			faded := 192 + gray/4
+			diff.Image.SetRGBA(x, y, color.RGBA{faded, faded, faded, 255})
+		}
+	}
+	return diff
+}
+
+// apart returns how far apart two channel values are
+func apart(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// ReadPNG decodes the PNG at path
+func ReadPNG(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
+
+// WritePNG encodes img as a PNG at path
+func WritePNG(path string, img image.Image) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
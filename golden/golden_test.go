@@ -0,0 +1,123 @@
+package golden
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// update rewrites the goldens from what the engines draw now
+var update = flag.Bool("update", false, "rewrite the golden PNGs in testdata")
+
+// The viewport fixtures are drawn in, and how much a rendering may differ
+// from its golden before the test fails
+const (
+	fixtureWidth  = 800
+	fixtureHeight = 600
+	tolerance     = 24    // per channel, for anti-aliasing
+	maxDiffRatio  = 0.002 // of the pixels
+)
+
+// runner runs the tests on ebiten's game loop, where images can be drawn
+// and read back, then ends the game
+type runner struct {
+	m    *testing.M
+	code int
+	ran  bool
+}
+
+func (r *runner) Update() error {
+	if !r.ran {
+		r.ran = true
+		r.code = r.m.Run()
+	}
+	return ebiten.Termination
+}
+
+func (r *runner) Draw(*ebiten.Image) {}
+
+func (r *runner) Layout(int, int) (int, int) { return 64, 64 }
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if err := UseGoFonts(); err != nil {
+		fmt.Fprintln(os.Stderr, "golden:", err)
+		os.Exit(1)
+	}
+	ebiten.SetWindowSize(64, 64)
+	ebiten.SetWindowTitle("golden")
+	r := &runner{m: m}
+	if err := ebiten.RunGame(r); err != nil {
+		// No display, as on a server without xvfb-run
+		fmt.Fprintln(os.Stderr, "golden: skipped, ebiten cannot start:", err)
+		os.Exit(0)
+	}
+	os.Exit(r.code)
+}
+
+// TestGoldens renders each testdata/*.html and compares it with the PNG of
+// the same name; make golden-update writes the PNGs
+func TestGoldens(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "*.html"))
+	if err != nil || len(fixtures) == 0 {
+		t.Fatal("no fixtures in testdata")
+	}
+	for _, fixture := range fixtures {
+		name := strings.TrimSuffix(filepath.Base(fixture), ".html")
+		t.Run(name, func(t *testing.T) {
+			html, err := os.ReadFile(fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := Render(string(html), fixtureWidth, fixtureHeight)
+			goldenPath := strings.TrimSuffix(fixture, ".html") + ".png"
+			if *update {
+				if err := WritePNG(goldenPath, got); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := ReadPNG(goldenPath)
+			if errors.Is(err, fs.ErrNotExist) {
+				t.Skipf("no golden %s yet: run make golden-update", goldenPath)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			diff := Compare(got, want, tolerance)
+			if float64(diff.Pixels) <= maxDiffRatio*fixtureWidth*fixtureHeight {
+				return
+			}
+			failures := filepath.Join("testdata", "failures")
+			os.MkdirAll(failures, 0o755)
+			WritePNG(filepath.Join(failures, name+".png"), got)
+			WritePNG(filepath.Join(failures, name+".diff.png"), diff.Image)
+			t.Errorf("%d pixels differ from %s; see %s", diff.Pixels, goldenPath, failures)
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	want := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	got := image.NewRGBA(want.Bounds())
+	for i := range want.Pix {
+		want.Pix[i], got.Pix[i] = 200, 200
+	}
+	got.SetRGBA(1, 1, color.RGBA{210, 190, 200, 200}) // within the tolerance
+	got.SetRGBA(2, 3, color.RGBA{0, 0, 0, 255})
+	if diff := Compare(got, want, tolerance); diff.Pixels != 1 {
+		t.Errorf("Compare found %d differing pixels, want 1", diff.Pixels)
+	}
+	if diff := Compare(image.NewRGBA(image.Rect(0, 0, 2, 2)), want, tolerance); diff.Pixels != 16 {
+		t.Errorf("images of different sizes: %d differing pixels, want 16", diff.Pixels)
+	}
+}
@@ -0,0 +1,249 @@
+// Package headless loads, scripts and lays out pages without a window. It
+// links the dom, css, layout and spidergopher packages but not ebiten or any
+// graphics, so it builds anywhere Go does (CGO_ENABLED=0 included) for
+// scraping, testing and server-side rendering checks.
+package headless
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go-browser/css"
+	"go-browser/dom"
+	"go-browser/layout"
+	"go-browser/spidergopher"
+	"go-browser/spidergopher/webapi"
+
+	"github.com/dop251/goja"
+)
+
+// =============================================================================
+// HEADLESS PAGES
+// A Page is a document parsed and styled with its stylesheets, the inline
+// and linked ones. Its scripts only run when RunScripts or Eval asks, on an
+// engine of its own whose event loop keeps running timers and fetch
+// callbacks until Close. Query and Layout run on that loop too, so they
+// never see the DOM halfway through a script's change.
+// =============================================================================
+
+// Page is a document loaded without a window
+type Page struct {
+	Document    *dom.Document
+	Stylesheets []*css.Stylesheet
+
+	engine  *spidergopher.Engine
+	mu      sync.Mutex // guards errors and console, which the loop appends to
+	errors  []spidergopher.ScriptError
+	console []webapi.ConsoleMessage
+}
+
+// LoadHTML parses html as a page at pageURL, which relative links,
+// stylesheets and scripts resolve against, and styles it for the default
+// medium, a 1024x768 screen
+func LoadHTML(html, pageURL string) *Page {
+	doc := dom.ParseDocument(html)
+	doc.SetURL(pageURL)
+	stylesheets := css.ExtractStylesheets(doc.Node)
+	stylesheets = append(stylesheets, css.FetchExternalStylesheets(doc.Node, doc.BaseURL, nil)...)
+	css.LoadImports(stylesheets, doc.BaseURL, nil)
+	css.ApplyStylesToTree(doc.Node, stylesheets, css.DefaultMedia())
+	return &Page{Document: doc, Stylesheets: stylesheets}
+}
+
+// Load fetches target, an http(s) or file:// URL or a local path, and
+// parses it with LoadHTML
+func Load(target string) (*Page, error) {
+	if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+		resp, err := http.Get(target)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("%s: %s", target, resp.Status)
+		}
+		content, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return LoadHTML(string(content), resp.Request.URL.String()), nil
+	}
+
+	path := target
+	if u, err := url.Parse(target); err == nil && u.Scheme == "file" {
+		path = u.Path
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return LoadHTML(string(content), (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()), nil
+}
+
+// RunScripts runs the page's <script> tags in document order, then fires
+// DOMContentLoaded and load. Errors the scripts don't catch are kept for
+// ScriptErrors rather than stopping the scripts after them.
+func (p *Page) RunScripts() {
+	e := p.startEngine()
+	for _, script := range spidergopher.PageScripts(p.Document.Node, p.Document.BaseURL, nil, nil) {
+		if script.Line > 0 {
+			e.RunInlineScript(script.Source, script.Line, script.Column)
+		} else {
+			e.RunScript(script.Source, script.URL)
+		}
+	}
+	e.DocumentParsed()
+	e.DocumentLoaded()
+}
+
+// Eval runs source as a script of the page and returns its completion
+// value as a Go value
+func (p *Page) Eval(source string) (any, error) {
+	value, err := p.startEngine().RunScript(source, p.Document.URL)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, nil
+	}
+	return value.Export(), nil
+}
+
+// startEngine returns the page's script engine, starting it the first time
+func (p *Page) startEngine() *spidergopher.Engine {
+	if p.engine != nil {
+		return p.engine
+	}
+	p.engine = spidergopher.NewEngine()
+	p.engine.OnError(func(err spidergopher.ScriptError) {
+		p.mu.Lock()
+		p.errors = append(p.errors, err)
+		p.mu.Unlock()
+	})
+	p.engine.Console.OnMessage(func(msg webapi.ConsoleMessage) {
+		p.mu.Lock()
+		p.console = append(p.console, msg)
+		p.mu.Unlock()
+	})
+	p.engine.SetDocument(p.Document)
+	p.engine.Start()
+	return p.engine
+}
+
+// ScriptErrors returns the errors the page's scripts didn't handle so far
+func (p *Page) ScriptErrors() []spidergopher.ScriptError {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]spidergopher.ScriptError(nil), p.errors...)
+}
+
+// ConsoleMessages returns what the page's scripts logged so far
+func (p *Page) ConsoleMessages() []webapi.ConsoleMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]webapi.ConsoleMessage(nil), p.console...)
+}
+
+// Close stops the page's scripts, timers and workers
+func (p *Page) Close() {
+	if p.engine != nil {
+		p.engine.Stop()
+	}
+}
+
+// onLoop runs fn between the page's script tasks
+func (p *Page) onLoop(fn func()) {
+	if p.engine == nil {
+		fn()
+		return
+	}
+	p.engine.Loop.RunOnLoop(func(*goja.Runtime) { fn() })
+}
+
+// Query returns the elements matching a CSS selector list, in document order
+func (p *Page) Query(selector string) []*dom.Node {
+	selectors := css.ParseSelectors(selector)
+	var matches []*dom.Node
+	var walk func(node *dom.Node)
+	walk = func(node *dom.Node) {
+		if node.Type == dom.NodeElement {
+			for _, sel := range selectors {
+				if sel.Matches(node) {
+					matches = append(matches, node)
+					break
+				}
+			}
+		}
+		for _, child := range node.Children {
+			walk(child)
+		}
+	}
+	p.onLoop(func() { walk(p.Document.Node) })
+	return matches
+}
+
+// Layout styles the page again, taking in what its scripts changed, and
+// lays it out in a viewport width pixels wide
+func (p *Page) Layout(width float64) *layout.RenderBox {
+	media := css.DefaultMedia()
+	media.Width = width
+	var tree *layout.RenderBox
+	p.onLoop(func() {
+		css.ApplyStylesToTree(p.Document.Node, p.Stylesheets, media)
+		tree = layout.BuildRenderTree(p.Document.Node, width, nil)
+	})
+	return tree
+}
+
+// SerializedLayout lays the page out at width and writes the box tree as
+// text, one box per line indented by depth: the element's tag, the text of
+// a text box quoted, or (anonymous) for a box of no element, then its
+// position and size
+//
+//	body 0,0 1024x86.8
+//	  h1 0,21 1024x44.8
+//	    (anonymous) 0,21 1024x0
+//	      "Hello" 0,21 88x44.8
+func (p *Page) SerializedLayout(width float64) string {
+	var sb strings.Builder
+	writeBox(&sb, p.Layout(width), 0)
+	return sb.String()
+}
+
+// writeBox writes box and its children at the given depth
+func writeBox(sb *strings.Builder, box *layout.RenderBox, depth int) {
+	if box == nil {
+		return
+	}
+	sb.WriteString(strings.Repeat("  ", depth))
+	switch {
+	case box.Text != "":
+		sb.WriteString(strconv.Quote(box.Text))
+	case box.Node != nil && box.Node.Type == dom.NodeElement:
+		sb.WriteString(box.Node.Tag)
+	case box.Node != nil && box.Node.Type == dom.NodeDocument:
+		sb.WriteString("#document")
+	default:
+		sb.WriteString("(anonymous)")
+	}
+	fmt.Fprintf(sb, " %s,%s %sx%s\n", formatPx(box.X), formatPx(box.Y), formatPx(box.W), formatPx(box.H))
+	for _, child := range box.Children {
+		writeBox(sb, child, depth+1)
+	}
+}
+
+// formatPx writes a length in pixels with at most two decimals
+func formatPx(v float64) string {
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,103 @@
+package headless
+
+import (
+	"strings"
+	"testing"
+)
+
+const testPage = `<html><head><style>.note { color: red; }</style></head><body>
+<h1>Title</h1>
+<p class="note">First</p>
+<p id="second">Second</p>
+<script>
+	document.querySelector("h1").textContent = "Changed";
+	document.getElementById("second").setAttribute("class", "note");
+	console.log("notes", document.querySelectorAll(".note").length);
+</script>
+</body></html>`
+
+func TestQuery(t *testing.T) {
+	page := LoadHTML(testPage, "https://example.com/")
+	defer page.Close()
+
+	if got := len(page.Query("p")); got != 2 {
+		t.Errorf("Query(p) = %d elements, want 2", got)
+	}
+	notes := page.Query("body > p.note, h1")
+	if len(notes) != 2 || notes[0].Tag != "h1" || notes[1].TextContent() != "First" {
+		t.Errorf("Query(body > p.note, h1) = %v, want the h1 then the first p", notes)
+	}
+	if got := len(page.Query(".note")); got != 1 {
+		t.Errorf("Query(.note) before scripts = %d elements, want 1", got)
+	}
+}
+
+func TestRunScripts(t *testing.T) {
+	page := LoadHTML(testPage, "https://example.com/")
+	defer page.Close()
+	page.RunScripts()
+
+	if got := len(page.Query(".note")); got != 2 {
+		t.Errorf("Query(.note) after scripts = %d elements, want 2", got)
+	}
+	if h1 := page.Query("h1"); len(h1) != 1 || h1[0].TextContent() != "Changed" {
+		t.Errorf("h1 after scripts = %v, want its text changed", h1)
+	}
+	messages := page.ConsoleMessages()
+	if len(messages) != 1 || messages[0].Text != "notes 2" {
+		t.Errorf("console = %v, want [notes 2]", messages)
+	}
+	if errs := page.ScriptErrors(); len(errs) != 0 {
+		t.Errorf("script errors = %v, want none", errs)
+	}
+}
+
+func TestEval(t *testing.T) {
+	page := LoadHTML(testPage, "https://example.com/")
+	defer page.Close()
+
+	value, err := page.Eval(`document.querySelector("h1").textContent + "!"`)
+	if err != nil || value != "Title!" {
+		t.Errorf("Eval = %v, %v; want Title!", value, err)
+	}
+	if _, err := page.Eval(`missing()`); err == nil {
+		t.Error("Eval of a failing script returned no error")
+	}
+}
+
+func TestSerializedLayout(t *testing.T) {
+	page := LoadHTML(`<html><body><h1>Hello</h1><p>World</p></body></html>`, "")
+	defer page.Close()
+
+	out := page.SerializedLayout(800)
+	for _, want := range []string{"body ", `"Hello" `, `"World" `} {
+		if !strings.Contains(out, want) {
+			t.Errorf("SerializedLayout missing %q:\n%s", want, out)
+		}
+	}
+	if out != page.SerializedLayout(800) {
+		t.Error("SerializedLayout differs between two runs")
+	}
+	narrow := page.Layout(200)
+	if narrow == nil || narrow.W > 200 {
+		t.Errorf("Layout(200) is %v wide, want at most 200", narrow)
+	}
+}
+
+// TestPagesRunConcurrently opens and closes pages while another page's timer
+// mutates its DOM; go test -race catches the pages sharing state unlocked
+func TestPagesRunConcurrently(t *testing.T) {
+	busy := LoadHTML(`<div id="d"></div>`, "https://a.example/")
+	defer busy.Close()
+	if _, err := busy.Eval(`var el = document.getElementById("d"), i = 0;
+		setInterval(function() { el.setAttribute("data-n", String(i++)); el.addEventListener("click", function() {}); }, 0)`); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		page := LoadHTML(`<p>x</p>`, "https://b.example/")
+		if _, err := page.Eval(`document.title = "t"`); err != nil {
+			t.Fatal(err)
+		}
+		page.Close()
+	}
+}
@@ -0,0 +1,29 @@
+package layout
+
+import "go-browser/dom"
+
+// =============================================================================
+// DETAILS
+// A <details> element shows its <summary> and, while it has the open
+// attribute, the rest of its content
+// =============================================================================
+
+// DetailsSummary returns the summary of a <details>: its first <summary>
+// child, or nil when it has none
+func DetailsSummary(details *dom.Node) *dom.Node {
+	for _, child := range details.Children {
+		if child.Type == dom.NodeElement && child.Tag == "summary" {
+			return child
+		}
+	}
+	return nil
+}
+
+// hiddenInDetails reports whether node is content of a closed <details>
+func hiddenInDetails(node *dom.Node) bool {
+	details := node.Parent
+	if details == nil || details.Tag != "details" || details.HasAttr("open") {
+		return false
+	}
+	return node != DetailsSummary(details)
+}
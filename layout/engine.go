@@ -7,8 +7,6 @@ import (
 
 	"go-browser/css"
 	"go-browser/dom"
-
-	"github.com/hajimehoshi/ebiten/v2"
 )
 
 // Constants for layout
@@ -32,7 +30,8 @@ type RenderBox struct {
 	IsLink   bool
 	IsButton bool
 	LinkURL  string
-	RowIndex int // For table striping
+	LinkNode *dom.Node // the <a> element a link text box belongs to
+	RowIndex int       // For table striping
 	// Image support
 	IsImage  bool
 	ImageURL string
@@ -44,6 +43,24 @@ type RenderBox struct {
 	// Positioning
 	Position string // static, relative, absolute, fixed
 	IsFixed  bool   // true if position: fixed
+	// Inline text styling
+	TextDecoration  string      // underline, line-through, overline, space separated
+	DecorationStyle string      // solid, double, dotted, dashed, wavy
+	DecorationColor *color.RGBA // nil draws decorations in the text color
+	DecorationWidth float64     // decoration thickness; 0 scales it to the font
+	LetterSpacing   float64     // extra space after each character
+	WordSpacing     float64     // extra space after each space
+	Monospace       bool        // text drawn in the monospace font
+	Baseline        float64     // distance from the top of a text box to its baseline
+	// Painting
+	Opacity float64 // opacity compounded with the ancestors'; 1 is opaque
+	Hidden  bool    // visibility: hidden; the box keeps its space but isn't painted
+	// Transform maps the box's subtree around its top-left corner, with
+	// transform-origin applied; nil when it has no transform
+	Transform *css.Matrix
+	// InlineBlock boxes are placed by their line box, so the parent keeps
+	// their geometry
+	InlineBlock bool
 }
 
 // Default spacing for block elements (margin in pixels)
@@ -73,43 +90,83 @@ var ElementSpacing = map[string]float64{
 	"tr":         4,
 	"figure":     16,
 	"figcaption": 8,
+	"dl":         16,
+	"fieldset":   16,
+	"dt":         4,
+	"dd":         4,
 	"hr":         16,
 }
 
+// Size images are laid out at
+const (
+	imageWidth  = 200.0
+	imageHeight = 150.0
+)
+
 // Inline elements that flow horizontally
 var InlineElements = map[string]bool{
 	"a": true, "abbr": true, "b": true, "bdo": true, "br": true,
 	"cite": true, "code": true, "dfn": true, "em": true, "i": true,
 	"kbd": true, "label": true, "q": true, "samp": true, "small": true,
 	"span": true, "strong": true, "sub": true, "sup": true,
-	"time": true, "var": true, "mark": true,
+	"time": true, "var": true, "mark": true, "del": true, "ins": true,
+	"s": true, "u": true, "strike": true, "acronym": true,
 }
 
 // LayoutContext holds the current layout state
 type LayoutContext struct {
 	CursorX, CursorY float64
-	MaxW             float64
+	Left             float64 // X lines start at inside the current block
+	MaxW             float64 // X lines must not extend past
 	LineHeight       float64
-	RowCounter       int
+	InLine           bool           // the current line holds text that hasn't been broken
+	TrailingSpace    bool           // the last text laid out on the line ended with a space
+	Line             []lineFragment // boxes on the current line, aligned when it ends
+	LineTop          float64        // Y of the current line's top
+	LastBaseline     float64        // baseline of the last line that ended
+	Floats           []floatBox     // floats placed so far, which lines flow around
+	Margin           float64        // the vertical margin that ended last, for collapsing
+	MarginEnd        float64        // Y at which that margin ended
+	root             *dom.Node      // the node the render tree is built from
+	pause            func()         // called before each element of the flow in a Layout
+	columnWidths     ColumnWidths   // widths the user gave tables' columns; nil for none
+}
+
+// BuildRenderTree creates a render tree from DOM nodes, sizing table columns
+// the user resized from widths, which may be nil
+func BuildRenderTree(node *dom.Node, width float64, widths ColumnWidths) *RenderBox {
+	return buildRenderTree(node, width, widths, nil)
 }
 
-// BuildRenderTree creates a render tree from DOM nodes
-func BuildRenderTree(node *dom.Node, width float64) *RenderBox {
+// buildRenderTree lays node out, calling pause, when set, before each
+// element of the page's flow
+func buildRenderTree(node *dom.Node, width float64, widths ColumnWidths, pause func()) *RenderBox {
 	box := &RenderBox{Node: node, W: width}
-	ctx := &LayoutContext{CursorX: 0, CursorY: 0, MaxW: width, LineHeight: 24}
+	ctx := &LayoutContext{CursorX: 0, CursorY: 0, MaxW: width, LineHeight: 24, root: node, pause: pause, columnWidths: widths}
 	layoutRecursive(node, box, ctx)
-	box.H = ctx.CursorY + ctx.LineHeight
+	ctx.finishLine()
+	box.H = max(ctx.CursorY, ctx.clearance("both")) + ctx.LineHeight
+	resolvePaintStyle(box, 1, false)
+	resolveTransforms(box, nil)
 	return box
 }
 
 func layoutRecursive(node *dom.Node, container *RenderBox, ctx *LayoutContext) {
-	if node.Tag == "title" && len(node.Children) > 0 && node.Children[0].Type == dom.NodeText {
-		ebiten.SetWindowTitle("GoBrowser: " + node.Children[0].Content)
+	if ctx.pause != nil && node.Type == dom.NodeElement {
+		ctx.pause()
+	}
+	// The title is read by the load pipeline, it is never laid out
+	if node.Tag == "title" {
 		return
 	}
 	if node.Display == dom.DisplayNone {
 		return
 	}
+	// A closed <details> shows only its summary, and a modal dialog is laid
+	// out over the page on its own
+	if hiddenInDetails(node) || (node.TopLayer && node != ctx.root) {
+		return
+	}
 
 	// Skip elements that are handled by their parent (like option inside select)
 	switch node.Tag {
@@ -126,44 +183,47 @@ func layoutRecursive(node *dom.Node, container *RenderBox, ctx *LayoutContext) {
 		}
 	}
 
-	// Apply margin-top from CSS for block elements
-	marginTop := 0.0
-	marginBottom := 0.0
-	paddingLeft := 0.0
-	paddingTop := 0.0
+	// Margins, borders and padding from CSS, percentages taken against the
+	// containing block's width
+	var edges boxEdges
+	var style *css.ComputedStyle
 	position := "static"
 
 	if node.ComputedStyle != nil {
 		if cs, ok := node.ComputedStyle.(*css.ComputedStyle); ok {
-			marginTop = cs.MarginTop
-			marginBottom = cs.MarginBottom
-			paddingLeft = cs.PaddingLeft
-			paddingTop = cs.PaddingTop
+			style = cs
+			edges = edgesOf(cs, ctx.MaxW-ctx.Left)
 			if cs.Position != "" {
 				position = cs.Position
 			}
 		}
 	}
+	marginTop, marginBottom := edges.margin.Top, edges.margin.Bottom
+	marginLeft, marginRight := edges.margin.Left, edges.margin.Right
+	paddingLeft, paddingRight := edges.padding.Left, edges.padding.Right
+	paddingTop, paddingBottom := edges.padding.Top, edges.padding.Bottom
+	borderTop, borderRight := edges.border.Top, edges.border.Right
+	borderBottom, borderLeft := edges.border.Bottom, edges.border.Left
 
 	// Store position in container for later use by render
 	container.Position = position
 	container.IsFixed = position == "fixed"
 
-	// Apply margin-top
-	if marginTop > 0 {
-		ctx.CursorY += marginTop
-	}
-
 	// Determine if this is a block or inline element
 	isInline := InlineElements[node.Tag]
 	defaultSpacing := ElementSpacing[node.Tag]
 	isBlockElement := !isInline && defaultSpacing > 0
 
 	// Also check for display from CSS
-	isInlineBlock := false
+	isInlineBlock, isFloat := false, false
 	if node.ComputedStyle != nil {
 		if cs, ok := node.ComputedStyle.(*css.ComputedStyle); ok {
-			if cs.Display == "inline-block" {
+			if isFloating(node, cs) {
+				// Floats lay out on their own, outside the lines and blocks
+				isFloat = true
+				isBlockElement = false
+				isInline = true
+			} else if cs.Display == "inline-block" {
 				isInlineBlock = true
 				isBlockElement = false
 				isInline = true
@@ -176,45 +236,81 @@ func layoutRecursive(node *dom.Node, container *RenderBox, ctx *LayoutContext) {
 			}
 		}
 	}
-	_ = isInlineBlock // Will be used for inline-block specific layout
 
 	// Block elements always start on new line with proper spacing
+	originalLeft, originalMaxW := ctx.Left, ctx.MaxW
 	if isBlockElement {
-		// Always reset X to 0 for block elements
-		ctx.CursorX = 0
-		// Add default spacing if no CSS margin
-		if marginTop == 0 && defaultSpacing > 0 {
-			ctx.CursorY += defaultSpacing
+		// Inline content left on the current line ends before the block
+		ctx.endLine()
+		// The top margin, or the default spacing when there is none,
+		// collapses with the margin above it
+		if marginTop != 0 {
+			ctx.addMargin(marginTop)
+		} else {
+			ctx.addMargin(defaultSpacing)
+		}
+		if cs, ok := node.ComputedStyle.(*css.ComputedStyle); ok && cs.Clear != "" {
+			ctx.CursorY = max(ctx.CursorY, ctx.clearance(cs.Clear))
 		}
+		// The block's box spans its parent's content box less its margins;
+		// its own lines start inside its border and padding
+		container.X, container.Y = ctx.Left+marginLeft, ctx.CursorY
+		ctx.Left = container.X + borderLeft + paddingLeft
+		ctx.MaxW -= marginRight + borderRight + paddingRight
+		ctx.CursorY += borderTop
+		ctx.CursorX = ctx.lineLeft(ctx.CursorY)
+	} else {
+		if marginTop > 0 {
+			ctx.CursorY += marginTop
+		}
+		// Apply padding to starting position
+		ctx.CursorX += paddingLeft
 	}
-
-	// Apply padding to starting position
-	ctx.CursorX += paddingLeft
 	ctx.CursorY += paddingTop
+	contentTop := ctx.CursorY
 
-	// Apply width/max-width constraints
-	originalMaxW := ctx.MaxW
+	// Apply width/max-width constraints, which box-sizing: border-box takes
+	// to include the padding and border
 	if node.ComputedStyle != nil {
 		if cs, ok := node.ComputedStyle.(*css.ComputedStyle); ok {
+			// Percentages and min()/max()/clamp() resolve against the
+			// containing block's width
+			availW := ctx.MaxW - ctx.Left
+			cbW := originalMaxW - originalLeft
+			size := func(l css.Length) float64 {
+				return contentSize(cs, l.Resolve(cs.FontSize, cbW, cs.Media.Viewport), edges.width())
+			}
+
 			// Apply max-width if set
-			if cs.MaxWidth > 0 && cs.MaxWidth < ctx.MaxW {
-				ctx.MaxW = cs.MaxWidth
+			if cs.MaxWidth.IsSet() && size(cs.MaxWidth) < availW {
+				ctx.MaxW = ctx.Left + size(cs.MaxWidth)
 			}
 			// Apply explicit width if set
-			if cs.Width > 0 {
-				ctx.MaxW = cs.Width
+			if cs.Width.IsSet() {
+				ctx.MaxW = ctx.Left + size(cs.Width)
+			}
+			// min-width wins over both
+			if cs.MinWidth.IsSet() && size(cs.MinWidth) > ctx.MaxW-ctx.Left {
+				ctx.MaxW = ctx.Left + size(cs.MinWidth)
 			}
 		}
 	}
-	_ = originalMaxW // Will be used to restore later if needed
-
-	// Track row for table striping
-	if node.Tag == "tr" {
-		ctx.RowCounter++
-	}
-
-	if node.Tag == "td" {
-		ctx.MaxW = 180
+	if isBlockElement {
+		// An auto left margin takes the width the block leaves free, half
+		// of it when the right margin is auto too, which centers the block
+		if cs, ok := node.ComputedStyle.(*css.ComputedStyle); ok && cs.MarginLeftAuto {
+			free := originalMaxW - marginRight - (ctx.MaxW + paddingRight + borderRight)
+			if cs.MarginRightAuto {
+				free /= 2
+			}
+			if free > 0 {
+				container.X += free
+				ctx.Left += free
+				ctx.MaxW += free
+				ctx.CursorX += free
+			}
+		}
+		container.W = ctx.MaxW + paddingRight + borderRight - container.X
 	}
 
 	startX := ctx.CursorX
@@ -230,9 +326,13 @@ func layoutRecursive(node *dom.Node, container *RenderBox, ctx *LayoutContext) {
 		isLink := false
 		isButton := false
 		linkURL := ""
+		var linkNode *dom.Node
 		textAlign := "left"
 		var textColor *color.RGBA
 		var bgColor *color.RGBA
+		transform := ""
+		letterSpacing, wordSpacing := 0.0, 0.0
+		mono := false
 
 		if node.Parent != nil {
 			// Try to get computed styles from parent
@@ -256,6 +356,10 @@ func layoutRecursive(node *dom.Node, container *RenderBox, ctx *LayoutContext) {
 					if cs.TextAlign != "" {
 						textAlign = cs.TextAlign
 					}
+					transform = cs.TextTransform
+					letterSpacing = cs.LetterSpacing.Resolve(cs.FontSize, 0, cs.Media.Viewport)
+					wordSpacing = cs.WordSpacing.Resolve(cs.FontSize, 0, cs.Media.Viewport)
+					mono = css.IsMonospace(cs.FontFamily)
 				}
 			}
 
@@ -277,6 +381,7 @@ func layoutRecursive(node *dom.Node, container *RenderBox, ctx *LayoutContext) {
 			case "a":
 				isLink = true
 				linkURL = node.Parent.GetAttr("href")
+				linkNode = node.Parent
 				// Check if it looks like a button
 				class := node.Parent.GetAttr("class")
 				if strings.Contains(class, "btn") || strings.Contains(class, "button") ||
@@ -288,76 +393,121 @@ func layoutRecursive(node *dom.Node, container *RenderBox, ctx *LayoutContext) {
 			}
 		}
 
-		words := strings.Fields(node.Content)
+		inline := inlineTextStyleOf(node)
+		opacity, hidden := paintStyleOf(node)
+		if bgColor == nil {
+			bgColor = inline.bg
+		}
+		// The glyphs sit in the middle of the line height
+		baseline := (lineH-fontSize*(textAscent+textDescent))/2 + fontSize*textAscent
+
+		mode := whiteSpaceOf(node)
 		line := ""
 		charW := fontSize * 0.55
-
-		for _, w := range words {
-			wLen := float64(len(w)+1) * charW
-			if ctx.CursorX+wLen > ctx.MaxW {
-				childBox := &RenderBox{
-					Text: line, X: startX, Y: ctx.CursorY,
-					W: ctx.CursorX - startX, H: lineH,
-					FontSize: fontSize, IsH1: isH1, IsH2: isH2, IsBold: isBold,
-					IsLink: isLink, IsButton: isButton, LinkURL: linkURL,
-					TextColor: textColor, BgColor: bgColor, TextAlign: textAlign,
-				}
-				container.Children = append(container.Children, childBox)
-
-				ctx.CursorX = 0
-				ctx.CursorY += lineH
-				startX = 0
-				line = w + " "
-				ctx.CursorX = wLen
-			} else {
-				line += w + " "
-				ctx.CursorX += wLen
-			}
+		if mono {
+			charW = fontSize * monoAdvance
 		}
+		textWidth := func(s string) float64 { return spacedWidth(s, charW, letterSpacing, wordSpacing) }
 
-		if len(line) > 0 {
+		// emitLine flushes the text gathered on the current line into a box
+		emitLine := func() {
+			if line == "" {
+				return
+			}
 			childBox := &RenderBox{
 				Text: line, X: startX, Y: ctx.CursorY,
 				W: ctx.CursorX - startX, H: lineH,
 				FontSize: fontSize, IsH1: isH1, IsH2: isH2, IsBold: isBold,
-				IsLink: isLink, IsButton: isButton, LinkURL: linkURL,
+				IsLink: isLink, IsButton: isButton, LinkURL: linkURL, LinkNode: linkNode,
 				TextColor: textColor, BgColor: bgColor, TextAlign: textAlign,
+				TextDecoration: inline.decoration, DecorationStyle: inline.decorationStyle,
+				DecorationColor: inline.decorationColor, DecorationWidth: inline.decorationWidth,
+				LetterSpacing: letterSpacing, WordSpacing: wordSpacing, Monospace: mono,
+				Opacity: opacity, Hidden: hidden,
+				Baseline: baseline,
 			}
 			container.Children = append(container.Children, childBox)
+			ctx.addFragment(lineFragment{box: childBox, ascent: baseline, descent: lineH - baseline, shift: inline.shift})
+			line = ""
+		}
+		// newLine breaks the line; a forced break of an empty line still
+		// takes a line's height
+		newLine := func() {
+			emitLine()
+			if !ctx.InLine {
+				ctx.CursorY += lineH
+			}
+			ctx.endLine()
+			startX = ctx.CursorX
+		}
 
-			// After text, if parent is block element, move to next line
-			if node.Parent != nil {
-				parentTag := node.Parent.Tag
-				isParentBlock := parentTag == "p" || parentTag == "div" || parentTag == "h1" ||
-					parentTag == "h2" || parentTag == "h3" || parentTag == "li" ||
-					parentTag == "section" || parentTag == "article"
-				if isParentBlock {
-					ctx.CursorY += lineH
-					ctx.CursorX = 0
+		for i, text := range processWhiteSpace(node.Content, mode) {
+			if i > 0 {
+				newLine()
+			}
+			text = transformText(text, transform, !ctx.InLine || ctx.TrailingSpace)
+			// A collapsible space at the start of a line, or after another
+			// space, is not rendered
+			if mode.collapse && (!ctx.InLine || ctx.TrailingSpace) {
+				text = strings.TrimLeft(text, " ")
+			}
+			if text == "" {
+				continue
+			}
+			for _, piece := range splitBreakable(text) {
+				// Trailing spaces hang past the edge instead of forcing a wrap
+				wordW := textWidth(strings.TrimRight(piece, " "))
+				if mode.wrap && ctx.CursorX+wordW > ctx.lineRight(ctx.CursorY) && (line != "" || ctx.InLine) {
+					newLine()
+					if mode.collapse {
+						piece = strings.TrimLeft(piece, " ")
+					}
 				}
+				line += piece
+				ctx.CursorX += textWidth(piece)
 			}
+			ctx.TrailingSpace = strings.HasSuffix(text, " ")
 		}
+		emitLine()
+	} else if cs, ok := node.ComputedStyle.(*css.ComputedStyle); ok && isFloat {
+		// Undo the padding applied for in-flow boxes; the float places its
+		// own content within it
+		ctx.CursorX -= paddingLeft
+		ctx.CursorY -= paddingTop + max(marginTop, 0)
+		contentMaxW := ctx.MaxW - ctx.Left
+		ctx.MaxW = originalMaxW
+		layoutFloat(node, container, ctx, cs, edges, contentMaxW)
+		return
 	} else if node.Tag == "hr" {
+		ctx.endLine()
 		ctx.CursorY += 12
-		childBox := &RenderBox{Node: node, X: 0, Y: ctx.CursorY, W: ctx.MaxW, H: 2}
+		childBox := &RenderBox{Node: node, X: ctx.Left, Y: ctx.CursorY, W: ctx.MaxW - ctx.Left, H: 2}
 		container.Children = append(container.Children, childBox)
 		ctx.CursorY += 16
-		ctx.CursorX = 0
+		ctx.CursorX = ctx.lineLeft(ctx.CursorY)
 	} else if node.Tag == "br" {
-		ctx.CursorX = 0
-		ctx.CursorY += ctx.LineHeight
+		if !ctx.InLine {
+			ctx.CursorY += ctx.LineHeight
+		}
+		ctx.endLine()
+		// <br clear> moves the next line below the floats
+		switch clear := strings.ToLower(node.GetAttr("clear")); clear {
+		case "all", "both", "left", "right":
+			if clear == "all" {
+				clear = "both"
+			}
+			ctx.CursorY = max(ctx.CursorY, ctx.clearance(clear))
+			ctx.CursorX = ctx.lineLeft(ctx.CursorY)
+		}
 	} else if node.Tag == "img" {
 		// Handle image tags
-		src := node.GetAttr("src")
+		src := ImageSource(node)
 		if src != "" {
-			imgW := 200.0 // Default width
-			imgH := 150.0 // Default height
+			imgW := imageWidth
+			imgH := imageHeight
 
 			// New line for images
-			if ctx.CursorX > 0 {
-				ctx.CursorX = 0
-				ctx.CursorY += ctx.LineHeight
-			}
+			ctx.endLine()
 
 			childBox := &RenderBox{
 				Node:     node,
@@ -371,6 +521,10 @@ func layoutRecursive(node *dom.Node, container *RenderBox, ctx *LayoutContext) {
 			container.Children = append(container.Children, childBox)
 			ctx.CursorY += imgH + 10
 		}
+	} else if node.Tag == "video" || node.Tag == "audio" || node.Tag == "canvas" {
+		layoutReplaced(node, container, ctx, style)
+	} else if node.Tag == "progress" || node.Tag == "meter" {
+		layoutGauge(node, container, ctx, style)
 	} else if node.Tag == "input" || node.Tag == "select" || node.Tag == "textarea" {
 		// Handle form input elements - give them proper size and spacing
 		inputType := node.GetAttr("type")
@@ -403,10 +557,7 @@ func layoutRecursive(node *dom.Node, container *RenderBox, ctx *LayoutContext) {
 
 		// For non-checkbox/radio, start on new line if there's content
 		if inputType != "checkbox" && inputType != "radio" {
-			if ctx.CursorX > 0 {
-				ctx.CursorX = 0
-				ctx.CursorY += ctx.LineHeight
-			}
+			ctx.endLine()
 		}
 
 		childBox := &RenderBox{
@@ -420,18 +571,19 @@ func layoutRecursive(node *dom.Node, container *RenderBox, ctx *LayoutContext) {
 
 		// Move cursor based on input type
 		if inputType == "checkbox" || inputType == "radio" {
-			// Checkboxes and radios stay inline, just move X
+			// Checkboxes and radios stay inline, their bottom on the baseline
+			ctx.addFragment(lineFragment{box: childBox, ascent: inputH})
 			ctx.CursorX += inputW + 8
 		} else {
 			// Other inputs are block, move to next line
 			ctx.CursorY += inputH + 12
-			ctx.CursorX = 0
+			ctx.CursorX = ctx.lineLeft(ctx.CursorY)
 		}
 	} else if node.Tag == "button" {
 		// Handle button elements - always start on new line with extra spacing
-		if ctx.CursorX > 0 {
-			ctx.CursorX = 0
-			ctx.CursorY += ctx.LineHeight + 10 // Extra space after inline elements
+		if ctx.InLine {
+			ctx.endLine()
+			ctx.CursorY += 10 // Extra space after inline elements
 		} else {
 			// Even if starting from X=0, add spacing to separate from content above
 			ctx.CursorY += 15
@@ -446,7 +598,16 @@ func layoutRecursive(node *dom.Node, container *RenderBox, ctx *LayoutContext) {
 		}
 		container.Children = append(container.Children, childBox)
 		ctx.CursorY += 48
-		ctx.CursorX = 0
+		ctx.CursorX = ctx.lineLeft(ctx.CursorY)
+	} else if cs, ok := node.ComputedStyle.(*css.ComputedStyle); ok && isInlineBlock {
+		// Undo the margin, padding and width applied for in-flow boxes;
+		// the inline-block places its own content within them
+		ctx.CursorX -= paddingLeft
+		ctx.CursorY -= paddingTop + max(marginTop, 0)
+		contentMaxW := ctx.MaxW - ctx.Left
+		ctx.MaxW = originalMaxW
+		layoutInlineBlock(node, container, ctx, cs, edges, contentMaxW)
+		return
 	} else {
 		// Check if this is a flex or grid container
 		isFlex := false
@@ -482,7 +643,7 @@ func layoutRecursive(node *dom.Node, container *RenderBox, ctx *LayoutContext) {
 			currentRowY := startY
 			maxRowH := 0.0
 
-			for _, child := range node.Children {
+			for _, child := range boxChildren(node) {
 				childBox := &RenderBox{Node: child}
 
 				// Calculate position in grid
@@ -490,13 +651,15 @@ func layoutRecursive(node *dom.Node, container *RenderBox, ctx *LayoutContext) {
 
 				// Create a temporary context for child layout
 				childCtx := &LayoutContext{
-					CursorX:    0,
-					CursorY:    0,
-					MaxW:       colWidth,
-					LineHeight: ctx.LineHeight,
+					CursorX:      0,
+					CursorY:      0,
+					MaxW:         colWidth,
+					LineHeight:   ctx.LineHeight,
+					columnWidths: ctx.columnWidths,
 				}
 
 				layoutRecursive(child, childBox, childCtx)
+				childCtx.finishLine()
 
 				childBox.X = childX
 				childBox.Y = currentRowY
@@ -524,106 +687,83 @@ func layoutRecursive(node *dom.Node, container *RenderBox, ctx *LayoutContext) {
 			} else {
 				ctx.CursorY = currentRowY
 			}
-		} else if isFlex && flexDirection == "row" {
-			// Horizontal flex layout
-			childX := ctx.CursorX
-			startY := ctx.CursorY
-			maxChildH := 0.0
-
-			for i, child := range node.Children {
-				childBox := &RenderBox{Node: child}
-
-				// Create a temporary context for child layout
-				childCtx := &LayoutContext{
-					CursorX:    0,
-					CursorY:    0,
-					MaxW:       ctx.MaxW / float64(len(node.Children)), // Distribute width
-					LineHeight: ctx.LineHeight,
-				}
-
-				layoutRecursive(child, childBox, childCtx)
-
-				childBox.X = childX
-				childBox.Y = startY
-				childBox.W = childCtx.CursorX
-				if childBox.W < 50 {
-					childBox.W = 50 // Minimum width
-				}
-				childBox.H = childCtx.CursorY + childCtx.LineHeight
-				if childBox.H > maxChildH {
-					maxChildH = childBox.H
-				}
-
-				container.Children = append(container.Children, childBox)
-
-				childX += childBox.W
-				if i < len(node.Children)-1 {
-					childX += flexGap
-				}
-			}
-
-			ctx.CursorY = startY + maxChildH
+		} else if isFlex && (flexDirection == "row" || flexDirection == "row-reverse") {
+			layoutFlex(node, container, ctx, style, edges)
 		} else if isFlex && (flexDirection == "column" || flexDirection == "column-reverse") {
 			// Vertical flex layout (similar to normal flow but with gap)
-			for i, child := range node.Children {
+			items := boxChildren(node)
+			for i, child := range items {
 				childBox := &RenderBox{Node: child}
 				childYStart := ctx.CursorY
 
 				layoutRecursive(child, childBox, ctx)
 
-				childBox.X = 0
-				childBox.Y = childYStart
-				childBox.W = ctx.MaxW
-				childBox.H = ctx.CursorY - childYStart
+				if childBox.W == 0 {
+					childBox.X, childBox.Y = ctx.Left, childYStart
+					childBox.W, childBox.H = ctx.MaxW-ctx.Left, ctx.CursorY-childYStart
+				}
 				container.Children = append(container.Children, childBox)
 
-				if i < len(node.Children)-1 {
+				if i < len(items)-1 {
 					ctx.CursorY += flexGap
 				}
 			}
+		} else if node.Tag == "table" {
+			layoutTable(node, container, ctx)
 		} else {
-			// Normal block flow layout
-			for _, child := range node.Children {
-				childBox := &RenderBox{Node: child}
-				childYStart := ctx.CursorY
-
-				layoutRecursive(child, childBox, ctx)
-
-				childBox.X = 0
-				childBox.Y = childYStart
-				childBox.W = ctx.MaxW
-				childBox.H = ctx.CursorY - childYStart
-				childBox.RowIndex = ctx.RowCounter
-
-				if node.Tag == "tr" && child.Tag == "td" {
-					ctx.CursorY = childYStart
-					ctx.CursorX += 190
-					childBox.X = ctx.CursorX - 190
-				} else {
-					container.Children = append(container.Children, childBox)
-				}
-			}
-
-			if node.Tag == "tr" {
-				ctx.CursorX = 0
-				ctx.CursorY += ctx.LineHeight * 1.6
-			}
+			layoutBlockChildren(node, container, ctx)
 		}
 	}
 
+	// A block's last line ends with it, and the lines after it start back at
+	// the parent's left edge
+	if isBlockElement {
+		ctx.endLine()
+		// height, min-height and max-height size the box whatever its
+		// content takes; content that doesn't fit overflows it
+		ctx.CursorY = contentTop + contentHeight(style, edges, ctx.CursorY-contentTop) + paddingBottom + borderBottom
+		container.H = ctx.CursorY - container.Y
+		ctx.Left, ctx.MaxW = originalLeft, originalMaxW
+		ctx.CursorX = ctx.lineLeft(ctx.CursorY)
+	}
+
 	// Post-margins - apply margin-bottom from CSS or fallback defaults
 	if marginBottom > 0 {
-		ctx.CursorY += marginBottom
+		ctx.addMargin(marginBottom)
 	} else {
 		// Fallback margins for common elements
 		if node.Tag == "p" {
-			ctx.CursorY += 12
+			ctx.addMargin(12)
 		}
 		if node.Tag == "h1" {
-			ctx.CursorY += 16
+			ctx.addMargin(16)
 		}
 		if node.Tag == "h2" {
-			ctx.CursorY += 12
+			ctx.addMargin(12)
+		}
+	}
+}
+
+// layoutBlockChildren lays out node's children in normal flow: blocks stack
+// and inline content fills line boxes
+func layoutBlockChildren(node *dom.Node, container *RenderBox, ctx *LayoutContext) {
+	for _, child := range node.Children {
+		if tableStructureTags[node.Tag] && child.IsWhiteSpaceText() {
+			continue
+		}
+		childBox := &RenderBox{Node: child}
+		childYStart := ctx.CursorY
+
+		layoutRecursive(child, childBox, ctx)
+
+		if !childBox.InlineBlock {
+			// Blocks place their own border box; other boxes span the
+			// content box from where they started
+			if childBox.W == 0 {
+				childBox.X, childBox.Y = ctx.Left, childYStart
+				childBox.W, childBox.H = ctx.MaxW-ctx.Left, ctx.CursorY-childYStart
+			}
 		}
+		container.Children = append(container.Children, childBox)
 	}
 }
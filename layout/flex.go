@@ -0,0 +1,330 @@
+package layout
+
+import (
+	"math"
+	"slices"
+
+	"go-browser/css"
+	"go-browser/dom"
+)
+
+// =============================================================================
+// FLEXBOX
+// Row flex containers place their items with the CSS Flexible Box algorithm
+// (https://www.w3.org/TR/css-flexbox-1/): items get a base size from their
+// flex-basis or content, break into lines when flex-wrap allows, grow or
+// shrink to fill each line, and are aligned by justify-content, align-items,
+// align-self and align-content. Item sizes are outer sizes, margins included.
+// =============================================================================
+
+// flexItem is one item of a flex container and where the algorithm put it
+type flexItem struct {
+	node         *dom.Node
+	box          *RenderBox
+	baseSize     float64 // flex base size
+	minSize      float64 // min-width, which shrinking stops at
+	margins      float64 // left and right margins
+	grow, shrink float64
+	alignSelf    string
+	sized        bool // has a width of its own
+	stretchable  bool // auto height, so align stretch may grow it
+
+	mainPos, mainSize   float64
+	crossPos, crossSize float64 // crossSize starts as the height the item took
+}
+
+// flexLine is a line of items and its place on the cross axis
+type flexLine struct {
+	items                 []*flexItem
+	crossStart, crossSize float64
+}
+
+// flexContainer holds the container properties the algorithm reads
+type flexContainer struct {
+	direction      string  // row, row-reverse
+	wrap           string  // nowrap, wrap, wrap-reverse
+	justifyContent string  // flex-start, flex-end, center, space-between, space-around, space-evenly
+	alignItems     string  // flex-start, flex-end, center, stretch, baseline
+	alignContent   string  // flex-start, flex-end, center, stretch, space-between, space-around
+	gap, rowGap    float64 // between items, and between lines
+
+	items []*flexItem
+	lines []flexLine
+}
+
+// layoutFlex lays out the items of a row or row-reverse flex container in
+// the content box the context describes
+func layoutFlex(node *dom.Node, container *RenderBox, ctx *LayoutContext, cs *css.ComputedStyle, e boxEdges) {
+	left, top := ctx.Left, ctx.CursorY
+	mainSize := ctx.MaxW - ctx.Left
+	fc := &flexContainer{
+		direction:      cs.FlexDirection,
+		wrap:           cs.FlexWrap,
+		justifyContent: cs.JustifyContent,
+		alignItems:     cs.AlignItems,
+		alignContent:   cs.AlignContent,
+		gap:            cs.ColumnGap,
+		rowGap:         cs.RowGap,
+	}
+	for _, child := range flexOrder(boxChildren(node)) {
+		fc.items = append(fc.items, newFlexItem(child, mainSize, ctx))
+	}
+	if len(fc.items) == 0 {
+		return
+	}
+
+	fc.collectIntoLines(mainSize)
+	for _, line := range fc.lines {
+		fc.resolveFlexibleLengths(line, mainSize)
+		fc.alignMainAxis(line, mainSize)
+	}
+	// Laid out again at their flexed widths, items take the height they need
+	for _, item := range fc.items {
+		item.box, item.crossSize = layoutFlexItem(item.node, item.mainSize, mainSize, ctx)
+		if item.sized {
+			item.box.W = max(item.mainSize-item.margins, 0)
+		}
+	}
+
+	crossSize := 0.0
+	if definiteHeight(cs.Height) {
+		crossSize = contentHeight(cs, e, 0)
+	}
+	used := fc.alignCrossAxis(crossSize)
+	for _, line := range fc.lines {
+		for _, item := range line.items {
+			if item.stretchable && fc.itemAlign(item) == "stretch" {
+				item.box.H += line.crossSize - item.crossSize
+			}
+			translateBox(item.box, left+item.mainPos, top+item.crossPos)
+			container.Children = append(container.Children, item.box)
+		}
+	}
+	ctx.CursorY = top + used
+}
+
+// flexOrder sorts flex items by their order property, keeping document order
+// among equals
+func flexOrder(nodes []*dom.Node) []*dom.Node {
+	order := func(n *dom.Node) int {
+		if cs, ok := n.ComputedStyle.(*css.ComputedStyle); ok {
+			return cs.Order
+		}
+		return 0
+	}
+	slices.SortStableFunc(nodes, func(a, b *dom.Node) int { return order(a) - order(b) })
+	return nodes
+}
+
+// newFlexItem measures an item in a container mainSize wide: its base size is
+// its flex-basis, else its width, else the width its content takes. outer is
+// the container's context.
+func newFlexItem(node *dom.Node, mainSize float64, outer *LayoutContext) *flexItem {
+	box, _ := layoutFlexItem(node, mainSize, mainSize, outer)
+	item := &flexItem{node: node, shrink: 1, baseSize: math.Ceil(contentWidth(box))}
+	cs, ok := node.ComputedStyle.(*css.ComputedStyle)
+	if !ok || node.Type != dom.NodeElement {
+		return item
+	}
+
+	e := edgesOf(cs, mainSize)
+	item.margins = e.margin.Left + e.margin.Right
+	item.sized = cs.Width.IsSet()
+	right := contentWidth(box) + e.padding.Right + e.border.Right
+	if item.sized {
+		right = box.X + box.W
+	}
+	item.baseSize = math.Ceil(right + e.margin.Right)
+	if cs.FlexBasis > 0 {
+		item.baseSize = contentSize(cs, cs.FlexBasis, e.width()) + e.width() + item.margins
+	}
+	if cs.MinWidth.IsSet() {
+		item.minSize = contentSize(cs, cs.MinWidth.Resolve(cs.FontSize, mainSize, cs.Media.Viewport), e.width()) + e.width() + item.margins
+	}
+	item.grow, item.shrink = cs.FlexGrow, cs.FlexShrink
+	item.alignSelf = cs.AlignSelf
+	item.stretchable = !definiteHeight(cs.Height)
+	return item
+}
+
+// layoutFlexItem lays out an item on its own, its margin box at the origin,
+// and returns its box and the height it took. A block item fills width; one
+// with a width of its own is laid out in the container, mainSize wide, for
+// percentages to resolve against it.
+func layoutFlexItem(node *dom.Node, width, mainSize float64, outer *LayoutContext) (*RenderBox, float64) {
+	box := &RenderBox{Node: node}
+	if cs, ok := node.ComputedStyle.(*css.ComputedStyle); ok && node.Type == dom.NodeElement && cs.Width.IsSet() {
+		width = mainSize
+	}
+	ctx := &LayoutContext{MaxW: width, LineHeight: outer.LineHeight, columnWidths: outer.columnWidths}
+	layoutRecursive(node, box, ctx)
+	ctx.endLine()
+	h := max(ctx.CursorY, ctx.clearance("both"))
+	if box.W == 0 {
+		// Text and inline items get a box around their lines
+		box.W, box.H = width, h
+	}
+	return box, h
+}
+
+// collectIntoLines divides the items into flex lines: one line unless
+// flex-wrap lets items that overflow the main size start another
+func (fc *flexContainer) collectIntoLines(mainSize float64) {
+	if fc.wrap == "nowrap" || fc.wrap == "" {
+		fc.lines = []flexLine{{items: fc.items}}
+		return
+	}
+
+	var line flexLine
+	used := 0.0
+	for _, item := range fc.items {
+		size := item.baseSize
+		if len(line.items) > 0 {
+			size += fc.gap
+		}
+		if len(line.items) > 0 && used+size > mainSize {
+			fc.lines = append(fc.lines, line)
+			line = flexLine{}
+			size = item.baseSize
+			used = 0
+		}
+		used += size
+		line.items = append(line.items, item)
+	}
+	fc.lines = append(fc.lines, line)
+	if fc.wrap == "wrap-reverse" {
+		slices.Reverse(fc.lines)
+	}
+}
+
+// resolveFlexibleLengths grows or shrinks the items of a line by their
+// flex-grow and flex-shrink factors to fill the main size
+func (fc *flexContainer) resolveFlexibleLengths(line flexLine, mainSize float64) {
+	used := fc.gap * float64(len(line.items)-1)
+	totalGrow, totalShrink := 0.0, 0.0
+	for _, item := range line.items {
+		used += item.baseSize
+		totalGrow += item.grow
+		totalShrink += item.shrink * item.baseSize
+	}
+	free := mainSize - used
+
+	for _, item := range line.items {
+		item.mainSize = item.baseSize
+		switch {
+		case free > 0 && totalGrow > 0:
+			item.mainSize += free * item.grow / totalGrow
+		case free < 0 && totalShrink > 0:
+			// Shrinking is weighted by base size, so large items give more
+			item.mainSize += free * item.shrink * item.baseSize / totalShrink
+		}
+		item.mainSize = max(item.mainSize, item.minSize, 0)
+	}
+}
+
+// alignMainAxis places the items of a line along the main axis by
+// justify-content. row-reverse starts from the right edge.
+func (fc *flexContainer) alignMainAxis(line flexLine, mainSize float64) {
+	n := len(line.items)
+	used := fc.gap * float64(n-1)
+	for _, item := range line.items {
+		used += item.mainSize
+	}
+	free := max(mainSize-used, 0)
+
+	start, spacing := 0.0, 0.0
+	switch fc.justifyContent {
+	case "flex-end", "end":
+		start = free
+	case "center":
+		start = free / 2
+	case "space-between":
+		if n > 1 {
+			spacing = free / float64(n-1)
+		}
+	case "space-around":
+		spacing = free / float64(n)
+		start = spacing / 2
+	case "space-evenly":
+		spacing = free / float64(n+1)
+		start = spacing
+	}
+
+	pos := start
+	for _, item := range line.items {
+		item.mainPos = pos
+		if fc.direction == "row-reverse" {
+			item.mainPos = mainSize - pos - item.mainSize
+		}
+		pos += item.mainSize + fc.gap + spacing
+	}
+}
+
+// alignCrossAxis sizes each line to its tallest item, places the lines by
+// align-content within crossSize (0 when the container's height is auto)
+// and the items within their line by align-items and align-self. It
+// returns the height the lines take.
+func (fc *flexContainer) alignCrossAxis(crossSize float64) float64 {
+	total := fc.rowGap * float64(len(fc.lines)-1)
+	for i := range fc.lines {
+		for _, item := range fc.lines[i].items {
+			fc.lines[i].crossSize = max(fc.lines[i].crossSize, item.crossSize)
+		}
+		total += fc.lines[i].crossSize
+	}
+	if crossSize == 0 && len(fc.lines) == 1 {
+		// A single line of an auto-height container is as tall as it is
+		crossSize = total
+	}
+
+	free := max(crossSize-total, 0)
+	n := float64(len(fc.lines))
+	start, spacing := 0.0, 0.0
+	switch fc.alignContent {
+	case "flex-end", "end":
+		start = free
+	case "center":
+		start = free / 2
+	case "space-between":
+		if n > 1 {
+			spacing = free / (n - 1)
+		}
+	case "space-around":
+		spacing = free / n
+		start = spacing / 2
+	case "stretch", "normal", "":
+		for i := range fc.lines {
+			fc.lines[i].crossSize += free / n
+		}
+	}
+
+	pos := start
+	for i := range fc.lines {
+		line := &fc.lines[i]
+		line.crossStart = pos
+		for _, item := range line.items {
+			switch fc.itemAlign(item) {
+			case "flex-end", "end":
+				item.crossPos = line.crossStart + line.crossSize - item.crossSize
+			case "center":
+				item.crossPos = line.crossStart + (line.crossSize-item.crossSize)/2
+			default:
+				item.crossPos = line.crossStart
+			}
+		}
+		pos += line.crossSize + fc.rowGap + spacing
+	}
+	return max(crossSize, pos-fc.rowGap-spacing)
+}
+
+// itemAlign returns how an item aligns in its line: its align-self, or the
+// container's align-items
+func (fc *flexContainer) itemAlign(item *flexItem) string {
+	if item.alignSelf != "" && item.alignSelf != "auto" {
+		return item.alignSelf
+	}
+	if fc.alignItems == "" || fc.alignItems == "normal" {
+		return "stretch"
+	}
+	return fc.alignItems
+}
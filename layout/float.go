@@ -0,0 +1,112 @@
+package layout
+
+import (
+	"go-browser/css"
+	"go-browser/dom"
+)
+
+// =============================================================================
+// FLOATS
+// A floated box leaves the normal flow for the left or right edge of its
+// containing block. Lines beside it are shortened to the space left between
+// the floats, and clear moves a block below them.
+// =============================================================================
+
+// floatBox is the margin box of a float placed in the current context
+type floatBox struct {
+	right      bool
+	x, y, w, h float64
+}
+
+// isFloating reports whether an element is taken out of the flow as a float.
+// Positioned boxes don't float.
+func isFloating(node *dom.Node, cs *css.ComputedStyle) bool {
+	if cs.Float == "" || node.Tag == "html" || node.Tag == "body" {
+		return false
+	}
+	return cs.Position != "absolute" && cs.Position != "fixed"
+}
+
+// lineLeft returns where a line at y starts: the content box's left edge, or
+// the right edge of a left float beside it
+func (ctx *LayoutContext) lineLeft(y float64) float64 {
+	left := ctx.Left
+	for _, f := range ctx.Floats {
+		if !f.right && y >= f.y && y < f.y+f.h {
+			left = max(left, f.x+f.w)
+		}
+	}
+	return left
+}
+
+// lineRight returns where a line at y must end: the content box's right
+// edge, or the left edge of a right float beside it
+func (ctx *LayoutContext) lineRight(y float64) float64 {
+	right := ctx.MaxW
+	for _, f := range ctx.Floats {
+		if f.right && y >= f.y && y < f.y+f.h {
+			right = min(right, f.x)
+		}
+	}
+	return right
+}
+
+// clearance returns the Y a box with the given clear value must start at:
+// below the floats on the cleared sides
+func (ctx *LayoutContext) clearance(clear string) float64 {
+	if clear == "" {
+		return 0
+	}
+	bottom := 0.0
+	for _, f := range ctx.Floats {
+		if clear == "both" || (clear == "right") == f.right {
+			bottom = max(bottom, f.y+f.h)
+		}
+	}
+	return bottom
+}
+
+// nextFloatBottom returns the nearest float bottom below y, or y when no
+// float ends below it
+func (ctx *LayoutContext) nextFloatBottom(y float64) float64 {
+	next := y
+	for _, f := range ctx.Floats {
+		if bottom := f.y + f.h; bottom > y && (next == y || bottom < next) {
+			next = bottom
+		}
+	}
+	return next
+}
+
+// layoutFloat lays out a floated element as a block of its own and places it
+// at the highest spot from the current line down where it fits beside the
+// floats already there
+func layoutFloat(node *dom.Node, box *RenderBox, ctx *LayoutContext, cs *css.ComputedStyle, e boxEdges, contentMaxW float64) {
+	_, w, h := layoutShrinkToFit(node, box, ctx, cs, e, contentMaxW)
+	outerW := e.margin.Left + w + e.margin.Right
+	outerH := e.margin.Top + h + e.margin.Bottom
+
+	y := max(ctx.CursorY, ctx.clearance(cs.Clear))
+	for ctx.lineRight(y)-ctx.lineLeft(y) < outerW {
+		next := ctx.nextFloatBottom(y)
+		if next == y {
+			break
+		}
+		y = next
+	}
+	x := ctx.lineLeft(y)
+	if cs.Float == "right" {
+		x = ctx.lineRight(y) - outerW
+	}
+
+	for _, child := range box.Children {
+		translateBox(child, x+e.margin.Left+e.border.Left+e.padding.Left, y+e.margin.Top+e.border.Top+e.padding.Top)
+	}
+	box.X, box.Y, box.W, box.H = x+e.margin.Left, y+e.margin.Top, w, h
+	ctx.Floats = append(ctx.Floats, floatBox{right: cs.Float == "right", x: x, y: y, w: outerW, h: outerH})
+
+	// A line that hasn't started yet starts beside the new float
+	if !ctx.InLine {
+		ctx.CursorX = ctx.lineLeft(ctx.CursorY)
+	}
+}
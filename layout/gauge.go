@@ -0,0 +1,129 @@
+package layout
+
+import (
+	"strconv"
+	"strings"
+
+	"go-browser/css"
+	"go-browser/dom"
+)
+
+// =============================================================================
+// GAUGES
+// <progress> and <meter> are inline bars the browser paints from their
+// value attributes; their fallback content is never laid out
+// =============================================================================
+
+// Gauge sizes in ems, as browsers draw them without CSS
+const (
+	progressWidth = 10.0
+	meterWidth    = 5.0
+	gaugeHeight   = 1.0
+)
+
+// Gauge is the state of a <progress> or <meter>, with its attributes
+// clamped as the HTML spec does
+type Gauge struct {
+	Min, Max, Value float64
+	Low, High       float64 // meter only: the ends of the optimum region's neighbours
+	Optimum         float64 // meter only
+	Indeterminate   bool    // a <progress> without a value
+}
+
+// Fraction returns how full the bar is, from 0 to 1
+func (g Gauge) Fraction() float64 {
+	if g.Max <= g.Min {
+		return 0
+	}
+	return (g.Value - g.Min) / (g.Max - g.Min)
+}
+
+// Level says how good a meter's value is: 0 in the optimum region, 1 in
+// the region next to it and 2 in the one furthest away
+func (g Gauge) Level() int {
+	region := func(v float64) int {
+		switch {
+		case v < g.Low:
+			return 0
+		case v > g.High:
+			return 2
+		}
+		return 1
+	}
+	level := region(g.Value) - region(g.Optimum)
+	if level < 0 {
+		level = -level
+	}
+	return level
+}
+
+// GaugeOf reads the state of a <progress> or <meter> from its attributes
+func GaugeOf(node *dom.Node) Gauge {
+	attr := func(name string) (float64, bool) {
+		v, err := strconv.ParseFloat(strings.TrimSpace(node.GetAttr(name)), 64)
+		return v, err == nil
+	}
+	clamp := func(v, lo, hi float64) float64 { return max(lo, min(v, hi)) }
+
+	if node.Tag == "progress" {
+		g := Gauge{Max: 1}
+		if m, ok := attr("max"); ok && m > 0 {
+			g.Max = m
+		}
+		v, ok := attr("value")
+		g.Indeterminate = !ok
+		g.Value = clamp(v, 0, g.Max)
+		return g
+	}
+
+	g := Gauge{Max: 1}
+	if v, ok := attr("min"); ok {
+		g.Min = v
+	}
+	if v, ok := attr("max"); ok {
+		g.Max = v
+	}
+	g.Max = max(g.Max, g.Min)
+	v, _ := attr("value")
+	g.Value = clamp(v, g.Min, g.Max)
+	g.Low, g.High = g.Min, g.Max
+	if v, ok := attr("low"); ok {
+		g.Low = clamp(v, g.Min, g.Max)
+	}
+	if v, ok := attr("high"); ok {
+		g.High = clamp(v, g.Low, g.Max)
+	}
+	g.Optimum = (g.Min + g.Max) / 2
+	if v, ok := attr("optimum"); ok {
+		g.Optimum = clamp(v, g.Min, g.Max)
+	}
+	return g
+}
+
+// layoutGauge places a <progress> or <meter> bar on the current line, its
+// bottom on the baseline
+func layoutGauge(node *dom.Node, container *RenderBox, ctx *LayoutContext, cs *css.ComputedStyle) {
+	fontSize := float64(FontSizeBody)
+	if cs != nil {
+		fontSize = cs.FontSize
+	}
+	w, h := progressWidth*fontSize, gaugeHeight*fontSize
+	if node.Tag == "meter" {
+		w = meterWidth * fontSize
+	}
+	if cs != nil && cs.Width.IsSet() {
+		w = cs.Width.Resolve(cs.FontSize, ctx.MaxW-ctx.Left, cs.Media.Viewport)
+	}
+	if cs != nil && definiteHeight(cs.Height) {
+		h = cs.Height.Resolve(cs.FontSize, 0, cs.Media.Viewport)
+	}
+	if ctx.InLine && ctx.CursorX+w > ctx.lineRight(ctx.CursorY) {
+		ctx.endLine()
+	}
+
+	box := &RenderBox{Node: node, X: ctx.CursorX, Y: ctx.CursorY, W: w, H: h}
+	container.Children = append(container.Children, box)
+	ctx.addFragment(lineFragment{box: box, ascent: h})
+	ctx.CursorX += w
+	ctx.TrailingSpace = false
+}
@@ -0,0 +1,164 @@
+package layout
+
+import (
+	"strconv"
+	"strings"
+
+	"go-browser/css"
+	"go-browser/dom"
+)
+
+// ======================================================================================
+// RESPONSIVE IMAGES
+// An <img> shows the candidate of its srcset, or of the first <source> of its
+// <picture> whose media and type match, that best fits the screen: the
+// lowest density at least the device pixel ratio, else the highest. Width
+// descriptors become densities through sizes, the width the image is laid
+// out at. src is the fallback, a 1x candidate.
+// ======================================================================================
+
+// imageTypes are the MIME types <source type> may name that images decode from
+var imageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/jpg":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// imageCandidate is a URL of srcset with its density
+type imageCandidate struct {
+	url     string
+	density float64 // image pixels per CSS pixel
+	width   float64 // the w descriptor, 0 when not given
+}
+
+// ImageSource returns the URL, unresolved, of the image an <img> shows, or ""
+// when it has none, on the medium it was styled for
+func ImageSource(img *dom.Node) string {
+	media := css.DefaultMedia()
+	if cs, ok := img.ComputedStyle.(*css.ComputedStyle); ok {
+		media = cs.Media
+	}
+	if picture := img.Parent; picture != nil && picture.Tag == "picture" {
+		for _, source := range picture.Children {
+			if source == img {
+				break
+			}
+			if source.Tag != "source" || !media.Match(source.GetAttr("media")) {
+				continue
+			}
+			if t := source.GetAttr("type"); t != "" && !imageTypes[strings.ToLower(strings.TrimSpace(t))] {
+				continue
+			}
+			if url := bestCandidate(parseSrcset(source.GetAttr("srcset"), source.GetAttr("sizes"), media), media.PixelRatio); url != "" {
+				return url
+			}
+		}
+	}
+
+	candidates := parseSrcset(img.GetAttr("srcset"), img.GetAttr("sizes"), media)
+	if src := strings.TrimSpace(img.GetAttr("src")); src != "" {
+		hasOneX := false
+		for _, c := range candidates {
+			hasOneX = hasOneX || (c.width == 0 && c.density == 1)
+		}
+		if !hasOneX {
+			candidates = append(candidates, imageCandidate{url: src, density: 1})
+		}
+	}
+	return bestCandidate(candidates, media.PixelRatio)
+}
+
+// bestCandidate picks the candidate for the device pixel ratio
+func bestCandidate(candidates []imageCandidate, pixelRatio float64) string {
+	var best *imageCandidate
+	for i := range candidates {
+		c := &candidates[i]
+		switch {
+		case best == nil:
+			best = c
+		case best.density < pixelRatio:
+			// Too blurry so far: anything denser is better
+			if c.density > best.density {
+				best = c
+			}
+		case c.density >= pixelRatio && c.density < best.density:
+			// Sharp enough, and fewer bytes
+			best = c
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	return best.url
+}
+
+// parseSrcset reads the candidates of a srcset, such as "a.jpg 1x, b.jpg 2x"
+// or "small.jpg 480w, large.jpg 1080w" with sizes evaluated on media
+func parseSrcset(srcset, sizes string, media css.Media) []imageCandidate {
+	var candidates []imageCandidate
+	var slotWidth float64
+	rest := srcset
+	for {
+		rest = strings.TrimLeft(rest, " \t\n\r\f,")
+		if rest == "" {
+			break
+		}
+		// The URL runs to whitespace; commas ending it separate candidates
+		end := strings.IndexAny(rest, " \t\n\r\f")
+		if end < 0 {
+			end = len(rest)
+		}
+		url, descriptors := rest[:end], ""
+		rest = rest[end:]
+		if trimmed := strings.TrimRight(url, ","); trimmed != url {
+			url = trimmed
+		} else {
+			descriptors, rest, _ = strings.Cut(rest, ",")
+		}
+
+		c := imageCandidate{url: url, density: 1}
+		for _, d := range strings.Fields(strings.ToLower(descriptors)) {
+			n, err := strconv.ParseFloat(d[:len(d)-1], 64)
+			if err != nil || n <= 0 {
+				continue
+			}
+			switch d[len(d)-1] {
+			case 'x':
+				c.density = n
+			case 'w':
+				if slotWidth == 0 {
+					slotWidth = sourceSize(sizes, media)
+				}
+				c.width, c.density = n, n/slotWidth
+			}
+		}
+		if url != "" {
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates
+}
+
+// sourceSize returns the width sizes gives the image: the length of the
+// first entry whose media condition matches, or of the last, without one;
+// 100vw when none does
+func sourceSize(sizes string, media css.Media) float64 {
+	for _, entry := range strings.Split(sizes, ",") {
+		entry = strings.TrimSpace(entry)
+		cond, length := "", entry
+		if i := strings.LastIndex(entry, ")"); i >= 0 && i < len(entry)-1 {
+			cond, length = entry[:i+1], entry[i+1:]
+		}
+		if cond != "" && !media.MatchCondition(cond) {
+			continue
+		}
+		if l, ok := css.ParseLengthValue(length); ok && l.Unit != css.UnitPercent {
+			if px := l.Resolve(16, 0, media.Viewport); px > 0 {
+				return px
+			}
+		}
+	}
+	return media.Width
+}
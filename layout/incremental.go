@@ -0,0 +1,78 @@
+package layout
+
+import (
+	"iter"
+	"time"
+
+	"go-browser/dom"
+)
+
+// ======================================================================================
+// INCREMENTAL LAYOUT
+// A Layout builds the same render tree as BuildRenderTree, a slice of time
+// at a time: it pauses before an element once its slice is spent and picks
+// up there on the next Step. The browser steps a large page once a frame,
+// so the window keeps drawing while it lays out. The viewport comes with
+// the elements' computed styles, so nothing needs setting between Steps.
+// ======================================================================================
+
+// Layout is a render tree being built over several steps
+type Layout struct {
+	next     func() (struct{}, bool)
+	stop     func()
+	deadline time.Time
+	box      *RenderBox
+	done     bool
+}
+
+// layoutCanceled unwinds a Layout stopped before it finished
+type layoutCanceled struct{}
+
+// NewLayout starts laying node out at width, sizing table columns the user
+// resized from widths; nothing runs until Step
+func NewLayout(node *dom.Node, width float64, widths ColumnWidths) *Layout {
+	l := &Layout{}
+	l.next, l.stop = iter.Pull(func(yield func(struct{}) bool) {
+		defer func() {
+			if r := recover(); r != nil {
+				if _, ok := r.(layoutCanceled); !ok {
+					panic(r)
+				}
+			}
+		}()
+		l.box = buildRenderTree(node, width, widths, func() {
+			if time.Now().Before(l.deadline) {
+				return
+			}
+			if !yield(struct{}{}) {
+				panic(layoutCanceled{})
+			}
+		})
+	})
+	return l
+}
+
+// Step lays out for about budget, and reports whether the tree is done
+func (l *Layout) Step(budget time.Duration) bool {
+	if l.done {
+		return true
+	}
+	l.deadline = time.Now().Add(budget)
+	if _, more := l.next(); !more {
+		l.done = true
+	}
+	return l.done
+}
+
+// Tree returns the render tree once Step has reported it done, else nil
+func (l *Layout) Tree() *RenderBox {
+	if !l.done {
+		return nil
+	}
+	return l.box
+}
+
+// Stop abandons a layout not yet done
+func (l *Layout) Stop() {
+	l.stop()
+}
@@ -0,0 +1,151 @@
+package layout
+
+import (
+	"image/color"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"go-browser/css"
+	"go-browser/dom"
+)
+
+// =============================================================================
+// INLINE TEXT STYLE
+// Text takes decorations, baseline shifts and backgrounds from every inline
+// element around it, not just its parent: <del><b>x</b></del> is struck out
+// =============================================================================
+
+// Baseline shifts of vertical-align: sub and super, in ems of the parent font
+const (
+	subShift   = 0.2
+	superShift = 0.35
+)
+
+// inlineTextStyle is what a text node picks up from its inline ancestors
+type inlineTextStyle struct {
+	decoration      string      // underline, line-through, overline, space separated
+	decorationStyle string      // solid, double, dotted, dashed, wavy
+	decorationColor *color.RGBA // color of the element that draws the lines
+	decorationWidth float64     // line thickness in pixels; 0 scales it to the font
+	shift           float64     // baseline offset in pixels, positive moves the text down
+	bg              *color.RGBA // background of an inline ancestor above the parent
+}
+
+// inlineTextStyleOf walks from the text's parent up to its block container.
+// Decorations propagate from the block too; backgrounds and shifts only come
+// from inline elements.
+func inlineTextStyleOf(node *dom.Node) inlineTextStyle {
+	var st inlineTextStyle
+	for p := node.Parent; p != nil; p = p.Parent {
+		cs, ok := p.ComputedStyle.(*css.ComputedStyle)
+		if !ok {
+			break
+		}
+		for _, line := range strings.Fields(cs.TextDecoration) {
+			if line != "none" && !strings.Contains(st.decoration, line) {
+				st.decoration = strings.TrimSpace(st.decoration + " " + line)
+				if st.decorationStyle == "" {
+					st.decorationStyle = cs.TextDecorationStyle
+				}
+				if st.decorationColor == nil {
+					// Lines are drawn in the color of the element decorating
+					// the text, so a link's underline stays link-colored
+					c := cs.TextDecorationColor
+					if c.A == 0 {
+						c = cs.Color
+					}
+					st.decorationColor = &c
+					if cs.TextDecorationThickness.IsSet() {
+						st.decorationWidth = cs.TextDecorationThickness.Resolve(cs.FontSize, cs.FontSize, cs.Media.Viewport)
+					}
+				}
+			}
+		}
+		if cs.Display != "inline" {
+			break
+		}
+
+		parentSize := cs.FontSize
+		if p.Parent != nil {
+			if pcs, ok := p.Parent.ComputedStyle.(*css.ComputedStyle); ok {
+				parentSize = pcs.FontSize
+			}
+		}
+		switch cs.VerticalAlign {
+		case "sub":
+			st.shift += parentSize * subShift
+		case "super":
+			st.shift -= parentSize * superShift
+		}
+		if p != node.Parent && st.bg == nil && cs.BackgroundColor.A > 0 {
+			bg := cs.BackgroundColor
+			st.bg = &bg
+		}
+	}
+	return st
+}
+
+// transformText applies text-transform to s. For capitalize, wordStart says
+// whether s begins a word rather than continuing the text before it.
+func transformText(s, transform string, wordStart bool) string {
+	switch transform {
+	case "uppercase":
+		return strings.ToUpper(s)
+	case "lowercase":
+		return strings.ToLower(s)
+	case "capitalize":
+		runes := []rune(s)
+		for i, r := range runes {
+			if wordStart && unicode.IsLetter(r) {
+				runes[i] = unicode.ToTitle(r)
+			}
+			wordStart = unicode.IsSpace(r)
+		}
+		return string(runes)
+	}
+	return s
+}
+
+// spacedWidth returns the width of s when every character is charW wide and
+// letter-spacing and word-spacing add their space after each character and
+// each space
+func spacedWidth(s string, charW, letterSpacing, wordSpacing float64) float64 {
+	n := float64(utf8.RuneCountInString(s))
+	return n*(charW+letterSpacing) + float64(strings.Count(s, " "))*wordSpacing
+}
+
+// paintStyleOf returns the opacity node is painted with, its own multiplied
+// by every ancestor's, and whether visibility hides it. Text takes its
+// visibility from its parent.
+func paintStyleOf(node *dom.Node) (opacity float64, hidden bool) {
+	opacity = 1
+	own := true
+	for p := node; p != nil; p = p.Parent {
+		cs, ok := p.ComputedStyle.(*css.ComputedStyle)
+		if !ok {
+			continue
+		}
+		if own {
+			hidden = cs.Visibility == "hidden" || cs.Visibility == "collapse"
+			own = false
+		}
+		opacity *= cs.Opacity
+	}
+	return opacity, hidden
+}
+
+// resolvePaintStyle sets the opacity and visibility of the boxes of a laid
+// out tree. Text boxes got theirs from their text node; anonymous boxes
+// paint like the box they are in.
+func resolvePaintStyle(box *RenderBox, opacity float64, hidden bool) {
+	switch {
+	case box.Node != nil:
+		box.Opacity, box.Hidden = paintStyleOf(box.Node)
+	case box.Text == "":
+		box.Opacity, box.Hidden = opacity, hidden
+	}
+	for _, child := range box.Children {
+		resolvePaintStyle(child, box.Opacity, box.Hidden)
+	}
+}
@@ -0,0 +1,204 @@
+package layout
+
+import (
+	"go-browser/css"
+	"go-browser/dom"
+)
+
+// =============================================================================
+// LINE BOXES
+// Inline content is gathered into the current line box. When the line ends its
+// fragments are aligned on a shared baseline, and the line is as tall as the
+// tallest extent above the baseline plus the tallest below it.
+// =============================================================================
+
+// Glyph metrics as fractions of the font size, used to place the baseline
+// inside a line of text
+const (
+	textAscent  = 0.8
+	textDescent = 0.2
+)
+
+// monoAdvance is the width of every glyph of the monospace font, in ems
+const monoAdvance = 0.6
+
+// lineFragment is a box on the current line and its extent around the baseline
+type lineFragment struct {
+	box     *RenderBox
+	ascent  float64 // above the baseline
+	descent float64 // below the baseline
+	shift   float64 // vertical-align offset, positive moves the box down
+	align   string  // top or bottom aligns to the line box instead of the baseline
+}
+
+// addFragment puts a box on the current line. Its Y is settled when the line
+// ends; until then it sits at the line's top.
+func (ctx *LayoutContext) addFragment(f lineFragment) {
+	if len(ctx.Line) == 0 {
+		ctx.LineTop = ctx.CursorY
+	}
+	translateBox(f.box, 0, ctx.LineTop-f.box.Y)
+	ctx.Line = append(ctx.Line, f)
+	ctx.InLine = true
+}
+
+// finishLine aligns the fragments of the current line and returns its height,
+// without moving the cursor
+func (ctx *LayoutContext) finishLine() float64 {
+	if len(ctx.Line) == 0 {
+		return 0
+	}
+	above, below := 0.0, 0.0
+	for _, f := range ctx.Line {
+		if f.align == "" {
+			above = max(above, f.ascent-f.shift)
+			below = max(below, f.descent+f.shift)
+		}
+	}
+	height := above + below
+	for _, f := range ctx.Line {
+		if f.align != "" {
+			height = max(height, f.ascent+f.descent)
+		}
+	}
+
+	for _, f := range ctx.Line {
+		y := ctx.LineTop + above + f.shift - f.ascent
+		switch f.align {
+		case "top":
+			y = ctx.LineTop
+		case "bottom":
+			y = ctx.LineTop + height - f.ascent - f.descent
+		}
+		translateBox(f.box, 0, y-f.box.Y)
+	}
+	ctx.LastBaseline = ctx.LineTop + above
+	ctx.Line = nil
+	return height
+}
+
+// endLine closes the current line and moves the cursor to the start of the
+// next one. Closing a line with nothing on it takes no space.
+func (ctx *LayoutContext) endLine() {
+	ctx.CursorY += ctx.finishLine()
+	ctx.CursorX = ctx.lineLeft(ctx.CursorY)
+	ctx.InLine = false
+	ctx.TrailingSpace = false
+}
+
+// translateBox moves a box and everything laid out inside it
+func translateBox(box *RenderBox, dx, dy float64) {
+	if dx == 0 && dy == 0 {
+		return
+	}
+	box.X += dx
+	box.Y += dy
+	for _, child := range box.Children {
+		translateBox(child, dx, dy)
+	}
+}
+
+// verticalAlign turns an element's vertical-align into a fragment shift or a
+// line box alignment. parentSize is the font size sub, super and middle are
+// measured against.
+func verticalAlign(f *lineFragment, valign string, parentSize float64) {
+	switch valign {
+	case "sub":
+		f.shift = parentSize * subShift
+	case "super":
+		f.shift = -parentSize * superShift
+	case "top", "text-top":
+		f.align = "top"
+	case "bottom", "text-bottom":
+		f.align = "bottom"
+	case "middle":
+		// The box's middle sits half an x-height above the baseline
+		h := f.ascent + f.descent
+		f.ascent = h/2 + parentSize*0.25
+		f.descent = h/2 - parentSize*0.25
+	}
+}
+
+// layoutShrinkToFit lays out the contents of an inline-block or float as a
+// block of their own, with the content box at the origin. Without an explicit
+// width the box shrinks to its content, up to contentMaxW. e holds its
+// resolved margins, borders and padding. It returns the inner context and the
+// size of the border box.
+func layoutShrinkToFit(node *dom.Node, box *RenderBox, ctx *LayoutContext, cs *css.ComputedStyle, e boxEdges, contentMaxW float64) (*LayoutContext, float64, float64) {
+	availW := ctx.MaxW - ctx.Left - e.margin.Left - e.width() - e.margin.Right
+	inner := &LayoutContext{MaxW: max(min(availW, contentMaxW), 0), LineHeight: ctx.LineHeight, columnWidths: ctx.columnWidths}
+	if src := ImageSource(node); node.Tag == "img" && src != "" {
+		image := &RenderBox{Node: node, W: imageWidth, H: imageHeight, IsImage: true, ImageURL: src}
+		box.Children = append(box.Children, image)
+		inner.CursorY = imageHeight
+	} else {
+		layoutBlockChildren(node, box, inner)
+	}
+	inner.endLine()
+	// Floats inside the box make it grow to hold them
+	inner.CursorY = max(inner.CursorY, inner.clearance("both"))
+
+	contentW := contentWidth(box)
+	if cs.Width.IsSet() {
+		contentW = contentSize(cs, cs.Width.Resolve(cs.FontSize, ctx.MaxW-ctx.Left, cs.Media.Viewport), e.width())
+	}
+	return inner, contentW + e.width(), contentHeight(cs, e, inner.CursorY) + e.height()
+}
+
+// layoutInlineBlock lays out an inline-block's contents, then places the whole
+// box on the current line like a word. Its baseline is that of its last line
+// of text, or its bottom edge when it has none.
+func layoutInlineBlock(node *dom.Node, box *RenderBox, ctx *LayoutContext, cs *css.ComputedStyle, e boxEdges, contentMaxW float64) {
+	inner, w, h := layoutShrinkToFit(node, box, ctx, cs, e, contentMaxW)
+	baseline := h
+	if inner.LastBaseline > 0 {
+		baseline = e.border.Top + e.padding.Top + inner.LastBaseline
+	}
+
+	if ctx.InLine && ctx.CursorX+e.margin.Left+w > ctx.lineRight(ctx.CursorY) {
+		ctx.endLine()
+	}
+	for _, child := range box.Children {
+		translateBox(child, ctx.CursorX+e.margin.Left+e.border.Left+e.padding.Left, ctx.CursorY+e.border.Top+e.padding.Top)
+	}
+	box.X, box.Y, box.W, box.H = ctx.CursorX+e.margin.Left, ctx.CursorY, w, h
+	box.InlineBlock = true
+
+	f := lineFragment{box: box, ascent: baseline, descent: h - baseline}
+	parentSize := cs.FontSize
+	if node.Parent != nil {
+		if pcs, ok := node.Parent.ComputedStyle.(*css.ComputedStyle); ok {
+			parentSize = pcs.FontSize
+		}
+	}
+	verticalAlign(&f, cs.VerticalAlign, parentSize)
+	ctx.addFragment(f)
+	ctx.CursorX += e.margin.Left + w + e.margin.Right
+	ctx.TrailingSpace = false
+}
+
+// fixedSizeTags, form controls and replaced elements, are laid out as
+// fixed-size leaf boxes
+var fixedSizeTags = map[string]bool{"input": true, "select": true, "textarea": true, "button": true, "video": true, "audio": true, "canvas": true, "progress": true, "meter": true}
+
+// contentWidth returns how far right the text, images and controls laid out
+// inside box reach, relative to box's content start
+func contentWidth(box *RenderBox) float64 {
+	right := 0.0
+	var walk func(b *RenderBox)
+	walk = func(b *RenderBox) {
+		if b.Text != "" || b.IsImage || b.InlineBlock || (b.Node != nil && fixedSizeTags[b.Node.Tag] && len(b.Children) == 0) {
+			right = max(right, b.X+b.W)
+		}
+		if b.InlineBlock {
+			return
+		}
+		for _, child := range b.Children {
+			walk(child)
+		}
+	}
+	for _, child := range box.Children {
+		walk(child)
+	}
+	return right
+}
@@ -0,0 +1,119 @@
+package layout
+
+import "go-browser/css"
+
+// =============================================================================
+// MARGINS AND PADDING
+// Percentages, alone or inside min(), max() and clamp(), are taken against
+// the containing block's width, vertical ones included. Vertical margins that touch, with no content, border or padding
+// between them, collapse into one.
+// =============================================================================
+
+// boxEdges are an element's margins, borders and padding in pixels
+type boxEdges struct {
+	margin, border, padding css.BoxSides
+}
+
+// edgesOf resolves an element's margins and padding for a containing block
+// cbWidth wide
+func edgesOf(cs *css.ComputedStyle, cbWidth float64) boxEdges {
+	if cs == nil {
+		return boxEdges{}
+	}
+	side := func(px, pct float64, expr *css.MathLength) float64 {
+		if expr != nil {
+			return expr.Resolve(cs.FontSize, cbWidth, cs.Media.Viewport)
+		}
+		return px + pct/100*cbWidth
+	}
+	return boxEdges{
+		margin: css.BoxSides{
+			Top:    side(cs.MarginTop, cs.MarginPercent.Top, cs.MarginMath.Top),
+			Right:  side(cs.MarginRight, cs.MarginPercent.Right, cs.MarginMath.Right),
+			Bottom: side(cs.MarginBottom, cs.MarginPercent.Bottom, cs.MarginMath.Bottom),
+			Left:   side(cs.MarginLeft, cs.MarginPercent.Left, cs.MarginMath.Left),
+		},
+		border: css.BoxSides{
+			Top: cs.BorderTopWidth, Right: cs.BorderRightWidth,
+			Bottom: cs.BorderBottomWidth, Left: cs.BorderLeftWidth,
+		},
+		padding: css.BoxSides{
+			Top:    side(cs.PaddingTop, cs.PaddingPercent.Top, cs.PaddingMath.Top),
+			Right:  side(cs.PaddingRight, cs.PaddingPercent.Right, cs.PaddingMath.Right),
+			Bottom: side(cs.PaddingBottom, cs.PaddingPercent.Bottom, cs.PaddingMath.Bottom),
+			Left:   side(cs.PaddingLeft, cs.PaddingPercent.Left, cs.PaddingMath.Left),
+		},
+	}
+}
+
+// width returns the horizontal border and padding
+func (e boxEdges) width() float64 {
+	return e.border.Left + e.padding.Left + e.padding.Right + e.border.Right
+}
+
+// height returns the vertical border and padding
+func (e boxEdges) height() float64 {
+	return e.border.Top + e.padding.Top + e.padding.Bottom + e.border.Bottom
+}
+
+// contentSize turns a CSS width or height into the content box's: under
+// box-sizing: border-box it includes the edge pixels of padding and border
+func contentSize(cs *css.ComputedStyle, size, edge float64) float64 {
+	if cs.BoxSizing == "border-box" {
+		return max(size-edge, 0)
+	}
+	return size
+}
+
+// contentHeight returns the height of an element's content box: the height
+// its content took, overridden by height and kept within min-height and
+// max-height
+func contentHeight(cs *css.ComputedStyle, e boxEdges, laidOut float64) float64 {
+	h := laidOut
+	if cs == nil {
+		return h
+	}
+	if definiteHeight(cs.Height) {
+		h = contentSize(cs, cs.Height.Resolve(cs.FontSize, 0, cs.Media.Viewport), e.height())
+	}
+	if definiteHeight(cs.MaxHeight) {
+		h = min(h, contentSize(cs, cs.MaxHeight.Resolve(cs.FontSize, 0, cs.Media.Viewport), e.height()))
+	}
+	if definiteHeight(cs.MinHeight) {
+		h = max(h, contentSize(cs, cs.MinHeight.Resolve(cs.FontSize, 0, cs.Media.Viewport), e.height()))
+	}
+	return h
+}
+
+// definiteHeight reports whether a height can be resolved before layout:
+// percentages of the containing block's height count as auto, since that
+// height is not known while its content is laid out
+func definiteHeight(l css.Length) bool {
+	return l.IsSet() && l.Unit != css.UnitPercent
+}
+
+// collapseMargins returns the space two adjoining margins take together
+func collapseMargins(a, b float64) float64 {
+	switch {
+	case a >= 0 && b >= 0:
+		return max(a, b)
+	case a < 0 && b < 0:
+		return min(a, b)
+	}
+	return a + b
+}
+
+// addMargin moves the cursor down by a vertical margin. A margin that directly
+// follows another, with nothing laid out in between, collapses with it.
+func (ctx *LayoutContext) addMargin(m float64) {
+	if m == 0 {
+		return
+	}
+	prev := 0.0
+	if ctx.CursorY == ctx.MarginEnd {
+		prev = ctx.Margin
+	}
+	total := collapseMargins(prev, m)
+	ctx.CursorY += total - prev
+	ctx.Margin, ctx.MarginEnd = total, ctx.CursorY
+}
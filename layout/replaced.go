@@ -0,0 +1,97 @@
+package layout
+
+import (
+	"strconv"
+	"strings"
+
+	"go-browser/css"
+	"go-browser/dom"
+)
+
+// =============================================================================
+// REPLACED ELEMENTS
+// <video>, <audio> and <canvas> are fixed-size boxes the browser paints
+// into: a poster and controls, or the canvas bitmap. Their <source> and
+// fallback children are never laid out.
+// =============================================================================
+
+// Sizes replaced elements get without width and height, as browsers use;
+// a canvas defaults to the size of a video
+const (
+	videoWidth  = 300.0
+	videoHeight = 150.0
+	audioWidth  = 300.0
+	audioHeight = 40.0
+)
+
+// replacedSize returns the size of a <video>, <audio> or <canvas> box, from
+// its width and height attributes or CSS, percentages taken against availW.
+// An <audio> without controls shows nothing, so ok is false for it.
+func replacedSize(node *dom.Node, cs *css.ComputedStyle, availW float64) (w, h float64, ok bool) {
+	attrSize := func(name string) (float64, bool) {
+		n, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(node.GetAttr(name)), "px"), 64)
+		return n, err == nil && n > 0
+	}
+
+	w, h = videoWidth, videoHeight
+	width, hasW := attrSize("width")
+	height, hasH := attrSize("height")
+	switch node.Tag {
+	case "audio":
+		if !node.HasAttr("controls") {
+			return 0, 0, false
+		}
+		w, h = audioWidth, audioHeight
+	case "canvas":
+		// The attributes size the bitmap, which CSS may scale
+		if hasW {
+			w = width
+		}
+		if hasH {
+			h = height
+		}
+		hasW, hasH = false, false
+	}
+	if cs != nil && cs.Width.IsSet() {
+		width, hasW = cs.Width.Resolve(cs.FontSize, availW, cs.Media.Viewport), true
+	}
+	if cs != nil && definiteHeight(cs.Height) {
+		height, hasH = cs.Height.Resolve(cs.FontSize, 0, cs.Media.Viewport), true
+	}
+
+	// A video or canvas given one side keeps its aspect ratio
+	keepRatio := node.Tag != "audio" && w > 0 && h > 0
+	switch {
+	case hasW && hasH:
+		w, h = width, height
+	case hasW:
+		if keepRatio {
+			h = width * h / w
+		}
+		w = width
+	case hasH:
+		if keepRatio {
+			w = height * w / h
+		}
+		h = height
+	}
+	return w, h, true
+}
+
+// layoutReplaced places a <video>, <audio> or <canvas> box on a line of its
+// own
+func layoutReplaced(node *dom.Node, container *RenderBox, ctx *LayoutContext, cs *css.ComputedStyle) {
+	w, h, ok := replacedSize(node, cs, ctx.MaxW-ctx.Left)
+	if !ok {
+		return
+	}
+	ctx.endLine()
+	container.Children = append(container.Children, &RenderBox{
+		Node: node,
+		X:    ctx.CursorX,
+		Y:    ctx.CursorY,
+		W:    w,
+		H:    h,
+	})
+	ctx.CursorY += h + 10
+}
@@ -0,0 +1,280 @@
+package layout
+
+import (
+	"strconv"
+	"strings"
+
+	"go-browser/css"
+	"go-browser/dom"
+)
+
+// =============================================================================
+// TABLES
+// A table lays out its caption above a grid of rows. Column widths come from
+// <col>/<colgroup> and first-row cell width hints; the space left is shared by
+// the other columns. Each row is as tall as its tallest cell.
+// =============================================================================
+
+// Spacing of tables without cellpadding or cellspacing attributes
+const (
+	defaultCellPadding = 4.0
+	defaultCellSpacing = 2.0
+)
+
+// ColumnWidths returns the column widths the user gave a table by dragging
+// its column boundaries, or nil to size it from its markup
+type ColumnWidths func(table *dom.Node) []float64
+
+// tableCell is a td or th and the columns it starts at and spans
+type tableCell struct {
+	node      *dom.Node
+	col, span int
+}
+
+// tableRow is a tr and its cells
+type tableRow struct {
+	node  *dom.Node
+	cells []tableCell
+}
+
+// widthHint is a column width from markup: pixels, or a percentage of the
+// table's width
+type widthHint struct {
+	px, percent float64
+}
+
+// resolve returns the hint in pixels for a table tableW wide, 0 when unset
+func (h widthHint) resolve(tableW float64) float64 {
+	if h.percent > 0 {
+		return h.percent / 100 * tableW
+	}
+	return h.px
+}
+
+// layoutTable lays out a table's caption and rows inside the table's content
+// box, which the caller has already set up in ctx
+func layoutTable(node *dom.Node, container *RenderBox, ctx *LayoutContext) {
+	padding := tableAttrLength(node, "cellpadding", defaultCellPadding)
+	spacing := tableAttrLength(node, "cellspacing", defaultCellSpacing)
+	caption, hints, rows := tableParts(node)
+
+	if caption != nil {
+		box := &RenderBox{Node: caption}
+		layoutRecursive(caption, box, ctx)
+		container.Children = append(container.Children, box)
+	}
+
+	columns := len(hints)
+	for _, row := range rows {
+		if n := len(row.cells); n > 0 {
+			last := row.cells[n-1]
+			columns = max(columns, last.col+last.span)
+		}
+	}
+	if columns == 0 {
+		return
+	}
+	for len(hints) < columns {
+		hints = append(hints, widthHint{})
+	}
+	// Cells of the first row hint the width of columns <col> left unsized
+	if len(rows) > 0 {
+		for _, cell := range rows[0].cells {
+			if cell.span == 1 && hints[cell.col] == (widthHint{}) {
+				hints[cell.col] = elementWidthHint(cell.node)
+			}
+		}
+	}
+	if ctx.columnWidths != nil {
+		if widths := ctx.columnWidths(node); len(widths) == columns {
+			for i, w := range widths {
+				hints[i] = widthHint{px: w}
+			}
+		}
+	}
+
+	tableW := ctx.MaxW - ctx.Left
+	widths := columnWidths(hints, tableW-spacing*float64(columns+1))
+
+	// Column start positions, after the spacing before each
+	starts := make([]float64, columns+1)
+	starts[0] = ctx.Left + spacing
+	for i, w := range widths {
+		starts[i+1] = starts[i] + w + spacing
+	}
+
+	ctx.endLine()
+	y := ctx.CursorY + spacing
+	for i, row := range rows {
+		rowBox := &RenderBox{Node: row.node, X: ctx.Left, Y: y, W: tableW, RowIndex: i + 1}
+		rowH := 0.0
+		for _, cell := range row.cells {
+			x := starts[cell.col]
+			w := starts[min(cell.col+cell.span, columns)] - spacing - x
+			cellBox := &RenderBox{Node: cell.node, X: x, Y: y, W: w, RowIndex: i + 1}
+			cellCtx := &LayoutContext{
+				CursorX: x + padding, CursorY: y + padding,
+				Left: x + padding, MaxW: x + w - padding,
+				LineHeight: ctx.LineHeight, columnWidths: ctx.columnWidths,
+			}
+			layoutBlockChildren(cell.node, cellBox, cellCtx)
+			cellCtx.endLine()
+			cellBox.H = max(cellCtx.CursorY, cellCtx.clearance("both")) + padding - y
+			rowH = max(rowH, cellBox.H)
+			rowBox.Children = append(rowBox.Children, cellBox)
+		}
+		rowH = max(rowH, ctx.LineHeight)
+		// Cells stretch to the row so their borders and backgrounds line up
+		for _, cellBox := range rowBox.Children {
+			cellBox.H = rowH
+		}
+		rowBox.H = rowH
+		container.Children = append(container.Children, rowBox)
+		y += rowH + spacing
+	}
+	ctx.CursorY = y
+	ctx.CursorX = ctx.lineLeft(ctx.CursorY)
+}
+
+// tableParts gathers a table's first caption, its column width hints from
+// <colgroup>/<col>, and its rows from thead, tbody, tfoot or the table itself
+func tableParts(table *dom.Node) (*dom.Node, []widthHint, []tableRow) {
+	var caption *dom.Node
+	var hints []widthHint
+	var rows []tableRow
+
+	addRow := func(tr *dom.Node) {
+		row := tableRow{node: tr}
+		col := 0
+		for _, cell := range tr.Children {
+			if cell.Tag != "td" && cell.Tag != "th" || isDisplayNone(cell) {
+				continue
+			}
+			span := max(attrInt(cell, "colspan", 1), 1)
+			row.cells = append(row.cells, tableCell{node: cell, col: col, span: span})
+			col += span
+		}
+		rows = append(rows, row)
+	}
+
+	for _, child := range table.Children {
+		if isDisplayNone(child) {
+			continue
+		}
+		switch child.Tag {
+		case "caption":
+			if caption == nil {
+				caption = child
+			}
+		case "colgroup":
+			cols := 0
+			for _, col := range child.Children {
+				if col.Tag == "col" {
+					hint := elementWidthHint(col)
+					for range max(attrInt(col, "span", 1), 1) {
+						hints = append(hints, hint)
+					}
+					cols++
+				}
+			}
+			// A colgroup without <col>s sizes span columns itself
+			if cols == 0 {
+				hint := elementWidthHint(child)
+				for range max(attrInt(child, "span", 1), 1) {
+					hints = append(hints, hint)
+				}
+			}
+		case "col":
+			hint := elementWidthHint(child)
+			for range max(attrInt(child, "span", 1), 1) {
+				hints = append(hints, hint)
+			}
+		case "thead", "tbody", "tfoot":
+			for _, tr := range child.Children {
+				if tr.Tag == "tr" && !isDisplayNone(tr) {
+					addRow(tr)
+				}
+			}
+		case "tr":
+			addRow(child)
+		}
+	}
+	return caption, hints, rows
+}
+
+// columnWidths shares width among the columns: hinted columns get their
+// hint and the others split what is left evenly. Hints that don't fit are
+// scaled down.
+func columnWidths(hints []widthHint, width float64) []float64 {
+	widths := make([]float64, len(hints))
+	fixed, auto := 0.0, 0
+	for i, hint := range hints {
+		widths[i] = hint.resolve(width)
+		if widths[i] > 0 {
+			fixed += widths[i]
+		} else {
+			auto++
+		}
+	}
+	if fixed > width {
+		for i := range widths {
+			widths[i] *= width / fixed
+		}
+		fixed = width
+	}
+	if auto > 0 {
+		share := (width - fixed) / float64(auto)
+		for i := range widths {
+			if widths[i] == 0 {
+				widths[i] = share
+			}
+		}
+	}
+	return widths
+}
+
+// elementWidthHint reads a width attribute ("120" or "25%"), falling back to
+// a CSS width
+func elementWidthHint(node *dom.Node) widthHint {
+	if raw := strings.TrimSpace(node.GetAttr("width")); raw != "" {
+		if pct, ok := strings.CutSuffix(raw, "%"); ok {
+			if n, err := strconv.ParseFloat(pct, 64); err == nil && n > 0 {
+				return widthHint{percent: n}
+			}
+		} else if n, err := strconv.ParseFloat(strings.TrimSuffix(raw, "px"), 64); err == nil && n > 0 {
+			return widthHint{px: n}
+		}
+	}
+	if cs, ok := node.ComputedStyle.(*css.ComputedStyle); ok && cs.Width.IsSet() {
+		if cs.Width.Unit == css.UnitPercent && cs.Width.Expr == nil {
+			return widthHint{percent: cs.Width.Value}
+		}
+		return widthHint{px: cs.Width.Resolve(cs.FontSize, 0, cs.Media.Viewport)}
+	}
+	return widthHint{}
+}
+
+// tableAttrLength reads a pixel attribute of a table such as cellpadding
+func tableAttrLength(node *dom.Node, name string, fallback float64) float64 {
+	if n, err := strconv.ParseFloat(strings.TrimSpace(node.GetAttr(name)), 64); err == nil && n >= 0 {
+		return n
+	}
+	return fallback
+}
+
+// attrInt reads an integer attribute such as colspan
+func attrInt(node *dom.Node, name string, fallback int) int {
+	if n, err := strconv.Atoi(strings.TrimSpace(node.GetAttr(name))); err == nil {
+		return n
+	}
+	return fallback
+}
+
+// isDisplayNone reports whether an element generates no box
+func isDisplayNone(node *dom.Node) bool {
+	if node.Display == dom.DisplayNone {
+		return true
+	}
+	cs, ok := node.ComputedStyle.(*css.ComputedStyle)
+	return ok && cs.Display == "none"
+}
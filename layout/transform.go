@@ -0,0 +1,59 @@
+package layout
+
+import (
+	"go-browser/css"
+	"go-browser/dom"
+)
+
+// resolveTransforms gives the boxes of transformed elements their matrix.
+// An element split over several boxes is transformed by the outermost;
+// inline boxes, which transforms don't apply to, are left alone.
+func resolveTransforms(box *RenderBox, parent *dom.Node) {
+	if box.Node != nil && box.Node != parent {
+		if cs, ok := box.Node.ComputedStyle.(*css.ComputedStyle); ok && len(cs.TransformFunctions) > 0 &&
+			(cs.Display != "inline" || box.IsImage || box.InlineBlock) {
+			originX := css.Percent(50)
+			if cs.TransformOriginX.IsSet() {
+				originX = cs.TransformOriginX
+			}
+			originY := css.Percent(50)
+			if cs.TransformOriginY.IsSet() {
+				originY = cs.TransformOriginY
+			}
+			ox, oy := originX.Resolve(cs.FontSize, box.W, cs.Media.Viewport), originY.Resolve(cs.FontSize, box.H, cs.Media.Viewport)
+			m := css.Translation(ox, oy).
+				Multiply(css.TransformMatrix(cs.TransformFunctions, box.W, box.H, cs.FontSize, cs.Media.Viewport)).
+				Multiply(css.Translation(-ox, -oy))
+			if !m.IsIdentity() {
+				box.Transform = &m
+			}
+		}
+	}
+	for _, child := range box.Children {
+		resolveTransforms(child, box.Node)
+	}
+}
+
+// TransformMatrix returns the box's transform in render tree coordinates,
+// the identity when it has none
+func (b *RenderBox) TransformMatrix() css.Matrix {
+	if b.Transform == nil {
+		return css.Identity()
+	}
+	return css.Translation(b.X, b.Y).Multiply(*b.Transform).Multiply(css.Translation(-b.X, -b.Y))
+}
+
+// Untransform maps a point in render tree coordinates to where it falls in
+// the box's subtree before its transform, for hit testing. ok is false when
+// the transform flattens the box, so that nothing in it can be hit.
+func (b *RenderBox) Untransform(x, y float64) (ux, uy float64, ok bool) {
+	if b.Transform == nil {
+		return x, y, true
+	}
+	inv, ok := b.TransformMatrix().Invert()
+	if !ok {
+		return x, y, false
+	}
+	ux, uy = inv.Apply(x, y)
+	return ux, uy, true
+}
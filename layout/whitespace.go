@@ -0,0 +1,149 @@
+package layout
+
+import (
+	"strings"
+
+	"go-browser/css"
+	"go-browser/dom"
+)
+
+// ======================================================================================
+// WHITE-SPACE PROCESSING
+// Text nodes keep their source white-space; the CSS white-space property
+// decides here how it is collapsed and where lines may break
+// ======================================================================================
+
+// whiteSpaceMode describes how a white-space value treats spaces and newlines
+type whiteSpaceMode struct {
+	collapse     bool // runs of spaces, tabs and newlines become one space
+	keepNewlines bool // newlines in the source force line breaks
+	wrap         bool // lines may break between words
+}
+
+// whiteSpaceModes maps white-space values to their behaviour; "" is normal
+var whiteSpaceModes = map[string]whiteSpaceMode{
+	"":         {collapse: true, wrap: true},
+	"normal":   {collapse: true, wrap: true},
+	"nowrap":   {collapse: true},
+	"pre":      {keepNewlines: true},
+	"pre-wrap": {keepNewlines: true, wrap: true},
+	"pre-line": {collapse: true, keepNewlines: true, wrap: true},
+}
+
+// tabSize is the number of columns a tab advances in preserved text
+const tabSize = 8
+
+// whiteSpaceOf returns the mode of a text node, taken from its parent's style
+func whiteSpaceOf(node *dom.Node) whiteSpaceMode {
+	if node.Parent != nil && node.Parent.ComputedStyle != nil {
+		if cs, ok := node.Parent.ComputedStyle.(*css.ComputedStyle); ok {
+			if mode, ok := whiteSpaceModes[cs.WhiteSpace]; ok {
+				return mode
+			}
+		}
+	}
+	return whiteSpaceModes[""]
+}
+
+// processWhiteSpace splits text into the lines separated by forced breaks,
+// collapsing or expanding white-space inside each line as mode requires
+func processWhiteSpace(text string, mode whiteSpaceMode) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	lines := []string{text}
+	if mode.keepNewlines {
+		lines = strings.Split(text, "\n")
+	}
+	for i, line := range lines {
+		if !mode.collapse {
+			lines[i] = expandTabs(line)
+			continue
+		}
+		line = collapseSpaces(line)
+		// Spaces next to a forced break are removed
+		if mode.keepNewlines {
+			if i > 0 {
+				line = strings.TrimLeft(line, " ")
+			}
+			if i < len(lines)-1 {
+				line = strings.TrimRight(line, " ")
+			}
+		}
+		lines[i] = line
+	}
+	return lines
+}
+
+// collapseSpaces replaces every run of white-space with a single space
+func collapseSpaces(s string) string {
+	var sb strings.Builder
+	inSpace := false
+	for _, r := range s {
+		switch r {
+		case ' ', '\t', '\n', '\f', '\r':
+			if !inSpace {
+				sb.WriteByte(' ')
+			}
+			inSpace = true
+		default:
+			sb.WriteRune(r)
+			inSpace = false
+		}
+	}
+	return sb.String()
+}
+
+// expandTabs replaces tabs with spaces up to the next tab stop
+func expandTabs(s string) string {
+	if !strings.Contains(s, "\t") {
+		return s
+	}
+	var sb strings.Builder
+	col := 0
+	for _, r := range s {
+		if r == '\t' {
+			n := tabSize - col%tabSize
+			sb.WriteString(strings.Repeat(" ", n))
+			col += n
+			continue
+		}
+		sb.WriteRune(r)
+		col++
+	}
+	return sb.String()
+}
+
+// splitBreakable splits a line into pieces that each end after a run of
+// spaces, the places a line may wrap: "a  b c" -> "a  ", "b ", "c"
+func splitBreakable(line string) []string {
+	var pieces []string
+	start := 0
+	for i := 0; i < len(line); i++ {
+		if line[i] == ' ' && (i+1 == len(line) || line[i+1] != ' ') {
+			pieces = append(pieces, line[start:i+1])
+			start = i + 1
+		}
+	}
+	if start < len(line) {
+		pieces = append(pieces, line[start:])
+	}
+	return pieces
+}
+
+// tableStructureTags are the table elements whose white-space text is not rendered
+var tableStructureTags = map[string]bool{
+	"table": true, "thead": true, "tbody": true, "tfoot": true, "tr": true, "colgroup": true,
+}
+
+// boxChildren returns the children of a flex or grid container that become
+// items; white-space-only text between them generates no box
+func boxChildren(node *dom.Node) []*dom.Node {
+	children := make([]*dom.Node, 0, len(node.Children))
+	for _, child := range node.Children {
+		if !child.IsWhiteSpaceText() {
+			children = append(children, child)
+		}
+	}
+	return children
+}
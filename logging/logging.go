@@ -0,0 +1,182 @@
+// Package logging is the browser's leveled log, split by category so that
+// what a page does to the network, its scripts, layout or painting can be
+// followed on its own while the rest stays quiet
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ======================================================================================
+// CATEGORIES AND LEVELS
+// Each category is an slog.Logger whose records carry cat=NAME and pass
+// only at or above the category's own level. Every level starts at warn, so
+// a normal run prints warnings and errors only; Configure lowers or raises
+// them at any time, from the --log flag or the "log" setting.
+// ======================================================================================
+
+// LevelOff is above every level: a category at it logs nothing
+const LevelOff = slog.LevelError + 4
+
+// DefaultLevel is the level every category starts at
+const DefaultLevel = slog.LevelWarn
+
+// The categories. Net is requests and what blocks them, JS page scripts and
+// their bindings, Layout styling and layout, Paint drawing and images, and
+// App the browser itself: settings, files, downloads and windows.
+var (
+	Net    = New("net")
+	JS     = New("js")
+	Layout = New("layout")
+	Paint  = New("paint")
+	App    = New("app")
+)
+
+// levels holds each category's level, by name
+var levels = struct {
+	sync.Mutex
+	byName map[string]*slog.LevelVar
+}{byName: make(map[string]*slog.LevelVar)}
+
+// output is where every category writes
+var output = struct {
+	sync.Mutex
+	w io.Writer
+}{w: os.Stderr}
+
+// New returns the logger of a category, creating the category the first
+// time its name is seen
+func New(category string) *slog.Logger {
+	levels.Lock()
+	defer levels.Unlock()
+	level := levels.byName[category]
+	if level == nil {
+		level = new(slog.LevelVar)
+		level.Set(DefaultLevel)
+		levels.byName[category] = level
+	}
+	text := slog.NewTextHandler(writer{}, &slog.HandlerOptions{Level: slog.LevelDebug - 4})
+	return slog.New(&handler{Handler: text.WithAttrs([]slog.Attr{slog.String("cat", category)}), level: level})
+}
+
+// Categories returns the names of the categories
+func Categories() []string {
+	levels.Lock()
+	defer levels.Unlock()
+	names := make([]string, 0, len(levels.byName))
+	for name := range levels.byName {
+		names = append(names, name)
+	}
+	return names
+}
+
+// SetLevel sets the level of a category, or of every category when
+// category is ""
+func SetLevel(category string, level slog.Level) error {
+	levels.Lock()
+	defer levels.Unlock()
+	if category == "" {
+		for _, v := range levels.byName {
+			v.Set(level)
+		}
+		return nil
+	}
+	v := levels.byName[category]
+	if v == nil {
+		return fmt.Errorf("unknown log category %q", category)
+	}
+	v.Set(level)
+	return nil
+}
+
+// Level returns the level of a category
+func Level(category string) slog.Level {
+	levels.Lock()
+	defer levels.Unlock()
+	if v := levels.byName[category]; v != nil {
+		return v.Level()
+	}
+	return DefaultLevel
+}
+
+// Configure sets levels from a comma-separated list such as
+// "info,js=debug,paint=off": a level alone applies to every category and
+// CATEGORY=LEVEL to one, left to right. Levels are debug, info, warn, error
+// and off.
+func Configure(spec string) error {
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		category, name, found := strings.Cut(entry, "=")
+		if !found {
+			category, name = "", entry
+		}
+		level, err := ParseLevel(name)
+		if err != nil {
+			return err
+		}
+		if err := SetLevel(strings.TrimSpace(category), level); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseLevel parses a level name: debug, info, warn, error or off
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	case "off", "none", "quiet":
+		return LevelOff, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q", name)
+}
+
+// SetOutput makes every category write to w, by default stderr
+func SetOutput(w io.Writer) {
+	output.Lock()
+	defer output.Unlock()
+	output.w = w
+}
+
+// writer writes to the output set with SetOutput
+type writer struct{}
+
+func (writer) Write(p []byte) (int, error) {
+	output.Lock()
+	defer output.Unlock()
+	return output.w.Write(p)
+}
+
+// handler passes on the records at or above its category's level
+type handler struct {
+	slog.Handler
+	level *slog.LevelVar
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &handler{Handler: h.Handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{Handler: h.Handler.WithGroup(name), level: h.level}
+}
@@ -0,0 +1,58 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+// reset puts every category back at the default level and output
+func reset(t *testing.T) {
+	t.Cleanup(func() {
+		SetLevel("", DefaultLevel)
+		SetOutput(os.Stderr)
+	})
+}
+
+func TestConfigure(t *testing.T) {
+	reset(t)
+	if err := Configure("info, js=debug,paint=off"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]slog.Level{"net": slog.LevelInfo, "js": slog.LevelDebug, "paint": LevelOff, "app": slog.LevelInfo}
+	for category, level := range want {
+		if got := Level(category); got != level {
+			t.Errorf("%s level = %v, want %v", category, got, level)
+		}
+	}
+	if err := Configure("js=loud"); err == nil {
+		t.Error("unknown level accepted")
+	}
+	if err := Configure("dns=debug"); err == nil {
+		t.Error("unknown category accepted")
+	}
+}
+
+func TestCategoryLevels(t *testing.T) {
+	reset(t)
+	var out bytes.Buffer
+	SetOutput(&out)
+	Configure("net=debug")
+
+	Net.Debug("fetching", "url", "https://example.com/")
+	JS.Info("hidden at warn")
+	JS.Warn("shown at warn")
+
+	got := out.String()
+	if !strings.Contains(got, "cat=net") || !strings.Contains(got, `url=https://example.com/`) {
+		t.Errorf("net debug record missing:\n%s", got)
+	}
+	if strings.Contains(got, "hidden at warn") {
+		t.Errorf("js info passed a warn level:\n%s", got)
+	}
+	if !strings.Contains(got, `msg="shown at warn" cat=js`) {
+		t.Errorf("js warn record missing:\n%s", got)
+	}
+}
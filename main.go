@@ -3,16 +3,26 @@ package main
 import (
 	"bytes"
 	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
-	"strings"
 
+	"go-browser/a11y"
 	"go-browser/browser"
+	"go-browser/conformance"
+	"go-browser/dom"
+	"go-browser/headless"
+	"go-browser/logging"
 	"go-browser/render"
+	"go-browser/spidergopher"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/text/v2"
+	"golang.org/x/image/font/gofont/gomono"
 )
 
 //go:embed fonts/Inter-Regular.ttf
@@ -24,37 +34,191 @@ func init() {
 		log.Fatal("Error loading font:", err)
 	}
 	render.SetFontSource(src)
+	browser.PrintFont = fontData
+
+	mono, err := text.NewGoTextFaceSource(bytes.NewReader(gomono.TTF))
+	if err != nil {
+		log.Fatal("Error loading monospace font:", err)
+	}
+	render.SetMonoFontSource(mono)
 }
 
 func main() {
+	// --a11y-dump <url|file> prints the accessibility tree and exits
+	if len(os.Args) > 2 && os.Args[1] == "--a11y-dump" {
+		if err := dumpAccessibilityTree(os.Args[2]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// --layout-dump <url|file> prints the laid-out box tree and exits
+	if len(os.Args) > 2 && os.Args[1] == "--layout-dump" {
+		if err := dumpLayout(os.Args[2]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// --api-manifest prints the scripting API as JSON and exits
+	if len(os.Args) > 1 && os.Args[1] == "--api-manifest" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(spidergopher.GenerateAPIManifest()); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// --wpt [dir] runs Web Platform Tests, the curated subset or the files
+	// under dir, prints the results and exits
+	if len(os.Args) > 1 && os.Args[1] == "--wpt" {
+		if err := runWPT(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// --dom-dump <url|file> prints the DOM tree with node IDs and exits
+	if len(os.Args) > 2 && os.Args[1] == "--dom-dump" {
+		if err := dumpDOMTree(os.Args[2]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	ebiten.SetWindowSize(browser.WindowWidth, browser.WindowHeight)
 	ebiten.SetWindowTitle("GoBrowser")
 	ebiten.SetWindowResizingMode(ebiten.WindowResizingModeEnabled)
 
-	app := browser.NewApp()
+	// --private opens a private window, --profile NAME keeps the browser's
+	// data in the named profile, --log SPEC sets the log levels and
+	// --debug-node-ids puts node IDs in serialized markup, before an
+	// optional url|file
+	args := os.Args[1:]
+	private := false
+	logSpec := ""
+	for len(args) > 0 {
+		if args[0] == browser.PrivateFlag {
+			private = true
+			args = args[1:]
+		} else if args[0] == browser.ProfileFlag && len(args) > 1 {
+			if err := browser.SetProfile(args[1]); err != nil {
+				log.Fatal(err)
+			}
+			args = args[2:]
+		} else if args[0] == "--debug-node-ids" {
+			dom.DebugNodeIDs = true
+			args = args[1:]
+		} else if args[0] == "--log" && len(args) > 1 {
+			logSpec = args[1]
+			args = args[2:]
+		} else {
+			break
+		}
+	}
+	if err := logging.Configure(logSpec); err != nil {
+		log.Fatal(err)
+	}
+	var app *browser.App
+	if private {
+		app = browser.NewPrivateApp()
+	} else {
+		app = browser.NewApp()
+	}
+	// The flag overrides the log setting NewApp applied
+	if err := logging.Configure(logSpec); err != nil {
+		log.Fatal(err)
+	}
 
-	// Load initial URL or default
-	if len(os.Args) > 1 {
-		url := os.Args[1]
+	// Load the URL given, or what the startup setting asks for
+	if len(args) > 0 {
+		target := args[0]
 
-		// If it's not a URL (no protocol), treat as file path
-		if !strings.HasPrefix(url, "http://") &&
-			!strings.HasPrefix(url, "https://") &&
-			!strings.HasPrefix(url, "file://") {
+		// A file on disk, or anything without a scheme, is a file path;
+		// data:, about:, view-source: and the like load as they are
+		_, statErr := os.Stat(target)
+		if u, err := url.Parse(target); statErr == nil || err != nil || u.Scheme == "" {
 			// Convert to absolute path for file:// protocol
-			absPath, err := filepath.Abs(url)
+			absPath, err := filepath.Abs(target)
 			if err == nil {
-				url = "file://" + absPath
+				target = "file://" + absPath
 			}
 		}
 
-		app.URL = url
-		app.LoadFromURL(url)
+		app.URL = target
+		app.LoadFromURL(target)
 	} else {
-		app.LoadFromURL("https://example.com")
+		app.Start()
 	}
 
 	if err := ebiten.RunGame(app); err != nil {
 		log.Fatal(err)
 	}
+	if err := app.SaveProfile(); err != nil {
+		logging.App.Error("saving profile", "err", err)
+	}
+}
+
+// dumpAccessibilityTree loads a page without opening a window and writes its
+// accessibility tree to stdout, one indented node per line
+func dumpAccessibilityTree(target string) error {
+	page, err := headless.Load(target)
+	if err != nil {
+		return err
+	}
+	return a11y.Dump(os.Stdout, a11y.Build(page.Document.Node))
+}
+
+// dumpDOMTree loads a page without opening a window and writes its DOM tree
+// to stdout, each node followed by its node ID
+func dumpDOMTree(target string) error {
+	page, err := headless.Load(target)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(os.Stdout, page.Document.Node.DebugString())
+	return err
+}
+
+// dumpLayout loads a page without opening a window, runs its scripts and
+// writes its box tree, laid out at the window's width, to stdout
+func dumpLayout(target string) error {
+	page, err := headless.Load(target)
+	if err != nil {
+		return err
+	}
+	defer page.Close()
+	page.RunScripts()
+	_, err = io.WriteString(os.Stdout, page.SerializedLayout(browser.WindowWidth))
+	return err
+}
+
+// runWPT runs the curated Web Platform Tests, or those under the directory
+// args names, and writes each file's score, its failing subtests and the
+// score of each area to stdout
+func runWPT(args []string) error {
+	report := conformance.RunWPT()
+	if len(args) > 0 {
+		var err error
+		if report, err = conformance.RunWPTDir(args[0]); err != nil {
+			return err
+		}
+	}
+	for _, f := range report.Files {
+		fmt.Printf("%-50s %d/%d\n", f.Path, f.Passed(), len(f.Subtests))
+		if f.Error != "" {
+			fmt.Printf("  ERROR %s\n", f.Error)
+		}
+		for _, s := range f.Subtests {
+			if s.Status != conformance.WPTPass {
+				fmt.Printf("  %s %s: %s\n", s.Status, s.Name, s.Message)
+			}
+		}
+	}
+	fmt.Println()
+	for _, s := range append(report.Scores(), report.Total()) {
+		fmt.Printf("%-10s %d/%d (%.0f%%)\n", s.Area, s.Passed, s.Total, s.Percent())
+	}
+	return nil
 }
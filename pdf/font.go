@@ -0,0 +1,124 @@
+package pdf
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// ======================================================================================
+// FONTS
+// Text is set in TrueType fonts embedded whole, encoded in WinAnsiEncoding
+// so viewers can select and copy it. Characters the encoding lacks are
+// written as question marks.
+// ======================================================================================
+
+// Font is a TrueType font text can be set in
+type Font struct {
+	name      string // its PostScript name
+	data      []byte
+	monospace bool     // whether every glyph is as wide
+	widths    [256]int // advance of each code, in thousandths of an em
+	bbox      [4]int   // bounding box of its glyphs, in thousandths of an em
+	ascent    int      // in thousandths of an em
+	descent   int      // in thousandths of an em, below the baseline so negative
+	capHeight int      // in thousandths of an em
+	resource  string   // its name in page resources
+	id        int      // object number of its font dictionary once written
+}
+
+// winAnsiHigh are the characters of WinAnsiEncoding codes 128 to 159; the
+// rest of it is Latin-1
+var winAnsiHigh = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
+
+// ParseFont reads a TrueType font to embed; monospace marks a font whose
+// glyphs are all as wide
+func ParseFont(data []byte, monospace bool) (*Font, error) {
+	sf, err := sfnt.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("pdf: %w", err)
+	}
+	f := &Font{data: data, monospace: monospace}
+	var buf sfnt.Buffer
+	// Measured at 1000 pixels per em, sizes come out in thousandths of an em
+	em := fixed.I(1000)
+
+	f.name, _ = sf.Name(&buf, sfnt.NameIDPostScript)
+	f.name = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || strings.ContainsRune("()<>[]{}/%#", r) {
+			return -1
+		}
+		return r
+	}, f.name)
+	if f.name == "" {
+		f.name = "Font"
+	}
+
+	for code := 32; code < 256; code++ {
+		r := decodeWinAnsi(byte(code))
+		if r == 0 {
+			continue
+		}
+		glyph, err := sf.GlyphIndex(&buf, r)
+		if err != nil || glyph == 0 {
+			continue
+		}
+		if advance, err := sf.GlyphAdvance(&buf, glyph, em, font.HintingNone); err == nil {
+			f.widths[code] = advance.Round()
+		}
+	}
+	if bounds, err := sf.Bounds(&buf, em, font.HintingNone); err == nil {
+		// sfnt's y axis points down
+		f.bbox = [4]int{bounds.Min.X.Round(), -bounds.Max.Y.Round(), bounds.Max.X.Round(), -bounds.Min.Y.Round()}
+	}
+	if metrics, err := sf.Metrics(&buf, em, font.HintingNone); err == nil {
+		f.ascent = metrics.Ascent.Round()
+		f.descent = -metrics.Descent.Round()
+		f.capHeight = metrics.CapHeight.Round()
+	}
+	if f.capHeight == 0 {
+		f.capHeight = f.ascent
+	}
+	return f, nil
+}
+
+// decodeWinAnsi returns the character a WinAnsiEncoding code stands for, or
+// 0 for none
+func decodeWinAnsi(code byte) rune {
+	switch {
+	case code < 32 || code == 127:
+		return 0
+	case code >= 128 && code < 160:
+		return winAnsiHigh[code-128]
+	}
+	return rune(code)
+}
+
+// encodeWinAnsi returns s in WinAnsiEncoding
+func encodeWinAnsi(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r >= 32 && r < 127, r >= 160 && r < 256:
+			out = append(out, byte(r))
+		case r == '\t' || r == '\n' || r == ' ':
+			out = append(out, ' ')
+		default:
+			code := byte('?')
+			for i, c := range winAnsiHigh {
+				if c == r && c != 0 {
+					code = byte(128 + i)
+					break
+				}
+			}
+			out = append(out, code)
+		}
+	}
+	return out
+}
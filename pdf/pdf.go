@@ -0,0 +1,326 @@
+// Package pdf writes PDF documents of filled rectangles, text in embedded
+// TrueType fonts and images, which is what a printed page is made of
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ======================================================================================
+// DOCUMENTS
+// A document is a run of pages of one size. Pages are drawn on in CSS
+// pixels, 96 to the inch, from their top-left corner, and written out in
+// points, 72 to the inch.
+// ======================================================================================
+
+// pointsPerPixel converts CSS pixels to PDF points
+const pointsPerPixel = 72.0 / 96.0
+
+// Paper sizes in CSS pixels
+const (
+	A4Width, A4Height         = 793.7, 1122.5
+	LetterWidth, LetterHeight = 816.0, 1056.0
+)
+
+// Document is a PDF being drawn
+type Document struct {
+	Title string // shown by viewers in place of the file name; "" for none
+
+	width, height float64 // page size in CSS pixels
+	pages         []*Page
+	fonts         []*Font
+	images        []*pdfImage
+	imageOf       map[image.Image]*pdfImage
+	alphas        map[uint8]bool // opacities drawn with, each an ExtGState
+}
+
+// Page is one page of a document
+type Page struct {
+	doc     *Document
+	content bytes.Buffer
+}
+
+// pdfImage is an image placed in the document, written once however often
+// it is drawn
+type pdfImage struct {
+	img      image.Image
+	resource string
+}
+
+// New creates a document whose pages are width by height CSS pixels
+func New(width, height float64) *Document {
+	return &Document{
+		width:   width,
+		height:  height,
+		imageOf: make(map[image.Image]*pdfImage),
+		alphas:  make(map[uint8]bool),
+	}
+}
+
+// AddFont makes f available to the document's pages
+func (d *Document) AddFont(f *Font) {
+	f.resource = "F" + strconv.Itoa(len(d.fonts)+1)
+	d.fonts = append(d.fonts, f)
+}
+
+// AddPage starts a new page at the end of the document
+func (d *Document) AddPage() *Page {
+	p := &Page{doc: d}
+	// Flip the y axis and scale pixels to points
+	fmt.Fprintf(&p.content, "%s 0 0 %s 0 %s cm\n",
+		num(pointsPerPixel), num(-pointsPerPixel), num(d.height*pointsPerPixel))
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// PageCount returns the number of pages
+func (d *Document) PageCount() int {
+	return len(d.pages)
+}
+
+// num formats a number for a content stream, to four decimals
+func num(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1e4)/1e4, 'f', -1, 64)
+}
+
+// setFill sets the fill color, with its opacity, for what is drawn next
+func (p *Page) setFill(c color.RGBA) {
+	fmt.Fprintf(&p.content, "%s %s %s rg\n", num(float64(c.R)/255), num(float64(c.G)/255), num(float64(c.B)/255))
+	if c.A < 255 {
+		p.doc.alphas[c.A] = true
+		fmt.Fprintf(&p.content, "/GS%d gs\n", c.A)
+	}
+}
+
+// Save pushes the drawing state, to be popped by Restore
+func (p *Page) Save() {
+	p.content.WriteString("q\n")
+}
+
+// Restore pops the drawing state Save pushed
+func (p *Page) Restore() {
+	p.content.WriteString("Q\n")
+}
+
+// Clip limits what is drawn from now on, until Restore, to a rectangle
+func (p *Page) Clip(x, y, w, h float64) {
+	fmt.Fprintf(&p.content, "%s %s %s %s re W n\n", num(x), num(y), num(w), num(h))
+}
+
+// FillRect fills a rectangle with c
+func (p *Page) FillRect(x, y, w, h float64, c color.RGBA) {
+	if w <= 0 || h <= 0 || c.A == 0 {
+		return
+	}
+	p.Save()
+	p.setFill(c)
+	fmt.Fprintf(&p.content, "%s %s %s %s re f\n", num(x), num(y), num(w), num(h))
+	p.Restore()
+}
+
+// Text sets txt in f at size pixels, starting at x on the baseline, with
+// letterSpacing added after every character and wordSpacing after every space
+func (p *Page) Text(f *Font, txt string, x, baseline, size, letterSpacing, wordSpacing float64, c color.RGBA) {
+	if txt == "" || c.A == 0 {
+		return
+	}
+	p.Save()
+	p.setFill(c)
+	fmt.Fprintf(&p.content, "BT /%s %s Tf %s Tc %s Tw 1 0 0 -1 %s %s Tm (",
+		f.resource, num(size), num(letterSpacing), num(wordSpacing), num(x), num(baseline))
+	for _, b := range encodeWinAnsi(txt) {
+		if b == '(' || b == ')' || b == '\\' {
+			p.content.WriteByte('\\')
+		}
+		p.content.WriteByte(b)
+	}
+	p.content.WriteString(") Tj ET\n")
+	p.Restore()
+}
+
+// Image draws img stretched over a rectangle
+func (p *Page) Image(img image.Image, x, y, w, h float64) {
+	if w <= 0 || h <= 0 || img.Bounds().Empty() {
+		return
+	}
+	placed := p.doc.imageOf[img]
+	if placed == nil {
+		placed = &pdfImage{img: img, resource: "Im" + strconv.Itoa(len(p.doc.images)+1)}
+		p.doc.images = append(p.doc.images, placed)
+		p.doc.imageOf[img] = placed
+	}
+	// The image fills the unit square with its first row at the top
+	fmt.Fprintf(&p.content, "q %s 0 0 %s %s %s cm /%s Do Q\n",
+		num(w), num(-h), num(x), num(y+h), placed.resource)
+}
+
+// ======================================================================================
+// WRITING
+// ======================================================================================
+
+// writer lays out the numbered objects of a PDF file
+type writer struct {
+	buf     bytes.Buffer
+	offsets []int // byte offset of each object, by number - 1
+}
+
+// reserve returns the number of an object to be written later
+func (w *writer) reserve() int {
+	w.offsets = append(w.offsets, 0)
+	return len(w.offsets)
+}
+
+// object writes object id with body as its value
+func (w *writer) object(id int, body string) {
+	w.offsets[id-1] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+// stream writes object id as a stream of data compressed, with the entries
+// of dict besides its length and filter
+func (w *writer) stream(id int, dict string, data []byte) {
+	var packed bytes.Buffer
+	zw := zlib.NewWriter(&packed)
+	zw.Write(data)
+	zw.Close()
+	w.offsets[id-1] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n<< %s /Filter /FlateDecode /Length %d >>\nstream\n", id, dict, packed.Len())
+	w.buf.Write(packed.Bytes())
+	w.buf.WriteString("\nendstream\nendobj\n")
+}
+
+// Write writes the document out as a PDF file
+func (d *Document) Write(out io.Writer) error {
+	w := &writer{}
+	w.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	catalog, pages, info := w.reserve(), w.reserve(), w.reserve()
+
+	var resources strings.Builder
+	resources.WriteString("<< /ProcSet [/PDF /Text /ImageC]")
+	if len(d.fonts) > 0 {
+		resources.WriteString(" /Font <<")
+		for _, f := range d.fonts {
+			f.id = w.reserve()
+			fmt.Fprintf(&resources, " /%s %d 0 R", f.resource, f.id)
+		}
+		resources.WriteString(" >>")
+	}
+	imageIDs := make([]int, len(d.images))
+	if len(d.images) > 0 {
+		resources.WriteString(" /XObject <<")
+		for i, img := range d.images {
+			imageIDs[i] = w.reserve()
+			fmt.Fprintf(&resources, " /%s %d 0 R", img.resource, imageIDs[i])
+		}
+		resources.WriteString(" >>")
+	}
+	if len(d.alphas) > 0 {
+		resources.WriteString(" /ExtGState <<")
+		for _, a := range slices.Sorted(maps.Keys(d.alphas)) {
+			fmt.Fprintf(&resources, " /GS%d << /ca %s /CA %s >>", a, num(float64(a)/255), num(float64(a)/255))
+		}
+		resources.WriteString(" >>")
+	}
+	resources.WriteString(" >>")
+
+	for _, f := range d.fonts {
+		writeFont(w, f)
+	}
+	for i, img := range d.images {
+		writeImage(w, imageIDs[i], img.img)
+	}
+
+	mediaBox := fmt.Sprintf("[0 0 %s %s]", num(d.width*pointsPerPixel), num(d.height*pointsPerPixel))
+	var kids []string
+	for _, p := range d.pages {
+		page, content := w.reserve(), w.reserve()
+		w.stream(content, "", p.content.Bytes())
+		w.object(page, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox %s /Resources %s /Contents %d 0 R >>",
+			pages, mediaBox, resources.String(), content))
+		kids = append(kids, fmt.Sprintf("%d 0 R", page))
+	}
+	w.object(pages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+	w.object(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+	w.object(info, fmt.Sprintf("<< /Title %s /Producer (go-browser) >>", textString(d.Title)))
+
+	xref := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, offset := range w.offsets {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(w.offsets)+1, catalog, info, xref)
+	_, err := out.Write(w.buf.Bytes())
+	return err
+}
+
+// textString writes s as a PDF text string, in UTF-16 so any character fits
+func textString(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, r := range s {
+		if r > 0xFFFF {
+			r -= 0x10000
+			fmt.Fprintf(&b, "%04X%04X", 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+			continue
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// writeFont writes f's font dictionary, its descriptor and the font file
+func writeFont(w *writer, f *Font) {
+	descriptor, file := w.reserve(), w.reserve()
+	widths := make([]string, 0, 224)
+	for code := 32; code < 256; code++ {
+		widths = append(widths, strconv.Itoa(f.widths[code]))
+	}
+	w.object(f.id, fmt.Sprintf("<< /Type /Font /Subtype /TrueType /BaseFont /%s /FirstChar 32 /LastChar 255 /Widths [%s] /Encoding /WinAnsiEncoding /FontDescriptor %d 0 R >>",
+		f.name, strings.Join(widths, " "), descriptor))
+	// Nonsymbolic, and fixed pitch for a monospace font
+	flags := 32
+	if f.monospace {
+		flags |= 1
+	}
+	w.object(descriptor, fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags %d /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		f.name, flags, f.bbox[0], f.bbox[1], f.bbox[2], f.bbox[3], f.ascent, f.descent, f.capHeight, file))
+	w.stream(file, fmt.Sprintf("/Length1 %d", len(f.data)), f.data)
+}
+
+// writeImage writes img as an RGB image, with its alpha as a soft mask
+// when it has transparent pixels
+func writeImage(w *writer, id int, img image.Image) {
+	bounds := img.Bounds()
+	rgb := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+	alpha := make([]byte, 0, bounds.Dx()*bounds.Dy())
+	opaque := true
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			rgb = append(rgb, c.R, c.G, c.B)
+			alpha = append(alpha, c.A)
+			opaque = opaque && c.A == 255
+		}
+	}
+	dict := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8",
+		bounds.Dx(), bounds.Dy())
+	if !opaque {
+		mask := w.reserve()
+		w.stream(mask, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 8",
+			bounds.Dx(), bounds.Dy()), alpha)
+		dict += fmt.Sprintf(" /SMask %d 0 R", mask)
+	}
+	w.stream(id, dict, rgb)
+}
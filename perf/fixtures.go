@@ -0,0 +1,38 @@
+package perf
+
+import (
+	"embed"
+	"path"
+	"slices"
+	"strings"
+)
+
+// ======================================================================================
+// FIXTURES
+// Pages the engines are benchmarked on, each standing for a kind of page:
+// a long article, a large data table and a page on utility-class framework
+// CSS, with many rules and many classes per element
+// ======================================================================================
+
+//go:embed fixtures/*.html
+var fixtures embed.FS
+
+// FixtureNames returns the names of the fixture pages, sorted
+func FixtureNames() []string {
+	entries, _ := fixtures.ReadDir("fixtures")
+	var names []string
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".html"))
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Fixture returns the HTML of the fixture page name, or "" when there is none
+func Fixture(name string) string {
+	data, err := fixtures.ReadFile(path.Join("fixtures", name+".html"))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
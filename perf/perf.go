@@ -0,0 +1,87 @@
+// Package perf times the stages a page goes through, so the browser can show
+// how long they take, and holds the pages the engines are benchmarked on
+package perf
+
+import (
+	"sync"
+	"time"
+)
+
+// ======================================================================================
+// TIMINGS
+// The browser records how long each stage of showing a page takes as it
+// runs: parsing, styling, layout and painting. Pages are prepared off the
+// main thread, so recording is safe from any goroutine.
+// ======================================================================================
+
+// The stages timings are recorded for, in the order a page goes through them
+const (
+	StageParse       = "parse"        // HTML into a document
+	StageStyle       = "style"        // the cascade over the document
+	StageLayout      = "layout"       // a page's whole render tree
+	StageLayoutSlice = "layout slice" // the part of a layout run in one frame
+	StagePaint       = "paint"        // drawing a frame
+)
+
+// Stages lists the stages in order
+var Stages = []string{StageParse, StageStyle, StageLayout, StageLayoutSlice, StagePaint}
+
+// Stat sums up the durations recorded for a stage
+type Stat struct {
+	Stage string
+	Count int
+	Last  time.Duration
+	Mean  time.Duration
+	Max   time.Duration
+	total time.Duration
+}
+
+// timings holds the stats of each stage
+var timings = struct {
+	sync.Mutex
+	stats map[string]*Stat
+}{stats: make(map[string]*Stat)}
+
+// Record adds a duration of stage
+func Record(stage string, d time.Duration) {
+	timings.Lock()
+	defer timings.Unlock()
+	s := timings.stats[stage]
+	if s == nil {
+		s = &Stat{Stage: stage}
+		timings.stats[stage] = s
+	}
+	s.Count++
+	s.Last = d
+	s.total += d
+	s.Mean = s.total / time.Duration(s.Count)
+	s.Max = max(s.Max, d)
+}
+
+// Since records the time since start as a duration of stage
+func Since(stage string, start time.Time) {
+	Record(stage, time.Since(start))
+}
+
+// Stats returns the stats of every stage, in the order of Stages; stages
+// not run yet have a zero Count
+func Stats() []Stat {
+	timings.Lock()
+	defer timings.Unlock()
+	stats := make([]Stat, 0, len(Stages))
+	for _, stage := range Stages {
+		if s := timings.stats[stage]; s != nil {
+			stats = append(stats, *s)
+		} else {
+			stats = append(stats, Stat{Stage: stage})
+		}
+	}
+	return stats
+}
+
+// Reset forgets every duration recorded
+func Reset() {
+	timings.Lock()
+	defer timings.Unlock()
+	timings.stats = make(map[string]*Stat)
+}
@@ -0,0 +1,105 @@
+package perf
+
+import (
+	"testing"
+
+	"go-browser/css"
+	"go-browser/dom"
+	"go-browser/layout"
+)
+
+// fixturePage parses a fixture with its stylesheets
+func fixturePage(b *testing.B, name string) (*dom.Document, []*css.Stylesheet) {
+	b.Helper()
+	html := Fixture(name)
+	if html == "" {
+		b.Fatalf("no fixture %q", name)
+	}
+	doc := dom.ParseDocument(html)
+	return doc, css.ExtractStylesheets(doc.Node)
+}
+
+// elements returns every element under node
+func elements(node *dom.Node) []*dom.Node {
+	var out []*dom.Node
+	if node.Type == dom.NodeElement {
+		out = append(out, node)
+	}
+	for _, child := range node.Children {
+		out = append(out, elements(child)...)
+	}
+	return out
+}
+
+func BenchmarkParse(b *testing.B) {
+	for _, name := range FixtureNames() {
+		html := Fixture(name)
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(html)))
+			for b.Loop() {
+				dom.ParseDocument(html)
+			}
+		})
+	}
+}
+
+func BenchmarkSelectorMatch(b *testing.B) {
+	for _, name := range FixtureNames() {
+		b.Run(name, func(b *testing.B) {
+			doc, sheets := fixturePage(b, name)
+			nodes := elements(doc.Node)
+			for b.Loop() {
+				for _, sheet := range sheets {
+					for _, rule := range sheet.Rules {
+						for _, sel := range rule.Selectors {
+							for _, node := range nodes {
+								sel.Matches(node)
+							}
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCascade(b *testing.B) {
+	for _, name := range FixtureNames() {
+		b.Run(name, func(b *testing.B) {
+			doc, sheets := fixturePage(b, name)
+			for b.Loop() {
+				css.ApplyStylesToTree(doc.Node, sheets, css.DefaultMedia())
+			}
+		})
+	}
+}
+
+func BenchmarkBuildRenderTree(b *testing.B) {
+	for _, name := range FixtureNames() {
+		b.Run(name, func(b *testing.B) {
+			doc, sheets := fixturePage(b, name)
+			css.ApplyStylesToTree(doc.Node, sheets, css.DefaultMedia())
+			for b.Loop() {
+				layout.BuildRenderTree(doc.Node, 1024, nil)
+			}
+		})
+	}
+}
+
+func TestRecord(t *testing.T) {
+	Reset()
+	Record(StageLayout, 2)
+	Record(StageLayout, 6)
+	for _, s := range Stats() {
+		switch s.Stage {
+		case StageLayout:
+			if s.Count != 2 || s.Last != 6 || s.Mean != 4 || s.Max != 6 {
+				t.Errorf("layout stat = %+v", s)
+			}
+		default:
+			if s.Count != 0 {
+				t.Errorf("%s recorded %d times", s.Stage, s.Count)
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	"time"
+
+	"github.com/hajimehoshi/ebiten/v2"
+	_ "golang.org/x/image/webp"
+)
+
+// ======================================================================================
+// IMAGE DECODING
+// PNG, JPEG, GIF and WebP images decode with the image package. Animated GIFs
+// keep every frame, composed as the GIF disposes of the one before, and play
+// as they are drawn. AVIF has no decoder here yet: those images fail, and the
+// Accept header asks servers for another format instead.
+// ======================================================================================
+
+// ImageAccept is the Accept header images are requested with: the formats
+// that decode, so servers choosing a format per client don't send AVIF
+const ImageAccept = "image/webp,image/png,image/jpeg,image/gif,image/*;q=0.8,*/*;q=0.5"
+
+// errAVIF is the error of AVIF images, which can't be decoded yet
+var errAVIF = errors.New("AVIF images are not supported")
+
+// minFrameDelay is the shortest a GIF frame shows; browsers slow faster
+// frames down to it, as old GIFs were made for slow decoders
+const minFrameDelay = 100 * time.Millisecond
+
+// Animation is an animated image: its frames and how long each one shows
+type Animation struct {
+	frames []*ebiten.Image
+	delays []time.Duration
+	total  time.Duration // one play through
+	plays  int           // times it plays before stopping on its last frame; 0 forever
+	start  time.Time
+}
+
+// decodeImage decodes an image. An animated GIF comes back as an Animation;
+// any other image as its only frame.
+func decodeImage(data []byte) (image.Image, *Animation, error) {
+	if isAVIF(data) {
+		return nil, nil, errAVIF
+	}
+	if g, err := gif.DecodeAll(bytes.NewReader(data)); err == nil && len(g.Image) > 1 {
+		return nil, newAnimation(g), nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, nil, err
+}
+
+// isAVIF reports whether data starts like an AVIF file: an ISO-BMFF ftyp
+// box with an AVIF brand
+func isAVIF(data []byte) bool {
+	if len(data) < 12 || string(data[4:8]) != "ftyp" {
+		return false
+	}
+	brand := string(data[8:12])
+	return brand == "avif" || brand == "avis"
+}
+
+// newAnimation composes the frames of an animated GIF
+func newAnimation(g *gif.GIF) *Animation {
+	bounds := image.Rect(0, 0, g.Config.Width, g.Config.Height)
+	if bounds.Empty() {
+		for _, frame := range g.Image {
+			bounds = bounds.Union(frame.Bounds())
+		}
+	}
+
+	a := &Animation{start: time.Now()}
+	switch {
+	case g.LoopCount == 0:
+		a.plays = 0
+	case g.LoopCount < 0:
+		a.plays = 1
+	default:
+		a.plays = g.LoopCount + 1
+	}
+
+	canvas := image.NewRGBA(bounds)
+	for i, frame := range g.Image {
+		var disposal byte
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		var previous *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(bounds)
+			copy(previous.Pix, canvas.Pix)
+		}
+
+		draw.Draw(canvas, frame.Bounds(), frame, frame.Bounds().Min, draw.Over)
+		a.frames = append(a.frames, ebiten.NewImageFromImage(canvas))
+		delay := minFrameDelay
+		if i < len(g.Delay) && g.Delay[i] > 1 {
+			delay = time.Duration(g.Delay[i]) * 10 * time.Millisecond
+		}
+		a.delays = append(a.delays, delay)
+		a.total += delay
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, frame.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = previous
+		}
+	}
+	return a
+}
+
+// Frame returns the frame showing at now
+func (a *Animation) Frame(now time.Time) *ebiten.Image {
+	last := a.frames[len(a.frames)-1]
+	elapsed := now.Sub(a.start)
+	if a.plays > 0 && elapsed >= a.total*time.Duration(a.plays) {
+		return last
+	}
+	elapsed %= a.total
+	for i, delay := range a.delays {
+		if elapsed < delay {
+			return a.frames[i]
+		}
+		elapsed -= delay
+	}
+	return last
+}
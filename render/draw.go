@@ -2,15 +2,17 @@
 package render
 
 import (
+	"container/list"
+	"fmt"
 	"image"
 	"image/color"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
-	"net/http"
-	"net/url"
+	"math"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/hajimehoshi/ebiten/v2"
 	"github.com/hajimehoshi/ebiten/v2/text/v2"
@@ -20,23 +22,54 @@ import (
 // FontSource holds the loaded font
 var FontSource *text.GoTextFaceSource
 
+// MonoFontSource holds the font monospace text is drawn with
+var MonoFontSource *text.GoTextFaceSource
+
 // SetFontSource sets the font source for text rendering
 func SetFontSource(src *text.GoTextFaceSource) {
 	FontSource = src
 }
 
+// SetMonoFontSource sets the font source for monospace text
+func SetMonoFontSource(src *text.GoTextFaceSource) {
+	MonoFontSource = src
+}
+
+// fontSource returns the font text is drawn with; monospace text falls back
+// to the regular font when no monospace font was loaded
+func fontSource(mono bool) *text.GoTextFaceSource {
+	if mono && MonoFontSource != nil {
+		return MonoFontSource
+	}
+	return FontSource
+}
+
 // DrawRoundedRect draws a filled rectangle
 func DrawRoundedRect(screen *ebiten.Image, x, y, w, h, radius float32, clr color.Color) {
 	vector.DrawFilledRect(screen, x, y, w, h, clr, false)
 }
 
+// Fade returns c drawn at opacity, from 0 (invisible) to 1 (unchanged).
+// Colors are premultiplied, so every channel scales.
+func Fade(c color.RGBA, opacity float64) color.RGBA {
+	if opacity >= 1 {
+		return c
+	}
+	scale := func(v uint8) uint8 { return uint8(float64(v)*max(opacity, 0) + 0.5) }
+	return color.RGBA{scale(c.R), scale(c.G), scale(c.B), scale(c.A)}
+}
+
 // DrawText draws text at the specified position
 func DrawText(screen *ebiten.Image, txt string, x, y float64, size float64, clr color.Color) {
-	if FontSource == nil {
+	drawText(screen, FontSource, txt, x, y, size, clr)
+}
+
+func drawText(screen *ebiten.Image, src *text.GoTextFaceSource, txt string, x, y float64, size float64, clr color.Color) {
+	if src == nil {
 		return
 	}
 	face := &text.GoTextFace{
-		Source: FontSource,
+		Source: src,
 		Size:   size,
 	}
 	op := &text.DrawOptions{}
@@ -45,6 +78,109 @@ func DrawText(screen *ebiten.Image, txt string, x, y float64, size float64, clr
 	text.Draw(screen, txt, face, op)
 }
 
+// textMetrics returns the ascent and x-height of a font at size
+func textMetrics(src *text.GoTextFaceSource, size float64) (ascent, xHeight float64) {
+	if src == nil {
+		return size * 0.9, size * 0.5
+	}
+	m := (&text.GoTextFace{Source: src, Size: size}).Metrics()
+	return m.HAscent, m.XHeight
+}
+
+// DrawTextAtBaseline draws text whose baseline is at y
+func DrawTextAtBaseline(screen *ebiten.Image, txt string, x, baseline float64, size float64, clr color.Color) {
+	ascent, _ := textMetrics(FontSource, size)
+	DrawText(screen, txt, x, baseline-ascent, size, clr)
+}
+
+// DrawSpacedTextAtBaseline draws text whose baseline is at y, in the
+// monospace font when mono is set, with letterSpacing added after every
+// character and wordSpacing after every space, placing the characters one
+// by one when either is set
+func DrawSpacedTextAtBaseline(screen *ebiten.Image, txt string, x, baseline, size, letterSpacing, wordSpacing float64, mono bool, clr color.Color) {
+	src := fontSource(mono)
+	if src == nil {
+		return
+	}
+	ascent, _ := textMetrics(src, size)
+	if letterSpacing == 0 && wordSpacing == 0 {
+		drawText(screen, src, txt, x, baseline-ascent, size, clr)
+		return
+	}
+	face := &text.GoTextFace{Source: src, Size: size}
+	for _, r := range txt {
+		ch := string(r)
+		drawText(screen, src, ch, x, baseline-ascent, size, clr)
+		w, _ := text.Measure(ch, face, 0)
+		x += w + letterSpacing
+		if r == ' ' {
+			x += wordSpacing
+		}
+	}
+}
+
+// DrawTextDecoration draws text-decoration lines for text of the given size
+// and width whose baseline is at y. lines holds any of underline,
+// line-through and overline; style is solid, double, dotted, dashed or wavy.
+// thickness is the lines' width, 0 to scale it to the font size.
+func DrawTextDecoration(screen *ebiten.Image, lines, style string, x, baseline, w, size, thickness float64, clr color.Color) {
+	if lines == "" || w <= 0 {
+		return
+	}
+	ascent, xHeight := textMetrics(FontSource, size)
+	if thickness <= 0 {
+		thickness = math.Max(1, math.Round(size/14))
+	}
+	y := baseline - ascent
+
+	for _, line := range strings.Fields(lines) {
+		var ly float64
+		switch line {
+		case "underline":
+			ly = baseline + thickness*1.5
+		case "line-through":
+			ly = baseline - xHeight/2
+		case "overline":
+			ly = y
+		default:
+			continue
+		}
+		drawDecorationLine(screen, style, x, ly, w, thickness, clr)
+		if style == "double" {
+			drawDecorationLine(screen, style, x, ly+thickness*2, w, thickness, clr)
+		}
+	}
+}
+
+// drawDecorationLine draws one horizontal decoration line, broken into dots or
+// dashes, or waving about y, for those styles
+func drawDecorationLine(screen *ebiten.Image, style string, x, y, w, thickness float64, clr color.Color) {
+	if style == "wavy" {
+		// A sine wave whose height and wavelength grow with the thickness
+		amp, wavelength := thickness*1.5, math.Max(4, thickness*6)
+		step := wavelength / 8
+		wave := func(dx float64) float32 {
+			return float32(y + thickness/2 + amp*math.Sin(2*math.Pi*dx/wavelength))
+		}
+		for dx := 0.0; dx < w; dx += step {
+			next := math.Min(dx+step, w)
+			vector.StrokeLine(screen, float32(x+dx), wave(dx), float32(x+next), wave(next), float32(thickness), clr, true)
+		}
+		return
+	}
+	dash, gap := w, 0.0
+	switch style {
+	case "dotted":
+		dash, gap = thickness, thickness*2
+	case "dashed":
+		dash, gap = thickness*3, thickness*2
+	}
+	for dx := 0.0; dx < w; dx += dash + gap {
+		seg := math.Min(dash, w-dx)
+		vector.DrawFilledRect(screen, float32(x+dx), float32(y), float32(seg), float32(thickness), clr, false)
+	}
+}
+
 // DrawTextCentered draws text centered at the specified position
 func DrawTextCentered(screen *ebiten.Image, txt string, x, y float64, size float64, clr color.Color) {
 	if FontSource == nil {
@@ -62,6 +198,43 @@ func DrawTextCentered(screen *ebiten.Image, txt string, x, y float64, size float
 	text.Draw(screen, txt, face, op)
 }
 
+// DrawSpinner draws a loading spinner of radius r centered at (cx, cy): a ring
+// of dots whose brightest dot advances with frame
+func DrawSpinner(screen *ebiten.Image, cx, cy, r float32, frame int, clr color.RGBA) {
+	const dots = 8
+	head := (frame / 6) % dots
+	for i := 0; i < dots; i++ {
+		angle := float64(i)*2*math.Pi/dots - math.Pi/2
+		x := cx + r*float32(math.Cos(angle))
+		y := cy + r*float32(math.Sin(angle))
+
+		// Dots fade out behind the head; scale every channel (premultiplied alpha)
+		fade := float64(dots-(head-i+dots)%dots) / dots
+		c := color.RGBA{
+			R: uint8(float64(clr.R) * fade),
+			G: uint8(float64(clr.G) * fade),
+			B: uint8(float64(clr.B) * fade),
+			A: uint8(float64(clr.A) * fade),
+		}
+		vector.DrawFilledCircle(screen, x, y, r/4, c, true)
+	}
+}
+
+// TruncateText shortens txt with an ellipsis so it fits within maxWidth
+func TruncateText(txt string, maxWidth, size float64) string {
+	if MeasureText(txt, size) <= maxWidth {
+		return txt
+	}
+	runes := []rune(txt)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		if MeasureText(string(runes)+"…", size) <= maxWidth {
+			break
+		}
+	}
+	return string(runes) + "…"
+}
+
 // MeasureText returns the width of text at a given font size
 func MeasureText(txt string, size float64) float64 {
 	if FontSource == nil {
@@ -77,29 +250,65 @@ func MeasureText(txt string, size float64) float64 {
 
 // ======================================================================================
 // IMAGE CACHE
+// Decoded images are kept up to a budget of bytes, four per pixel of every
+// frame. Past it, the images drawn least recently are dropped; one still on
+// a page loads again when it is next drawn.
 // ======================================================================================
 
+// DefaultImageCacheBudget is the bytes of images the cache keeps
+const DefaultImageCacheBudget = 256 << 20
+
 // ImageCache stores loaded images
 type ImageCache struct {
-	images  map[string]*ebiten.Image
+	budget  int64 // bytes of images kept; 0 keeps every image
+	entries map[string]*list.Element
+	recent  *list.List // cachedImages, drawn most recently first
+	bytes   int64
 	loading map[string]bool
 	failed  map[string]bool
-	mutex   sync.RWMutex
+	mutex   sync.Mutex
+}
+
+// cachedImage is a decoded image in the cache: a still image or an animation
+type cachedImage struct {
+	url   string
+	img   *ebiten.Image
+	anim  *Animation
+	bytes int64
 }
 
-// Cache is the global image cache
-var Cache = &ImageCache{
-	images:  make(map[string]*ebiten.Image),
-	loading: make(map[string]bool),
-	failed:  make(map[string]bool),
+// ImageCacheStats is what the image cache holds
+type ImageCacheStats struct {
+	Images int   // decoded images kept
+	Bytes  int64 // their size
+	Budget int64
+	Failed int // images that failed to load
 }
 
-// Get returns a cached image and its loading/failed status
+// NewImageCache creates an empty cache keeping budget bytes of images; 0
+// keeps every image
+func NewImageCache(budget int64) *ImageCache {
+	return &ImageCache{
+		budget:  budget,
+		entries: make(map[string]*list.Element),
+		recent:  list.New(),
+		loading: make(map[string]bool),
+		failed:  make(map[string]bool),
+	}
+}
+
+// Get returns a cached image and its loading/failed status. For an animated
+// image it is the frame showing now, so drawing it every tick plays it.
 func (c *ImageCache) Get(imgURL string) (*ebiten.Image, bool, bool) {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
-	if img, ok := c.images[imgURL]; ok {
-		return img, true, false
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if el, ok := c.entries[imgURL]; ok {
+		c.recent.MoveToFront(el)
+		entry := el.Value.(*cachedImage)
+		if entry.anim != nil {
+			return entry.anim.Frame(time.Now()), true, false
+		}
+		return entry.img, true, false
 	}
 	if c.failed[imgURL] {
 		return nil, false, true
@@ -111,7 +320,7 @@ func (c *ImageCache) Get(imgURL string) (*ebiten.Image, bool, bool) {
 func (c *ImageCache) StartLoading(imgURL string) bool {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
-	if c.loading[imgURL] || c.images[imgURL] != nil || c.failed[imgURL] {
+	if c.loading[imgURL] || c.entries[imgURL] != nil || c.failed[imgURL] {
 		return false
 	}
 	c.loading[imgURL] = true
@@ -120,9 +329,73 @@ func (c *ImageCache) StartLoading(imgURL string) bool {
 
 // SetImage stores a loaded image in the cache
 func (c *ImageCache) SetImage(imgURL string, img *ebiten.Image) {
+	c.store(&cachedImage{url: imgURL, img: img, bytes: imageBytes(img)})
+}
+
+// SetAnimation stores a loaded animated image in the cache
+func (c *ImageCache) SetAnimation(imgURL string, anim *Animation) {
+	entry := &cachedImage{url: imgURL, anim: anim}
+	for _, frame := range anim.frames {
+		entry.bytes += imageBytes(frame)
+	}
+	c.store(entry)
+}
+
+// store adds a decoded image as the most recently drawn
+func (c *ImageCache) store(entry *cachedImage) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.loading, entry.url)
+	if el, ok := c.entries[entry.url]; ok {
+		c.remove(el)
+	}
+	c.entries[entry.url] = c.recent.PushFront(entry)
+	c.bytes += entry.bytes
+	c.evict()
+}
+
+// evict drops the least recently drawn images while the cache is over
+// budget, keeping the newest
+func (c *ImageCache) evict() {
+	for c.budget > 0 && c.bytes > c.budget && c.recent.Len() > 1 {
+		c.remove(c.recent.Back())
+	}
+}
+
+// remove drops an image from the cache
+func (c *ImageCache) remove(el *list.Element) {
+	entry := c.recent.Remove(el).(*cachedImage)
+	delete(c.entries, entry.url)
+	c.bytes -= entry.bytes
+}
+
+// imageBytes is the memory a decoded image takes
+func imageBytes(img *ebiten.Image) int64 {
+	b := img.Bounds()
+	return int64(b.Dx()) * int64(b.Dy()) * 4
+}
+
+// Stats reports what the cache holds
+func (c *ImageCache) Stats() ImageCacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return ImageCacheStats{Images: len(c.entries), Bytes: c.bytes, Budget: c.budget, Failed: len(c.failed)}
+}
+
+// SetBudget changes the bytes of images the cache keeps, dropping images
+// past it
+func (c *ImageCache) SetBudget(budget int64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.budget = budget
+	c.evict()
+}
+
+// abandon forgets an image whose load was canceled, so it can be asked for
+// again
+func (c *ImageCache) abandon(imgURL string) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
-	c.images[imgURL] = img
 	delete(c.loading, imgURL)
 }
 
@@ -134,42 +407,39 @@ func (c *ImageCache) SetFailed(imgURL string) {
 	delete(c.loading, imgURL)
 }
 
-// CurrentBaseURL tracks the current page URL for relative image resolution
-var CurrentBaseURL string
-
-// LoadImageAsync loads an image asynchronously
-func LoadImageAsync(imgURL string, baseURL string) {
-	if !Cache.StartLoading(imgURL) {
+// DrawBackgroundImage paints img inside the rectangle x, y, w, h from its
+// top-left corner, tiling it along the axes where repeatX / repeatY are set,
+// at opacity
+func DrawBackgroundImage(screen, img *ebiten.Image, x, y, w, h float64, repeatX, repeatY bool, opacity float64) {
+	iw, ih := float64(img.Bounds().Dx()), float64(img.Bounds().Dy())
+	clipRect := image.Rect(int(x), int(y), int(math.Ceil(x+w)), int(math.Ceil(y+h))).Intersect(screen.Bounds())
+	if iw == 0 || ih == 0 || clipRect.Empty() {
 		return
 	}
+	clip := screen.SubImage(clipRect).(*ebiten.Image)
 
-	go func() {
-		// Resolve relative URLs
-		fullURL := imgURL
-		if !strings.HasPrefix(imgURL, "http") && baseURL != "" {
-			if base, err := url.Parse(baseURL); err == nil {
-				if ref, err := url.Parse(imgURL); err == nil {
-					fullURL = base.ResolveReference(ref).String()
-				}
+	// Start at the first tile that reaches the visible area
+	startX, startY := x, y
+	if repeatX && float64(clipRect.Min.X) > x {
+		startX += math.Floor((float64(clipRect.Min.X)-x)/iw) * iw
+	}
+	if repeatY && float64(clipRect.Min.Y) > y {
+		startY += math.Floor((float64(clipRect.Min.Y)-y)/ih) * ih
+	}
+	for ty := startY; ty < float64(clipRect.Max.Y); ty += ih {
+		for tx := startX; tx < float64(clipRect.Max.X); tx += iw {
+			op := &ebiten.DrawImageOptions{}
+			op.GeoM.Translate(tx, ty)
+			op.ColorScale.ScaleAlpha(float32(opacity))
+			clip.DrawImage(img, op)
+			if !repeatX {
+				break
 			}
 		}
-
-		resp, err := http.Get(fullURL)
-		if err != nil {
-			Cache.SetFailed(imgURL)
-			return
+		if !repeatY {
+			break
 		}
-		defer resp.Body.Close()
-
-		img, _, err := image.Decode(resp.Body)
-		if err != nil {
-			Cache.SetFailed(imgURL)
-			return
-		}
-
-		ebitenImg := ebiten.NewImageFromImage(img)
-		Cache.SetImage(imgURL, ebitenImg)
-	}()
+	}
 }
 
 // ======================================================================================
@@ -182,57 +452,150 @@ type GradientStop struct {
 	Position   float64
 }
 
-// DrawLinearGradient draws a linear gradient on the screen
-func DrawLinearGradient(screen *ebiten.Image, x, y, w, h float32, angle float64, stops []GradientStop) {
-	if len(stops) < 2 {
+// GradientShape says how a gradient's colors run across its box
+type GradientShape struct {
+	Radial    bool
+	Repeating bool    // the stops tile past the last one
+	Angle     float64 // linear: degrees, 0 = to top, 90 = to right
+
+	// Radial: a circle or an ellipse centered at CenterX, CenterY (fractions
+	// of the box) whose edge is set by Extent, e.g. "farthest-corner"
+	Circle           bool
+	Extent           string
+	CenterX, CenterY float64
+}
+
+// gradientKey identifies a painted gradient image in gradientCache
+type gradientKey struct {
+	w, h    int
+	shape   GradientShape
+	stops   string
+	opacity float64
+}
+
+// gradientCache keeps painted gradients so unchanged boxes aren't repainted
+// pixel by pixel every frame
+var (
+	gradientCache   = map[gradientKey]*ebiten.Image{}
+	gradientCacheMu sync.Mutex
+)
+
+// maxCachedGradients bounds gradientCache; it is emptied when full
+const maxCachedGradients = 64
+
+// DrawGradient fills the box at x, y of size w × h with a gradient at opacity
+func DrawGradient(screen *ebiten.Image, x, y, w, h float64, shape GradientShape, stops []GradientStop, opacity float64) {
+	iw, ih := int(math.Ceil(w)), int(math.Ceil(h))
+	if len(stops) < 2 || iw <= 0 || ih <= 0 || opacity <= 0 {
 		return
 	}
 
-	// Create a temporary image for the gradient
-	gradImg := ebiten.NewImage(int(w), int(h))
-
-	// For simplicity, we'll render horizontal or vertical gradients
-	// Convert angle to radians and determine direction
-	// 0deg = to top, 90deg = to right, 180deg = to bottom, 270deg = to left
-
-	for py := 0; py < int(h); py++ {
-		for px := 0; px < int(w); px++ {
-			// Calculate position along gradient axis (0.0 to 1.0)
-			var t float64
-			switch {
-			case angle == 0 || angle == 360:
-				// To top
-				t = 1.0 - float64(py)/float64(h)
-			case angle == 90:
-				// To right
-				t = float64(px) / float64(w)
-			case angle == 180:
-				// To bottom
-				t = float64(py) / float64(h)
-			case angle == 270:
-				// To left
-				t = 1.0 - float64(px)/float64(w)
-			case angle == 135:
-				// To bottom-right (diagonal)
-				t = (float64(px)/float64(w) + float64(py)/float64(h)) / 2.0
-			case angle == 315:
-				// To top-left (diagonal)
-				t = 1.0 - (float64(px)/float64(w)+float64(py)/float64(h))/2.0
-			default:
-				// Default: treat as to bottom
-				t = float64(py) / float64(h)
+	key := gradientKey{iw, ih, shape, fmt.Sprint(stops), opacity}
+	gradientCacheMu.Lock()
+	img := gradientCache[key]
+	if img == nil {
+		if len(gradientCache) >= maxCachedGradients {
+			for k, cached := range gradientCache {
+				cached.Deallocate()
+				delete(gradientCache, k)
 			}
+		}
+		img = paintGradient(iw, ih, shape, stops, opacity)
+		gradientCache[key] = img
+	}
+	gradientCacheMu.Unlock()
+
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(x, y)
+	screen.DrawImage(img, op)
+}
+
+// paintGradient renders a gradient into a new w × h image
+func paintGradient(w, h int, shape GradientShape, stops []GradientStop, opacity float64) *ebiten.Image {
+	position := linearPosition(w, h, shape.Angle)
+	if shape.Radial {
+		position = radialPosition(w, h, shape)
+	}
 
-			// Interpolate color
+	first, last := stops[0].Position, stops[len(stops)-1].Position
+	pixels := make([]byte, 4*w*h)
+	for py := 0; py < h; py++ {
+		for px := 0; px < w; px++ {
+			t := position(float64(px)+0.5, float64(py)+0.5)
+			if shape.Repeating && last > first {
+				t = first + math.Mod(t-first, last-first)
+				if t < first {
+					t += last - first
+				}
+			}
+			// ebiten images hold premultiplied alpha
 			c := interpolateColor(stops, t)
-			gradImg.Set(px, py, c)
+			a := float64(c.A) / 255 * opacity
+			i := 4 * (py*w + px)
+			pixels[i] = uint8(float64(c.R)*a + 0.5)
+			pixels[i+1] = uint8(float64(c.G)*a + 0.5)
+			pixels[i+2] = uint8(float64(c.B)*a + 0.5)
+			pixels[i+3] = uint8(255*a + 0.5)
 		}
 	}
+	img := ebiten.NewImage(w, h)
+	img.WritePixels(pixels)
+	return img
+}
 
-	// Draw the gradient image onto the screen
-	op := &ebiten.DrawImageOptions{}
-	op.GeoM.Translate(float64(x), float64(y))
-	screen.DrawImage(gradImg, op)
+// linearPosition returns where a point falls along a linear gradient's line,
+// 0 at its start and 1 at its end. The line runs through the center at
+// angle and is long enough for the corners to get the end colors.
+func linearPosition(w, h int, angle float64) func(x, y float64) float64 {
+	rad := angle * math.Pi / 180
+	dx, dy := math.Sin(rad), -math.Cos(rad)
+	length := math.Abs(float64(w)*dx) + math.Abs(float64(h)*dy)
+	cx, cy := float64(w)/2, float64(h)/2
+	return func(x, y float64) float64 {
+		if length == 0 {
+			return 0
+		}
+		return ((x-cx)*dx+(y-cy)*dy)/length + 0.5
+	}
+}
+
+// radialPosition returns how far a point is from a radial gradient's center,
+// 0 at the center and 1 on its ending shape
+func radialPosition(w, h int, shape GradientShape) func(x, y float64) float64 {
+	fw, fh := float64(w), float64(h)
+	cx, cy := shape.CenterX*fw, shape.CenterY*fh
+	near := func(a, b float64) float64 { return math.Min(math.Abs(a), math.Abs(b)) }
+	far := func(a, b float64) float64 { return math.Max(math.Abs(a), math.Abs(b)) }
+
+	// Distances to the nearest or farthest side along each axis
+	sx, sy := far(cx, fw-cx), far(cy, fh-cy)
+	if shape.Extent == "closest-side" || shape.Extent == "closest-corner" {
+		sx, sy = near(cx, fw-cx), near(cy, fh-cy)
+	}
+
+	var rx, ry float64
+	switch {
+	case shape.Circle && strings.HasSuffix(shape.Extent, "side"):
+		rx = math.Min(sx, sy)
+		if shape.Extent == "farthest-side" {
+			rx = math.Max(sx, sy)
+		}
+		ry = rx
+	case shape.Circle:
+		rx = math.Hypot(sx, sy)
+		ry = rx
+	case strings.HasSuffix(shape.Extent, "side"):
+		rx, ry = sx, sy
+	default:
+		// An ellipse through the corner keeps the sides' aspect ratio
+		rx, ry = sx*math.Sqrt2, sy*math.Sqrt2
+	}
+	return func(x, y float64) float64 {
+		if rx <= 0 || ry <= 0 {
+			return 1
+		}
+		return math.Hypot((x-cx)/rx, (y-cy)/ry)
+	}
 }
 
 // interpolateColor finds the right color for position t (0.0 to 1.0)
@@ -0,0 +1,182 @@
+package render
+
+import (
+	"context"
+	"errors"
+	"image"
+	"io"
+	"math"
+	"net/http"
+	"sync"
+
+	"go-browser/logging"
+
+	"github.com/hajimehoshi/ebiten/v2"
+)
+
+// ======================================================================================
+// IMAGE LOADING
+// A browser's images download through its queue, a few at a time. Each
+// request says how far its image is from the viewport, and the nearest
+// waiting image starts next, so what is on screen comes first. Leaving a page
+// cancels its images still queued or downloading.
+// ======================================================================================
+
+// DefaultImageDownloads is how many images download at once unless Images
+// says otherwise
+const DefaultImageDownloads = 6
+
+// imageRequest is an image waiting in the queue or downloading
+type imageRequest struct {
+	url      string
+	pageURL  string
+	distance float64            // from the viewport, in CSS pixels; 0 on screen
+	cancel   context.CancelFunc // set once it downloads
+}
+
+// Images loads the images of one browser's pages through its HTTP client
+// into Cache. Each browser has its own queue, so one never waits on
+// another's downloads.
+type Images struct {
+	Cache     *ImageCache
+	Downloads int // images downloading at once
+	// Allowed, when set, decides whether images load on the page at the
+	// given URL; a blocked image is skipped without being marked failed
+	Allowed func(pageURL string) bool
+	client  *http.Client
+
+	mu      sync.Mutex // guards the queue
+	waiting map[string]*imageRequest
+	active  map[string]*imageRequest
+}
+
+// NewImages creates an image loader that downloads through client into
+// cache
+func NewImages(client *http.Client, cache *ImageCache) *Images {
+	return &Images{
+		Cache:     cache,
+		Downloads: DefaultImageDownloads,
+		client:    client,
+		waiting:   make(map[string]*imageRequest),
+		active:    make(map[string]*imageRequest),
+	}
+}
+
+// LoadImageAsync starts loading the image at an absolute URL for the page at
+// pageURL, unless images are blocked there, ahead of any off screen
+func (l *Images) LoadImageAsync(imgURL, pageURL string) {
+	l.RequestImage(imgURL, pageURL, 0)
+}
+
+// RequestImage queues the image at an absolute URL for the page at pageURL,
+// distance CSS pixels from the viewport. Asking again for an image still
+// waiting moves it to its new distance.
+func (l *Images) RequestImage(imgURL, pageURL string, distance float64) {
+	if l.Allowed != nil && !l.Allowed(pageURL) {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if req, ok := l.waiting[imgURL]; ok {
+		req.distance = distance
+		return
+	}
+	if !l.Cache.StartLoading(imgURL) {
+		return
+	}
+	l.waiting[imgURL] = &imageRequest{url: imgURL, pageURL: pageURL, distance: distance}
+	l.startDownloads()
+}
+
+// CancelImages drops the images queued for the page at pageURL and stops
+// those downloading
+func (l *Images) CancelImages(pageURL string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for imgURL, req := range l.waiting {
+		if req.pageURL == pageURL {
+			delete(l.waiting, imgURL)
+			l.Cache.abandon(imgURL)
+		}
+	}
+	for _, req := range l.active {
+		if req.pageURL == pageURL {
+			req.cancel()
+		}
+	}
+}
+
+// CachedImage returns the image at an absolute URL once it has loaded,
+// starting the load for the page at pageURL the first time it is asked for
+func (l *Images) CachedImage(imgURL, pageURL string) *ebiten.Image {
+	img, _, failed := l.Cache.Get(imgURL)
+	if img == nil && !failed {
+		l.LoadImageAsync(imgURL, pageURL)
+	}
+	return img
+}
+
+// startDownloads starts the nearest waiting images while there is room.
+// The queue must be locked.
+func (l *Images) startDownloads() {
+	for len(l.active) < l.Downloads && len(l.waiting) > 0 {
+		var next *imageRequest
+		nearest := math.Inf(1)
+		for _, req := range l.waiting {
+			if req.distance < nearest {
+				next, nearest = req, req.distance
+			}
+		}
+		delete(l.waiting, next.url)
+		ctx, cancel := context.WithCancel(context.Background())
+		next.cancel = cancel
+		l.active[next.url] = next
+		go l.download(ctx, next)
+	}
+}
+
+// download fetches and decodes an image into the cache, then lets the next
+// one start
+func (l *Images) download(ctx context.Context, req *imageRequest) {
+	defer func() {
+		req.cancel()
+		l.mu.Lock()
+		delete(l.active, req.url)
+		l.startDownloads()
+		l.mu.Unlock()
+	}()
+
+	img, anim, err := fetchImage(ctx, l.client, req.url)
+	switch {
+	case ctx.Err() != nil:
+		l.Cache.abandon(req.url)
+	case err != nil:
+		if errors.Is(err, errAVIF) {
+			logging.Net.Info("cannot load image", "url", req.url, "err", err)
+		}
+		l.Cache.SetFailed(req.url)
+	case anim != nil:
+		l.Cache.SetAnimation(req.url, anim)
+	default:
+		l.Cache.SetImage(req.url, ebiten.NewImageFromImage(img))
+	}
+}
+
+// fetchImage downloads the image at imgURL through client and decodes it
+func fetchImage(ctx context.Context, client *http.Client, imgURL string) (image.Image, *Animation, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imgURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Accept", ImageAccept)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return decodeImage(data)
+}
@@ -0,0 +1,93 @@
+package spidergopher
+
+import (
+	realdom "go-browser/dom"
+	"go-browser/logging"
+	"go-browser/spidergopher/dom"
+	"go-browser/spidergopher/webapi"
+
+	"github.com/dop251/goja"
+)
+
+// ======================================================================================
+// USER ACTIVATION
+// APIs a page could abuse without the user's say (popups, clipboard reads,
+// fullscreen, audible autoplay) only work shortly after a click or key press,
+// which the browser reports through Activate
+// ======================================================================================
+
+// Activate gives the page transient activation; the browser calls it for
+// clicks and key presses the page receives
+func (e *Engine) Activate() {
+	e.Activation.Activate()
+}
+
+// DispatchClick fires click at node on the page's event loop; the browser
+// calls it for clicks the page receives
+func (e *Engine) DispatchClick(node *realdom.Node) {
+	e.Loop.Schedule(func() { dom.DispatchClickEvent(node, e.vm) })
+}
+
+// OnWindowOpen registers the browser's handler for popups window.open is
+// allowed to open. url is absolute.
+func (e *Engine) OnWindowOpen(fn func(url string)) {
+	e.openWindow = fn
+}
+
+// OnClipboardRead registers how navigator.clipboard.readText reads the
+// system clipboard
+func (e *Engine) OnClipboardRead(fn func() string) {
+	e.readClipboard = fn
+}
+
+// windowOpen implements window.open(url). Without transient activation the
+// popup is blocked and null returned, as browsers do; an allowed popup uses
+// the activation up.
+func (e *Engine) windowOpen(call goja.FunctionCall) goja.Value {
+	url := "about:blank"
+	if arg := call.Argument(0); !goja.IsUndefined(arg) && arg.String() != "" {
+		url = arg.String()
+	}
+	if e.doc != nil {
+		url = ResolveURL(url, e.doc.BaseURL)
+	}
+
+	if !e.Activation.Consume() {
+		logging.JS.Info("blocked popup: not opened by a user gesture", "url", url)
+		return goja.Null()
+	}
+	if e.openWindow != nil {
+		e.openWindow(url)
+	}
+	// Stands in for the new window so scripts see the popup was not blocked
+	win := e.vm.NewObject()
+	win.Set("closed", false)
+	win.Set("close", func() {})
+	return win
+}
+
+// clipboardReadText implements navigator.clipboard.readText(), which
+// rejects with NotAllowedError unless the page has transient activation
+func (e *Engine) clipboardReadText() *goja.Promise {
+	promise, resolve, reject := e.vm.NewPromise()
+	switch {
+	case !e.Activation.IsActive():
+		reject(webapi.DOMException(e.vm, "NotAllowedError", "Reading the clipboard requires a user gesture"))
+	case e.readClipboard == nil:
+		reject(webapi.DOMException(e.vm, "NotAllowedError", "The clipboard is not available"))
+	default:
+		resolve(e.readClipboard())
+	}
+	return promise
+}
+
+// navigatorObject creates navigator with its user activation and clipboard
+func (e *Engine) navigatorObject() *goja.Object {
+	navigator := e.vm.NewObject()
+	navigator.Set("userActivation", e.Activation.Object(e.vm))
+
+	clipboard := e.vm.NewObject()
+	clipboard.Set("readText", e.clipboardReadText)
+	navigator.Set("clipboard", clipboard)
+	return navigator
+}
@@ -0,0 +1,287 @@
+package spidergopher
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+
+	realdom "go-browser/dom"
+
+	"github.com/dop251/goja"
+)
+
+// ======================================================================================
+// API MANIFEST
+// The scripting API is described by walking what a fresh engine registers:
+// the globals, the objects reachable from them (console, navigator, document,
+// ...) and the members of nodes. Signatures come from
+// apiSignatures, or from the Go types of functions bound by reflection.
+// ======================================================================================
+
+// APIManifest lists the web APIs the engine exposes to scripts
+type APIManifest struct {
+	Interfaces []APIInterface `json:"interfaces"`
+}
+
+// APIInterface is a global object, or the members shared by nodes
+type APIInterface struct {
+	Name    string      `json:"name"` // "global", a path such as "navigator.clipboard", or "Element"
+	Members []APIMember `json:"members"`
+}
+
+// APIMember is one method or property of an interface
+type APIMember struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`                // method or property
+	Type      string `json:"type,omitempty"`      // a property's type
+	Signature string `json:"signature,omitempty"` // a method's arguments and result
+}
+
+// apiSignatures documents the methods whose arguments reflection can't see,
+// keyed by interface and member name
+var apiSignatures = map[string]string{
+	"global.setTimeout":          "(handler, timeout, ...args): number",
+	"global.clearTimeout":        "(id)",
+	"global.setInterval":         "(handler, timeout, ...args): number",
+	"global.clearInterval":       "(id)",
+	"global.queueMicrotask":      "(callback)",
+	"global.importScripts":       "(...urls)",
+	"global.addEventListener":    "(type, listener)",
+	"global.removeEventListener": "(type, listener)",
+	"global.dispatchEvent":       "(event): boolean",
+	"global.open":                "(url): Window | null",
+	"global.matchMedia":          "(query): MediaQueryList",
+	"global.fetch":               "(input, init): Promise<Response>",
+	"global.Worker":              "new (scriptURL): Worker",
+	"global.Headers":             "new (init): Headers",
+	"global.URLSearchParams":     "new (init): URLSearchParams",
+	"global.FormData":            "new (): FormData",
+	"global.AbortController":     "new (): AbortController",
+
+	"console.log":            "(...data)",
+	"console.info":           "(...data)",
+	"console.debug":          "(...data)",
+	"console.warn":           "(...data)",
+	"console.error":          "(...data)",
+	"console.trace":          "(...data)",
+	"console.assert":         "(condition, ...data)",
+	"console.group":          "(...data)",
+	"console.groupCollapsed": "(...data)",
+	"console.groupEnd":       "()",
+	"console.time":           "(label)",
+	"console.timeLog":        "(label, ...data)",
+	"console.timeEnd":        "(label)",
+	"console.table":          "(tabularData, properties)",
+
+	"navigator.clipboard.readText": "(): Promise<string>",
+
+	"document.getElementById":         "(id): Element | null",
+	"document.getElementsByClassName": "(classNames): Element[]",
+	"document.getElementsByTagName":   "(qualifiedName): Element[]",
+	"document.querySelector":          "(selectors): Element | null",
+	"document.querySelectorAll":       "(selectors): Element[]",
+	"document.createElement":          "(tagName): Element",
+	"document.createTextNode":         "(data): Element",
+	"document.createDocumentFragment": "(): DocumentFragment",
+	"document.addEventListener":       "(type, listener)",
+	"document.removeEventListener":    "(type, listener)",
+	"document.dispatchEvent":          "(event): boolean",
+	"document.exitFullscreen":         "(): Promise<void>",
+	"document.hasFocus":               "(): boolean",
+
+	"Element.getAttribute":          "(qualifiedName): string | null",
+	"Element.setAttribute":          "(qualifiedName, value)",
+	"Element.removeAttribute":       "(qualifiedName)",
+	"Element.appendChild":           "(node): Element",
+	"Element.removeChild":           "(child): Element",
+	"Element.remove":                "()",
+	"Element.before":                "(...nodes)",
+	"Element.after":                 "(...nodes)",
+	"Element.replaceWith":           "(...nodes)",
+	"Element.append":                "(...nodes)",
+	"Element.prepend":               "(...nodes)",
+	"Element.insertAdjacentHTML":    "(position, string)",
+	"Element.insertAdjacentElement": "(where, element): Element | null",
+	"Element.insertAdjacentText":    "(where, data)",
+	"Element.matches":               "(selectors): boolean",
+	"Element.closest":               "(selectors): Element | null",
+	"Element.addEventListener":      "(type, listener)",
+	"Element.click":                 "()",
+	"Element.requestFullscreen":     "(): Promise<void>",
+	"Element.querySelector":         "(selectors): Element | null",
+	"Element.querySelectorAll":      "(selectors): Element[]",
+
+	"Element.classList.contains": "(token): boolean",
+	"Element.classList.add":      "(...tokens)",
+	"Element.classList.remove":   "(...tokens)",
+	"Element.classList.toggle":   "(token, force): boolean",
+
+	"HTMLMediaElement.play": "(): Promise<void>",
+
+	"HTMLCanvasElement.getContext": "(contextId): CanvasRenderingContext2D | null",
+
+	"HTMLDialogElement.close": "(returnValue)",
+}
+
+// GenerateAPIManifest describes the API of an engine attached to an empty
+// document
+func GenerateAPIManifest() *APIManifest {
+	e := NewEngine()
+	defer e.Stop()
+	e.SetDocument(realdom.ParseDocument("<html><head></head><body></body></html>"))
+	return e.apiManifest()
+}
+
+// apiWalker collects interfaces, naming each object after where it was
+// first reached so that aliases (self, window.document) point back to it
+type apiWalker struct {
+	vm      *goja.Runtime
+	names   map[*goja.Object]string
+	pending []*goja.Object
+	out     []APIInterface
+}
+
+// apiManifest walks the engine's globals and the node interfaces
+func (e *Engine) apiManifest() *APIManifest {
+	w := &apiWalker{vm: e.vm, names: make(map[*goja.Object]string)}
+	w.visit("global", e.vm.GlobalObject())
+
+	// Elements and text nodes are the same kind of object; one shows them
+	element, err := e.vm.RunString("document.createElement('div')")
+	if err != nil {
+		return &APIManifest{Interfaces: w.out}
+	}
+	w.visit("Element", element.ToObject(e.vm))
+
+	// Media, canvas, dialog and details elements add members to those of
+	// every element
+	for _, special := range []struct{ name, tag string }{
+		{"HTMLMediaElement", "video"},
+		{"HTMLCanvasElement", "canvas"},
+		{"HTMLDialogElement", "dialog"},
+		{"HTMLDetailsElement", "details"},
+	} {
+		v, err := e.vm.RunString("document.createElement('" + special.tag + "')")
+		if err != nil {
+			continue
+		}
+		iface := w.describe(special.name, v.ToObject(e.vm))
+		iface.Members = slices.DeleteFunc(iface.Members, func(m APIMember) bool {
+			return element.ToObject(e.vm).Get(m.Name) != nil
+		})
+		w.out = append(w.out, iface)
+	}
+	return &APIManifest{Interfaces: w.out}
+}
+
+// visit describes obj as the interface name, then the plain objects it
+// leads to
+func (w *apiWalker) visit(name string, obj *goja.Object) {
+	w.names[obj] = name
+	w.pending = append(w.pending, obj)
+	for len(w.pending) > 0 {
+		obj := w.pending[0]
+		w.pending = w.pending[1:]
+		w.out = append(w.out, w.describe(w.names[obj], obj))
+	}
+}
+
+// describe lists the members of obj, queueing the objects it holds
+func (w *apiWalker) describe(name string, obj *goja.Object) APIInterface {
+	iface := APIInterface{Name: name}
+	for _, key := range obj.Keys() {
+		value := obj.Get(key)
+		member := APIMember{Name: key, Kind: "property"}
+		if fn, ok := goja.AssertFunction(value); ok && fn != nil {
+			member.Kind = "method"
+			member.Signature = signatureOf(name+"."+key, value)
+		} else {
+			member.Type = w.typeOf(name, key, value)
+		}
+		iface.Members = append(iface.Members, member)
+	}
+	return iface
+}
+
+// typeOf names a property's type. Plain objects become interfaces of their
+// own, named by their path from the global scope.
+func (w *apiWalker) typeOf(parent, key string, value goja.Value) string {
+	switch {
+	case value == nil || goja.IsUndefined(value):
+		return "undefined"
+	case goja.IsNull(value):
+		return "null"
+	}
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		switch value.ExportType().Kind() {
+		case reflect.String:
+			return "string"
+		case reflect.Bool:
+			return "boolean"
+		}
+		return "number"
+	}
+	if name, seen := w.names[obj]; seen {
+		return name
+	}
+	switch {
+	case obj.ClassName() == "Array":
+		return "Array"
+	case obj.Get("nodeType") != nil:
+		return "Element"
+	}
+	name := key
+	if parent != "global" {
+		name = parent + "." + key
+	}
+	w.names[obj] = name
+	w.pending = append(w.pending, obj)
+	return name
+}
+
+// signatureOf returns a method's documented signature, or one derived from
+// the Go function it is bound to
+func signatureOf(name string, fn goja.Value) string {
+	if sig, ok := apiSignatures[name]; ok {
+		return sig
+	}
+	t := fn.ExportType()
+	if t == nil || t.Kind() != reflect.Func || t == reflect.TypeOf(func(goja.FunctionCall) goja.Value { return nil }) {
+		return "(...)"
+	}
+	args := make([]string, t.NumIn())
+	for i := range args {
+		args[i] = jsTypeName(t.In(i))
+	}
+	sig := "(" + strings.Join(args, ", ") + ")"
+	if t.NumOut() > 0 {
+		sig += ": " + jsTypeName(t.Out(0))
+	}
+	return sig
+}
+
+// jsTypeName names the JavaScript type a Go value converts to
+func jsTypeName(t reflect.Type) string {
+	switch t {
+	case reflect.TypeOf((*goja.Promise)(nil)):
+		return "Promise"
+	case reflect.TypeOf((*goja.Value)(nil)).Elem(), reflect.TypeOf((*goja.Object)(nil)):
+		return "any"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "Array"
+	case reflect.Func:
+		return "function"
+	}
+	return "object"
+}
@@ -16,20 +16,18 @@ func main() {
 	// NOTE: NOT calling engine.Start() to avoid async complexity
 
 	// Create DOM tree
-	html := realdom.NewElement("html")
-	body := realdom.NewElement("body")
-	html.AppendChild(body)
+	doc := realdom.NewDocument()
 
 	div := realdom.NewElement("div")
 	div.Attributes = map[string]string{"id": "test-div", "class": "container"}
-	body.AppendChild(div)
+	doc.Body.AppendChild(div)
 
 	p := realdom.NewElement("p")
 	p.AppendChild(realdom.NewText("Hello from DOM!"))
 	div.AppendChild(p)
 
 	// Connect to real DOM
-	engine.SetDOM(html)
+	engine.SetDocument(doc)
 
 	// Run synchronous script
 	_, err := engine.Run(`
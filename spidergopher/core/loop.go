@@ -1,7 +1,9 @@
 package core
 
 import (
+	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"github.com/dop251/goja"
 )
@@ -13,17 +15,33 @@ type Job func()
 type EventLoop struct {
 	jobQueue   chan Job
 	stopSignal chan struct{}
+	stopped    chan struct{} // closed once the loop's goroutine has returned
 	running    bool
 	mu         sync.Mutex
 	vm         *goja.Runtime
+
+	onError  func(error) // hears about errors jobs raise and don't handle
+	afterJob func()      // runs after every job
+
+	microtasks []Job         // microtasks queued while no script was running
+	then       goja.Callable // then() of an already resolved promise
+	resolved   goja.Value    // that promise
 }
 
 // NewEventLoop creates a new EventLoop attached to a Goja runtime.
 func NewEventLoop(vm *goja.Runtime) *EventLoop {
+	promise, resolve, _ := vm.NewPromise()
+	resolve(goja.Undefined())
+	resolved := vm.ToValue(promise)
+	then, _ := goja.AssertFunction(resolved.ToObject(vm).Get("then"))
+
 	return &EventLoop{
 		jobQueue:   make(chan Job, 100),
 		stopSignal: make(chan struct{}),
+		stopped:    make(chan struct{}),
 		vm:         vm,
+		then:       then,
+		resolved:   resolved,
 	}
 }
 
@@ -59,13 +77,39 @@ func (el *EventLoop) Schedule(job Job) {
 	select {
 	case el.jobQueue <- job:
 	default:
+		// The queue is full: wait for room, or drop the job if the loop
+		// stops first
+		stop := el.stopSignal
 		go func() {
-			el.jobQueue <- job
+			select {
+			case el.jobQueue <- job:
+			case <-stop:
+			}
 		}()
 	}
 }
 
+// SetErrorHandler registers fn to hear about the errors jobs raise and
+// don't handle themselves
+func (el *EventLoop) SetErrorHandler(fn func(error)) {
+	el.onError = fn
+}
+
+// SetAfterJob registers fn to run on the loop after every job
+func (el *EventLoop) SetAfterJob(fn func()) {
+	el.afterJob = fn
+}
+
+// ReportError hands err, raised by a job or a callback it ran, to the error
+// handler. Nil errors are ignored.
+func (el *EventLoop) ReportError(err error) {
+	if err != nil && el.onError != nil {
+		el.onError(err)
+	}
+}
+
 func (el *EventLoop) runLoop() {
+	defer close(el.stopped)
 	for {
 		select {
 		case job := <-el.jobQueue:
@@ -79,12 +123,72 @@ func (el *EventLoop) runLoop() {
 func (el *EventLoop) safeRun(job Job) {
 	defer func() {
 		if r := recover(); r != nil {
-			// In production, log this via a hooked logger
+			el.ReportError(panicError(r))
+		}
+		el.PerformMicrotaskCheckpoint()
+		if el.afterJob != nil {
+			el.afterJob()
+		}
+	}()
+	job()
+}
+
+// QueueMicrotask queues job to run once the current task or script is done,
+// before the next task, in order with the promise reactions queued around it.
+// Errors it raises are reported, not passed on.
+func (el *EventLoop) QueueMicrotask(job Job) {
+	if len(el.vm.CaptureCallStack(1, nil)) > 0 {
+		// A script is running: queue the job behind the promise reactions it
+		// queued so far, which the runtime runs as the script returns
+		task := func(goja.FunctionCall) goja.Value {
+			el.runMicrotask(job)
+			return goja.Undefined()
+		}
+		if _, err := el.then(el.resolved, el.vm.ToValue(task)); err != nil {
+			el.ReportError(err)
+		}
+		return
+	}
+	el.mu.Lock()
+	el.microtasks = append(el.microtasks, job)
+	el.mu.Unlock()
+}
+
+// PerformMicrotaskCheckpoint runs the microtasks queued while no script was
+// running, and those they queue in turn, until none is left. The loop
+// performs one after every job.
+func (el *EventLoop) PerformMicrotaskCheckpoint() {
+	for {
+		el.mu.Lock()
+		if len(el.microtasks) == 0 {
+			el.mu.Unlock()
+			return
+		}
+		job := el.microtasks[0]
+		el.microtasks = el.microtasks[1:]
+		el.mu.Unlock()
+		el.runMicrotask(job)
+	}
+}
+
+// runMicrotask runs a microtask, reporting what it panics with
+func (el *EventLoop) runMicrotask(job Job) {
+	defer func() {
+		if r := recover(); r != nil {
+			el.ReportError(panicError(r))
 		}
 	}()
 	job()
 }
 
+// panicError turns a recovered panic value into an error
+func panicError(r interface{}) error {
+	if err, ok := r.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%v", r)
+}
+
 // RunOnLoop is a helper to execute code on the loop synchronously
 func (el *EventLoop) RunOnLoop(fn func(*goja.Runtime)) {
 	// If not running, just execute directly
@@ -94,17 +198,39 @@ func (el *EventLoop) RunOnLoop(fn func(*goja.Runtime)) {
 		fn(el.vm)
 		return
 	}
+	stop := el.stopSignal
 	el.mu.Unlock()
 
+	// Whoever claims the job first runs it: the loop, or this caller once
+	// the loop has stopped without taking it
+	var claimed atomic.Bool
 	done := make(chan struct{})
+	job := func() {
+		if !claimed.CompareAndSwap(false, true) {
+			return
+		}
+		defer close(done)
+		fn(el.vm)
+	}
 
 	// Use a goroutine to avoid blocking if the queue is full
 	go func() {
-		el.jobQueue <- func() {
-			fn(el.vm)
-			close(done)
+		select {
+		case el.jobQueue <- job:
+		case <-stop:
 		}
 	}()
 
-	<-done
+	select {
+	case <-done:
+	case <-stop:
+		if !claimed.CompareAndSwap(false, true) {
+			// The loop took the job before it stopped
+			<-done
+			return
+		}
+		// As when the loop isn't running, once its last job is done
+		<-el.stopped
+		fn(el.vm)
+	}
 }
@@ -0,0 +1,64 @@
+package spidergopher
+
+import (
+	"slices"
+
+	realdom "go-browser/dom"
+	"go-browser/spidergopher/dom"
+)
+
+// ======================================================================================
+// DIALOGS
+// dialog.showModal() puts a <dialog> in the top layer: the browser shows it
+// over the page, and only it takes clicks, until it is closed. Modals stack;
+// the last one shown is on top.
+// ======================================================================================
+
+// OnModalDialog registers the browser's handler for the topmost modal
+// dialog changing; node is nil when no modal dialog is open
+func (e *Engine) OnModalDialog(fn func(node *realdom.Node)) {
+	e.onModal = fn
+}
+
+// ModalDialog returns the topmost modal dialog, or nil
+func (e *Engine) ModalDialog() *realdom.Node {
+	if len(e.modals) == 0 {
+		return nil
+	}
+	return e.modals[len(e.modals)-1]
+}
+
+// DialogShownModal implements dom.DialogHandler
+func (e *Engine) DialogShownModal(node *realdom.Node) {
+	e.modals = append(e.modals, node)
+	e.modalChanged()
+}
+
+// DialogClosed implements dom.DialogHandler
+func (e *Engine) DialogClosed(node *realdom.Node) {
+	e.modals = slices.DeleteFunc(e.modals, func(n *realdom.Node) bool { return n == node })
+	e.modalChanged()
+}
+
+// modalChanged tells the browser which modal dialog is on top now
+func (e *Engine) modalChanged() {
+	if e.onModal != nil {
+		e.onModal(e.ModalDialog())
+	}
+}
+
+// CancelDialog closes the topmost modal dialog; the browser calls it when
+// the user presses Escape. The page's listeners run on its event loop.
+func (e *Engine) CancelDialog() {
+	e.Loop.Schedule(func() {
+		if node := e.ModalDialog(); node != nil {
+			dom.CancelDialog(node, e.vm)
+		}
+	})
+}
+
+// ToggleDetails opens or closes a <details>; the browser calls it when the
+// user clicks its summary. The page's listeners run on its event loop.
+func (e *Engine) ToggleDetails(node *realdom.Node) {
+	e.Loop.Schedule(func() { dom.ToggleDetails(node, e.vm) })
+}
@@ -1,26 +1,30 @@
 package dom
 
 import (
-	"fmt"
 	realdom "go-browser/dom"
+	"go-browser/logging"
 
 	"github.com/dop251/goja"
 )
 
 // DOMBridge connects SpiderGopher's JS runtime with the real DOM tree
 type DOMBridge struct {
+	doc  *realdom.Document
 	root *realdom.Node
 	vm   *goja.Runtime
 }
 
-// NewDOMBridge creates a new bridge to a real DOM tree
-func NewDOMBridge(root *realdom.Node, vm *goja.Runtime) *DOMBridge {
-	return &DOMBridge{root: root, vm: vm}
+// NewDOMBridge creates a new bridge to a real document
+func NewDOMBridge(doc *realdom.Document, vm *goja.Runtime) *DOMBridge {
+	b := &DOMBridge{vm: vm}
+	b.SetDocument(doc)
+	return b
 }
 
-// SetRoot updates the DOM root (called when page loads)
-func (b *DOMBridge) SetRoot(root *realdom.Node) {
-	b.root = root
+// SetDocument updates the bridged document (called when page loads)
+func (b *DOMBridge) SetDocument(doc *realdom.Document) {
+	b.doc = doc
+	b.root = doc.Node
 }
 
 // GetDocumentObject returns a JS document object connected to the real DOM
@@ -35,10 +39,10 @@ func (b *DOMBridge) GetDocumentObject() *goja.Object {
 		id := call.Argument(0).String()
 		node := b.findById(b.root, id)
 		if node == nil {
-			fmt.Printf("[DOMBridge] getElementById('%s') = null (not found)\n", id)
+			logging.JS.Debug("getElementById", "id", id, "found", false)
 			return goja.Null()
 		}
-		fmt.Printf("[DOMBridge] getElementById('%s') = <%s> ptr=%p\n", id, node.Tag, node)
+		logging.JS.Debug("getElementById", "id", id, "tag", node.Tag)
 		return NewJSNode(node, b.vm).ToJSObject()
 	})
 
@@ -57,9 +61,7 @@ func (b *DOMBridge) GetDocumentObject() *goja.Object {
 		if len(call.Arguments) < 1 {
 			return b.vm.NewArray()
 		}
-		tagName := call.Argument(0).String()
-		nodes := b.findByTagName(b.root, tagName)
-		return b.nodesToArray(nodes)
+		return b.nodesToArray(b.root.GetElementsByTagName(call.Argument(0).String()))
 	})
 
 	// querySelector
@@ -107,36 +109,114 @@ func (b *DOMBridge) GetDocumentObject() *goja.Object {
 		return NewJSNode(newNode, b.vm).ToJSObject()
 	})
 
-	// documentElement (root html element)
-	obj.Set("documentElement", func() goja.Value {
-		html := b.findByTagName(b.root, "html")
-		if len(html) > 0 {
-			return NewJSNode(html[0], b.vm).ToJSObject()
-		}
-		return goja.Null()
-	}())
+	// createDocumentFragment: nodes appended to a fragment are built off the
+	// page, and inserting it moves them all in with one relayout
+	obj.Set("createDocumentFragment", func(goja.FunctionCall) goja.Value {
+		return NewJSNode(realdom.NewDocumentFragment(), b.vm).ToJSObject()
+	})
 
-	// body
-	obj.Set("body", func() goja.Value {
-		body := b.findByTagName(b.root, "body")
-		if len(body) > 0 {
-			return NewJSNode(body[0], b.vm).ToJSObject()
+	// Listeners on the document hear events that bubble up to its node
+	obj.Set("addEventListener", func(call goja.FunctionCall) goja.Value {
+		if fn, ok := goja.AssertFunction(call.Argument(1)); ok {
+			NewJSNode(b.root, b.vm).addEventListener(call.Argument(0).String(), fn)
 		}
-		return goja.Null()
-	}())
+		return goja.Undefined()
+	})
+
+	// readyState follows the page load; the engine fires readystatechange
+	obj.DefineAccessorProperty("readyState", b.vm.ToValue(func(goja.FunctionCall) goja.Value {
+		return b.vm.ToValue(b.doc.ReadyState)
+	}), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	obj.Set("onreadystatechange", goja.Null())
+
+	// title reads and replaces the <title> text; the browser shows the new
+	// title on the tab and the window
+	obj.DefineAccessorProperty("title", b.vm.ToValue(func(goja.FunctionCall) goja.Value {
+		return b.vm.ToValue(b.doc.Title())
+	}), b.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		b.doc.SetTitle(call.Argument(0).String())
+		notifyTitleChanged(b.vm, b.doc.Title())
+		return goja.Undefined()
+	}), goja.FLAG_FALSE, goja.FLAG_TRUE)
 
-	// head
-	obj.Set("head", func() goja.Value {
-		head := b.findByTagName(b.root, "head")
-		if len(head) > 0 {
-			return NewJSNode(head[0], b.vm).ToJSObject()
+	// URL and documentURI are the address the page was loaded from
+	documentURL := b.vm.ToValue(func(goja.FunctionCall) goja.Value {
+		if b.doc.URL == "" {
+			return b.vm.ToValue("about:blank")
 		}
-		return goja.Null()
-	}())
+		return b.vm.ToValue(b.doc.URL)
+	})
+	obj.DefineAccessorProperty("URL", documentURL, nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	obj.DefineAccessorProperty("documentURI", documentURL, nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	// characterSet and its legacy aliases name the encoding the page was
+	// decoded with
+	obj.Set("characterSet", realdom.CharacterSet)
+	obj.Set("charset", realdom.CharacterSet)
+	obj.Set("inputEncoding", realdom.CharacterSet)
+	obj.Set("contentType", "text/html")
+
+	// Fullscreen API
+	obj.Set("fullscreenEnabled", true)
+	obj.Set("exitFullscreen", b.exitFullscreen)
+	obj.DefineAccessorProperty("fullscreenElement", b.vm.ToValue(func(goja.FunctionCall) goja.Value {
+		return b.fullscreenElement()
+	}), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	// documentElement, head and body come straight from the document
+	obj.Set("documentElement", b.nodeOrNull(b.doc.DocumentElement))
+	obj.Set("head", b.nodeOrNull(b.doc.Head))
+	obj.Set("body", b.nodeOrNull(b.doc.Body))
+
+	// compatMode and doctype
+	obj.Set("compatMode", b.doc.CompatMode)
+	if b.doc.Doctype != "" {
+		doctype := b.vm.NewObject()
+		doctype.Set("name", b.doc.Doctype)
+		obj.Set("doctype", doctype)
+	} else {
+		obj.Set("doctype", goja.Null())
+	}
 
 	return obj
 }
 
+// DispatchDocumentEvent fires eventType at the listeners added to the
+// document
+func DispatchDocumentEvent(doc *realdom.Document, vm *goja.Runtime, eventType string) {
+	NewJSNode(doc.Node, vm).dispatchEvent(eventType)
+}
+
+// TitleChangedFunc is called after script changes the document's title
+type TitleChangedFunc func(title string)
+
+// titleObservers holds the title callback of each runtime
+var titleObservers perRuntime[TitleChangedFunc]
+
+// SetTitleObserver registers fn to be notified of title changes made
+// through vm. Passing nil removes the observer.
+func SetTitleObserver(vm *goja.Runtime, fn TitleChangedFunc) {
+	if fn == nil {
+		titleObservers.remove(vm)
+		return
+	}
+	titleObservers.set(vm, fn)
+}
+
+func notifyTitleChanged(vm *goja.Runtime, title string) {
+	if fn := titleObservers.get(vm); fn != nil {
+		fn(title)
+	}
+}
+
+// nodeOrNull wraps node for JS, mapping nil to null
+func (b *DOMBridge) nodeOrNull(node *realdom.Node) goja.Value {
+	if node == nil {
+		return goja.Null()
+	}
+	return NewJSNode(node, b.vm).ToJSObject()
+}
+
 func (b *DOMBridge) findById(node *realdom.Node, id string) *realdom.Node {
 	if node == nil {
 		return nil
@@ -170,24 +250,6 @@ func (b *DOMBridge) collectByClass(node *realdom.Node, className string, results
 	}
 }
 
-func (b *DOMBridge) findByTagName(node *realdom.Node, tagName string) []*realdom.Node {
-	var results []*realdom.Node
-	b.collectByTag(node, tagName, &results)
-	return results
-}
-
-func (b *DOMBridge) collectByTag(node *realdom.Node, tagName string, results *[]*realdom.Node) {
-	if node == nil {
-		return
-	}
-	if node.Tag == tagName {
-		*results = append(*results, node)
-	}
-	for _, child := range node.Children {
-		b.collectByTag(child, tagName, results)
-	}
-}
-
 func (b *DOMBridge) nodesToArray(nodes []*realdom.Node) goja.Value {
 	arr := b.vm.NewArray()
 	for i, node := range nodes {
@@ -0,0 +1,255 @@
+package dom
+
+import (
+	"math"
+	"strconv"
+
+	realdom "go-browser/dom"
+
+	"github.com/dop251/goja"
+)
+
+// CanvasStyle is the drawing state a 2D context draws with
+type CanvasStyle struct {
+	FillStyle    string // CSS color
+	StrokeStyle  string // CSS color
+	LineWidth    float64
+	GlobalAlpha  float64 // from 0 to 1
+	Font         string  // CSS font shorthand such as "10px sans-serif"
+	TextAlign    string  // start, end, left, right or center
+	TextBaseline string  // alphabetic, top, hanging, middle, ideographic or bottom
+}
+
+// CanvasContext draws on the bitmap of one <canvas> element. Sizes given
+// as NaN to DrawImage stand for the source image's own.
+type CanvasContext interface {
+	FillRect(style CanvasStyle, x, y, w, h float64)
+	StrokeRect(style CanvasStyle, x, y, w, h float64)
+	ClearRect(x, y, w, h float64)
+
+	BeginPath()
+	MoveTo(x, y float64)
+	LineTo(x, y float64)
+	Arc(x, y, radius, startAngle, endAngle float64, counterclockwise bool)
+	Rect(x, y, w, h float64)
+	ClosePath()
+	Fill(style CanvasStyle)
+	Stroke(style CanvasStyle)
+
+	FillText(style CanvasStyle, text string, x, y float64)
+	MeasureText(style CanvasStyle, text string) float64
+
+	DrawImage(source *realdom.Node, sx, sy, sw, sh, dx, dy, dw, dh float64)
+	GetImageData(x, y, w, h int) []byte // RGBA, not premultiplied
+	PutImageData(data []byte, x, y, w, h int)
+}
+
+// CanvasHandler gives the <canvas> elements of one runtime's page their
+// drawing surfaces
+type CanvasHandler interface {
+	CanvasContext(node *realdom.Node) CanvasContext
+}
+
+// canvasHandlers holds the canvas handler of each runtime
+var canvasHandlers perRuntime[CanvasHandler]
+
+// canvasContexts holds the context objects handed out in each runtime, so
+// that getContext('2d') returns the same object, and its state, every time
+var canvasContexts perRuntime[map[*realdom.Node]*goja.Object]
+
+// SetCanvasHandler registers h to draw the canvases of vm. Passing nil
+// removes it.
+func SetCanvasHandler(vm *goja.Runtime, h CanvasHandler) {
+	canvasContexts.remove(vm)
+	if h == nil {
+		canvasHandlers.remove(vm)
+		return
+	}
+	canvasHandlers.set(vm, h)
+}
+
+// Default canvas size, used when the width or height attribute is missing
+const (
+	DefaultCanvasWidth  = 300
+	DefaultCanvasHeight = 150
+)
+
+// CanvasSize returns the bitmap size of a <canvas> from its attributes
+func CanvasSize(node *realdom.Node) (w, h int) {
+	size := func(name string, def int) int {
+		if n, err := strconv.Atoi(node.GetAttr(name)); err == nil && n >= 0 {
+			return n
+		}
+		return def
+	}
+	return size("width", DefaultCanvasWidth), size("height", DefaultCanvasHeight)
+}
+
+// defineCanvasMembers adds width, height and getContext() to a <canvas>
+func (n *JSNode) defineCanvasMembers(obj *goja.Object) {
+	dimension := func(name string, get func() int) {
+		obj.DefineAccessorProperty(name,
+			n.vm.ToValue(func(goja.FunctionCall) goja.Value { return n.vm.ToValue(get()) }),
+			n.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+				n.setAttr(name, strconv.Itoa(int(call.Argument(0).ToInteger())))
+				return goja.Undefined()
+			}),
+			goja.FLAG_FALSE, goja.FLAG_TRUE)
+	}
+	dimension("width", func() int { w, _ := CanvasSize(n.node); return w })
+	dimension("height", func() int { _, h := CanvasSize(n.node); return h })
+
+	obj.Set("getContext", func(call goja.FunctionCall) goja.Value {
+		h := canvasHandlers.get(n.vm)
+		if call.Argument(0).String() != "2d" || h == nil {
+			return goja.Null()
+		}
+		var ctx *goja.Object
+		canvasContexts.read(n.vm, func(contexts map[*realdom.Node]*goja.Object) { ctx = contexts[n.node] })
+		if ctx != nil {
+			return ctx
+		}
+		ctx = n.newContext2D(h.CanvasContext(n.node), obj)
+		canvasContexts.update(n.vm, func(contexts map[*realdom.Node]*goja.Object) map[*realdom.Node]*goja.Object {
+			if contexts == nil {
+				contexts = make(map[*realdom.Node]*goja.Object)
+			}
+			contexts[n.node] = ctx
+			return contexts
+		})
+		return ctx
+	})
+}
+
+// canvasStyleDefaults are the drawing state properties of a fresh context
+var canvasStyleDefaults = []struct {
+	name  string
+	value any
+}{
+	{"fillStyle", "#000000"},
+	{"strokeStyle", "#000000"},
+	{"lineWidth", 1.0},
+	{"globalAlpha", 1.0},
+	{"font", "10px sans-serif"},
+	{"textAlign", "start"},
+	{"textBaseline", "alphabetic"},
+}
+
+// newContext2D creates the CanvasRenderingContext2D object drawing on ctx.
+// The drawing state lives in the object's properties; save() and restore()
+// keep a stack of it.
+func (n *JSNode) newContext2D(ctx CanvasContext, canvas *goja.Object) *goja.Object {
+	vm := n.vm
+	c := vm.NewObject()
+	c.Set("canvas", canvas)
+	for _, prop := range canvasStyleDefaults {
+		c.Set(prop.name, prop.value)
+	}
+	style := func() CanvasStyle {
+		return CanvasStyle{
+			FillStyle:    c.Get("fillStyle").String(),
+			StrokeStyle:  c.Get("strokeStyle").String(),
+			LineWidth:    c.Get("lineWidth").ToFloat(),
+			GlobalAlpha:  math.Max(0, math.Min(1, c.Get("globalAlpha").ToFloat())),
+			Font:         c.Get("font").String(),
+			TextAlign:    c.Get("textAlign").String(),
+			TextBaseline: c.Get("textBaseline").String(),
+		}
+	}
+
+	var saved [][]goja.Value
+	c.Set("save", func() {
+		state := make([]goja.Value, len(canvasStyleDefaults))
+		for i, prop := range canvasStyleDefaults {
+			state[i] = c.Get(prop.name)
+		}
+		saved = append(saved, state)
+	})
+	c.Set("restore", func() {
+		if len(saved) == 0 {
+			return
+		}
+		state := saved[len(saved)-1]
+		saved = saved[:len(saved)-1]
+		for i, prop := range canvasStyleDefaults {
+			c.Set(prop.name, state[i])
+		}
+	})
+
+	// Rectangles
+	c.Set("fillRect", func(x, y, w, h float64) { ctx.FillRect(style(), x, y, w, h) })
+	c.Set("strokeRect", func(x, y, w, h float64) { ctx.StrokeRect(style(), x, y, w, h) })
+	c.Set("clearRect", ctx.ClearRect)
+
+	// Paths
+	c.Set("beginPath", ctx.BeginPath)
+	c.Set("moveTo", ctx.MoveTo)
+	c.Set("lineTo", ctx.LineTo)
+	c.Set("arc", ctx.Arc)
+	c.Set("rect", ctx.Rect)
+	c.Set("closePath", ctx.ClosePath)
+	c.Set("fill", func() { ctx.Fill(style()) })
+	c.Set("stroke", func() { ctx.Stroke(style()) })
+
+	// Text
+	c.Set("fillText", func(text string, x, y float64) { ctx.FillText(style(), text, x, y) })
+	c.Set("measureText", func(text string) *goja.Object {
+		metrics := vm.NewObject()
+		metrics.Set("width", ctx.MeasureText(style(), text))
+		return metrics
+	})
+
+	// Images: drawImage(image, dx, dy), drawImage(image, dx, dy, dw, dh) and
+	// drawImage(image, sx, sy, sw, sh, dx, dy, dw, dh)
+	c.Set("drawImage", func(call goja.FunctionCall) goja.Value {
+		source := nodeOf(call.Argument(0))
+		if source == nil {
+			panic(vm.NewTypeError("drawImage: the image argument is not an element"))
+		}
+		arg := func(i int) float64 { return call.Argument(i).ToFloat() }
+		nan := math.NaN()
+		switch len(call.Arguments) {
+		case 3:
+			ctx.DrawImage(source, 0, 0, nan, nan, arg(1), arg(2), nan, nan)
+		case 5:
+			ctx.DrawImage(source, 0, 0, nan, nan, arg(1), arg(2), arg(3), arg(4))
+		case 9:
+			ctx.DrawImage(source, arg(1), arg(2), arg(3), arg(4), arg(5), arg(6), arg(7), arg(8))
+		default:
+			panic(vm.NewTypeError("drawImage takes 3, 5 or 9 arguments"))
+		}
+		return goja.Undefined()
+	})
+
+	// Pixels
+	c.Set("getImageData", func(x, y, w, h int) *goja.Object {
+		if w <= 0 || h <= 0 {
+			panic(vm.NewTypeError("getImageData: the width and height must not be zero"))
+		}
+		return n.imageData(ctx.GetImageData(x, y, w, h), w, h)
+	})
+	c.Set("createImageData", func(w, h int) *goja.Object {
+		return n.imageData(make([]byte, 4*max(w, 0)*max(h, 0)), w, h)
+	})
+	c.Set("putImageData", func(imageData *goja.Object, x, y int) {
+		data, _ := imageData.Get("data").Export().([]byte)
+		w, h := int(imageData.Get("width").ToInteger()), int(imageData.Get("height").ToInteger())
+		if len(data) == 4*w*h {
+			ctx.PutImageData(data, x, y, w, h)
+		}
+	})
+	return c
+}
+
+// imageData wraps RGBA pixels in an ImageData object
+func (n *JSNode) imageData(pixels []byte, w, h int) *goja.Object {
+	data, err := n.vm.New(n.vm.Get("Uint8ClampedArray"), n.vm.ToValue(n.vm.NewArrayBuffer(pixels)))
+	if err != nil {
+		panic(err)
+	}
+	obj := n.vm.NewObject()
+	obj.Set("width", w)
+	obj.Set("height", h)
+	obj.Set("data", data)
+	return obj
+}
@@ -0,0 +1,110 @@
+package dom
+
+import (
+	"sort"
+	"strings"
+
+	"go-browser/spidergopher/webapi"
+
+	"github.com/dop251/goja"
+)
+
+// ======================================================================================
+// DATASET
+// element.dataset maps the data-* attributes to camelCase properties:
+// data-user-id is dataset.userId. It is live, reading and writing the node's
+// attributes through setAttr, so style invalidation sees the changes.
+// ======================================================================================
+
+// dataset is the goja.DynamicObject behind element.dataset
+type dataset struct {
+	n *JSNode
+}
+
+// dataset returns the element's DOMStringMap
+func (n *JSNode) dataset() *goja.Object {
+	return n.vm.NewDynamicObject(dataset{n})
+}
+
+func (d dataset) Get(key string) goja.Value {
+	name, ok := dataAttrName(key)
+	if !ok {
+		return nil
+	}
+	if value, ok := d.n.node.Attributes[name]; ok {
+		return d.n.vm.ToValue(value)
+	}
+	return nil
+}
+
+func (d dataset) Set(key string, val goja.Value) bool {
+	name, ok := dataAttrName(key)
+	if !ok {
+		panic(webapi.DOMException(d.n.vm, "SyntaxError", "'"+key+"' has a hyphen followed by a lowercase letter"))
+	}
+	d.n.setAttr(name, val.String())
+	return true
+}
+
+func (d dataset) Has(key string) bool {
+	name, ok := dataAttrName(key)
+	return ok && d.n.node.HasAttr(name)
+}
+
+func (d dataset) Delete(key string) bool {
+	if name, ok := dataAttrName(key); ok {
+		d.n.removeAttr(name)
+	}
+	return true
+}
+
+func (d dataset) Keys() []string {
+	var keys []string
+	for name := range d.n.node.Attributes {
+		if key, ok := dataPropName(name); ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// dataAttrName turns a dataset property into its attribute: fooBar is
+// data-foo-bar. A hyphen followed by a lowercase letter has no attribute.
+func dataAttrName(key string) (string, bool) {
+	var sb strings.Builder
+	sb.WriteString("data-")
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c == '-' && i+1 < len(key) && key[i+1] >= 'a' && key[i+1] <= 'z':
+			return "", false
+		case c >= 'A' && c <= 'Z':
+			sb.WriteByte('-')
+			sb.WriteByte(c + 'a' - 'A')
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), true
+}
+
+// dataPropName turns a data-* attribute into its dataset property, and
+// reports false for other attributes
+func dataPropName(name string) (string, bool) {
+	rest, ok := strings.CutPrefix(name, "data-")
+	if !ok {
+		return "", false
+	}
+	var sb strings.Builder
+	for i := 0; i < len(rest); i++ {
+		c := rest[i]
+		if c == '-' && i+1 < len(rest) && rest[i+1] >= 'a' && rest[i+1] <= 'z' {
+			sb.WriteByte(rest[i+1] - 'a' + 'A')
+			i++
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String(), true
+}
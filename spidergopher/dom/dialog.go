@@ -0,0 +1,151 @@
+package dom
+
+import (
+	realdom "go-browser/dom"
+	"go-browser/spidergopher/webapi"
+
+	"github.com/dop251/goja"
+)
+
+// DialogHandler shows the modal dialogs of one runtime's page over the page
+type DialogHandler interface {
+	DialogShownModal(node *realdom.Node)
+	DialogClosed(node *realdom.Node)
+}
+
+// dialogHandlers holds the dialog handler of each runtime
+var dialogHandlers perRuntime[DialogHandler]
+
+// dialogReturnValues holds the returnValue of each runtime's dialogs
+var dialogReturnValues perRuntime[map[*realdom.Node]string]
+
+// SetDialogHandler registers h to show the modal dialogs of vm. Passing nil
+// removes it.
+func SetDialogHandler(vm *goja.Runtime, h DialogHandler) {
+	dialogReturnValues.remove(vm)
+	if h == nil {
+		dialogHandlers.remove(vm)
+		return
+	}
+	dialogHandlers.set(vm, h)
+}
+
+// defineDetailsMembers adds the open property of HTMLDetailsElement to obj
+func (n *JSNode) defineDetailsMembers(obj *goja.Object) {
+	obj.DefineAccessorProperty("open",
+		n.vm.ToValue(func(goja.FunctionCall) goja.Value { return n.vm.ToValue(n.node.HasAttr("open")) }),
+		n.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if call.Argument(0).ToBoolean() != n.node.HasAttr("open") {
+				n.toggleDetails()
+			}
+			return goja.Undefined()
+		}),
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+}
+
+// toggleDetails opens or closes a <details> and fires toggle at it
+func (n *JSNode) toggleDetails() {
+	if n.node.HasAttr("open") {
+		n.removeAttr("open")
+	} else {
+		n.setAttr("open", "")
+	}
+	n.dispatchEvent("toggle")
+}
+
+// ToggleDetails opens or closes a <details> the user clicked the summary
+// of, telling the page's scripts
+func ToggleDetails(node *realdom.Node, vm *goja.Runtime) {
+	NewJSNode(node, vm).toggleDetails()
+}
+
+// defineDialogMembers adds show(), showModal(), close(), open and
+// returnValue of HTMLDialogElement to obj
+func (n *JSNode) defineDialogMembers(obj *goja.Object) {
+	obj.DefineAccessorProperty("open",
+		n.vm.ToValue(func(goja.FunctionCall) goja.Value { return n.vm.ToValue(n.node.HasAttr("open")) }),
+		n.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			if call.Argument(0).ToBoolean() {
+				n.setAttr("open", "")
+			} else {
+				n.removeAttr("open")
+			}
+			return goja.Undefined()
+		}),
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+	obj.DefineAccessorProperty("returnValue",
+		n.vm.ToValue(func(goja.FunctionCall) goja.Value {
+			var value string
+			dialogReturnValues.read(n.vm, func(values map[*realdom.Node]string) { value = values[n.node] })
+			return n.vm.ToValue(value)
+		}),
+		n.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			n.setReturnValue(call.Argument(0).String())
+			return goja.Undefined()
+		}),
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	obj.Set("show", func() {
+		if n.node.HasAttr("open") {
+			return
+		}
+		n.setAttr("open", "")
+	})
+	obj.Set("showModal", func() {
+		if n.node.HasAttr("open") {
+			if n.node.TopLayer {
+				return
+			}
+			panic(webapi.DOMException(n.vm, "InvalidStateError", "The dialog is already open as a non-modal dialog"))
+		}
+		n.node.TopLayer = true
+		n.setAttr("open", "")
+		if h := dialogHandlers.get(n.vm); h != nil {
+			h.DialogShownModal(n.node)
+		}
+	})
+	obj.Set("close", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) > 0 && !goja.IsUndefined(call.Argument(0)) {
+			n.closeDialog(call.Argument(0).String(), true)
+		} else {
+			n.closeDialog("", false)
+		}
+		return goja.Undefined()
+	})
+}
+
+func (n *JSNode) setReturnValue(value string) {
+	dialogReturnValues.update(n.vm, func(values map[*realdom.Node]string) map[*realdom.Node]string {
+		if values == nil {
+			values = make(map[*realdom.Node]string)
+		}
+		values[n.node] = value
+		return values
+	})
+}
+
+// closeDialog closes an open dialog, setting its returnValue when set is
+// true, and fires close at it
+func (n *JSNode) closeDialog(returnValue string, set bool) {
+	if !n.node.HasAttr("open") {
+		return
+	}
+	modal := n.node.TopLayer
+	n.node.TopLayer = false
+	n.removeAttr("open")
+	if set {
+		n.setReturnValue(returnValue)
+	}
+	if h := dialogHandlers.get(n.vm); h != nil && modal {
+		h.DialogClosed(n.node)
+	}
+	n.dispatchEvent("close")
+}
+
+// CancelDialog closes a modal dialog the user dismissed with Escape,
+// firing cancel and then close at it
+func CancelDialog(node *realdom.Node, vm *goja.Runtime) {
+	n := NewJSNode(node, vm)
+	n.dispatchEvent("cancel")
+	n.closeDialog("", false)
+}
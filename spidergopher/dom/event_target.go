@@ -15,6 +15,7 @@ type Event struct {
 	Cancelable       bool
 	DefaultPrevented bool
 	TimeStamp        int64
+	Fields           map[string]interface{} // members of the event's own interface, e.g. ErrorEvent.message
 }
 
 // NewEvent creates a new Event
@@ -28,7 +29,7 @@ func NewEvent(eventType string) *Event {
 
 // ToJSObject converts Event to a JS-compatible map
 func (e *Event) ToJSObject() map[string]interface{} {
-	return map[string]interface{}{
+	obj := map[string]interface{}{
 		"type":             e.Type,
 		"target":           e.Target,
 		"currentTarget":    e.CurrentTarget,
@@ -44,6 +45,32 @@ func (e *Event) ToJSObject() map[string]interface{} {
 			// For now, just a stub
 		},
 	}
+	for name, value := range e.Fields {
+		obj[name] = value
+	}
+	return obj
+}
+
+// errorReporters holds the uncaught error reporter of each runtime
+var errorReporters perRuntime[func(error)]
+
+// SetErrorReporter registers fn to hear about the errors event listeners run
+// through vm throw. Passing nil removes it.
+func SetErrorReporter(vm *goja.Runtime, fn func(error)) {
+	if fn == nil {
+		errorReporters.remove(vm)
+		return
+	}
+	errorReporters.set(vm, fn)
+}
+
+// callListener calls an event listener, reporting what it throws
+func callListener(vm *goja.Runtime, cb goja.Callable, event goja.Value) {
+	if _, err := cb(goja.Undefined(), event); err != nil {
+		if fn := errorReporters.get(vm); fn != nil {
+			fn(err)
+		}
+	}
 }
 
 // EventListener wraps a JS function
@@ -133,7 +160,7 @@ func (et *EventTarget) DispatchEvent(vm *goja.Runtime, event *Event) bool {
 	for _, listener := range toCall {
 		if fn, ok := goja.AssertFunction(listener.Callback); ok {
 			eventObj := vm.ToValue(event.ToJSObject())
-			fn(goja.Undefined(), eventObj)
+			callListener(vm, fn, eventObj)
 
 			if listener.Once {
 				toRemove = append(toRemove, listener.Callback)
@@ -0,0 +1,78 @@
+package dom
+
+import (
+	realdom "go-browser/dom"
+
+	"github.com/dop251/goja"
+)
+
+// FullscreenHandler shows elements fullscreen for the page of one runtime
+type FullscreenHandler interface {
+	RequestFullscreen(node *realdom.Node) error
+	ExitFullscreen() error
+	FullscreenElement() *realdom.Node
+}
+
+// fullscreenHandlers holds the fullscreen handler of each runtime
+var fullscreenHandlers perRuntime[FullscreenHandler]
+
+// SetFullscreenHandler registers h to serve the Fullscreen API of vm.
+// Passing nil removes it.
+func SetFullscreenHandler(vm *goja.Runtime, h FullscreenHandler) {
+	if h == nil {
+		fullscreenHandlers.remove(vm)
+		return
+	}
+	fullscreenHandlers.set(vm, h)
+}
+
+// settleFullscreen returns a promise settled by fn: resolved when it
+// succeeds, rejected with a TypeError when it fails or the page has no
+// fullscreen handler
+func settleFullscreen(vm *goja.Runtime, fn func(FullscreenHandler) error) *goja.Promise {
+	promise, resolve, reject := vm.NewPromise()
+	h := fullscreenHandlers.get(vm)
+	if h == nil {
+		reject(vm.NewTypeError("Fullscreen is not supported"))
+		return promise
+	}
+	if err := fn(h); err != nil {
+		reject(vm.NewTypeError(err.Error()))
+		return promise
+	}
+	resolve(goja.Undefined())
+	return promise
+}
+
+// requestFullscreen implements element.requestFullscreen()
+func (n *JSNode) requestFullscreen() *goja.Promise {
+	return settleFullscreen(n.vm, func(h FullscreenHandler) error { return h.RequestFullscreen(n.node) })
+}
+
+// exitFullscreen implements document.exitFullscreen()
+func (b *DOMBridge) exitFullscreen() *goja.Promise {
+	return settleFullscreen(b.vm, FullscreenHandler.ExitFullscreen)
+}
+
+// fullscreenElement implements document.fullscreenElement
+func (b *DOMBridge) fullscreenElement() goja.Value {
+	if h := fullscreenHandlers.get(b.vm); h != nil {
+		return b.nodeOrNull(h.FullscreenElement())
+	}
+	return goja.Null()
+}
+
+// DispatchFullscreenChange fires fullscreenchange at node, bubbling up to
+// the document
+func DispatchFullscreenChange(node *realdom.Node, vm *goja.Runtime) {
+	target := NewJSNode(node, vm).ToJSObject()
+	for p := node; p != nil; p = p.Parent {
+		for _, cb := range nodeListenersOf(vm, p, "fullscreenchange") {
+			event := vm.NewObject()
+			event.Set("type", "fullscreenchange")
+			event.Set("bubbles", true)
+			event.Set("target", target)
+			callListener(vm, cb, event)
+		}
+	}
+}
@@ -2,7 +2,10 @@ package dom
 
 import (
 	"fmt"
+	"go-browser/css"
 	realdom "go-browser/dom"
+	"go-browser/logging"
+	"strings"
 
 	"github.com/dop251/goja"
 )
@@ -13,6 +16,20 @@ type JSNode struct {
 	vm   *goja.Runtime
 }
 
+// nodeSymbol keys the real node on the objects wrapping it, so that an
+// object passed back from JS (to drawImage, say) leads to its node
+var nodeSymbol = goja.NewSymbol("node")
+
+// nodeOf returns the real node a JS object wraps, or nil for other values
+func nodeOf(v goja.Value) *realdom.Node {
+	obj, ok := v.(*goja.Object)
+	if !ok {
+		return nil
+	}
+	node, _ := obj.GetSymbol(nodeSymbol).Export().(*realdom.Node)
+	return node
+}
+
 // NewJSNode creates a JS-accessible wrapper around a real DOM node
 func NewJSNode(node *realdom.Node, vm *goja.Runtime) *JSNode {
 	if node == nil {
@@ -28,17 +45,44 @@ func (n *JSNode) ToJSObject() *goja.Object {
 	}
 
 	obj := n.vm.NewObject()
+	obj.SetSymbol(nodeSymbol, n.node)
+
+	// Tags are stored in lower case; tagName and nodeName are the upper-case
+	// qualified name, as for HTML elements in browsers
+	switch n.node.Type {
+	case realdom.NodeElement:
+		obj.Set("tagName", strings.ToUpper(n.node.Tag))
+		obj.Set("nodeName", strings.ToUpper(n.node.Tag))
+		obj.Set("localName", n.node.Tag)
+	case realdom.NodeText:
+		obj.Set("nodeName", "#text")
+	case realdom.NodeDocument, realdom.NodeDocumentFragment:
+		obj.Set("nodeName", n.node.Tag)
+	}
+
+	// id, className, href, src, value... read and write the attributes
+	n.defineReflectedProperties(obj)
+
+	// classList writes through setAttr so style invalidation sees it
+	obj.DefineAccessorProperty("classList",
+		n.vm.ToValue(func(call goja.FunctionCall) goja.Value { return n.classList() }),
+		goja.Undefined(), goja.FLAG_FALSE, goja.FLAG_TRUE)
 
-	// Basic properties (safe - no recursion)
-	obj.Set("tagName", n.node.Tag)
-	obj.Set("nodeName", n.node.Tag)
-	obj.Set("id", n.node.GetAttr("id"))
-	obj.Set("className", n.node.GetAttr("class"))
+	// dataset maps data-* attributes to camelCase properties
+	if n.node.Type == realdom.NodeElement {
+		obj.Set("dataset", n.dataset())
+	}
 
-	// nodeType: 1 for Element, 3 for Text
+	// nodeType: 1 for Element, 3 for Text, 9 for Document, 11 for
+	// DocumentFragment
 	nodeType := 1
-	if n.node.Type == realdom.NodeText {
+	switch n.node.Type {
+	case realdom.NodeText:
 		nodeType = 3
+	case realdom.NodeDocument:
+		nodeType = 9
+	case realdom.NodeDocumentFragment:
+		nodeType = 11
 	}
 	obj.Set("nodeType", nodeType)
 
@@ -48,10 +92,10 @@ func (n *JSNode) ToJSObject() *goja.Object {
 			return n.vm.ToValue(n.getTextContent())
 		}),
 		n.vm.ToValue(func(call goja.FunctionCall) goja.Value {
-			fmt.Printf("[textContent setter] Called with %d args\n", len(call.Arguments))
+			logging.JS.Debug("textContent setter", "args", len(call.Arguments))
 			if len(call.Arguments) > 0 {
 				text := call.Argument(0).String()
-				fmt.Printf("[textContent setter] Setting: %s\n", text)
+				logging.JS.Debug("textContent setter", "text", text)
 				n.setTextContent(text)
 			}
 			return goja.Undefined()
@@ -93,12 +137,16 @@ func (n *JSNode) ToJSObject() *goja.Object {
 		if len(call.Arguments) < 2 {
 			return goja.Undefined()
 		}
-		name := call.Argument(0).String()
-		value := call.Argument(1).String()
-		if n.node.Attributes == nil {
-			n.node.Attributes = make(map[string]string)
+		n.setAttr(call.Argument(0).String(), call.Argument(1).String())
+		return goja.Undefined()
+	})
+
+	// removeAttribute method
+	obj.Set("removeAttribute", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) < 1 {
+			return goja.Undefined()
 		}
-		n.node.Attributes[name] = value
+		n.removeAttr(call.Argument(0).String())
 		return goja.Undefined()
 	})
 
@@ -129,29 +177,8 @@ func (n *JSNode) ToJSObject() *goja.Object {
 		n.vm.ToValue(func(call goja.FunctionCall) goja.Value { return n.getPreviousSibling() }),
 		goja.Undefined(), goja.FLAG_FALSE, goja.FLAG_TRUE)
 
-	// appendChild method - adds a child node
-	obj.Set("appendChild", func(call goja.FunctionCall) goja.Value {
-		if len(call.Arguments) < 1 {
-			return goja.Undefined()
-		}
-		// Extract the node from the JS object
-		childObj := call.Argument(0).ToObject(n.vm)
-		if childObj == nil {
-			return goja.Undefined()
-		}
-		// For now, we can't get the real node back from JS object easily
-		// This is a simplified implementation
-		return call.Argument(0) // Return the child as per spec
-	})
-
-	// removeChild method - removes a child node
-	obj.Set("removeChild", func(call goja.FunctionCall) goja.Value {
-		if len(call.Arguments) < 1 {
-			return goja.Undefined()
-		}
-		// Simplified - would need node mapping to properly implement
-		return call.Argument(0)
-	})
+	// appendChild, remove, before, insertAdjacentHTML, closest...
+	n.defineMutationMembers(obj)
 
 	// addEventListener method - crucial for interactivity!
 	obj.Set("addEventListener", func(call goja.FunctionCall) goja.Value {
@@ -174,6 +201,27 @@ func (n *JSNode) ToJSObject() *goja.Object {
 		return goja.Undefined()
 	})
 
+	// Fullscreen API
+	obj.Set("requestFullscreen", n.requestFullscreen)
+
+	// HTMLMediaElement API
+	if isMediaElement(n.node) {
+		n.defineMediaMembers(obj)
+	}
+
+	// HTMLCanvasElement API
+	if n.node.Tag == "canvas" {
+		n.defineCanvasMembers(obj)
+	}
+
+	// HTMLDetailsElement and HTMLDialogElement APIs
+	switch n.node.Tag {
+	case "details":
+		n.defineDetailsMembers(obj)
+	case "dialog":
+		n.defineDialogMembers(obj)
+	}
+
 	// querySelector method (searches within this node)
 	obj.Set("querySelector", func(call goja.FunctionCall) goja.Value {
 		if len(call.Arguments) < 1 {
@@ -207,6 +255,92 @@ func (n *JSNode) ToJSObject() *goja.Object {
 	return obj
 }
 
+// setAttr sets an attribute and notifies the page's attribute observer
+func (n *JSNode) setAttr(name, value string) {
+	name = strings.ToLower(name)
+	if n.node.Attributes == nil {
+		n.node.Attributes = make(map[string]string)
+	}
+	oldValue := n.node.Attributes[name]
+	n.node.Attributes[name] = value
+	notifyAttributeChanged(n.vm, n.node, name, oldValue, value)
+}
+
+// removeAttr removes an attribute, if present, and notifies the page's
+// attribute observer
+func (n *JSNode) removeAttr(name string) {
+	name = strings.ToLower(name)
+	oldValue, ok := n.node.Attributes[name]
+	if ok {
+		delete(n.node.Attributes, name)
+		notifyAttributeChanged(n.vm, n.node, name, oldValue, "")
+	}
+}
+
+// classList returns a DOMTokenList-like object over the class attribute
+func (n *JSNode) classList() goja.Value {
+	list := n.vm.NewObject()
+	update := func(fn func(classes []string) []string) {
+		n.setAttr("class", strings.Join(fn(splitClasses(n.node.GetAttr("class"))), " "))
+	}
+	without := func(classes []string, name string) []string {
+		var out []string
+		for _, c := range classes {
+			if c != name {
+				out = append(out, c)
+			}
+		}
+		return out
+	}
+
+	list.Set("contains", func(call goja.FunctionCall) goja.Value {
+		return n.vm.ToValue(containsClass(n.node.GetAttr("class"), call.Argument(0).String()))
+	})
+	list.Set("add", func(call goja.FunctionCall) goja.Value {
+		update(func(classes []string) []string {
+			for _, arg := range call.Arguments {
+				if name := arg.String(); !containsClass(strings.Join(classes, " "), name) {
+					classes = append(classes, name)
+				}
+			}
+			return classes
+		})
+		return goja.Undefined()
+	})
+	list.Set("remove", func(call goja.FunctionCall) goja.Value {
+		update(func(classes []string) []string {
+			for _, arg := range call.Arguments {
+				classes = without(classes, arg.String())
+			}
+			return classes
+		})
+		return goja.Undefined()
+	})
+	list.Set("toggle", func(call goja.FunctionCall) goja.Value {
+		name := call.Argument(0).String()
+		present := containsClass(n.node.GetAttr("class"), name)
+		if len(call.Arguments) > 1 {
+			present = !call.Argument(1).ToBoolean()
+		}
+		update(func(classes []string) []string {
+			if present {
+				return without(classes, name)
+			}
+			if containsClass(strings.Join(classes, " "), name) {
+				return classes
+			}
+			return append(classes, name)
+		})
+		return n.vm.ToValue(!present)
+	})
+	list.DefineAccessorProperty("length",
+		n.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			return n.vm.ToValue(len(splitClasses(n.node.GetAttr("class"))))
+		}),
+		goja.Undefined(), goja.FLAG_FALSE, goja.FLAG_TRUE)
+	return list
+}
+
 func (n *JSNode) getTextContent() string {
 	return collectText(n.node)
 }
@@ -233,38 +367,108 @@ func (n *JSNode) getInnerHTML() string {
 // setTextContent replaces all children with a single text node
 func (n *JSNode) setTextContent(text string) {
 	nodeID := n.node.GetAttr("id")
-	fmt.Printf("[setTextContent] Setting '%s' on #%s (tag=%s, ptr=%p)\n", text, nodeID, n.node.Tag, n.node)
+	logging.JS.Debug("setTextContent", "text", text, "id", nodeID, "tag", n.node.Tag)
 	// Clear all children
 	n.node.Children = nil
 	// Add new text node
 	textNode := realdom.NewText(text)
 	n.node.AppendChild(textNode)
-	fmt.Printf("[setTextContent] Node now has %d children\n", len(n.node.Children))
+	if doc := n.node.OwnerDocument(); doc != nil {
+		doc.AssignNodeIDs(textNode)
+		if n.node.Tag == "title" {
+			notifyTitleChanged(n.vm, doc.Title())
+		}
+	}
+	logging.JS.Debug("setTextContent", "children", len(n.node.Children))
 }
 
-// setInnerHTML parses HTML and replaces children (simplified - just sets text for now)
+// setInnerHTML replaces the children with the nodes html parses to
 func (n *JSNode) setInnerHTML(html string) {
-	// For now, treat innerHTML like textContent
-	// A full implementation would parse the HTML
-	n.setTextContent(html)
+	for _, child := range n.node.Children {
+		child.Parent = nil
+	}
+	n.node.Children = nil
+	n.insertNodes(n.node, nil, realdom.ParseFragment(html))
+}
+
+// nodeListeners holds the listeners each runtime's scripts added to elements:
+// by node key (see getNodeKey), then by event type. A page's are dropped
+// with ClearListeners when it unloads.
+var nodeListeners perRuntime[map[string]map[string][]goja.Callable]
+
+// ClearListeners drops the element listeners of vm's page
+func ClearListeners(vm *goja.Runtime) {
+	nodeListeners.remove(vm)
+}
+
+// ListenerCount returns how many element listeners vm's page has, or every
+// page when vm is nil
+func ListenerCount(vm *goja.Runtime) int {
+	count := 0
+	nodeListeners.each(func(pageVM *goja.Runtime, byNode map[string]map[string][]goja.Callable) {
+		if vm != nil && pageVM != vm {
+			return
+		}
+		for _, byType := range byNode {
+			for _, callbacks := range byType {
+				count += len(callbacks)
+			}
+		}
+	})
+	return count
+}
+
+// nodeListenersOf returns the listeners for eventType on node in vm's page
+func nodeListenersOf(vm *goja.Runtime, node *realdom.Node, eventType string) []goja.Callable {
+	key := NewJSNode(node, vm).getNodeKey()
+	var callbacks []goja.Callable
+	nodeListeners.read(vm, func(byNode map[string]map[string][]goja.Callable) {
+		callbacks = byNode[key][eventType]
+	})
+	return callbacks
 }
 
-// Event listener storage - CHANGED from pointer to ID-based for reliable matching
-// Key is element ID, value is map of event types to callbacks
-var nodeEventListenersByID = make(map[string]map[string][]goja.Callable)
+// AttributeChangedFunc is called after script changes an element attribute
+type AttributeChangedFunc func(node *realdom.Node, name, oldValue, newValue string)
+
+// attributeObservers holds the mutation callback of each runtime, so every
+// page (and its engine) only hears about its own DOM
+var attributeObservers perRuntime[AttributeChangedFunc]
+
+// SetAttributeObserver registers fn to be notified of attribute changes made
+// through vm. Passing nil removes the observer.
+func SetAttributeObserver(vm *goja.Runtime, fn AttributeChangedFunc) {
+	if fn == nil {
+		attributeObservers.remove(vm)
+		return
+	}
+	attributeObservers.set(vm, fn)
+}
+
+func notifyAttributeChanged(vm *goja.Runtime, node *realdom.Node, name, oldValue, newValue string) {
+	if fn := attributeObservers.get(vm); fn != nil {
+		fn(node, name, oldValue, newValue)
+	}
+}
 
 // addEventListener registers an event listener on this node
 func (n *JSNode) addEventListener(eventType string, callback goja.Callable) {
 	// Use element ID for storage - if no ID, use a generated key
 	nodeID := n.getNodeKey()
 
-	if nodeEventListenersByID[nodeID] == nil {
-		nodeEventListenersByID[nodeID] = make(map[string][]goja.Callable)
-	}
-	nodeEventListenersByID[nodeID][eventType] = append(nodeEventListenersByID[nodeID][eventType], callback)
+	nodeListeners.update(n.vm, func(byNode map[string]map[string][]goja.Callable) map[string]map[string][]goja.Callable {
+		if byNode == nil {
+			byNode = make(map[string]map[string][]goja.Callable)
+		}
+		if byNode[nodeID] == nil {
+			byNode[nodeID] = make(map[string][]goja.Callable)
+		}
+		byNode[nodeID][eventType] = append(byNode[nodeID][eventType], callback)
+		return byNode
+	})
 
 	// Debug log
-	fmt.Printf("[SpiderGopher] addEventListener: %s on #%s\n", eventType, nodeID)
+	logging.JS.Debug("addEventListener", "type", eventType, "id", nodeID)
 }
 
 // getNodeKey returns a unique key for this node (ID or generated)
@@ -276,43 +480,31 @@ func (n *JSNode) getNodeKey() string {
 	if id := n.node.GetAttr("id"); id != "" {
 		return id
 	}
-	// Fallback to pointer address (less reliable but better than nothing)
+	// Then the document's node ID
+	if n.node.NodeID != 0 {
+		return fmt.Sprintf("node_%d", n.node.NodeID)
+	}
+	// Fallback to pointer address for nodes never attached to a document
 	return fmt.Sprintf("node_%p", n.node)
 }
 
 // dispatchEvent triggers all listeners for an event type
 func (n *JSNode) dispatchEvent(eventType string) {
-	nodeID := n.getNodeKey()
-	listeners := nodeEventListenersByID[nodeID]
-	if listeners == nil {
-		return
-	}
-	callbacks := listeners[eventType]
-	for _, cb := range callbacks {
+	for _, cb := range nodeListenersOf(n.vm, n.node, eventType) {
 		// Create a simple event object
 		eventObj := n.vm.NewObject()
 		eventObj.Set("type", eventType)
 		eventObj.Set("target", n.ToJSObject())
-		cb(goja.Undefined(), eventObj)
+		callListener(n.vm, cb, eventObj)
 	}
 }
 
-// GetNodeListeners returns listeners for a node by ID
-func GetNodeListeners(node *realdom.Node, eventType string) []goja.Callable {
+// GetNodeListeners returns the listeners for eventType on a node of vm's page
+func GetNodeListeners(node *realdom.Node, vm *goja.Runtime, eventType string) []goja.Callable {
 	if node == nil {
 		return nil
 	}
-	// Get ID from node
-	nodeID := node.GetAttr("id")
-	if nodeID == "" {
-		nodeID = fmt.Sprintf("node_%p", node)
-	}
-
-	listeners := nodeEventListenersByID[nodeID]
-	if listeners == nil {
-		return nil
-	}
-	return listeners[eventType]
+	return nodeListenersOf(vm, node, eventType)
 }
 
 // DispatchClickEvent dispatches a click event to all listeners on a node
@@ -322,21 +514,17 @@ func DispatchClickEvent(node *realdom.Node, vm *goja.Runtime) {
 		return
 	}
 
-	// Get ID from node
-	nodeID := node.GetAttr("id")
-	if nodeID == "" {
-		nodeID = fmt.Sprintf("node_%p", node)
-	}
+	nodeID := NewJSNode(node, vm).getNodeKey()
 
-	fmt.Printf("[SpiderGopher] DispatchClickEvent: looking for listeners on #%s\n", nodeID)
+	logging.JS.Debug("DispatchClickEvent", "id", nodeID)
 
-	callbacks := GetNodeListeners(node, "click")
+	callbacks := GetNodeListeners(node, vm, "click")
 	if len(callbacks) == 0 {
-		fmt.Printf("[SpiderGopher] No click listeners found for #%s\n", nodeID)
+		logging.JS.Debug("no click listeners", "id", nodeID)
 		return
 	}
 
-	fmt.Printf("[SpiderGopher] Found %d click listeners for #%s\n", len(callbacks), nodeID)
+	logging.JS.Debug("click listeners", "count", len(callbacks), "id", nodeID)
 
 	// Create event object
 	eventObj := vm.NewObject()
@@ -346,7 +534,7 @@ func DispatchClickEvent(node *realdom.Node, vm *goja.Runtime) {
 
 	// Call all callbacks
 	for _, cb := range callbacks {
-		cb(goja.Undefined(), eventObj)
+		callListener(vm, cb, eventObj)
 	}
 }
 
@@ -421,62 +609,60 @@ func (n *JSNode) getPreviousSibling() goja.Value {
 	return goja.Null()
 }
 
-// querySelector - simple selector matching
+// querySelector returns the first element at or under node, in document
+// order, that matches the selector list
 func (n *JSNode) querySelector(node *realdom.Node, selector string) *realdom.Node {
+	return firstMatching(node, css.ParseSelectors(selector))
+}
+
+// querySelectorAll returns every element at or under node that matches the
+// selector list, in document order
+func (n *JSNode) querySelectorAll(node *realdom.Node, selector string) []*realdom.Node {
+	var results []*realdom.Node
+	collectMatching(node, css.ParseSelectors(selector), &results)
+	return results
+}
+
+func firstMatching(node *realdom.Node, selectors []css.Selector) *realdom.Node {
 	if node == nil {
 		return nil
 	}
-
-	if n.matchesSelector(node, selector) {
+	if matchesAny(node, selectors) {
 		return node
 	}
-
 	for _, child := range node.Children {
-		if found := n.querySelector(child, selector); found != nil {
+		if found := firstMatching(child, selectors); found != nil {
 			return found
 		}
 	}
 	return nil
 }
 
-// querySelectorAll - simple selector matching
-func (n *JSNode) querySelectorAll(node *realdom.Node, selector string) []*realdom.Node {
-	var results []*realdom.Node
-	n.collectMatching(node, selector, &results)
-	return results
-}
-
-func (n *JSNode) collectMatching(node *realdom.Node, selector string, results *[]*realdom.Node) {
+func collectMatching(node *realdom.Node, selectors []css.Selector, results *[]*realdom.Node) {
 	if node == nil {
 		return
 	}
-	if n.matchesSelector(node, selector) {
+	if matchesAny(node, selectors) {
 		*results = append(*results, node)
 	}
 	for _, child := range node.Children {
-		n.collectMatching(child, selector, results)
+		collectMatching(child, selectors, results)
 	}
 }
 
-// matchesSelector - simple selector matching (tag, .class, #id)
-func (n *JSNode) matchesSelector(node *realdom.Node, selector string) bool {
+// matchesAny reports whether node is an element matched by one of the
+// selectors, with the same matching stylesheets use: tags, classes, ids,
+// attributes, pseudo-classes and combinators
+func matchesAny(node *realdom.Node, selectors []css.Selector) bool {
 	if node.Type != realdom.NodeElement {
 		return false
 	}
-
-	if len(selector) == 0 {
-		return false
-	}
-
-	switch selector[0] {
-	case '#': // ID selector
-		return node.GetAttr("id") == selector[1:]
-	case '.': // Class selector
-		class := node.GetAttr("class")
-		return containsClass(class, selector[1:])
-	default: // Tag selector
-		return node.Tag == selector
+	for _, sel := range selectors {
+		if sel.Matches(node) {
+			return true
+		}
 	}
+	return false
 }
 
 func containsClass(classList, className string) bool {
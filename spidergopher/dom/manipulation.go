@@ -0,0 +1,271 @@
+package dom
+
+import (
+	"strings"
+
+	"go-browser/css"
+	realdom "go-browser/dom"
+	"go-browser/spidergopher/webapi"
+
+	"github.com/dop251/goja"
+)
+
+// ======================================================================================
+// TREE MUTATION
+// appendChild/removeChild and the ChildNode/ParentNode conveniences (remove,
+// before, after, append, prepend, replaceWith), insertAdjacent*, and the
+// selector tests closest and matches. Nodes inserted into the document get
+// node IDs, and the page's child-list observer styles them and relays out.
+// ======================================================================================
+
+// ChildListChangedFunc is called after script adds or removes children of
+// parent; added lists the nodes inserted, which aren't styled yet
+type ChildListChangedFunc func(parent *realdom.Node, added []*realdom.Node)
+
+// childListObservers holds the child-list callback of each runtime
+var childListObservers perRuntime[ChildListChangedFunc]
+
+// SetChildListObserver registers fn to be notified of children added or
+// removed through vm. Passing nil removes the observer.
+func SetChildListObserver(vm *goja.Runtime, fn ChildListChangedFunc) {
+	if fn == nil {
+		childListObservers.remove(vm)
+		return
+	}
+	childListObservers.set(vm, fn)
+}
+
+// childListChanged gives the nodes inserted under parent node IDs and tells
+// the observer, if parent is in a document
+func (n *JSNode) childListChanged(parent *realdom.Node, added []*realdom.Node) {
+	doc := parent.OwnerDocument()
+	if doc == nil {
+		return
+	}
+	for _, node := range added {
+		doc.AssignNodeIDs(node)
+	}
+	if fn := childListObservers.get(n.vm); fn != nil {
+		fn(parent, added)
+	}
+}
+
+// defineMutationMembers adds the tree mutation methods to obj
+func (n *JSNode) defineMutationMembers(obj *goja.Object) {
+	obj.Set("appendChild", func(call goja.FunctionCall) goja.Value {
+		child := nodeOf(call.Argument(0))
+		if child == nil {
+			panic(n.vm.NewTypeError("appendChild: the argument is not a node"))
+		}
+		n.insertNodes(n.node, nil, []*realdom.Node{child})
+		return call.Argument(0)
+	})
+	obj.Set("removeChild", func(call goja.FunctionCall) goja.Value {
+		child := nodeOf(call.Argument(0))
+		if child == nil || child.Parent != n.node {
+			panic(webapi.DOMException(n.vm, "NotFoundError", "The node to be removed is not a child of this node"))
+		}
+		n.node.RemoveChild(child)
+		n.childListChanged(n.node, nil)
+		return call.Argument(0)
+	})
+
+	obj.Set("remove", func(goja.FunctionCall) goja.Value {
+		if parent := n.node.Parent; parent != nil {
+			parent.RemoveChild(n.node)
+			n.childListChanged(parent, nil)
+		}
+		return goja.Undefined()
+	})
+	obj.Set("before", func(call goja.FunctionCall) goja.Value {
+		parent := n.node.Parent
+		if parent == nil {
+			return goja.Undefined()
+		}
+		nodes := n.nodesFromArgs(call.Arguments)
+		// Insert after the closest previous sibling that isn't being moved
+		prev := n.node.PreviousSibling()
+		for prev != nil && containsNode(nodes, prev) {
+			prev = prev.PreviousSibling()
+		}
+		ref := parent.FirstChild()
+		if prev != nil {
+			ref = prev.NextSibling()
+		}
+		n.insertNodes(parent, ref, nodes)
+		return goja.Undefined()
+	})
+	obj.Set("after", func(call goja.FunctionCall) goja.Value {
+		parent := n.node.Parent
+		if parent == nil {
+			return goja.Undefined()
+		}
+		n.insertNodes(parent, n.node.NextSibling(), n.nodesFromArgs(call.Arguments))
+		return goja.Undefined()
+	})
+	obj.Set("replaceWith", func(call goja.FunctionCall) goja.Value {
+		parent := n.node.Parent
+		if parent == nil {
+			return goja.Undefined()
+		}
+		nodes := n.nodesFromArgs(call.Arguments)
+		ref := n.node.NextSibling()
+		if !containsNode(nodes, n.node) {
+			parent.RemoveChild(n.node)
+		}
+		n.insertNodes(parent, ref, nodes)
+		return goja.Undefined()
+	})
+	obj.Set("append", func(call goja.FunctionCall) goja.Value {
+		n.insertNodes(n.node, nil, n.nodesFromArgs(call.Arguments))
+		return goja.Undefined()
+	})
+	obj.Set("prepend", func(call goja.FunctionCall) goja.Value {
+		n.insertNodes(n.node, n.node.FirstChild(), n.nodesFromArgs(call.Arguments))
+		return goja.Undefined()
+	})
+
+	obj.Set("insertAdjacentHTML", func(call goja.FunctionCall) goja.Value {
+		parent, ref := n.adjacent(call.Argument(0).String())
+		if parent == nil {
+			panic(webapi.DOMException(n.vm, "NoModificationAllowedError", "The element has no parent"))
+		}
+		n.insertNodes(parent, ref, realdom.ParseFragment(call.Argument(1).String()))
+		return goja.Undefined()
+	})
+	obj.Set("insertAdjacentElement", func(call goja.FunctionCall) goja.Value {
+		element := nodeOf(call.Argument(1))
+		if element == nil || element.Type != realdom.NodeElement {
+			panic(n.vm.NewTypeError("insertAdjacentElement: the argument is not an element"))
+		}
+		parent, ref := n.adjacent(call.Argument(0).String())
+		if parent == nil {
+			return goja.Null()
+		}
+		n.insertNodes(parent, ref, []*realdom.Node{element})
+		return call.Argument(1)
+	})
+	obj.Set("insertAdjacentText", func(call goja.FunctionCall) goja.Value {
+		if parent, ref := n.adjacent(call.Argument(0).String()); parent != nil {
+			n.insertNodes(parent, ref, []*realdom.Node{realdom.NewText(call.Argument(1).String())})
+		}
+		return goja.Undefined()
+	})
+
+	obj.Set("matches", func(call goja.FunctionCall) goja.Value {
+		return n.vm.ToValue(matchesAny(n.node, n.selectors(call.Argument(0).String())))
+	})
+	obj.Set("closest", func(call goja.FunctionCall) goja.Value {
+		selectors := n.selectors(call.Argument(0).String())
+		for node := n.node; node != nil; node = node.Parent {
+			if matchesAny(node, selectors) {
+				return NewJSNode(node, n.vm).ToJSObject()
+			}
+		}
+		return goja.Null()
+	})
+}
+
+// insertNodes moves nodes, in order, into parent before ref, or to its end
+// when ref is nil; a ref that is itself moved gives way to its next sibling
+// that isn't. A document fragment is replaced by its children, which leave
+// it empty. Moving an ancestor of parent into it is an error.
+func (n *JSNode) insertNodes(parent, ref *realdom.Node, nodes []*realdom.Node) {
+	nodes = expandFragments(nodes)
+	for _, node := range nodes {
+		if node == parent || node.Contains(parent) {
+			panic(webapi.DOMException(n.vm, "HierarchyRequestError", "The new child contains the parent"))
+		}
+	}
+	for ref != nil && containsNode(nodes, ref) {
+		ref = ref.NextSibling()
+	}
+	for _, node := range nodes {
+		if old := node.Parent; old != nil {
+			old.RemoveChild(node)
+			if old != parent {
+				n.childListChanged(old, nil)
+			}
+		}
+		if ref == nil || !parent.InsertBefore(node, ref) {
+			parent.AppendChild(node)
+		}
+	}
+	if len(nodes) > 0 {
+		n.childListChanged(parent, nodes)
+	}
+}
+
+// expandFragments replaces the document fragments among nodes with their
+// children, emptying them
+func expandFragments(nodes []*realdom.Node) []*realdom.Node {
+	var out []*realdom.Node
+	for _, node := range nodes {
+		if node.Type != realdom.NodeDocumentFragment {
+			out = append(out, node)
+			continue
+		}
+		for _, child := range node.Children {
+			child.Parent = nil
+		}
+		out = append(out, node.Children...)
+		node.Children = []*realdom.Node{}
+	}
+	return out
+}
+
+// nodesFromArgs turns the arguments of append, before and the like into
+// nodes: strings become text nodes
+func (n *JSNode) nodesFromArgs(args []goja.Value) []*realdom.Node {
+	nodes := make([]*realdom.Node, 0, len(args))
+	for _, arg := range args {
+		if node := nodeOf(arg); node != nil {
+			nodes = append(nodes, node)
+		} else {
+			nodes = append(nodes, realdom.NewText(arg.String()))
+		}
+	}
+	return nodes
+}
+
+// adjacent returns where insertAdjacent* puts nodes for position: before
+// ref in parent, or at its end when ref is nil. parent is nil when the
+// position is outside the element and it has no parent, or the document.
+func (n *JSNode) adjacent(position string) (parent, ref *realdom.Node) {
+	switch strings.ToLower(position) {
+	case "beforebegin":
+		if p := n.node.Parent; p != nil && p.Type != realdom.NodeDocument {
+			return p, n.node
+		}
+		return nil, nil
+	case "afterbegin":
+		return n.node, n.node.FirstChild()
+	case "beforeend":
+		return n.node, nil
+	case "afterend":
+		if p := n.node.Parent; p != nil && p.Type != realdom.NodeDocument {
+			return p, n.node.NextSibling()
+		}
+		return nil, nil
+	}
+	panic(webapi.DOMException(n.vm, "SyntaxError", "'"+position+"' is not beforebegin, afterbegin, beforeend or afterend"))
+}
+
+// selectors parses a selector list for matches and closest; a list that
+// selects nothing is a SyntaxError, as in browsers
+func (n *JSNode) selectors(text string) []css.Selector {
+	selectors := css.ParseSelectors(text)
+	if len(selectors) == 0 {
+		panic(webapi.DOMException(n.vm, "SyntaxError", "'"+text+"' is not a valid selector"))
+	}
+	return selectors
+}
+
+func containsNode(nodes []*realdom.Node, node *realdom.Node) bool {
+	for _, candidate := range nodes {
+		if candidate == node {
+			return true
+		}
+	}
+	return false
+}
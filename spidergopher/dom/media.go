@@ -0,0 +1,135 @@
+package dom
+
+import (
+	"errors"
+	"math"
+
+	realdom "go-browser/dom"
+	"go-browser/spidergopher/webapi"
+
+	"github.com/dop251/goja"
+)
+
+// MediaState is the playback state of an <audio> or <video> element
+type MediaState struct {
+	Paused      bool
+	Ended       bool
+	Muted       bool
+	CurrentTime float64 // seconds
+	Duration    float64 // seconds; NaN until the media has loaded
+	Volume      float64 // from 0 to 1
+}
+
+// MediaError is why media could not play; Name is the DOMException name
+// play() rejects with, such as NotAllowedError
+type MediaError struct {
+	Name    string
+	Message string
+}
+
+func (e *MediaError) Error() string {
+	return e.Message
+}
+
+// MediaHandler plays the <audio> and <video> elements of one runtime's page
+type MediaHandler interface {
+	PlayMedia(node *realdom.Node) error
+	PauseMedia(node *realdom.Node)
+	MediaState(node *realdom.Node) MediaState
+	SeekMedia(node *realdom.Node, seconds float64)
+	SetMediaMuted(node *realdom.Node, muted bool)
+	SetMediaVolume(node *realdom.Node, volume float64)
+}
+
+// mediaHandlers holds the media handler of each runtime
+var mediaHandlers perRuntime[MediaHandler]
+
+// SetMediaHandler registers h to play the media elements of vm. Passing nil
+// removes it.
+func SetMediaHandler(vm *goja.Runtime, h MediaHandler) {
+	if h == nil {
+		mediaHandlers.remove(vm)
+		return
+	}
+	mediaHandlers.set(vm, h)
+}
+
+// isMediaElement reports whether node is an <audio> or <video> element
+func isMediaElement(node *realdom.Node) bool {
+	return node.Type == realdom.NodeElement && (node.Tag == "audio" || node.Tag == "video")
+}
+
+// defineMediaMembers adds play(), pause() and the playback properties of
+// HTMLMediaElement to obj
+func (n *JSNode) defineMediaMembers(obj *goja.Object) {
+	state := func() MediaState {
+		if h := mediaHandlers.get(n.vm); h != nil {
+			return h.MediaState(n.node)
+		}
+		return MediaState{Paused: true, Duration: math.NaN(), Volume: 1}
+	}
+	accessor := func(name string, get func(MediaState) any, set func(h MediaHandler, v goja.Value)) {
+		getter := n.vm.ToValue(func(goja.FunctionCall) goja.Value { return n.vm.ToValue(get(state())) })
+		setter := goja.Undefined()
+		if set != nil {
+			setter = n.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+				if h := mediaHandlers.get(n.vm); h != nil {
+					set(h, call.Argument(0))
+				}
+				return goja.Undefined()
+			})
+		}
+		obj.DefineAccessorProperty(name, getter, setter, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	}
+
+	obj.Set("play", n.playMedia)
+	obj.Set("pause", func() {
+		if h := mediaHandlers.get(n.vm); h != nil {
+			h.PauseMedia(n.node)
+		}
+	})
+	accessor("paused", func(s MediaState) any { return s.Paused }, nil)
+	accessor("ended", func(s MediaState) any { return s.Ended }, nil)
+	accessor("duration", func(s MediaState) any { return s.Duration }, nil)
+	accessor("currentTime", func(s MediaState) any { return s.CurrentTime }, func(h MediaHandler, v goja.Value) {
+		h.SeekMedia(n.node, v.ToFloat())
+	})
+	accessor("muted", func(s MediaState) any { return s.Muted }, func(h MediaHandler, v goja.Value) {
+		h.SetMediaMuted(n.node, v.ToBoolean())
+	})
+	accessor("volume", func(s MediaState) any { return s.Volume }, func(h MediaHandler, v goja.Value) {
+		h.SetMediaVolume(n.node, math.Max(0, math.Min(1, v.ToFloat())))
+	})
+	obj.Set("src", n.node.GetAttr("src"))
+	obj.Set("autoplay", n.node.HasAttr("autoplay"))
+	obj.Set("loop", n.node.HasAttr("loop"))
+	obj.Set("controls", n.node.HasAttr("controls"))
+}
+
+// playMedia implements element.play(): the promise resolves once playback
+// starts and rejects with a DOMException when it can't
+func (n *JSNode) playMedia() *goja.Promise {
+	promise, resolve, reject := n.vm.NewPromise()
+	h := mediaHandlers.get(n.vm)
+	if h == nil {
+		reject(webapi.DOMException(n.vm, "NotSupportedError", "Media playback is not supported"))
+		return promise
+	}
+	if err := h.PlayMedia(n.node); err != nil {
+		var mediaErr *MediaError
+		if errors.As(err, &mediaErr) {
+			reject(webapi.DOMException(n.vm, mediaErr.Name, mediaErr.Message))
+		} else {
+			reject(webapi.DOMException(n.vm, "NotSupportedError", err.Error()))
+		}
+		return promise
+	}
+	resolve(goja.Undefined())
+	return promise
+}
+
+// DispatchMediaEvent fires a media event such as play, pause or ended at
+// node; media events don't bubble
+func DispatchMediaEvent(node *realdom.Node, vm *goja.Runtime, eventType string) {
+	NewJSNode(node, vm).dispatchEvent(eventType)
+}
@@ -0,0 +1,155 @@
+package dom
+
+import (
+	realdom "go-browser/dom"
+
+	"github.com/dop251/goja"
+)
+
+// ======================================================================================
+// REFLECTED ATTRIBUTES
+// id, className, title, href, src and value read and write the node's
+// attributes when they're used rather than when the node is wrapped, going
+// through setAttr so the browser restyles and relays out. href and src read
+// back resolved against the document's base URL; value reads what the user
+// typed into a form control, through the page's FormValueHandler.
+// ======================================================================================
+
+// FormValueHandler holds the values of one runtime's form controls: what
+// the user typed into an <input> or <textarea>, or picked in a <select>
+type FormValueHandler interface {
+	FormValue(node *realdom.Node) (value string, ok bool)
+	SetFormValue(node *realdom.Node, value string)
+}
+
+// formValueHandlers holds the form value handler of each runtime
+var formValueHandlers perRuntime[FormValueHandler]
+
+// SetFormValueHandler registers h to hold the form values of vm. Passing
+// nil removes it.
+func SetFormValueHandler(vm *goja.Runtime, h FormValueHandler) {
+	if h == nil {
+		formValueHandlers.remove(vm)
+		return
+	}
+	formValueHandlers.set(vm, h)
+}
+
+// urlAttributes lists the elements whose href or src attribute is a URL
+var urlAttributes = map[string]string{
+	"a": "href", "area": "href", "link": "href", "base": "href",
+	"img": "src", "script": "src", "iframe": "src", "frame": "src", "embed": "src",
+	"audio": "src", "video": "src", "source": "src", "track": "src", "input": "src",
+}
+
+// defineReflectedProperties adds the attribute-reflecting properties of the
+// element's tag to obj
+func (n *JSNode) defineReflectedProperties(obj *goja.Object) {
+	if n.node.Type != realdom.NodeElement {
+		return
+	}
+	n.reflect(obj, "id", "id")
+	n.reflect(obj, "className", "class")
+	n.reflect(obj, "title", "title")
+
+	if attr, ok := urlAttributes[n.node.Tag]; ok {
+		obj.DefineAccessorProperty(attr,
+			n.vm.ToValue(func(goja.FunctionCall) goja.Value {
+				if !n.node.HasAttr(attr) {
+					return n.vm.ToValue("")
+				}
+				value := n.node.GetAttr(attr)
+				if doc := n.node.OwnerDocument(); doc != nil && doc.BaseURL != "" {
+					value = doc.ResolveURL(value)
+				}
+				return n.vm.ToValue(value)
+			}),
+			n.setter(attr),
+			goja.FLAG_FALSE, goja.FLAG_TRUE)
+	}
+
+	switch n.node.Tag {
+	case "input", "textarea", "select", "option", "button":
+		obj.DefineAccessorProperty("value",
+			n.vm.ToValue(func(goja.FunctionCall) goja.Value { return n.vm.ToValue(n.value()) }),
+			n.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+				n.setValue(call.Argument(0).String())
+				return goja.Undefined()
+			}),
+			goja.FLAG_FALSE, goja.FLAG_TRUE)
+	}
+}
+
+// reflect defines prop as the string value of the attribute attr
+func (n *JSNode) reflect(obj *goja.Object, prop, attr string) {
+	obj.DefineAccessorProperty(prop,
+		n.vm.ToValue(func(goja.FunctionCall) goja.Value { return n.vm.ToValue(n.node.GetAttr(attr)) }),
+		n.setter(attr),
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+}
+
+// setter returns a property setter that writes the attribute attr
+func (n *JSNode) setter(attr string) goja.Value {
+	return n.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+		n.setAttr(attr, call.Argument(0).String())
+		return goja.Undefined()
+	})
+}
+
+// value is the control's current value: what the user entered, if the
+// browser holds one, or else what the markup gives it
+func (n *JSNode) value() string {
+	if h := formValueHandlers.get(n.vm); h != nil && n.node.Tag != "option" {
+		if value, ok := h.FormValue(n.node); ok {
+			return value
+		}
+	}
+	switch n.node.Tag {
+	case "textarea":
+		return n.node.TextContent()
+	case "select":
+		options := n.node.GetElementsByTagName("option")
+		for _, option := range options {
+			if option.HasAttr("selected") {
+				return optionValue(option)
+			}
+		}
+		if len(options) > 0 {
+			return optionValue(options[0])
+		}
+		return ""
+	case "option":
+		return optionValue(n.node)
+	}
+	return n.node.GetAttr("value")
+}
+
+// setValue sets the control's value, both what the browser shows and what
+// the markup says
+func (n *JSNode) setValue(value string) {
+	if h := formValueHandlers.get(n.vm); h != nil && n.node.Tag != "option" {
+		h.SetFormValue(n.node, value)
+	}
+	switch n.node.Tag {
+	case "textarea":
+		n.setTextContent(value)
+	case "select":
+		for _, option := range n.node.GetElementsByTagName("option") {
+			if optionValue(option) == value {
+				NewJSNode(option, n.vm).setAttr("selected", "")
+			} else if option.HasAttr("selected") {
+				NewJSNode(option, n.vm).removeAttr("selected")
+			}
+		}
+	default:
+		n.setAttr("value", value)
+	}
+}
+
+// optionValue is an <option>'s value attribute, or its text without one
+func optionValue(option *realdom.Node) string {
+	if option.HasAttr("value") {
+		return option.GetAttr("value")
+	}
+	return option.TextContent()
+}
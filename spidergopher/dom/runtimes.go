@@ -0,0 +1,72 @@
+package dom
+
+import (
+	"sync"
+
+	"github.com/dop251/goja"
+)
+
+// ======================================================================================
+// PER-RUNTIME STATE
+// Each page's runtime has its own observers, handlers and listeners. Its
+// event loop goroutine reads them while the browser starts and stops other
+// pages from its own, so every access goes through a lock.
+// ======================================================================================
+
+// perRuntime holds a value for each goja runtime
+type perRuntime[T any] struct {
+	mu     sync.RWMutex
+	values map[*goja.Runtime]T
+}
+
+// get returns vm's value, or the zero value when it has none
+func (p *perRuntime[T]) get(vm *goja.Runtime) T {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.values[vm]
+}
+
+// set gives vm the value v
+func (p *perRuntime[T]) set(vm *goja.Runtime, v T) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.values == nil {
+		p.values = make(map[*goja.Runtime]T)
+	}
+	p.values[vm] = v
+}
+
+// remove drops vm's value
+func (p *perRuntime[T]) remove(vm *goja.Runtime) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.values, vm)
+}
+
+// read calls fn with vm's value, holding the lock so fn may look into what
+// the value refers to
+func (p *perRuntime[T]) read(vm *goja.Runtime, fn func(T)) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	fn(p.values[vm])
+}
+
+// update replaces vm's value with what fn makes of it, holding the lock so
+// fn may also change what the value refers to, such as a map
+func (p *perRuntime[T]) update(vm *goja.Runtime, fn func(T) T) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.values == nil {
+		p.values = make(map[*goja.Runtime]T)
+	}
+	p.values[vm] = fn(p.values[vm])
+}
+
+// each calls fn with the value of every runtime, holding the lock
+func (p *perRuntime[T]) each(fn func(vm *goja.Runtime, v T)) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	for vm, v := range p.values {
+		fn(vm, v)
+	}
+}
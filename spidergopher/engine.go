@@ -1,6 +1,9 @@
 package spidergopher
 
 import (
+	"net/http"
+	"sync/atomic"
+
 	realdom "go-browser/dom"
 	"go-browser/spidergopher/core"
 	"go-browser/spidergopher/dom"
@@ -11,10 +14,49 @@ import (
 
 // Engine is the main entry point for the SpiderGopher JS environment.
 type Engine struct {
-	Loop      *core.EventLoop
-	Window    *dom.Window
-	vm        *goja.Runtime
-	domBridge *dom.DOMBridge
+	Loop       *core.EventLoop
+	Window     *dom.Window
+	Activation *webapi.UserActivation // the user's clicks and key presses on the page
+	Console    *webapi.Console        // the page's console; OnMessage sees what it logs
+	vm         *goja.Runtime
+	domBridge  *dom.DOMBridge
+	doc        *realdom.Document
+
+	scriptURLs []string     // URLs of the scripts being run, innermost last
+	client     *http.Client // the embedder's client the page's requests go through; nil for net/http's
+
+	localStore   *webapi.LocalStore // the embedder's, where localStorage is kept
+	localStorage *goja.Object       // the page's localStorage, once read
+
+	openWindow    func(url string) // opens popups window.open allows
+	readClipboard func() string    // reads the system clipboard
+
+	fullscreen   *realdom.Node       // element shown fullscreen, nil when none
+	onFullscreen func(*realdom.Node) // shows the fullscreen element
+
+	media dom.MediaHandler // the browser's player for <audio> and <video>
+
+	modals  []*realdom.Node     // modal dialogs shown, topmost last
+	onModal func(*realdom.Node) // shows the topmost modal dialog
+
+	windowObj  *goja.Object      // the page's window object
+	viewport   viewportState     // the page's viewport size, scroll position and focus
+	mediaLists []*mediaQueryList // matchMedia lists with change listeners
+	onError    func(ScriptError) // hears about errors the page didn't handle
+	rejections []*goja.Promise   // promises rejected without a handler yet
+	reporting  bool              // an error is being offered to the page's handlers
+
+	workers []*worker // workers the page started and hasn't terminated
+	stopped bool      // Stop has run
+}
+
+// liveEngines counts the engines created and not yet stopped
+var liveEngines atomic.Int64
+
+// LiveEngines returns how many engines are running: one per page shown,
+// more if pages are leaking
+func LiveEngines() int64 {
+	return liveEngines.Load()
 }
 
 // NewEngine creates a new SpiderGopher engine.
@@ -24,20 +66,36 @@ func NewEngine() *Engine {
 	window := dom.NewWindow()
 
 	engine := &Engine{
-		Loop:   loop,
-		Window: window,
-		vm:     vm,
+		Loop:       loop,
+		Window:     window,
+		Activation: webapi.NewUserActivation(),
+		Console:    webapi.NewConsole(),
+		vm:         vm,
 	}
 
 	engine.setupGlobalEnv()
+	engine.setupErrorReporting()
+	dom.SetFullscreenHandler(vm, engine)
+	dom.SetMediaHandler(vm, engine)
+	dom.SetDialogHandler(vm, engine)
+	liveEngines.Add(1)
 	return engine
 }
 
-// SetDOM connects the engine to a real DOM tree
-func (e *Engine) SetDOM(root *realdom.Node) {
-	e.domBridge = dom.NewDOMBridge(root, e.vm)
+// SetDocument connects the engine to a real document
+func (e *Engine) SetDocument(doc *realdom.Document) {
+	e.doc = doc
+	e.domBridge = dom.NewDOMBridge(doc, e.vm)
 	// Update the document object in JS
-	e.vm.Set("document", e.domBridge.GetDocumentObject())
+	document := e.domBridge.GetDocumentObject()
+	document.Set("hasFocus", func(goja.FunctionCall) goja.Value { return e.vm.ToValue(e.hasFocus()) })
+	e.vm.Set("document", document)
+}
+
+// SetClient makes the page's requests, its scripts' and fetch()'s and its
+// workers', go through client. Call it before the page's scripts run.
+func (e *Engine) SetClient(client *http.Client) {
+	e.client = client
 }
 
 // Start begins the event loop.
@@ -45,16 +103,78 @@ func (e *Engine) Start() {
 	e.Loop.Start()
 }
 
-// Stop halts the event loop.
+// Stop halts the event loop and the page's workers, and drops what the
+// page registered, so the engine can be collected.
 func (e *Engine) Stop() {
+	if e.stopped {
+		return
+	}
+	e.stopped = true
+	liveEngines.Add(-1)
+	for _, w := range e.workers {
+		w.scope.stop()
+	}
+	e.workers = nil
 	e.Loop.Stop()
+	dom.SetAttributeObserver(e.vm, nil)
+	dom.SetTitleObserver(e.vm, nil)
+	dom.SetChildListObserver(e.vm, nil)
+	dom.SetFormValueHandler(e.vm, nil)
+	dom.SetFullscreenHandler(e.vm, nil)
+	dom.SetMediaHandler(e.vm, nil)
+	dom.SetCanvasHandler(e.vm, nil)
+	dom.SetDialogHandler(e.vm, nil)
+	dom.SetErrorReporter(e.vm, nil)
+	dom.ClearListeners(e.vm)
+}
+
+// ListenerCount returns how many listeners the page's scripts have added to
+// its elements
+func (e *Engine) ListenerCount() int {
+	return dom.ListenerCount(e.vm)
+}
+
+// LiveListeners returns how many element listeners the pages of every
+// running engine hold
+func LiveListeners() int {
+	return dom.ListenerCount(nil)
 }
 
 // Run executes a script synchronously.
 // For the initial page script, this runs directly since we're on the main thread.
 // For async callbacks, the EventLoop handles scheduling.
 func (e *Engine) Run(script string) (goja.Value, error) {
-	return e.vm.RunString(script)
+	value, err := e.vm.RunString(script)
+	e.Loop.PerformMicrotaskCheckpoint()
+	return value, err
+}
+
+// OnAttributeChanged registers a callback for attribute changes made by scripts
+func (e *Engine) OnAttributeChanged(fn dom.AttributeChangedFunc) {
+	dom.SetAttributeObserver(e.vm, fn)
+}
+
+// OnChildListChanged registers a callback for scripts adding or removing
+// nodes
+func (e *Engine) OnChildListChanged(fn dom.ChildListChangedFunc) {
+	dom.SetChildListObserver(e.vm, fn)
+}
+
+// OnTitleChanged registers a callback for scripts changing the document's
+// title
+func (e *Engine) OnTitleChanged(fn dom.TitleChangedFunc) {
+	dom.SetTitleObserver(e.vm, fn)
+}
+
+// OnFormValues registers the browser's values of the page's form controls
+func (e *Engine) OnFormValues(h dom.FormValueHandler) {
+	dom.SetFormValueHandler(e.vm, h)
+}
+
+// OnCanvas registers the browser's drawing surfaces for the page's <canvas>
+// elements
+func (e *Engine) OnCanvas(h dom.CanvasHandler) {
+	dom.SetCanvasHandler(e.vm, h)
 }
 
 // GetVM returns the Goja runtime for external use
@@ -66,20 +186,14 @@ func (e *Engine) setupGlobalEnv() {
 	// Register global objects
 
 	// Console
-	console := webapi.NewConsole()
-	consoleObj := e.vm.NewObject()
-	consoleObj.Set("log", console.Log)
-	consoleObj.Set("warn", console.Warn)
-	consoleObj.Set("error", console.Error)
-	e.vm.Set("console", consoleObj)
+	e.Console.SetVM(e.vm)
+	e.vm.Set("console", consoleObject(e.vm, e.Console))
+
+	// Scripts loading scripts relative to their own URL
+	e.vm.Set("importScripts", e.importScripts)
 
 	// Timers
-	timers := webapi.NewTimers(e.Loop)
-	timers.SetVM(e.vm)
-	e.vm.Set("setTimeout", timers.SetTimeout)
-	e.vm.Set("clearTimeout", timers.ClearTimeout)
-	e.vm.Set("setInterval", timers.SetInterval)
-	e.vm.Set("clearInterval", timers.ClearInterval)
+	installTimers(e.vm, e.Loop)
 
 	// Document - explicitly set methods with lowercase names for JS compatibility
 	doc := e.Window.Document
@@ -157,6 +271,17 @@ func (e *Engine) setupGlobalEnv() {
 		}
 		return vm.ToValue(false)
 	})
+	windowObj.Set("open", e.windowOpen)
+	windowObj.Set("matchMedia", e.matchMedia)
+	windowObj.Set("onload", goja.Null())
+	windowObj.Set("onerror", goja.Null())
+	windowObj.Set("onunhandledrejection", goja.Null())
+	for _, name := range []string{"onresize", "onscroll", "onfocus", "onblur"} {
+		windowObj.Set(name, goja.Null())
+	}
+	e.defineViewportProperties(windowObj)
+	e.defineViewportProperties(e.vm.GlobalObject())
+	e.windowObj = windowObj
 	windowObj.Set("document", documentObj)
 	e.vm.Set("window", windowObj)
 
@@ -167,14 +292,73 @@ func (e *Engine) setupGlobalEnv() {
 	e.vm.Set("addEventListener", windowObj.Get("addEventListener"))
 	e.vm.Set("removeEventListener", windowObj.Get("removeEventListener"))
 	e.vm.Set("dispatchEvent", windowObj.Get("dispatchEvent"))
+	e.vm.Set("open", windowObj.Get("open"))
+	e.vm.Set("matchMedia", windowObj.Get("matchMedia"))
 
-	// Fetch API
+	// Navigator, with the APIs gated on user activation
+	navigator := e.navigatorObject()
+	windowObj.Set("navigator", navigator)
+	e.vm.Set("navigator", navigator)
+
+	// Fetch API, relative to the document
 	fetchAPI := webapi.NewFetchAPI(e.Loop, e.vm)
-	e.vm.Set("fetch", fetchAPI.Fetch)
+	fetchAPI.SetURLs(func() (string, string) {
+		if e.doc == nil {
+			return "", ""
+		}
+		return e.doc.BaseURL, e.doc.URL
+	})
+	fetchAPI.SetClient(func() *http.Client { return e.client })
+	installFetch(e.vm, fetchAPI)
+
+	// Workers, each with a runtime and event loop of its own
+	e.vm.Set("Worker", e.newWorker)
+
+	// localStorage, opened when first read
+	e.defineLocalStorage(windowObj)
+	e.defineLocalStorage(e.vm.GlobalObject())
+}
+
+// consoleObject creates the console object of vm, logging to console
+func consoleObject(vm *goja.Runtime, console *webapi.Console) *goja.Object {
+	obj := vm.NewObject()
+	obj.Set("log", console.Log)
+	obj.Set("info", console.Info)
+	obj.Set("debug", console.Debug)
+	obj.Set("warn", console.Warn)
+	obj.Set("error", console.Error)
+	obj.Set("trace", console.Trace)
+	obj.Set("assert", console.Assert)
+	obj.Set("group", console.Group)
+	obj.Set("groupCollapsed", console.Group)
+	obj.Set("groupEnd", console.GroupEnd)
+	obj.Set("time", console.Time)
+	obj.Set("timeLog", console.TimeLog)
+	obj.Set("timeEnd", console.TimeEnd)
+	obj.Set("table", console.Table)
+	return obj
+}
+
+// installFetch adds fetch() and the constructors of its requests' parts to
+// vm's globals
+func installFetch(vm *goja.Runtime, fetchAPI *webapi.FetchAPI) {
+	vm.Set("fetch", fetchAPI.Fetch)
+	vm.Set("Headers", fetchAPI.NewHeaders)
+	vm.Set("URLSearchParams", fetchAPI.NewURLSearchParams)
+	vm.Set("FormData", fetchAPI.NewFormData)
+	vm.Set("AbortController", fetchAPI.NewAbortController)
+}
 
-	// NOTE: Storage APIs are disabled for now due to SQLite init blocking the event loop.
-	// They will be initialized lazily when first accessed.
-	// TODO: Implement lazy initialization within the event loop context
+// installTimers adds the timer functions and queueMicrotask, running on
+// loop, to vm's globals
+func installTimers(vm *goja.Runtime, loop *core.EventLoop) {
+	timers := webapi.NewTimers(loop)
+	timers.SetVM(vm)
+	vm.Set("setTimeout", timers.SetTimeout)
+	vm.Set("clearTimeout", timers.ClearTimeout)
+	vm.Set("setInterval", timers.SetInterval)
+	vm.Set("clearInterval", timers.ClearInterval)
+	vm.Set("queueMicrotask", timers.QueueMicrotask)
 }
 
 // Wait blocks until the loop stops (if ever) or just for a duration?
@@ -0,0 +1,236 @@
+package spidergopher
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go-browser/spidergopher/dom"
+
+	"github.com/dop251/goja"
+)
+
+// ======================================================================================
+// UNCAUGHT ERRORS
+// What a page's scripts throw and don't catch, in a script, an event listener,
+// a timer or a fetch callback, and the promises they reject without a handler,
+// are offered to window.onerror / onunhandledrejection and their events first.
+// Unless the page cancels them they are logged to the console and handed to
+// the browser with their file and line.
+// ======================================================================================
+
+// ScriptError is an error a page's script didn't handle
+type ScriptError struct {
+	Message   string // e.g. "Uncaught TypeError: x is not a function"
+	Source    string // URL of the script it was thrown in; empty when unknown
+	Line      int    // 1-based; 0 when unknown
+	Column    int
+	Stack     string // JS stack trace, when there is one
+	Rejection bool   // a promise rejected without a handler rather than a throw
+	Syntax    bool   // the script didn't parse, so none of it ran
+}
+
+// Location returns "source:line:column", or "" when the source is unknown
+func (s ScriptError) Location() string {
+	if s.Source == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d:%d", s.Source, s.Line, s.Column)
+}
+
+// String returns the message followed by the location, if known
+func (s ScriptError) String() string {
+	if loc := s.Location(); loc != "" {
+		return s.Message + " (" + loc + ")"
+	}
+	return s.Message
+}
+
+// OnError registers a callback for the errors the page's scripts didn't
+// handle. It may be called from the event loop's goroutine.
+func (e *Engine) OnError(fn func(ScriptError)) {
+	e.onError = fn
+}
+
+// setupErrorReporting routes the errors of event listeners and loop jobs to
+// ReportError and starts tracking rejected promises
+func (e *Engine) setupErrorReporting() {
+	dom.SetErrorReporter(e.vm, e.ReportError)
+	e.Loop.SetErrorHandler(e.ReportError)
+	e.Loop.SetAfterJob(e.reportRejections)
+	e.vm.SetPromiseRejectionTracker(func(p *goja.Promise, op goja.PromiseRejectionOperation) {
+		switch op {
+		case goja.PromiseRejectionReject:
+			e.rejections = append(e.rejections, p)
+		case goja.PromiseRejectionHandle:
+			for i, rejected := range e.rejections {
+				if rejected == p {
+					e.rejections = append(e.rejections[:i], e.rejections[i+1:]...)
+					break
+				}
+			}
+		}
+	})
+}
+
+// ReportError reports an error a script threw and didn't catch: it fires
+// window.onerror and the error event, then logs it unless the page
+// canceled it
+func (e *Engine) ReportError(err error) {
+	var interrupted *goja.InterruptedError
+	if err == nil || errors.As(err, &interrupted) {
+		return
+	}
+	report, thrown := e.scriptError(err)
+	if e.reporting {
+		// Thrown by an error handler itself: don't offer it to the page again
+		e.logError(report)
+		return
+	}
+	e.reporting = true
+	handled := e.dispatchError(report, thrown)
+	e.reporting = false
+	if !handled {
+		e.logError(report)
+	}
+}
+
+// scriptError describes err, returning the value the script threw with it
+func (e *Engine) scriptError(err error) (ScriptError, goja.Value) {
+	var syntax *SyntaxError
+	if errors.As(err, &syntax) {
+		thrown, _ := e.vm.New(e.vm.Get("SyntaxError"), e.vm.ToValue(syntax.Message))
+		return syntax.report(), thrown
+	}
+	var ex *goja.Exception
+	if !errors.As(err, &ex) {
+		return ScriptError{Message: "Uncaught " + err.Error(), Source: e.ScriptURL()}, e.vm.NewGoError(err)
+	}
+	return exceptionReport(ex), ex.Value()
+}
+
+// exceptionReport describes an uncaught exception, locating it at its
+// innermost script frame
+func exceptionReport(ex *goja.Exception) ScriptError {
+	report := ScriptError{Message: "Uncaught " + ex.Value().String()}
+	var stack bytes.Buffer
+	for _, frame := range ex.Stack() {
+		stack.WriteString("\tat ")
+		frame.Write(&stack)
+		stack.WriteByte('\n')
+		if report.Source == "" && frame.SrcName() != "<native>" {
+			pos := frame.Position()
+			report.Source, report.Line, report.Column = frame.SrcName(), pos.Line, pos.Column
+		}
+	}
+	report.Stack = strings.TrimSuffix(stack.String(), "\n")
+	return report
+}
+
+// dispatchError offers an error to window.onerror and the error event's
+// listeners. It returns true when the page canceled it.
+func (e *Engine) dispatchError(report ScriptError, thrown goja.Value) bool {
+	handled := false
+	if onerror, ok := goja.AssertFunction(e.eventHandler("onerror")); ok {
+		ret, err := onerror(e.windowObj, e.vm.ToValue(report.Message), e.vm.ToValue(report.Source),
+			e.vm.ToValue(report.Line), e.vm.ToValue(report.Column), thrown)
+		if err != nil {
+			e.ReportError(err)
+		}
+		handled = ret != nil && ret.ToBoolean()
+	}
+
+	event := dom.NewEvent("error")
+	event.Cancelable = true
+	event.Fields = map[string]interface{}{
+		"message":  report.Message,
+		"filename": report.Source,
+		"lineno":   report.Line,
+		"colno":    report.Column,
+		"error":    thrown,
+	}
+	return !e.Window.DispatchEvent(e.vm, event) || handled
+}
+
+// reportRejections reports the promises rejected since the last call that
+// still have no handler: it fires unhandledrejection for each, then logs
+// those the page didn't cancel
+func (e *Engine) reportRejections() {
+	rejections := e.rejections
+	e.rejections = nil
+	for _, p := range rejections {
+		if p.State() != goja.PromiseStateRejected {
+			continue
+		}
+		reason := p.Result()
+		report := rejectionError(reason)
+
+		event := dom.NewEvent("unhandledrejection")
+		event.Cancelable = true
+		event.Fields = map[string]interface{}{
+			"promise": e.vm.ToValue(p),
+			"reason":  reason,
+		}
+		eventObj := e.vm.ToValue(event.ToJSObject())
+		if handler, ok := goja.AssertFunction(e.eventHandler("onunhandledrejection")); ok {
+			if _, err := handler(e.windowObj, eventObj); err != nil {
+				e.ReportError(err)
+			}
+		}
+		if e.Window.DispatchEvent(e.vm, event) {
+			e.logError(report)
+		}
+	}
+}
+
+// stackLocation matches the first "file:line:column" of a JS stack trace
+var stackLocation = regexp.MustCompile(`\bat (?:[^\n(]* \()?([^\s()]+):(\d+):(\d+)`)
+
+// rejectionError describes a promise rejected with reason, locating it by
+// the reason's stack when it is an Error
+func rejectionError(reason goja.Value) ScriptError {
+	report := ScriptError{Message: "Uncaught (in promise) " + reason.String(), Rejection: true}
+	obj, ok := reason.(*goja.Object)
+	if !ok {
+		return report
+	}
+	if stack := obj.Get("stack"); stack != nil && !goja.IsUndefined(stack) {
+		// The stack starts with the error's message, which is already said
+		if i := strings.Index(stack.String(), "\tat "); i >= 0 {
+			report.Stack = strings.TrimRight(stack.String()[i:], "\n")
+		}
+		if m := stackLocation.FindStringSubmatch(report.Stack); m != nil {
+			report.Source = m[1]
+			report.Line, _ = strconv.Atoi(m[2])
+			report.Column, _ = strconv.Atoi(m[3])
+		}
+	}
+	return report
+}
+
+// eventHandler returns the window's on<event> handler property, which
+// scripts may have set on window or as a global
+func (e *Engine) eventHandler(name string) goja.Value {
+	if v := e.windowObj.Get(name); v != nil {
+		if _, ok := goja.AssertFunction(v); ok {
+			return v
+		}
+	}
+	return e.vm.Get(name)
+}
+
+// logError writes an unhandled error to the console and hands it to the
+// browser
+func (e *Engine) logError(report ScriptError) {
+	text := report.String()
+	if report.Stack != "" {
+		text += "\n" + report.Stack
+	}
+	e.Console.Emit("error", text)
+	if e.onError != nil {
+		e.onError(report)
+	}
+}
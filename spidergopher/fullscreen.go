@@ -0,0 +1,63 @@
+package spidergopher
+
+import (
+	"errors"
+
+	realdom "go-browser/dom"
+	"go-browser/spidergopher/dom"
+)
+
+// ======================================================================================
+// FULLSCREEN
+// element.requestFullscreen() asks the browser to show one element over the
+// whole window; like popups it needs, and uses up, a user gesture
+// ======================================================================================
+
+// OnFullscreenChange registers the browser's handler for the element shown
+// fullscreen changing; node is nil when the page leaves fullscreen
+func (e *Engine) OnFullscreenChange(fn func(node *realdom.Node)) {
+	e.onFullscreen = fn
+}
+
+// RequestFullscreen shows node fullscreen if the page has transient activation
+func (e *Engine) RequestFullscreen(node *realdom.Node) error {
+	if node == nil || node.Type != realdom.NodeElement {
+		return errors.New("only elements can be shown fullscreen")
+	}
+	if !e.Activation.Consume() {
+		return errors.New("requestFullscreen() needs a user gesture")
+	}
+	e.setFullscreen(node)
+	return nil
+}
+
+// ExitFullscreen leaves fullscreen; the browser calls it when the user
+// presses Escape
+func (e *Engine) ExitFullscreen() error {
+	if e.fullscreen == nil {
+		return errors.New("the document is not fullscreen")
+	}
+	e.setFullscreen(nil)
+	return nil
+}
+
+// FullscreenElement returns the element shown fullscreen, or nil
+func (e *Engine) FullscreenElement() *realdom.Node {
+	return e.fullscreen
+}
+
+// setFullscreen tells the browser node is the fullscreen element now and
+// fires fullscreenchange at the elements entering and leaving it
+func (e *Engine) setFullscreen(node *realdom.Node) {
+	old := e.fullscreen
+	e.fullscreen = node
+	if e.onFullscreen != nil {
+		e.onFullscreen(node)
+	}
+	if old != nil && old != node {
+		dom.DispatchFullscreenChange(old, e.vm)
+	}
+	if node != nil {
+		dom.DispatchFullscreenChange(node, e.vm)
+	}
+}
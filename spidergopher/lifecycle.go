@@ -0,0 +1,70 @@
+package spidergopher
+
+import (
+	"slices"
+
+	realdom "go-browser/dom"
+	"go-browser/spidergopher/dom"
+
+	"github.com/dop251/goja"
+)
+
+// ======================================================================================
+// DOCUMENT LIFECYCLE
+// document.readyState is loading while the page is parsed and its scripts
+// run. It turns interactive once they have, when DOMContentLoaded fires, and
+// complete once the page's images and stylesheets have loaded, when load
+// fires at the window. The browser's page load pipeline says when.
+// ======================================================================================
+
+// readyStates are the values of readyState, in the order they are reached
+var readyStates = []string{realdom.ReadyLoading, realdom.ReadyInteractive, realdom.ReadyComplete}
+
+// DocumentParsed tells the engine the page is parsed and its scripts have
+// run: readyState turns interactive and DOMContentLoaded fires
+func (e *Engine) DocumentParsed() {
+	e.Loop.Schedule(func() {
+		if e.setReadyState(realdom.ReadyInteractive) {
+			event := dom.NewEvent("DOMContentLoaded")
+			event.Bubbles = true
+			dom.DispatchDocumentEvent(e.doc, e.vm, event.Type)
+			e.Window.DispatchEvent(e.vm, event)
+		}
+	})
+}
+
+// DocumentLoaded tells the engine the page's images and stylesheets have
+// loaded: readyState turns complete and load fires at the window
+func (e *Engine) DocumentLoaded() {
+	e.Loop.Schedule(func() {
+		if !e.setReadyState(realdom.ReadyComplete) {
+			return
+		}
+		event := dom.NewEvent("load")
+		if handler, ok := goja.AssertFunction(e.eventHandler("onload")); ok {
+			if _, err := handler(e.windowObj, e.vm.ToValue(event.ToJSObject())); err != nil {
+				e.ReportError(err)
+			}
+		}
+		e.Window.DispatchEvent(e.vm, event)
+	})
+}
+
+// setReadyState moves readyState forward to state, firing readystatechange
+// at the document. It reports false when the document is there already.
+func (e *Engine) setReadyState(state string) bool {
+	if e.doc == nil || slices.Index(readyStates, state) <= slices.Index(readyStates, e.doc.ReadyState) {
+		return false
+	}
+	e.doc.ReadyState = state
+
+	document := e.vm.Get("document").ToObject(e.vm)
+	if handler, ok := goja.AssertFunction(document.Get("onreadystatechange")); ok {
+		event := dom.NewEvent("readystatechange")
+		if _, err := handler(document, e.vm.ToValue(event.ToJSObject())); err != nil {
+			e.ReportError(err)
+		}
+	}
+	dom.DispatchDocumentEvent(e.doc, e.vm, "readystatechange")
+	return true
+}
@@ -0,0 +1,149 @@
+package spidergopher
+
+import (
+	"slices"
+	"strings"
+
+	"go-browser/css"
+	"go-browser/spidergopher/dom"
+
+	"github.com/dop251/goja"
+)
+
+// ======================================================================================
+// MATCH MEDIA
+// window.matchMedia(query) returns a MediaQueryList whose matches evaluates
+// the query the way @media rules are, against the medium the browser last
+// gave with SetMedia. Lists with change listeners are kept, and fire change
+// when the query starts or stops matching.
+// ======================================================================================
+
+// mediaQueryList is one MediaQueryList matchMedia returned
+type mediaQueryList struct {
+	query    string
+	obj      *goja.Object
+	target   *dom.EventTarget
+	onchange goja.Value
+	matches  bool // whether the query matched when last evaluated
+}
+
+// SetMedia tells the engine the medium the page is shown on: its viewport,
+// screen and the user's color scheme. The page's media query lists are
+// evaluated again when it changes.
+func (e *Engine) SetMedia(m css.Media) {
+	v := &e.viewport
+	v.mu.Lock()
+	changed := m != v.media
+	v.media = m
+	v.mu.Unlock()
+	if changed {
+		e.Loop.Schedule(e.mediaChanged)
+	}
+}
+
+// currentMedia returns the medium queries are evaluated against: what the
+// browser last said, or until it has, the default one
+func (e *Engine) currentMedia() css.Media {
+	v := &e.viewport
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.media.Type == "" {
+		return css.DefaultMedia()
+	}
+	return v.media
+}
+
+// matchMedia is window.matchMedia
+func (e *Engine) matchMedia(call goja.FunctionCall) goja.Value {
+	list := &mediaQueryList{
+		query:  strings.ToLower(strings.TrimSpace(call.Argument(0).String())),
+		obj:    e.vm.NewObject(),
+		target: dom.NewEventTarget(),
+	}
+	list.obj.Set("media", list.query)
+	list.obj.DefineAccessorProperty("matches",
+		e.vm.ToValue(func(goja.FunctionCall) goja.Value {
+			return e.vm.ToValue(e.currentMedia().Match(list.query))
+		}),
+		nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	list.obj.DefineAccessorProperty("onchange",
+		e.vm.ToValue(func(goja.FunctionCall) goja.Value {
+			if list.onchange == nil {
+				return goja.Null()
+			}
+			return list.onchange
+		}),
+		e.vm.ToValue(func(call goja.FunctionCall) goja.Value {
+			list.onchange = nil
+			if _, ok := goja.AssertFunction(call.Argument(0)); ok {
+				list.onchange = call.Argument(0)
+			}
+			e.watchMedia(list)
+			return goja.Undefined()
+		}),
+		goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+	add := func(eventType string, listener goja.Value) {
+		if eventType == "change" && listener != nil && !goja.IsUndefined(listener) && !goja.IsNull(listener) {
+			list.target.AddEventListener(eventType, listener)
+			e.watchMedia(list)
+		}
+	}
+	remove := func(eventType string, listener goja.Value) {
+		list.target.RemoveEventListener(eventType, listener)
+		e.watchMedia(list)
+	}
+	list.obj.Set("addEventListener", func(call goja.FunctionCall) goja.Value {
+		add(call.Argument(0).String(), call.Argument(1))
+		return goja.Undefined()
+	})
+	list.obj.Set("removeEventListener", func(call goja.FunctionCall) goja.Value {
+		remove(call.Argument(0).String(), call.Argument(1))
+		return goja.Undefined()
+	})
+	// The older addListener and removeListener many libraries still use
+	list.obj.Set("addListener", func(call goja.FunctionCall) goja.Value {
+		add("change", call.Argument(0))
+		return goja.Undefined()
+	})
+	list.obj.Set("removeListener", func(call goja.FunctionCall) goja.Value {
+		remove("change", call.Argument(0))
+		return goja.Undefined()
+	})
+	return list.obj
+}
+
+// watchMedia keeps list among those evaluated when the medium changes while
+// it has change listeners, and drops it once it has none
+func (e *Engine) watchMedia(list *mediaQueryList) {
+	i := slices.Index(e.mediaLists, list)
+	listening := list.onchange != nil || list.target.HasEventListeners("change")
+	switch {
+	case listening && i < 0:
+		list.matches = e.currentMedia().Match(list.query)
+		e.mediaLists = append(e.mediaLists, list)
+	case !listening && i >= 0:
+		e.mediaLists = slices.Delete(e.mediaLists, i, i+1)
+	}
+}
+
+// mediaChanged fires change at the lists whose query started or stopped
+// matching
+func (e *Engine) mediaChanged() {
+	m := e.currentMedia()
+	for _, list := range slices.Clone(e.mediaLists) {
+		matches := m.Match(list.query)
+		if matches == list.matches {
+			continue
+		}
+		list.matches = matches
+		event := dom.NewEvent("change")
+		event.Fields = map[string]interface{}{"matches": matches, "media": list.query}
+		if handler, ok := goja.AssertFunction(list.onchange); ok {
+			if _, err := handler(list.obj, e.vm.ToValue(event.ToJSObject())); err != nil {
+				e.ReportError(err)
+			}
+		}
+		list.target.DispatchEvent(e.vm, event)
+	}
+}
@@ -0,0 +1,94 @@
+package spidergopher
+
+import (
+	"math"
+
+	realdom "go-browser/dom"
+	"go-browser/spidergopher/dom"
+)
+
+// ======================================================================================
+// MEDIA
+// The browser plays <audio> and <video>; the engine stands between it and
+// the page to apply the autoplay policy and fire the media events
+// ======================================================================================
+
+// OnMedia registers the browser's player for the page's media elements
+func (e *Engine) OnMedia(player dom.MediaHandler) {
+	e.media = player
+}
+
+// PlayMedia starts node playing. Audible media needs the user to have
+// interacted with the page first; muted media may always play.
+func (e *Engine) PlayMedia(node *realdom.Node) error {
+	if e.media == nil {
+		return &dom.MediaError{Name: "NotSupportedError", Message: "Media playback is not available"}
+	}
+	state := e.media.MediaState(node)
+	if !e.Activation.AutoplayAllowed(state.Muted) {
+		return &dom.MediaError{Name: "NotAllowedError", Message: "play() failed because the user didn't interact with the page first"}
+	}
+	if err := e.media.PlayMedia(node); err != nil {
+		return err
+	}
+	if state.Paused {
+		e.dispatchMediaEvents(node, "play")
+	}
+	return nil
+}
+
+// PauseMedia pauses node, firing pause if it was playing
+func (e *Engine) PauseMedia(node *realdom.Node) {
+	if e.media == nil || e.media.MediaState(node).Paused {
+		return
+	}
+	e.media.PauseMedia(node)
+	e.dispatchMediaEvents(node, "pause")
+}
+
+// MediaState returns the playback state of node
+func (e *Engine) MediaState(node *realdom.Node) dom.MediaState {
+	if e.media == nil {
+		return dom.MediaState{Paused: true, Duration: math.NaN(), Volume: 1}
+	}
+	return e.media.MediaState(node)
+}
+
+// SeekMedia moves node's playback to seconds
+func (e *Engine) SeekMedia(node *realdom.Node, seconds float64) {
+	if e.media != nil {
+		e.media.SeekMedia(node, seconds)
+	}
+}
+
+// SetMediaMuted mutes or unmutes node
+func (e *Engine) SetMediaMuted(node *realdom.Node, muted bool) {
+	if e.media != nil && e.media.MediaState(node).Muted != muted {
+		e.media.SetMediaMuted(node, muted)
+		e.dispatchMediaEvents(node, "volumechange")
+	}
+}
+
+// SetMediaVolume sets node's volume, from 0 to 1
+func (e *Engine) SetMediaVolume(node *realdom.Node, volume float64) {
+	if e.media != nil && e.media.MediaState(node).Volume != volume {
+		e.media.SetMediaVolume(node, volume)
+		e.dispatchMediaEvents(node, "volumechange")
+	}
+}
+
+// MediaEnded tells the page node played to its end; the browser calls it
+func (e *Engine) MediaEnded(node *realdom.Node) {
+	e.dispatchMediaEvents(node, "pause", "ended")
+}
+
+// dispatchMediaEvents fires the events at node in order. Both the browser
+// and the page's scripts change playback, so like in browsers the events
+// are queued as a task on the page's event loop.
+func (e *Engine) dispatchMediaEvents(node *realdom.Node, eventTypes ...string) {
+	e.Loop.Schedule(func() {
+		for _, eventType := range eventTypes {
+			dom.DispatchMediaEvent(node, e.vm, eventType)
+		}
+	})
+}
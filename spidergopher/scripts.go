@@ -0,0 +1,237 @@
+package spidergopher
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	realdom "go-browser/dom"
+	"go-browser/logging"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/parser"
+)
+
+// ======================================================================================
+// SCRIPT SOURCES
+// Each script runs under the URL it was loaded from, so relative URLs inside
+// it (importScripts) resolve against its own file instead of the page.
+// Inline scripts run under the page's URL, at the line of the HTML they start
+// at. A script is parsed whole before any of it runs: one that doesn't parse
+// is reported as a SyntaxError and skipped, and the scripts after it still run.
+// ======================================================================================
+
+// scriptTimeout is how long a script may take to download
+const scriptTimeout = 10 * time.Second
+
+// ResolveURL makes ref absolute against base; ref is returned unchanged when
+// either can't be parsed
+func ResolveURL(ref, base string) string {
+	b, err := url.Parse(base)
+	if err != nil || base == "" {
+		return ref
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return b.ResolveReference(r).String()
+}
+
+// LoadScript fetches the source of an external script through client, or
+// net/http's default client when nil
+func LoadScript(client *http.Client, scriptURL string) (string, error) {
+	timed := http.Client{}
+	if client != nil {
+		timed = *client
+	}
+	timed.Timeout = scriptTimeout
+	resp, err := timed.Get(scriptURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", scriptURL, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// PageScript is a script's source and the URL it came from; inline scripts
+// carry the document's base URL and where they start in the HTML
+type PageScript struct {
+	Source       string
+	URL          string
+	Line, Column int // 0 for external scripts
+}
+
+// PageScripts collects the <script> tags under node in document order,
+// fetching external ones from their src resolved against baseURL through
+// client, net/http's default client when nil. allow, when not nil, says
+// which external scripts may load at all.
+func PageScripts(node *realdom.Node, baseURL string, client *http.Client, allow func(scriptURL string) bool) []PageScript {
+	var scripts []PageScript
+	if node == nil {
+		return scripts
+	}
+
+	if node.Tag == "script" {
+		if src := node.GetAttr("src"); src != "" {
+			scriptURL := ResolveURL(src, baseURL)
+			if allow != nil && !allow(scriptURL) {
+				// Refused by the caller
+			} else if source, err := LoadScript(client, scriptURL); err != nil {
+				logging.Net.Warn("cannot load script", "url", scriptURL, "err", err)
+			} else {
+				scripts = append(scripts, PageScript{Source: source, URL: scriptURL})
+			}
+		} else {
+			for _, child := range node.Children {
+				if child.Type == realdom.NodeText && child.Content != "" {
+					scripts = append(scripts, PageScript{Source: child.Content, URL: baseURL, Line: child.Line, Column: child.Column})
+				}
+			}
+		}
+	}
+
+	for _, child := range node.Children {
+		scripts = append(scripts, PageScripts(child, baseURL, client, allow)...)
+	}
+	return scripts
+}
+
+// RunScript executes a script loaded from scriptURL. Errors and stack traces
+// name that URL, and relative URLs the script loads resolve against it. An
+// error the script doesn't catch is reported as uncaught, then returned.
+//
+// The script runs as a task of the event loop, so timers and callbacks never
+// run in the middle of it, and the microtasks it queues run before it returns.
+func (e *Engine) RunScript(source, scriptURL string) (goja.Value, error) {
+	return e.runTask(source, scriptURL, scriptURL)
+}
+
+// RunInlineScript executes a script of the page's HTML whose source starts at
+// line and column of it. Errors and stack traces name the page and count
+// lines and columns in its HTML; relative URLs resolve against its base URL.
+func (e *Engine) RunInlineScript(source string, line, column int) (goja.Value, error) {
+	pageURL := ""
+	if e.doc != nil {
+		pageURL = e.doc.URL
+	}
+	// Padding the source puts its first character where it is in the HTML
+	padding := strings.Repeat("\n", max(line-1, 0)) + strings.Repeat(" ", max(column-1, 0))
+	return e.runTask(padding+source, pageURL, "")
+}
+
+// runTask runs a script named name as a task of the event loop; baseURL is
+// what relative URLs inside it resolve against, the document's when empty
+func (e *Engine) runTask(source, name, baseURL string) (value goja.Value, err error) {
+	e.Loop.RunOnLoop(func(*goja.Runtime) {
+		value, err = e.runScript(source, name, baseURL)
+		if len(e.scriptURLs) == 0 {
+			// Scripts run by importScripts throw into the script that imported them
+			e.ReportError(err)
+			e.Loop.PerformMicrotaskCheckpoint()
+			e.reportRejections()
+		}
+	})
+	return value, err
+}
+
+// runScript runs a script named name on the calling goroutine, which must be
+// the one running the page's scripts
+func (e *Engine) runScript(source, name, baseURL string) (goja.Value, error) {
+	program, err := compileScript(source, name)
+	if err != nil {
+		return nil, err
+	}
+	e.scriptURLs = append(e.scriptURLs, baseURL)
+	defer func() { e.scriptURLs = e.scriptURLs[:len(e.scriptURLs)-1] }()
+	return e.vm.RunProgram(program)
+}
+
+// SyntaxError is a script that didn't parse or compile, so none of it ran
+type SyntaxError struct {
+	Message string // e.g. "Unexpected token ;"
+	Source  string // name of the script
+	Line    int
+	Column  int
+}
+
+func (s *SyntaxError) Error() string {
+	return fmt.Sprintf("SyntaxError: %s (%s:%d:%d)", s.Message, s.Source, s.Line, s.Column)
+}
+
+// report describes the error as one the page didn't handle
+func (s *SyntaxError) report() ScriptError {
+	return ScriptError{
+		Message: "Uncaught SyntaxError: " + s.Message,
+		Source:  s.Source,
+		Line:    s.Line,
+		Column:  s.Column,
+		Syntax:  true,
+	}
+}
+
+// compileScript parses and compiles a script, returning a *SyntaxError at the
+// first problem found when it can't
+func compileScript(source, name string) (*goja.Program, error) {
+	ast, err := parser.ParseFile(nil, name, source, 0)
+	if err != nil {
+		var list parser.ErrorList
+		if errors.As(err, &list) && len(list) > 0 {
+			pos := list[0].Position
+			return nil, &SyntaxError{Message: list[0].Message, Source: name, Line: pos.Line, Column: pos.Column}
+		}
+		return nil, &SyntaxError{Message: err.Error(), Source: name}
+	}
+	program, err := goja.CompileAST(ast, false)
+	if err != nil {
+		var compileErr *goja.CompilerSyntaxError
+		if errors.As(err, &compileErr) && compileErr.File != nil {
+			pos := compileErr.File.Position(compileErr.Offset)
+			return nil, &SyntaxError{Message: compileErr.Message, Source: name, Line: pos.Line, Column: pos.Column}
+		}
+		return nil, err
+	}
+	return program, nil
+}
+
+// ScriptURL returns the URL of the script running now, or the document's
+// base URL when no script is on the stack (e.g. inside async callbacks)
+func (e *Engine) ScriptURL() string {
+	if n := len(e.scriptURLs); n > 0 && e.scriptURLs[n-1] != "" {
+		return e.scriptURLs[n-1]
+	}
+	if e.doc != nil {
+		return e.doc.BaseURL
+	}
+	return ""
+}
+
+// importScripts loads and runs each URL in order, relative to the calling script
+func (e *Engine) importScripts(call goja.FunctionCall) goja.Value {
+	for _, arg := range call.Arguments {
+		scriptURL := ResolveURL(arg.String(), e.ScriptURL())
+		source, err := LoadScript(e.client, scriptURL)
+		if err != nil {
+			panic(e.vm.NewGoError(err))
+		}
+		if _, err := e.runScript(source, scriptURL, scriptURL); err != nil {
+			if ex, ok := err.(*goja.Exception); ok {
+				panic(ex)
+			}
+			_, thrown := e.scriptError(err)
+			panic(thrown)
+		}
+	}
+	return goja.Undefined()
+}
@@ -0,0 +1,64 @@
+package spidergopher
+
+import (
+	"net/url"
+
+	"go-browser/spidergopher/webapi"
+
+	"github.com/dop251/goja"
+)
+
+// ======================================================================================
+// STORAGE
+// localStorage is kept in the embedder's LocalStore, shared by its pages and
+// split by origin. Opening the database can block, so it waits for the
+// first script that reads localStorage instead of slowing every page's start.
+// Pages of an embedder that gives no store, and pages without an origin,
+// have no localStorage.
+// ======================================================================================
+
+// SetLocalStore keeps the page's localStorage in store. Call it before the
+// page's scripts run.
+func (e *Engine) SetLocalStore(store *webapi.LocalStore) {
+	e.localStore = store
+}
+
+// defineLocalStorage makes obj's localStorage open the page's storage the
+// first time it is read
+func (e *Engine) defineLocalStorage(obj *goja.Object) {
+	obj.DefineAccessorProperty("localStorage",
+		e.vm.ToValue(func(goja.FunctionCall) goja.Value {
+			if e.localStorage == nil {
+				origin := e.storageOrigin()
+				if e.localStore == nil || origin == "" {
+					return goja.Undefined()
+				}
+				storage, err := webapi.NewLocalStorage(e.vm, e.localStore, origin)
+				if err != nil {
+					panic(e.vm.NewGoError(err))
+				}
+				e.localStorage = storage.ToJSObject()
+			}
+			return e.localStorage
+		}),
+		nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+}
+
+// storageOrigin returns the origin the page's localStorage belongs to, ""
+// for a page without one
+func (e *Engine) storageOrigin() string {
+	if e.doc == nil {
+		return ""
+	}
+	u, err := url.Parse(e.doc.URL)
+	if err != nil {
+		return ""
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return u.Scheme + "://" + u.Host
+	case "file":
+		return "file://"
+	}
+	return ""
+}
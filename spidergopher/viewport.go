@@ -0,0 +1,139 @@
+package spidergopher
+
+import (
+	"sync"
+
+	"go-browser/css"
+	"go-browser/spidergopher/dom"
+
+	"github.com/dop251/goja"
+)
+
+// ======================================================================================
+// VIEWPORT
+// The browser tells the engine the size of the page's viewport, how far the
+// page is scrolled and whether it has the user's focus. innerWidth,
+// scrollY, document.hasFocus() and the like read the latest values; resize,
+// scroll, focus and blur fire at the window when they change. Like in
+// browsers scroll fires at most once per task however often the page moved.
+// ======================================================================================
+
+// viewportState is what the browser last told the engine about the page's
+// viewport. The browser writes it from the UI thread and scripts read it, so
+// mu guards it.
+type viewportState struct {
+	mu               sync.Mutex
+	width, height    float64 // 0 until the browser says
+	scrollX, scrollY float64
+	focused          bool
+	focusKnown       bool      // the browser has said whether the page has focus
+	scrollPending    bool      // a scroll event is scheduled
+	media            css.Media // the medium media queries test; its Type is empty until the browser says
+}
+
+// SetViewport tells the engine the size of the page's viewport in CSS
+// pixels. A size other than the first fires resize at the window.
+func (e *Engine) SetViewport(width, height float64) {
+	v := &e.viewport
+	v.mu.Lock()
+	known := v.width > 0
+	changed := width != v.width || height != v.height
+	v.width, v.height = width, height
+	v.mu.Unlock()
+	if known && changed {
+		e.Loop.Schedule(func() { e.fireWindowEvent("resize") })
+	}
+}
+
+// SetScroll tells the engine how far the page is scrolled. scroll fires at
+// the document, bubbling to the window, once for any number of moves made
+// before it runs.
+func (e *Engine) SetScroll(x, y float64) {
+	v := &e.viewport
+	v.mu.Lock()
+	if x == v.scrollX && y == v.scrollY {
+		v.mu.Unlock()
+		return
+	}
+	v.scrollX, v.scrollY = x, y
+	pending := v.scrollPending
+	v.scrollPending = true
+	v.mu.Unlock()
+	if pending {
+		return
+	}
+	e.Loop.Schedule(func() {
+		v.mu.Lock()
+		v.scrollPending = false
+		v.mu.Unlock()
+		if e.doc != nil {
+			dom.DispatchDocumentEvent(e.doc, e.vm, "scroll")
+		}
+		e.fireWindowEvent("scroll")
+	})
+}
+
+// SetFocused tells the engine whether the page has the user's focus: its
+// tab is the one shown and the browser window is focused. A change fires
+// focus or blur at the window.
+func (e *Engine) SetFocused(focused bool) {
+	v := &e.viewport
+	v.mu.Lock()
+	changed := v.focusKnown && focused != v.focused
+	v.focused, v.focusKnown = focused, true
+	v.mu.Unlock()
+	if !changed {
+		return
+	}
+	eventType := "blur"
+	if focused {
+		eventType = "focus"
+	}
+	e.Loop.Schedule(func() { e.fireWindowEvent(eventType) })
+}
+
+// hasFocus reports whether the page has the user's focus; until the browser
+// says, it does
+func (e *Engine) hasFocus() bool {
+	v := &e.viewport
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.focused || !v.focusKnown
+}
+
+// fireWindowEvent calls the window's on<type> handler and then the
+// listeners for eventType
+func (e *Engine) fireWindowEvent(eventType string) {
+	event := dom.NewEvent(eventType)
+	if handler, ok := goja.AssertFunction(e.eventHandler("on" + eventType)); ok {
+		if _, err := handler(e.windowObj, e.vm.ToValue(event.ToJSObject())); err != nil {
+			e.ReportError(err)
+		}
+	}
+	e.Window.DispatchEvent(e.vm, event)
+}
+
+// defineViewportProperties adds innerWidth, innerHeight, scrollX, scrollY
+// and their aliases to obj, the window or the global object
+func (e *Engine) defineViewportProperties(obj *goja.Object) {
+	v := &e.viewport
+	values := map[string]func() float64{
+		"innerWidth":  func() float64 { return v.width },
+		"innerHeight": func() float64 { return v.height },
+		"outerWidth":  func() float64 { return v.width },
+		"outerHeight": func() float64 { return v.height },
+		"scrollX":     func() float64 { return v.scrollX },
+		"scrollY":     func() float64 { return v.scrollY },
+		"pageXOffset": func() float64 { return v.scrollX },
+		"pageYOffset": func() float64 { return v.scrollY },
+	}
+	for name, value := range values {
+		obj.DefineAccessorProperty(name,
+			e.vm.ToValue(func(goja.FunctionCall) goja.Value {
+				v.mu.Lock()
+				defer v.mu.Unlock()
+				return e.vm.ToValue(value())
+			}),
+			nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	}
+}
@@ -0,0 +1,82 @@
+package webapi
+
+import (
+	"slices"
+
+	"github.com/dop251/goja"
+)
+
+// abortSignal is the state behind an AbortSignal object
+type abortSignal struct {
+	obj       *goja.Object
+	aborted   bool
+	reason    goja.Value
+	listeners []goja.Value // abort listeners added by scripts
+	onAbort   []func()     // what to cancel, e.g. the requests using the signal
+}
+
+// NewAbortController implements the AbortController constructor
+func (f *FetchAPI) NewAbortController(call goja.ConstructorCall) *goja.Object {
+	signal := f.newAbortSignal()
+	call.This.Set("signal", signal.obj)
+	call.This.Set("abort", func(reason goja.Value) { f.abort(signal, reason) })
+	return nil
+}
+
+// newAbortSignal creates an AbortSignal that isn't aborted
+func (f *FetchAPI) newAbortSignal() *abortSignal {
+	signal := &abortSignal{obj: f.vm.NewObject(), reason: goja.Undefined()}
+	obj := signal.obj
+	f.signals[obj] = signal
+
+	obj.DefineAccessorProperty("aborted",
+		f.vm.ToValue(func(goja.FunctionCall) goja.Value { return f.vm.ToValue(signal.aborted) }),
+		nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	obj.DefineAccessorProperty("reason",
+		f.vm.ToValue(func(goja.FunctionCall) goja.Value { return signal.reason }),
+		nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	obj.Set("onabort", goja.Null())
+	obj.Set("throwIfAborted", func() {
+		if signal.aborted {
+			panic(signal.reason)
+		}
+	})
+	obj.Set("addEventListener", func(eventType string, listener goja.Value) {
+		if eventType == "abort" {
+			signal.listeners = append(signal.listeners, listener)
+		}
+	})
+	obj.Set("removeEventListener", func(eventType string, listener goja.Value) {
+		if eventType == "abort" {
+			signal.listeners = slices.DeleteFunc(signal.listeners, listener.SameAs)
+		}
+	})
+	return signal
+}
+
+// abort aborts a signal with reason, an AbortError when undefined: what uses
+// the signal is canceled, then abort fires at it
+func (f *FetchAPI) abort(signal *abortSignal, reason goja.Value) {
+	if signal.aborted {
+		return
+	}
+	if reason == nil || goja.IsUndefined(reason) {
+		reason = DOMException(f.vm, "AbortError", "The operation was aborted")
+	}
+	signal.aborted, signal.reason = true, reason
+	for _, cancel := range signal.onAbort {
+		cancel()
+	}
+	signal.onAbort = nil
+
+	event := f.vm.NewObject()
+	event.Set("type", "abort")
+	event.Set("target", signal.obj)
+	handlers := append([]goja.Value{signal.obj.Get("onabort")}, signal.listeners...)
+	for _, handler := range handlers {
+		if fn, ok := goja.AssertFunction(handler); ok {
+			_, err := fn(signal.obj, event)
+			f.loop.ReportError(err)
+		}
+	}
+}
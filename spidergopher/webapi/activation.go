@@ -0,0 +1,87 @@
+package webapi
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// TransientActivationDuration is how long a click or key press lets a page
+// do the things that need a user gesture
+const TransientActivationDuration = 5 * time.Second
+
+// UserActivation tracks whether the user has interacted with the page, as
+// browsers do to gate popups, clipboard reads, fullscreen and autoplay
+// (https://html.spec.whatwg.org/multipage/interaction.html#tracking-user-activation)
+type UserActivation struct {
+	mu       sync.Mutex
+	last     time.Time // when the page was last activated
+	sticky   bool      // activated at least once
+	consumed bool      // the last activation was used up
+}
+
+// NewUserActivation creates a tracker for a page the user has not touched
+func NewUserActivation() *UserActivation {
+	return &UserActivation{}
+}
+
+// Activate records a click or key press on the page
+func (u *UserActivation) Activate() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.last = time.Now()
+	u.sticky = true
+	u.consumed = false
+}
+
+// IsActive reports whether the page has transient activation: it was
+// activated recently and the activation has not been consumed
+func (u *UserActivation) IsActive() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.active()
+}
+
+func (u *UserActivation) active() bool {
+	return u.sticky && !u.consumed && time.Since(u.last) < TransientActivationDuration
+}
+
+// HasBeenActive reports whether the page has sticky activation: it was
+// activated at least once
+func (u *UserActivation) HasBeenActive() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.sticky
+}
+
+// Consume uses up the transient activation, so one click opens one popup.
+// It reports whether there was an activation to use.
+func (u *UserActivation) Consume() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if !u.active() {
+		return false
+	}
+	u.consumed = true
+	return true
+}
+
+// AutoplayAllowed reports whether media may start playing without a click
+// on its controls: muted media always may, audible media once the user has
+// interacted with the page
+func (u *UserActivation) AutoplayAllowed(muted bool) bool {
+	return muted || u.HasBeenActive()
+}
+
+// Object returns navigator.userActivation, whose isActive and hasBeenActive
+// read the tracker live
+func (u *UserActivation) Object(vm *goja.Runtime) *goja.Object {
+	obj := vm.NewObject()
+	getter := func(fn func() bool) goja.Value {
+		return vm.ToValue(func(goja.FunctionCall) goja.Value { return vm.ToValue(fn()) })
+	}
+	obj.DefineAccessorProperty("isActive", getter(u.IsActive), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	obj.DefineAccessorProperty("hasBeenActive", getter(u.HasBeenActive), nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
+	return obj
+}
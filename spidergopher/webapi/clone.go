@@ -0,0 +1,112 @@
+package webapi
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// =============================================================================
+// STRUCTURED CLONE
+// Values passed between runtimes (postMessage) are copied out of one as plain
+// Go data and rebuilt in the other. Primitives, arrays, plain objects and
+// dates survive; functions and cyclic structures can't be cloned.
+// =============================================================================
+
+// ErrDataClone is the error of values that can't be cloned
+var ErrDataClone = errors.New("DataCloneError")
+
+// cloneDepth bounds how deep a cloned value may nest
+const cloneDepth = 100
+
+// undefinedValue stands for undefined in cloned data, where nil is null
+type undefinedValue struct{}
+
+// clonedObject is a plain object's own enumerable properties, in order
+type clonedObject struct {
+	keys   []string
+	values []interface{}
+}
+
+// Clone copies v out of its runtime. The result is only meant for Revive.
+func Clone(v goja.Value) (interface{}, error) {
+	return clone(v, make(map[*goja.Object]bool), 0)
+}
+
+func clone(v goja.Value, seen map[*goja.Object]bool, depth int) (interface{}, error) {
+	switch {
+	case v == nil || goja.IsUndefined(v):
+		return undefinedValue{}, nil
+	case goja.IsNull(v):
+		return nil, nil
+	}
+	obj, ok := v.(*goja.Object)
+	if !ok {
+		return v.Export(), nil // boolean, number, string or bigint
+	}
+	if _, isFunc := goja.AssertFunction(obj); isFunc {
+		return nil, ErrDataClone
+	}
+	if seen[obj] || depth > cloneDepth {
+		return nil, ErrDataClone
+	}
+	seen[obj] = true
+	defer delete(seen, obj)
+
+	switch obj.ClassName() {
+	case "Date":
+		if t, ok := obj.Export().(time.Time); ok {
+			return t, nil
+		}
+	case "Array":
+		n := obj.Get("length").ToInteger()
+		items := make([]interface{}, n)
+		for i := range items {
+			item, err := clone(obj.Get(strconv.Itoa(i)), seen, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	}
+	cloned := &clonedObject{}
+	for _, key := range obj.Keys() {
+		value, err := clone(obj.Get(key), seen, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		cloned.keys = append(cloned.keys, key)
+		cloned.values = append(cloned.values, value)
+	}
+	return cloned, nil
+}
+
+// Revive rebuilds data made by Clone as a value of vm
+func Revive(vm *goja.Runtime, data interface{}) goja.Value {
+	switch d := data.(type) {
+	case undefinedValue:
+		return goja.Undefined()
+	case nil:
+		return goja.Null()
+	case time.Time:
+		date, _ := vm.New(vm.Get("Date"), vm.ToValue(d.UnixMilli()))
+		return date
+	case []interface{}:
+		items := make([]interface{}, len(d))
+		for i, item := range d {
+			items[i] = Revive(vm, item)
+		}
+		return vm.NewArray(items...)
+	case *clonedObject:
+		obj := vm.NewObject()
+		for i, key := range d.keys {
+			obj.Set(key, Revive(vm, d.values[i]))
+		}
+		return obj
+	default:
+		return vm.ToValue(d)
+	}
+}
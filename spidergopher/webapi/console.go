@@ -1,43 +1,430 @@
 package webapi
 
 import (
+	"bytes"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-browser/logging"
 
 	"github.com/dop251/goja"
 )
 
-// Console implements a subset of the Console API
-type Console struct{}
+// ConsoleMessage is one message a page wrote to the console
+type ConsoleMessage struct {
+	Level string // log, info, debug, warn, error or trace
+	Text  string // the formatted message, indented by its console.group depth
+}
+
+// Console implements the Console API. Messages go to the js log, at debug
+// for console.debug and info for the rest, and to the handler set with
+// OnMessage, such as a devtools console.
+type Console struct {
+	vm        *goja.Runtime
+	mu        sync.Mutex
+	depth     int                  // console.group nesting
+	timers    map[string]time.Time // console.time labels
+	onMessage func(ConsoleMessage)
+}
 
 func NewConsole() *Console {
-	return &Console{}
+	return &Console{timers: make(map[string]time.Time)}
+}
+
+// SetVM sets the runtime (called during engine setup)
+func (c *Console) SetVM(vm *goja.Runtime) {
+	c.vm = vm
+}
+
+// OnMessage registers a handler for every message the page logs
+func (c *Console) OnMessage(fn func(ConsoleMessage)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onMessage = fn
+}
+
+// Emit prints a message at level, indented by the group depth. The engine
+// also logs the errors pages leave uncaught with it.
+func (c *Console) Emit(level, text string) {
+	c.mu.Lock()
+	indent := strings.Repeat("  ", c.depth)
+	handler := c.onMessage
+	c.mu.Unlock()
+
+	text = indent + strings.ReplaceAll(text, "\n", "\n"+indent)
+	if level == "debug" {
+		logging.JS.Debug(text, "console", level)
+	} else {
+		logging.JS.Info(text, "console", level)
+	}
+	if handler != nil {
+		handler(ConsoleMessage{Level: level, Text: text})
+	}
 }
 
 func (c *Console) Log(call goja.FunctionCall) goja.Value {
-	msg := formatArgs(call.Arguments)
-	fmt.Println("[LOG]", msg)
+	c.Emit("log", formatArgs(call.Arguments))
+	return goja.Undefined()
+}
+
+func (c *Console) Info(call goja.FunctionCall) goja.Value {
+	c.Emit("info", formatArgs(call.Arguments))
+	return goja.Undefined()
+}
+
+func (c *Console) Debug(call goja.FunctionCall) goja.Value {
+	c.Emit("debug", formatArgs(call.Arguments))
 	return goja.Undefined()
 }
 
 func (c *Console) Warn(call goja.FunctionCall) goja.Value {
-	msg := formatArgs(call.Arguments)
-	fmt.Println("[WARN]", msg)
+	c.Emit("warn", formatArgs(call.Arguments))
 	return goja.Undefined()
 }
 
 func (c *Console) Error(call goja.FunctionCall) goja.Value {
-	msg := formatArgs(call.Arguments)
-	fmt.Println("[ERROR]", msg)
+	c.Emit("error", formatArgs(call.Arguments))
+	return goja.Undefined()
+}
+
+// Trace logs its arguments followed by the script's call stack
+func (c *Console) Trace(call goja.FunctionCall) goja.Value {
+	msg := "Trace"
+	if len(call.Arguments) > 0 {
+		msg += ": " + formatArgs(call.Arguments)
+	}
+	if c.vm != nil {
+		var buf bytes.Buffer
+		frames := c.vm.CaptureCallStack(0, nil)
+		if len(frames) > 0 {
+			frames = frames[1:] // console.trace itself
+		}
+		for _, frame := range frames {
+			buf.WriteString("\n    at ")
+			frame.Write(&buf)
+		}
+		msg += buf.String()
+	}
+	c.Emit("trace", msg)
+	return goja.Undefined()
+}
+
+// Assert logs an error when its first argument is falsy
+func (c *Console) Assert(call goja.FunctionCall) goja.Value {
+	if call.Argument(0).ToBoolean() {
+		return goja.Undefined()
+	}
+	msg := "Assertion failed"
+	if len(call.Arguments) > 1 {
+		msg += ": " + formatArgs(call.Arguments[1:])
+	}
+	c.Emit("error", msg)
+	return goja.Undefined()
+}
+
+// ======================================================================================
+// GROUPS
+// ======================================================================================
+
+// Group logs its arguments as a heading and indents later messages
+func (c *Console) Group(call goja.FunctionCall) goja.Value {
+	if len(call.Arguments) > 0 {
+		c.Emit("log", formatArgs(call.Arguments))
+	}
+	c.mu.Lock()
+	c.depth++
+	c.mu.Unlock()
+	return goja.Undefined()
+}
+
+// GroupEnd ends the innermost group
+func (c *Console) GroupEnd(call goja.FunctionCall) goja.Value {
+	c.mu.Lock()
+	c.depth = max(c.depth-1, 0)
+	c.mu.Unlock()
+	return goja.Undefined()
+}
+
+// ======================================================================================
+// TIMERS
+// ======================================================================================
+
+// timerLabel returns the label a console.time call names, "default" when none
+func timerLabel(call goja.FunctionCall) string {
+	if arg := call.Argument(0); !goja.IsUndefined(arg) {
+		return arg.String()
+	}
+	return "default"
+}
+
+// Time starts a timer under a label
+func (c *Console) Time(call goja.FunctionCall) goja.Value {
+	label := timerLabel(call)
+	c.mu.Lock()
+	_, exists := c.timers[label]
+	if !exists {
+		c.timers[label] = time.Now()
+	}
+	c.mu.Unlock()
+	if exists {
+		c.Emit("warn", fmt.Sprintf("Timer '%s' already exists", label))
+	}
+	return goja.Undefined()
+}
+
+// TimeLog logs how long a timer has run, with any further arguments
+func (c *Console) TimeLog(call goja.FunctionCall) goja.Value {
+	c.logTimer(call, false)
+	return goja.Undefined()
+}
+
+// TimeEnd logs how long a timer ran and stops it
+func (c *Console) TimeEnd(call goja.FunctionCall) goja.Value {
+	c.logTimer(call, true)
+	return goja.Undefined()
+}
+
+func (c *Console) logTimer(call goja.FunctionCall, end bool) {
+	label := timerLabel(call)
+	c.mu.Lock()
+	start, ok := c.timers[label]
+	if ok && end {
+		delete(c.timers, label)
+	}
+	c.mu.Unlock()
+	if !ok {
+		c.Emit("warn", fmt.Sprintf("Timer '%s' does not exist", label))
+		return
+	}
+	elapsed := float64(time.Since(start).Microseconds()) / 1000
+	msg := fmt.Sprintf("%s: %sms", label, strconv.FormatFloat(elapsed, 'f', 3, 64))
+	if !end && len(call.Arguments) > 1 {
+		msg += " " + formatArgs(call.Arguments[1:])
+	}
+	c.Emit("log", msg)
+}
+
+// ======================================================================================
+// TABLES
+// ======================================================================================
+
+// Table logs an array or object of rows as a table; an optional second
+// argument picks the columns. Anything else is logged as console.log would.
+func (c *Console) Table(call goja.FunctionCall) goja.Value {
+	data, ok := call.Argument(0).(*goja.Object)
+	if !ok || goja.IsNull(call.Argument(0)) {
+		c.Emit("log", formatArgs(call.Arguments))
+		return goja.Undefined()
+	}
+
+	var columns []string
+	if cols, ok := call.Argument(1).(*goja.Object); ok && cols.ClassName() == "Array" {
+		for _, key := range cols.Keys() {
+			columns = append(columns, cols.Get(key).String())
+		}
+	}
+	pick := columns == nil
+	seen := make(map[string]bool)
+	hasValues := false
+
+	keys := data.Keys()
+	rows := make([]map[string]string, len(keys))
+	for i, key := range keys {
+		rows[i] = map[string]string{"(index)": key}
+		row, isObject := data.Get(key).(*goja.Object)
+		if !isObject || row.ClassName() == "Function" {
+			rows[i]["Values"] = inspect(data.Get(key), 1)
+			hasValues = true
+			continue
+		}
+		for _, col := range row.Keys() {
+			rows[i][col] = inspect(row.Get(col), 1)
+			if pick && !seen[col] {
+				seen[col] = true
+				columns = append(columns, col)
+			}
+		}
+	}
+	header := append([]string{"(index)"}, columns...)
+	if hasValues {
+		header = append(header, "Values")
+	}
+	c.Emit("log", drawTable(header, rows))
 	return goja.Undefined()
 }
 
+// drawTable lays rows out under header in box-drawing characters
+func drawTable(header []string, rows []map[string]string) string {
+	widths := make([]int, len(header))
+	for i, col := range header {
+		widths[i] = len([]rune(col))
+		for _, row := range rows {
+			widths[i] = max(widths[i], len([]rune(row[col])))
+		}
+	}
+	rule := func(left, mid, right string) string {
+		parts := make([]string, len(widths))
+		for i, w := range widths {
+			parts[i] = strings.Repeat("─", w+2)
+		}
+		return left + strings.Join(parts, mid) + right
+	}
+	line := func(cell func(col string) string) string {
+		parts := make([]string, len(header))
+		for i, col := range header {
+			text := cell(col)
+			parts[i] = " " + text + strings.Repeat(" ", widths[i]-len([]rune(text))) + " "
+		}
+		return "│" + strings.Join(parts, "│") + "│"
+	}
+
+	lines := []string{rule("┌", "┬", "┐"), line(func(col string) string { return col }), rule("├", "┼", "┤")}
+	for _, row := range rows {
+		lines = append(lines, line(func(col string) string { return row[col] }))
+	}
+	lines = append(lines, rule("└", "┴", "┘"))
+	return strings.Join(lines, "\n")
+}
+
+// ======================================================================================
+// FORMATTING
+// ======================================================================================
+
+// formatArgs joins a console call's arguments. A first argument holding
+// %s, %d, %i, %f, %o, %O or %c substitutes the arguments after it.
 func formatArgs(args []goja.Value) string {
-	var s string
-	for i, arg := range args {
-		if i > 0 {
-			s += " "
+	var parts []string
+	if len(args) > 0 {
+		if format, ok := args[0].Export().(string); ok && strings.Contains(format, "%") {
+			var text string
+			text, args = substitute(format, args[1:])
+			parts = append(parts, text)
+		} else {
+			parts = append(parts, inspectTop(args[0]))
+			args = args[1:]
+		}
+	}
+	for _, arg := range args {
+		parts = append(parts, inspectTop(arg))
+	}
+	return strings.Join(parts, " ")
+}
+
+// substitute fills the format specifiers of format from args, returning the
+// text and the arguments left over
+func substitute(format string, args []goja.Value) (string, []goja.Value) {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			b.WriteByte(format[i])
+			continue
+		}
+		spec := format[i+1]
+		if spec == '%' {
+			b.WriteByte('%')
+			i++
+			continue
+		}
+		if !strings.ContainsRune("sdifoOc", rune(spec)) || len(args) == 0 {
+			b.WriteByte('%')
+			continue
+		}
+		arg := args[0]
+		args = args[1:]
+		i++
+		switch spec {
+		case 's':
+			b.WriteString(inspectTop(arg))
+		case 'd', 'i':
+			if n := arg.ToFloat(); math.IsNaN(n) {
+				b.WriteString("NaN")
+			} else {
+				b.WriteString(strconv.FormatFloat(math.Trunc(n), 'f', -1, 64))
+			}
+		case 'f':
+			b.WriteString(strconv.FormatFloat(arg.ToFloat(), 'g', -1, 64))
+		case 'o', 'O':
+			b.WriteString(inspect(arg, 0))
+		case 'c':
+			// CSS styling of the output does not apply to text
+		}
+	}
+	return b.String(), args
+}
+
+// inspectTop formats a logged value: strings as they are, anything else as
+// inspect shows it
+func inspectTop(v goja.Value) string {
+	if s, ok := v.Export().(string); ok {
+		return s
+	}
+	return inspect(v, 0)
+}
+
+// inspectDepth is how deep nested objects are shown before "[Object]"
+const inspectDepth = 2
+
+// inspect formats a value as the console shows it: errors with their stack,
+// arrays and objects by their members, and strings quoted
+func inspect(v goja.Value, depth int) string {
+	if v == nil || goja.IsUndefined(v) {
+		return "undefined"
+	}
+	if goja.IsNull(v) {
+		return "null"
+	}
+	obj, ok := v.(*goja.Object)
+	if !ok {
+		if s, ok := v.Export().(string); ok {
+			return "'" + s + "'"
+		}
+		return v.String()
+	}
+
+	switch obj.ClassName() {
+	case "Error":
+		if stack := obj.Get("stack"); stack != nil && !goja.IsUndefined(stack) {
+			return strings.TrimRight(stack.String(), "\n")
+		}
+		return obj.String()
+	case "Function":
+		name := obj.Get("name").String()
+		if name == "" {
+			name = "(anonymous)"
 		}
-		s += fmt.Sprint(arg.Export())
+		return "[Function: " + name + "]"
+	case "Date", "RegExp":
+		return obj.String()
+	}
+
+	isArray := obj.ClassName() == "Array"
+	if depth > inspectDepth {
+		if isArray {
+			return "[Array]"
+		}
+		return "[Object]"
+	}
+	keys := obj.Keys()
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := inspect(obj.Get(key), depth+1)
+		if isArray {
+			if _, err := strconv.Atoi(key); err == nil {
+				parts = append(parts, value)
+				continue
+			}
+		}
+		parts = append(parts, key+": "+value)
+	}
+	if isArray {
+		return "[" + strings.Join(parts, ", ") + "]"
+	}
+	if len(parts) == 0 {
+		return "{}"
 	}
-	return s
+	return "{ " + strings.Join(parts, ", ") + " }"
 }
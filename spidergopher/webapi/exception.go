@@ -0,0 +1,11 @@
+package webapi
+
+import "github.com/dop251/goja"
+
+// DOMException creates an Error whose name is a DOMException name, such as
+// NotAllowedError, as the page's scripts see the errors of web APIs
+func DOMException(vm *goja.Runtime, name, message string) *goja.Object {
+	err, _ := vm.New(vm.Get("Error"), vm.ToValue(message))
+	err.Set("name", name)
+	return err
+}
@@ -1,149 +1,345 @@
 package webapi
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 
 	"go-browser/spidergopher/core"
 
 	"github.com/dop251/goja"
 )
 
-// FetchAPI provides the fetch function
+// FetchAPI provides the fetch function, and the Headers, URLSearchParams,
+// FormData and AbortController its requests are built with
 type FetchAPI struct {
 	loop *core.EventLoop
 	vm   *goja.Runtime
+
+	// urls returns the URL relative requests resolve against and the URL of
+	// the page, whose origin decides what is same-origin
+	urls func() (baseURL, pageURL string)
+
+	// base returns the embedder's client requests go through, nil for
+	// net/http's default
+	base func() *http.Client
+
+	headers      map[*goja.Object]http.Header
+	searchParams map[*goja.Object]*formEntries
+	formData     map[*goja.Object]*formEntries
+	signals      map[*goja.Object]*abortSignal
 }
 
+var (
+	errHeaderPair   = errors.New("Headers: each pair must have a name and a value")
+	errRedirect     = errors.New("redirect was not allowed by the request's redirect mode")
+	errBodyWithHead = errors.New("a GET or HEAD request can't have a body")
+)
+
 // NewFetchAPI creates a new FetchAPI
 func NewFetchAPI(loop *core.EventLoop, vm *goja.Runtime) *FetchAPI {
-	return &FetchAPI{loop: loop, vm: vm}
-}
-
-// Fetch implements the fetch() function
-// Returns a Promise-like object
-func (f *FetchAPI) Fetch(call goja.FunctionCall) goja.Value {
-	if len(call.Arguments) < 1 {
-		return goja.Undefined()
+	return &FetchAPI{
+		loop:         loop,
+		vm:           vm,
+		urls:         func() (string, string) { return "", "" },
+		base:         func() *http.Client { return nil },
+		headers:      make(map[*goja.Object]http.Header),
+		searchParams: make(map[*goja.Object]*formEntries),
+		formData:     make(map[*goja.Object]*formEntries),
+		signals:      make(map[*goja.Object]*abortSignal),
 	}
+}
 
-	url := call.Argument(0).String()
+// SetURLs tells fetch where the page is: relative URLs resolve against
+// baseURL, and credentials: "same-origin" compares origins with pageURL
+func (f *FetchAPI) SetURLs(urls func() (baseURL, pageURL string)) {
+	f.urls = urls
+}
 
-	// Create a promise-like object
-	promiseObj := f.vm.NewObject()
+// SetClient tells fetch the client its requests go through, with its
+// transport and cookie jar
+func (f *FetchAPI) SetClient(base func() *http.Client) {
+	f.base = base
+}
 
-	var thenCallback goja.Callable
-	var catchCallback goja.Callable
+// fetchOptions are the members of fetch's init argument that aren't part of
+// the HTTP request itself
+type fetchOptions struct {
+	credentials string // omit, same-origin or include
+	redirect    string // follow, manual or error
+	signal      *abortSignal
+}
 
-	promiseObj.Set("then", func(call goja.FunctionCall) goja.Value {
-		if len(call.Arguments) > 0 {
-			if fn, ok := goja.AssertFunction(call.Argument(0)); ok {
-				thenCallback = fn
-			}
-		}
-		return promiseObj // Allow chaining
-	})
+// Fetch implements fetch(input, init). It returns a Promise for a Response.
+func (f *FetchAPI) Fetch(call goja.FunctionCall) goja.Value {
+	promise, resolve, reject := f.vm.NewPromise()
+	req, opts, err := f.newRequest(call.Argument(0), call.Argument(1))
+	if err != nil {
+		reject(f.vm.NewTypeError(err.Error()))
+		return f.vm.ToValue(promise)
+	}
+	if opts.signal != nil && opts.signal.aborted {
+		reject(opts.signal.reason)
+		return f.vm.ToValue(promise)
+	}
 
-	promiseObj.Set("catch", func(call goja.FunctionCall) goja.Value {
-		if len(call.Arguments) > 0 {
-			if fn, ok := goja.AssertFunction(call.Argument(0)); ok {
-				catchCallback = fn
-			}
-		}
-		return promiseObj
-	})
+	ctx, cancel := context.WithCancel(context.Background())
+	req = req.WithContext(ctx)
+	if opts.signal != nil {
+		opts.signal.onAbort = append(opts.signal.onAbort, cancel)
+	}
+	client := f.client(req.URL, opts)
 
 	// Make the HTTP request asynchronously
 	go func() {
-		resp, err := http.Get(url)
+		defer cancel()
+		resp, err := client.Do(req)
+		var body []byte
+		if err == nil {
+			body, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
 
-		// Schedule the callback on the event loop
+		// Settle the promise on the event loop
 		f.loop.Schedule(func() {
-			if err != nil {
-				if catchCallback != nil {
-					catchCallback(goja.Undefined(), f.vm.ToValue(err.Error()))
-				}
-				return
-			}
-			defer resp.Body.Close()
-
-			// Create Response object
-			responseObj := f.createResponse(resp)
-
-			if thenCallback != nil {
-				thenCallback(goja.Undefined(), responseObj)
+			switch {
+			case opts.signal != nil && opts.signal.aborted:
+				reject(opts.signal.reason)
+			case err != nil:
+				reject(f.vm.NewTypeError(fmt.Sprintf("Failed to fetch %s: %v", req.URL, err)))
+			default:
+				resolve(f.createResponse(resp, body, opts))
 			}
 		})
 	}()
 
-	return promiseObj
+	return f.vm.ToValue(promise)
+}
+
+// newRequest builds the HTTP request of fetch(input, init)
+func (f *FetchAPI) newRequest(input, init goja.Value) (*http.Request, fetchOptions, error) {
+	opts := fetchOptions{credentials: "same-origin", redirect: "follow"}
+	baseURL, _ := f.urls()
+	target, err := resolveURL(input.String(), baseURL)
+	if err != nil {
+		return nil, opts, err
+	}
+
+	method := http.MethodGet
+	header := http.Header{}
+	var body []byte
+	var contentType string
+	if init != nil && !goja.IsUndefined(init) && !goja.IsNull(init) {
+		obj := init.ToObject(f.vm)
+		if v := obj.Get("method"); v != nil && !goja.IsUndefined(v) {
+			method = normalizeMethod(v.String())
+		}
+		if header, err = f.headersFrom(obj.Get("headers")); err != nil {
+			return nil, opts, err
+		}
+		if v := obj.Get("body"); v != nil && !goja.IsUndefined(v) && !goja.IsNull(v) {
+			body, contentType = f.encodeBody(v)
+		}
+		if v := obj.Get("credentials"); v != nil && !goja.IsUndefined(v) {
+			opts.credentials = v.String()
+		}
+		if v := obj.Get("redirect"); v != nil && !goja.IsUndefined(v) {
+			opts.redirect = v.String()
+		}
+		if v := obj.Get("signal"); isObject(v) {
+			opts.signal = f.signals[v.ToObject(f.vm)]
+		}
+	}
+	if body != nil && (method == http.MethodGet || method == http.MethodHead) {
+		return nil, opts, errBodyWithHead
+	}
+
+	req, err := http.NewRequest(method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, opts, err
+	}
+	req.Header = header
+	if contentType != "" && header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, opts, nil
+}
+
+// fetchSchemes are the URL schemes fetch requests besides http and https,
+// whose requests the embedder's client serves
+var fetchSchemes = struct {
+	sync.RWMutex
+	allowed map[string]bool
+}{allowed: make(map[string]bool)}
+
+// AllowFetchScheme lets fetch request URLs of scheme, or no longer
+func AllowFetchScheme(scheme string, allow bool) {
+	fetchSchemes.Lock()
+	defer fetchSchemes.Unlock()
+	if allow {
+		fetchSchemes.allowed[strings.ToLower(scheme)] = true
+	} else {
+		delete(fetchSchemes.allowed, strings.ToLower(scheme))
+	}
+}
+
+// fetchSchemeAllowed reports whether fetch may request URLs of scheme
+func fetchSchemeAllowed(scheme string) bool {
+	if scheme == "http" || scheme == "https" {
+		return true
+	}
+	fetchSchemes.RLock()
+	defer fetchSchemes.RUnlock()
+	return fetchSchemes.allowed[scheme]
+}
+
+// resolveURL makes ref absolute against base
+func resolveURL(ref, base string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return "", err
+	}
+	if b, err := url.Parse(base); err == nil && base != "" {
+		u = b.ResolveReference(u)
+	}
+	if !fetchSchemeAllowed(u.Scheme) {
+		return "", fmt.Errorf("Failed to fetch %s: unsupported scheme", u)
+	}
+	return u.String(), nil
+}
+
+// normalizeMethod uppercases the standard methods, as fetch does; others
+// are sent as written
+func normalizeMethod(method string) string {
+	switch upper := strings.ToUpper(method); upper {
+	case "DELETE", "GET", "HEAD", "OPTIONS", "POST", "PUT", "PATCH":
+		return upper
+	}
+	return method
+}
+
+// encodeBody serializes a request body, returning it with the content type
+// it implies
+func (f *FetchAPI) encodeBody(v goja.Value) ([]byte, string) {
+	if isObject(v) {
+		obj := v.ToObject(f.vm)
+		if fe, ok := f.searchParams[obj]; ok {
+			return []byte(fe.encode()), "application/x-www-form-urlencoded;charset=UTF-8"
+		}
+		if fe, ok := f.formData[obj]; ok {
+			return fe.multipart()
+		}
+		if buf, ok := v.Export().(goja.ArrayBuffer); ok {
+			return buf.Bytes(), ""
+		}
+	}
+	return []byte(v.String()), "text/plain;charset=UTF-8"
+}
+
+// client returns the HTTP client of a request: it sends cookies as the
+// credentials mode allows and follows redirects as the redirect mode says
+func (f *FetchAPI) client(target *url.URL, opts fetchOptions) *http.Client {
+	base := f.base()
+	if base == nil {
+		base = http.DefaultClient
+	}
+	client := &http.Client{Transport: base.Transport}
+	_, pageURL := f.urls()
+	switch opts.credentials {
+	case "include":
+		client.Jar = base.Jar
+	case "omit":
+	default:
+		if page, err := url.Parse(pageURL); err == nil && SameOrigin(page, target) {
+			client.Jar = base.Jar
+		}
+	}
+	switch opts.redirect {
+	case "manual":
+		client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	case "error":
+		client.CheckRedirect = func(*http.Request, []*http.Request) error { return errRedirect }
+	}
+	return client
+}
+
+// SameOrigin reports whether two URLs share scheme, host and port
+func SameOrigin(a, b *url.URL) bool {
+	return a.Scheme == b.Scheme && a.Host == b.Host
 }
 
 // createResponse creates a JS Response object
-func (f *FetchAPI) createResponse(resp *http.Response) goja.Value {
+func (f *FetchAPI) createResponse(resp *http.Response, body []byte, opts fetchOptions) goja.Value {
 	responseObj := f.vm.NewObject()
 
-	responseObj.Set("ok", resp.StatusCode >= 200 && resp.StatusCode < 300)
-	responseObj.Set("status", resp.StatusCode)
-	responseObj.Set("statusText", resp.Status)
+	_, pageURL := f.urls()
+	responseType := "cors"
+	if page, err := url.Parse(pageURL); err == nil && SameOrigin(page, resp.Request.URL) {
+		responseType = "basic"
+	}
+	status, statusText, header := resp.StatusCode, strings.TrimSpace(strings.TrimPrefix(resp.Status, fmt.Sprint(resp.StatusCode))), resp.Header
+	if opts.redirect == "manual" && resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		// An opaque redirect: scripts see neither where it goes nor its body
+		responseType, status, statusText, header, body = "opaqueredirect", 0, "", http.Header{}, nil
+	}
 
-	// Read body once and store it
-	bodyBytes, _ := io.ReadAll(resp.Body)
-	bodyStr := string(bodyBytes)
+	responseObj.Set("ok", status >= 200 && status < 300)
+	responseObj.Set("status", status)
+	responseObj.Set("statusText", statusText)
+	responseObj.Set("url", resp.Request.URL.String())
+	responseObj.Set("redirected", resp.Request.Response != nil)
+	responseObj.Set("type", responseType)
+	responseObj.Set("headers", f.headersObject(header))
 
-	// text() returns a promise-like that resolves with the body as text
-	responseObj.Set("text", func(call goja.FunctionCall) goja.Value {
-		textPromise := f.vm.NewObject()
-		var thenCb goja.Callable
+	bodyUsed := false
+	responseObj.DefineAccessorProperty("bodyUsed",
+		f.vm.ToValue(func(goja.FunctionCall) goja.Value { return f.vm.ToValue(bodyUsed) }),
+		nil, goja.FLAG_FALSE, goja.FLAG_TRUE)
 
-		textPromise.Set("then", func(call goja.FunctionCall) goja.Value {
-			if len(call.Arguments) > 0 {
-				if fn, ok := goja.AssertFunction(call.Argument(0)); ok {
-					thenCb = fn
-				}
-			}
-			// Immediately resolve since we have the data
-			if thenCb != nil {
-				f.loop.Schedule(func() {
-					thenCb(goja.Undefined(), f.vm.ToValue(bodyStr))
-				})
-			}
-			return textPromise
-		})
+	// consume returns a promise for the body as read gives it, or for the
+	// reason it gives; a body can only be read once
+	consume := func(read func() (value, reason goja.Value)) goja.Value {
+		promise, resolve, reject := f.vm.NewPromise()
+		if bodyUsed {
+			reject(f.vm.NewTypeError("Body has already been consumed"))
+			return f.vm.ToValue(promise)
+		}
+		bodyUsed = true
+		if value, reason := read(); reason != nil {
+			reject(reason)
+		} else {
+			resolve(value)
+		}
+		return f.vm.ToValue(promise)
+	}
 
-		return textPromise
+	// text() resolves with the body as text
+	responseObj.Set("text", func() goja.Value {
+		return consume(func() (goja.Value, goja.Value) { return f.vm.ToValue(string(body)), nil })
 	})
 
-	// json() returns a promise-like that resolves with parsed JSON
-	responseObj.Set("json", func(call goja.FunctionCall) goja.Value {
-		jsonPromise := f.vm.NewObject()
-		var thenCb goja.Callable
-
-		jsonPromise.Set("then", func(call goja.FunctionCall) goja.Value {
-			if len(call.Arguments) > 0 {
-				if fn, ok := goja.AssertFunction(call.Argument(0)); ok {
-					thenCb = fn
-				}
+	// json() resolves with the body parsed as JSON
+	responseObj.Set("json", func() goja.Value {
+		return consume(func() (goja.Value, goja.Value) {
+			parse, _ := goja.AssertFunction(f.vm.Get("JSON").ToObject(f.vm).Get("parse"))
+			value, err := parse(goja.Undefined(), f.vm.ToValue(string(body)))
+			var ex *goja.Exception
+			if errors.As(err, &ex) {
+				return nil, ex.Value()
 			}
-			if thenCb != nil {
-				f.loop.Schedule(func() {
-					// Parse JSON using Goja's JSON.parse
-					jsonParse := f.vm.Get("JSON").ToObject(f.vm).Get("parse")
-					if parseFn, ok := goja.AssertFunction(jsonParse); ok {
-						result, err := parseFn(goja.Undefined(), f.vm.ToValue(bodyStr))
-						if err != nil {
-							// Return the raw string on parse error
-							thenCb(goja.Undefined(), f.vm.ToValue(bodyStr))
-						} else {
-							thenCb(goja.Undefined(), result)
-						}
-					}
-				})
-			}
-			return jsonPromise
+			return value, nil
 		})
+	})
 
-		return jsonPromise
+	// arrayBuffer() resolves with the body's bytes
+	responseObj.Set("arrayBuffer", func() goja.Value {
+		return consume(func() (goja.Value, goja.Value) { return f.vm.ToValue(f.vm.NewArrayBuffer(body)), nil })
 	})
 
 	return responseObj
@@ -0,0 +1,180 @@
+package webapi
+
+import (
+	"bytes"
+	"fmt"
+	"mime/multipart"
+	"net/url"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// formEntries are the name/value pairs of URLSearchParams and FormData, in
+// the order they were added
+type formEntries struct {
+	names  []string
+	values []string
+}
+
+func (fe *formEntries) append(name, value string) {
+	fe.names = append(fe.names, name)
+	fe.values = append(fe.values, value)
+}
+
+func (fe *formEntries) delete(name string) {
+	var names, values []string
+	for i, n := range fe.names {
+		if n != name {
+			names = append(names, n)
+			values = append(values, fe.values[i])
+		}
+	}
+	fe.names, fe.values = names, values
+}
+
+// set replaces the first entry named name and removes the others, or
+// appends one when there is none
+func (fe *formEntries) set(name, value string) {
+	for i, n := range fe.names {
+		if n == name {
+			fe.values[i] = value
+			rest := &formEntries{names: fe.names[i+1:], values: fe.values[i+1:]}
+			rest.delete(name)
+			fe.names = append(fe.names[:i+1], rest.names...)
+			fe.values = append(fe.values[:i+1], rest.values...)
+			return
+		}
+	}
+	fe.append(name, value)
+}
+
+func (fe *formEntries) getAll(name string) []interface{} {
+	values := []interface{}{}
+	for i, n := range fe.names {
+		if n == name {
+			values = append(values, fe.values[i])
+		}
+	}
+	return values
+}
+
+// encode serializes the entries as application/x-www-form-urlencoded
+func (fe *formEntries) encode() string {
+	parts := make([]string, len(fe.names))
+	for i, name := range fe.names {
+		parts[i] = url.QueryEscape(name) + "=" + url.QueryEscape(fe.values[i])
+	}
+	return strings.Join(parts, "&")
+}
+
+// multipart serializes the entries as multipart/form-data, returning the
+// body and its content type
+func (fe *formEntries) multipart() ([]byte, string) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for i, name := range fe.names {
+		w.WriteField(name, fe.values[i])
+	}
+	w.Close()
+	return buf.Bytes(), w.FormDataContentType()
+}
+
+// defineEntries adds the methods URLSearchParams and FormData share to obj
+func (f *FetchAPI) defineEntries(obj *goja.Object, fe *formEntries) {
+	obj.Set("append", func(name, value string) { fe.append(name, value) })
+	obj.Set("delete", func(name string) { fe.delete(name) })
+	obj.Set("set", func(name, value string) { fe.set(name, value) })
+	obj.Set("get", func(name string) goja.Value {
+		if values := fe.getAll(name); len(values) > 0 {
+			return f.vm.ToValue(values[0])
+		}
+		return goja.Null()
+	})
+	obj.Set("getAll", func(name string) goja.Value { return f.vm.NewArray(fe.getAll(name)...) })
+	obj.Set("has", func(name string) bool { return len(fe.getAll(name)) > 0 })
+	obj.Set("forEach", func(callback goja.Callable) {
+		for i, name := range fe.names {
+			if _, err := callback(goja.Undefined(), f.vm.ToValue(fe.values[i]), f.vm.ToValue(name), obj); err != nil {
+				panic(err)
+			}
+		}
+	})
+	obj.Set("entries", func() goja.Value {
+		entries := make([]interface{}, len(fe.names))
+		for i, name := range fe.names {
+			entries[i] = f.vm.NewArray(name, fe.values[i])
+		}
+		return f.vm.NewArray(entries...)
+	})
+}
+
+// NewURLSearchParams implements the URLSearchParams constructor: new
+// URLSearchParams(init), where init is a query string, an array of pairs,
+// an object or another URLSearchParams
+func (f *FetchAPI) NewURLSearchParams(call goja.ConstructorCall) *goja.Object {
+	fe := &formEntries{}
+	init := call.Argument(0)
+	switch {
+	case goja.IsUndefined(init) || goja.IsNull(init):
+	case isObject(init):
+		obj := init.ToObject(f.vm)
+		if other, ok := f.searchParams[obj]; ok {
+			fe.names = append(fe.names, other.names...)
+			fe.values = append(fe.values, other.values...)
+			break
+		}
+		if obj.ClassName() == "Array" {
+			for _, pair := range obj.Export().([]interface{}) {
+				kv, ok := pair.([]interface{})
+				if !ok || len(kv) != 2 {
+					panic(f.vm.NewTypeError("URLSearchParams: each pair must have a name and a value"))
+				}
+				fe.append(toString(kv[0]), toString(kv[1]))
+			}
+			break
+		}
+		for _, name := range obj.Keys() {
+			fe.append(name, obj.Get(name).String())
+		}
+	default:
+		for _, part := range strings.Split(strings.TrimPrefix(init.String(), "?"), "&") {
+			if part == "" {
+				continue
+			}
+			name, value, _ := strings.Cut(part, "=")
+			name, _ = url.QueryUnescape(name)
+			value, _ = url.QueryUnescape(value)
+			fe.append(name, value)
+		}
+	}
+
+	obj := call.This
+	f.searchParams[obj] = fe
+	f.defineEntries(obj, fe)
+	obj.Set("toString", fe.encode)
+	return nil
+}
+
+// NewFormData implements the FormData constructor for forms built by
+// scripts with append() and set()
+func (f *FetchAPI) NewFormData(call goja.ConstructorCall) *goja.Object {
+	fe := &formEntries{}
+	f.formData[call.This] = fe
+	f.defineEntries(call.This, fe)
+	return nil
+}
+
+// isObject reports whether v is an object, functions included
+func isObject(v goja.Value) bool {
+	_, ok := v.(*goja.Object)
+	return ok
+}
+
+// toString converts an exported JS value to its string form
+func toString(v interface{}) string {
+	if v == nil {
+		return "null"
+	}
+	return fmt.Sprint(v)
+}
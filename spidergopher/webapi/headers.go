@@ -0,0 +1,102 @@
+package webapi
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// NewHeaders implements the Headers constructor: new Headers(init), where
+// init is another Headers, an array of [name, value] pairs or an object
+func (f *FetchAPI) NewHeaders(call goja.ConstructorCall) *goja.Object {
+	h, err := f.headersFrom(call.Argument(0))
+	if err != nil {
+		panic(f.vm.NewTypeError(err.Error()))
+	}
+	f.defineHeaders(call.This, h)
+	return nil
+}
+
+// headersObject creates a Headers object over h
+func (f *FetchAPI) headersObject(h http.Header) *goja.Object {
+	obj := f.vm.NewObject()
+	f.defineHeaders(obj, h)
+	return obj
+}
+
+// defineHeaders adds the methods of Headers over h to obj
+func (f *FetchAPI) defineHeaders(obj *goja.Object, h http.Header) {
+	f.headers[obj] = h
+	obj.Set("get", func(name string) goja.Value {
+		values := h.Values(name)
+		if len(values) == 0 {
+			return goja.Null()
+		}
+		return f.vm.ToValue(strings.Join(values, ", "))
+	})
+	obj.Set("has", func(name string) bool { return len(h.Values(name)) > 0 })
+	obj.Set("set", func(name, value string) { h.Set(name, value) })
+	obj.Set("append", func(name, value string) { h.Add(name, value) })
+	obj.Set("delete", func(name string) { h.Del(name) })
+	obj.Set("forEach", func(callback goja.Callable) {
+		for _, name := range sortedHeaderNames(h) {
+			if _, err := callback(goja.Undefined(), f.vm.ToValue(strings.Join(h.Values(name), ", ")), f.vm.ToValue(name), obj); err != nil {
+				panic(err)
+			}
+		}
+	})
+	obj.Set("keys", func() goja.Value {
+		names := make([]interface{}, 0, len(h))
+		for _, name := range sortedHeaderNames(h) {
+			names = append(names, name)
+		}
+		return f.vm.NewArray(names...)
+	})
+	obj.Set("entries", func() goja.Value {
+		entries := make([]interface{}, 0, len(h))
+		for _, name := range sortedHeaderNames(h) {
+			entries = append(entries, f.vm.NewArray(name, strings.Join(h.Values(name), ", ")))
+		}
+		return f.vm.NewArray(entries...)
+	})
+}
+
+// sortedHeaderNames returns h's header names lowercased and sorted, as
+// Headers iterates them
+func sortedHeaderNames(h http.Header) []string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// headersFrom reads the headers of a Headers object, an array of pairs or a
+// plain object. Undefined gives no headers.
+func (f *FetchAPI) headersFrom(v goja.Value) (http.Header, error) {
+	h := http.Header{}
+	if v == nil || goja.IsUndefined(v) || goja.IsNull(v) {
+		return h, nil
+	}
+	obj := v.ToObject(f.vm)
+	if other, ok := f.headers[obj]; ok {
+		return other.Clone(), nil
+	}
+	if obj.ClassName() == "Array" {
+		for _, pair := range obj.Export().([]interface{}) {
+			kv, ok := pair.([]interface{})
+			if !ok || len(kv) != 2 {
+				return nil, errHeaderPair
+			}
+			h.Add(toString(kv[0]), toString(kv[1]))
+		}
+		return h, nil
+	}
+	for _, name := range obj.Keys() {
+		h.Add(name, obj.Get(name).String())
+	}
+	return h, nil
+}
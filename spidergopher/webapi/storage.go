@@ -11,28 +11,68 @@ import (
 	"github.com/dop251/goja"
 )
 
-// Storage implements the Web Storage API (localStorage/sessionStorage)
+// Storage implements the Web Storage API (localStorage/sessionStorage).
+// Each origin sees only its own items.
 type Storage struct {
 	db        *sql.DB
 	tableName string
+	origin    string
 	mu        sync.RWMutex
 	vm        *goja.Runtime
 }
 
-// NewLocalStorage creates a persistent SQLite-based localStorage
-func NewLocalStorage(vm *goja.Runtime) (*Storage, error) {
-	// Store in user's home directory
+// LocalStore is the SQLite database a browser keeps localStorage in, shared
+// by the pages of all its tabs. It is opened on first use.
+type LocalStore struct {
+	path string // "" keeps it in memory
+	once sync.Once
+	db   *sql.DB
+	err  error
+}
+
+// DefaultLocalStorePath is the database localStorage is kept in when the
+// embedder chooses none: ~/.spidergopher/storage.db
+func DefaultLocalStorePath() string {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		homeDir = "."
 	}
+	return filepath.Join(homeDir, ".spidergopher", "storage.db")
+}
 
-	dbPath := filepath.Join(homeDir, ".spidergopher", "storage.db")
+// NewLocalStore creates a store keeping localStorage in the database at
+// path
+func NewLocalStore(path string) *LocalStore {
+	return &LocalStore{path: path}
+}
 
-	// Create directory if not exists
-	os.MkdirAll(filepath.Dir(dbPath), 0755)
+// NewMemoryLocalStore creates a store keeping localStorage in memory only,
+// gone with the store, for private browsing
+func NewMemoryLocalStore() *LocalStore {
+	return &LocalStore{}
+}
+
+// open opens the database the first time it is needed
+func (s *LocalStore) open() (*sql.DB, error) {
+	s.once.Do(func() {
+		dbPath := ":memory:"
+		if s.path != "" {
+			dbPath = s.path
+			os.MkdirAll(filepath.Dir(dbPath), 0755)
+		}
+		s.db, s.err = sql.Open("sqlite", dbPath)
+		if s.err == nil && s.path == "" {
+			// Each connection to :memory: is a database of its own
+			s.db.SetMaxOpenConns(1)
+		}
+	})
+	return s.db, s.err
+}
 
-	db, err := sql.Open("sqlite", dbPath)
+// NewLocalStorage creates the localStorage of vm for pages of origin, kept
+// in store
+func NewLocalStorage(vm *goja.Runtime, store *LocalStore, origin string) (*Storage, error) {
+	db, err := store.open()
 	if err != nil {
 		return nil, err
 	}
@@ -40,6 +80,7 @@ func NewLocalStorage(vm *goja.Runtime) (*Storage, error) {
 	storage := &Storage{
 		db:        db,
 		tableName: "localStorage",
+		origin:    origin,
 		vm:        vm,
 	}
 
@@ -73,8 +114,10 @@ func NewSessionStorage(vm *goja.Runtime) (*Storage, error) {
 func (s *Storage) ensureTable() error {
 	_, err := s.db.Exec(`
 		CREATE TABLE IF NOT EXISTS ` + s.tableName + ` (
-			key TEXT PRIMARY KEY,
-			value TEXT
+			origin TEXT NOT NULL,
+			key TEXT NOT NULL,
+			value TEXT,
+			PRIMARY KEY (origin, key)
 		)
 	`)
 	return err
@@ -92,7 +135,7 @@ func (s *Storage) GetItem(call goja.FunctionCall) goja.Value {
 	defer s.mu.RUnlock()
 
 	var value string
-	err := s.db.QueryRow("SELECT value FROM "+s.tableName+" WHERE key = ?", key).Scan(&value)
+	err := s.db.QueryRow("SELECT value FROM "+s.tableName+" WHERE origin = ? AND key = ?", s.origin, key).Scan(&value)
 	if err != nil {
 		return goja.Null()
 	}
@@ -113,8 +156,8 @@ func (s *Storage) SetItem(call goja.FunctionCall) goja.Value {
 	defer s.mu.Unlock()
 
 	_, err := s.db.Exec(`
-		INSERT OR REPLACE INTO `+s.tableName+` (key, value) VALUES (?, ?)
-	`, key, value)
+		INSERT OR REPLACE INTO `+s.tableName+` (origin, key, value) VALUES (?, ?, ?)
+	`, s.origin, key, value)
 
 	if err != nil {
 		// Could throw an exception here
@@ -135,7 +178,7 @@ func (s *Storage) RemoveItem(call goja.FunctionCall) goja.Value {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.db.Exec("DELETE FROM "+s.tableName+" WHERE key = ?", key)
+	s.db.Exec("DELETE FROM "+s.tableName+" WHERE origin = ? AND key = ?", s.origin, key)
 
 	return goja.Undefined()
 }
@@ -145,7 +188,7 @@ func (s *Storage) Clear(call goja.FunctionCall) goja.Value {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.db.Exec("DELETE FROM " + s.tableName)
+	s.db.Exec("DELETE FROM "+s.tableName+" WHERE origin = ?", s.origin)
 
 	return goja.Undefined()
 }
@@ -162,7 +205,7 @@ func (s *Storage) Key(call goja.FunctionCall) goja.Value {
 	defer s.mu.RUnlock()
 
 	var key string
-	err := s.db.QueryRow("SELECT key FROM "+s.tableName+" LIMIT 1 OFFSET ?", index).Scan(&key)
+	err := s.db.QueryRow("SELECT key FROM "+s.tableName+" WHERE origin = ? LIMIT 1 OFFSET ?", s.origin, index).Scan(&key)
 	if err != nil {
 		return goja.Null()
 	}
@@ -176,7 +219,7 @@ func (s *Storage) Length() int {
 	defer s.mu.RUnlock()
 
 	var count int
-	s.db.QueryRow("SELECT COUNT(*) FROM " + s.tableName).Scan(&count)
+	s.db.QueryRow("SELECT COUNT(*) FROM "+s.tableName+" WHERE origin = ?", s.origin).Scan(&count)
 	return count
 }
 
@@ -2,7 +2,6 @@ package webapi
 
 import (
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"go-browser/spidergopher/core"
@@ -10,6 +9,13 @@ import (
 	"github.com/dop251/goja"
 )
 
+// Timer nesting, as in the HTML spec: timers set from timer callbacks more
+// than maxTimerNesting levels deep wait at least minNestedDelay
+const (
+	maxTimerNesting = 5
+	minNestedDelay  = 4 * time.Millisecond
+)
+
 // Timers implements setTimeout, setInterval, clearTimeout, clearInterval
 type Timers struct {
 	loop     *core.EventLoop
@@ -17,13 +23,16 @@ type Timers struct {
 	timers   map[int64]*timerEntry
 	timersMu sync.Mutex
 	nextID   int64
+	nesting  int // nesting level of the timer whose callback is running, 0 outside
 }
 
 type timerEntry struct {
-	timer      *time.Timer
-	ticker     *time.Ticker
-	done       chan struct{}
-	isInterval bool
+	timer    *time.Timer
+	callback goja.Callable
+	args     []goja.Value // extra arguments passed to the callback
+	delay    time.Duration
+	nesting  int
+	repeat   bool
 }
 
 func NewTimers(loop *core.EventLoop) *Timers {
@@ -40,108 +49,119 @@ func (t *Timers) SetVM(vm *goja.Runtime) {
 
 // SetTimeout schedules a one-time callback
 func (t *Timers) SetTimeout(call goja.FunctionCall) goja.Value {
-	callback := call.Argument(0)
-	delay := call.Argument(1).ToInteger()
-
-	fn, ok := goja.AssertFunction(callback)
-	if !ok {
-		return t.vm.ToValue(0)
-	}
-
-	id := atomic.AddInt64(&t.nextID, 1)
-
-	timer := time.AfterFunc(time.Duration(delay)*time.Millisecond, func() {
-		t.loop.Schedule(func() {
-			fn(goja.Undefined())
-		})
-		t.removeTimer(id)
-	})
-
-	t.timersMu.Lock()
-	t.timers[id] = &timerEntry{timer: timer, isInterval: false}
-	t.timersMu.Unlock()
-
-	return t.vm.ToValue(id)
+	return t.vm.ToValue(t.start(call, false))
 }
 
 // ClearTimeout cancels a setTimeout
 func (t *Timers) ClearTimeout(call goja.FunctionCall) goja.Value {
-	id := call.Argument(0).ToInteger()
-	t.cancelTimer(id)
+	t.cancelTimer(call.Argument(0).ToInteger())
 	return goja.Undefined()
 }
 
 // SetInterval schedules a repeating callback
 func (t *Timers) SetInterval(call goja.FunctionCall) goja.Value {
-	callback := call.Argument(0)
-	delay := call.Argument(1).ToInteger()
+	return t.vm.ToValue(t.start(call, true))
+}
 
-	fn, ok := goja.AssertFunction(callback)
+// ClearInterval cancels a setInterval
+func (t *Timers) ClearInterval(call goja.FunctionCall) goja.Value {
+	t.cancelTimer(call.Argument(0).ToInteger())
+	return goja.Undefined()
+}
+
+// start sets a timer from the arguments of setTimeout or setInterval and
+// returns its id. Timeouts and intervals share ids, so either clear function
+// cancels either.
+func (t *Timers) start(call goja.FunctionCall, repeat bool) int64 {
+	fn, ok := goja.AssertFunction(call.Argument(0))
 	if !ok {
-		return t.vm.ToValue(0)
+		return 0
 	}
-
-	if delay < 1 {
-		delay = 1
+	var args []goja.Value
+	if len(call.Arguments) > 2 {
+		args = append(args, call.Arguments[2:]...)
+	}
+	entry := &timerEntry{
+		callback: fn,
+		args:     args,
+		delay:    time.Duration(max(call.Argument(1).ToInteger(), 0)) * time.Millisecond,
+		nesting:  t.nesting + 1,
+		repeat:   repeat,
 	}
-
-	id := atomic.AddInt64(&t.nextID, 1)
-	done := make(chan struct{})
-
-	ticker := time.NewTicker(time.Duration(delay) * time.Millisecond)
-
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				t.loop.Schedule(func() {
-					fn(goja.Undefined())
-				})
-			case <-done:
-				ticker.Stop()
-				return
-			}
-		}
-	}()
 
 	t.timersMu.Lock()
-	t.timers[id] = &timerEntry{ticker: ticker, done: done, isInterval: true}
+	t.nextID++
+	id := t.nextID
+	t.timers[id] = entry
+	t.arm(id, entry)
 	t.timersMu.Unlock()
-
-	return t.vm.ToValue(id)
+	return id
 }
 
-// ClearInterval cancels a setInterval
-func (t *Timers) ClearInterval(call goja.FunctionCall) goja.Value {
-	id := call.Argument(0).ToInteger()
-	t.cancelTimer(id)
-	return goja.Undefined()
+// arm waits out the entry's delay, then queues its callback on the loop
+func (t *Timers) arm(id int64, entry *timerEntry) {
+	delay := entry.delay
+	if entry.nesting > maxTimerNesting && delay < minNestedDelay {
+		delay = minNestedDelay
+	}
+	entry.timer = time.AfterFunc(delay, func() {
+		t.loop.Schedule(func() { t.fire(id) })
+	})
 }
 
-func (t *Timers) cancelTimer(id int64) {
+// fire runs a timer's callback on the loop, unless it was cleared while
+// waiting, and sets an interval going again. The callback may clear its own
+// timer; no lock is held while it runs.
+func (t *Timers) fire(id int64) {
 	t.timersMu.Lock()
-	defer t.timersMu.Unlock()
-
 	entry, ok := t.timers[id]
+	if ok && !entry.repeat {
+		delete(t.timers, id)
+	}
+	t.timersMu.Unlock()
 	if !ok {
 		return
 	}
 
-	if entry.isInterval {
-		if entry.done != nil {
-			close(entry.done)
-		}
-	} else {
-		if entry.timer != nil {
-			entry.timer.Stop()
-		}
+	outer := t.nesting
+	t.nesting = entry.nesting
+	_, err := entry.callback(goja.Undefined(), entry.args...)
+	t.nesting = outer
+	t.loop.ReportError(err)
+
+	if !entry.repeat {
+		return
+	}
+	t.timersMu.Lock()
+	defer t.timersMu.Unlock()
+	if t.timers[id] == entry {
+		entry.nesting++
+		t.arm(id, entry)
 	}
+}
 
-	delete(t.timers, id)
+// QueueMicrotask runs a callback once the running task or script is done,
+// with the promise reactions
+func (t *Timers) QueueMicrotask(call goja.FunctionCall) goja.Value {
+	fn, ok := goja.AssertFunction(call.Argument(0))
+	if !ok {
+		panic(t.vm.NewTypeError("queueMicrotask: argument 1 is not a function"))
+	}
+	t.loop.QueueMicrotask(func() {
+		_, err := fn(goja.Undefined())
+		t.loop.ReportError(err)
+	})
+	return goja.Undefined()
 }
 
-func (t *Timers) removeTimer(id int64) {
+func (t *Timers) cancelTimer(id int64) {
 	t.timersMu.Lock()
 	defer t.timersMu.Unlock()
+
+	entry, ok := t.timers[id]
+	if !ok {
+		return
+	}
+	entry.timer.Stop()
 	delete(t.timers, id)
 }
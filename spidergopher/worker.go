@@ -0,0 +1,280 @@
+package spidergopher
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync/atomic"
+
+	"go-browser/spidergopher/core"
+	"go-browser/spidergopher/dom"
+	"go-browser/spidergopher/webapi"
+
+	"github.com/dop251/goja"
+)
+
+// ======================================================================================
+// WEB WORKERS
+// new Worker(url) runs a script in a runtime of its own, on its own event loop
+// goroutine and without a DOM, so heavy work doesn't hold up the page. The
+// page and the worker share nothing but messages, cloned by postMessage.
+// Errors the worker leaves uncaught fire error at its Worker object.
+// ======================================================================================
+
+// worker is the page's side of a worker: the Worker object its scripts hold
+type worker struct {
+	engine *Engine
+	obj    *goja.Object
+	events *dom.EventTarget // message and error listeners
+	scope  *workerScope
+}
+
+// workerScope is the global scope the worker's scripts run in
+type workerScope struct {
+	worker    *worker
+	url       string
+	vm        *goja.Runtime
+	loop      *core.EventLoop
+	listeners map[string][]goja.Value // listeners added with addEventListener
+	closed    atomic.Bool             // set by terminate() and close()
+}
+
+// newWorker implements the Worker constructor
+func (e *Engine) newWorker(call goja.ConstructorCall) *goja.Object {
+	scriptURL := ResolveURL(call.Argument(0).String(), e.ScriptURL())
+	script, err1 := url.Parse(scriptURL)
+	page, err2 := url.Parse(e.ScriptURL())
+	if err1 != nil || err2 != nil || !webapi.SameOrigin(script, page) {
+		panic(webapi.DOMException(e.vm, "SecurityError", "Worker scripts must be same-origin: "+scriptURL))
+	}
+
+	w := &worker{engine: e, obj: call.This, events: dom.NewEventTarget()}
+	obj := call.This
+	obj.Set("postMessage", w.postMessage)
+	obj.Set("terminate", w.terminate)
+	obj.Set("onmessage", goja.Null())
+	obj.Set("onerror", goja.Null())
+	obj.Set("addEventListener", func(eventType string, listener goja.Value) {
+		w.events.AddEventListener(eventType, listener)
+	})
+	obj.Set("removeEventListener", func(eventType string, listener goja.Value) {
+		w.events.RemoveEventListener(eventType, listener)
+	})
+
+	w.scope = newWorkerScope(w, scriptURL)
+	e.workers = append(e.workers, w)
+	w.scope.start()
+	return nil
+}
+
+// postMessage sends a clone of a value to the worker
+func (w *worker) postMessage(message goja.Value) {
+	data, err := webapi.Clone(message)
+	if err != nil {
+		panic(webapi.DOMException(w.engine.vm, "DataCloneError", "The message could not be cloned"))
+	}
+	if w.scope.closed.Load() {
+		return
+	}
+	w.scope.loop.Schedule(func() { w.scope.receive(data) })
+}
+
+// terminate stops the worker at once
+func (w *worker) terminate() {
+	w.scope.stop()
+	w.engine.workers = slices.DeleteFunc(w.engine.workers, func(other *worker) bool { return other == w })
+}
+
+// receive fires message at the Worker object with data the worker posted
+func (w *worker) receive(data interface{}) {
+	e := w.engine
+	event := dom.NewEvent("message")
+	event.Fields = map[string]interface{}{"data": webapi.Revive(e.vm, data)}
+	if handler, ok := goja.AssertFunction(w.obj.Get("onmessage")); ok {
+		if _, err := handler(w.obj, e.vm.ToValue(event.ToJSObject())); err != nil {
+			e.ReportError(err)
+		}
+	}
+	w.events.DispatchEvent(e.vm, event)
+}
+
+// uncaught fires error at the Worker object for an error the worker didn't
+// handle, and logs it unless the page cancels it
+func (w *worker) uncaught(report ScriptError) {
+	e := w.engine
+	handled := false
+	if handler, ok := goja.AssertFunction(w.obj.Get("onerror")); ok {
+		ret, err := handler(w.obj, e.vm.ToValue(report.Message), e.vm.ToValue(report.Source),
+			e.vm.ToValue(report.Line), e.vm.ToValue(report.Column))
+		if err != nil {
+			e.ReportError(err)
+		}
+		handled = ret != nil && ret.ToBoolean()
+	}
+
+	event := dom.NewEvent("error")
+	event.Cancelable = true
+	event.Fields = map[string]interface{}{
+		"message":  report.Message,
+		"filename": report.Source,
+		"lineno":   report.Line,
+		"colno":    report.Column,
+	}
+	if w.events.DispatchEvent(e.vm, event) && !handled {
+		e.logError(report)
+	}
+}
+
+// newWorkerScope creates the runtime of a worker running scriptURL, with
+// the globals of a worker: no document or window
+func newWorkerScope(w *worker, scriptURL string) *workerScope {
+	vm := goja.New()
+	s := &workerScope{
+		worker:    w,
+		url:       scriptURL,
+		vm:        vm,
+		loop:      core.NewEventLoop(vm),
+		listeners: make(map[string][]goja.Value),
+	}
+	s.loop.SetErrorHandler(s.uncaught)
+
+	global := vm.GlobalObject()
+	vm.Set("self", global)
+	vm.Set("onmessage", goja.Null())
+	vm.Set("onerror", goja.Null())
+	vm.Set("postMessage", s.postMessage)
+	vm.Set("close", s.stop)
+	vm.Set("importScripts", s.importScripts)
+	vm.Set("addEventListener", func(eventType string, listener goja.Value) {
+		if _, ok := goja.AssertFunction(listener); ok {
+			s.listeners[eventType] = append(s.listeners[eventType], listener)
+		}
+	})
+	vm.Set("removeEventListener", func(eventType string, listener goja.Value) {
+		s.listeners[eventType] = slices.DeleteFunc(s.listeners[eventType], listener.SameAs)
+	})
+
+	console := webapi.NewConsole()
+	console.SetVM(vm)
+	vm.Set("console", consoleObject(vm, console))
+	installTimers(vm, s.loop)
+	fetchAPI := webapi.NewFetchAPI(s.loop, vm)
+	fetchAPI.SetURLs(func() (string, string) { return scriptURL, scriptURL })
+	fetchAPI.SetClient(func() *http.Client { return w.engine.client })
+	installFetch(vm, fetchAPI)
+	return s
+}
+
+// start runs the worker's script on its loop
+func (s *workerScope) start() {
+	s.loop.Start()
+	s.loop.Schedule(func() {
+		source, err := LoadScript(s.worker.engine.client, s.url)
+		if err != nil {
+			s.worker.engine.Loop.Schedule(func() {
+				s.worker.uncaught(ScriptError{Message: "Uncaught NetworkError: " + err.Error(), Source: s.url})
+			})
+			return
+		}
+		program, err := compileScript(source, s.url)
+		if err == nil {
+			_, err = s.vm.RunProgram(program)
+		}
+		s.loop.ReportError(err)
+	})
+}
+
+// stop ends the worker: its loop stops and the script running is interrupted
+func (s *workerScope) stop() {
+	if s.closed.Swap(true) {
+		return
+	}
+	s.loop.Stop()
+	s.vm.Interrupt("worker terminated")
+}
+
+// postMessage sends a clone of a value to the page
+func (s *workerScope) postMessage(message goja.Value) {
+	data, err := webapi.Clone(message)
+	if err != nil {
+		err, _ := s.vm.New(s.vm.Get("Error"), s.vm.ToValue("The message could not be cloned"))
+		err.Set("name", "DataCloneError")
+		panic(err)
+	}
+	if s.closed.Load() {
+		return
+	}
+	w := s.worker
+	w.engine.Loop.Schedule(func() { w.receive(data) })
+}
+
+// receive fires message at the worker's global scope with data the page
+// posted
+func (s *workerScope) receive(data interface{}) {
+	if s.closed.Load() {
+		return
+	}
+	event := s.vm.NewObject()
+	event.Set("type", "message")
+	event.Set("data", webapi.Revive(s.vm, data))
+	event.Set("target", s.vm.GlobalObject())
+
+	handlers := s.listeners["message"]
+	if onmessage := s.vm.Get("onmessage"); onmessage != nil {
+		handlers = append([]goja.Value{onmessage}, handlers...)
+	}
+	for _, handler := range handlers {
+		if fn, ok := goja.AssertFunction(handler); ok {
+			_, err := fn(goja.Undefined(), event)
+			s.loop.ReportError(err)
+		}
+	}
+}
+
+// importScripts loads and runs each URL in order, relative to the worker's
+// script
+func (s *workerScope) importScripts(call goja.FunctionCall) goja.Value {
+	for _, arg := range call.Arguments {
+		scriptURL := ResolveURL(arg.String(), s.url)
+		source, err := LoadScript(s.worker.engine.client, scriptURL)
+		if err != nil {
+			panic(s.vm.NewGoError(err))
+		}
+		if _, err := s.vm.RunScript(scriptURL, source); err != nil {
+			if ex, ok := err.(*goja.Exception); ok {
+				panic(ex)
+			}
+			panic(s.vm.NewGoError(err))
+		}
+	}
+	return goja.Undefined()
+}
+
+// uncaught offers an error the worker's scripts didn't catch to the
+// worker's onerror, then to the page
+func (s *workerScope) uncaught(err error) {
+	var interrupted *goja.InterruptedError
+	if errors.As(err, &interrupted) {
+		return
+	}
+	report := ScriptError{Message: "Uncaught " + err.Error(), Source: s.url}
+	var thrown goja.Value = goja.Undefined()
+	var ex *goja.Exception
+	var syntax *SyntaxError
+	switch {
+	case errors.As(err, &ex):
+		report, thrown = exceptionReport(ex), ex.Value()
+	case errors.As(err, &syntax):
+		report = syntax.report()
+	}
+	if onerror, ok := goja.AssertFunction(s.vm.Get("onerror")); ok {
+		ret, err := onerror(goja.Undefined(), s.vm.ToValue(report.Message), s.vm.ToValue(report.Source),
+			s.vm.ToValue(report.Line), s.vm.ToValue(report.Column), thrown)
+		if err == nil && ret != nil && ret.ToBoolean() {
+			return
+		}
+	}
+	w := s.worker
+	w.engine.Loop.Schedule(func() { w.uncaught(report) })
+}